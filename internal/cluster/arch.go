@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// HostArch returns the CPU architecture local cluster nodes run with, named
+// like the kubernetes.io/arch node label: the architecture of the container
+// runtime, or of deskrun itself when the runtime can't be queried
+func HostArch(ctx context.Context) string {
+	format := "{{.Architecture}}"
+	if containerCLI() == RuntimePodman {
+		format = "{{.Host.Arch}}"
+	}
+	out, err := exec.CommandContext(ctx, containerCLI(), "info", "--format", format).Output()
+	if err != nil || strings.TrimSpace(string(out)) == "" {
+		return runtime.GOARCH
+	}
+	return normalizeArch(strings.TrimSpace(string(out)))
+}
+
+// normalizeArch maps the architecture names of uname, which docker info
+// reports, to the names of Go and Kubernetes
+func normalizeArch(arch string) string {
+	switch arch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	default:
+		return arch
+	}
+}
+
+// ImageArchitectures returns the Linux architectures an image is published
+// for, read from its registry manifest. It returns no architectures when the
+// manifest doesn't name them.
+func ImageArchitectures(ctx context.Context, image string) ([]string, error) {
+	args := []string{"manifest", "inspect", image}
+	if containerCLI() == RuntimeDocker {
+		// Only the verbose output names the platform of single-platform images
+		args = []string{"manifest", "inspect", "--verbose", image}
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, containerCLI(), args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect the manifest of %s: %w: %s", image, err, strings.TrimSpace(stderr.String()))
+	}
+	return parseManifestArchitectures(out)
+}
+
+// manifestPlatform is the platform of a manifest descriptor
+type manifestPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// verboseManifest is an entry of the verbose output of docker manifest inspect
+type verboseManifest struct {
+	Descriptor struct {
+		Platform *manifestPlatform `json:"platform"`
+	} `json:"Descriptor"`
+}
+
+// parseManifestArchitectures returns the sorted Linux architectures of the
+// output of manifest inspect: a manifest list or image index, or the verbose
+// output of docker, a descriptor or a list of descriptors
+func parseManifestArchitectures(out []byte) ([]string, error) {
+	var platforms []*manifestPlatform
+	out = bytes.TrimSpace(out)
+	switch {
+	case bytes.HasPrefix(out, []byte("[")):
+		var entries []verboseManifest
+		if err := json.Unmarshal(out, &entries); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		for _, entry := range entries {
+			platforms = append(platforms, entry.Descriptor.Platform)
+		}
+	default:
+		var manifest struct {
+			verboseManifest
+			Manifests []struct {
+				Platform *manifestPlatform `json:"platform"`
+			} `json:"manifests"`
+		}
+		if err := json.Unmarshal(out, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		platforms = append(platforms, manifest.Descriptor.Platform)
+		for _, entry := range manifest.Manifests {
+			platforms = append(platforms, entry.Platform)
+		}
+	}
+
+	var archs []string
+	for _, platform := range platforms {
+		// Attestation manifests have the unknown platform
+		if platform == nil || platform.OS != "linux" || slices.Contains(archs, platform.Architecture) {
+			continue
+		}
+		archs = append(archs, platform.Architecture)
+	}
+	slices.Sort(archs)
+	return archs, nil
+}
@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeArch(t *testing.T) {
+	for arch, want := range map[string]string{"x86_64": "amd64", "aarch64": "arm64", "arm64": "arm64", "amd64": "amd64"} {
+		if got := normalizeArch(arch); got != want {
+			t.Errorf("normalizeArch(%q) = %q, want %q", arch, got, want)
+		}
+	}
+}
+
+func TestParseManifestArchitectures(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []string
+	}{
+		{
+			name: "image index with attestations",
+			out: `{"schemaVersion": 2, "manifests": [
+				{"platform": {"architecture": "arm64", "os": "linux"}},
+				{"platform": {"architecture": "amd64", "os": "linux"}},
+				{"platform": {"architecture": "unknown", "os": "unknown"}}
+			]}`,
+			want: []string{"amd64", "arm64"},
+		},
+		{
+			name: "verbose list",
+			out: `[
+				{"Ref": "docker.io/library/docker:dind@sha256:1", "Descriptor": {"platform": {"architecture": "amd64", "os": "linux"}}},
+				{"Ref": "docker.io/library/docker:dind@sha256:2", "Descriptor": {"platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}}},
+				{"Ref": "docker.io/library/docker:dind@sha256:3", "Descriptor": {"platform": {"architecture": "amd64", "os": "windows"}}}
+			]`,
+			want: []string{"amd64", "arm64"},
+		},
+		{
+			name: "verbose single-platform image",
+			out:  `{"Ref": "ghcr.io/owner/runner:1.0", "Descriptor": {"platform": {"architecture": "amd64", "os": "linux"}}}`,
+			want: []string{"amd64"},
+		},
+		{
+			name: "single manifest without platform",
+			out:  `{"schemaVersion": 2, "config": {"digest": "sha256:1"}}`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseManifestArchitectures([]byte(tt.out))
+			if err != nil {
+				t.Fatalf("parseManifestArchitectures() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseManifestArchitectures() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseManifestArchitectures([]byte("not json")); err == nil {
+		t.Error("parseManifestArchitectures() accepted invalid output")
+	}
+}
@@ -3,47 +3,60 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/rkoster/deskrun/pkg/types"
-	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
-	"sigs.k8s.io/kind/pkg/cluster"
 )
 
-// Manager handles kind cluster operations
+// Manager handles cluster operations, delegating provisioning to the
+// configured cluster provider
 type Manager struct {
 	config   *types.ClusterConfig
-	provider *cluster.Provider
+	provider Provider
 }
 
 // NewManager creates a new cluster manager
 func NewManager(config *types.ClusterConfig) *Manager {
 	return &Manager{
 		config:   config,
-		provider: cluster.NewProvider(),
+		provider: NewProvider(config.Provider),
 	}
 }
 
-// Exists checks if the cluster exists
-func (m *Manager) Exists(ctx context.Context) (bool, error) {
-	clusters, err := m.provider.List()
-	if err != nil {
-		return false, fmt.Errorf("failed to list clusters: %w", err)
-	}
+// ProviderName returns the name of the provider that provisions the cluster
+func (m *Manager) ProviderName() string {
+	return m.provider.Name()
+}
+
+// IsKind reports whether the cluster is a kind cluster deskrun manages
+func (m *Manager) IsKind() bool {
+	return !m.IsExternal() && m.provider.Name() == ProviderKind
+}
 
-	// Check if our cluster is in the list
-	for _, name := range clusters {
-		if name == m.config.Name {
-			return true, nil
+// Exists checks if the cluster exists. Existing clusters deskrun deploys to
+// through their kube context always exist, but must be reachable.
+func (m *Manager) Exists(ctx context.Context) (bool, error) {
+	if m.IsExternal() {
+		if _, err := m.ServerVersion(ctx); err != nil {
+			return false, err
 		}
+		return true, nil
 	}
 
-	return false, nil
+	return m.provider.Exists(ctx, m.config.Name)
 }
 
-// Create creates a new kind cluster
+// Create creates a new cluster. For existing clusters deskrun deploys to
+// through their kube context, it only checks that the cluster is reachable.
 func (m *Manager) Create(ctx context.Context) error {
+	if m.IsExternal() {
+		_, err := m.ServerVersion(ctx)
+		return err
+	}
+
 	exists, err := m.Exists(ctx)
 	if err != nil {
 		return err
@@ -53,23 +66,25 @@ func (m *Manager) Create(ctx context.Context) error {
 		return fmt.Errorf("cluster %s already exists", m.config.Name)
 	}
 
-	// Build kind configuration with nix mounts
-	kindConfig := m.buildKindConfig()
-
-	// Create cluster using kind Go package with custom config
-	err = m.provider.Create(m.config.Name,
-		cluster.CreateWithV1Alpha4Config(kindConfig),
-		cluster.CreateWithWaitForReady(0), // Use default wait time
-	)
-	if err != nil {
+	if err := ValidateHost(); err != nil {
+		return err
+	}
+	if err := m.provider.Create(ctx, m.config); err != nil {
 		return fmt.Errorf("failed to create cluster: %w", err)
 	}
 
 	return nil
 }
 
-// Delete deletes the kind cluster
+// Delete deletes the cluster. Existing clusters deskrun deploys to
+// through their kube context are not managed by deskrun and are left alone,
+// after checking that they are reachable.
 func (m *Manager) Delete(ctx context.Context) error {
+	if m.IsExternal() {
+		_, err := m.ServerVersion(ctx)
+		return err
+	}
+
 	exists, err := m.Exists(ctx)
 	if err != nil {
 		return err
@@ -79,19 +94,26 @@ func (m *Manager) Delete(ctx context.Context) error {
 		return fmt.Errorf("cluster %s does not exist", m.config.Name)
 	}
 
-	// Delete cluster using kind Go package
-	err = m.provider.Delete(m.config.Name, "")
-	if err != nil {
+	if err := m.provider.Delete(ctx, m.config.Name); err != nil {
 		return fmt.Errorf("failed to delete cluster: %w", err)
 	}
 
 	return nil
 }
 
-// DetectNixMounts detects available nix mounts on the host system
+// DetectNixMounts detects available nix mounts on the host system. On macOS
+// the Nix store holds Darwin binaries and is not shared with the VM of the
+// container runtime, so nothing is mounted.
 func DetectNixMounts() (*types.ClusterMount, *types.ClusterMount) {
 	var nixStore, nixSocket *types.ClusterMount
 
+	if vm := CurrentHostVM(); vm != nil {
+		if _, err := os.Stat("/nix/store"); err == nil {
+			slog.Info("Not mounting the Nix store of macOS into the cluster, it holds Darwin binaries", "vm", vm.Name)
+		}
+		return nil, nil
+	}
+
 	// Check for /nix/store
 	if _, err := os.Stat("/nix/store"); err == nil {
 		nixStore = &types.ClusterMount{
@@ -123,15 +145,20 @@ func DetectNixMounts() (*types.ClusterMount, *types.ClusterMount) {
 func DetectDeskrunCache() *types.ClusterMount {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to get home directory for deskrun cache: %v\n", err)
+		slog.Warn("Failed to get home directory for deskrun cache", "error", err)
 		return nil
 	}
 
 	deskrunCachePath := filepath.Join(homeDir, ".cache", "deskrun")
+	if vm := CurrentHostVM(); vm != nil && !vm.Shares(deskrunCachePath) {
+		slog.Warn("Not mounting the deskrun cache directory, the VM of the container runtime does not share it",
+			"path", deskrunCachePath, "vm", vm.Name, "shared", strings.Join(vm.SharedPaths, ", "))
+		return nil
+	}
 
 	// Create the directory if it doesn't exist
 	if err := os.MkdirAll(deskrunCachePath, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to create deskrun cache directory at %s: %v\n", deskrunCachePath, err)
+		slog.Warn("Failed to create deskrun cache directory", "path", deskrunCachePath, "error", err)
 		return nil
 	}
 
@@ -141,79 +168,42 @@ func DetectDeskrunCache() *types.ClusterMount {
 	}
 }
 
-// DetectDockerSocket detects if Docker socket is available on the host system
+// DetectDockerSocket detects the socket of the container runtime on the host
+// system, the Docker socket or the (rootless) Podman socket. It is mounted at
+// the Docker socket path, which the runner modes expect. On macOS the nodes
+// mount the Docker socket inside the VM of colima or Docker Desktop instead.
 func DetectDockerSocket() *types.ClusterMount {
-	dockerSocketPath := "/var/run/docker.sock"
-
-	// Check if Docker socket exists
-	if _, err := os.Stat(dockerSocketPath); err == nil {
+	if vm := CurrentHostVM(); vm != nil {
+		if vm.Name == VMPodmanMachine {
+			return nil
+		}
 		return &types.ClusterMount{
 			HostPath:      dockerSocketPath,
 			ContainerPath: dockerSocketPath,
 		}
 	}
 
-	return nil
-}
-
-// buildKindConfig creates a kind cluster configuration with nix and cache mounts
-func (m *Manager) buildKindConfig() *v1alpha4.Cluster {
-	config := &v1alpha4.Cluster{
-		TypeMeta: v1alpha4.TypeMeta{
-			Kind:       "Cluster",
-			APIVersion: "kind.x-k8s.io/v1alpha4",
-		},
-		Name: m.config.Name,
-	}
-
-	// Add a single node configuration
-	node := v1alpha4.Node{
-		Role: v1alpha4.ControlPlaneRole,
-	}
-
-	// Add nix mounts if available
-	var extraMounts []v1alpha4.Mount
-
-	if m.config.NixStore != nil {
-		extraMounts = append(extraMounts, v1alpha4.Mount{
-			HostPath:      m.config.NixStore.HostPath,
-			ContainerPath: m.config.NixStore.ContainerPath,
-			Readonly:      true,
-		})
-	}
-
-	if m.config.NixSocket != nil {
-		// Create the directory for the socket if it doesn't exist
-		socketDir := filepath.Dir(m.config.NixSocket.ContainerPath)
-		extraMounts = append(extraMounts, v1alpha4.Mount{
-			HostPath:      filepath.Dir(m.config.NixSocket.HostPath),
-			ContainerPath: socketDir,
-		})
-	}
-
-	if m.config.DeskrunCache != nil {
-		extraMounts = append(extraMounts, v1alpha4.Mount{
-			HostPath:      m.config.DeskrunCache.HostPath,
-			ContainerPath: m.config.DeskrunCache.ContainerPath,
-		})
-	}
-
-	if m.config.DockerSocket != nil {
-		extraMounts = append(extraMounts, v1alpha4.Mount{
-			HostPath:      m.config.DockerSocket.HostPath,
-			ContainerPath: m.config.DockerSocket.ContainerPath,
-		})
+	runtime := containerRuntime()
+	if runtime.Socket == "" {
+		return nil
 	}
 
-	if len(extraMounts) > 0 {
-		node.ExtraMounts = extraMounts
+	return &types.ClusterMount{
+		HostPath:      runtime.Socket,
+		ContainerPath: dockerSocketPath,
 	}
-
-	config.Nodes = []v1alpha4.Node{node}
-	return config
 }
 
 // GetKubeconfig returns the kubeconfig context name for the cluster
 func (m *Manager) GetKubeconfig() string {
-	return fmt.Sprintf("kind-%s", m.config.Name)
+	if m.IsExternal() {
+		return m.config.KubeContext
+	}
+	return m.provider.KubeContext(m.config.Name)
+}
+
+// NodeMounts returns the container paths that are mounted into the cluster's
+// control plane node
+func (m *Manager) NodeMounts(ctx context.Context) ([]string, error) {
+	return m.provider.NodeMounts(ctx, m.config.Name)
 }
@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// exposeImage is the image of the containers forwarding host ports to the node
+	exposeImage = "docker.io/alpine/socat:latest"
+	// exposeClusterLabel labels the port forwarding containers with their cluster
+	exposeClusterLabel = "deskrun.io/expose-cluster"
+)
+
+// exposeContainerName returns the name of the container forwarding a host
+// port to the node of a cluster, e.g. deskrun-expose-deskrun-9090
+func exposeContainerName(clusterName string, hostPort int32) string {
+	return fmt.Sprintf("deskrun-expose-%s-%d", clusterName, hostPort)
+}
+
+// exposeArgs returns the arguments of the container run command that forwards
+// 127.0.0.1:hostPort to the node port of the cluster's kind node
+func exposeArgs(clusterName string, hostPort, nodePort int32) []string {
+	port := strconv.Itoa(int(hostPort))
+	return []string{"run", "--detach",
+		"--name", exposeContainerName(clusterName, hostPort),
+		"--restart", "always",
+		"--network", kindNetwork,
+		"--label", exposeClusterLabel + "=" + clusterName,
+		"--publish", "127.0.0.1:" + port + ":" + port,
+		exposeImage,
+		fmt.Sprintf("tcp-listen:%d,fork,reuseaddr", hostPort),
+		fmt.Sprintf("tcp-connect:%s:%d", kindNodeName(clusterName), nodePort),
+	}
+}
+
+// ExposeNodePort forwards a port on 127.0.0.1 of the host to a NodePort of the
+// kind node with a socat container on the kind network. Unlike port mappings
+// this works for running clusters. An existing forward of the host port is
+// replaced. Only kind clusters are supported.
+func (m *Manager) ExposeNodePort(ctx context.Context, hostPort, nodePort int32) error {
+	if !m.IsKind() {
+		return fmt.Errorf("exposing node ports is only supported for kind clusters")
+	}
+
+	name := exposeContainerName(m.config.Name, hostPort)
+	if err := removeContainers(ctx, name); err != nil {
+		return err
+	}
+
+	out, err := exec.CommandContext(ctx, containerCLI(), exposeArgs(m.config.Name, hostPort, nodePort)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to expose node port %d on port %d: %w: %s", nodePort, hostPort, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RemoveExposedPorts removes all containers forwarding host ports to the
+// node of the cluster
+func (m *Manager) RemoveExposedPorts(ctx context.Context) error {
+	out, err := exec.CommandContext(ctx, containerCLI(), "ps", "--all", "--quiet",
+		"--filter", "label="+exposeClusterLabel+"="+m.config.Name).Output()
+	if err != nil {
+		return fmt.Errorf("failed to list port forwarding containers: %w", err)
+	}
+
+	return removeContainers(ctx, strings.Fields(string(out))...)
+}
+
+// removeContainers force-removes containers, ignoring ones that don't exist
+func removeContainers(ctx context.Context, containers ...string) error {
+	if len(containers) == 0 {
+		return nil
+	}
+
+	args := append([]string{"rm", "--force"}, containers...)
+	out, err := exec.CommandContext(ctx, containerCLI(), args...).CombinedOutput()
+	if err != nil && !strings.Contains(strings.ToLower(string(out)), "no such container") {
+		return fmt.Errorf("failed to remove containers %s: %w: %s", strings.Join(containers, ", "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package cluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+func TestExposeArgs(t *testing.T) {
+	args := exposeArgs("deskrun", 9090, 30090)
+
+	want := []string{"run", "--detach",
+		"--name", "deskrun-expose-deskrun-9090",
+		"--restart", "always",
+		"--network", "kind",
+		"--label", "deskrun.io/expose-cluster=deskrun",
+		"--publish", "127.0.0.1:9090:9090",
+		exposeImage,
+		"tcp-listen:9090,fork,reuseaddr",
+		"tcp-connect:deskrun-control-plane:30090",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("exposeArgs() = %v, want %v", args, want)
+	}
+}
+
+func TestExposeNodePortRequiresKind(t *testing.T) {
+	mgr := NewManager(&types.ClusterConfig{Name: "k3s", KubeContext: "default"})
+
+	err := mgr.ExposeNodePort(t.Context(), 9090, 30090)
+	if err == nil || !strings.Contains(err.Error(), "only supported for kind clusters") {
+		t.Errorf("ExposeNodePort() of external cluster error = %v, want kind clusters only", err)
+	}
+}
@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// externalTimeout bounds the reachability check of existing clusters
+const externalTimeout = 10 * time.Second
+
+// IsExternal reports whether the cluster is an existing cluster deskrun
+// deploys to through its kube context, instead of a kind cluster it manages
+func (m *Manager) IsExternal() bool {
+	return m.config.KubeContext != ""
+}
+
+// ServerVersion checks that the API server of the cluster is reachable
+// through its kube context and returns its version
+func (m *Manager) ServerVersion(ctx context.Context) (string, error) {
+	kubeContext := m.GetKubeconfig()
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rawConfig, err := loadingRules.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	if _, ok := rawConfig.Contexts[kubeContext]; !ok {
+		return "", fmt.Errorf("kube context %s not found in kubeconfig", kubeContext)
+	}
+
+	configOverrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*rawConfig, kubeContext, configOverrides, loadingRules).ClientConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to load kube context %s: %w", kubeContext, err)
+	}
+	restConfig.Timeout = externalTimeout
+
+	client, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to create discovery client: %w", err)
+	}
+
+	version, err := client.ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("kube context %s is not reachable: %w", kubeContext, err)
+	}
+	return version.GitVersion, nil
+}
@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SaveImages pulls images into the container runtime of the host and saves
+// them to a docker image archive, which LoadImages loads into cluster nodes
+// without registry access
+func SaveImages(ctx context.Context, archive string, images []string) error {
+	for _, image := range images {
+		if out, err := exec.CommandContext(ctx, containerCLI(), "pull", image).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to pull %s: %w: %s", image, err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	args := []string{"save", "--output", archive}
+	if containerCLI() == RuntimePodman {
+		// podman only saves a single image without it
+		args = append(args, "--multi-image-archive")
+	}
+	args = append(args, images...)
+	if out, err := exec.CommandContext(ctx, containerCLI(), args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to save images: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// LoadImages loads the images of a docker image archive into the cluster
+// node. Existing clusters deskrun deploys to through their kube context are
+// not supported, their nodes are not known.
+func (m *Manager) LoadImages(ctx context.Context, archive string) error {
+	if m.IsExternal() {
+		return fmt.Errorf("cluster %s uses kube context %s; load the images into its nodes with its own tooling", m.config.Name, m.config.KubeContext)
+	}
+
+	if err := m.provider.LoadImages(ctx, m.config.Name, archive); err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+	return nil
+}
+
+// PrePullImages pulls images into the container runtime of the host and loads
+// them into the cluster node, like kind load docker-image does, so pods start
+// without pulling them. Images cached by the host runtime survive deleting
+// the cluster.
+func (m *Manager) PrePullImages(ctx context.Context, images []string) error {
+	dir, err := os.MkdirTemp("", "deskrun-images-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "images.tar")
+	if err := SaveImages(ctx, archive, images); err != nil {
+		return err
+	}
+	return m.LoadImages(ctx, archive)
+}
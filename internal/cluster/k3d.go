@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+// k3sImageRepository is the repository of the k3s images k3d runs
+const k3sImageRepository = "rancher/k3s"
+
+// k3dServerNode is the k3d node filter of the single server node
+const k3dServerNode = "@server:0"
+
+// k3dProvider provisions single node k3s clusters with the k3d CLI
+type k3dProvider struct{}
+
+func (p *k3dProvider) Name() string {
+	return ProviderK3d
+}
+
+func (p *k3dProvider) Exists(ctx context.Context, name string) (bool, error) {
+	out, err := runProviderCommand(ctx, "k3d", "cluster", "list", "--output", "json")
+	if err != nil {
+		return false, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	var clusters []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(out, &clusters); err != nil {
+		return false, fmt.Errorf("failed to parse k3d clusters: %w", err)
+	}
+
+	for _, cluster := range clusters {
+		if cluster.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *k3dProvider) Create(ctx context.Context, config *types.ClusterConfig) error {
+	args, err := k3dCreateArgs(config)
+	if err != nil {
+		return err
+	}
+	_, err = runProviderCommand(ctx, "k3d", args...)
+	return err
+}
+
+func (p *k3dProvider) Delete(ctx context.Context, name string) error {
+	_, err := runProviderCommand(ctx, "k3d", "cluster", "delete", name)
+	return err
+}
+
+func (p *k3dProvider) LoadImages(ctx context.Context, name, archive string) error {
+	_, err := runProviderCommand(ctx, "k3d", "image", "import", archive, "--cluster", name)
+	return err
+}
+
+func (p *k3dProvider) KubeContext(name string) string {
+	return "k3d-" + name
+}
+
+func (p *k3dProvider) NodeMounts(ctx context.Context, name string) ([]string, error) {
+	return containerMounts(ctx, "k3d-"+name+"-server-0")
+}
+
+// k3dCreateArgs returns the k3d arguments that create a single node cluster
+// with the mounts, port mappings and Kubernetes version of the config
+func k3dCreateArgs(config *types.ClusterConfig) ([]string, error) {
+	args := []string{"cluster", "create", config.Name,
+		"--servers", "1",
+		"--agents", "0",
+		"--wait",
+		"--kubeconfig-update-default",
+		"--kubeconfig-switch-context=false",
+	}
+
+	if config.KubernetesVersion != "" {
+		version, err := patchVersion(config.KubernetesVersion)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--image", fmt.Sprintf("%s:%s-k3s1", k3sImageRepository, version))
+	}
+
+	for _, mount := range nodeMounts(config) {
+		volume := mount.HostPath + ":" + mount.ContainerPath
+		if mount.ReadOnly {
+			volume += ":ro"
+		}
+		args = append(args, "--volume", volume+k3dServerNode)
+	}
+
+	// Map the ports to the server node, where NodePorts listen
+	for _, mapping := range config.PortMappings {
+		args = append(args, "--port", dockerPortSpec(mapping)+k3dServerNode)
+	}
+
+	return args, nil
+}
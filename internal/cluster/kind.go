@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rkoster/deskrun/pkg/types"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha4"
+	"sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+// kindProvider provisions clusters with the kind Go package
+type kindProvider struct {
+	provider *cluster.Provider
+}
+
+func newKindProvider() *kindProvider {
+	// Select the node provider like the kind CLI does with
+	// KIND_EXPERIMENTAL_PROVIDER, rather than kind's own detection which
+	// prefers any docker CLI (e.g. the podman-docker shim)
+	option := cluster.ProviderWithDocker()
+	if containerCLI() == RuntimePodman {
+		option = cluster.ProviderWithPodman()
+	}
+	return &kindProvider{provider: cluster.NewProvider(option)}
+}
+
+func (p *kindProvider) Name() string {
+	return ProviderKind
+}
+
+func (p *kindProvider) Exists(ctx context.Context, name string) (bool, error) {
+	clusters, err := p.provider.List()
+	if err != nil {
+		return false, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	// Check if our cluster is in the list
+	for _, cluster := range clusters {
+		if cluster == name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (p *kindProvider) Create(ctx context.Context, config *types.ClusterConfig) error {
+	// Build kind configuration with nix mounts
+	kindConfig, err := buildKindConfig(config)
+	if err != nil {
+		return err
+	}
+
+	// Create cluster using kind Go package with custom config
+	return p.provider.Create(config.Name,
+		cluster.CreateWithV1Alpha4Config(kindConfig),
+		cluster.CreateWithWaitForReady(0), // Use default wait time
+	)
+}
+
+func (p *kindProvider) Delete(ctx context.Context, name string) error {
+	return p.provider.Delete(name, "")
+}
+
+func (p *kindProvider) KubeContext(name string) string {
+	return fmt.Sprintf("kind-%s", name)
+}
+
+func (p *kindProvider) NodeMounts(ctx context.Context, name string) ([]string, error) {
+	return containerMounts(ctx, kindNodeName(name))
+}
+
+func (p *kindProvider) LoadImages(ctx context.Context, name, archive string) error {
+	nodes, err := p.provider.ListInternalNodes(name)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	// Import the archive like kind load image-archive does
+	for _, node := range nodes {
+		file, err := os.Open(archive)
+		if err != nil {
+			return fmt.Errorf("failed to open image archive: %w", err)
+		}
+		err = nodeutils.LoadImageArchive(node, file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to load images into node %s: %w", node.String(), err)
+		}
+	}
+	return nil
+}
+
+// kindNodeName returns the name of the control plane node container of a kind cluster
+func kindNodeName(name string) string {
+	return fmt.Sprintf("%s-control-plane", name)
+}
+
+// buildKindConfig creates a kind cluster configuration with nix and cache mounts
+// running the configured Kubernetes version
+func buildKindConfig(clusterConfig *types.ClusterConfig) (*v1alpha4.Cluster, error) {
+	image, err := NodeImage(clusterConfig.KubernetesVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &v1alpha4.Cluster{
+		TypeMeta: v1alpha4.TypeMeta{
+			Kind:       "Cluster",
+			APIVersion: "kind.x-k8s.io/v1alpha4",
+		},
+		Name: clusterConfig.Name,
+		// Allow registry mirrors to be configured on the running nodes
+		ContainerdConfigPatches: []string{containerdRegistryConfigPatch},
+	}
+
+	// Add a single node configuration
+	node := v1alpha4.Node{
+		Role:  v1alpha4.ControlPlaneRole,
+		Image: image,
+	}
+
+	// Add nix and cache mounts if available
+	for _, mount := range nodeMounts(clusterConfig) {
+		node.ExtraMounts = append(node.ExtraMounts, v1alpha4.Mount{
+			HostPath:      mount.HostPath,
+			ContainerPath: mount.ContainerPath,
+			Readonly:      mount.ReadOnly,
+		})
+	}
+
+	// Expose node ports on the host, only on localhost unless configured otherwise
+	for _, mapping := range clusterConfig.PortMappings {
+		listenAddress := mapping.ListenAddress
+		if listenAddress == "" {
+			listenAddress = defaultPortListenAddress
+		}
+		protocol := v1alpha4.PortMappingProtocolTCP
+		if strings.EqualFold(mapping.Protocol, string(v1alpha4.PortMappingProtocolUDP)) {
+			protocol = v1alpha4.PortMappingProtocolUDP
+		}
+		node.ExtraPortMappings = append(node.ExtraPortMappings, v1alpha4.PortMapping{
+			ContainerPort: mapping.ContainerPort,
+			HostPort:      mapping.HostPort,
+			ListenAddress: listenAddress,
+			Protocol:      protocol,
+		})
+	}
+
+	config.Nodes = []v1alpha4.Node{node}
+	return config, nil
+}
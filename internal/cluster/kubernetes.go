@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+)
+
+// nodeImageRepository is the repository of the kind node images
+const nodeImageRepository = "kindest/node"
+
+// nodeImages are the node images published with the kind release deskrun is
+// built with, by Kubernetes minor version. Other minor versions are not
+// supported by this kind release.
+var nodeImages = map[string]string{
+	"v1.34": defaults.Image,
+	"v1.33": nodeImageRepository + ":v1.33.4",
+	"v1.32": nodeImageRepository + ":v1.32.8",
+	"v1.31": nodeImageRepository + ":v1.31.12",
+}
+
+// SupportedKubernetesVersions returns the supported Kubernetes minor versions,
+// newest first
+func SupportedKubernetesVersions() []string {
+	versions := make([]string, 0, len(nodeImages))
+	for version := range nodeImages {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return minorOf(versions[i]) > minorOf(versions[j])
+	})
+	return versions
+}
+
+// NormalizeKubernetesVersion validates a Kubernetes version like 1.33, v1.33
+// or v1.33.2 and returns it with a v prefix
+func NormalizeKubernetesVersion(version string) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", fmt.Errorf("invalid Kubernetes version '%s', expected a version like 1.33 or v1.33.4", version)
+	}
+	for _, part := range parts {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return "", fmt.Errorf("invalid Kubernetes version '%s', expected a version like 1.33 or v1.33.4", version)
+		}
+	}
+
+	normalized := "v" + strings.Join(parts, ".")
+	minor := "v" + parts[0] + "." + parts[1]
+	if _, ok := nodeImages[minor]; !ok {
+		return "", fmt.Errorf("kubernetes %s is not supported by this deskrun version, supported versions: %s",
+			normalized, strings.Join(SupportedKubernetesVersions(), ", "))
+	}
+	return normalized, nil
+}
+
+// NodeImage returns the kind node image of a Kubernetes version. Minor
+// versions map to the image published with kind, patch versions to the
+// kindest/node image of that release. An empty version returns kind's
+// default node image.
+func NodeImage(version string) (string, error) {
+	if version == "" {
+		return defaults.Image, nil
+	}
+
+	normalized, err := NormalizeKubernetesVersion(version)
+	if err != nil {
+		return "", err
+	}
+	if image, ok := nodeImages[normalized]; ok {
+		return image, nil
+	}
+	return nodeImageRepository + ":" + normalized, nil
+}
+
+// patchVersion returns the vX.Y.Z release of a Kubernetes version, using the
+// release of the kind node image for minor versions
+func patchVersion(version string) (string, error) {
+	normalized, err := NormalizeKubernetesVersion(version)
+	if err != nil {
+		return "", err
+	}
+	if strings.Count(normalized, ".") == 2 {
+		return normalized, nil
+	}
+
+	image, _, _ := strings.Cut(nodeImages[normalized], "@")
+	_, tag, _ := strings.Cut(image, ":")
+	return tag, nil
+}
+
+// minorOf returns the minor number of a normalized vX.Y version
+func minorOf(version string) int {
+	_, minor, _ := strings.Cut(version, ".")
+	n, _ := strconv.Atoi(minor)
+	return n
+}
+
+// KubernetesVersion returns the Kubernetes version of the cluster's control
+// plane node, taken from the tag of its node image for kind clusters. For
+// other clusters, the API server is asked.
+func (m *Manager) KubernetesVersion(ctx context.Context) (string, error) {
+	if !m.IsKind() {
+		return m.ServerVersion(ctx)
+	}
+
+	nodeName := kindNodeName(m.config.Name)
+	out, err := exec.CommandContext(ctx, containerCLI(), "inspect", "--format", "{{.Config.Image}}", nodeName).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect node %s: %w", nodeName, err)
+	}
+
+	image, _, _ := strings.Cut(strings.TrimSpace(string(out)), "@")
+	_, tag, found := strings.Cut(image, ":")
+	if !found {
+		return "", fmt.Errorf("node image %s has no version tag", image)
+	}
+	return tag, nil
+}
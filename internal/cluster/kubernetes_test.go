@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/kind/pkg/apis/config/defaults"
+)
+
+func TestNodeImage(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+		wantErr string
+	}{
+		{version: "", want: defaults.Image},
+		{version: "1.34", want: defaults.Image},
+		{version: "v1.33", want: "kindest/node:v1.33.4"},
+		{version: "v1.32.2", want: "kindest/node:v1.32.2"},
+		{version: "1.31.0", want: "kindest/node:v1.31.0"},
+		{version: "v1.20", wantErr: "not supported"},
+		{version: "1", wantErr: "invalid Kubernetes version"},
+		{version: "v1.33.x", wantErr: "invalid Kubernetes version"},
+		{version: "latest", wantErr: "invalid Kubernetes version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := NodeImage(tt.version)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NodeImage() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NodeImage() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NodeImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupportedKubernetesVersions(t *testing.T) {
+	want := []string{"v1.34", "v1.33", "v1.32", "v1.31"}
+	if got := SupportedKubernetesVersions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SupportedKubernetesVersions() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+// minikubeProvider provisions clusters with the minikube CLI and its docker or
+// podman driver, one minikube profile per cluster
+type minikubeProvider struct{}
+
+func (p *minikubeProvider) Name() string {
+	return ProviderMinikube
+}
+
+func (p *minikubeProvider) Exists(ctx context.Context, name string) (bool, error) {
+	out, err := runProviderCommand(ctx, "minikube", "profile", "list", "--output", "json")
+	if err != nil {
+		return false, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	var profiles struct {
+		Valid   []struct{ Name string } `json:"valid"`
+		Invalid []struct{ Name string } `json:"invalid"`
+	}
+	if err := json.Unmarshal(out, &profiles); err != nil {
+		return false, fmt.Errorf("failed to parse minikube profiles: %w", err)
+	}
+
+	for _, profile := range append(profiles.Valid, profiles.Invalid...) {
+		if profile.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *minikubeProvider) Create(ctx context.Context, config *types.ClusterConfig) error {
+	args, err := minikubeStartArgs(config, containerRuntime())
+	if err != nil {
+		return err
+	}
+	_, err = runProviderCommand(ctx, "minikube", args...)
+	return err
+}
+
+func (p *minikubeProvider) Delete(ctx context.Context, name string) error {
+	_, err := runProviderCommand(ctx, "minikube", "delete", "--profile", name)
+	return err
+}
+
+func (p *minikubeProvider) LoadImages(ctx context.Context, name, archive string) error {
+	_, err := runProviderCommand(ctx, "minikube", "image", "load", archive, "--profile", name)
+	return err
+}
+
+func (p *minikubeProvider) KubeContext(name string) string {
+	return name
+}
+
+func (p *minikubeProvider) NodeMounts(ctx context.Context, name string) ([]string, error) {
+	return containerMounts(ctx, name)
+}
+
+// minikubeStartArgs returns the minikube arguments that start a cluster with
+// the mounts, port mappings and Kubernetes version of the config. minikube
+// supports a single mount, so only the first mount (the Nix store if
+// available) is mounted.
+func minikubeStartArgs(config *types.ClusterConfig, runtime ContainerRuntime) ([]string, error) {
+	args := []string{"start",
+		"--profile", config.Name,
+		"--driver", runtime.Name,
+		"--container-runtime", "containerd",
+		"--keep-context",
+	}
+	if runtime.Rootless {
+		args = append(args, "--rootless")
+	}
+
+	if config.KubernetesVersion != "" {
+		version, err := patchVersion(config.KubernetesVersion)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--kubernetes-version", version)
+	}
+
+	mounts := nodeMounts(config)
+	if len(mounts) > 0 {
+		args = append(args, "--mount", "--mount-string", mounts[0].HostPath+":"+mounts[0].ContainerPath)
+	}
+	for _, mount := range mounts[min(len(mounts), 1):] {
+		slog.Warn("minikube supports a single mount, not mounting into the cluster", "path", mount.HostPath)
+	}
+
+	for _, mapping := range config.PortMappings {
+		args = append(args, "--ports", dockerPortSpec(mapping))
+	}
+
+	return args, nil
+}
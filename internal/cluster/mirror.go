@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+const (
+	// mirrorImage is the registry image run as pull-through cache
+	mirrorImage = "registry:2"
+	// mirrorPort is the port the pull-through caches listen on
+	mirrorPort = 5000
+	// kindNetwork is the docker network kind nodes are attached to
+	kindNetwork = "kind"
+	// containerdCertsDir is the containerd registry host config directory of the nodes
+	containerdCertsDir = "/etc/containerd/certs.d"
+	// dockerHubRegistry is the registry name of images without a registry host
+	dockerHubRegistry = "docker.io"
+)
+
+// containerdRegistryConfigPatch makes containerd read registry mirrors from
+// per-registry hosts.toml files, so mirrors can be added to running nodes
+const containerdRegistryConfigPatch = `[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "` + containerdCertsDir + `"
+`
+
+// MirrorContainerName returns the name of the docker container running the
+// pull-through cache of a registry, e.g. deskrun-mirror-docker-io
+func MirrorContainerName(registry string) string {
+	name := strings.NewReplacer(".", "-", ":", "-").Replace(registry)
+	return "deskrun-mirror-" + name
+}
+
+// mirrorRemoteURL returns the upstream URL a registry's pull-through cache proxies
+func mirrorRemoteURL(registry string) string {
+	if registry == dockerHubRegistry {
+		return "https://registry-1.docker.io"
+	}
+	return "https://" + registry
+}
+
+// mirrorHostsTOML returns the containerd hosts.toml that pulls images of a
+// registry through its pull-through cache, falling back to the registry itself
+func mirrorHostsTOML(registry string) string {
+	return fmt.Sprintf(`server = %q
+
+[host."http://%s:%d"]
+  capabilities = ["pull", "resolve"]
+`, mirrorRemoteURL(registry), MirrorContainerName(registry), mirrorPort)
+}
+
+// EnsureMirror starts the pull-through cache container of a registry on the
+// kind network, creating it (and a docker volume for its data) if needed
+func EnsureMirror(ctx context.Context, registry string) error {
+	name := MirrorContainerName(registry)
+
+	out, err := exec.CommandContext(ctx, containerCLI(), "inspect", "--format", "{{.State.Running}}", name).Output()
+	if err == nil {
+		if strings.TrimSpace(string(out)) == "true" {
+			return nil
+		}
+		if out, err := exec.CommandContext(ctx, containerCLI(), "start", name).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to start mirror %s: %w: %s", name, err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	out, err = exec.CommandContext(ctx, containerCLI(), "run", "--detach",
+		"--name", name,
+		"--restart", "always",
+		"--network", kindNetwork,
+		"--volume", name+":/var/lib/registry",
+		"--env", "REGISTRY_PROXY_REMOTEURL="+mirrorRemoteURL(registry),
+		mirrorImage,
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to run mirror %s: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RemoveMirror removes the pull-through cache container of a registry. The
+// docker volume with the cached images is kept.
+func RemoveMirror(ctx context.Context, registry string) error {
+	name := MirrorContainerName(registry)
+	out, err := exec.CommandContext(ctx, containerCLI(), "rm", "--force", name).CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "No such container") {
+		return fmt.Errorf("failed to remove mirror %s: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ConfigureMirrors points containerd on all nodes of the cluster at the
+// pull-through caches of the enabled registries and removes the mirror
+// configuration of the disabled ones. containerd picks up the changes without
+// a restart. Only kind clusters are configured.
+func (m *Manager) ConfigureMirrors(ctx context.Context, enabled, disabled []string) error {
+	kind, ok := m.provider.(*kindProvider)
+	if !ok || m.IsExternal() {
+		return nil
+	}
+
+	nodes, err := kind.provider.ListNodes(m.config.Name)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	for _, node := range nodes {
+		for _, registry := range enabled {
+			dir := path.Join(containerdCertsDir, registry)
+			cmd := node.CommandContext(ctx, "sh", "-c", `mkdir -p "$1" && cat > "$1/hosts.toml"`, "sh", dir)
+			cmd.SetStdin(bytes.NewBufferString(mirrorHostsTOML(registry)))
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("failed to configure mirror of %s on node %s: %w", registry, node.String(), err)
+			}
+		}
+		for _, registry := range disabled {
+			dir := path.Join(containerdCertsDir, registry)
+			if err := node.CommandContext(ctx, "rm", "-rf", dir).Run(); err != nil {
+				return fmt.Errorf("failed to remove mirror of %s on node %s: %w", registry, node.String(), err)
+			}
+		}
+	}
+
+	return nil
+}
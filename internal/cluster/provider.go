@@ -0,0 +1,194 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+const (
+	// ProviderKind provisions clusters with kind (the default)
+	ProviderKind = "kind"
+	// ProviderK3d provisions clusters with k3d
+	ProviderK3d = "k3d"
+	// ProviderMinikube provisions clusters with minikube and its docker driver
+	ProviderMinikube = "minikube"
+)
+
+// defaultPortListenAddress is the host address port mappings listen on by default
+const defaultPortListenAddress = "127.0.0.1"
+
+// Provider provisions the local clusters deskrun runs runners on. All
+// providers run the cluster node as a Docker or Podman container on the host.
+type Provider interface {
+	// Name returns the name of the provider, e.g. kind
+	Name() string
+	// Exists checks if the cluster exists
+	Exists(ctx context.Context, name string) (bool, error)
+	// Create creates the cluster with the mounts, port mappings and
+	// Kubernetes version of the config
+	Create(ctx context.Context, config *types.ClusterConfig) error
+	// Delete deletes the cluster
+	Delete(ctx context.Context, name string) error
+	// KubeContext returns the kubeconfig context of the cluster
+	KubeContext(name string) string
+	// NodeMounts returns the container paths mounted into the cluster node
+	NodeMounts(ctx context.Context, name string) ([]string, error)
+	// LoadImages loads the images of a docker image archive into the
+	// container runtime of the cluster node
+	LoadImages(ctx context.Context, name, archive string) error
+}
+
+// Providers returns the names of the supported cluster providers
+func Providers() []string {
+	return []string{ProviderKind, ProviderK3d, ProviderMinikube}
+}
+
+// NewProvider returns the cluster provider of the given name; an empty name
+// selects kind. Unknown providers fail on every operation.
+func NewProvider(name string) Provider {
+	switch name {
+	case "", ProviderKind:
+		return newKindProvider()
+	case ProviderK3d:
+		return &k3dProvider{}
+	case ProviderMinikube:
+		return &minikubeProvider{}
+	default:
+		return unknownProvider(name)
+	}
+}
+
+// unknownProvider is a provider name that is not supported
+type unknownProvider string
+
+func (p unknownProvider) err() error {
+	return fmt.Errorf("unknown cluster provider '%s', supported providers: %s", string(p), strings.Join(Providers(), ", "))
+}
+
+func (p unknownProvider) Name() string { return string(p) }
+
+func (p unknownProvider) Exists(ctx context.Context, name string) (bool, error) {
+	return false, p.err()
+}
+
+func (p unknownProvider) Create(ctx context.Context, config *types.ClusterConfig) error {
+	return p.err()
+}
+
+func (p unknownProvider) Delete(ctx context.Context, name string) error { return p.err() }
+
+func (p unknownProvider) KubeContext(name string) string { return "" }
+
+func (p unknownProvider) NodeMounts(ctx context.Context, name string) ([]string, error) {
+	return nil, p.err()
+}
+
+func (p unknownProvider) LoadImages(ctx context.Context, name, archive string) error {
+	return p.err()
+}
+
+// nodeMount is a host path mounted into the cluster node
+type nodeMount struct {
+	HostPath      string
+	ContainerPath string
+	ReadOnly      bool
+}
+
+// nodeMounts returns the host paths to mount into the cluster node: the Nix
+// store (read-only), the directory of the Nix daemon socket, the deskrun
+// cache, the docker socket and the Windows drives of WSL, as far as they are
+// configured
+func nodeMounts(config *types.ClusterConfig) []nodeMount {
+	var mounts []nodeMount
+
+	if config.NixStore != nil {
+		mounts = append(mounts, nodeMount{
+			HostPath:      config.NixStore.HostPath,
+			ContainerPath: config.NixStore.ContainerPath,
+			ReadOnly:      true,
+		})
+	}
+
+	if config.NixSocket != nil {
+		// Mount the directory of the socket, so the socket survives daemon restarts
+		mounts = append(mounts, nodeMount{
+			HostPath:      filepath.Dir(config.NixSocket.HostPath),
+			ContainerPath: filepath.Dir(config.NixSocket.ContainerPath),
+		})
+	}
+
+	if config.DeskrunCache != nil {
+		mounts = append(mounts, nodeMount{
+			HostPath:      config.DeskrunCache.HostPath,
+			ContainerPath: config.DeskrunCache.ContainerPath,
+		})
+	}
+
+	if config.DockerSocket != nil {
+		mounts = append(mounts, nodeMount{
+			HostPath:      config.DockerSocket.HostPath,
+			ContainerPath: config.DockerSocket.ContainerPath,
+		})
+	}
+
+	for _, drive := range config.WindowsDrives {
+		mounts = append(mounts, nodeMount{
+			HostPath:      drive.HostPath,
+			ContainerPath: drive.ContainerPath,
+		})
+	}
+
+	return mounts
+}
+
+// dockerPortSpec formats a port mapping as docker publish spec,
+// address:hostPort:containerPort/protocol
+func dockerPortSpec(mapping types.PortMapping) string {
+	listenAddress := mapping.ListenAddress
+	if listenAddress == "" {
+		listenAddress = defaultPortListenAddress
+	}
+	protocol := strings.ToLower(mapping.Protocol)
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	return fmt.Sprintf("%s:%d:%d/%s", listenAddress, mapping.HostPort, mapping.ContainerPort, protocol)
+}
+
+// containerMounts returns the destinations of the mounts of a node container
+func containerMounts(ctx context.Context, container string) ([]string, error) {
+	out, err := exec.CommandContext(ctx, containerCLI(), "inspect", "--format", "{{range .Mounts}}{{println .Destination}}{{end}}", container).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect node %s: %w", container, err)
+	}
+
+	return strings.Fields(string(out)), nil
+}
+
+// runProviderCommand runs a provider CLI and returns its output, with the
+// error output of the CLI in the error
+func runProviderCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	if runtime := containerRuntime(); runtime.Name == RuntimePodman && runtime.Socket != "" {
+		// Point CLIs talking to the Docker API (k3d) at the Podman socket
+		cmd.Env = append(os.Environ(), "DOCKER_HOST=unix://"+runtime.Socket)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		if _, lookErr := exec.LookPath(name); lookErr != nil {
+			return nil, fmt.Errorf("%s is not installed: %w", name, lookErr)
+		}
+		return nil, fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
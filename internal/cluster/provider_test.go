@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+func TestNewProvider(t *testing.T) {
+	for name, want := range map[string]string{
+		"":         ProviderKind,
+		"kind":     ProviderKind,
+		"k3d":      ProviderK3d,
+		"minikube": ProviderMinikube,
+	} {
+		if got := NewProvider(name).Name(); got != want {
+			t.Errorf("NewProvider(%q).Name() = %v, want %v", name, got, want)
+		}
+	}
+
+	_, err := NewProvider("kindest").Exists(t.Context(), "deskrun")
+	if err == nil || !strings.Contains(err.Error(), "unknown cluster provider") {
+		t.Errorf("Exists() of unknown provider error = %v, want unknown cluster provider", err)
+	}
+}
+
+func testClusterConfig() *types.ClusterConfig {
+	return &types.ClusterConfig{
+		Name:              "deskrun",
+		KubernetesVersion: "v1.33",
+		NixStore:          &types.ClusterMount{HostPath: "/nix/store", ContainerPath: "/nix/store"},
+		DeskrunCache:      &types.ClusterMount{HostPath: "/home/me/.cache/deskrun", ContainerPath: "/host-cache/deskrun"},
+		PortMappings:      []types.PortMapping{{HostPort: 8080, ContainerPort: 30080, Protocol: "TCP"}},
+	}
+}
+
+func TestK3dCreateArgs(t *testing.T) {
+	args, err := k3dCreateArgs(testClusterConfig())
+	if err != nil {
+		t.Fatalf("k3dCreateArgs() error = %v", err)
+	}
+
+	want := []string{"cluster", "create", "deskrun",
+		"--servers", "1",
+		"--agents", "0",
+		"--wait",
+		"--kubeconfig-update-default",
+		"--kubeconfig-switch-context=false",
+		"--image", "rancher/k3s:v1.33.4-k3s1",
+		"--volume", "/nix/store:/nix/store:ro@server:0",
+		"--volume", "/home/me/.cache/deskrun:/host-cache/deskrun@server:0",
+		"--port", "127.0.0.1:8080:30080/tcp@server:0",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("k3dCreateArgs() =\n%v\nwant\n%v", args, want)
+	}
+}
+
+func TestMinikubeStartArgs(t *testing.T) {
+	args, err := minikubeStartArgs(testClusterConfig(), ContainerRuntime{Name: RuntimeDocker})
+	if err != nil {
+		t.Fatalf("minikubeStartArgs() error = %v", err)
+	}
+
+	want := []string{"start",
+		"--profile", "deskrun",
+		"--driver", "docker",
+		"--container-runtime", "containerd",
+		"--keep-context",
+		"--kubernetes-version", "v1.33.4",
+		"--mount", "--mount-string", "/nix/store:/nix/store",
+		"--ports", "127.0.0.1:8080:30080/tcp",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("minikubeStartArgs() =\n%v\nwant\n%v", args, want)
+	}
+}
+
+func TestMinikubeStartArgsRootlessPodman(t *testing.T) {
+	args, err := minikubeStartArgs(&types.ClusterConfig{Name: "deskrun"}, ContainerRuntime{Name: RuntimePodman, Rootless: true})
+	if err != nil {
+		t.Fatalf("minikubeStartArgs() error = %v", err)
+	}
+
+	want := []string{"start",
+		"--profile", "deskrun",
+		"--driver", "podman",
+		"--container-runtime", "containerd",
+		"--keep-context",
+		"--rootless",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("minikubeStartArgs() =\n%v\nwant\n%v", args, want)
+	}
+}
@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+const (
+	// RuntimeDocker runs cluster nodes as Docker containers (the default)
+	RuntimeDocker = "docker"
+	// RuntimePodman runs cluster nodes as rootful or rootless Podman containers
+	RuntimePodman = "podman"
+)
+
+// kindProviderEnv is the environment variable kind reads its node provider from
+const kindProviderEnv = "KIND_EXPERIMENTAL_PROVIDER"
+
+// dockerSocketPath is the path of the Docker socket. The socket of the
+// container runtime is mounted at this path into the cluster node, so runners
+// mounting /var/run/docker.sock work with Podman as well.
+const dockerSocketPath = "/var/run/docker.sock"
+
+// rootfulPodmanSocketPath is the API socket of the system Podman service
+const rootfulPodmanSocketPath = "/run/podman/podman.sock"
+
+// ContainerRuntime is the container engine cluster nodes run on
+type ContainerRuntime struct {
+	Name     string // docker or podman
+	Socket   string // host path of the API socket, empty if not found
+	Rootless bool   // true for rootless Podman
+}
+
+// containerRuntime detects the container runtime once per process
+var containerRuntime = sync.OnceValue(DetectContainerRuntime)
+
+// containerCLI returns the CLI of the detected container runtime
+func containerCLI() string {
+	return containerRuntime().Name
+}
+
+// DetectContainerRuntime detects the container runtime cluster nodes run on.
+// KIND_EXPERIMENTAL_PROVIDER selects it explicitly; otherwise Docker is used
+// if its socket exists, then Podman if its rootless or rootful socket exists,
+// then whichever CLI is installed.
+func DetectContainerRuntime() ContainerRuntime {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = filepath.Join("/run/user", strconv.Itoa(os.Getuid()))
+	}
+
+	return detectContainerRuntime(os.Getenv(kindProviderEnv), runtimeDir, fileExists, cliInstalled)
+}
+
+// detectContainerRuntime implements DetectContainerRuntime with the host
+// lookups passed in
+func detectContainerRuntime(providerEnv, runtimeDir string, exists, installed func(string) bool) ContainerRuntime {
+	rootlessSocket := filepath.Join(runtimeDir, "podman", "podman.sock")
+	podman := func() ContainerRuntime {
+		switch {
+		case exists(rootlessSocket):
+			return ContainerRuntime{Name: RuntimePodman, Socket: rootlessSocket, Rootless: true}
+		case exists(rootfulPodmanSocketPath):
+			return ContainerRuntime{Name: RuntimePodman, Socket: rootfulPodmanSocketPath}
+		default:
+			return ContainerRuntime{Name: RuntimePodman}
+		}
+	}
+	docker := func() ContainerRuntime {
+		if exists(dockerSocketPath) {
+			return ContainerRuntime{Name: RuntimeDocker, Socket: dockerSocketPath}
+		}
+		return ContainerRuntime{Name: RuntimeDocker}
+	}
+
+	switch providerEnv {
+	case RuntimePodman:
+		return podman()
+	case RuntimeDocker:
+		return docker()
+	}
+
+	switch {
+	case exists(dockerSocketPath):
+		return docker()
+	case exists(rootlessSocket), exists(rootfulPodmanSocketPath):
+		return podman()
+	case !installed(RuntimeDocker) && installed(RuntimePodman):
+		return podman()
+	default:
+		return docker()
+	}
+}
+
+// DockerSocketGroup returns the group owning the socket of the container
+// runtime, which runner pods of host-docker mode join to use the socket of
+// the node. It is 0 without a socket and for rootless Podman, whose socket is
+// owned by root in the node, and on macOS, where the socket of the host is
+// not the one in the VM, as in WSL with Docker Desktop.
+func DockerSocketGroup() int {
+	runtime := containerRuntime()
+	if runtime.Socket == "" || runtime.Rootless || CurrentHostVM() != nil {
+		return 0
+	}
+	if WSLVersion() > 0 && isDockerDesktopSocket(runtime.Socket) {
+		return 0
+	}
+	info, err := os.Stat(runtime.Socket)
+	if err != nil {
+		return 0
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(stat.Gid)
+	}
+	return 0
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func cliInstalled(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
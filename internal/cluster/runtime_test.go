@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetectContainerRuntime(t *testing.T) {
+	const rootlessSocket = "/run/user/1000/podman/podman.sock"
+
+	tests := []struct {
+		name        string
+		providerEnv string
+		sockets     []string
+		clis        []string
+		want        ContainerRuntime
+	}{
+		{
+			name:    "docker socket",
+			sockets: []string{dockerSocketPath, rootlessSocket},
+			clis:    []string{"docker", "podman"},
+			want:    ContainerRuntime{Name: RuntimeDocker, Socket: dockerSocketPath},
+		},
+		{
+			name:    "rootless podman",
+			sockets: []string{rootlessSocket, rootfulPodmanSocketPath},
+			clis:    []string{"podman"},
+			want:    ContainerRuntime{Name: RuntimePodman, Socket: rootlessSocket, Rootless: true},
+		},
+		{
+			name:    "rootful podman",
+			sockets: []string{rootfulPodmanSocketPath},
+			want:    ContainerRuntime{Name: RuntimePodman, Socket: rootfulPodmanSocketPath},
+		},
+		{
+			name: "podman CLI only",
+			clis: []string{"podman"},
+			want: ContainerRuntime{Name: RuntimePodman},
+		},
+		{
+			name: "nothing installed",
+			want: ContainerRuntime{Name: RuntimeDocker},
+		},
+		{
+			name:        "kind provider selects podman",
+			providerEnv: "podman",
+			sockets:     []string{dockerSocketPath, rootlessSocket},
+			want:        ContainerRuntime{Name: RuntimePodman, Socket: rootlessSocket, Rootless: true},
+		},
+		{
+			name:        "kind provider selects docker",
+			providerEnv: "docker",
+			sockets:     []string{rootlessSocket},
+			want:        ContainerRuntime{Name: RuntimeDocker},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists := func(path string) bool { return slices.Contains(tt.sockets, path) }
+			installed := func(name string) bool { return slices.Contains(tt.clis, name) }
+
+			got := detectContainerRuntime(tt.providerEnv, "/run/user/1000", exists, installed)
+			if got != tt.want {
+				t.Errorf("detectContainerRuntime() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+const (
+	// VMColima is the Lima VM of colima
+	VMColima = "colima"
+	// VMDockerDesktop is the VM of Docker Desktop
+	VMDockerDesktop = "docker-desktop"
+	// VMPodmanMachine is the VM of podman machine
+	VMPodmanMachine = "podman-machine"
+)
+
+// HostVM is the Linux VM the container runtime runs cluster nodes in on
+// macOS. Host paths only reach the nodes through the directories the VM
+// shares, and the sockets of the runtime live in the VM.
+type HostVM struct {
+	Name        string   // colima, docker-desktop, podman-machine or the name the runtime reports
+	SharedPaths []string // host directories the VM shares with its containers
+}
+
+// hostVM detects the VM of the container runtime once per process
+var hostVM = sync.OnceValue(func() *HostVM {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return DetectHostVM(ctx)
+})
+
+// CurrentHostVM returns the VM cluster nodes run in, or nil when they run on
+// the host itself, as on Linux
+func CurrentHostVM() *HostVM {
+	return hostVM()
+}
+
+// DetectHostVM detects the VM the container runtime runs containers in. It
+// returns nil on Linux.
+func DetectHostVM(ctx context.Context) *HostVM {
+	if runtime.GOOS != "darwin" {
+		return nil
+	}
+
+	home, _ := os.UserHomeDir()
+	if containerCLI() == RuntimePodman {
+		return detectHostVM(RuntimePodman, "", "", home)
+	}
+	out, err := exec.CommandContext(ctx, containerCLI(), "info", "--format", "{{.Name}}|{{.OperatingSystem}}").Output()
+	if err != nil {
+		return detectHostVM(containerCLI(), "", "", home)
+	}
+	name, operatingSystem, _ := strings.Cut(strings.TrimSpace(string(out)), "|")
+	return detectHostVM(containerCLI(), name, operatingSystem, home)
+}
+
+// detectHostVM identifies the VM from the node name and operating system
+// docker info reports, with the directories it shares by default
+func detectHostVM(cli, name, operatingSystem, home string) *HostVM {
+	switch {
+	case cli == RuntimePodman:
+		return &HostVM{Name: VMPodmanMachine, SharedPaths: []string{home, "/private", "/var/folders"}}
+	case operatingSystem == "Docker Desktop":
+		return &HostVM{Name: VMDockerDesktop, SharedPaths: []string{"/Users", "/Volumes", "/private", "/tmp", "/var/folders"}}
+	case strings.HasPrefix(name, VMColima):
+		return &HostVM{Name: VMColima, SharedPaths: []string{home, "/tmp/colima"}}
+	case name != "":
+		return &HostVM{Name: name, SharedPaths: []string{home}}
+	default:
+		return &HostVM{Name: "unknown", SharedPaths: []string{home}}
+	}
+}
+
+// Shares returns true if the VM shares a host path with its containers
+func (vm *HostVM) Shares(path string) bool {
+	for _, shared := range vm.SharedPaths {
+		if shared != "" && (path == shared || strings.HasPrefix(path, shared+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ModeWarning explains why a container mode doesn't work in the VM, or
+// returns an empty string for the modes that do
+func (vm *HostVM) ModeWarning(mode types.ContainerMode) string {
+	if mode == types.ContainerModeHostDocker {
+		return fmt.Sprintf("%s mode is not supported on macOS (%s): the Docker socket in the VM is not owned by a group runner pods can join; use dind mode instead", mode, vm.Name)
+	}
+	return ""
+}
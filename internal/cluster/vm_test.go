@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+func TestDetectHostVM(t *testing.T) {
+	tests := []struct {
+		name            string
+		cli             string
+		nodeName        string
+		operatingSystem string
+		want            string
+	}{
+		{name: "docker desktop", cli: RuntimeDocker, nodeName: "docker-desktop", operatingSystem: "Docker Desktop", want: VMDockerDesktop},
+		{name: "colima", cli: RuntimeDocker, nodeName: "colima", operatingSystem: "Ubuntu 24.04 LTS", want: VMColima},
+		{name: "colima profile", cli: RuntimeDocker, nodeName: "colima-arm", operatingSystem: "Ubuntu 24.04 LTS", want: VMColima},
+		{name: "podman machine", cli: RuntimePodman, want: VMPodmanMachine},
+		{name: "other VM", cli: RuntimeDocker, nodeName: "orbstack", operatingSystem: "OrbStack", want: "orbstack"},
+		{name: "docker info failed", cli: RuntimeDocker, want: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := detectHostVM(tt.cli, tt.nodeName, tt.operatingSystem, "/Users/dev")
+			if vm.Name != tt.want {
+				t.Errorf("detectHostVM() = %q, want %q", vm.Name, tt.want)
+			}
+			if !vm.Shares("/Users/dev/.cache/deskrun") {
+				t.Errorf("%s does not share the home directory", vm.Name)
+			}
+		})
+	}
+}
+
+func TestHostVMShares(t *testing.T) {
+	vm := detectHostVM(RuntimeDocker, "colima", "Ubuntu 24.04 LTS", "/Users/dev")
+
+	for path, want := range map[string]bool{
+		"/Users/dev":                true,
+		"/Users/dev/.cache/deskrun": true,
+		"/Users/devops":             false,
+		"/nix/store":                false,
+		"/tmp/colima/cache":         true,
+	} {
+		if got := vm.Shares(path); got != want {
+			t.Errorf("Shares(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestHostVMModeWarning(t *testing.T) {
+	vm := &HostVM{Name: VMDockerDesktop}
+
+	if warning := vm.ModeWarning(types.ContainerModeHostDocker); !strings.Contains(warning, "not supported on macOS (docker-desktop)") {
+		t.Errorf("ModeWarning(host-docker) = %q, want unsupported", warning)
+	}
+	for _, mode := range []types.ContainerMode{types.ContainerModeKubernetes, types.ContainerModeDinD, types.ContainerModePrivileged} {
+		if warning := vm.ModeWarning(mode); warning != "" {
+			t.Errorf("ModeWarning(%s) = %q, want none", mode, warning)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+const (
+	// wslOSReleasePath holds the kernel release, which names the WSL kernel
+	wslOSReleasePath = "/proc/sys/kernel/osrelease"
+	// wslConfPath is the configuration of the WSL distribution
+	wslConfPath = "/etc/wsl.conf"
+	// defaultWSLAutomountRoot is where WSL mounts the Windows drives
+	defaultWSLAutomountRoot = "/mnt/"
+	// dockerDesktopWSLDir holds the sockets Docker Desktop shares with the
+	// WSL distributions it integrates with
+	dockerDesktopWSLDir = "/mnt/wsl/docker-desktop"
+)
+
+// wslVersion detects the WSL version once per process
+var wslVersion = sync.OnceValue(func() int {
+	release, err := os.ReadFile(wslOSReleasePath)
+	if err != nil {
+		return 0
+	}
+	return detectWSLVersion(string(release))
+})
+
+// WSLVersion returns 2 when deskrun runs in a WSL 2 distribution, 1 in a
+// WSL 1 distribution and 0 outside of WSL
+func WSLVersion() int {
+	return wslVersion()
+}
+
+// detectWSLVersion returns the WSL version of a kernel release: WSL 2 runs a
+// Microsoft built Linux kernel, WSL 1 emulates one with a Microsoft suffix
+func detectWSLVersion(release string) int {
+	release = strings.TrimSpace(release)
+	switch {
+	case strings.Contains(release, "microsoft-standard"), strings.Contains(release, "WSL2"):
+		return 2
+	case strings.Contains(release, "Microsoft"):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ValidateHost checks that the host can run local clusters: deskrun must run
+// inside a WSL 2 distribution on Windows, as kind needs a Linux kernel
+func ValidateHost() error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("deskrun can't create clusters on Windows itself; run it inside a WSL 2 distribution")
+	}
+	if WSLVersion() == 1 {
+		return fmt.Errorf("kind does not run on WSL 1; convert the distribution with 'wsl --set-version <distribution> 2'")
+	}
+	return nil
+}
+
+// IsWindowsPath returns true for paths starting with a Windows drive letter,
+// like C:\cache or C:/cache
+func IsWindowsPath(path string) bool {
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/') &&
+		(('a' <= path[0] && path[0] <= 'z') || ('A' <= path[0] && path[0] <= 'Z'))
+}
+
+// TranslateWindowsPath translates a Windows path to the path WSL mounts it
+// at, e.g. C:\cache to /mnt/c/cache. Other paths are returned as is.
+func TranslateWindowsPath(path string) (string, error) {
+	if !IsWindowsPath(path) {
+		return path, nil
+	}
+	if WSLVersion() == 0 {
+		return "", fmt.Errorf("%s is a Windows path, which deskrun only translates in WSL", path)
+	}
+	return translateWindowsPath(path, wslAutomountRoot()), nil
+}
+
+// translateWindowsPath translates a Windows path to a path below the WSL
+// automount root
+func translateWindowsPath(path, root string) string {
+	drive := strings.ToLower(path[:1])
+	rest := strings.Trim(strings.ReplaceAll(path[2:], `\`, "/"), "/")
+	return filepath.Join(root, drive, rest)
+}
+
+// wslAutomountRoot returns where WSL mounts the Windows drives
+func wslAutomountRoot() string {
+	conf, err := os.ReadFile(wslConfPath)
+	if err != nil {
+		return defaultWSLAutomountRoot
+	}
+	return parseAutomountRoot(string(conf))
+}
+
+// parseAutomountRoot returns the root setting of the automount section of
+// wsl.conf, or the default root
+func parseAutomountRoot(conf string) string {
+	section := ""
+	scanner := bufio.NewScanner(strings.NewReader(conf))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.Trim(line, "[]"))
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section != "automount" || strings.TrimSpace(key) != "root" {
+			continue
+		}
+		if root := strings.Trim(strings.TrimSpace(value), `"`); root != "" {
+			return strings.TrimSuffix(root, "/") + "/"
+		}
+	}
+	return defaultWSLAutomountRoot
+}
+
+// DetectWindowsDrives returns the mounts of the Windows drives the mounts and
+// cache paths of installations use, so their directories exist in the
+// cluster node at the same paths as in WSL
+func DetectWindowsDrives(installations map[string]*types.RunnerInstallation) []types.ClusterMount {
+	if WSLVersion() == 0 {
+		return nil
+	}
+
+	var sources []string
+	for _, installation := range installations {
+		for _, mount := range installation.Mounts {
+			sources = append(sources, mount.Source)
+		}
+		for _, cachePath := range installation.CachePaths {
+			sources = append(sources, cachePath.Source)
+		}
+	}
+	return windowsDriveMounts(sources, wslAutomountRoot())
+}
+
+// windowsDriveMounts returns the mounts of the drive directories below the
+// WSL automount root that paths are in, sorted by drive
+func windowsDriveMounts(paths []string, root string) []types.ClusterMount {
+	drives := map[string]bool{}
+	for _, path := range paths {
+		rest, ok := strings.CutPrefix(path, root)
+		if !ok {
+			continue
+		}
+		drive, _, _ := strings.Cut(rest, "/")
+		if len(drive) == 1 {
+			drives[drive] = true
+		}
+	}
+
+	var mounts []types.ClusterMount
+	for drive := 'a'; drive <= 'z'; drive++ {
+		if drives[string(drive)] {
+			path := root + string(drive)
+			mounts = append(mounts, types.ClusterMount{HostPath: path, ContainerPath: path})
+		}
+	}
+	return mounts
+}
+
+// isDockerDesktopSocket returns true for the socket Docker Desktop shares with
+// WSL distributions, whose group in WSL is not the group of the socket of the
+// Docker Desktop VM
+func isDockerDesktopSocket(socket string) bool {
+	resolved, err := filepath.EvalSymlinks(socket)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(resolved, dockerDesktopWSLDir+"/")
+}
@@ -0,0 +1,78 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+func TestDetectWSLVersion(t *testing.T) {
+	for release, want := range map[string]int{
+		"5.15.167.4-microsoft-standard-WSL2\n": 2,
+		"4.4.0-19041-Microsoft":                1,
+		"6.8.0-45-generic":                     0,
+	} {
+		if got := detectWSLVersion(release); got != want {
+			t.Errorf("detectWSLVersion(%q) = %d, want %d", release, got, want)
+		}
+	}
+}
+
+func TestTranslateWindowsPath(t *testing.T) {
+	for path, want := range map[string]bool{
+		`C:\cache`:        true,
+		"d:/cache":        true,
+		"/var/lib/docker": false,
+		"C:":              false,
+		"cache:/target":   false,
+	} {
+		if got := IsWindowsPath(path); got != want {
+			t.Errorf("IsWindowsPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	for path, want := range map[string]string{
+		`C:\Users\dev\cache`: "/mnt/c/Users/dev/cache",
+		"D:/cache/":          "/mnt/d/cache",
+		`E:\`:                "/mnt/e",
+	} {
+		if got := translateWindowsPath(path, "/mnt/"); got != want {
+			t.Errorf("translateWindowsPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if got := translateWindowsPath(`C:\cache`, "/"); got != "/c/cache" {
+		t.Errorf("translateWindowsPath() with root / = %q, want /c/cache", got)
+	}
+}
+
+func TestParseAutomountRoot(t *testing.T) {
+	for conf, want := range map[string]string{
+		"":                       "/mnt/",
+		"[boot]\nsystemd=true\n": "/mnt/",
+		"[automount]\nenabled = true\nroot = /\n":          "/",
+		"[automount]\nroot = \"/windir\"\n[boot]\nroot=/x": "/windir/",
+		"[network]\nroot = /net\n":                         "/mnt/",
+	} {
+		if got := parseAutomountRoot(conf); got != want {
+			t.Errorf("parseAutomountRoot(%q) = %q, want %q", conf, got, want)
+		}
+	}
+}
+
+func TestWindowsDriveMounts(t *testing.T) {
+	got := windowsDriveMounts([]string{
+		"/mnt/d/cache",
+		"/mnt/c/Users/dev/cache",
+		"/mnt/c/other",
+		"/mnt/wsl/shared",
+		"/var/lib/docker",
+	}, "/mnt/")
+	want := []types.ClusterMount{
+		{HostPath: "/mnt/c", ContainerPath: "/mnt/c"},
+		{HostPath: "/mnt/d", ContainerPath: "/mnt/d"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("windowsDriveMounts() = %v, want %v", got, want)
+	}
+}
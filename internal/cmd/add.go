@@ -1,24 +1,109 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/rkoster/deskrun/internal/cluster"
 	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/pkg/templates"
 	"github.com/rkoster/deskrun/pkg/types"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 var (
-	addRepository string
-	addMode       string
-	addMinRunners int
-	addMaxRunners int
-	addInstances  int
-	addAuthType   string
-	addAuthValue  string
-	addCachePaths []string // Deprecated: kept for backward compatibility
-	addMounts     []string
+	addRepository   string
+	addMode         string
+	addMinRunners   int
+	addMaxRunners   int
+	addSchedule     []string
+	addInstances    int
+	addAuthType     string
+	addAuthValue    string
+	addCachePaths   []string // Deprecated: kept for backward compatibility
+	addMounts       []string
+	addMaxSizes     []string
+	addCacheVolumes []string
+
+	addCacheType         string
+	addCacheStorageClass string
+	addCacheSize         string
+
+	// addAuthValueFile and addAuthValueEnv keep the auth value out of shell history
+	addAuthValueFile string
+	addAuthValueEnv  string
+
+	addRunnerGroup string
+	addImage       string
+
+	addCPURequest    string
+	addCPULimit      string
+	addMemoryRequest string
+	addMemoryLimit   string
+
+	addGitHubAppID             int64
+	addGitHubAppInstallationID int64
+
+	addDinDImage         string
+	addDinDCPURequest    string
+	addDinDCPULimit      string
+	addDinDMemoryRequest string
+	addDinDMemoryLimit   string
+	addDinDMTU           int
+	addDinDMirrors       []string
+	addDinDInsecure      []string
+
+	addListenerCPURequest    string
+	addListenerCPULimit      string
+	addListenerMemoryRequest string
+	addListenerMemoryLimit   string
+
+	addEphemeralStorageRequest string
+	addEphemeralStorageLimit   string
+
+	addCluster   string
+	addNamespace string
+
+	addHTTPProxy  string
+	addHTTPSProxy string
+	addNoProxy    string
+
+	addNodeSelector []string
+	addTolerations  []string
+	addRuntimeClass string
+	addArch         string
+
+	addEnv []string
+
+	addMountSecrets    []string
+	addMountConfigMaps []string
+
+	addOverlays []string
+
+	addLabels      []string
+	addAnnotations []string
+
+	addNetworkPolicy string
+	addAllowEgress   []string
+
+	addPodSecurity string
+
+	addValueFiles []string
+	addSetValues  []string
+
+	addSkipValidation bool
 )
 
 var addCmd = &cobra.Command{
@@ -29,6 +114,12 @@ var addCmd = &cobra.Command{
 This is a config-only operation. After adding a runner, you need to run 'deskrun up'
 to deploy the changes to the cluster.
 
+Personal access tokens are validated against the GitHub API before the
+installation is saved: the token must be valid and have admin access to the
+repository or organization. GitHub App credentials must get an installation
+token for it. Use --skip-validation to store it regardless, e.g. when working
+offline.
+
 The installation will be configured with the specified container mode and
 authentication credentials. Use different container modes based on your needs:
 
@@ -41,10 +132,21 @@ For scaling runners, you have two options:
 2. Multiple instances with --instances: Creates separate runner scale sets (each with min=1, max=1)
    Each instance has its own cache paths for better cache isolation.
 
+The auth value (PAT or GitHub App private key) is given with one of:
+- --auth-value: on the command line, visible in shell history and process listings
+- --auth-value-file: read from a file, e.g. the private key of a GitHub App
+- --auth-value-env: read from an environment variable, e.g. GITHUB_TOKEN
+Without these flags, the token is prompted for without echoing it (for GitHub
+Apps the path of the private key file is asked).
+
 Examples:
   # Add a standard runner (single instance, scales 1-5)
   deskrun add my-runner --repository https://github.com/owner/repo --auth-type pat --auth-value ghp_xxx
 
+  # Add a runner with the token from the environment or a prompt
+  deskrun add my-runner --repository https://github.com/owner/repo --auth-value-env GITHUB_TOKEN
+  deskrun add my-runner --repository https://github.com/owner/repo
+
   # Add a privileged runner with Docker cache (single instance, scales 1-5)
   deskrun add docker-runner \
     --repository https://github.com/owner/repo \
@@ -53,6 +155,13 @@ Examples:
     --max-runners 5 \
     --auth-type pat --auth-value ghp_xxx
 
+  # Keep the Docker cache below 20Gi with 'deskrun cache gc'
+  deskrun add docker-runner \
+    --repository https://github.com/owner/repo \
+    --mode cached-privileged-kubernetes \
+    --mount /var/lib/docker --max-size /var/lib/docker=20Gi \
+    --auth-type pat --auth-value ghp_xxx
+
   # Add a privileged runner with custom source and target mount paths
   deskrun add custom-runner \
     --repository https://github.com/owner/repo \
@@ -61,6 +170,16 @@ Examples:
     --mount /host/cache/cargo:/usr/local/cargo/registry \
     --auth-type pat --auth-value ghp_xxx
 
+  # Add a privileged runner on a remote cluster, keeping the Docker cache in a
+  # persistent volume claim instead of a directory on the node
+  deskrun add remote-runner \
+    --repository https://github.com/owner/repo \
+    --mode cached-privileged-kubernetes \
+    --max-runners 1 \
+    --mount /var/lib/docker \
+    --cache-type pvc --cache-storage-class standard --cache-size 50Gi \
+    --auth-type pat --auth-value ghp_xxx
+
   # Add a runner with Docker socket mount for host Docker access
   deskrun add docker-runner \
     --repository https://github.com/owner/repo \
@@ -76,6 +195,118 @@ Examples:
     --instances 3 \
     --auth-type pat --auth-value ghp_xxx
 
+  # Keep the Docker caches of the instances on a fast disk, one directory per
+  # instance ({instance} is replaced by the instance number)
+  deskrun add nvme-runner \
+    --repository https://github.com/owner/repo \
+    --mode cached-privileged-kubernetes \
+    --mount /nvme/docker-cache/instance-{instance}:/var/lib/docker \
+    --instances 3 \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner capped at 2 CPUs and 4Gi of memory for constrained desktops
+  deskrun add small-runner \
+    --repository https://github.com/owner/repo \
+    --cpu-request 500m --cpu-limit 2 \
+    --memory-request 1Gi --memory-limit 4Gi \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a privileged runner whose runner and job containers can use at most 50Gi of disk
+  deskrun add big-builds \
+    --repository https://github.com/owner/repo \
+    --mode cached-privileged-kubernetes \
+    --ephemeral-storage-request 10Gi --ephemeral-storage-limit 50Gi \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add an organization-level runner in a dedicated runner group
+  deskrun add org-runner \
+    --repository https://github.com/myorg \
+    --runner-group ci-runners \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner using a custom runner image with preinstalled tools
+  deskrun add custom-image-runner \
+    --repository https://github.com/owner/repo \
+    --image ghcr.io/owner/custom-runner:2.321.0 \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner authenticated as a GitHub App
+  deskrun add app-runner \
+    --repository https://github.com/owner/repo \
+    --auth-type github-app \
+    --github-app-id 123456 --github-app-installation-id 7890123 \
+    --auth-value-file private-key.pem
+
+  # Add a dind runner with a pinned sidecar image and resource limits
+  deskrun add dind-runner \
+    --repository https://github.com/owner/repo \
+    --mode dind \
+    --dind-image docker:27-dind \
+    --dind-cpu-limit 2 --dind-memory-limit 4Gi \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner that reaches GitHub through a corporate proxy
+  deskrun add corp-runner \
+    --repository https://github.com/owner/repo \
+    --https-proxy http://proxy.corp:3128 \
+    --no-proxy .corp.example.com \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner with step debug logging and a custom tool path
+  deskrun add debug-runner \
+    --repository https://github.com/owner/repo \
+    --env ACTIONS_STEP_DEBUG=true \
+    --env TOOLS_DIR=/opt/tools \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner scheduled only on tainted build nodes of a multi-node cluster
+  deskrun add build-runner \
+    --repository https://github.com/owner/repo \
+    --node-selector node-role/build=true \
+    --toleration dedicated=build:NoSchedule \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner with registry credentials and a CA bundle mounted from the cluster
+  # (create them first with kubectl -n arc-systems create secret/configmap, or in
+  # the namespace given with --namespace)
+  deskrun add registry-runner \
+    --repository https://github.com/owner/repo \
+    --mount-secret registry-creds:/etc/registry \
+    --mount-configmap ca-bundle:/etc/ssl/custom \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner sharing the npm cache volume with other installations
+  # (create it first with 'deskrun cache create npm --path /home/runner/.npm')
+  deskrun add web-runner \
+    --repository https://github.com/owner/web \
+    --cache-volume npm \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner pinned to a separate kind cluster
+  deskrun add gpu-runner \
+    --repository https://github.com/owner/repo \
+    --cluster deskrun-gpu \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner isolated in a namespace of its own
+  deskrun add team-a-runner \
+    --repository https://github.com/owner/repo \
+    --namespace team-a \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner whose manifests are customized with a ytt overlay
+  deskrun add labeled-runner \
+    --repository https://github.com/owner/repo \
+    --overlay ./team-labels.yaml \
+    --auth-type pat --auth-value ghp_xxx
+
+  # Add a runner with labels and a listener log level deskrun has no flags for
+  deskrun add team-runner \
+    --repository https://github.com/owner/repo \
+    --set labels.team=platform \
+    --values ./listener-values.yaml \
+    --auth-type pat --auth-value ghp_xxx
+
   # After adding, deploy the configuration
   deskrun up
 `,
@@ -84,22 +315,71 @@ Examples:
 }
 
 func init() {
-	addCmd.Flags().StringVarP(&addRepository, "repository", "r", "", "GitHub repository URL (required)")
-	addCmd.Flags().StringVarP(&addMode, "mode", "m", "kubernetes", "Container mode (kubernetes, cached-privileged-kubernetes, dind)")
+	addCmd.Flags().StringVarP(&addRepository, "repository", "r", "", "GitHub repository or organization URL (required)")
+	addCmd.Flags().StringVar(&addRunnerGroup, "runner-group", "", "Runner group for organization-level runners (defaults to the Default group)")
+	addCmd.Flags().StringVarP(&addMode, "mode", "m", "kubernetes", "Container mode (kubernetes, cached-privileged-kubernetes, dind, dind-rootless, host-docker)")
+	addCmd.Flags().StringVar(&addImage, "image", "", "Runner container image (defaults to ghcr.io/actions/actions-runner:latest)")
 	addCmd.Flags().IntVar(&addMinRunners, "min-runners", 1, "Minimum number of runners (ignored when using --instances)")
 	addCmd.Flags().IntVar(&addMaxRunners, "max-runners", 5, "Maximum number of runners (ignored when using --instances)")
+	addCmd.Flags().StringArrayVar(&addSchedule, "schedule", []string{}, "Runner limits during a recurring time window, applied by 'deskrun agent'. Format: 'DAYS HH:MM-HH:MM MIN:MAX', e.g. 'mon-fri 09:00-18:00 2:8' (can be specified multiple times)")
 	addCmd.Flags().IntVar(&addInstances, "instances", 1, "Number of separate runner scale set instances (each will have min=1, max=1 for cache isolation)")
 	addCmd.Flags().StringVar(&addAuthType, "auth-type", "pat", "Authentication type (pat, github-app)")
 	addCmd.Flags().StringVar(&addAuthValue, "auth-value", "", "Authentication value (PAT token or GitHub App private key)")
-	addCmd.Flags().StringSliceVar(&addMounts, "mount", []string{}, "Mount paths. Format: target, src:target, or src:target:type (can be specified multiple times)")
-	addCmd.Flags().StringSliceVar(&addCachePaths, "cache", []string{}, "Deprecated: use --mount instead. Cache paths to mount. Format: target or src:target")
+	addCmd.Flags().StringVar(&addAuthValueFile, "auth-value-file", "", "File to read the authentication value from")
+	addCmd.Flags().StringVar(&addAuthValueEnv, "auth-value-env", "", "Environment variable to read the authentication value from")
+	addCmd.Flags().Int64Var(&addGitHubAppID, "github-app-id", 0, "GitHub App ID (required with --auth-type github-app)")
+	addCmd.Flags().Int64Var(&addGitHubAppInstallationID, "github-app-installation-id", 0, "GitHub App installation ID (required with --auth-type github-app)")
+	addCmd.Flags().StringSliceVar(&addMounts, "mount", []string{}, "Mount paths. Format: target, src:target, or src:target:type; {instance} in src is replaced by the instance number (can be specified multiple times)")
+	addCmd.Flags().StringSliceVar(&addMaxSizes, "max-size", []string{}, "Size above which 'deskrun cache gc' prunes a mount. Format: target=size, e.g. /var/lib/docker=20Gi (can be specified multiple times)")
+	addCmd.Flags().StringSliceVar(&addCacheVolumes, "cache-volume", []string{}, "Shared cache volume to mount, created with 'deskrun cache create' (can be specified multiple times)")
+	addCmd.Flags().StringVar(&addCacheType, "cache-type", string(types.CacheTypeHostPath), "Storage of mounts without a source path (hostpath: directories on the node, pvc: persistent volume claims)")
+	addCmd.Flags().StringVar(&addCacheStorageClass, "cache-storage-class", "", "Storage class of the cache claims (--cache-type pvc only, defaults to the default storage class)")
+	addCmd.Flags().StringVar(&addCacheSize, "cache-size", "", "Size of every cache claim (--cache-type pvc only, defaults to 10Gi)")
+	addCmd.Flags().StringSliceVar(&addCachePaths, "cache", []string{}, "Deprecated: use --mount instead. Cache paths to mount. Format: target or src:target; {instance} in src is replaced by the instance number")
+	addCmd.Flags().StringVar(&addCPURequest, "cpu-request", "", "CPU request for the runner container (e.g. 500m)")
+	addCmd.Flags().StringVar(&addCPULimit, "cpu-limit", "", "CPU limit for the runner container (e.g. 2)")
+	addCmd.Flags().StringVar(&addMemoryRequest, "memory-request", "", "Memory request for the runner container (e.g. 1Gi)")
+	addCmd.Flags().StringVar(&addMemoryLimit, "memory-limit", "", "Memory limit for the runner container (e.g. 4Gi)")
+	addCmd.Flags().StringVar(&addListenerCPURequest, "listener-cpu-request", "", "CPU request for the autoscaling listener container (e.g. 50m)")
+	addCmd.Flags().StringVar(&addListenerCPULimit, "listener-cpu-limit", "", "CPU limit for the autoscaling listener container (e.g. 200m)")
+	addCmd.Flags().StringVar(&addListenerMemoryRequest, "listener-memory-request", "", "Memory request for the autoscaling listener container (e.g. 64Mi)")
+	addCmd.Flags().StringVar(&addListenerMemoryLimit, "listener-memory-limit", "", "Memory limit for the autoscaling listener container (e.g. 256Mi)")
+	addCmd.Flags().StringVar(&addEphemeralStorageRequest, "ephemeral-storage-request", "", "Ephemeral storage request for runner and job containers (e.g. 10Gi)")
+	addCmd.Flags().StringVar(&addEphemeralStorageLimit, "ephemeral-storage-limit", "", "Ephemeral storage limit for runner and job containers (e.g. 50Gi)")
+	addCmd.Flags().StringVar(&addDinDImage, "dind-image", "", "Docker-in-Docker sidecar image (dind modes only, defaults to docker:dind or docker:dind-rootless)")
+	addCmd.Flags().StringVar(&addDinDCPURequest, "dind-cpu-request", "", "CPU request for the dind sidecar (dind modes only, e.g. 500m)")
+	addCmd.Flags().StringVar(&addDinDCPULimit, "dind-cpu-limit", "", "CPU limit for the dind sidecar (dind modes only, e.g. 2)")
+	addCmd.Flags().StringVar(&addDinDMemoryRequest, "dind-memory-request", "", "Memory request for the dind sidecar (dind modes only, e.g. 1Gi)")
+	addCmd.Flags().StringVar(&addDinDMemoryLimit, "dind-memory-limit", "", "Memory limit for the dind sidecar (dind modes only, e.g. 4Gi)")
+	addCmd.Flags().IntVar(&addDinDMTU, "dind-mtu", 0, "MTU of the networks of the dind daemon, e.g. 1400 below a VPN (dind modes only)")
+	addCmd.Flags().StringSliceVar(&addDinDMirrors, "dind-registry-mirror", []string{}, "Docker Hub mirror of the dind daemon, e.g. https://mirror.gcr.io (dind modes only, can be specified multiple times)")
+	addCmd.Flags().StringSliceVar(&addDinDInsecure, "dind-insecure-registry", []string{}, "Registry the dind daemon reaches over plain HTTP. Format: host[:port] or CIDR (dind modes only, can be specified multiple times)")
+	addCmd.Flags().StringVar(&addCluster, "cluster", "", "Name of the kind cluster to deploy this runner to (defaults to the default cluster)")
+	addCmd.Flags().StringVar(&addNamespace, "namespace", "", "Kubernetes namespace for the runner scale sets, their secret and RBAC (defaults to arc-systems)")
+	addCmd.Flags().StringVar(&addHTTPProxy, "http-proxy", "", "HTTP proxy URL for the runner and listener (defaults to the proxy of the cluster)")
+	addCmd.Flags().StringVar(&addHTTPSProxy, "https-proxy", "", "HTTPS proxy URL for the runner and listener (defaults to the proxy of the cluster)")
+	addCmd.Flags().StringVar(&addNoProxy, "no-proxy", "", "Comma-separated hosts, domains and CIDRs that bypass the proxy")
+	addCmd.Flags().StringArrayVar(&addEnv, "env", []string{}, "Extra environment variable of the runner container. Format: KEY=VALUE (can be specified multiple times)")
+	addCmd.Flags().StringVar(&addRuntimeClass, "runtime-class", "", "RuntimeClass of the runner pods, e.g. sysbox-runc; the dind sidecar of dind mode runs unprivileged with it")
+	addCmd.Flags().StringVar(&addArch, "arch", "", "CPU architecture of the nodes runner pods run on: amd64 or arm64 (defaults to any node); the images must be available for it")
+	addCmd.Flags().StringSliceVar(&addNodeSelector, "node-selector", []string{}, "Node label runner pods must match. Format: key=value (can be specified multiple times)")
+	addCmd.Flags().StringSliceVar(&addTolerations, "toleration", []string{}, "Taint runner pods tolerate. Format: key[=value][:effect] (can be specified multiple times)")
+	addCmd.Flags().StringSliceVar(&addMountSecrets, "mount-secret", []string{}, "Secret in the runner namespace to mount into the runner container. Format: name:/path (can be specified multiple times)")
+	addCmd.Flags().StringSliceVar(&addMountConfigMaps, "mount-configmap", []string{}, "ConfigMap in the runner namespace to mount into the runner container. Format: name:/path (can be specified multiple times)")
+	addCmd.Flags().StringSliceVar(&addOverlays, "overlay", []string{}, "ytt overlay file applied to the runner manifests after deskrun's overlay (can be specified multiple times)")
+	addCmd.Flags().StringVar(&addNetworkPolicy, "network-policy", "", "Restrict the egress of runner pods to DNS, the Kubernetes API and public HTTPS endpoints like GitHub (restricted; dind and cached-privileged-kubernetes modes)")
+	addCmd.Flags().StringSliceVar(&addAllowEgress, "allow-egress", []string{}, "Extra CIDR runner pods may reach with --network-policy restricted, e.g. a proxy (can be specified multiple times)")
+	addCmd.Flags().StringVar(&addPodSecurity, "pss", "", "Pod Security Standards profile the runner and job pods comply with and the namespace enforces (baseline, restricted; kubernetes mode with --namespace)")
+	addCmd.Flags().StringArrayVar(&addLabels, "label", []string{}, "Label of every runner resource and runner pod. Format: key=value (can be specified multiple times)")
+	addCmd.Flags().StringArrayVar(&addAnnotations, "annotation", []string{}, "Annotation of every runner resource and runner pod. Format: key=value (can be specified multiple times)")
+	addCmd.Flags().StringSliceVar(&addValueFiles, "values", []string{}, "YAML file of extra scale set data values (can be specified multiple times)")
+	addCmd.Flags().StringArrayVar(&addSetValues, "set", []string{}, "Extra scale set data value, applied after --values. Format: key.path=value (can be specified multiple times)")
+	addCmd.Flags().BoolVar(&addSkipValidation, "skip-validation", false, "Do not validate the credentials against the GitHub API and the image architectures against their registries")
 
 	if err := addCmd.MarkFlagRequired("repository"); err != nil {
 		panic(err)
 	}
-	if err := addCmd.MarkFlagRequired("auth-value"); err != nil {
-		panic(err)
-	}
+	addCmd.MarkFlagsMutuallyExclusive("auth-value", "auth-value-file", "auth-value-env")
 
 	rootCmd.AddCommand(addCmd)
 }
@@ -111,130 +391,56 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	repository := sanitizeRepositoryURL(addRepository)
 
 	// Validate container mode
-	var containerMode types.ContainerMode
-	switch addMode {
-	case "kubernetes":
-		containerMode = types.ContainerModeKubernetes
-	case "cached-privileged-kubernetes":
-		containerMode = types.ContainerModePrivileged
-	case "dind":
-		containerMode = types.ContainerModeDinD
-	default:
-		return fmt.Errorf("invalid container mode: %s", addMode)
+	containerMode, err := parseContainerMode(addMode)
+	if err != nil {
+		return err
 	}
+	warnHostVMMode(containerMode)
 
 	// Validate auth type
-	var authType types.AuthType
-	switch addAuthType {
-	case "pat":
-		authType = types.AuthTypePAT
-	case "github-app":
-		authType = types.AuthTypeGitHubApp
-	default:
-		return fmt.Errorf("invalid auth type: %s", addAuthType)
+	authType, err := parseAuthType(addAuthType)
+	if err != nil {
+		return err
 	}
 
-	// Create cache paths from --cache flag (deprecated, for backward compatibility)
-	cachePaths := []types.CachePath{}
-	for _, path := range addCachePaths {
-		// Parse src:target notation
-		var source, target string
-		if strings.Contains(path, ":") {
-			parts := strings.SplitN(path, ":", 2)
-			if len(parts) == 2 {
-				source = parts[0]
-				target = parts[1]
-			} else {
-				return fmt.Errorf("invalid cache path format '%s', expected src:target or just target", path)
-			}
-		} else {
-			// Single path provided - use as target path, auto-generate source path
-			target = path
-			// Auto-generate source path by creating a unique directory under /host-cache/deskrun
-			// Replace slashes with dashes and remove leading slash for path safety
-			safePath := strings.TrimPrefix(path, "/")
-			safePath = strings.ReplaceAll(safePath, "/", "-")
-			source = fmt.Sprintf("/host-cache/deskrun/%s", safePath)
-		}
+	if err := validateGitHubAppParams(authType, addGitHubAppID, addGitHubAppInstallationID); err != nil {
+		return err
+	}
 
-		cachePaths = append(cachePaths, types.CachePath{
-			Target: target,
-			Source: source,
-		})
+	authValue, err := resolveAuthValue(authType, addAuthValue, addAuthValueFile, addAuthValueEnv, promptSecret, promptLine)
+	if err != nil {
+		return err
 	}
 
-	// Create mounts from --mount flag (new format)
-	mounts := []types.Mount{}
-	for _, path := range addMounts {
-		// Parse src:target:type notation
-		// Supported formats:
-		// - target (auto-generated source, DirectoryOrCreate type)
-		// - src:target (explicit source, DirectoryOrCreate type)
-		// - src:target:type (explicit source and type)
-		var source, target string
-		mountType := types.MountTypeDirectoryOrCreate
+	if err := validateNamespace(addNamespace); err != nil {
+		return err
+	}
+	if err := validateRuntimeClass(addRuntimeClass); err != nil {
+		return err
+	}
+	arch, err := parseArch(addArch)
+	if err != nil {
+		return err
+	}
 
-		parts := strings.Split(path, ":")
-		switch len(parts) {
-		case 1:
-			// Just target path, auto-generate source
-			target = parts[0]
-			safePath := strings.TrimPrefix(path, "/")
-			safePath = strings.ReplaceAll(safePath, "/", "-")
-			source = fmt.Sprintf("/tmp/deskrun-cache/%s", safePath)
-		case 2:
-			// src:target
-			source = parts[0]
-			target = parts[1]
-		case 3:
-			// src:target:type
-			source = parts[0]
-			target = parts[1]
-			typeStr := parts[2]
-			switch typeStr {
-			case "DirectoryOrCreate":
-				mountType = types.MountTypeDirectoryOrCreate
-			case "Directory":
-				mountType = types.MountTypeDirectory
-			case "Socket":
-				mountType = types.MountTypeSocket
-			default:
-				return fmt.Errorf("invalid mount type '%s', must be one of: DirectoryOrCreate, Directory, Socket", typeStr)
-			}
-		default:
-			return fmt.Errorf("invalid mount format '%s', expected target, src:target, or src:target:type", path)
-		}
+	if addRunnerGroup != "" && !types.IsOrganizationURL(repository) {
+		return fmt.Errorf("--runner-group can only be used with an organization URL (e.g. https://github.com/myorg)")
+	}
 
-		mounts = append(mounts, types.Mount{
-			Source: source,
-			Target: target,
-			Type:   mountType,
-		})
+	// Create cache paths from --cache flag (deprecated, for backward compatibility)
+	cachePaths, err := parseCachePaths(addCachePaths)
+	if err != nil {
+		return err
 	}
 
-	// Check for duplicates within mounts
-	mountTargets := make(map[string]struct{}, len(mounts))
-	for _, m := range mounts {
-		if _, exists := mountTargets[m.Target]; exists {
-			return fmt.Errorf("duplicate mount target '%s' specified multiple times", m.Target)
-		}
-		mountTargets[m.Target] = struct{}{}
+	// Create mounts from --mount flag (new format)
+	mounts, err := parseMounts(addMounts)
+	if err != nil {
+		return err
 	}
 
-	// Validate that there are no duplicate target paths between deprecated --cache
-	// paths (cachePaths) and new --mount targets. Using the same target path with
-	// both flags would result in duplicate volume mounts and cause pod creation
-	// to fail with duplicate mountPath errors.
-	if len(cachePaths) > 0 && len(mounts) > 0 {
-		cacheTargets := make(map[string]struct{}, len(cachePaths))
-		for _, p := range cachePaths {
-			cacheTargets[p.Target] = struct{}{}
-		}
-		for _, m := range mounts {
-			if _, exists := cacheTargets[m.Target]; exists {
-				return fmt.Errorf("duplicate mount target '%s' specified via both --cache and --mount; use only one of these flags for this path", m.Target)
-			}
-		}
+	if err := applyMaxSizes(addMaxSizes, cachePaths, mounts); err != nil {
+		return err
 	}
 
 	// Validate parameters including mounts
@@ -242,6 +448,17 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	cacheStorage, err := buildCacheStorage(addCacheType, addCacheStorageClass, addCacheSize)
+	if err != nil {
+		return err
+	}
+
+	// Build dind sidecar configuration from flags
+	dind, err := buildDinDConfig(containerMode)
+	if err != nil {
+		return err
+	}
+
 	// When using multiple instances, automatically set minRunners and maxRunners to 1
 	// for each instance (no point in scaling within an instance if we're scaling via instances)
 	minRunners := addMinRunners
@@ -255,14 +472,102 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	installation := &types.RunnerInstallation{
 		Name:          name,
 		Repository:    repository,
+		RunnerGroup:   addRunnerGroup,
 		ContainerMode: containerMode,
+		Image:         addImage,
 		MinRunners:    minRunners,
 		MaxRunners:    maxRunners,
 		Instances:     addInstances,
 		Mounts:        mounts,
 		CachePaths:    cachePaths, // Keep for backward compatibility
+		CacheStorage:  cacheStorage,
 		AuthType:      authType,
-		AuthValue:     addAuthValue,
+		AuthValue:     authValue,
+		DinD:          dind,
+		Cluster:       addCluster,
+		Namespace:     addNamespace,
+	}
+
+	if authType == types.AuthTypeGitHubApp {
+		installation.GitHubAppID = addGitHubAppID
+		installation.GitHubAppInstallationID = addGitHubAppInstallationID
+	}
+
+	runnerResources := &types.ResourceRequirements{
+		Requests: types.ResourceList{CPU: addCPURequest, Memory: addMemoryRequest},
+		Limits:   types.ResourceList{CPU: addCPULimit, Memory: addMemoryLimit},
+	}
+	if !runnerResources.IsEmpty() {
+		installation.Resources = runnerResources
+	}
+
+	listenerResources := &types.ResourceRequirements{
+		Requests: types.ResourceList{CPU: addListenerCPURequest, Memory: addListenerMemoryRequest},
+		Limits:   types.ResourceList{CPU: addListenerCPULimit, Memory: addListenerMemoryLimit},
+	}
+	if !listenerResources.IsEmpty() {
+		installation.Listener = &types.ListenerConfig{Resources: listenerResources}
+	}
+
+	if err := validateCacheStorage(installation); err != nil {
+		return err
+	}
+
+	schedule, err := parseSchedule(addSchedule)
+	if err != nil {
+		return err
+	}
+	installation.Schedule = schedule
+	if err := validateSchedule(installation); err != nil {
+		return err
+	}
+
+	if addEphemeralStorageRequest != "" || addEphemeralStorageLimit != "" {
+		installation.EphemeralStorage = &types.EphemeralStorage{
+			Request: addEphemeralStorageRequest,
+			Limit:   addEphemeralStorageLimit,
+		}
+	}
+
+	if addHTTPProxy != "" || addHTTPSProxy != "" || addNoProxy != "" {
+		installation.Proxy = &types.ProxyConfig{
+			HTTPProxy:  addHTTPProxy,
+			HTTPSProxy: addHTTPSProxy,
+			NoProxy:    addNoProxy,
+		}
+		if err := validateProxyConfig(installation.Proxy); err != nil {
+			return err
+		}
+	}
+
+	if installation.EnvVars, err = parseEnvVars(addEnv); err != nil {
+		return err
+	}
+	installation.RuntimeClassName = addRuntimeClass
+	installation.Arch = arch
+	if installation.NodeSelector, err = parseNodeSelector(addNodeSelector); err != nil {
+		return err
+	}
+	if installation.Tolerations, err = parseTolerations(addTolerations); err != nil {
+		return err
+	}
+	if installation.ObjectMounts, err = parseObjectMounts(addMountSecrets, addMountConfigMaps); err != nil {
+		return err
+	}
+	if installation.Overlays, err = overlayPaths(addOverlays); err != nil {
+		return err
+	}
+	if installation.NetworkPolicy, err = buildNetworkPolicy(addNetworkPolicy, addAllowEgress, containerMode); err != nil {
+		return err
+	}
+	if installation.PodSecurity, err = parsePodSecurity(addPodSecurity, installation); err != nil {
+		return err
+	}
+	if installation.Labels, installation.Annotations, err = parseMetadata(addLabels, addAnnotations); err != nil {
+		return err
+	}
+	if installation.Values, err = parseValues(nil, addValueFiles, addSetValues); err != nil {
+		return err
 	}
 
 	// Load config
@@ -271,6 +576,25 @@ func runAdd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if err := validateCacheVolumeReferences(configMgr.GetConfig(), installation); err != nil {
+		return err
+	}
+
+	if !addSkipValidation {
+		if err := validateImageArch(installation); err != nil {
+			return err
+		}
+		if err := validateInstallationToken(installation); err != nil {
+			return err
+		}
+	}
+
+	if addCluster != "" {
+		if err := configMgr.AddCluster(addCluster); err != nil {
+			return fmt.Errorf("failed to register cluster: %w", err)
+		}
+	}
+
 	// Save to config
 	if err := configMgr.AddInstallation(installation); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
@@ -282,28 +606,633 @@ func runAdd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// validateAddParams validates the instances, max-runners, cache paths, and mounts
-func validateAddParams(instances, maxRunners int, containerMode types.ContainerMode, cachePaths []types.CachePath, mounts []types.Mount) error {
-	// Validate instances
-	if instances < 1 {
-		return fmt.Errorf("instances must be at least 1")
-	}
+// validateInstallationToken checks that a PAT can register runners for the
+// installation's repository or organization, or that GitHub App credentials
+// can get an installation token for it
+func validateInstallationToken(installation *types.RunnerInstallation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	// Validate cache paths - provide helpful guidance for /nix/store
-	for _, cachePath := range cachePaths {
-		if cachePath.Target == "/nix/store" {
-			return fmt.Errorf(
-				"caching /nix/store is not supported in deskrun: " +
-					"mounting host paths directly to /nix/store breaks NixOS containers by overwriting essential NixOS binaries and libraries.\n\n" +
-					"To cache Nix packages, consider:\n" +
-					"1. Use the opencode-workspace-action with overlayfs support for /nix/store caching\n" +
-					"2. Cache alternative paths like /root/.cache/nix for user-level Nix cache\n" +
-					"3. Cache /var/lib/docker for Docker layer caching (unaffected by this limitation)\n\n" +
-					"See: https://github.com/rkoster/opencode-workspace-action/issues (overlay filesystem support for /nix/store)")
+	if installation.AuthType == types.AuthTypeGitHubApp {
+		slog.Info("Validating GitHub App installation", "repository", installation.Repository)
+		if err := validateAppInstallation(ctx, installation); err != nil {
+			return fmt.Errorf("GitHub App validation failed (use --skip-validation to save anyway): %w", err)
 		}
+		fmt.Printf("✓ GitHub App installation %d has access\n", installation.GitHubAppInstallationID)
+		return nil
+	}
+	if installation.AuthType != types.AuthTypePAT {
+		return nil
+	}
 
-		// Validate that target path is absolute
-		if !strings.HasPrefix(cachePath.Target, "/") {
+	slog.Info("Validating token", "repository", installation.Repository)
+	info, err := github.NewClient(installation.AuthValue).ValidateRunnerToken(ctx, installation.Repository)
+	if err != nil {
+		return fmt.Errorf("token validation failed (use --skip-validation to save anyway): %w", err)
+	}
+
+	fmt.Printf("✓ Token of user %s has admin access\n", info.Login)
+	return nil
+}
+
+// validateAppInstallation checks the GitHub App credentials of an installation
+func validateAppInstallation(ctx context.Context, installation *types.RunnerInstallation) error {
+	client, err := github.NewAppClient(installation.GitHubAppID, installation.GitHubAppInstallationID, []byte(installation.AuthValue))
+	if err != nil {
+		return err
+	}
+	return client.ValidateAppInstallation(ctx, installation.Repository)
+}
+
+// validateProxyConfig checks that proxy settings name at least one proxy and
+// that the proxies are http(s) URLs
+func validateProxyConfig(proxy *types.ProxyConfig) error {
+	if proxy.IsEmpty() {
+		return fmt.Errorf("--no-proxy requires --http-proxy or --https-proxy")
+	}
+
+	for _, setting := range []struct{ flag, value string }{
+		{"--http-proxy", proxy.HTTPProxy},
+		{"--https-proxy", proxy.HTTPSProxy},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		u, err := url.Parse(setting.value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid %s '%s', expected a URL like http://proxy.example.com:3128", setting.flag, setting.value)
+		}
+	}
+	return nil
+}
+
+// validateNamespace checks that a --namespace value is a valid Kubernetes
+// namespace name; empty means arc-systems
+func validateNamespace(namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid --namespace '%s': %s", namespace, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// validateRuntimeClass checks that a --runtime-class value is a valid
+// RuntimeClass name; whether the cluster has it is checked on deploy
+func validateRuntimeClass(name string) error {
+	if name == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid --runtime-class '%s': %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// warnHostVMMode warns when a container mode doesn't work in the VM cluster
+// nodes run in on macOS
+func warnHostVMMode(mode types.ContainerMode) {
+	vm := cluster.CurrentHostVM()
+	if vm == nil {
+		return
+	}
+	if warning := vm.ModeWarning(mode); warning != "" {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+}
+
+// parseArch parses an --arch value: amd64, arm64, or empty for any node
+func parseArch(arch string) (types.Architecture, error) {
+	switch types.Architecture(arch) {
+	case "", types.ArchAMD64, types.ArchARM64:
+		return types.Architecture(arch), nil
+	default:
+		return "", fmt.Errorf("invalid --arch '%s': must be %s or %s", arch, types.ArchAMD64, types.ArchARM64)
+	}
+}
+
+// validateImageArch checks that the runner pod images are published for the
+// architecture of the installation, or of the local cluster nodes when it has
+// none, so they don't silently run emulated. Images whose manifest can't be
+// inspected, e.g. offline, are not checked.
+func validateImageArch(installation *types.RunnerInstallation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	arch := string(installation.Arch)
+	if arch == "" {
+		arch = cluster.HostArch(ctx)
+	}
+	for _, image := range templates.InstallationImages(installation) {
+		archs, err := cluster.ImageArchitectures(ctx, image)
+		if err != nil {
+			slog.Warn("Skipping the architecture check of image", "image", image, "error", err)
+			continue
+		}
+		if err := imageArchError(image, arch, archs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// imageArchError returns an error when an image is not published for an
+// architecture. Images without known architectures pass.
+func imageArchError(image, arch string, available []string) error {
+	if len(available) == 0 || slices.Contains(available, arch) {
+		return nil
+	}
+	return fmt.Errorf("image %s is not available for %s (available: %s); use an image built for %s (use --skip-validation to save anyway)",
+		image, arch, strings.Join(available, ", "), arch)
+}
+
+// reservedEnvVars are set by deskrun on the runner container and can't be
+// overridden with --env, mapped to the flag to use instead (if any)
+var reservedEnvVars = map[string]string{
+	"ACTIONS_RUNNER_CONTAINER_HOOKS":         "",
+	"ACTIONS_RUNNER_CONTAINER_HOOK_TEMPLATE": "",
+	"ACTIONS_RUNNER_POD_NAME":                "",
+	"ACTIONS_RUNNER_REQUIRE_JOB_CONTAINER":   "",
+	"DOCKER_HOST":                            "",
+	"RUNNER_WAIT_FOR_DOCKER_IN_SECONDS":      "",
+	"HTTP_PROXY":                             "--http-proxy",
+	"HTTPS_PROXY":                            "--https-proxy",
+	"NO_PROXY":                               "--no-proxy",
+}
+
+// envVarNamePattern matches the env var names accepted by --env
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// parseEnvVars parses --env flag values in KEY=VALUE notation, returning nil
+// when none are given. Values may contain '=' and be empty.
+func parseEnvVars(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	envVars := make(map[string]string, len(values))
+	for _, value := range values {
+		name, envValue, ok := strings.Cut(value, "=")
+		if !ok || !envVarNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid env var '%s', expected KEY=VALUE", value)
+		}
+		if flag, reserved := reservedEnvVars[strings.ToUpper(name)]; reserved {
+			if flag != "" {
+				return nil, fmt.Errorf("env var %s is set by deskrun, use %s instead", name, flag)
+			}
+			return nil, fmt.Errorf("env var %s is set by deskrun and can't be overridden", name)
+		}
+		if _, duplicate := envVars[name]; duplicate {
+			return nil, fmt.Errorf("env var %s specified multiple times", name)
+		}
+		envVars[name] = envValue
+	}
+	return envVars, nil
+}
+
+// parseNodeSelector parses --node-selector flag values in key=value notation,
+// returning nil when none are given
+func parseNodeSelector(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	nodeSelector := make(map[string]string, len(values))
+	for _, value := range values {
+		key, labelValue, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid node selector '%s', expected key=value", value)
+		}
+		nodeSelector[key] = labelValue
+	}
+	return nodeSelector, nil
+}
+
+// parseMetadata parses --label and --annotation flag values in key=value
+// notation, returning nil maps when none are given. Annotation values may
+// contain '=' and commas.
+func parseMetadata(labels, annotations []string) (map[string]string, map[string]string, error) {
+	parse := func(kind string, values []string) (map[string]string, error) {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		result := make(map[string]string, len(values))
+		for _, value := range values {
+			key, metadataValue, ok := strings.Cut(value, "=")
+			if !ok || key == "" {
+				return nil, fmt.Errorf("invalid %s '%s', expected key=value", kind, value)
+			}
+			if _, duplicate := result[key]; duplicate {
+				return nil, fmt.Errorf("%s %s specified multiple times", kind, key)
+			}
+			result[key] = metadataValue
+		}
+		return result, nil
+	}
+
+	parsedLabels, err := parse("label", labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	parsedAnnotations, err := parse("annotation", annotations)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := templates.ValidateMetadata(parsedLabels, parsedAnnotations); err != nil {
+		return nil, nil, err
+	}
+	return parsedLabels, parsedAnnotations, nil
+}
+
+// parseTolerations parses --toleration flag values in key[=value][:effect]
+// notation, like the taints of 'kubectl taint'. Without a value the toleration
+// matches any value of the key.
+func parseTolerations(values []string) ([]types.Toleration, error) {
+	var tolerations []types.Toleration
+	for _, value := range values {
+		spec, effect, _ := strings.Cut(value, ":")
+		key, tolerationValue, hasValue := strings.Cut(spec, "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid toleration '%s', expected key[=value][:effect]", value)
+		}
+
+		toleration := types.Toleration{Key: key, Operator: "Exists"}
+		if hasValue {
+			toleration.Operator = "Equal"
+			toleration.Value = tolerationValue
+		}
+
+		switch types.TolerationEffect(effect) {
+		case "", types.TolerationEffectNoSchedule, types.TolerationEffectPreferNoSchedule, types.TolerationEffectNoExecute:
+			toleration.Effect = types.TolerationEffect(effect)
+		default:
+			return nil, fmt.Errorf("invalid toleration effect '%s', must be one of: NoSchedule, PreferNoSchedule, NoExecute", effect)
+		}
+
+		tolerations = append(tolerations, toleration)
+	}
+	return tolerations, nil
+}
+
+// parseObjectMounts parses --mount-secret and --mount-configmap flag values in
+// name:/path notation. Mount paths must be absolute and unique.
+func parseObjectMounts(secrets, configMaps []string) ([]types.ObjectMount, error) {
+	var objectMounts []types.ObjectMount
+	paths := make(map[string]bool)
+	for _, group := range []struct {
+		kind   types.ObjectKind
+		flag   string
+		values []string
+	}{
+		{types.ObjectKindSecret, "--mount-secret", secrets},
+		{types.ObjectKindConfigMap, "--mount-configmap", configMaps},
+	} {
+		for _, value := range group.values {
+			name, path, ok := strings.Cut(value, ":")
+			if !ok || name == "" || !filepath.IsAbs(path) {
+				return nil, fmt.Errorf("invalid %s '%s', expected name:/path", group.flag, value)
+			}
+			path = filepath.Clean(path)
+			if paths[path] {
+				return nil, fmt.Errorf("mount path %s specified multiple times", path)
+			}
+			paths[path] = true
+			objectMounts = append(objectMounts, types.ObjectMount{Kind: group.kind, Name: name, Path: path})
+		}
+	}
+	return objectMounts, nil
+}
+
+// overlayPaths returns the absolute paths of --overlay files, so they are
+// found when deskrun runs in another directory. The files must exist.
+func overlayPaths(overlays []string) ([]string, error) {
+	var paths []string
+	for _, overlay := range overlays {
+		path, err := filepath.Abs(overlay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --overlay '%s': %w", overlay, err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --overlay '%s': %w", overlay, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("invalid --overlay '%s': is a directory", overlay)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// parseValues merges the scale set data values of --values files and --set
+// flags into values, like helm does: files first, then --set. --set values are
+// parsed as YAML, so --set maxRunners=3 sets a number. The result is checked
+// against the data values schema.
+func parseValues(values map[string]any, files, sets []string) (map[string]any, error) {
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --values file: %w", err)
+		}
+		var fileValues map[string]any
+		if err := yaml.Unmarshal(data, &fileValues); err != nil {
+			return nil, fmt.Errorf("invalid --values file %s: %w", file, err)
+		}
+		values = templates.MergeValues(values, fileValues)
+	}
+
+	for _, set := range sets {
+		path, raw, ok := strings.Cut(set, "=")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid --set '%s', expected key.path=value", set)
+		}
+		var value any = raw
+		if raw != "" {
+			if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+				return nil, fmt.Errorf("invalid --set '%s': %w", set, err)
+			}
+		}
+
+		keys := strings.Split(path, ".")
+		for i := len(keys) - 1; i >= 0; i-- {
+			if keys[i] == "" {
+				return nil, fmt.Errorf("invalid --set '%s', expected key.path=value", set)
+			}
+			value = map[string]any{keys[i]: value}
+		}
+		values = templates.MergeValues(values, value.(map[string]any))
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+	if err := templates.ValidateValues(values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// parseContainerMode converts a --mode flag value to a container mode
+func parseContainerMode(mode string) (types.ContainerMode, error) {
+	switch mode {
+	case "kubernetes":
+		return types.ContainerModeKubernetes, nil
+	case "cached-privileged-kubernetes":
+		return types.ContainerModePrivileged, nil
+	case "dind":
+		return types.ContainerModeDinD, nil
+	case "dind-rootless":
+		return types.ContainerModeDinDRootless, nil
+	case "host-docker":
+		return types.ContainerModeHostDocker, nil
+	default:
+		return "", fmt.Errorf("invalid container mode: %s", mode)
+	}
+}
+
+// resolveAuthValue returns the auth value given on the command line, read from
+// a file or environment variable, or prompted for when none of these is given.
+// Tokens are prompted for with promptSecret, the private key file of a GitHub
+// App with promptLine.
+func resolveAuthValue(authType types.AuthType, value, file, env string, promptSecret, promptLine func(label string) (string, error)) (string, error) {
+	switch {
+	case value != "":
+		return value, nil
+	case file != "":
+		return readAuthValueFile(authType, file)
+	case env != "":
+		value := os.Getenv(env)
+		if value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", env)
+		}
+		return strings.TrimSpace(value), nil
+	case authType == types.AuthTypeGitHubApp:
+		path, err := promptLine("Private key file of the GitHub App: ")
+		if err != nil {
+			return "", err
+		}
+		if path == "" {
+			return "", fmt.Errorf("no private key given")
+		}
+		return readAuthValueFile(authType, path)
+	default:
+		value, err := promptSecret("Personal access token: ")
+		if err != nil {
+			return "", err
+		}
+		if value == "" {
+			return "", fmt.Errorf("no token given")
+		}
+		return value, nil
+	}
+}
+
+// readAuthValueFile reads an auth value from a file. Tokens are trimmed, private
+// keys are kept as is.
+func readAuthValueFile(authType types.AuthType, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read auth value: %w", err)
+	}
+	if authType == types.AuthTypeGitHubApp {
+		return string(data), nil
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("auth value file %s is empty", path)
+	}
+	return value, nil
+}
+
+// parseAuthType converts an --auth-type flag value to an auth type
+func parseAuthType(authType string) (types.AuthType, error) {
+	switch authType {
+	case "pat":
+		return types.AuthTypePAT, nil
+	case "github-app":
+		return types.AuthTypeGitHubApp, nil
+	default:
+		return "", fmt.Errorf("invalid auth type: %s", authType)
+	}
+}
+
+// parseCachePaths parses --cache flag values (deprecated, for backward compatibility)
+func parseCachePaths(paths []string) ([]types.CachePath, error) {
+	cachePaths := []types.CachePath{}
+	for _, path := range paths {
+		// Parse src:target notation
+		var source, target string
+		if parts := splitMountSpec(path); len(parts) > 1 {
+			var err error
+			if source, err = cluster.TranslateWindowsPath(parts[0]); err != nil {
+				return nil, err
+			}
+			target = strings.Join(parts[1:], ":")
+		} else {
+			// Single path provided - use as target path, auto-generate source path
+			target = path
+			// Auto-generate source path by creating a unique directory under /host-cache/deskrun
+			source = types.GeneratedCacheSource(path)
+		}
+
+		cachePaths = append(cachePaths, types.CachePath{
+			Target: target,
+			Source: source,
+		})
+	}
+	return cachePaths, nil
+}
+
+// splitMountSpec splits a src:target[:type] flag value at its colons, keeping
+// the colon of a Windows drive letter in the source (C:\cache:/target)
+func splitMountSpec(spec string) []string {
+	if !cluster.IsWindowsPath(spec) {
+		return strings.Split(spec, ":")
+	}
+	parts := strings.Split(spec[2:], ":")
+	parts[0] = spec[:2] + parts[0]
+	return parts
+}
+
+// parseMounts parses --mount flag values in target, src:target, or src:target:type notation
+func parseMounts(paths []string) ([]types.Mount, error) {
+	mounts := []types.Mount{}
+	for _, path := range paths {
+		// Parse src:target:type notation
+		// Supported formats:
+		// - target (auto-generated source, DirectoryOrCreate type)
+		// - src:target (explicit source, DirectoryOrCreate type)
+		// - src:target:type (explicit source and type)
+		var source, target string
+		mountType := types.MountTypeDirectoryOrCreate
+
+		parts := splitMountSpec(path)
+		switch len(parts) {
+		case 1:
+			// Just target path, auto-generate source
+			target = parts[0]
+			source = types.GeneratedMountSource(path)
+		case 2:
+			// src:target
+			source = parts[0]
+			target = parts[1]
+		case 3:
+			// src:target:type
+			source = parts[0]
+			target = parts[1]
+			typeStr := parts[2]
+			switch typeStr {
+			case "DirectoryOrCreate":
+				mountType = types.MountTypeDirectoryOrCreate
+			case "Directory":
+				mountType = types.MountTypeDirectory
+			case "Socket":
+				mountType = types.MountTypeSocket
+			default:
+				return nil, fmt.Errorf("invalid mount type '%s', must be one of: DirectoryOrCreate, Directory, Socket", typeStr)
+			}
+		default:
+			return nil, fmt.Errorf("invalid mount format '%s', expected target, src:target, or src:target:type", path)
+		}
+
+		source, err := cluster.TranslateWindowsPath(source)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, types.Mount{
+			Source: source,
+			Target: target,
+			Type:   mountType,
+		})
+	}
+
+	// Check for duplicates within mounts
+	mountTargets := make(map[string]struct{}, len(mounts))
+	for _, m := range mounts {
+		if _, exists := mountTargets[m.Target]; exists {
+			return nil, fmt.Errorf("duplicate mount target '%s' specified multiple times", m.Target)
+		}
+		mountTargets[m.Target] = struct{}{}
+	}
+
+	return mounts, nil
+}
+
+// applyMaxSizes sets the max sizes of --max-size flag values (target=size) on
+// the mounts and cache paths with that target
+func applyMaxSizes(values []string, cachePaths []types.CachePath, mounts []types.Mount) error {
+	for _, value := range values {
+		target, size, ok := strings.Cut(value, "=")
+		if !ok || target == "" || size == "" {
+			return fmt.Errorf("invalid --max-size '%s': expected target=size", value)
+		}
+
+		found := false
+		for i := range cachePaths {
+			if cachePaths[i].Target == target {
+				cachePaths[i].MaxSize = size
+				found = true
+			}
+		}
+		for i := range mounts {
+			if mounts[i].Target == target {
+				mounts[i].MaxSize = size
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid --max-size '%s': no mount with target %s", value, target)
+		}
+	}
+	return nil
+}
+
+// validateAddParams validates the instances, max-runners, cache paths, and mounts
+func validateAddParams(instances, maxRunners int, containerMode types.ContainerMode, cachePaths []types.CachePath, mounts []types.Mount) error {
+	// Validate instances
+	if instances < 1 {
+		return fmt.Errorf("instances must be at least 1")
+	}
+
+	// Validate that there are no duplicate target paths between deprecated --cache
+	// paths (cachePaths) and new --mount targets. Using the same target path with
+	// both flags would result in duplicate volume mounts and cause pod creation
+	// to fail with duplicate mountPath errors.
+	if len(cachePaths) > 0 && len(mounts) > 0 {
+		cacheTargets := make(map[string]struct{}, len(cachePaths))
+		for _, p := range cachePaths {
+			cacheTargets[p.Target] = struct{}{}
+		}
+		for _, m := range mounts {
+			if _, exists := cacheTargets[m.Target]; exists {
+				return fmt.Errorf("duplicate mount target '%s' specified via both --cache and --mount; use only one of these flags for this path", m.Target)
+			}
+		}
+	}
+
+	// Every instance of a multi-instance installation runs a single runner
+	runnersPerScaleSet := maxRunners
+	if instances > 1 {
+		runnersPerScaleSet = 1
+	}
+
+	if instances > 1 {
+		if err := validateInstanceSources(cachePaths, mounts); err != nil {
+			return err
+		}
+	}
+
+	for _, cachePath := range cachePaths {
+		if cachePath.Target == nixStorePath {
+			if err := validateNixStoreCache(containerMode, runnersPerScaleSet, cachePath.MaxSize); err != nil {
+				return err
+			}
+		}
+
+		// Validate that target path is absolute
+		if !strings.HasPrefix(cachePath.Target, "/") {
 			return fmt.Errorf("cache target path '%s' must be an absolute path", cachePath.Target)
 		}
 
@@ -311,19 +1240,20 @@ func validateAddParams(instances, maxRunners int, containerMode types.ContainerM
 		if cachePath.Source != "" && !strings.HasPrefix(cachePath.Source, "/") {
 			return fmt.Errorf("cache source path '%s' must be an absolute path", cachePath.Source)
 		}
+
+		if err := validateMaxSize(cachePath.Target, cachePath.MaxSize); err != nil {
+			return err
+		}
 	}
 
-	// Validate mounts - provide helpful guidance for /nix/store
 	for _, mount := range mounts {
-		if mount.Target == "/nix/store" {
-			return fmt.Errorf(
-				"mount target /nix/store is not supported in deskrun: " +
-					"mounting host paths directly to /nix/store breaks NixOS containers by overwriting essential NixOS binaries and libraries.\n\n" +
-					"To cache Nix packages, consider:\n" +
-					"1. Use the opencode-workspace-action with overlayfs support for /nix/store caching\n" +
-					"2. Mount alternative paths like /root/.cache/nix for user-level Nix cache\n" +
-					"3. Mount /var/lib/docker for Docker layer caching (unaffected by this limitation)\n\n" +
-					"See: https://github.com/rkoster/opencode-workspace-action/issues (overlay filesystem support for /nix/store)")
+		if mount.Target == nixStorePath {
+			if mount.Type == types.MountTypeSocket {
+				return fmt.Errorf("mount target %s cannot be a socket mount", nixStorePath)
+			}
+			if err := validateNixStoreCache(containerMode, runnersPerScaleSet, mount.MaxSize); err != nil {
+				return err
+			}
 		}
 
 		// Validate that target path is absolute
@@ -335,11 +1265,239 @@ func validateAddParams(instances, maxRunners int, containerMode types.ContainerM
 		if mount.Source != "" && !strings.HasPrefix(mount.Source, "/") {
 			return fmt.Errorf("mount source path '%s' must be an absolute path", mount.Source)
 		}
+
+		if mount.MaxSize != "" && mount.Type == types.MountTypeSocket {
+			return fmt.Errorf("socket mount '%s' cannot have a max size", mount.Target)
+		}
+		if err := validateMaxSize(mount.Target, mount.MaxSize); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dockerDataRoot is the data directory of the Docker daemon in the runner
+const dockerDataRoot = "/var/lib/docker"
+
+// instanceSources names the targets whose source paths the instances of a
+// multi-instance installation can't share: concurrent Docker daemons would
+// corrupt a shared data directory, and concurrent jobs can't share the upper
+// layer of the Nix store overlay
+var instanceSources = map[string]string{
+	dockerDataRoot: "Docker data directory",
+	nixStorePath:   "Nix store cache",
+}
+
+// validateInstanceSources checks that the instances of a multi-instance
+// installation don't share the source path of a target in instanceSources.
+// Generated source paths are per instance already; explicit ones need the
+// {instance} placeholder.
+func validateInstanceSources(cachePaths []types.CachePath, mounts []types.Mount) error {
+	shared := func(source, generated string) bool {
+		return source != "" && source != generated && !strings.Contains(source, types.InstancePlaceholder)
+	}
+	sharedErr := func(source, target string) error {
+		return fmt.Errorf("all instances would share the %s %s; add %s to the source path, e.g. %s/instance-%s:%s",
+			instanceSources[target], source, types.InstancePlaceholder, source, types.InstancePlaceholder, target)
+	}
+	for _, cachePath := range cachePaths {
+		if _, ok := instanceSources[cachePath.Target]; ok && shared(cachePath.Source, types.GeneratedCacheSource(cachePath.Target)) {
+			return sharedErr(cachePath.Source, cachePath.Target)
+		}
+	}
+	for _, mount := range mounts {
+		if _, ok := instanceSources[mount.Target]; ok && shared(mount.Source, types.GeneratedMountSource(mount.Target)) {
+			return sharedErr(mount.Source, mount.Target)
+		}
 	}
+	return nil
+}
 
+// nixStorePath is the Nix store, which is only cached through an overlay in
+// cached-privileged-kubernetes mode
+const nixStorePath = "/nix/store"
+
+// validateNixStoreCache checks that /nix/store can be cached. Mounting a host
+// directory over it would hide the store of NixOS job images, so the
+// privileged hook overlays the cache on the image's store instead. The
+// writable layer of the overlay can't be shared by concurrent jobs, and
+// pruning files from it would leave incomplete store paths behind.
+func validateNixStoreCache(containerMode types.ContainerMode, maxRunners int, maxSize string) error {
+	if containerMode != types.ContainerModePrivileged {
+		return fmt.Errorf(
+			"caching /nix/store is only supported with --mode cached-privileged-kubernetes: " +
+				"mounting host paths directly to /nix/store breaks NixOS containers by overwriting essential NixOS binaries and libraries.\n\n" +
+				"In cached-privileged-kubernetes mode the cache is mounted as an overlay on the store of the job image instead. Otherwise, consider:\n" +
+				"1. Cache alternative paths like /root/.cache/nix for user-level Nix cache\n" +
+				"2. Cache /var/lib/docker for Docker layer caching (unaffected by this limitation)")
+	}
+	if maxRunners > 1 {
+		return fmt.Errorf("caching /nix/store requires --max-runners 1, concurrent jobs cannot share the overlay; use --instances to run more jobs in parallel")
+	}
+	if maxSize != "" {
+		return fmt.Errorf("/nix/store cannot have a max size, 'deskrun cache gc' would leave incomplete store paths behind")
+	}
+	return nil
+}
+
+// validateMaxSize checks the max size of a mount or cache path, a positive
+// Kubernetes quantity such as 20Gi
+func validateMaxSize(target, maxSize string) error {
+	if maxSize == "" {
+		return nil
+	}
+	quantity, err := resource.ParseQuantity(maxSize)
+	if err != nil || quantity.Sign() <= 0 {
+		return fmt.Errorf("invalid max size '%s' of %s, expected a positive size such as 20Gi", maxSize, target)
+	}
 	return nil
 }
 
+// buildCacheStorage builds the cache storage of an installation from the
+// --cache-type, --cache-storage-class and --cache-size flags. Returns nil for
+// directories on the node, the default.
+func buildCacheStorage(cacheType, storageClass, size string) (*types.CacheStorage, error) {
+	switch types.CacheType(cacheType) {
+	case "", types.CacheTypeHostPath:
+		if storageClass != "" || size != "" {
+			return nil, fmt.Errorf("--cache-storage-class and --cache-size can only be used with --cache-type pvc")
+		}
+		return nil, nil
+	case types.CacheTypePVC:
+		return &types.CacheStorage{Type: types.CacheTypePVC, StorageClass: storageClass, Size: size}, nil
+	default:
+		return nil, fmt.Errorf("invalid --cache-type '%s', expected hostpath or pvc", cacheType)
+	}
+}
+
+// buildNetworkPolicy builds the network policy of the --network-policy and
+// --allow-egress flags, returning nil without a policy
+func buildNetworkPolicy(mode string, allowEgress []string, containerMode types.ContainerMode) (*types.NetworkPolicyConfig, error) {
+	if mode == "" || mode == "none" {
+		if len(allowEgress) > 0 {
+			return nil, fmt.Errorf("--allow-egress can only be used with --network-policy restricted")
+		}
+		return nil, nil
+	}
+
+	policy := &types.NetworkPolicyConfig{Mode: types.NetworkPolicyMode(mode), AllowCIDRs: allowEgress}
+	if len(allowEgress) == 0 {
+		policy.AllowCIDRs = nil
+	}
+	if err := templates.ValidateNetworkPolicy(policy, containerMode); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// parsePodSecurity parses the profile of the --pss flag for an installation,
+// returning an empty level without a profile
+func parsePodSecurity(level string, installation *types.RunnerInstallation) (types.PodSecurityLevel, error) {
+	if level == "none" {
+		level = ""
+	}
+	check := *installation
+	check.PodSecurity = types.PodSecurityLevel(level)
+	if err := templates.ValidatePodSecurity(&check); err != nil {
+		return "", err
+	}
+	return check.PodSecurity, nil
+}
+
+// validateCacheStorage checks the persistent volume claims backing the caches
+// of an installation. Only privileged mode mounts caches, and 'deskrun cache gc'
+// only prunes directories on the node, so claims can't have a max size.
+func validateCacheStorage(installation *types.RunnerInstallation) error {
+	storage := installation.CacheStorage
+	if storage == nil {
+		return nil
+	}
+	if _, err := buildCacheStorage(string(storage.Type), storage.StorageClass, storage.Size); err != nil {
+		return err
+	}
+	if !storage.IsPVC() {
+		return nil
+	}
+
+	if installation.ContainerMode != types.ContainerModePrivileged {
+		return fmt.Errorf("--cache-type pvc requires --mode cached-privileged-kubernetes, the only mode that mounts caches")
+	}
+	if storage.StorageClass != "" {
+		if errs := validation.IsDNS1123Subdomain(storage.StorageClass); len(errs) > 0 {
+			return fmt.Errorf("invalid --cache-storage-class '%s': %s", storage.StorageClass, strings.Join(errs, ", "))
+		}
+	}
+	if storage.Size != "" {
+		quantity, err := resource.ParseQuantity(storage.Size)
+		if err != nil || quantity.Sign() <= 0 {
+			return fmt.Errorf("invalid --cache-size '%s', expected a positive size such as 20Gi", storage.Size)
+		}
+	}
+
+	for _, cachePath := range installation.CachePaths {
+		if installation.ClaimsCachePath(cachePath) && cachePath.MaxSize != "" {
+			return fmt.Errorf("cache %s is a persistent volume claim and cannot have a max size, set --cache-size instead", cachePath.Target)
+		}
+	}
+	for _, mount := range installation.Mounts {
+		if installation.ClaimsMount(mount) && mount.MaxSize != "" {
+			return fmt.Errorf("mount %s is a persistent volume claim and cannot have a max size, set --cache-size instead", mount.Target)
+		}
+	}
+	return nil
+}
+
+// validateGitHubAppParams ensures the GitHub App ID and installation ID are given
+// for github-app authentication, and only then
+func validateGitHubAppParams(authType types.AuthType, appID, installationID int64) error {
+	if authType != types.AuthTypeGitHubApp {
+		if appID != 0 || installationID != 0 {
+			return fmt.Errorf("--github-app-id and --github-app-installation-id can only be used with --auth-type github-app")
+		}
+		return nil
+	}
+
+	if appID <= 0 {
+		return fmt.Errorf("--github-app-id is required when using --auth-type github-app")
+	}
+	if installationID <= 0 {
+		return fmt.Errorf("--github-app-installation-id is required when using --auth-type github-app")
+	}
+	return nil
+}
+
+// buildDinDConfig builds the dind sidecar configuration from the --dind-* flags.
+// Returns nil when no dind flags are set.
+func buildDinDConfig(containerMode types.ContainerMode) (*types.DinDConfig, error) {
+	resources := &types.ResourceRequirements{
+		Requests: types.ResourceList{CPU: addDinDCPURequest, Memory: addDinDMemoryRequest},
+		Limits:   types.ResourceList{CPU: addDinDCPULimit, Memory: addDinDMemoryLimit},
+	}
+
+	dind := &types.DinDConfig{
+		Image:              addDinDImage,
+		MTU:                addDinDMTU,
+		RegistryMirrors:    nonEmpty(addDinDMirrors),
+		InsecureRegistries: nonEmpty(addDinDInsecure),
+	}
+	if dind.Image == "" && resources.IsEmpty() && !dind.HasDaemonConfig() {
+		return nil, nil
+	}
+
+	if !containerMode.UsesDinD() {
+		return nil, fmt.Errorf("--dind-* flags can only be used with --mode dind or dind-rootless")
+	}
+
+	if !resources.IsEmpty() {
+		dind.Resources = resources
+	}
+	if err := templates.ValidateDinDConfig(dind); err != nil {
+		return nil, err
+	}
+	return dind, nil
+}
+
 // sanitizeRepositoryURL cleans up the repository URL by ensuring HTTPS and removing trailing slashes
 func sanitizeRepositoryURL(url string) string {
 	// Convert HTTP to HTTPS for GitHub URLs
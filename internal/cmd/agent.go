@@ -0,0 +1,536 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/host"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// defaultAgentInterval is how often the agent checks the host without a configured interval
+const defaultAgentInterval = 30 * time.Second
+
+var (
+	agentPauseOnBattery  bool
+	agentMinBattery      int
+	agentMaxLoad         float64
+	agentInstallations   []string
+	agentInterval        string
+	agentCacheGCInterval string
+	agentDisable         bool
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Pause runners while the host is on battery or busy",
+	Long: `Run in the foreground and pause runner installations while the host runs
+on battery or its CPUs are busy, so a workstation stops taking jobs when it is
+unplugged. The installations are resumed once the host is back on AC power and
+the load dropped. Linux and macOS are supported.
+
+The policies are stored in the configuration; set them with
+'deskrun agent configure'. Changes to the policies are picked up by a running
+agent, except for the interval.
+
+With --cache-gc-interval the agent also prunes the cache mounts of the
+managed installations that grew above their max size, like 'deskrun cache gc'.
+
+Installations with a --schedule are scaled to the runner limits of the
+current schedule window, e.g. no runners overnight and two during work hours.
+Limits set with 'deskrun scale' hold until the next window starts or ends.
+
+Only installations that were running when the host entered the paused state are
+paused, and only those are resumed again: installations paused or resumed with
+'deskrun pause' and 'deskrun resume' are left alone. When the agent stops, it
+resumes the installations it paused.
+
+Examples:
+  # Pause all runners while unplugged
+  deskrun agent configure --pause-on-battery
+  deskrun agent
+`,
+	Args: cobra.NoArgs,
+	RunE: runAgent,
+}
+
+var agentConfigureCmd = &cobra.Command{
+	Use:   "configure",
+	Short: "Set the policies of the agent",
+	Long: `Set the policies 'deskrun agent' pauses and resumes runner installations by.
+Only the given flags are changed; without flags the current policies are shown.
+
+Examples:
+  # Pause while on battery or while the load per CPU is above 1.5
+  deskrun agent configure --pause-on-battery --max-load 1.5
+
+  # Only pause while the battery is below 30%, and only my-runner
+  deskrun agent configure --pause-on-battery=false --min-battery 30 --installation my-runner
+
+  # Prune cache mounts above their max size once a day
+  deskrun agent configure --cache-gc-interval 24h
+
+  # Remove all policies
+  deskrun agent configure --disable
+`,
+	Args: cobra.NoArgs,
+	RunE: runAgentConfigure,
+}
+
+func init() {
+	agentConfigureCmd.Flags().BoolVar(&agentPauseOnBattery, "pause-on-battery", false, "Pause while the host runs on battery")
+	agentConfigureCmd.Flags().IntVar(&agentMinBattery, "min-battery", 0, "Pause while on battery with a charge below this percentage (0 to disable)")
+	agentConfigureCmd.Flags().Float64Var(&agentMaxLoad, "max-load", 0, "Pause while the 1-minute load average per CPU is above this (0 to disable)")
+	agentConfigureCmd.Flags().StringSliceVar(&agentInstallations, "installation", []string{}, "Installation to manage (can be specified multiple times, all installations by default)")
+	agentConfigureCmd.Flags().StringVar(&agentInterval, "interval", "", "How often to check the host, e.g. 1m (default 30s)")
+	agentConfigureCmd.Flags().StringVar(&agentCacheGCInterval, "cache-gc-interval", "", "How often to prune cache mounts above their max size, e.g. 24h (empty to disable)")
+	agentConfigureCmd.Flags().BoolVar(&agentDisable, "disable", false, "Remove all policies")
+	agentConfigureCmd.MarkFlagsMutuallyExclusive("disable", "pause-on-battery")
+	agentConfigureCmd.MarkFlagsMutuallyExclusive("disable", "min-battery")
+	agentConfigureCmd.MarkFlagsMutuallyExclusive("disable", "max-load")
+	agentConfigureCmd.MarkFlagsMutuallyExclusive("disable", "installation")
+	agentConfigureCmd.MarkFlagsMutuallyExclusive("disable", "interval")
+	agentConfigureCmd.MarkFlagsMutuallyExclusive("disable", "cache-gc-interval")
+
+	agentCmd.AddCommand(agentConfigureCmd)
+	rootCmd.AddCommand(agentCmd)
+}
+
+func runAgentConfigure(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if agentDisable {
+		if err := configMgr.SetAgent(nil); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Println("✓ Agent policies removed")
+		return nil
+	}
+
+	policy := &types.AgentConfig{}
+	if current := configMgr.GetConfig().Agent; current != nil {
+		*policy = *current
+	}
+	if cmd.Flags().NFlag() == 0 {
+		fmt.Printf("Agent policies: %s\n", describeAgentPolicy(policy))
+		return nil
+	}
+
+	applyAgentFlags(cmd.Flags(), policy)
+	if err := validateAgentConfig(configMgr.GetConfig(), policy); err != nil {
+		return err
+	}
+
+	if err := configMgr.SetAgent(policy); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("✓ Agent policies set: %s\n", describeAgentPolicy(policy))
+	return nil
+}
+
+// applyAgentFlags sets the policies of the 'agent configure' flags that were given
+func applyAgentFlags(flags *pflag.FlagSet, policy *types.AgentConfig) {
+	if flags.Changed("pause-on-battery") {
+		policy.PauseOnBattery = agentPauseOnBattery
+	}
+	if flags.Changed("min-battery") {
+		policy.MinBattery = agentMinBattery
+	}
+	if flags.Changed("max-load") {
+		policy.MaxLoad = agentMaxLoad
+	}
+	if flags.Changed("installation") {
+		policy.Installations = agentInstallations
+	}
+	if flags.Changed("interval") {
+		policy.Interval = agentInterval
+	}
+	if flags.Changed("cache-gc-interval") {
+		policy.CacheGCInterval = agentCacheGCInterval
+	}
+}
+
+// validateAgentConfig checks the agent policies against the configured installations
+func validateAgentConfig(cfg *config.Config, policy *types.AgentConfig) error {
+	if policy.MinBattery < 0 || policy.MinBattery > 100 {
+		return fmt.Errorf("--min-battery must be between 0 and 100, got %d", policy.MinBattery)
+	}
+	if policy.MaxLoad < 0 {
+		return fmt.Errorf("--max-load must be 0 or more, got %g", policy.MaxLoad)
+	}
+	if _, err := agentCheckInterval(policy); err != nil {
+		return err
+	}
+	if _, err := cacheGCInterval(policy); err != nil {
+		return err
+	}
+	for _, name := range policy.Installations {
+		if cfg.Installations[name] == nil {
+			return fmt.Errorf("installation %s does not exist", name)
+		}
+	}
+	return nil
+}
+
+// agentCheckInterval returns how often the agent checks the host
+func agentCheckInterval(policy *types.AgentConfig) (time.Duration, error) {
+	if policy.Interval == "" {
+		return defaultAgentInterval, nil
+	}
+	interval, err := time.ParseDuration(policy.Interval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid --interval '%s': must be a positive duration, e.g. 30s", policy.Interval)
+	}
+	return interval, nil
+}
+
+// cacheGCInterval returns how often the agent prunes cache mounts, 0 if
+// it does not
+func cacheGCInterval(policy *types.AgentConfig) (time.Duration, error) {
+	if policy.CacheGCInterval == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(policy.CacheGCInterval)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid --cache-gc-interval '%s': must be a positive duration, e.g. 24h", policy.CacheGCInterval)
+	}
+	return interval, nil
+}
+
+// describeAgentPolicy summarizes the agent policies in a single line
+func describeAgentPolicy(policy *types.AgentConfig) string {
+	var rules []string
+	if policy.PauseOnBattery {
+		rules = append(rules, "pause on battery")
+	}
+	if policy.MinBattery > 0 {
+		rules = append(rules, fmt.Sprintf("pause on battery below %d%%", policy.MinBattery))
+	}
+	if policy.MaxLoad > 0 {
+		rules = append(rules, fmt.Sprintf("pause above a load of %g per CPU", policy.MaxLoad))
+	}
+	if policy.CacheGCInterval != "" {
+		rules = append(rules, fmt.Sprintf("prune caches every %s", policy.CacheGCInterval))
+	}
+	if len(rules) == 0 {
+		return "none"
+	}
+
+	installations := "all installations"
+	if len(policy.Installations) > 0 {
+		installations = strings.Join(policy.Installations, ", ")
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(rules, ", "), installations)
+}
+
+// agentPauseReason returns why the installations should be paused in the
+// given host state, or an empty string if they can run
+func agentPauseReason(policy *types.AgentConfig, state host.State) string {
+	switch {
+	case policy.PauseOnBattery && state.OnBattery:
+		return "running on battery"
+	case policy.MinBattery > 0 && state.OnBattery && state.BatteryPercent >= 0 && state.BatteryPercent < policy.MinBattery:
+		return fmt.Sprintf("battery at %d%%", state.BatteryPercent)
+	case policy.MaxLoad > 0 && state.Load > policy.MaxLoad:
+		return fmt.Sprintf("load of %.2f per CPU", state.Load)
+	}
+	return ""
+}
+
+// agentManagedInstallations returns the installations the agent policies apply
+// to, sorted by name
+func agentManagedInstallations(cfg *config.Config, policy *types.AgentConfig) []*types.RunnerInstallation {
+	var installations []*types.RunnerInstallation
+	for name, installation := range cfg.Installations {
+		if len(policy.Installations) == 0 || slices.Contains(policy.Installations, name) {
+			installations = append(installations, installation)
+		}
+	}
+	sort.Slice(installations, func(i, j int) bool {
+		return installations[i].Name < installations[j].Name
+	})
+	return installations
+}
+
+// agent pauses and resumes installations when the host enters or leaves the
+// state its policies pause installations in
+type agent struct {
+	// pausing is whether the policies called for pausing at the previous check
+	pausing bool
+	// paused are the installations the agent paused itself
+	paused map[string]bool
+	// lastCacheGC is when the agent last pruned cache mounts
+	lastCacheGC time.Time
+	// limits are the scheduled runner limits the agent last applied
+	limits map[string]runnerLimits
+}
+
+// runnerLimits are the minimum and maximum runners of a scale set
+type runnerLimits struct {
+	min, max int
+}
+
+func newAgent() *agent {
+	return &agent{paused: make(map[string]bool), limits: make(map[string]runnerLimits)}
+}
+
+// transition returns the installations to pause or resume for the pause reason
+// of the current check. Installations are only paused when the host enters
+// the paused state and only resumed when it leaves it, so manual pauses and
+// resumes in between are kept.
+func (a *agent) transition(reason string, installations []*types.RunnerInstallation) (pause, resume []*types.RunnerInstallation) {
+	pausing := reason != ""
+	defer func() { a.pausing = pausing }()
+
+	switch {
+	case pausing && !a.pausing:
+		for _, installation := range installations {
+			if !installation.Paused {
+				pause = append(pause, installation)
+			}
+		}
+	case !pausing && a.pausing:
+		for _, installation := range installations {
+			if installation.Paused && a.paused[installation.Name] {
+				resume = append(resume, installation)
+			}
+		}
+	}
+	return pause, resume
+}
+
+// scheduleChanges returns the installations with a schedule whose runner
+// limits at the given time differ from the ones the agent last applied, with
+// their new limits. Paused installations are skipped; resuming them applies
+// the limits of the schedule.
+func (a *agent) scheduleChanges(installations []*types.RunnerInstallation, now time.Time) map[string]runnerLimits {
+	changes := make(map[string]runnerLimits)
+	for _, installation := range installations {
+		if len(installation.Schedule) == 0 || installation.Paused {
+			delete(a.limits, installation.Name)
+			continue
+		}
+		minRunners, maxRunners := scheduledLimits(installation, now)
+		limits := runnerLimits{min: minRunners, max: maxRunners}
+		if current, ok := a.limits[installation.Name]; !ok || current != limits {
+			changes[installation.Name] = limits
+		}
+	}
+	return changes
+}
+
+func runAgent(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	policy := configMgr.GetConfig().Agent
+	if policy == nil {
+		if !hasSchedules(configMgr.GetConfig()) {
+			return fmt.Errorf("no agent policies or schedules configured, set them with 'deskrun agent configure' or 'deskrun edit --schedule'")
+		}
+		policy = &types.AgentConfig{}
+	}
+	interval, err := agentCheckInterval(policy)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.Info("Checking the host", "interval", interval, "policies", describeAgentPolicy(policy))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	a := newAgent()
+	for {
+		if err := a.check(ctx); err != nil {
+			slog.Warn("Agent check failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return a.resumeAll()
+		case <-ticker.C:
+		}
+	}
+}
+
+// check reads the host state and pauses or resumes the managed installations.
+// The configuration is reloaded on every check to pick up changed policies
+// and installations.
+func (a *agent) check(ctx context.Context) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	// Without policies nothing is paused anymore
+	policy := cfg.Agent
+	if policy == nil {
+		policy = &types.AgentConfig{}
+	}
+
+	a.applySchedules(ctx, cfg, time.Now())
+
+	state, err := host.ReadState(ctx)
+	if err != nil {
+		return err
+	}
+
+	reason := agentPauseReason(policy, state)
+	pause, resume := a.transition(reason, agentManagedInstallations(cfg, policy))
+	for _, installation := range pause {
+		if err := a.setPaused(ctx, configMgr, installation, true); err != nil {
+			slog.Warn("Failed to pause runner", "name", installation.Name, "error", err)
+			continue
+		}
+		slog.Info("Paused runner", "name", installation.Name, "reason", reason)
+	}
+	for _, installation := range resume {
+		if err := a.setPaused(ctx, configMgr, installation, false); err != nil {
+			slog.Warn("Failed to resume runner", "name", installation.Name, "error", err)
+			continue
+		}
+		slog.Info("Resumed runner", "name", installation.Name)
+	}
+
+	return a.collectCaches(ctx, cfg, policy)
+}
+
+// applySchedules scales the installations with a schedule to the runner
+// limits of the current window when they changed since the previous check
+func (a *agent) applySchedules(ctx context.Context, cfg *config.Config, now time.Time) {
+	installations := sortedInstallations(cfg.Installations)
+	changes := a.scheduleChanges(installations, now)
+	for _, installation := range installations {
+		limits, ok := changes[installation.Name]
+		if !ok {
+			continue
+		}
+		if err := scaleScheduled(ctx, cfg, installation, limits); err != nil {
+			slog.Warn("Failed to apply runner schedule", "name", installation.Name, "error", err)
+			continue
+		}
+		a.limits[installation.Name] = limits
+		slog.Info("Applied runner schedule", "name", installation.Name, "min", limits.min, "max", limits.max)
+	}
+}
+
+// scaleScheduled scales the deployed scale set of an installation to its
+// scheduled limits. Scale sets that are not deployed get them on the next
+// 'deskrun up'.
+func scaleScheduled(ctx context.Context, cfg *config.Config, installation *types.RunnerInstallation, limits runnerLimits) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	runnerMgr, err := clusterRunnerManager(ctx, cfg, cfg.ClusterFor(installation))
+	if err != nil || runnerMgr == nil {
+		return err
+	}
+	deployed, err := runnerMgr.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list runners: %w", err)
+	}
+	if !slices.Contains(deployed, installation.Name) {
+		return nil
+	}
+	return runnerMgr.ScaleScaleSet(ctx, installation.Name, limits.min, limits.max)
+}
+
+// hasSchedules returns true if any installation has a runner limit schedule
+func hasSchedules(cfg *config.Config) bool {
+	for _, installation := range cfg.Installations {
+		if len(installation.Schedule) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// collectCaches prunes the cache mounts of the managed installations when the
+// cache gc interval passed since the previous run
+func (a *agent) collectCaches(ctx context.Context, cfg *config.Config, policy *types.AgentConfig) error {
+	interval, err := cacheGCInterval(policy)
+	if err != nil || interval == 0 || time.Since(a.lastCacheGC) < interval {
+		return err
+	}
+	a.lastCacheGC = time.Now()
+
+	slog.Info("Pruning cache mounts")
+	return garbageCollectCaches(ctx, cfg, agentManagedInstallations(cfg, policy), false, os.Stdout)
+}
+
+// resumeAll resumes the installations the agent paused that are still paused
+func (a *agent) resumeAll() error {
+	if len(a.paused) == 0 {
+		return nil
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	names := make([]string, 0, len(a.paused))
+	for name := range a.paused {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		installation, err := configMgr.GetInstallation(name)
+		if err != nil || !installation.Paused {
+			continue
+		}
+		if err := a.setPaused(ctx, configMgr, installation, false); err != nil {
+			return fmt.Errorf("failed to resume %s: %w", name, err)
+		}
+		slog.Info("Resumed runner", "name", name)
+	}
+	return nil
+}
+
+// setPaused pauses or resumes an installation and records which installations
+// the agent paused
+func (a *agent) setPaused(ctx context.Context, configMgr *config.Manager, installation *types.RunnerInstallation, paused bool) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	cfg := configMgr.GetConfig()
+	runnerMgr, err := clusterRunnerManager(ctx, cfg, cfg.ClusterFor(installation))
+	if err != nil {
+		return err
+	}
+	if err := setPaused(ctx, runnerMgr, configMgr, installation, paused); err != nil {
+		return err
+	}
+
+	if paused {
+		a.paused[installation.Name] = true
+	} else {
+		delete(a.paused, installation.Name)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/host"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Agent Command", func() {
+	DescribeTable("agentPauseReason",
+		func(policy types.AgentConfig, state host.State, expected string) {
+			Expect(agentPauseReason(&policy, state)).To(Equal(expected))
+		},
+		Entry("on battery", types.AgentConfig{PauseOnBattery: true}, host.State{OnBattery: true, BatteryPercent: 90}, "running on battery"),
+		Entry("on AC", types.AgentConfig{PauseOnBattery: true}, host.State{BatteryPercent: 90}, ""),
+		Entry("battery below the minimum", types.AgentConfig{MinBattery: 30}, host.State{OnBattery: true, BatteryPercent: 20}, "battery at 20%"),
+		Entry("battery above the minimum", types.AgentConfig{MinBattery: 30}, host.State{OnBattery: true, BatteryPercent: 40}, ""),
+		Entry("charging below the minimum", types.AgentConfig{MinBattery: 30}, host.State{BatteryPercent: 20}, ""),
+		Entry("load above the maximum", types.AgentConfig{MaxLoad: 1.5}, host.State{Load: 2}, "load of 2.00 per CPU"),
+		Entry("load below the maximum", types.AgentConfig{MaxLoad: 1.5}, host.State{Load: 1}, ""),
+		Entry("no policies", types.AgentConfig{}, host.State{OnBattery: true, Load: 8}, ""),
+	)
+
+	Describe("agent transitions", func() {
+		var (
+			a             *agent
+			running       *types.RunnerInstallation
+			paused        *types.RunnerInstallation
+			installations []*types.RunnerInstallation
+		)
+
+		BeforeEach(func() {
+			a = newAgent()
+			running = &types.RunnerInstallation{Name: "running"}
+			paused = &types.RunnerInstallation{Name: "paused", Paused: true}
+			installations = []*types.RunnerInstallation{paused, running}
+		})
+
+		It("pauses running installations when the host enters the paused state", func() {
+			pause, resume := a.transition("running on battery", installations)
+			Expect(pause).To(ConsistOf(running))
+			Expect(resume).To(BeEmpty())
+		})
+
+		It("does not pause again while the host stays in the paused state", func() {
+			a.transition("running on battery", installations)
+
+			// The user resumed the installation manually
+			pause, _ := a.transition("running on battery", installations)
+			Expect(pause).To(BeEmpty())
+		})
+
+		It("only resumes the installations it paused", func() {
+			a.transition("running on battery", installations)
+			running.Paused = true
+			a.paused[running.Name] = true
+
+			pause, resume := a.transition("", installations)
+			Expect(pause).To(BeEmpty())
+			Expect(resume).To(ConsistOf(running))
+		})
+
+		It("does nothing while the host stays in the running state", func() {
+			pause, resume := a.transition("", installations)
+			Expect(pause).To(BeEmpty())
+			Expect(resume).To(BeEmpty())
+		})
+	})
+
+	Describe("scheduleChanges", func() {
+		var (
+			a         *agent
+			scheduled *types.RunnerInstallation
+			now       time.Time
+		)
+
+		BeforeEach(func() {
+			a = newAgent()
+			scheduled = &types.RunnerInstallation{
+				Name:       "scheduled",
+				MinRunners: 0,
+				MaxRunners: 1,
+				Schedule:   []types.ScheduleWindow{{Days: "*", Start: "09:00", End: "18:00", MinRunners: 2, MaxRunners: 8}},
+			}
+			now = time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+		})
+
+		It("returns the limits of installations the schedule was not applied to yet", func() {
+			unscheduled := &types.RunnerInstallation{Name: "unscheduled", MaxRunners: 5}
+			changes := a.scheduleChanges([]*types.RunnerInstallation{scheduled, unscheduled}, now)
+			Expect(changes).To(Equal(map[string]runnerLimits{"scheduled": {min: 2, max: 8}}))
+		})
+
+		It("only returns limits that changed since they were applied", func() {
+			a.limits["scheduled"] = runnerLimits{min: 2, max: 8}
+			Expect(a.scheduleChanges([]*types.RunnerInstallation{scheduled}, now)).To(BeEmpty())
+
+			changes := a.scheduleChanges([]*types.RunnerInstallation{scheduled}, now.Add(9*time.Hour))
+			Expect(changes).To(Equal(map[string]runnerLimits{"scheduled": {min: 0, max: 1}}))
+		})
+
+		It("skips paused installations", func() {
+			a.limits["scheduled"] = runnerLimits{min: 0, max: 1}
+			scheduled.Paused = true
+			Expect(a.scheduleChanges([]*types.RunnerInstallation{scheduled}, now)).To(BeEmpty())
+			Expect(a.limits).NotTo(HaveKey("scheduled"))
+		})
+	})
+
+	Describe("agentManagedInstallations", func() {
+		cfg := &config.Config{Installations: map[string]*types.RunnerInstallation{
+			"b": {Name: "b"},
+			"a": {Name: "a"},
+		}}
+
+		It("returns all installations sorted without an installation filter", func() {
+			installations := agentManagedInstallations(cfg, &types.AgentConfig{})
+			Expect(installations).To(HaveLen(2))
+			Expect(installations[0].Name).To(Equal("a"))
+			Expect(installations[1].Name).To(Equal("b"))
+		})
+
+		It("returns the configured installations", func() {
+			installations := agentManagedInstallations(cfg, &types.AgentConfig{Installations: []string{"b"}})
+			Expect(installations).To(HaveLen(1))
+			Expect(installations[0].Name).To(Equal("b"))
+		})
+	})
+
+	Describe("validateAgentConfig", func() {
+		cfg := &config.Config{Installations: map[string]*types.RunnerInstallation{"my-runner": {Name: "my-runner"}}}
+
+		It("accepts valid policies", func() {
+			policy := &types.AgentConfig{PauseOnBattery: true, MaxLoad: 1.5, Interval: "1m", Installations: []string{"my-runner"}}
+			Expect(validateAgentConfig(cfg, policy)).To(Succeed())
+		})
+
+		It("rejects unknown installations", func() {
+			policy := &types.AgentConfig{Installations: []string{"other"}}
+			Expect(validateAgentConfig(cfg, policy)).To(MatchError("installation other does not exist"))
+		})
+
+		It("rejects a battery percentage above 100", func() {
+			policy := &types.AgentConfig{MinBattery: 120}
+			Expect(validateAgentConfig(cfg, policy)).To(MatchError("--min-battery must be between 0 and 100, got 120"))
+		})
+
+		It("rejects invalid intervals", func() {
+			policy := &types.AgentConfig{Interval: "-5s"}
+			Expect(validateAgentConfig(cfg, policy)).To(MatchError(ContainSubstring("invalid --interval '-5s'")))
+		})
+
+		It("rejects invalid cache gc intervals", func() {
+			policy := &types.AgentConfig{CacheGCInterval: "daily"}
+			Expect(validateAgentConfig(cfg, policy)).To(MatchError(ContainSubstring("invalid --cache-gc-interval 'daily'")))
+		})
+	})
+
+	It("describes the policies", func() {
+		policy := &types.AgentConfig{PauseOnBattery: true, MaxLoad: 1.5, Installations: []string{"my-runner"}}
+		Expect(describeAgentPolicy(policy)).To(Equal("pause on battery, pause above a load of 1.5 per CPU (my-runner)"))
+		Expect(describeAgentPolicy(&types.AgentConfig{})).To(Equal("none"))
+		Expect(describeAgentPolicy(&types.AgentConfig{CacheGCInterval: "24h"})).To(Equal("prune caches every 24h (all installations)"))
+	})
+})
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyFile   string
+	applyDryRun bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply -f <file>",
+	Short: "Apply a declarative description of clusters and runners",
+	Long: `Apply a YAML file describing clusters and runner installations, e.g. a
+version-controlled deskrun.yaml shared across machines.
+
+The clusters and installations of the config are replaced with those of the
+file: missing ones are created, changed ones updated and the ones not in the
+file deleted. The runners are then deployed like 'deskrun up' does, including
+the removal of runners that are no longer described. Clusters themselves are
+never deleted; use 'deskrun cluster delete' for that.
+
+The file uses the schema of the config file (see 'deskrun config validate'):
+
+  version: 1
+  clusters:
+    deskrun:
+      cache_server: {}
+  installations:
+    my-runner:
+      Repository: https://github.com/owner/repo
+      ContainerMode: kubernetes
+      MinRunners: 1
+      MaxRunners: 3
+      Instances: 1
+      AuthType: pat
+
+AuthValue can be left out for installations that are already configured, so
+tokens don't need to be committed. Applying the same file again is a no-op
+for the config.
+
+Examples:
+  deskrun apply -f deskrun.yaml
+  deskrun apply -f deskrun.yaml --dry-run   # Only show the changes
+`,
+	Args: cobra.NoArgs,
+	RunE: runApply,
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "", "YAML file describing the clusters and runner installations")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show the changes without applying them")
+	if err := applyCmd.MarkFlagRequired("file"); err != nil {
+		panic(err)
+	}
+	rootCmd.AddCommand(applyCmd)
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	// Load config
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	manifest, err := config.ReadManifest(applyFile)
+	if err != nil {
+		return err
+	}
+
+	if problems := validateInstallations(manifest.Installations); len(problems) > 0 {
+		printConfigProblems(os.Stdout, applyFile, len(manifest.Installations), problems)
+		return fmt.Errorf("%s has %d problem(s)", applyFile, len(problems))
+	}
+
+	changes, err := cfg.PlanManifest(manifest)
+	if err != nil {
+		return err
+	}
+	printManifestChanges(os.Stdout, changes)
+	if applyDryRun {
+		return nil
+	}
+
+	controllerVersion, err := resolveControllerVersion("", cfg.ControllerVersion)
+	if err != nil {
+		return err
+	}
+	applyOpts, err := kappApplyOptions(cfg, 0, false)
+	if err != nil {
+		return err
+	}
+
+	// Clusters that lose all their installations are visited as well, so their runners are removed
+	clusters := cfg.ClusterNames()
+	if err := configMgr.ApplyManifest(manifest); err != nil {
+		return fmt.Errorf("failed to apply %s: %w", applyFile, err)
+	}
+	clusters = mergeClusterNames(clusters, cfg.ClusterNames())
+
+	for _, name := range clusters {
+		fmt.Println()
+		if err := upClusterInstallations(cfg, name, controllerVersion, applyOpts); err != nil {
+			return fmt.Errorf("failed to deploy to cluster '%s': %w", name, err)
+		}
+	}
+
+	fmt.Println("\nApply complete!")
+	return nil
+}
+
+// printManifestChanges displays the config changes of applying a manifest
+func printManifestChanges(w io.Writer, changes []config.ManifestChange) {
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintln(w, "Config is up to date")
+		return
+	}
+
+	symbols := map[config.ChangeAction]string{
+		config.ChangeCreate: "+",
+		config.ChangeUpdate: "~",
+		config.ChangeDelete: "-",
+	}
+	_, _ = fmt.Fprintln(w, "Config changes:")
+	for _, change := range changes {
+		_, _ = fmt.Fprintf(w, "  %s %s %s\n", symbols[change.Action], change.Kind, change.Name)
+	}
+}
+
+// mergeClusterNames returns the sorted union of two lists of cluster names
+func mergeClusterNames(a, b []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range append(append([]string(nil), a...), b...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
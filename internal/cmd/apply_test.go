@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+
+	"github.com/rkoster/deskrun/internal/config"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Apply", func() {
+	Describe("printManifestChanges", func() {
+		It("should list the changes with their action", func() {
+			var buf bytes.Buffer
+			printManifestChanges(&buf, []config.ManifestChange{
+				{Action: config.ChangeCreate, Kind: "cluster", Name: "gpu"},
+				{Action: config.ChangeUpdate, Kind: "installation", Name: "my-runner"},
+				{Action: config.ChangeDelete, Kind: "installation", Name: "old-runner"},
+			})
+			Expect(buf.String()).To(Equal("Config changes:\n" +
+				"  + cluster gpu\n" +
+				"  ~ installation my-runner\n" +
+				"  - installation old-runner\n"))
+		})
+
+		It("should report an up to date config", func() {
+			var buf bytes.Buffer
+			printManifestChanges(&buf, nil)
+			Expect(buf.String()).To(Equal("Config is up to date\n"))
+		})
+	})
+
+	Describe("mergeClusterNames", func() {
+		It("should return the sorted union", func() {
+			Expect(mergeClusterNames([]string{"deskrun", "old"}, []string{"gpu", "deskrun"})).
+				To(Equal([]string{"deskrun", "gpu", "old"}))
+		})
+	})
+})
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var (
+	cacheCluster string
+
+	cacheImage    string
+	cacheHostPath string
+	cacheSize     string
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the in-cluster actions cache server and cache mounts",
+	Long: `Manage the in-cluster GitHub Actions cache server and the hostPath cache
+mounts of runner installations.
+
+When enabled, a cache server implementing the GitHub Actions cache API runs in
+the arc-systems namespace of the cluster and all runners of the cluster use it
+through ACTIONS_CACHE_URL, so actions/cache stores and restores caches on local
+disk instead of GitHub. 'deskrun cache status' shows the disk usage of cache
+mounts and 'deskrun cache gc' prunes the ones that grew above their max size.
+
+By default the commands operate on the default cluster. Use --cluster to
+manage one of the named clusters installations are pinned to.`,
+}
+
+var cacheEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Deploy the actions cache server",
+	Long: `Deploy the actions cache server and point the runners of the cluster at it.
+
+The cache is stored in a hostPath volume on the kind node, which survives
+cache server restarts and 'deskrun cache disable'. Run 'deskrun up' to
+redeploy the runners with the cache server.
+
+Examples:
+  deskrun cache enable
+  deskrun cache enable --size 100Gi
+  deskrun cache enable --host-path /host-cache/deskrun/actions-cache   # Store the cache on the host
+  deskrun cache enable --cluster deskrun-gpu
+`,
+	RunE: runCacheEnable,
+}
+
+var cacheDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Remove the actions cache server",
+	Long: `Remove the actions cache server; runners use the GitHub hosted cache again
+after the next 'deskrun up'. The cached data is kept on the kind node.`,
+	RunE: runCacheDisable,
+}
+
+func init() {
+	cacheEnableCmd.Flags().StringVar(&cacheImage, "image", "", "Cache server image (defaults to ghcr.io/falcondev-oss/github-actions-cache-server)")
+	cacheEnableCmd.Flags().StringVar(&cacheHostPath, "host-path", "", "Directory on the kind node to store the cache in (defaults to /var/lib/deskrun/actions-cache)")
+	cacheEnableCmd.Flags().StringVar(&cacheSize, "size", "", "Capacity of the cache volume (defaults to 50Gi)")
+
+	cacheCmd.PersistentFlags().StringVar(&cacheCluster, "cluster", "", "Name of the cluster to manage (defaults to the default cluster)")
+	cacheCmd.AddCommand(cacheEnableCmd)
+	cacheCmd.AddCommand(cacheDisableCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func runCacheEnable(cmd *cobra.Command, args []string) error {
+	cacheServer := &types.CacheServerConfig{
+		Image:    cacheImage,
+		HostPath: cacheHostPath,
+		Size:     cacheSize,
+	}
+	if err := validateCacheServerConfig(cacheServer); err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	clusterName := resolveClusterName(configMgr.GetConfig(), cacheCluster)
+	if err := configMgr.SetClusterCacheServer(clusterName, cacheServer); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	clusterMgr := newClusterManager(configMgr.GetConfig(), clusterName)
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		fmt.Printf("Cache server enabled for cluster '%s'; it is deployed when the cluster is created with 'deskrun up'\n", clusterName)
+		return nil
+	}
+
+	slog.Info("Deploying actions cache server", "cluster", clusterName)
+	if err := runner.NewManager(clusterMgr).EnableCacheServer(ctx, cacheServer); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Cache server available at %s\n", runner.ActionsCacheURL)
+	fmt.Println("\nTo point the runners at the cache server, run:")
+	fmt.Println("  deskrun up")
+	return nil
+}
+
+func runCacheDisable(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	clusterName := resolveClusterName(configMgr.GetConfig(), cacheCluster)
+	if err := configMgr.SetClusterCacheServer(clusterName, nil); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	clusterMgr := newClusterManager(configMgr.GetConfig(), clusterName)
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if exists {
+		if err := runner.NewManager(clusterMgr).DisableCacheServer(ctx); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✓ Cache server of cluster '%s' disabled\n", clusterName)
+	fmt.Println("\nTo point the runners back at the GitHub hosted cache, run:")
+	fmt.Println("  deskrun up")
+	return nil
+}
+
+// validateCacheServerConfig checks the --host-path and --size flags of cache enable
+func validateCacheServerConfig(cacheServer *types.CacheServerConfig) error {
+	if cacheServer.HostPath != "" && !filepath.IsAbs(cacheServer.HostPath) {
+		return fmt.Errorf("invalid --host-path '%s', must be an absolute path", cacheServer.HostPath)
+	}
+	if cacheServer.Size != "" {
+		if _, err := resource.ParseQuantity(cacheServer.Size); err != nil {
+			return fmt.Errorf("invalid --size '%s': %w", cacheServer.Size, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"syscall"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var cacheGCDryRun bool
+
+var cacheGCCmd = &cobra.Command{
+	Use:   "gc [installation...]",
+	Short: "Prune cache mounts above their max size",
+	Long: `Prune the hostPath cache mounts of runner installations that grew above
+their max size. Set the max size with 'deskrun add --max-size' or the MaxSize
+field of a mount in the configuration; mounts without one are left alone.
+
+Docker data directories (mounts at /var/lib/docker) are pruned with
+'docker builder prune' and, when that is not enough, 'docker image prune'.
+Other caches lose their least recently accessed files until they fit.
+
+Caches are only pruned while no job uses them: caches of runners that run a
+job are skipped, and Docker data directories are skipped while any runner
+using them exists, as its Docker daemon holds the directory. Pause an
+installation with 'deskrun pause' to prune its Docker cache.
+
+Without arguments, the caches of all installations are pruned. Use
+'deskrun agent configure --cache-gc-interval' to prune them on a schedule.
+
+Examples:
+  deskrun cache gc --dry-run
+  deskrun cache gc my-runner
+`,
+	ValidArgsFunction: completeInstallations,
+	RunE:              runCacheGC,
+}
+
+func init() {
+	cacheGCCmd.Flags().BoolVar(&cacheGCDryRun, "dry-run", false, "Show what would be pruned without removing anything")
+
+	cacheCmd.AddCommand(cacheGCCmd)
+}
+
+func runCacheGC(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	installations, err := cacheGCInstallations(cfg, args, cacheCluster)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return garbageCollectCaches(ctx, cfg, installations, cacheGCDryRun, os.Stdout)
+}
+
+// cacheGCInstallations returns the named installations, or all installations
+// of the given cluster (all clusters if empty), sorted by name
+func cacheGCInstallations(cfg *config.Config, names []string, clusterName string) ([]*types.RunnerInstallation, error) {
+	var installations []*types.RunnerInstallation
+	for _, name := range names {
+		installation := cfg.Installations[name]
+		if installation == nil {
+			return nil, fmt.Errorf("installation %s does not exist", name)
+		}
+		installations = append(installations, installation)
+	}
+	if len(names) == 0 {
+		for _, installation := range cfg.Installations {
+			installations = append(installations, installation)
+		}
+	}
+
+	if clusterName != "" {
+		installations = slices.DeleteFunc(installations, func(installation *types.RunnerInstallation) bool {
+			return cfg.ClusterFor(installation) != clusterName
+		})
+	}
+
+	sort.Slice(installations, func(i, j int) bool {
+		return installations[i].Name < installations[j].Name
+	})
+	return installations, nil
+}
+
+// cacheGCTarget is a cache directory with a max size and the scale sets
+// mounting it. Directories with an explicit host path can be shared.
+type cacheGCTarget struct {
+	Dir       runner.CacheDir
+	ScaleSets []string
+}
+
+// cacheGCTargets returns the cache directories with a max size of the
+// installations, one per host path
+func cacheGCTargets(installations []*types.RunnerInstallation) ([]*cacheGCTarget, error) {
+	var targets []*cacheGCTarget
+	byHostPath := make(map[string]*cacheGCTarget)
+	for _, installation := range installations {
+		dirs, err := runner.CacheDirs(installation)
+		if err != nil {
+			return nil, fmt.Errorf("installation %s: %w", installation.Name, err)
+		}
+
+		for _, dir := range dirs {
+			target := byHostPath[dir.HostPath]
+			if target == nil {
+				target = &cacheGCTarget{Dir: dir}
+				byHostPath[dir.HostPath] = target
+				targets = append(targets, target)
+			}
+			if !slices.Contains(target.ScaleSets, dir.ScaleSet) {
+				target.ScaleSets = append(target.ScaleSets, dir.ScaleSet)
+			}
+			// The smallest max size of a shared directory wins
+			if dir.MaxSize > 0 && (target.Dir.MaxSize == 0 || dir.MaxSize < target.Dir.MaxSize) {
+				target.Dir.MaxSize = dir.MaxSize
+			}
+		}
+	}
+
+	return slices.DeleteFunc(targets, func(target *cacheGCTarget) bool {
+		return target.Dir.MaxSize == 0
+	}), nil
+}
+
+// cacheGCSkipReason returns why a cache directory cannot be pruned while the
+// given runners of the scale sets mounting it exist, or an empty string
+func cacheGCSkipReason(dir runner.CacheDir, runners []runner.EphemeralRunner) string {
+	if dir.IsDocker() && len(runners) > 0 {
+		return fmt.Sprintf("%d runner(s) use the Docker data directory, pause the installation first", len(runners))
+	}
+	for _, r := range runners {
+		if r.Busy() {
+			return fmt.Sprintf("runner %s is running a job", r.Name)
+		}
+	}
+	return ""
+}
+
+// garbageCollectCaches prunes the cache directories of the installations that
+// are above their max size, cluster by cluster
+func garbageCollectCaches(ctx context.Context, cfg *config.Config, installations []*types.RunnerInstallation, dryRun bool, out io.Writer) error {
+	byCluster := make(map[string][]*types.RunnerInstallation)
+	for _, installation := range installations {
+		clusterName := cfg.ClusterFor(installation)
+		byCluster[clusterName] = append(byCluster[clusterName], installationWithCacheVolumes(cfg, installation))
+	}
+
+	found := 0
+	for _, clusterName := range cfg.ClusterNames() {
+		targets, err := cacheGCTargets(byCluster[clusterName])
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			continue
+		}
+
+		runnerMgr, err := clusterRunnerManager(ctx, cfg, clusterName)
+		if err != nil {
+			return err
+		}
+		if runnerMgr == nil {
+			_, _ = fmt.Fprintf(out, "Skipping cluster '%s': it does not exist\n", clusterName)
+			continue
+		}
+
+		_, _ = fmt.Fprintf(out, "Caches of cluster '%s':\n", clusterName)
+		for _, target := range targets {
+			found++
+			if err := garbageCollectCache(ctx, runnerMgr, target, dryRun, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	if found == 0 {
+		_, _ = fmt.Fprintln(out, "No caches with a max size configured")
+	}
+	return nil
+}
+
+// garbageCollectCache prunes one cache directory unless a runner uses it
+func garbageCollectCache(ctx context.Context, runnerMgr *runner.Manager, target *cacheGCTarget, dryRun bool, out io.Writer) error {
+	name := fmt.Sprintf("%s %s", target.Dir.ScaleSet, target.Dir.Target)
+
+	// A dry run only measures the directory, which is safe while it is in use
+	if !dryRun {
+		var runners []runner.EphemeralRunner
+		for _, scaleSet := range target.ScaleSets {
+			scaleSetRunners, err := runnerMgr.EphemeralRunners(ctx, scaleSet)
+			if err != nil {
+				return err
+			}
+			runners = append(runners, scaleSetRunners...)
+		}
+		if reason := cacheGCSkipReason(target.Dir, runners); reason != "" {
+			_, _ = fmt.Fprintf(out, "  - %s: skipped, %s\n", name, reason)
+			return nil
+		}
+	}
+
+	result, err := runnerMgr.GarbageCollectCache(ctx, target.Dir, dryRun)
+	if err != nil {
+		_, _ = fmt.Fprintf(out, "  ✗ %s: %v\n", name, err)
+		return nil
+	}
+	_, _ = fmt.Fprintf(out, "  %s %s: %s\n", cacheGCMark(result), name, describeCacheGCResult(result, dryRun))
+	return nil
+}
+
+// cacheGCMark returns the status mark of a pruned cache
+func cacheGCMark(result *runner.CacheGCResult) string {
+	if result.Freed > 0 {
+		return "✓"
+	}
+	return "-"
+}
+
+// describeCacheGCResult summarizes the outcome of pruning a cache directory
+func describeCacheGCResult(result *runner.CacheGCResult, dryRun bool) string {
+	size := formatBytes(uint64(result.Size))
+	maxSize := formatBytes(uint64(result.Dir.MaxSize))
+	if result.Freed == 0 {
+		return fmt.Sprintf("%s, within %s", size, maxSize)
+	}
+
+	freed := formatBytes(uint64(result.Freed))
+	files := ""
+	if result.Removed > 0 {
+		files = fmt.Sprintf(" (%d files)", result.Removed)
+	}
+	if dryRun {
+		return fmt.Sprintf("%s, would free %s%s to fit %s", size, freed, files, maxSize)
+	}
+	return fmt.Sprintf("%s, freed %s%s to fit %s", size, freed, files, maxSize)
+}
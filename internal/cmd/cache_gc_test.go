@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Cache GC Command", func() {
+	Describe("cacheGCInstallations", func() {
+		cfg := &config.Config{
+			ClusterName: "deskrun",
+			Installations: map[string]*types.RunnerInstallation{
+				"b":   {Name: "b"},
+				"a":   {Name: "a"},
+				"gpu": {Name: "gpu", Cluster: "deskrun-gpu"},
+			},
+		}
+
+		It("returns all installations sorted by name", func() {
+			installations, err := cacheGCInstallations(cfg, nil, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installations).To(HaveLen(3))
+			Expect(installations[0].Name).To(Equal("a"))
+		})
+
+		It("filters installations by cluster", func() {
+			installations, err := cacheGCInstallations(cfg, nil, "deskrun-gpu")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installations).To(HaveLen(1))
+			Expect(installations[0].Name).To(Equal("gpu"))
+		})
+
+		It("rejects unknown installations", func() {
+			_, err := cacheGCInstallations(cfg, []string{"missing"}, "")
+			Expect(err).To(MatchError("installation missing does not exist"))
+		})
+	})
+
+	Describe("cacheGCTargets", func() {
+		It("returns the directories with a max size once per host path", func() {
+			installations := []*types.RunnerInstallation{
+				{Name: "a", Mounts: []types.Mount{{Source: "/shared", Target: "/cache", MaxSize: "2Gi"}, {Target: "/tmp/build"}}},
+				{Name: "b", Mounts: []types.Mount{{Source: "/shared", Target: "/cache", MaxSize: "1Gi"}}},
+			}
+
+			targets, err := cacheGCTargets(installations)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(targets).To(HaveLen(1))
+			Expect(targets[0].Dir.HostPath).To(Equal("/shared"))
+			Expect(targets[0].Dir.MaxSize).To(Equal(int64(1 << 30)))
+			Expect(targets[0].ScaleSets).To(Equal([]string{"a", "b"}))
+		})
+	})
+
+	Describe("cacheGCSkipReason", func() {
+		idle := runner.EphemeralRunner{Name: "idle"}
+		busy := runner.EphemeralRunner{Name: "busy", JobDisplayName: "build"}
+
+		It("skips caches of runners running a job", func() {
+			dir := runner.CacheDir{Target: "/cache"}
+			Expect(cacheGCSkipReason(dir, []runner.EphemeralRunner{idle})).To(BeEmpty())
+			Expect(cacheGCSkipReason(dir, []runner.EphemeralRunner{idle, busy})).To(Equal("runner busy is running a job"))
+		})
+
+		It("skips Docker data directories while runners exist", func() {
+			dir := runner.CacheDir{Target: "/var/lib/docker"}
+			Expect(cacheGCSkipReason(dir, nil)).To(BeEmpty())
+			Expect(cacheGCSkipReason(dir, []runner.EphemeralRunner{idle})).To(ContainSubstring("pause the installation first"))
+		})
+	})
+
+	It("describes the result of pruning a cache", func() {
+		result := &runner.CacheGCResult{Dir: runner.CacheDir{MaxSize: 1 << 30}, Size: 3 << 30, Freed: 2 << 30, Removed: 12}
+		Expect(describeCacheGCResult(result, false)).To(Equal("3.0 GiB, freed 2.0 GiB (12 files) to fit 1.0 GiB"))
+		Expect(describeCacheGCResult(result, true)).To(Equal("3.0 GiB, would free 2.0 GiB (12 files) to fit 1.0 GiB"))
+
+		result.Freed = 0
+		Expect(describeCacheGCResult(result, false)).To(Equal("3.0 GiB, within 1.0 GiB"))
+	})
+})
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var cacheStatusCmd = &cobra.Command{
+	Use:   "status [installation...]",
+	Short: "Show the disk usage of cache mounts",
+	Long: `Show the disk usage of the hostPath cache mounts of runner installations and
+of the hostPath persistent volumes in the cluster, e.g. the one of the actions
+cache server.
+
+For every directory on the cluster node the size, when a job last wrote to it
+and which installations mount it are shown, followed by the total per
+installation. Directories are measured with a short-lived pod on the node.
+
+Without arguments, the caches of all installations are shown.
+
+Examples:
+  deskrun cache status
+  deskrun cache status my-runner
+  deskrun cache status --cluster deskrun-gpu
+`,
+	ValidArgsFunction: completeInstallations,
+	RunE:              runCacheStatus,
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatusCmd)
+}
+
+// cacheStatusEntry is a cache directory on the node and what uses it
+type cacheStatusEntry struct {
+	HostPath string
+	// Users are the scale set mounts (scale-set:target) and claims using the directory
+	Users []string
+	// Installations are the installations mounting the directory
+	Installations []string
+	// MaxSize is the smallest max size of the mounts in bytes (0 = unbounded)
+	MaxSize int64
+	Usage   runner.CacheUsage
+}
+
+func runCacheStatus(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	installations, err := cacheGCInstallations(cfg, args, cacheCluster)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+
+	byCluster := make(map[string][]*types.RunnerInstallation)
+	for _, installation := range installations {
+		clusterName := cfg.ClusterFor(installation)
+		byCluster[clusterName] = append(byCluster[clusterName], installationWithCacheVolumes(cfg, installation))
+	}
+
+	for _, clusterName := range cfg.ClusterNames() {
+		if cacheCluster != "" && clusterName != cacheCluster {
+			continue
+		}
+		// Named installations only show the caches of their clusters
+		if len(args) > 0 && len(byCluster[clusterName]) == 0 {
+			continue
+		}
+
+		runnerMgr, err := clusterRunnerManager(ctx, cfg, clusterName)
+		if err != nil {
+			return err
+		}
+		if runnerMgr == nil {
+			fmt.Printf("Cluster '%s' does not exist\n\n", clusterName)
+			continue
+		}
+
+		var volumes []runner.CacheVolume
+		if len(args) == 0 {
+			if volumes, err = runnerMgr.CacheVolumes(ctx); err != nil {
+				return err
+			}
+		}
+		entries, err := cacheStatusEntries(byCluster[clusterName], volumes)
+		if err != nil {
+			return err
+		}
+
+		hostPaths := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			hostPaths = append(hostPaths, entry.HostPath)
+		}
+		usages, err := runnerMgr.CacheUsage(ctx, hostPaths)
+		if err != nil {
+			return err
+		}
+		for _, usage := range usages {
+			for _, entry := range entries {
+				if entry.HostPath == usage.HostPath {
+					entry.Usage = usage
+				}
+			}
+		}
+
+		printCacheStatus(os.Stdout, clusterName, entries)
+	}
+	return nil
+}
+
+// cacheStatusEntries returns the cache directories of the installations and
+// volumes, one per host path, sorted by host path
+func cacheStatusEntries(installations []*types.RunnerInstallation, volumes []runner.CacheVolume) ([]*cacheStatusEntry, error) {
+	byHostPath := make(map[string]*cacheStatusEntry)
+	entry := func(hostPath string) *cacheStatusEntry {
+		if byHostPath[hostPath] == nil {
+			byHostPath[hostPath] = &cacheStatusEntry{HostPath: hostPath}
+		}
+		return byHostPath[hostPath]
+	}
+
+	for _, installation := range installations {
+		dirs, err := runner.CacheDirs(installation)
+		if err != nil {
+			return nil, fmt.Errorf("installation %s: %w", installation.Name, err)
+		}
+		for _, dir := range dirs {
+			e := entry(dir.HostPath)
+			e.Users = append(e.Users, fmt.Sprintf("%s:%s", dir.ScaleSet, dir.Target))
+			if !slices.Contains(e.Installations, installation.Name) {
+				e.Installations = append(e.Installations, installation.Name)
+			}
+			if dir.MaxSize > 0 && (e.MaxSize == 0 || dir.MaxSize < e.MaxSize) {
+				e.MaxSize = dir.MaxSize
+			}
+		}
+	}
+
+	for _, volume := range volumes {
+		e := entry(volume.HostPath)
+		e.Users = append(e.Users, fmt.Sprintf("pvc %s/%s", volume.Namespace, volume.Name))
+	}
+
+	entries := make([]*cacheStatusEntry, 0, len(byHostPath))
+	for _, e := range byHostPath {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].HostPath < entries[j].HostPath
+	})
+	return entries, nil
+}
+
+// installationCacheSizes returns the total size of the cache directories
+// mounted by every installation, sorted by installation name
+func installationCacheSizes(entries []*cacheStatusEntry) ([]string, map[string]int64) {
+	sizes := make(map[string]int64)
+	for _, entry := range entries {
+		for _, name := range entry.Installations {
+			sizes[name] += entry.Usage.Size
+		}
+	}
+
+	names := make([]string, 0, len(sizes))
+	for name := range sizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, sizes
+}
+
+// printCacheStatus prints the cache directories of a cluster and the total
+// per installation
+func printCacheStatus(w io.Writer, clusterName string, entries []*cacheStatusEntry) {
+	_, _ = fmt.Fprintf(w, "Cluster: %s\n\n", clusterName)
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintf(w, "No cache mounts\n\n")
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "%-50s %-20s %-17s %s\n", "DIRECTORY", "SIZE", "LAST USED", "USED BY")
+	var total int64
+	for _, entry := range entries {
+		size, lastUsed := "-", "never"
+		if entry.Usage.Exists {
+			size = formatBytes(uint64(entry.Usage.Size))
+			if entry.MaxSize > 0 {
+				size += " / " + formatBytes(uint64(entry.MaxSize))
+			}
+			if !entry.Usage.LastUsed.IsZero() {
+				lastUsed = entry.Usage.LastUsed.Local().Format("2006-01-02 15:04")
+			}
+			total += entry.Usage.Size
+		}
+		_, _ = fmt.Fprintf(w, "%-50s %-20s %-17s %s\n", entry.HostPath, size, lastUsed, strings.Join(entry.Users, ", "))
+	}
+
+	names, sizes := installationCacheSizes(entries)
+	if len(names) > 0 {
+		_, _ = fmt.Fprintf(w, "\n%-30s %s\n", "INSTALLATION", "SIZE")
+		for _, name := range names {
+			_, _ = fmt.Fprintf(w, "%-30s %s\n", name, formatBytes(uint64(sizes[name])))
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\nTotal: %s\n\n", formatBytes(uint64(total)))
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Cache Status Command", func() {
+	installations := []*types.RunnerInstallation{
+		{Name: "a", Mounts: []types.Mount{{Source: "/shared", Target: "/root/.npm", MaxSize: "2Gi"}, {Target: "/var/lib/docker"}}},
+		{Name: "b", Mounts: []types.Mount{{Source: "/shared", Target: "/root/.npm"}}},
+	}
+	volumes := []runner.CacheVolume{{Namespace: "arc-systems", Name: "actions-cache", HostPath: "/var/lib/deskrun/actions-cache"}}
+
+	It("lists every directory once with the mounts and claims using it", func() {
+		entries, err := cacheStatusEntries(installations, volumes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(3))
+
+		Expect(entries[0].HostPath).To(Equal("/shared"))
+		Expect(entries[0].Users).To(Equal([]string{"a:/root/.npm", "b:/root/.npm"}))
+		Expect(entries[0].Installations).To(Equal([]string{"a", "b"}))
+		Expect(entries[0].MaxSize).To(Equal(int64(2 << 30)))
+
+		Expect(entries[1].HostPath).To(Equal("/tmp/github-runner-cache/a/mount-1"))
+		Expect(entries[2].Users).To(Equal([]string{"pvc arc-systems/actions-cache"}))
+	})
+
+	It("prints the usage and the total per installation", func() {
+		entries, err := cacheStatusEntries(installations, nil)
+		Expect(err).NotTo(HaveOccurred())
+		entries[0].Usage = runner.CacheUsage{HostPath: "/shared", Exists: true, Size: 1 << 30}
+		entries[1].Usage = runner.CacheUsage{HostPath: "/tmp/github-runner-cache/a/mount-1", Exists: true, Size: 3 << 30}
+
+		var out bytes.Buffer
+		printCacheStatus(&out, "deskrun", entries)
+		Expect(out.String()).To(ContainSubstring("1.0 GiB / 2.0 GiB"))
+		Expect(out.String()).To(MatchRegexp(`a\s+4.0 GiB`))
+		Expect(out.String()).To(MatchRegexp(`b\s+1.0 GiB`))
+		Expect(out.String()).To(ContainSubstring("Total: 4.0 GiB"))
+	})
+})
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Cache Command", func() {
+	DescribeTable("validating cache server settings",
+		func(cacheServer types.CacheServerConfig, expectedErrorMsg string) {
+			err := validateCacheServerConfig(&cacheServer)
+
+			if expectedErrorMsg == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectedErrorMsg)))
+			}
+		},
+		Entry("valid: defaults", types.CacheServerConfig{}, ""),
+		Entry("valid: host path and size", types.CacheServerConfig{HostPath: "/host-cache/deskrun/actions-cache", Size: "100Gi"}, ""),
+		Entry("invalid: relative host path", types.CacheServerConfig{HostPath: "cache"}, "invalid --host-path"),
+		Entry("invalid: size", types.CacheServerConfig{Size: "lots"}, "invalid --size"),
+	)
+
+	It("points installations of clusters with a cache server at it", func() {
+		cfg := &config.Config{
+			ClusterName: "deskrun",
+			Clusters: map[string]*types.ClusterSettings{
+				"deskrun": {Name: "deskrun", CacheServer: &types.CacheServerConfig{}},
+			},
+		}
+
+		installation := &types.RunnerInstallation{Name: "my-runner"}
+		Expect(installationToDeploy(cfg, installation).ActionsCacheURL).To(Equal(runner.ActionsCacheURL))
+		Expect(installation.ActionsCacheURL).To(BeEmpty(), "the configured installation should not be modified")
+
+		pinned := &types.RunnerInstallation{Name: "gpu-runner", Cluster: "deskrun-gpu"}
+		Expect(installationToDeploy(cfg, pinned)).To(BeIdenticalTo(pinned))
+	})
+})
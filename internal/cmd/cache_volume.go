@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var (
+	cacheVolumePath    string
+	cacheVolumeMaxSize string
+)
+
+var cacheCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create or update a shared cache volume",
+	Long: `Create or update a named cache volume that installations mount with
+'deskrun add --cache-volume' or 'deskrun edit --cache-volume'.
+
+All runners mounting a volume share one directory on the cluster node, at
+` + types.CacheVolumeRoot + `/<name>, which is ~/.cache/deskrun/volumes/<name>
+on the host. The directory is mounted at --path inside the runner container.
+
+Changing the path of a volume takes effect when the installations mounting it
+are redeployed with 'deskrun up'.
+
+Examples:
+  deskrun cache create npm --path /home/runner/.npm
+  deskrun cache create go-mod --path /home/runner/go/pkg/mod --max-size 10Gi
+  deskrun add my-runner --repository https://github.com/owner/repo --cache-volume npm
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCacheCreate,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the shared cache volumes",
+	RunE:  runCacheList,
+}
+
+var cacheDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a shared cache volume",
+	Long: `Delete a shared cache volume from the configuration. Volumes that
+installations still mount cannot be deleted; remove them from the
+installations with 'deskrun edit --cache-volume ""' first.
+
+The cached data is kept on the host in ~/.cache/deskrun/volumes/<name>.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCacheVolumes,
+	RunE:              runCacheDelete,
+}
+
+func init() {
+	cacheCreateCmd.Flags().StringVar(&cacheVolumePath, "path", "", "Path the volume is mounted at inside the runner container (required)")
+	cacheCreateCmd.Flags().StringVar(&cacheVolumeMaxSize, "max-size", "", "Size above which 'deskrun cache gc' prunes the volume, e.g. 20Gi")
+	if err := cacheCreateCmd.MarkFlagRequired("path"); err != nil {
+		panic(err)
+	}
+
+	cacheCmd.AddCommand(cacheCreateCmd)
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cacheDeleteCmd)
+}
+
+func runCacheCreate(cmd *cobra.Command, args []string) error {
+	volume := &types.CacheVolume{
+		Name:    args[0],
+		Path:    cacheVolumePath,
+		MaxSize: cacheVolumeMaxSize,
+	}
+	if err := validateCacheVolume(volume); err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// The new path must not clash with the mounts of the installations using the volume
+	cfg := configMgr.GetConfig()
+	updated := *cfg
+	updated.CacheVolumes = make(map[string]*types.CacheVolume, len(cfg.CacheVolumes)+1)
+	for name, existing := range cfg.CacheVolumes {
+		updated.CacheVolumes[name] = existing
+	}
+	updated.CacheVolumes[volume.Name] = volume
+	for _, installation := range cacheVolumeUsers(cfg, volume.Name) {
+		if err := validateCacheVolumeReferences(&updated, installation); err != nil {
+			return fmt.Errorf("installation %s: %w", installation.Name, err)
+		}
+	}
+
+	if err := configMgr.SetCacheVolume(volume); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("Cache volume '%s' saved to configuration (%s on the node)\n", volume.Name, volume.HostPath())
+
+	if users := cacheVolumeUsers(cfg, volume.Name); len(users) > 0 {
+		fmt.Println("\nTo remount the volume in the installations using it, run:")
+		fmt.Println("  deskrun up")
+	}
+	return nil
+}
+
+func runCacheList(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	volumes := cfg.SortedCacheVolumes()
+	if len(volumes) == 0 {
+		fmt.Println("No cache volumes configured")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tPATH\tMAX SIZE\tUSED BY")
+	for _, volume := range volumes {
+		maxSize := volume.MaxSize
+		if maxSize == "" {
+			maxSize = "-"
+		}
+		var users []string
+		for _, installation := range cacheVolumeUsers(cfg, volume.Name) {
+			users = append(users, installation.Name)
+		}
+		usedBy := strings.Join(users, ", ")
+		if usedBy == "" {
+			usedBy = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", volume.Name, volume.Path, maxSize, usedBy)
+	}
+	return w.Flush()
+}
+
+func runCacheDelete(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := configMgr.RemoveCacheVolume(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Cache volume '%s' removed from configuration\n", args[0])
+	return nil
+}
+
+// validateCacheVolume checks the name, path and max size of a cache volume
+func validateCacheVolume(volume *types.CacheVolume) error {
+	if errs := validation.IsDNS1123Label(volume.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid cache volume name '%s': %s", volume.Name, strings.Join(errs, ", "))
+	}
+	if !strings.HasPrefix(volume.Path, "/") {
+		return fmt.Errorf("invalid --path '%s', must be an absolute path", volume.Path)
+	}
+	if volume.Path == nixStorePath {
+		return fmt.Errorf("cache volume path /nix/store is not supported, concurrent jobs cannot share its overlay; use --mount /nix/store with --mode cached-privileged-kubernetes instead")
+	}
+	return validateMaxSize(volume.Path, volume.MaxSize)
+}
+
+// validateCacheVolumeReferences checks that the cache volumes an installation
+// mounts exist, are mounted once and don't share a path with its other mounts
+func validateCacheVolumeReferences(cfg *config.Config, installation *types.RunnerInstallation) error {
+	targets := make(map[string]bool)
+	for _, cachePath := range installation.CachePaths {
+		targets[cachePath.Target] = true
+	}
+	for _, mount := range installation.Mounts {
+		targets[mount.Target] = true
+	}
+
+	for i, name := range installation.CacheVolumes {
+		volume := cfg.CacheVolumes[name]
+		if volume == nil {
+			return fmt.Errorf("cache volume '%s' does not exist, create it with 'deskrun cache create'", name)
+		}
+		if slices.Contains(installation.CacheVolumes[:i], name) {
+			return fmt.Errorf("cache volume '%s' is mounted more than once", name)
+		}
+		if targets[volume.Path] {
+			return fmt.Errorf("cache volume '%s' is mounted at %s, which is already a mount target", name, volume.Path)
+		}
+		targets[volume.Path] = true
+	}
+	return nil
+}
+
+// validateCacheVolumeConfig checks the configured cache volumes and the
+// references of all installations to them, sorted by name
+func validateCacheVolumeConfig(cfg *config.Config) []error {
+	var problems []error
+	for _, volume := range cfg.SortedCacheVolumes() {
+		if err := validateCacheVolume(volume); err != nil {
+			problems = append(problems, fmt.Errorf("cache volume %s: %w", volume.Name, err))
+		}
+	}
+	for _, installation := range sortedInstallations(cfg.Installations) {
+		if err := validateCacheVolumeReferences(cfg, installation); err != nil {
+			problems = append(problems, fmt.Errorf("installation %s: %w", installation.Name, err))
+		}
+	}
+	return problems
+}
+
+// cacheVolumeUsers returns the installations mounting a cache volume, sorted by name
+func cacheVolumeUsers(cfg *config.Config, name string) []*types.RunnerInstallation {
+	var users []*types.RunnerInstallation
+	for _, installation := range sortedInstallations(cfg.Installations) {
+		if slices.Contains(installation.CacheVolumes, name) {
+			users = append(users, installation)
+		}
+	}
+	return users
+}
+
+// installationWithCacheVolumes returns the installation with the cache
+// volumes it references added to its mounts. Unknown volumes are left out;
+// 'deskrun config validate' reports them.
+func installationWithCacheVolumes(cfg *config.Config, installation *types.RunnerInstallation) *types.RunnerInstallation {
+	if len(installation.CacheVolumes) == 0 {
+		return installation
+	}
+
+	withVolumes := *installation
+	withVolumes.Mounts = slices.Clone(installation.Mounts)
+	for _, name := range installation.CacheVolumes {
+		if volume := cfg.CacheVolumes[name]; volume != nil {
+			withVolumes.Mounts = append(withVolumes.Mounts, volume.Mount())
+		}
+	}
+	return &withVolumes
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Cache Volumes", func() {
+	DescribeTable("validating cache volumes",
+		func(volume types.CacheVolume, expectedErrorMsg string) {
+			err := validateCacheVolume(&volume)
+
+			if expectedErrorMsg == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectedErrorMsg)))
+			}
+		},
+		Entry("valid: path", types.CacheVolume{Name: "npm", Path: "/home/runner/.npm"}, ""),
+		Entry("valid: max size", types.CacheVolume{Name: "go-mod", Path: "/home/runner/go/pkg/mod", MaxSize: "10Gi"}, ""),
+		Entry("invalid: name", types.CacheVolume{Name: "My_Cache", Path: "/cache"}, "invalid cache volume name"),
+		Entry("invalid: relative path", types.CacheVolume{Name: "npm", Path: ".npm"}, "must be an absolute path"),
+		Entry("invalid: nix store", types.CacheVolume{Name: "nix", Path: "/nix/store"}, "/nix/store is not supported"),
+		Entry("invalid: max size", types.CacheVolume{Name: "npm", Path: "/cache", MaxSize: "lots"}, "invalid max size"),
+	)
+
+	Describe("references of installations", func() {
+		var cfg *config.Config
+
+		BeforeEach(func() {
+			cfg = &config.Config{
+				CacheVolumes: map[string]*types.CacheVolume{
+					"npm": {Name: "npm", Path: "/home/runner/.npm", MaxSize: "5Gi"},
+				},
+			}
+		})
+
+		It("accepts existing volumes", func() {
+			installation := &types.RunnerInstallation{Name: "web", CacheVolumes: []string{"npm"}}
+			Expect(validateCacheVolumeReferences(cfg, installation)).To(Succeed())
+		})
+
+		It("rejects unknown volumes", func() {
+			installation := &types.RunnerInstallation{Name: "web", CacheVolumes: []string{"pip"}}
+			Expect(validateCacheVolumeReferences(cfg, installation)).To(MatchError(ContainSubstring("cache volume 'pip' does not exist")))
+		})
+
+		It("rejects volumes mounted twice", func() {
+			installation := &types.RunnerInstallation{Name: "web", CacheVolumes: []string{"npm", "npm"}}
+			Expect(validateCacheVolumeReferences(cfg, installation)).To(MatchError(ContainSubstring("mounted more than once")))
+		})
+
+		It("rejects volumes at the target of another mount", func() {
+			installation := &types.RunnerInstallation{
+				Name:         "web",
+				Mounts:       []types.Mount{{Target: "/home/runner/.npm"}},
+				CacheVolumes: []string{"npm"},
+			}
+			Expect(validateCacheVolumeReferences(cfg, installation)).To(MatchError(ContainSubstring("already a mount target")))
+		})
+
+		It("reports the problems of the whole config", func() {
+			cfg.Installations = map[string]*types.RunnerInstallation{
+				"web": {Name: "web", CacheVolumes: []string{"npm"}},
+				"api": {Name: "api", CacheVolumes: []string{"pip"}},
+			}
+			problems := validateCacheVolumeConfig(cfg)
+			Expect(problems).To(HaveLen(1))
+			Expect(problems[0]).To(MatchError(ContainSubstring("installation api: cache volume 'pip' does not exist")))
+		})
+
+		It("lists the installations using a volume", func() {
+			cfg.Installations = map[string]*types.RunnerInstallation{
+				"web": {Name: "web", CacheVolumes: []string{"npm"}},
+				"api": {Name: "api", CacheVolumes: []string{"npm"}},
+				"go":  {Name: "go"},
+			}
+			users := cacheVolumeUsers(cfg, "npm")
+			Expect(users).To(HaveLen(2))
+			Expect(users[0].Name).To(Equal("api"))
+			Expect(users[1].Name).To(Equal("web"))
+		})
+	})
+
+	It("mounts the cache volumes of installations when they are deployed", func() {
+		cfg := &config.Config{
+			CacheVolumes: map[string]*types.CacheVolume{
+				"npm": {Name: "npm", Path: "/home/runner/.npm", MaxSize: "5Gi"},
+			},
+		}
+		installation := &types.RunnerInstallation{
+			Name:         "web",
+			Mounts:       []types.Mount{{Target: "/var/lib/docker"}},
+			CacheVolumes: []string{"npm"},
+		}
+
+		deployed := installationToDeploy(cfg, installation)
+		Expect(deployed.Mounts).To(Equal([]types.Mount{
+			{Target: "/var/lib/docker"},
+			{Source: "/host-cache/deskrun/volumes/npm", Target: "/home/runner/.npm", Type: types.MountTypeDirectoryOrCreate, MaxSize: "5Gi"},
+		}))
+		Expect(installation.Mounts).To(HaveLen(1), "the configured installation should not be modified")
+
+		plain := &types.RunnerInstallation{Name: "plain"}
+		Expect(installationWithCacheVolumes(cfg, plain)).To(BeIdenticalTo(plain))
+	})
+
+	It("shares the directory of a volume between installations in cache gc", func() {
+		cfg := &config.Config{
+			CacheVolumes: map[string]*types.CacheVolume{
+				"npm": {Name: "npm", Path: "/home/runner/.npm", MaxSize: "5Gi"},
+			},
+		}
+		installations := []*types.RunnerInstallation{
+			installationWithCacheVolumes(cfg, &types.RunnerInstallation{Name: "api", CacheVolumes: []string{"npm"}}),
+			installationWithCacheVolumes(cfg, &types.RunnerInstallation{Name: "web", CacheVolumes: []string{"npm"}}),
+		}
+
+		targets, err := cacheGCTargets(installations)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(targets).To(HaveLen(1))
+		Expect(targets[0].Dir.HostPath).To(Equal("/host-cache/deskrun/volumes/npm"))
+		Expect(targets[0].ScaleSets).To(Equal([]string{"api", "web"}))
+	})
+})
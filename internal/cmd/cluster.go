@@ -3,45 +3,119 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/rkoster/deskrun/internal/cluster"
 	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/progress"
 	"github.com/rkoster/deskrun/pkg/types"
 	"github.com/spf13/cobra"
 )
 
+var (
+	clusterTarget string
+
+	clusterK8sVersion  string
+	clusterKubeContext string
+
+	clusterHTTPProxy  string
+	clusterHTTPSProxy string
+	clusterNoProxy    string
+	clusterClearProxy bool
+)
+
 var clusterCmd = &cobra.Command{
 	Use:   "cluster",
-	Short: "Manage the kind cluster",
-	Long:  `Manage the kind cluster used for running GitHub Actions runners.`,
+	Short: "Manage the local cluster",
+	Long: `Manage the local cluster used for running GitHub Actions runners.
+
+Clusters are provisioned with kind unless cluster_provider in the config
+selects k3d or minikube. By default the commands operate on the default cluster. Use --cluster to
+manage one of the named clusters installations are pinned to.`,
 }
 
 var clusterCreateCmd = &cobra.Command{
 	Use:   "create",
-	Short: "Create the kind cluster",
-	Long:  `Create a new kind cluster for running GitHub Actions runners.`,
-	RunE:  runClusterCreate,
+	Short: "Create the local cluster",
+	Long: `Create a new local cluster for running GitHub Actions runners.
+
+Use --k8s-version to run a specific Kubernetes release, e.g. to test workflows
+that deploy to Kubernetes against the version used in production. The version
+is saved, so 'deskrun up' recreates the cluster with the same version.
+
+Use --kube-context to deploy to an existing cluster (k3s, minikube, a remote
+cluster, ...) instead of kind. deskrun then never creates or deletes the
+cluster; it only checks that the context is reachable and deploys the ARC
+controller and runners to it.
+
+Examples:
+  deskrun cluster create
+  deskrun cluster create --k8s-version 1.32          # Latest supported 1.32 patch release
+  deskrun cluster create --k8s-version v1.33.1       # Exact release
+  deskrun cluster create --k8s-version ""            # Back to the kind default
+  deskrun cluster create --cluster k3s --kube-context default   # Use an existing cluster
+  deskrun cluster create --cluster k3s --kube-context ""        # Back to kind
+`,
+	RunE: runClusterCreate,
 }
 
 var clusterDeleteCmd = &cobra.Command{
 	Use:   "delete",
-	Short: "Delete the kind cluster",
-	Long:  `Delete the kind cluster and all associated resources.`,
+	Short: "Delete the local cluster",
+	Long:  `Delete the local cluster and all associated resources.`,
 	RunE:  runClusterDelete,
 }
 
 var clusterStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Check cluster status",
-	Long:  `Check if the kind cluster exists and is running.`,
+	Long:  `Check if the cluster exists and is running.`,
 	RunE:  runClusterStatus,
 }
 
+var clusterProxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Show or set the HTTP(S) proxy of the cluster",
+	Long: `Show or set the HTTP(S) proxy used to reach GitHub from the cluster.
+
+The proxy is injected as HTTP_PROXY/HTTPS_PROXY/NO_PROXY env vars into the ARC
+controller and into the runner and listener containers of every installation
+that does not set its own proxy with 'deskrun add/edit --https-proxy'.
+In-cluster destinations (the Kubernetes API and services) always bypass the proxy.
+
+Without flags, the current settings are shown. Run 'deskrun up' to redeploy the
+runners and 'deskrun upgrade-controller --force' to reconfigure an installed
+controller.
+
+Examples:
+  deskrun cluster proxy                                           # Show the proxy
+  deskrun cluster proxy --https-proxy http://proxy.corp:3128      # Set the proxy
+  deskrun cluster proxy --no-proxy .corp.example.com,10.0.0.0/8   # Bypass hosts
+  deskrun cluster proxy --clear                                   # Remove the proxy
+`,
+	RunE: runClusterProxy,
+}
+
 func init() {
+	clusterCreateCmd.Flags().StringVar(&clusterK8sVersion, "k8s-version", "", "Kubernetes version of the cluster, e.g. 1.33 or v1.33.4 (supported: "+strings.Join(cluster.SupportedKubernetesVersions(), ", ")+")")
+	clusterCreateCmd.Flags().StringVar(&clusterKubeContext, "kube-context", "", "Deploy to the existing cluster of this kubeconfig context instead of kind (empty to switch back to kind)")
+	clusterCreateCmd.MarkFlagsMutuallyExclusive("k8s-version", "kube-context")
+
+	clusterProxyCmd.Flags().StringVar(&clusterHTTPProxy, "http-proxy", "", "HTTP proxy URL (empty to unset)")
+	clusterProxyCmd.Flags().StringVar(&clusterHTTPSProxy, "https-proxy", "", "HTTPS proxy URL (empty to unset)")
+	clusterProxyCmd.Flags().StringVar(&clusterNoProxy, "no-proxy", "", "Comma-separated hosts, domains and CIDRs that bypass the proxy (empty to unset)")
+	clusterProxyCmd.Flags().BoolVar(&clusterClearProxy, "clear", false, "Remove the proxy settings")
+	clusterProxyCmd.MarkFlagsMutuallyExclusive("clear", "http-proxy")
+	clusterProxyCmd.MarkFlagsMutuallyExclusive("clear", "https-proxy")
+	clusterProxyCmd.MarkFlagsMutuallyExclusive("clear", "no-proxy")
+
+	clusterCmd.PersistentFlags().StringVar(&clusterTarget, "cluster", "", "Name of the cluster to manage (defaults to the default cluster)")
 	clusterCmd.AddCommand(clusterCreateCmd)
 	clusterCmd.AddCommand(clusterDeleteCmd)
 	clusterCmd.AddCommand(clusterStatusCmd)
+	clusterCmd.AddCommand(clusterProxyCmd)
 	rootCmd.AddCommand(clusterCmd)
 }
 
@@ -51,34 +125,67 @@ func runClusterCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	clusterName := resolveClusterName(configMgr.GetConfig(), clusterTarget)
+	if cmd.Flags().Changed("k8s-version") {
+		version := clusterK8sVersion
+		if version != "" {
+			if version, err = cluster.NormalizeKubernetesVersion(version); err != nil {
+				return err
+			}
+		}
+		if err := configMgr.SetClusterKubernetesVersion(clusterName, version); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+	k8sVersion := configMgr.GetConfig().ClusterKubernetesVersion(clusterName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if cmd.Flags().Changed("kube-context") {
+		if err := useKubeContext(ctx, configMgr, clusterName, clusterKubeContext); err != nil {
+			return err
+		}
+	}
+	if kubeContext := configMgr.GetConfig().ClusterKubeContext(clusterName); kubeContext != "" {
+		if !cmd.Flags().Changed("kube-context") {
+			if err := newClusterManager(configMgr.GetConfig(), clusterName).Create(ctx); err != nil {
+				return err
+			}
+			fmt.Printf("Cluster '%s' uses the existing cluster of kube context '%s'\n", clusterName, kubeContext)
+		}
+		return nil
+	}
+
 	// Detect available nix mounts
 	nixStore, nixSocket := cluster.DetectNixMounts()
 
 	// Log what we found
 	if nixStore != nil {
-		fmt.Printf("Detected Nix store at: %s\n", nixStore.HostPath)
+		slog.Info("Detected Nix store", "path", nixStore.HostPath)
 	}
 	if nixSocket != nil {
-		fmt.Printf("Detected Nix daemon socket at: %s\n", nixSocket.HostPath)
+		slog.Info("Detected Nix daemon socket", "path", nixSocket.HostPath)
 	}
 
 	// Detect deskrun cache directory
 	deskrunCache := cluster.DetectDeskrunCache()
 	if deskrunCache != nil {
-		fmt.Printf("Using deskrun cache directory: %s\n", deskrunCache.HostPath)
+		slog.Info("Using deskrun cache directory", "path", deskrunCache.HostPath)
 	}
 
 	clusterConfig := &types.ClusterConfig{
-		Name:         configMgr.GetConfig().ClusterName,
-		NixStore:     nixStore,
-		NixSocket:    nixSocket,
-		DeskrunCache: deskrunCache,
+		Name:              clusterName,
+		Provider:          configMgr.GetConfig().ClusterProvider,
+		KubernetesVersion: k8sVersion,
+		PortMappings:      configMgr.GetConfig().ClusterPortMappings(clusterName),
+		NixStore:          nixStore,
+		NixSocket:         nixSocket,
+		DeskrunCache:      deskrunCache,
+		WindowsDrives:     cluster.DetectWindowsDrives(configMgr.GetConfig().InstallationsForCluster(clusterName)),
 	}
 	clusterMgr := cluster.NewManager(clusterConfig)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
 	exists, err := clusterMgr.Exists(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to check cluster: %w", err)
@@ -86,16 +193,25 @@ func runClusterCreate(cmd *cobra.Command, args []string) error {
 
 	if exists {
 		fmt.Printf("Cluster '%s' already exists\n", clusterConfig.Name)
+		if cmd.Flags().Changed("k8s-version") {
+			fmt.Println("The Kubernetes version applies when the cluster is recreated:")
+			fmt.Println("  deskrun cluster delete && deskrun up")
+		}
 		return nil
 	}
 
-	fmt.Printf("Creating kind cluster '%s'", clusterConfig.Name)
+	label := fmt.Sprintf("Creating %s cluster '%s'", clusterMgr.ProviderName(), clusterConfig.Name)
+	if k8sVersion != "" {
+		label += " running Kubernetes " + k8sVersion
+	}
 	if nixStore != nil || nixSocket != nil {
-		fmt.Print(" with Nix support")
+		label += " with Nix support"
 	}
-	fmt.Println("...")
 
-	if err := clusterMgr.Create(ctx); err != nil {
+	phase := progress.Start("%s", label)
+	err = clusterMgr.Create(ctx)
+	phase.End(err)
+	if err != nil {
 		return fmt.Errorf("failed to create cluster: %w", err)
 	}
 
@@ -107,36 +223,78 @@ func runClusterCreate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// useKubeContext makes deskrun deploy a cluster to the existing cluster of a
+// kube context after checking that it is reachable. An empty context switches
+// the cluster back to kind.
+func useKubeContext(ctx context.Context, configMgr *config.Manager, clusterName, kubeContext string) error {
+	if kubeContext == "" {
+		if err := configMgr.SetClusterKubeContext(clusterName, ""); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("Cluster '%s' uses kind again\n", clusterName)
+		return nil
+	}
+
+	clusterMgr := cluster.NewManager(&types.ClusterConfig{Name: clusterName, KubeContext: kubeContext})
+	version, err := clusterMgr.ServerVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := configMgr.SetClusterKubeContext(clusterName, kubeContext); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("Cluster '%s' uses the existing cluster of kube context '%s' (Kubernetes %s)\n", clusterName, kubeContext, version)
+	fmt.Println("\nTo deploy the runners of the cluster, run:")
+	fmt.Printf("  deskrun up --cluster %s\n", clusterName)
+	return nil
+}
+
 func runClusterDelete(cmd *cobra.Command, args []string) error {
 	configMgr, err := config.NewManager()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	clusterConfig := &types.ClusterConfig{
-		Name: configMgr.GetConfig().ClusterName,
-	}
-	clusterMgr := cluster.NewManager(clusterConfig)
+	clusterName := resolveClusterName(configMgr.GetConfig(), clusterTarget)
+	clusterMgr := newClusterManager(configMgr.GetConfig(), clusterName)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
+	if clusterMgr.IsExternal() {
+		if err := clusterMgr.Delete(ctx); err != nil {
+			return err
+		}
+		fmt.Printf("Cluster '%s' is the existing cluster of kube context '%s' and is not deleted by deskrun\n", clusterName, clusterMgr.GetKubeconfig())
+		fmt.Println("\nTo remove the runners from it, run:")
+		fmt.Printf("  deskrun down --cluster %s\n", clusterName)
+		return nil
+	}
+
 	exists, err := clusterMgr.Exists(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to check cluster: %w", err)
 	}
 
 	if !exists {
-		fmt.Printf("Cluster '%s' does not exist\n", clusterConfig.Name)
+		fmt.Printf("Cluster '%s' does not exist\n", clusterName)
 		return nil
 	}
 
-	fmt.Printf("Deleting kind cluster '%s'...\n", clusterConfig.Name)
-	if err := clusterMgr.Delete(ctx); err != nil {
+	return deleteCluster(ctx, clusterMgr, clusterName)
+}
+
+// deleteCluster deletes an existing cluster deskrun created
+func deleteCluster(ctx context.Context, clusterMgr *cluster.Manager, clusterName string) error {
+	phase := progress.Start("Deleting %s cluster '%s'", clusterMgr.ProviderName(), clusterName)
+	err := clusterMgr.Delete(ctx)
+	phase.End(err)
+	if err != nil {
 		return fmt.Errorf("failed to delete cluster: %w", err)
 	}
 
-	fmt.Printf("Cluster '%s' deleted successfully\n", clusterConfig.Name)
+	fmt.Printf("Cluster '%s' deleted successfully\n", clusterName)
 	return nil
 }
 
@@ -146,10 +304,8 @@ func runClusterStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	clusterConfig := &types.ClusterConfig{
-		Name: configMgr.GetConfig().ClusterName,
-	}
-	clusterMgr := cluster.NewManager(clusterConfig)
+	clusterName := resolveClusterName(configMgr.GetConfig(), clusterTarget)
+	clusterMgr := newClusterManager(configMgr.GetConfig(), clusterName)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -160,11 +316,115 @@ func runClusterStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	if exists {
-		fmt.Printf("Cluster '%s' is running\n", clusterConfig.Name)
+		fmt.Printf("Cluster '%s' is running\n", clusterName)
 		fmt.Printf("Kubeconfig context: %s\n", clusterMgr.GetKubeconfig())
+		if version, err := clusterMgr.KubernetesVersion(ctx); err == nil {
+			fmt.Printf("Kubernetes version: %s\n", version)
+		}
 	} else {
-		fmt.Printf("Cluster '%s' does not exist\n", clusterConfig.Name)
+		fmt.Printf("Cluster '%s' does not exist\n", clusterName)
 	}
 
 	return nil
 }
+
+func runClusterProxy(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	clusterName := resolveClusterName(configMgr.GetConfig(), clusterTarget)
+	proxy := types.ProxyConfig{}
+	if current := configMgr.GetConfig().ClusterProxy(clusterName); current != nil {
+		proxy = *current
+	}
+
+	flags := cmd.Flags()
+	if !clusterClearProxy && !flags.Changed("http-proxy") && !flags.Changed("https-proxy") && !flags.Changed("no-proxy") {
+		if proxy.IsEmpty() {
+			fmt.Printf("Cluster '%s' has no proxy configured\n", clusterName)
+			return nil
+		}
+		fmt.Printf("Proxy of cluster '%s':\n", clusterName)
+		fmt.Print(formatProxyConfig(&proxy))
+		return nil
+	}
+
+	if clusterClearProxy {
+		proxy = types.ProxyConfig{}
+	}
+	if flags.Changed("http-proxy") {
+		proxy.HTTPProxy = clusterHTTPProxy
+	}
+	if flags.Changed("https-proxy") {
+		proxy.HTTPSProxy = clusterHTTPSProxy
+	}
+	if flags.Changed("no-proxy") {
+		proxy.NoProxy = clusterNoProxy
+	}
+
+	if proxy != (types.ProxyConfig{}) {
+		if err := validateProxyConfig(&proxy); err != nil {
+			return err
+		}
+	}
+
+	if err := configMgr.SetClusterProxy(clusterName, &proxy); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	if proxy.IsEmpty() {
+		fmt.Printf("Removed the proxy of cluster '%s'\n", clusterName)
+	} else {
+		fmt.Printf("Proxy of cluster '%s' updated:\n", clusterName)
+		fmt.Print(formatProxyConfig(&proxy))
+	}
+	fmt.Println("\nTo apply this change, run:")
+	fmt.Println("  deskrun up")
+	fmt.Println("  deskrun upgrade-controller --force")
+	return nil
+}
+
+// formatProxyConfig describes the set proxy settings, one per line
+func formatProxyConfig(proxy *types.ProxyConfig) string {
+	var b strings.Builder
+	for _, setting := range []struct{ name, value string }{
+		{"HTTP proxy", proxy.HTTPProxy},
+		{"HTTPS proxy", proxy.HTTPSProxy},
+		{"No proxy", proxy.NoProxy},
+	} {
+		if setting.value != "" {
+			fmt.Fprintf(&b, "  %-12s %s\n", setting.name+":", setting.value)
+		}
+	}
+	return b.String()
+}
+
+// newClusterManager returns the manager of a cluster, targeting the existing
+// cluster of its kube context instead of kind when it has one
+func newClusterManager(cfg *config.Config, clusterName string) *cluster.Manager {
+	return cluster.NewManager(&types.ClusterConfig{
+		Name:        clusterName,
+		Provider:    cfg.ClusterProvider,
+		KubeContext: cfg.ClusterKubeContext(clusterName),
+	})
+}
+
+// resolveClusterName returns the cluster given with --cluster, falling back
+// to the default cluster
+func resolveClusterName(cfg *config.Config, name string) string {
+	if name != "" {
+		return name
+	}
+	return cfg.DefaultCluster()
+}
+
+// targetClusters returns the clusters a command operates on: the cluster
+// given with --cluster, or all known clusters
+func targetClusters(cfg *config.Config, name string) []string {
+	if name != "" {
+		return []string{name}
+	}
+	return cfg.ClusterNames()
+}
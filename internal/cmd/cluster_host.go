@@ -5,6 +5,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/rkoster/deskrun/internal/config"
@@ -18,6 +23,18 @@ var (
 	clusterHostDiskSize    string
 	clusterHostImage       string
 	clusterHostStoragePool string
+	clusterHostVM          bool
+	clusterHostCPUs        int
+	clusterHostMemory      string
+	clusterHostRemote      string
+	clusterHostSSHKeys     []string
+)
+
+const (
+	// defaultContainerImage is the image of container cluster hosts
+	defaultContainerImage = "images:nixos/25.11"
+	// defaultVMImage is the image of VM cluster hosts, provisioned with cloud-init
+	defaultVMImage = "images:ubuntu/24.04/cloud"
 )
 
 var clusterHostCmd = &cobra.Command{
@@ -25,15 +42,27 @@ var clusterHostCmd = &cobra.Command{
 	Short: "Manage remote Incus cluster hosts",
 	Long: `Manage remote Incus cluster hosts for running deskrun on dedicated infrastructure.
 	
-Cluster hosts are NixOS containers provisioned on Incus with Docker, Kind, and deskrun pre-installed.`,
+Cluster hosts are NixOS, Ubuntu or Debian containers, or virtual machines,
+provisioned on Incus with Docker, Kind, and deskrun pre-installed.`,
 }
 
 var clusterHostCreateCmd = &cobra.Command{
-	Use:   "create [--name <name>] [--disk <size>] [--image <image>]",
+	Use:   "create [--name <name>] [--disk <size>] [--image <image>] [--vm]",
 	Short: "Create a new cluster host",
-	Long: `Create a new Incus container with NixOS pre-configured with Docker, Kind, and deskrun.
+	Long: `Create a new Incus container, NixOS by default, pre-configured with Docker, Kind, and deskrun.
+
+The container will be created on the current Incus remote (use 'incus remote switch' to change),
+or on the remote given with --remote. The remote is saved, so the other
+cluster-host commands manage the host on the right Incus server.
+
+Use --image to create an Ubuntu or Debian host instead. Their cloud images
+(e.g. images:ubuntu/24.04/cloud) are provisioned with cloud-init on the first
+boot; other Ubuntu and Debian images (e.g. images:ubuntu/24.04) are provisioned
+by running the same shell script on the host.
 
-The container will be created on the current Incus remote (use 'incus remote switch' to change).
+Use --vm to create a virtual machine instead. VMs run their own kernel, which
+avoids the nesting issues of privileged and dind runners in containers. They
+default to an Ubuntu cloud image; NixOS images are configured like containers.
 
 Examples:
   # Create with auto-generated name
@@ -43,40 +72,75 @@ Examples:
   deskrun cluster-host create --name my-host --disk 300GiB
 
   # Create with specific NixOS image
-  deskrun cluster-host create --image images:nixos/25.11`,
+  deskrun cluster-host create --image images:nixos/25.11
+
+  # Create an Ubuntu container
+  deskrun cluster-host create --image images:ubuntu/24.04
+
+  # Create a virtual machine with 8 vCPUs and 16GiB of memory
+  deskrun cluster-host create --vm --cpus 8 --memory 16GiB
+
+  # Create a Debian virtual machine
+  deskrun cluster-host create --vm --image images:debian/12/cloud
+
+  # Create on another incus server (see 'incus remote list')
+  deskrun cluster-host create --remote my-server
+
+  # Enable SSH access as root with your public key
+  deskrun cluster-host create --ssh-key ~/.ssh/id_ed25519.pub`,
 	RunE: runClusterHostCreate,
 }
 
 var clusterHostDeleteCmd = &cobra.Command{
 	Use:   "delete <name>",
 	Short: "Delete a cluster host",
-	Long:  `Delete a cluster host container and remove it from configuration.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runClusterHostDelete,
+	Long: `Delete a cluster host container and remove it from configuration.
+
+The host is deleted on the incus remote it was created on. Use --remote for
+hosts that are not in the configuration and don't live on the default remote.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeClusterHosts,
+	RunE:              runClusterHostDelete,
 }
 
 var clusterHostListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all cluster hosts",
-	Long:  `List all cluster hosts with their status and configuration.`,
-	RunE:  runClusterHostList,
+	Long: `List all cluster hosts with their status and configuration.
+
+The status of the hosts is queried from every incus remote hosts live on. Use
+--remote to only list the hosts of one remote.`,
+	RunE: runClusterHostList,
 }
 
 var clusterHostConfigureCmd = &cobra.Command{
 	Use:   "configure <name>",
 	Short: "Re-configure a cluster host",
-	Long: `Re-apply NixOS configuration to a cluster host.
+	Long: `Re-apply NixOS configuration to a cluster host, or re-run the provisioning
+script on Ubuntu and Debian cluster hosts.
+
+Use --ssh-key to authorize additional SSH public keys to log in as root.
 
 This is useful after deskrun updates or if the initial configuration failed.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runClusterHostConfigure,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeClusterHosts,
+	RunE:              runClusterHostConfigure,
 }
 
 func init() {
 	clusterHostCreateCmd.Flags().StringVar(&clusterHostName, "name", "", "Container name (auto-generated if not specified)")
 	clusterHostCreateCmd.Flags().StringVar(&clusterHostDiskSize, "disk", "200GiB", "Root disk size")
-	clusterHostCreateCmd.Flags().StringVar(&clusterHostImage, "image", "images:nixos/25.11", "NixOS image to use")
+	clusterHostCreateCmd.Flags().StringVar(&clusterHostImage, "image", "", fmt.Sprintf("NixOS, Ubuntu or Debian image to use (default %s, or %s with --vm)", defaultContainerImage, defaultVMImage))
 	clusterHostCreateCmd.Flags().StringVar(&clusterHostStoragePool, "storage-pool", "local", "Incus storage pool to use")
+	clusterHostCreateCmd.Flags().BoolVar(&clusterHostVM, "vm", false, "Create a virtual machine instead of a container")
+	clusterHostCreateCmd.Flags().IntVar(&clusterHostCPUs, "cpus", 4, "Number of vCPUs of the virtual machine (with --vm)")
+	clusterHostCreateCmd.Flags().StringVar(&clusterHostMemory, "memory", "8GiB", "Memory of the virtual machine (with --vm)")
+	clusterHostCreateCmd.Flags().StringVar(&clusterHostRemote, "remote", "", "Incus remote to create the host on (default: the current remote)")
+	clusterHostDeleteCmd.Flags().StringVar(&clusterHostRemote, "remote", "", "Incus remote of hosts that are not in the configuration")
+	clusterHostListCmd.Flags().StringVar(&clusterHostRemote, "remote", "", "Only list the hosts on this incus remote")
+	for _, cmd := range []*cobra.Command{clusterHostCreateCmd, clusterHostConfigureCmd} {
+		cmd.Flags().StringArrayVar(&clusterHostSSHKeys, "ssh-key", nil, "SSH public key file to authorize for root logins (can be repeated)")
+	}
 
 	clusterHostCmd.AddCommand(clusterHostCreateCmd)
 	clusterHostCmd.AddCommand(clusterHostDeleteCmd)
@@ -104,8 +168,46 @@ func runClusterHostCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("cluster host %s already exists in configuration", name)
 	}
 
-	incusMgr := incus.NewManager()
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	if !clusterHostVM && (cmd.Flags().Changed("cpus") || cmd.Flags().Changed("memory")) {
+		return fmt.Errorf("--cpus and --memory require --vm")
+	}
+
+	host := &types.ClusterHost{
+		Name:     name,
+		Image:    clusterHostImage,
+		DiskSize: clusterHostDiskSize,
+		VM:       clusterHostVM,
+	}
+	if host.SSHKeys, err = readSSHPublicKeys(nil, clusterHostSSHKeys); err != nil {
+		return err
+	}
+	if host.VM {
+		host.CPUs = clusterHostCPUs
+		host.Memory = clusterHostMemory
+	}
+	if host.Image == "" {
+		host.Image = defaultContainerImage
+		if host.VM {
+			host.Image = defaultVMImage
+		}
+	}
+	provisioning, err := incus.ImageProvisioning(host.Image)
+	if err != nil {
+		return err
+	}
+	userData := ""
+	if provisioning == incus.ProvisionCloudInit {
+		userData = incus.CloudInitUserData()
+	}
+
+	incusMgr, err := incus.NewManager(clusterHostRemote)
+	if err != nil {
+		return fmt.Errorf("failed to connect to incus: %w", err)
+	}
+	host.Remote = incusMgr.Remote()
+
+	// Provisioning Ubuntu and Debian hosts installs everything from scratch
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
 	defer cancel()
 
 	exists, err := incusMgr.ContainerExists(ctx, name)
@@ -116,51 +218,63 @@ func runClusterHostCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("container %s already exists", name)
 	}
 
-	fmt.Printf("Creating cluster host '%s'...\n", name)
+	slog.Info("Creating cluster host", "host", name, "remote", host.Remote)
 
-	fmt.Println("Launching NixOS container...")
-	if err := incusMgr.CreateContainer(ctx, name, clusterHostImage, clusterHostDiskSize, clusterHostStoragePool); err != nil {
-		return fmt.Errorf("failed to create container: %w", err)
+	if host.VM {
+		slog.Info("Launching virtual machine", "image", host.Image)
+		opts := incus.VMOptions{CPUs: host.CPUs, Memory: host.Memory, UserData: userData}
+		if err := incusMgr.CreateVM(ctx, name, host.Image, host.DiskSize, clusterHostStoragePool, opts); err != nil {
+			return fmt.Errorf("failed to create VM: %w", err)
+		}
+	} else {
+		slog.Info("Launching container", "image", host.Image)
+		if err := incusMgr.CreateContainer(ctx, name, host.Image, host.DiskSize, clusterHostStoragePool, userData); err != nil {
+			return fmt.Errorf("failed to create container: %w", err)
+		}
 	}
 
-	fmt.Println("Waiting for container to start...")
+	slog.Info("Waiting for container to start")
 	if err := incusMgr.WaitForRunning(ctx, name, 2*time.Minute); err != nil {
 		_ = incusMgr.DeleteContainer(ctx, name)
 		return fmt.Errorf("container failed to start: %w", err)
 	}
 
-	fmt.Println("Waiting for network connectivity...")
-	if err := incusMgr.WaitForNetwork(ctx, name, 2*time.Minute); err != nil {
+	slog.Info("Waiting for network connectivity")
+	// VMs need to boot and start the incus agent before commands can run
+	if err := incusMgr.WaitForNetwork(ctx, name, 5*time.Minute); err != nil {
 		_ = incusMgr.DeleteContainer(ctx, name)
 		return fmt.Errorf("network failed to initialize: %w", err)
 	}
 
-	fmt.Println("Configuring NixOS with Docker, Kind, and deskrun...")
-	if err := incusMgr.ConfigureNixOS(ctx, name); err != nil {
+	if err := provisionClusterHost(ctx, incusMgr, host, true); err != nil {
 		_ = incusMgr.DeleteContainer(ctx, name)
-		return fmt.Errorf("failed to configure NixOS: %w", err)
+		return err
 	}
 
-	fmt.Println("Copying deskrun configuration to cluster host...")
-	if err := incusMgr.PushConfigFile(ctx, name, configMgr.GetConfigPath()); err != nil {
+	slog.Info("Copying deskrun configuration to cluster host")
+	// Auth values live in the local secret store, so push a copy of the config
+	// that includes them; deskrun on the host moves them into its own store
+	configData, err := configMgr.ExportPlaintext()
+	if err != nil {
 		_ = incusMgr.DeleteContainer(ctx, name)
-		return fmt.Errorf("failed to push config file: %w", err)
+		return fmt.Errorf("failed to export config: %w", err)
 	}
-
-	host := &types.ClusterHost{
-		Name:      name,
-		Image:     clusterHostImage,
-		DiskSize:  clusterHostDiskSize,
-		CreatedAt: time.Now().Format(time.RFC3339),
+	if err := incusMgr.PushConfig(ctx, name, configData); err != nil {
+		_ = incusMgr.DeleteContainer(ctx, name)
+		return fmt.Errorf("failed to push config file: %w", err)
 	}
 
+	host.CreatedAt = time.Now().Format(time.RFC3339)
 	if err := configMgr.AddClusterHost(host); err != nil {
 		return fmt.Errorf("failed to save cluster host to config: %w", err)
 	}
 
 	fmt.Printf("\nCluster host '%s' created successfully\n\n", name)
-	fmt.Printf("To access: incus exec %s -- bash\n", name)
-	fmt.Printf("To run deskrun: incus exec %s -- deskrun --help\n", name)
+	fmt.Printf("To access: deskrun cluster-host shell %s\n", name)
+	fmt.Printf("To run deskrun: deskrun cluster-host shell %s -- deskrun --help\n", name)
+	if len(host.SSHKeys) > 0 {
+		fmt.Println("SSH access as root is enabled for the configured keys")
+	}
 
 	return nil
 }
@@ -173,11 +287,19 @@ func runClusterHostDelete(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	if _, err := configMgr.GetClusterHost(name); err != nil {
-		fmt.Printf("Warning: cluster host %s not found in configuration\n", name)
+	remote := clusterHostRemote
+	if host, err := configMgr.GetClusterHost(name); err != nil {
+		slog.Warn("Cluster host not found in configuration", "host", name)
+	} else if remote == "" {
+		remote = host.Remote
+	} else if host.Remote != "" && host.Remote != remote {
+		return fmt.Errorf("cluster host %s lives on incus remote %s, not %s", name, host.Remote, remote)
 	}
 
-	incusMgr := incus.NewManager()
+	incusMgr, err := incus.NewManager(remote)
+	if err != nil {
+		return fmt.Errorf("failed to connect to incus: %w", err)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
@@ -189,7 +311,7 @@ func runClusterHostDelete(cmd *cobra.Command, args []string) error {
 	if !exists {
 		fmt.Printf("Container %s does not exist\n", name)
 	} else {
-		fmt.Printf("Deleting cluster host '%s'...\n", name)
+		slog.Info("Deleting cluster host", "host", name)
 		if err := incusMgr.DeleteContainer(ctx, name); err != nil {
 			return fmt.Errorf("failed to delete container: %w", err)
 		}
@@ -209,55 +331,49 @@ func runClusterHostList(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	incusMgr := incus.NewManager()
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	allContainers, err := incusMgr.ListContainers(ctx, "")
-	if err != nil {
-		return fmt.Errorf("failed to list containers: %w", err)
-	}
-
-	configuredHosts := make(map[string]bool)
-	for name := range configMgr.GetConfig().ClusterHosts {
-		configuredHosts[name] = true
-	}
-
-	var containers []incus.ContainerInfo
-	for _, container := range allContainers {
-		if configuredHosts[container.Name] {
-			containers = append(containers, container)
+	hosts := make(map[string]*types.ClusterHost)
+	for name, host := range configMgr.GetConfig().ClusterHosts {
+		if clusterHostRemote == "" || host.Remote == clusterHostRemote {
+			hosts[name] = host
 		}
 	}
 
-	if len(containers) == 0 && len(configMgr.GetConfig().ClusterHosts) == 0 {
+	if len(hosts) == 0 {
 		fmt.Println("No cluster hosts found")
 		return nil
 	}
 
-	fmt.Printf("%-20s %-10s %-20s %-10s %-20s\n", "NAME", "STATUS", "IMAGE", "DISK", "CREATED")
-	fmt.Println("--------------------------------------------------------------------------------------------")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	for _, container := range containers {
-		host, err := configMgr.GetClusterHost(container.Name)
-		if err != nil {
-			fmt.Printf("%-20s %-10s %-20s %-10s %-20s\n",
-				container.Name,
-				container.Status,
-				"N/A",
-				"N/A",
-				"N/A")
-			continue
-		}
+	statuses, _ := clusterHostStatuses(ctx, hosts)
+
+	fmt.Printf("%-20s %-12s %-12s %-10s %-28s %-10s %-20s\n", "NAME", "REMOTE", "STATUS", "TYPE", "IMAGE", "DISK", "CREATED")
+	fmt.Println("------------------------------------------------------------------------------------------------------------------------")
+
+	for _, name := range slices.Sorted(maps.Keys(hosts)) {
+		host := hosts[name]
 
 		createdAt := host.CreatedAt
 		if t, err := time.Parse(time.RFC3339, host.CreatedAt); err == nil {
 			createdAt = t.Format("2006-01-02 15:04:05")
 		}
 
-		fmt.Printf("%-20s %-10s %-20s %-10s %-20s\n",
+		hostType := "container"
+		if host.VM {
+			hostType = "vm"
+		}
+
+		remote := host.Remote
+		if remote == "" {
+			remote = "(default)"
+		}
+
+		fmt.Printf("%-20s %-12s %-12s %-10s %-28s %-10s %-20s\n",
 			host.Name,
-			container.Status,
+			remote,
+			statuses[name],
+			hostType,
 			host.Image,
 			host.DiskSize,
 			createdAt)
@@ -266,6 +382,52 @@ func runClusterHostList(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// clusterHostStatuses returns the instance status (RUNNING, STOPPED, ...) of
+// the cluster hosts, listing the instances of every incus remote the hosts
+// live on once, and the managers of the reachable remotes by remote name.
+// Hosts without instance are MISSING, hosts on unreachable remotes UNREACHABLE.
+func clusterHostStatuses(ctx context.Context, hosts map[string]*types.ClusterHost) (map[string]string, map[string]*incus.Manager) {
+	statuses := make(map[string]string, len(hosts))
+	managers := make(map[string]*incus.Manager)
+	unreachable := make(map[string]bool)
+
+	for _, name := range slices.Sorted(maps.Keys(hosts)) {
+		remote := hosts[name].Remote
+		if _, ok := managers[remote]; ok || unreachable[remote] {
+			continue
+		}
+
+		incusMgr, err := incus.NewManager(remote)
+		var containers []incus.ContainerInfo
+		if err == nil {
+			containers, err = incusMgr.ListContainers(ctx, "")
+		}
+		if err != nil {
+			slog.Warn("Failed to list the containers of incus remote", "remote", remote, "error", err)
+			unreachable[remote] = true
+			continue
+		}
+
+		managers[remote] = incusMgr
+		for _, container := range containers {
+			if host, ok := hosts[container.Name]; ok && host.Remote == remote {
+				statuses[container.Name] = container.Status
+			}
+		}
+	}
+
+	for name, host := range hosts {
+		switch {
+		case unreachable[host.Remote]:
+			statuses[name] = "UNREACHABLE"
+		case statuses[name] == "":
+			statuses[name] = "MISSING"
+		}
+	}
+
+	return statuses, managers
+}
+
 func runClusterHostConfigure(cmd *cobra.Command, args []string) error {
 	name := args[0]
 
@@ -274,12 +436,25 @@ func runClusterHostConfigure(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	if _, err := configMgr.GetClusterHost(name); err != nil {
+	host, err := configMgr.GetClusterHost(name)
+	if err != nil {
 		return fmt.Errorf("cluster host %s not found in configuration", name)
 	}
 
-	incusMgr := incus.NewManager()
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	if len(clusterHostSSHKeys) > 0 {
+		if host.SSHKeys, err = readSSHPublicKeys(host.SSHKeys, clusterHostSSHKeys); err != nil {
+			return err
+		}
+		if err := configMgr.UpdateClusterHost(host); err != nil {
+			return fmt.Errorf("failed to save SSH keys: %w", err)
+		}
+	}
+
+	incusMgr, err := incus.NewManager(host.Remote)
+	if err != nil {
+		return fmt.Errorf("failed to connect to incus: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
 	defer cancel()
 
 	exists, err := incusMgr.ContainerExists(ctx, name)
@@ -290,11 +465,68 @@ func runClusterHostConfigure(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("container %s does not exist", name)
 	}
 
-	fmt.Println("Applying NixOS configuration...")
-	if err := incusMgr.ConfigureNixOS(ctx, name); err != nil {
-		return fmt.Errorf("failed to configure NixOS: %w", err)
+	if err := provisionClusterHost(ctx, incusMgr, host, false); err != nil {
+		return err
 	}
 
 	fmt.Println("Configuration applied successfully")
 	return nil
 }
+
+// provisionClusterHost installs Docker, Kind, and deskrun on a cluster host:
+// NixOS images are configured with the deskrun NixOS module, Debian and Ubuntu
+// images with the provisioning script, which cloud-init runs on the first boot
+// of cloud images
+func provisionClusterHost(ctx context.Context, incusMgr *incus.Manager, host *types.ClusterHost, firstBoot bool) error {
+	provisioning, err := incus.ImageProvisioning(host.Image)
+	if err != nil {
+		return err
+	}
+
+	if provisioning == incus.ProvisionNixOS {
+		slog.Info("Configuring NixOS with Docker, Kind, and deskrun")
+		if err := incusMgr.ConfigureNixOS(ctx, host.Name, host.SSHKeys); err != nil {
+			return fmt.Errorf("failed to configure NixOS: %w", err)
+		}
+		return nil
+	}
+
+	if firstBoot && provisioning == incus.ProvisionCloudInit {
+		slog.Info("Waiting for cloud-init to install Docker, Kind, and deskrun, this may take a few minutes")
+		if err := incusMgr.WaitForCloudInit(ctx, host.Name); err != nil {
+			return err
+		}
+	} else {
+		slog.Info("Running provisioning script to install Docker, Kind, and deskrun, this may take a few minutes")
+		if err := incusMgr.RunProvisionScript(ctx, host.Name); err != nil {
+			return err
+		}
+	}
+
+	if len(host.SSHKeys) > 0 {
+		slog.Info("Authorizing SSH keys")
+		return incusMgr.PushAuthorizedKeys(ctx, host.Name, host.SSHKeys)
+	}
+	return nil
+}
+
+// readSSHPublicKeys reads and validates the SSH public keys in the given
+// files and returns them added to the existing keys, skipping duplicates
+func readSSHPublicKeys(existing []string, paths []string) ([]string, error) {
+	keys := slices.Clone(existing)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH public key: %w", err)
+		}
+
+		key := strings.TrimSpace(string(data))
+		if err := incus.ValidateSSHPublicKey(key); err != nil {
+			return nil, fmt.Errorf("invalid SSH public key %s: %w", path, err)
+		}
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
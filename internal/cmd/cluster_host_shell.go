@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rkoster/deskrun/internal/incus"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// defaultShellCommand is the command 'cluster-host shell' runs without arguments
+var defaultShellCommand = []string{"bash", "-l"}
+
+var clusterHostShellCmd = &cobra.Command{
+	Use:   "shell <name> [-- <command>...]",
+	Short: "Open a shell on a cluster host",
+	Long: `Open an interactive root shell on a cluster host, or run a command on it.
+
+The session runs through the Incus API on the remote the host lives on, so
+neither the incus CLI nor SSH access is needed. When stdin is a terminal the
+command gets a terminal of the same size; otherwise its input and output are
+streamed, so it can be used in pipes. The exit status of the command is the
+exit status of deskrun.
+
+Examples:
+  deskrun cluster-host shell my-host
+  deskrun cluster-host shell my-host -- deskrun status
+  deskrun cluster-host shell my-host -- journalctl -u docker -f`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeClusterHosts,
+	RunE:              runClusterHostShell,
+}
+
+func init() {
+	clusterHostCmd.AddCommand(clusterHostShellCmd)
+}
+
+func runClusterHostShell(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	command := args[1:]
+	if len(command) == 0 {
+		command = defaultShellCommand
+	}
+
+	_, incusMgr, err := clusterHostManager(name)
+	if err != nil {
+		return err
+	}
+
+	return execOnClusterHost(incusMgr, name, command)
+}
+
+// execOnClusterHost runs a command on a cluster host with the standard streams
+// of deskrun, attached to a terminal if stdin is one, and exits with the exit
+// status of the command if it fails
+func execOnClusterHost(incusMgr *incus.Manager, name string, command []string) error {
+	ctx := context.Background()
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return exitWithCommandStatus(incusMgr.ExecStream(ctx, name, os.Stdin, os.Stdout, os.Stderr, command...), nil)
+	}
+
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	env := map[string]string{}
+	if termEnv := os.Getenv("TERM"); termEnv != "" {
+		env["TERM"] = termEnv
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to set terminal to raw mode: %w", err)
+	}
+	restore := func() { _ = term.Restore(fd, state) }
+	defer restore()
+
+	err = incusMgr.ExecInteractive(ctx, name, os.Stdin, os.Stdout, width, height, env, command...)
+	return exitWithCommandStatus(err, restore)
+}
+
+// exitWithCommandStatus exits with the status of a command that exited with a
+// non-zero status, like the incus CLI does, after restoring the terminal.
+// Other errors are returned.
+func exitWithCommandStatus(err error, restore func()) error {
+	var exitErr *incus.ExitError
+	if !errors.As(err, &exitErr) {
+		if err != nil {
+			return fmt.Errorf("failed to run command on cluster host: %w", err)
+		}
+		return nil
+	}
+
+	if restore != nil {
+		restore()
+	}
+	os.Exit(exitErr.Code)
+	return nil
+}
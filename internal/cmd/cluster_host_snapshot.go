@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/incus"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var clusterHostSnapshotDelete bool
+
+var clusterHostSnapshotCmd = &cobra.Command{
+	Use:   "snapshot <name> [<snapshot>]",
+	Short: "Take a snapshot of a cluster host",
+	Long: `Take a snapshot of the disk of a cluster host, e.g. of a fully configured
+host with kind and runners, to roll back to after destructive experiments.
+
+Without a snapshot name, the snapshot is named after the current time. Use
+--delete to delete a snapshot instead.
+
+Examples:
+  deskrun cluster-host snapshot my-host
+  deskrun cluster-host snapshot my-host before-upgrade
+  deskrun cluster-host snapshot my-host before-upgrade --delete`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeClusterHosts,
+	RunE:              runClusterHostSnapshot,
+}
+
+var clusterHostSnapshotsCmd = &cobra.Command{
+	Use:               "snapshots <name>",
+	Short:             "List the snapshots of a cluster host",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeClusterHosts,
+	RunE:              runClusterHostSnapshots,
+}
+
+var clusterHostRestoreCmd = &cobra.Command{
+	Use:   "restore <name> <snapshot>",
+	Short: "Restore a cluster host from a snapshot",
+	Long: `Roll a cluster host back to a snapshot taken with 'deskrun cluster-host snapshot'.
+
+Everything on the host changed since the snapshot is lost, including the
+deskrun configuration pushed to it. A running host is restarted.
+
+Examples:
+  deskrun cluster-host restore my-host before-upgrade`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeClusterHosts,
+	RunE:              runClusterHostRestore,
+}
+
+func init() {
+	clusterHostSnapshotCmd.Flags().BoolVar(&clusterHostSnapshotDelete, "delete", false, "Delete the snapshot instead of taking it")
+
+	clusterHostCmd.AddCommand(clusterHostSnapshotCmd)
+	clusterHostCmd.AddCommand(clusterHostSnapshotsCmd)
+	clusterHostCmd.AddCommand(clusterHostRestoreCmd)
+}
+
+// clusterHostManager returns a configured cluster host and the incus manager
+// of the remote it lives on
+func clusterHostManager(name string) (*types.ClusterHost, *incus.Manager, error) {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	host, err := configMgr.GetClusterHost(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cluster host %s not found in configuration", name)
+	}
+
+	incusMgr, err := incus.NewManager(host.Remote)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to incus: %w", err)
+	}
+
+	return host, incusMgr, nil
+}
+
+func runClusterHostSnapshot(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	snapshot := time.Now().Format("20060102-150405")
+	if len(args) == 2 {
+		snapshot = args[1]
+	} else if clusterHostSnapshotDelete {
+		return fmt.Errorf("--delete requires the name of the snapshot")
+	}
+
+	_, incusMgr, err := clusterHostManager(name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if clusterHostSnapshotDelete {
+		if err := incusMgr.DeleteSnapshot(ctx, name, snapshot); err != nil {
+			return err
+		}
+		fmt.Printf("Snapshot '%s' of cluster host '%s' deleted\n", snapshot, name)
+		return nil
+	}
+
+	slog.Info("Taking snapshot of cluster host", "host", name, "snapshot", snapshot)
+	if err := incusMgr.CreateSnapshot(ctx, name, snapshot); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot '%s' created\n", snapshot)
+	fmt.Printf("To roll back: deskrun cluster-host restore %s %s\n", name, snapshot)
+	return nil
+}
+
+func runClusterHostSnapshots(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	_, incusMgr, err := clusterHostManager(name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	snapshots, err := incusMgr.ListSnapshots(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Printf("Cluster host '%s' has no snapshots\n", name)
+		return nil
+	}
+
+	fmt.Printf("%-30s %-20s %-10s\n", "SNAPSHOT", "CREATED", "SIZE")
+	for _, snapshot := range snapshots {
+		size := "N/A"
+		if snapshot.Size >= 0 {
+			size = formatBytes(uint64(snapshot.Size))
+		}
+		fmt.Printf("%-30s %-20s %-10s\n", snapshot.Name, snapshot.CreatedAt.Local().Format("2006-01-02 15:04:05"), size)
+	}
+	return nil
+}
+
+func runClusterHostRestore(cmd *cobra.Command, args []string) error {
+	name, snapshot := args[0], args[1]
+
+	_, incusMgr, err := clusterHostManager(name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	slog.Info("Restoring cluster host from snapshot", "host", name, "snapshot", snapshot)
+	if err := incusMgr.RestoreSnapshot(ctx, name, snapshot); err != nil {
+		return err
+	}
+
+	fmt.Printf("Cluster host '%s' restored\n", name)
+	return nil
+}
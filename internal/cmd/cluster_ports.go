@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var clusterPortsListenAddress string
+
+var clusterPortsCmd = &cobra.Command{
+	Use:   "ports",
+	Short: "Manage ports of the cluster exposed on the host",
+	Long: `Manage ports of the kind node that are exposed on the host.
+
+Services started by jobs (webhook receivers, preview environments, ...) run
+inside the cluster and can't be reached from the host. Map a host port to a
+port of the kind node and expose the service on that port as a NodePort, e.g.
+with 'kubectl expose deployment preview --type NodePort' and a fixed nodePort
+in the 30000-32767 range.
+
+Port mappings are part of the kind cluster configuration: they apply when the
+cluster is created, so existing clusters need to be recreated.`,
+}
+
+var clusterPortsAddCmd = &cobra.Command{
+	Use:   "add <host-port>[:<node-port>][/<protocol>]",
+	Short: "Expose a port of the kind node on the host",
+	Long: `Expose a port of the kind node on the host. Without a node port, the host
+port is mapped to the same port on the node. The protocol is tcp (default) or
+udp. Ports listen on 127.0.0.1 unless --listen-address is given.
+
+Examples:
+  deskrun cluster ports add 30080                              # localhost:30080 -> NodePort 30080
+  deskrun cluster ports add 8080:30080                         # localhost:8080 -> NodePort 30080
+  deskrun cluster ports add 5353:30053/udp
+  deskrun cluster ports add 8443:30443 --listen-address 0.0.0.0   # Reachable from the network
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runClusterPortsAdd,
+}
+
+var clusterPortsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the ports exposed on the host",
+	RunE:  runClusterPortsList,
+}
+
+var clusterPortsRemoveCmd = &cobra.Command{
+	Use:   "remove <host-port>",
+	Short: "Stop exposing a host port",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runClusterPortsRemove,
+}
+
+func init() {
+	clusterPortsAddCmd.Flags().StringVar(&clusterPortsListenAddress, "listen-address", "", "Host address to listen on (defaults to 127.0.0.1)")
+
+	clusterPortsCmd.AddCommand(clusterPortsAddCmd)
+	clusterPortsCmd.AddCommand(clusterPortsListCmd)
+	clusterPortsCmd.AddCommand(clusterPortsRemoveCmd)
+	clusterCmd.AddCommand(clusterPortsCmd)
+}
+
+func runClusterPortsAdd(cmd *cobra.Command, args []string) error {
+	mapping, err := parsePortMapping(args[0])
+	if err != nil {
+		return err
+	}
+	mapping.ListenAddress = clusterPortsListenAddress
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	clusterName := resolveClusterName(configMgr.GetConfig(), clusterTarget)
+	if err := configMgr.AddClusterPortMapping(clusterName, mapping); err != nil {
+		return err
+	}
+
+	fmt.Printf("Port %s added to cluster '%s'\n", mapping, clusterName)
+	return printRecreateClusterHint(configMgr.GetConfig(), clusterName)
+}
+
+func runClusterPortsList(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	clusterName := resolveClusterName(configMgr.GetConfig(), clusterTarget)
+	mappings := configMgr.GetConfig().ClusterPortMappings(clusterName)
+	if len(mappings) == 0 {
+		fmt.Printf("Cluster '%s' exposes no ports\n", clusterName)
+		return nil
+	}
+
+	for _, mapping := range mappings {
+		fmt.Println(mapping)
+	}
+	return nil
+}
+
+func runClusterPortsRemove(cmd *cobra.Command, args []string) error {
+	hostPort, err := parsePort(args[0])
+	if err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	clusterName := resolveClusterName(configMgr.GetConfig(), clusterTarget)
+	if err := configMgr.RemoveClusterPortMapping(clusterName, hostPort); err != nil {
+		return err
+	}
+
+	fmt.Printf("Host port %d removed from cluster '%s'\n", hostPort, clusterName)
+	return printRecreateClusterHint(configMgr.GetConfig(), clusterName)
+}
+
+// printRecreateClusterHint tells how to apply a change of the kind
+// configuration when the cluster already exists
+func printRecreateClusterHint(cfg *config.Config, clusterName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	clusterMgr := newClusterManager(cfg, clusterName)
+	if clusterMgr.IsExternal() {
+		fmt.Printf("\nNote: cluster '%s' uses kube context '%s'; port mappings only apply to kind clusters\n", clusterName, clusterMgr.GetKubeconfig())
+		return nil
+	}
+
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if exists {
+		fmt.Println("\nTo apply this change, recreate the cluster:")
+		fmt.Printf("  deskrun cluster delete --cluster %s && deskrun up\n", clusterName)
+	}
+	return nil
+}
+
+// parsePortMapping parses a <host-port>[:<node-port>][/<protocol>] argument
+func parsePortMapping(value string) (types.PortMapping, error) {
+	ports, protocol, hasProtocol := strings.Cut(value, "/")
+	mapping := types.PortMapping{Protocol: "TCP"}
+	if hasProtocol {
+		switch strings.ToUpper(protocol) {
+		case "TCP", "UDP":
+			mapping.Protocol = strings.ToUpper(protocol)
+		default:
+			return types.PortMapping{}, fmt.Errorf("invalid protocol '%s' in '%s', must be tcp or udp", protocol, value)
+		}
+	}
+
+	hostPort, nodePort, hasNodePort := strings.Cut(ports, ":")
+	var err error
+	if mapping.HostPort, err = parsePort(hostPort); err != nil {
+		return types.PortMapping{}, err
+	}
+	mapping.ContainerPort = mapping.HostPort
+	if hasNodePort {
+		if mapping.ContainerPort, err = parsePort(nodePort); err != nil {
+			return types.PortMapping{}, err
+		}
+	}
+	return mapping, nil
+}
+
+// parsePort parses a port number between 1 and 65535
+func parsePort(value string) (int32, error) {
+	port, err := strconv.ParseUint(value, 10, 16)
+	if err != nil || port == 0 {
+		return 0, fmt.Errorf("invalid port '%s', must be a number between 1 and 65535", value)
+	}
+	return int32(port), nil
+}
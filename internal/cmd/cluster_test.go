@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Cluster Targeting", func() {
+	var cfg *config.Config
+
+	BeforeEach(func() {
+		cfg = &config.Config{
+			ClusterName: "deskrun",
+			Installations: map[string]*types.RunnerInstallation{
+				"default-runner": {Name: "default-runner"},
+				"gpu-runner":     {Name: "gpu-runner", Cluster: "deskrun-gpu"},
+			},
+		}
+	})
+
+	It("targets every known cluster without --cluster", func() {
+		Expect(targetClusters(cfg, "")).To(Equal([]string{"deskrun", "deskrun-gpu"}))
+	})
+
+	It("targets only the cluster given with --cluster", func() {
+		Expect(targetClusters(cfg, "deskrun-gpu")).To(Equal([]string{"deskrun-gpu"}))
+	})
+
+	It("resolves to the default cluster without --cluster", func() {
+		Expect(resolveClusterName(cfg, "")).To(Equal("deskrun"))
+		Expect(resolveClusterName(cfg, "deskrun-gpu")).To(Equal("deskrun-gpu"))
+	})
+
+	It("targets the kube context of clusters registered with one", func() {
+		cfg.Clusters = map[string]*types.ClusterSettings{
+			"k3s": {Name: "k3s", KubeContext: "default"},
+		}
+
+		external := newClusterManager(cfg, "k3s")
+		Expect(external.IsExternal()).To(BeTrue())
+		Expect(external.GetKubeconfig()).To(Equal("default"))
+
+		kind := newClusterManager(cfg, "deskrun")
+		Expect(kind.IsExternal()).To(BeFalse())
+		Expect(kind.GetKubeconfig()).To(Equal("kind-deskrun"))
+	})
+})
+
+var _ = Describe("Cluster Proxy", func() {
+	It("applies the cluster proxy to installations without their own proxy", func() {
+		clusterProxy := &types.ProxyConfig{HTTPSProxy: "http://proxy.corp:3128"}
+		cfg := &config.Config{
+			ClusterName: "deskrun",
+			Clusters: map[string]*types.ClusterSettings{
+				"deskrun": {Name: "deskrun", Proxy: clusterProxy},
+			},
+		}
+
+		installation := &types.RunnerInstallation{Name: "my-runner"}
+		withProxy := installationWithProxy(cfg, installation)
+		Expect(withProxy.Proxy).To(Equal(clusterProxy))
+		Expect(installation.Proxy).To(BeNil(), "the configured installation should not be modified")
+
+		ownProxy := &types.ProxyConfig{HTTPProxy: "http://other:8080"}
+		pinned := &types.RunnerInstallation{Name: "pinned", Proxy: ownProxy}
+		Expect(installationWithProxy(cfg, pinned)).To(BeIdenticalTo(pinned))
+	})
+
+	It("formats the set proxy settings", func() {
+		Expect(formatProxyConfig(&types.ProxyConfig{HTTPSProxy: "http://proxy:3128", NoProxy: ".corp"})).To(Equal(
+			"  HTTPS proxy: http://proxy:3128\n  No proxy:    .corp\n"))
+	})
+})
+
+var _ = Describe("Cluster Ports", func() {
+	DescribeTable("parsing port mappings",
+		func(value string, expected types.PortMapping, expectedErrorMsg string) {
+			mapping, err := parsePortMapping(value)
+
+			if expectedErrorMsg == "" {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mapping).To(Equal(expected))
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectedErrorMsg)))
+			}
+		},
+		Entry("same port", "30080", types.PortMapping{HostPort: 30080, ContainerPort: 30080, Protocol: "TCP"}, ""),
+		Entry("host and node port", "8080:30080", types.PortMapping{HostPort: 8080, ContainerPort: 30080, Protocol: "TCP"}, ""),
+		Entry("udp", "5353:30053/udp", types.PortMapping{HostPort: 5353, ContainerPort: 30053, Protocol: "UDP"}, ""),
+		Entry("invalid: protocol", "8080/sctp", types.PortMapping{}, "invalid protocol"),
+		Entry("invalid: port out of range", "70000", types.PortMapping{}, "invalid port"),
+		Entry("invalid: zero node port", "8080:0", types.PortMapping{}, "invalid port"),
+		Entry("invalid: not a number", "http", types.PortMapping{}, "invalid port"),
+	)
+})
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish",
+	Short: "Generate shell completion scripts",
+	Long: `Generate a completion script for bash, zsh or fish.
+
+Besides commands and flags, the names of configured runner installations and
+cluster hosts are completed, e.g. for 'deskrun remove' and
+'deskrun cluster-host delete'.
+
+Bash (requires the bash-completion package):
+  source <(deskrun completion bash)
+  # Load for every session
+  deskrun completion bash > ~/.local/share/bash-completion/completions/deskrun
+
+Zsh:
+  # Load for every session (compinit must be enabled)
+  deskrun completion zsh > "${fpath[1]}/_deskrun"
+
+Fish:
+  deskrun completion fish > ~/.config/fish/completions/deskrun.fish
+
+Examples:
+  deskrun completion bash
+  deskrun completion zsh
+  deskrun completion fish
+`,
+	ValidArgs:             []string{"bash", "zsh", "fish"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(os.Stdout, true)
+	default:
+		return fmt.Errorf("unsupported shell: %s", args[0])
+	}
+}
+
+// completeInstallations completes the first argument with the names of the
+// configured runner installations
+func completeInstallations(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	names := make([]string, 0, len(configMgr.GetConfig().Installations))
+	for name := range configMgr.GetConfig().Installations {
+		names = append(names, name)
+	}
+	return matchingNames(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeClusterHosts completes the first argument with the names of the
+// configured cluster hosts
+func completeClusterHosts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	names := make([]string, 0, len(configMgr.GetConfig().ClusterHosts))
+	for name := range configMgr.GetConfig().ClusterHosts {
+		names = append(names, name)
+	}
+	return matchingNames(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeCacheVolumes completes the first argument with the names of the
+// configured cache volumes
+func completeCacheVolumes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	names := make([]string, 0, len(configMgr.GetConfig().CacheVolumes))
+	for name := range configMgr.GetConfig().CacheVolumes {
+		names = append(names, name)
+	}
+	return matchingNames(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// matchingNames returns the sorted names that start with prefix
+func matchingNames(names []string, prefix string) []string {
+	var matching []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			matching = append(matching, name)
+		}
+	}
+	sort.Strings(matching)
+	return matching
+}
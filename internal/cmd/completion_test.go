@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Completion", func() {
+	Describe("matchingNames", func() {
+		It("should return the sorted names with the prefix", func() {
+			names := []string{"gpu-runner", "my-runner-b", "my-runner-a"}
+			Expect(matchingNames(names, "my-")).To(Equal([]string{"my-runner-a", "my-runner-b"}))
+			Expect(matchingNames(names, "")).To(Equal([]string{"gpu-runner", "my-runner-a", "my-runner-b"}))
+			Expect(matchingNames(names, "x")).To(BeEmpty())
+		})
+	})
+
+	Describe("completion functions", func() {
+		It("should only complete the first argument", func() {
+			names, directive := completeInstallations(removeCmd, []string{"my-runner"}, "")
+			Expect(names).To(BeEmpty())
+			Expect(directive).To(Equal(cobra.ShellCompDirectiveNoFileComp))
+
+			names, directive = completeClusterHosts(clusterHostDeleteCmd, []string{"deskrun-a1b2c3"}, "")
+			Expect(names).To(BeEmpty())
+			Expect(directive).To(Equal(cobra.ShellCompDirectiveNoFileComp))
+		})
+
+		It("should be registered for commands taking names", func() {
+			for _, cmd := range []*cobra.Command{removeCmd, statusCmd, describeCmd, clusterHostDeleteCmd} {
+				Expect(cmd.ValidArgsFunction).NotTo(BeNil(), cmd.Name())
+			}
+		})
+	})
+
+	Describe("completion command", func() {
+		DescribeTable("shell validation",
+			func(args []string, valid bool) {
+				err := completionCmd.Args(completionCmd, args)
+				if valid {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(err).To(HaveOccurred())
+				}
+			},
+			Entry("bash", []string{"bash"}, true),
+			Entry("zsh", []string{"zsh"}, true),
+			Entry("fish", []string{"fish"}, true),
+			Entry("unsupported shell", []string{"tcsh"}, false),
+			Entry("no shell", []string{}, false),
+		)
+	})
+})
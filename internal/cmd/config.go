@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/pkg/templates"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the deskrun configuration file",
+	Long: `Manage the deskrun configuration file.
+
+The configuration is stored as YAML in ~/.deskrun/config.yaml. The JSON config
+of older deskrun versions (~/.deskrun/config.json) is migrated automatically.`,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check the configuration file for errors",
+	Long: `Check a configuration file for errors, e.g. after editing it by hand.
+
+The file must match the config schema: unknown fields, e.g. misspelled ones,
+are reported. Every runner installation is checked against the same rules
+that are applied when its manifests are rendered and when it is added.
+
+Without a file argument, ~/.deskrun/config.yaml is checked.
+
+Examples:
+  deskrun config validate
+  deskrun config validate ./config.yaml
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigValidate,
+}
+
+var (
+	configExportRedact bool
+	configExportOutput string
+)
+
+var configExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the configuration, e.g. to move it to another machine",
+	Long: `Export the configuration as YAML, e.g. to move it to another machine or to
+commit it to a dotfiles repository.
+
+Auth values and registry passwords are read from the secret store and
+included in the export. With --redact-secrets they are replaced with
+'` + config.RedactedValue + `', and 'deskrun config import' prompts for them.
+The secret store backend is not exported; an import keeps the one of the
+importing machine.
+
+Examples:
+  deskrun config export -o deskrun-config.yaml
+  deskrun config export --redact-secrets > ~/dotfiles/deskrun.yaml
+`,
+	Args: cobra.NoArgs,
+	RunE: runConfigExport,
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Replace the configuration with an exported one",
+	Long: `Replace the configuration with one exported with 'deskrun config export'.
+
+Redacted auth values and registry passwords are prompted for: tokens and
+passwords are read without echoing them, for GitHub App installations the
+path of the private key file is asked. The previous config file is kept as
+~/.deskrun/config.yaml.bak. Run 'deskrun up' afterwards to deploy the
+imported runners.
+
+Examples:
+  deskrun config import deskrun-config.yaml
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigImport,
+}
+
+func init() {
+	configExportCmd.Flags().BoolVar(&configExportRedact, "redact-secrets", false, "Replace auth values and registry passwords with a placeholder")
+	configExportCmd.Flags().StringVarP(&configExportOutput, "output", "o", "", "File to write the export to (default: stdout)")
+
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		// Loading the config migrates a JSON config of older versions first
+		configMgr, err := config.NewManager()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		path = configMgr.GetConfigPath()
+	}
+
+	cfg, err := config.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	problems := append(validateInstallations(cfg.Installations), validateCacheVolumeConfig(cfg)...)
+	problems = append(problems, validateDeployConfig(cfg)...)
+	printConfigProblems(os.Stdout, path, len(cfg.Installations), problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("config has %d problem(s)", len(problems))
+	}
+	return nil
+}
+
+// validateInstallations checks installations and returns their problems,
+// sorted by installation name
+func validateInstallations(installations map[string]*types.RunnerInstallation) []error {
+	names := make([]string, 0, len(installations))
+	for name := range installations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []error
+	for _, name := range names {
+		for _, err := range validateInstallation(name, installations[name]) {
+			problems = append(problems, fmt.Errorf("installation %s: %w", name, err))
+		}
+	}
+	return problems
+}
+
+// validateInstallation applies the checks of the templates and of
+// 'deskrun add' to a configured installation
+func validateInstallation(name string, installation *types.RunnerInstallation) []error {
+	var problems []error
+	if installation.Name != name {
+		problems = append(problems, fmt.Errorf("name %q does not match its key", installation.Name))
+	}
+
+	instances := installation.Instances
+	if instances == 0 {
+		// Configs of older versions leave out the number of instances
+		instances = 1
+	}
+	for _, instanceName := range instanceNames(name, instances) {
+		templateConfig := templates.Config{
+			Installation: installation,
+			InstanceName: instanceName,
+		}
+		if err := templateConfig.Validate(); err != nil {
+			problems = append(problems, err)
+			// All instances share the installation's settings
+			break
+		}
+	}
+
+	if err := validateAddParams(instances, installation.MaxRunners, installation.ContainerMode, installation.CachePaths, installation.Mounts); err != nil {
+		problems = append(problems, err)
+	}
+	if err := validateCacheStorage(installation); err != nil {
+		problems = append(problems, err)
+	}
+
+	return problems
+}
+
+// instanceNames returns the scale set names of an installation's instances
+func instanceNames(name string, instances int) []string {
+	if instances <= 1 {
+		return []string{name}
+	}
+
+	names := make([]string, 0, instances)
+	for i := 1; i <= instances; i++ {
+		names = append(names, fmt.Sprintf("%s-%d", name, i))
+	}
+	return names
+}
+
+// printConfigProblems displays the result of validating a config file
+func printConfigProblems(w io.Writer, path string, installations int, problems []error) {
+	if len(problems) == 0 {
+		_, _ = fmt.Fprintf(w, "✓ %s is valid (%d installation(s))\n", path, installations)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "✗ %s has %d problem(s):\n", path, len(problems))
+	for _, problem := range problems {
+		_, _ = fmt.Fprintf(w, "  - %v\n", problem)
+	}
+}
+
+func runConfigExport(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	data, err := configMgr.Export(configExportRedact)
+	if err != nil {
+		return err
+	}
+
+	if configExportOutput == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	// Exports with secrets must only be readable by the user
+	mode := os.FileMode(0600)
+	if configExportRedact {
+		mode = 0644
+	}
+	if err := os.WriteFile(configExportOutput, data, mode); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Printf("✓ Config exported to %s\n", configExportOutput)
+	return nil
+}
+
+func runConfigImport(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	imported, err := config.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	if problems := append(validateInstallations(imported.Installations), validateCacheVolumeConfig(imported)...); len(problems) > 0 {
+		printConfigProblems(os.Stdout, args[0], len(imported.Installations), problems)
+		return fmt.Errorf("%s has %d problem(s)", args[0], len(problems))
+	}
+
+	if err := fillRedactedSecrets(imported, promptSecret, promptLine); err != nil {
+		return err
+	}
+	if err := configMgr.Import(imported); err != nil {
+		return fmt.Errorf("failed to import config: %w", err)
+	}
+
+	fmt.Printf("✓ Imported %d installation(s) and %d cluster host(s)\n", len(imported.Installations), len(imported.ClusterHosts))
+	fmt.Println("\nTo deploy the runners, run: deskrun up")
+	return nil
+}
+
+// fillRedactedSecrets prompts for the redacted auth values and registry
+// passwords of an imported config. Tokens and passwords are read with
+// promptSecret, the private key file of GitHub App installations with promptLine.
+func fillRedactedSecrets(cfg *config.Config, promptSecret, promptLine func(label string) (string, error)) error {
+	for _, installation := range sortedInstallations(cfg.Installations) {
+		if installation.AuthValue != config.RedactedValue {
+			continue
+		}
+
+		if installation.AuthType == types.AuthTypeGitHubApp {
+			path, err := promptLine(fmt.Sprintf("Private key file of the GitHub App of installation %s: ", installation.Name))
+			if err != nil {
+				return err
+			}
+			if path == "" {
+				return fmt.Errorf("no private key given for installation %s", installation.Name)
+			}
+			key, err := readAuthValueFile(installation.AuthType, path)
+			if err != nil {
+				return err
+			}
+			installation.AuthValue = key
+			continue
+		}
+
+		value, err := promptSecret(fmt.Sprintf("Auth value of installation %s: ", installation.Name))
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			return fmt.Errorf("no auth value given for installation %s", installation.Name)
+		}
+		installation.AuthValue = value
+	}
+
+	for _, registry := range cfg.SortedRegistries() {
+		if registry.Password != config.RedactedValue {
+			continue
+		}
+
+		value, err := promptSecret(fmt.Sprintf("Password of registry %s (%s): ", registry.Name, registry.Server))
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			return fmt.Errorf("no password given for registry %s", registry.Name)
+		}
+		registry.Password = value
+	}
+
+	return nil
+}
@@ -0,0 +1,687 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	dashboardCluster  string
+	dashboardInterval time.Duration
+)
+
+const (
+	// dashboardJobsInterval is how often recent jobs are fetched from GitHub,
+	// which is less often than the cluster to stay within the API rate limit
+	dashboardJobsInterval = 30 * time.Second
+	// dashboardRecentRuns is the number of completed workflow runs whose jobs are fetched
+	dashboardRecentRuns = 10
+	// dashboardRecentJobs is the number of recent jobs shown for a scale set
+	dashboardRecentJobs = 5
+
+	// enterAltScreen and leaveAltScreen switch to and from the terminal's
+	// alternate screen, hiding the cursor while the dashboard is shown
+	enterAltScreen = "\033[?1049h\033[?25l"
+	leaveAltScreen = "\033[?25h\033[?1049l"
+)
+
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Interactive terminal dashboard of clusters and runners",
+	Long: `Show an interactive dashboard of the clusters, their runner scale sets,
+the live state of their EphemeralRunners and the outcome of recent jobs.
+
+The dashboard refreshes every --interval. Recent jobs are fetched from the
+GitHub Actions API for repository-level installations using PAT
+authentication.
+
+Keys:
+  ↑/k, ↓/j  Select a runner scale set
+  +/-       Raise or lower the maximum runners of the selected scale set
+  r         Restart the listener of the selected scale set
+  l         Follow the logs of the selected scale set (Ctrl-C to return)
+  d         Delete the selected scale set from the cluster (asks to confirm)
+  q         Quit
+
+Examples:
+  deskrun dashboard
+  deskrun dashboard --cluster deskrun-gpu --interval 5s
+`,
+	Args: cobra.NoArgs,
+	RunE: runDashboard,
+}
+
+func init() {
+	dashboardCmd.Flags().StringVar(&dashboardCluster, "cluster", "", "Only show runners of this cluster")
+	dashboardCmd.Flags().DurationVar(&dashboardInterval, "interval", 2*time.Second, "Refresh interval")
+	rootCmd.AddCommand(dashboardCmd)
+}
+
+// dashboardClusterState is a cluster shown on the dashboard
+type dashboardClusterState struct {
+	Name   string
+	Exists bool
+	Err    string
+}
+
+// dashboardScaleSet is a deployed runner scale set shown on the dashboard
+type dashboardScaleSet struct {
+	Cluster string
+	Name    string
+	// Installation is the configured installation of the scale set, nil for
+	// scale sets that are no longer configured
+	Installation *types.RunnerInstallation
+	Runners      []runner.EphemeralRunner
+	// Jobs are the recently completed jobs that targeted the scale set
+	Jobs []github.WorkflowJob
+	Err  string
+}
+
+// dashboardData is a snapshot of everything the dashboard shows
+type dashboardData struct {
+	Clusters  []dashboardClusterState
+	ScaleSets []dashboardScaleSet
+	UpdatedAt time.Time
+}
+
+// dashboardAction is an action the dashboard performs in response to a key
+type dashboardAction int
+
+const (
+	dashboardNone dashboardAction = iota
+	dashboardQuit
+	dashboardScaleUp
+	dashboardScaleDown
+	dashboardRestart
+	dashboardLogs
+	dashboardDelete
+)
+
+// dashboardModel is the state of the dashboard. Keys update it and view
+// renders it, so the dashboard can be tested without a terminal.
+type dashboardModel struct {
+	data     dashboardData
+	selected int
+	// confirming is the scale set whose deletion awaits confirmation
+	confirming string
+	// message reports the outcome of the last action
+	message string
+}
+
+// selectedScaleSet returns the selected scale set, or nil if there is none
+func (m *dashboardModel) selectedScaleSet() *dashboardScaleSet {
+	if m.selected < 0 || m.selected >= len(m.data.ScaleSets) {
+		return nil
+	}
+	return &m.data.ScaleSets[m.selected]
+}
+
+// setData replaces the shown data, keeping the selected scale set selected
+func (m *dashboardModel) setData(data dashboardData) {
+	selected := m.selectedScaleSet()
+	m.data = data
+	if selected != nil {
+		for i, scaleSet := range data.ScaleSets {
+			if scaleSet.Cluster == selected.Cluster && scaleSet.Name == selected.Name {
+				m.selected = i
+				return
+			}
+		}
+	}
+	if m.selected >= len(data.ScaleSets) {
+		m.selected = len(data.ScaleSets) - 1
+	}
+	if m.selected < 0 {
+		m.selected = 0
+	}
+}
+
+// update handles a key and returns the action to perform
+func (m *dashboardModel) update(key string) dashboardAction {
+	if m.confirming != "" {
+		name := m.confirming
+		m.confirming = ""
+		if key == "y" || key == "Y" {
+			return dashboardDelete
+		}
+		m.message = fmt.Sprintf("Kept %s", name)
+		return dashboardNone
+	}
+
+	switch key {
+	case "q", "ctrl+c":
+		return dashboardQuit
+	case "up", "k":
+		if m.selected > 0 {
+			m.selected--
+		}
+		return dashboardNone
+	case "down", "j":
+		if m.selected < len(m.data.ScaleSets)-1 {
+			m.selected++
+		}
+		return dashboardNone
+	}
+
+	selected := m.selectedScaleSet()
+	if selected == nil {
+		return dashboardNone
+	}
+	switch key {
+	case "+":
+		return dashboardScaleUp
+	case "-":
+		return dashboardScaleDown
+	case "r":
+		return dashboardRestart
+	case "l":
+		return dashboardLogs
+	case "d":
+		m.confirming = selected.Name
+		m.message = fmt.Sprintf("Delete %s from cluster '%s'? [y/N]", selected.Name, selected.Cluster)
+	}
+	return dashboardNone
+}
+
+// view renders the dashboard, cutting lines to the terminal width
+func (m *dashboardModel) view(now time.Time, width int) string {
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	updated := "loading..."
+	if !m.data.UpdatedAt.IsZero() {
+		updated = "updated " + m.data.UpdatedAt.Format("15:04:05")
+	}
+	add("deskrun dashboard (%s)", updated)
+	add("↑/↓ select  +/- scale  r restart  l logs  d delete  q quit")
+
+	for _, cluster := range m.data.Clusters {
+		add("")
+		switch {
+		case cluster.Err != "":
+			add("Cluster '%s': %s", cluster.Name, cluster.Err)
+			continue
+		case !cluster.Exists:
+			add("Cluster '%s' does not exist", cluster.Name)
+			continue
+		}
+		add("Cluster '%s' is running", cluster.Name)
+
+		shown := false
+		for i, scaleSet := range m.data.ScaleSets {
+			if scaleSet.Cluster != cluster.Name {
+				continue
+			}
+			shown = true
+
+			cursor := " "
+			if i == m.selected {
+				cursor = ">"
+			}
+			add("%s %-30s %s", cursor, scaleSet.Name, dashboardScaleSetSummary(scaleSet))
+		}
+		if !shown {
+			add("  No runners found in cluster")
+		}
+	}
+
+	if selected := m.selectedScaleSet(); selected != nil {
+		add("")
+		add("Runners of %s:", selected.Name)
+		if selected.Err != "" {
+			add("  %s", selected.Err)
+		} else if len(selected.Runners) == 0 {
+			add("  none")
+		}
+		for _, r := range selected.Runners {
+			phase := r.Phase
+			if phase == "" {
+				phase = "Pending"
+			}
+			line := fmt.Sprintf("  %-5s %-10s %s", formatSince(now.Sub(r.Created)), phase, r.Name)
+			if r.JobDisplayName != "" {
+				line += fmt.Sprintf("  [%s]", r.JobDisplayName)
+			}
+			add("%s", line)
+		}
+
+		add("")
+		add("Recent jobs:")
+		if len(selected.Jobs) == 0 {
+			add("  none")
+		}
+		for _, job := range selected.Jobs {
+			name := job.Name
+			if job.WorkflowName != "" {
+				name = job.WorkflowName + " / " + name
+			}
+			add("  %s %-10s %-5s %s", jobOutcomeSymbol(job.Conclusion), job.Conclusion, formatSince(now.Sub(job.CompletedAt)), name)
+		}
+	}
+
+	if m.message != "" {
+		add("")
+		add("%s", m.message)
+	}
+
+	for i, line := range lines {
+		if width > 0 && len([]rune(line)) > width {
+			lines[i] = string([]rune(line)[:width])
+		}
+	}
+	// The terminal is in raw mode, so lines need an explicit carriage return
+	return clearScreen + strings.Join(lines, "\r\n")
+}
+
+// dashboardScaleSetSummary summarizes the limits and runner phases of a scale set
+func dashboardScaleSetSummary(scaleSet dashboardScaleSet) string {
+	var summary []string
+	if scaleSet.Installation != nil {
+		if scaleSet.Installation.Instances > 1 {
+			summary = append(summary, "min 1, max 1")
+		} else {
+			summary = append(summary, fmt.Sprintf("min %d, max %d", scaleSet.Installation.MinRunners, scaleSet.Installation.MaxRunners))
+		}
+	} else {
+		summary = append(summary, "not configured")
+	}
+
+	phases := map[string]int{}
+	busy := 0
+	for _, r := range scaleSet.Runners {
+		phase := r.Phase
+		if phase == "" {
+			phase = "Pending"
+		}
+		phases[phase]++
+		if r.Busy() {
+			busy++
+		}
+	}
+	runners := fmt.Sprintf("%d runners", len(scaleSet.Runners))
+	var counts []string
+	for _, phase := range []string{"Pending", "Running", "Failed"} {
+		if phases[phase] > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", phases[phase], strings.ToLower(phase)))
+		}
+	}
+	if busy > 0 {
+		counts = append(counts, fmt.Sprintf("%d busy", busy))
+	}
+	if len(counts) > 0 {
+		runners += " (" + strings.Join(counts, ", ") + ")"
+	}
+	return strings.Join(append(summary, runners), "  ")
+}
+
+// jobOutcomeSymbol returns the symbol shown for the conclusion of a job
+func jobOutcomeSymbol(conclusion string) string {
+	switch conclusion {
+	case "success":
+		return "✓"
+	case "failure", "timed_out", "startup_failure":
+		return "✗"
+	default:
+		return "-"
+	}
+}
+
+// parseKeys translates the bytes read from a terminal in raw mode into key names
+func parseKeys(input []byte) []string {
+	var keys []string
+	for i := 0; i < len(input); i++ {
+		switch {
+		case input[i] == 0x03:
+			keys = append(keys, "ctrl+c")
+		case input[i] == 0x1b && i+2 < len(input) && input[i+1] == '[':
+			switch input[i+2] {
+			case 'A':
+				keys = append(keys, "up")
+			case 'B':
+				keys = append(keys, "down")
+			}
+			i += 2
+		case input[i] == 0x1b:
+			keys = append(keys, "esc")
+		default:
+			keys = append(keys, string(input[i]))
+		}
+	}
+	return keys
+}
+
+// readKeys sends the keys typed on the terminal until reading fails
+func readKeys(r io.Reader, keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+// dashboardSession loads the dashboard data and performs its actions. Data is
+// loaded in the background, so mu serializes loading and actions.
+type dashboardSession struct {
+	mu         sync.Mutex
+	configMgr  *config.Manager
+	runnerMgrs map[string]*runner.Manager
+
+	// jobs caches the recent jobs by installation name between GitHub fetches
+	jobs        map[string][]github.WorkflowJob
+	jobsFetched time.Time
+}
+
+// load gathers the clusters, their scale sets and EphemeralRunners, and the
+// recent jobs of the configured installations
+func (s *dashboardSession) load(ctx context.Context) dashboardData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg := s.configMgr.GetConfig()
+	data := dashboardData{}
+
+	if time.Since(s.jobsFetched) >= dashboardJobsInterval {
+		s.jobs = map[string][]github.WorkflowJob{}
+		for _, installation := range sortedInstallations(cfg.Installations) {
+			if jobs, err := recentJobs(ctx, installation); err == nil {
+				s.jobs[installation.Name] = jobs
+			}
+		}
+		s.jobsFetched = time.Now()
+	}
+
+	for _, clusterName := range targetClusters(cfg, dashboardCluster) {
+		cluster := dashboardClusterState{Name: clusterName}
+
+		runnerMgr, err := s.runnerManager(ctx, clusterName)
+		if err != nil {
+			cluster.Err = err.Error()
+			data.Clusters = append(data.Clusters, cluster)
+			continue
+		}
+		cluster.Exists = runnerMgr != nil
+		data.Clusters = append(data.Clusters, cluster)
+		if runnerMgr == nil {
+			continue
+		}
+
+		names, err := runnerMgr.List(ctx)
+		if err != nil {
+			data.Clusters[len(data.Clusters)-1].Err = fmt.Sprintf("failed to list runners: %v", err)
+			continue
+		}
+		for _, name := range names {
+			scaleSet := dashboardScaleSet{
+				Cluster:      clusterName,
+				Name:         name,
+				Installation: installationOf(cfg, name),
+			}
+			if scaleSet.Runners, err = runnerMgr.EphemeralRunners(ctx, name); err != nil {
+				scaleSet.Err = err.Error()
+			}
+			if scaleSet.Installation != nil {
+				scaleSet.Jobs = recentScaleSetJobs(s.jobs[scaleSet.Installation.Name], name, dashboardRecentJobs)
+			}
+			data.ScaleSets = append(data.ScaleSets, scaleSet)
+		}
+	}
+
+	data.UpdatedAt = time.Now()
+	return data
+}
+
+// runnerManager returns the runner manager of a cluster, or nil if the
+// cluster does not exist
+func (s *dashboardSession) runnerManager(ctx context.Context, clusterName string) (*runner.Manager, error) {
+	if runnerMgr, ok := s.runnerMgrs[clusterName]; ok {
+		return runnerMgr, nil
+	}
+	runnerMgr, err := clusterRunnerManager(ctx, s.configMgr.GetConfig(), clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if runnerMgr != nil {
+		s.runnerMgrs[clusterName] = runnerMgr
+	}
+	return runnerMgr, nil
+}
+
+// scale raises or lowers the maximum runners of a scale set by delta, both
+// on the cluster and in the configuration. It is called with mu held.
+func (s *dashboardSession) scale(ctx context.Context, scaleSet dashboardScaleSet, delta int) (string, error) {
+	installation := scaleSet.Installation
+	if installation == nil {
+		return "", fmt.Errorf("%s is not configured", scaleSet.Name)
+	}
+	if installation.Instances > 1 {
+		return "", fmt.Errorf("instances of %s run a single runner each; change --instances with 'deskrun edit' instead", installation.Name)
+	}
+
+	maxRunners := installation.MaxRunners + delta
+	if maxRunners < 1 {
+		return "", fmt.Errorf("%s runs at most one runner already", scaleSet.Name)
+	}
+	minRunners := min(installation.MinRunners, maxRunners)
+
+	runnerMgr, err := s.runnerManager(ctx, scaleSet.Cluster)
+	if err != nil || runnerMgr == nil {
+		return "", fmt.Errorf("cluster '%s' is not available", scaleSet.Cluster)
+	}
+	if err := setRunnerLimits(ctx, runnerMgr, s.configMgr, installation, minRunners, maxRunners); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Scaled %s to min %d, max %d runners", scaleSet.Name, minRunners, maxRunners), nil
+}
+
+// recentJobs lists the recently completed jobs of an installation's
+// repository, for the installations jobs can be listed for
+func recentJobs(ctx context.Context, installation *types.RunnerInstallation) ([]github.WorkflowJob, error) {
+	target, err := github.ParseConfigURL(installation.Repository)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsOrganization() {
+		return nil, fmt.Errorf("jobs can only be listed for repository installations")
+	}
+
+	client, err := githubClient(installation, target.APIBaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return client.RecentJobs(ctx, target.Owner, target.Repo, dashboardRecentRuns)
+}
+
+// recentScaleSetJobs returns up to limit jobs that targeted the scale set
+func recentScaleSetJobs(jobs []github.WorkflowJob, scaleSet string, limit int) []github.WorkflowJob {
+	var matching []github.WorkflowJob
+	for _, job := range jobs {
+		if job.Targets(scaleSet) && len(matching) < limit {
+			matching = append(matching, job)
+		}
+	}
+	return matching
+}
+
+// installationOf returns the configured installation a scale set belongs to,
+// or nil if it is not configured
+func installationOf(cfg *config.Config, scaleSet string) *types.RunnerInstallation {
+	if installation, ok := cfg.Installations[scaleSet]; ok {
+		return installation
+	}
+	for name, installation := range cfg.Installations {
+		if isInstanceOf(scaleSet, name) {
+			return installation
+		}
+	}
+	return nil
+}
+
+func runDashboard(cmd *cobra.Command, args []string) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("the dashboard requires an interactive terminal")
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	session := &dashboardSession{configMgr: configMgr, runnerMgrs: map[string]*runner.Manager{}}
+
+	// suspend leaves the dashboard screen and restores the terminal, resume enters it again
+	var state *term.State
+	resume := func() error {
+		if state, err = term.MakeRaw(fd); err != nil {
+			return fmt.Errorf("failed to set up terminal: %w", err)
+		}
+		fmt.Print(enterAltScreen)
+		return nil
+	}
+	suspend := func() {
+		fmt.Print(leaveAltScreen)
+		_ = term.Restore(fd, state)
+	}
+	if err := resume(); err != nil {
+		return err
+	}
+	defer suspend()
+
+	keys := make(chan string, 16)
+	go readKeys(os.Stdin, keys)
+
+	loaded := make(chan dashboardData, 1)
+	loading := false
+	load := func() {
+		loading = true
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			loaded <- session.load(ctx)
+		}()
+	}
+	load()
+
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+
+	model := &dashboardModel{}
+	for {
+		width, _, _ := term.GetSize(fd)
+		fmt.Print(model.view(time.Now(), width))
+
+		select {
+		case data := <-loaded:
+			loading = false
+			model.setData(data)
+			continue
+		case <-ticker.C:
+			if !loading {
+				load()
+			}
+			continue
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			action := model.update(key)
+			if action == dashboardNone {
+				continue
+			}
+			if action == dashboardQuit {
+				return nil
+			}
+
+			selected := *model.selectedScaleSet()
+			if err := performDashboardAction(session, model, action, selected, suspend, resume); err != nil {
+				return err
+			}
+			// Drop the keys typed while the action ran
+			for len(keys) > 0 {
+				<-keys
+			}
+			if !loading {
+				load()
+			}
+		}
+	}
+}
+
+// performDashboardAction performs an action on the selected scale set and
+// reports its outcome in the model. Actions that print output run with the
+// dashboard suspended.
+func performDashboardAction(session *dashboardSession, model *dashboardModel, action dashboardAction, selected dashboardScaleSet, suspend func(), resume func() error) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	runnerMgr, err := session.runnerManager(ctx, selected.Cluster)
+	if err != nil || runnerMgr == nil {
+		model.message = fmt.Sprintf("Cluster '%s' is not available", selected.Cluster)
+		return nil
+	}
+
+	switch action {
+	case dashboardScaleUp, dashboardScaleDown:
+		delta := 1
+		if action == dashboardScaleDown {
+			delta = -1
+		}
+		message, err := session.scale(ctx, selected, delta)
+		if err != nil {
+			message = err.Error()
+		}
+		model.message = message
+
+	case dashboardRestart:
+		model.message = fmt.Sprintf("Restarted the listener of %s", selected.Name)
+		if err := runnerMgr.RestartListener(ctx, selected.Name); err != nil {
+			model.message = err.Error()
+		}
+
+	case dashboardLogs:
+		suspend()
+		fmt.Printf("Following logs of %s, press Ctrl-C to return to the dashboard\n", selected.Name)
+		logsCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err := runnerMgr.StreamLogs(logsCtx, []string{selected.Name}, runner.LogOptions{Follow: true, Since: 10 * time.Minute}, os.Stdout)
+		stop()
+		model.message = ""
+		if err != nil && logsCtx.Err() == nil {
+			model.message = fmt.Sprintf("failed to stream logs: %v", err)
+		}
+		return resume()
+
+	case dashboardDelete:
+		suspend()
+		fmt.Printf("Deleting %s from cluster '%s'...\n", selected.Name, selected.Cluster)
+		model.message = fmt.Sprintf("Deleted %s; 'deskrun up' deploys it again unless it is removed from the configuration", selected.Name)
+		if err := runnerMgr.Uninstall(ctx, selected.Name); err != nil {
+			model.message = err.Error()
+		}
+		return resume()
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dashboard", func() {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newData := func() dashboardData {
+		return dashboardData{
+			Clusters: []dashboardClusterState{{Name: "deskrun", Exists: true}, {Name: "deskrun-gpu"}},
+			ScaleSets: []dashboardScaleSet{
+				{
+					Cluster:      "deskrun",
+					Name:         "my-runner",
+					Installation: &types.RunnerInstallation{Name: "my-runner", MinRunners: 1, MaxRunners: 5},
+					Runners: []runner.EphemeralRunner{
+						{Name: "my-runner-abc12-runner-x7k2p", Phase: "Running", Created: now.Add(-2 * time.Minute), JobDisplayName: "build"},
+						{Name: "my-runner-abc12-runner-q9w8e", Created: now.Add(-10 * time.Second)},
+					},
+					Jobs: []github.WorkflowJob{
+						{Name: "test", WorkflowName: "CI", Conclusion: "failure", CompletedAt: now.Add(-5 * time.Minute)},
+					},
+				},
+				{Cluster: "deskrun", Name: "other-runner"},
+			},
+			UpdatedAt: now,
+		}
+	}
+
+	Describe("update", func() {
+		It("should move the selection within the scale sets", func() {
+			model := &dashboardModel{data: newData()}
+			Expect(model.update("up")).To(Equal(dashboardNone))
+			Expect(model.selected).To(Equal(0))
+
+			model.update("down")
+			model.update("j")
+			Expect(model.selected).To(Equal(1))
+			model.update("k")
+			Expect(model.selected).To(Equal(0))
+		})
+
+		DescribeTable("actions",
+			func(key string, action dashboardAction) {
+				model := &dashboardModel{data: newData()}
+				Expect(model.update(key)).To(Equal(action))
+			},
+			Entry("scale up", "+", dashboardScaleUp),
+			Entry("scale down", "-", dashboardScaleDown),
+			Entry("restart", "r", dashboardRestart),
+			Entry("logs", "l", dashboardLogs),
+			Entry("quit", "q", dashboardQuit),
+			Entry("ctrl+c", "ctrl+c", dashboardQuit),
+			Entry("unknown key", "x", dashboardNone),
+		)
+
+		It("should ask to confirm deletion", func() {
+			model := &dashboardModel{data: newData()}
+			Expect(model.update("d")).To(Equal(dashboardNone))
+			Expect(model.message).To(Equal("Delete my-runner from cluster 'deskrun'? [y/N]"))
+			Expect(model.update("y")).To(Equal(dashboardDelete))
+
+			model.update("d")
+			Expect(model.update("n")).To(Equal(dashboardNone))
+			Expect(model.message).To(Equal("Kept my-runner"))
+		})
+
+		It("should ignore actions without scale sets", func() {
+			model := &dashboardModel{}
+			Expect(model.update("+")).To(Equal(dashboardNone))
+			Expect(model.update("d")).To(Equal(dashboardNone))
+			Expect(model.confirming).To(BeEmpty())
+		})
+	})
+
+	Describe("setData", func() {
+		It("should keep the selected scale set selected", func() {
+			model := &dashboardModel{data: newData(), selected: 1}
+			data := newData()
+			data.ScaleSets = append([]dashboardScaleSet{{Cluster: "deskrun", Name: "a-runner"}}, data.ScaleSets...)
+
+			model.setData(data)
+			Expect(model.selectedScaleSet().Name).To(Equal("other-runner"))
+
+			model.setData(dashboardData{})
+			Expect(model.selected).To(Equal(0))
+			Expect(model.selectedScaleSet()).To(BeNil())
+		})
+	})
+
+	Describe("view", func() {
+		It("should show clusters, scale sets, runners and recent jobs", func() {
+			model := &dashboardModel{data: newData(), message: "Scaled my-runner to min 1, max 6 runners"}
+			output := model.view(now, 0)
+
+			Expect(output).To(HavePrefix(clearScreen + "deskrun dashboard (updated 12:00:00)\r\n"))
+			lines := strings.Split(strings.TrimPrefix(output, clearScreen), "\r\n")
+			Expect(lines).To(ContainElements(
+				"Cluster 'deskrun' is running",
+				"> my-runner                      min 1, max 5  2 runners (1 pending, 1 running, 1 busy)",
+				"  other-runner                   not configured  0 runners",
+				"Cluster 'deskrun-gpu' does not exist",
+				"  2m    Running    my-runner-abc12-runner-x7k2p  [build]",
+				"  10s   Pending    my-runner-abc12-runner-q9w8e",
+				"  ✗ failure    5m    CI / test",
+				"Scaled my-runner to min 1, max 6 runners",
+			))
+		})
+
+		It("should cut lines to the terminal width", func() {
+			model := &dashboardModel{data: newData()}
+			for _, line := range strings.Split(model.view(now, 20), "\r\n")[1:] {
+				Expect(len([]rune(line))).To(BeNumerically("<=", 20))
+			}
+		})
+	})
+
+	Describe("parseKeys", func() {
+		It("should translate arrow keys and control characters", func() {
+			Expect(parseKeys([]byte("\x1b[A\x1b[Bq+\x03"))).To(Equal([]string{"up", "down", "q", "+", "ctrl+c"}))
+		})
+	})
+
+	Describe("recentScaleSetJobs", func() {
+		It("should return the most recent jobs targeting the scale set", func() {
+			jobs := []github.WorkflowJob{
+				{ID: 1, Labels: []string{"my-runner"}},
+				{ID: 2, Labels: []string{"ubuntu-latest"}},
+				{ID: 3, Labels: []string{"my-runner"}},
+				{ID: 4, Labels: []string{"my-runner"}},
+			}
+			matching := recentScaleSetJobs(jobs, "my-runner", 2)
+			Expect(matching).To(HaveLen(2))
+			Expect(matching[0].ID).To(Equal(int64(1)))
+			Expect(matching[1].ID).To(Equal(int64(3)))
+		})
+	})
+
+	Describe("installationOf", func() {
+		It("should match installations and their instances", func() {
+			cfg := &config.Config{Installations: map[string]*types.RunnerInstallation{
+				"my-runner":    {Name: "my-runner"},
+				"multi-runner": {Name: "multi-runner", Instances: 3},
+			}}
+			Expect(installationOf(cfg, "my-runner").Name).To(Equal("my-runner"))
+			Expect(installationOf(cfg, "multi-runner-2").Name).To(Equal("multi-runner"))
+			Expect(installationOf(cfg, "unknown")).To(BeNil())
+		})
+	})
+})
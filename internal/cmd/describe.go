@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <name>",
+	Short: "Show a detailed report of a runner installation",
+	Long: `Show a detailed, runner-aware report of a deployed runner installation,
+similar to 'kubectl describe' but covering all resources ARC creates for it.
+
+For every runner scale set of the installation the report includes:
+- The AutoscalingRunnerSet spec and runner counts
+- The listener and the state of its pod
+- The most recent EphemeralRunners and their phases
+- Whether the GitHub config secret and mounted secrets exist
+- The RoleBindings of the runners and the listener
+- Recent warning events of these resources
+
+Examples:
+  deskrun describe my-runner
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runDescribe,
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+}
+
+func runDescribe(cmd *cobra.Command, args []string) error {
+	// Load config
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	name := args[0]
+	clusterName := configMgr.GetConfig().DefaultCluster()
+	var secrets []string
+	if installation, err := configMgr.GetInstallation(name); err == nil {
+		clusterName = configMgr.GetConfig().ClusterFor(installation)
+		secrets = mountedSecrets(installation)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	runnerMgr, err := clusterRunnerManager(ctx, configMgr.GetConfig(), clusterName)
+	if err != nil {
+		return err
+	}
+	if runnerMgr == nil {
+		return fmt.Errorf("cluster '%s' does not exist", clusterName)
+	}
+
+	for i, scaleSet := range scaleSetNames(configMgr, name) {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		description, err := runnerMgr.DescribeScaleSet(ctx, scaleSet, secrets)
+		if err != nil {
+			return fmt.Errorf("failed to describe %s: %w", scaleSet, err)
+		}
+		printScaleSetDescription(os.Stdout, description, time.Now())
+	}
+
+	return nil
+}
+
+// mountedSecrets returns the names of the secrets mounted into the runners of an installation
+func mountedSecrets(installation *types.RunnerInstallation) []string {
+	var secrets []string
+	for _, objectMount := range installation.ObjectMounts {
+		if objectMount.Kind == types.ObjectKindSecret {
+			secrets = append(secrets, objectMount.Name)
+		}
+	}
+	return secrets
+}
+
+// printScaleSetDescription displays the description of a runner scale set
+func printScaleSetDescription(w io.Writer, d *runner.ScaleSetDescription, now time.Time) {
+	_, _ = fmt.Fprintf(w, "Name:          %s\n", d.Name)
+	_, _ = fmt.Fprintf(w, "Namespace:     %s\n", d.Namespace)
+	_, _ = fmt.Fprintf(w, "GitHub URL:    %s\n", d.GitHubConfigURL)
+	if d.RunnerGroup != "" {
+		_, _ = fmt.Fprintf(w, "Runner group:  %s\n", d.RunnerGroup)
+	}
+	_, _ = fmt.Fprintf(w, "Runners:       min %s, max %s\n", formatRunnerLimit(d.MinRunners), formatRunnerLimit(d.MaxRunners))
+	_, _ = fmt.Fprintf(w, "Status:        %d current (%d pending, %d running, %d failed)\n",
+		d.CurrentRunners, d.PendingRunners, d.RunningRunners, d.FailedRunners)
+
+	_, _ = fmt.Fprintln(w)
+	switch {
+	case d.Listener == nil:
+		_, _ = fmt.Fprintln(w, "Listener:      not created yet")
+	case d.Listener.PodPhase == "":
+		_, _ = fmt.Fprintf(w, "Listener:      %s (pod not found)\n", d.Listener.Name)
+	default:
+		ready := "not ready"
+		if d.Listener.Ready {
+			ready = "ready"
+		}
+		_, _ = fmt.Fprintf(w, "Listener:      %s (%s, %s, %d restarts)\n", d.Listener.Name, d.Listener.PodPhase, ready, d.Listener.Restarts)
+	}
+
+	_, _ = fmt.Fprintln(w)
+	if d.TotalRunners > len(d.Runners) {
+		_, _ = fmt.Fprintf(w, "Ephemeral runners (%d most recent of %d):\n", len(d.Runners), d.TotalRunners)
+	} else {
+		_, _ = fmt.Fprintf(w, "Ephemeral runners (%d):\n", d.TotalRunners)
+	}
+	for _, r := range d.Runners {
+		phase := r.Phase
+		if phase == "" {
+			phase = "Pending"
+		}
+		line := fmt.Sprintf("  %-5s %-10s %s", formatSince(now.Sub(r.Created)), phase, r.Name)
+		if r.JobDisplayName != "" {
+			line += fmt.Sprintf("  [%s]", r.JobDisplayName)
+		}
+		if r.Message != "" {
+			line += ": " + r.Message
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Secrets:")
+	if len(d.Secrets) == 0 {
+		_, _ = fmt.Fprintln(w, "  none")
+	}
+	for _, secret := range d.Secrets {
+		presence := "present"
+		if !secret.Exists {
+			presence = "missing"
+		}
+		_, _ = fmt.Fprintf(w, "  %-8s %s\n", presence, secret.Name)
+	}
+
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Role bindings:")
+	if len(d.RoleBindings) == 0 {
+		_, _ = fmt.Fprintln(w, "  none")
+	}
+	for _, rb := range d.RoleBindings {
+		_, _ = fmt.Fprintf(w, "  %s → %s (%s)\n", rb.Name, rb.Role, strings.Join(rb.Subjects, ", "))
+	}
+
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Events:")
+	printed := false
+	printEvents := func(kind, namespace, name string) {
+		events := d.Events[runner.EventKey(namespace, name)]
+		if len(events) == 0 {
+			return
+		}
+		printed = true
+		_, _ = fmt.Fprintf(w, "  [%s] %s\n", kind, name)
+		for _, event := range events {
+			_, _ = fmt.Fprintf(w, "    ✗ : %s\n", formatResourceEvent(event))
+		}
+	}
+	printEvents("AutoscalingRunnerSet", d.Namespace, d.Name)
+	if d.Listener != nil {
+		printEvents("AutoscalingListener", d.Listener.Namespace, d.Listener.Name)
+	}
+	for _, r := range d.Runners {
+		printEvents("EphemeralRunner", d.Namespace, r.Name)
+	}
+	if !printed {
+		_, _ = fmt.Fprintln(w, "  none")
+	}
+}
+
+// formatRunnerLimit formats the min or max runners of a scale set
+func formatRunnerLimit(limit *int64) string {
+	if limit == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *limit)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Describe Command", func() {
+	Describe("mountedSecrets", func() {
+		It("should only return mounted secrets", func() {
+			installation := &types.RunnerInstallation{ObjectMounts: []types.ObjectMount{
+				{Kind: types.ObjectKindSecret, Name: "npm-token", Path: "/secrets/npm"},
+				{Kind: types.ObjectKindConfigMap, Name: "settings", Path: "/etc/settings"},
+			}}
+			Expect(mountedSecrets(installation)).To(Equal([]string{"npm-token"}))
+		})
+	})
+
+	Describe("printScaleSetDescription", func() {
+		now := time.Now()
+		maxRunners := int64(5)
+
+		description := &runner.ScaleSetDescription{
+			Name:            "my-runner",
+			Namespace:       "arc-systems",
+			GitHubConfigURL: "https://github.com/owner/repo",
+			MaxRunners:      &maxRunners,
+			CurrentRunners:  1,
+			RunningRunners:  1,
+			Listener:        &runner.ListenerDescription{Name: "my-runner-754b578d-listener", Namespace: "arc-systems", PodPhase: "Running", Ready: true},
+			Runners: []runner.EphemeralRunner{
+				{Name: "my-runner-abc12-runner-x7k2p", Phase: "Running", Created: now.Add(-3 * time.Minute), JobDisplayName: "build"},
+			},
+			TotalRunners: 1,
+			Secrets: []runner.SecretPresence{
+				{Name: "my-runner-gha-rs-github-secret", Exists: true},
+				{Name: "npm-token"},
+			},
+			RoleBindings: []runner.RoleBindingDescription{
+				{Name: "my-runner-gha-rs-kube-mode", Role: "Role/my-runner-gha-rs-kube-mode", Subjects: []string{"ServiceAccount arc-systems/my-runner-gha-rs-kube-mode"}},
+			},
+			Events: map[string][]runner.ResourceEvent{
+				runner.EventKey("arc-systems", "my-runner-abc12-runner-x7k2p"): {{Reason: "BackOff", Message: "Back-off pulling image", Count: 3}},
+			},
+		}
+
+		It("should aggregate all sections into one report", func() {
+			var buf bytes.Buffer
+			printScaleSetDescription(&buf, description, now)
+
+			output := buf.String()
+			Expect(output).To(ContainSubstring("Runners:       min -, max 5\n"))
+			Expect(output).To(ContainSubstring("Status:        1 current (0 pending, 1 running, 0 failed)\n"))
+			Expect(output).To(ContainSubstring("Listener:      my-runner-754b578d-listener (Running, ready, 0 restarts)\n"))
+			Expect(output).To(ContainSubstring("  3m    Running    my-runner-abc12-runner-x7k2p  [build]\n"))
+			Expect(output).To(ContainSubstring("  present  my-runner-gha-rs-github-secret\n  missing  npm-token\n"))
+			Expect(output).To(ContainSubstring("  my-runner-gha-rs-kube-mode → Role/my-runner-gha-rs-kube-mode (ServiceAccount arc-systems/my-runner-gha-rs-kube-mode)\n"))
+			Expect(output).To(ContainSubstring("  [EphemeralRunner] my-runner-abc12-runner-x7k2p\n    ✗ : BackOff: Back-off pulling image (x3)\n"))
+		})
+
+		It("should report a missing listener and the absence of events", func() {
+			var buf bytes.Buffer
+			printScaleSetDescription(&buf, &runner.ScaleSetDescription{Name: "my-runner", Namespace: "arc-systems"}, now)
+
+			output := buf.String()
+			Expect(output).To(ContainSubstring("Listener:      not created yet\n"))
+			Expect(output).To(ContainSubstring("Ephemeral runners (0):\n"))
+			Expect(output).To(HaveSuffix("Events:\n  none\n"))
+		})
+	})
+})
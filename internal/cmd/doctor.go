@@ -0,0 +1,762 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/cluster"
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/internal/incus"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+	kindversion "sigs.k8s.io/kind/pkg/cmd/kind/version"
+)
+
+const (
+	// diskSpaceWarnBytes and diskSpaceFailBytes are the free disk space thresholds
+	// below which the disk space check warns or fails
+	diskSpaceWarnBytes = 10 << 30
+	diskSpaceFailBytes = 2 << 30
+
+	// stuckFinalizerAge is how long a resource may wait on finalizers before
+	// it is reported as stuck
+	stuckFinalizerAge = 10 * time.Minute
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose common problems with the local setup",
+	Long: `Run a series of checks against the local environment and every known
+cluster, and print remediation hints for anything that looks wrong.
+
+The following is checked:
+- Docker daemon or Podman availability
+- kind version, or the CLI of the configured cluster provider
+- Free disk space for container images and the deskrun cache
+- Incus availability (for cluster hosts)
+- Per cluster: Nix store mounts, kubeconfig context reachability,
+  ARC CRD health and resources stuck on finalizers
+- Validity and admin access of the GitHub tokens of all installations
+
+The command exits with a non-zero status when any check fails.
+
+Example:
+  deskrun doctor
+`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// checkStatus is the outcome of a single doctor check
+type checkStatus int
+
+const (
+	checkOK checkStatus = iota
+	checkWarn
+	checkFail
+	checkSkip
+)
+
+// checkResult is the result of a single doctor check
+type checkResult struct {
+	Name    string
+	Status  checkStatus
+	Message string
+	Hint    string // Remediation hint, shown for warnings and failures
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	// Load config
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	cfg := configMgr.GetConfig()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	failures := 0
+	report := func(results ...checkResult) {
+		for _, result := range results {
+			printCheckResult(os.Stdout, result)
+			if result.Status == checkFail {
+				failures++
+			}
+		}
+	}
+
+	fmt.Println("Host:")
+	runtime := cluster.DetectContainerRuntime()
+	runtimeResult := checkContainerRuntime(ctx, runtime)
+	report(runtimeResult)
+	report(checkClusterProvider(ctx, cfg.ClusterProvider))
+	if hostErr := cluster.ValidateHost(); hostErr != nil || cluster.WSLVersion() > 0 {
+		report(checkWSL(cluster.WSLVersion(), hostErr))
+	}
+	report(checkDiskSpace(diskSpacePaths(runtime))...)
+	report(checkIncus(ctx, cfg.ClusterHosts)...)
+
+	for _, clusterName := range cfg.ClusterNames() {
+		fmt.Printf("\nCluster '%s':\n", clusterName)
+		if runtimeResult.Status == checkFail {
+			report(checkResult{Name: "Cluster", Status: checkSkip, Message: fmt.Sprintf("skipped, %s is not available", runtimeResult.Name)})
+			continue
+		}
+		report(checkCluster(ctx, cfg, clusterName)...)
+	}
+
+	if len(cfg.Installations) > 0 {
+		fmt.Println("\nGitHub:")
+		report(checkGitHubTokens(ctx, cfg.Installations)...)
+	}
+
+	fmt.Println()
+	if failures > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", failures)
+	}
+
+	fmt.Println("No problems found")
+	return nil
+}
+
+// printCheckResult prints a check result with its remediation hint
+func printCheckResult(w io.Writer, result checkResult) {
+	symbol := map[checkStatus]string{
+		checkOK:   "✓",
+		checkWarn: "⚠",
+		checkFail: "✗",
+		checkSkip: "-",
+	}[result.Status]
+
+	_, _ = fmt.Fprintf(w, "  %s %s: %s\n", symbol, result.Name, result.Message)
+	if result.Hint != "" && (result.Status == checkWarn || result.Status == checkFail) {
+		_, _ = fmt.Fprintf(w, "      → %s\n", result.Hint)
+	}
+}
+
+// checkContainerRuntime verifies that the CLI of the container runtime
+// (Docker or Podman) is installed and its daemon or socket is reachable
+func checkContainerRuntime(ctx context.Context, runtime cluster.ContainerRuntime) checkResult {
+	if runtime.Name == cluster.RuntimePodman {
+		return checkPodman(ctx, runtime)
+	}
+
+	result := checkResult{Name: "Docker"}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		result.Status = checkFail
+		result.Message = "docker CLI not found in PATH"
+		result.Hint = "Install Docker (https://docs.docker.com/engine/install/) or Podman"
+		return result
+	}
+
+	out, err := exec.CommandContext(ctx, "docker", "info", "--format", "{{.ServerVersion}}").CombinedOutput()
+	if err != nil {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("daemon not reachable: %s", firstLine(string(out)))
+		result.Hint = "Start the Docker daemon and make sure your user can access /var/run/docker.sock (e.g. add it to the docker group)"
+		return result
+	}
+
+	result.Status = checkOK
+	result.Message = fmt.Sprintf("daemon running (server version %s)", strings.TrimSpace(string(out)))
+	return result
+}
+
+// checkPodman verifies that the Podman CLI works and warns when its API
+// socket, which runners use as Docker socket, is not running
+func checkPodman(ctx context.Context, runtime cluster.ContainerRuntime) checkResult {
+	result := checkResult{Name: "Podman"}
+
+	if _, err := exec.LookPath("podman"); err != nil {
+		result.Status = checkFail
+		result.Message = "podman CLI not found in PATH"
+		result.Hint = "Install Podman: https://podman.io/docs/installation"
+		return result
+	}
+
+	out, err := exec.CommandContext(ctx, "podman", "version", "--format", "{{.Client.Version}}").CombinedOutput()
+	if err != nil {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("podman not working: %s", firstLine(string(out)))
+		return result
+	}
+
+	mode := "rootful"
+	if runtime.Rootless {
+		mode = "rootless"
+	}
+	result.Status = checkOK
+	result.Message = fmt.Sprintf("%s (version %s)", mode, strings.TrimSpace(string(out)))
+
+	if runtime.Socket == "" {
+		result.Status = checkWarn
+		result.Message += ", API socket not found"
+		result.Hint = "Enable the Podman API socket (systemctl --user enable --now podman.socket, or without --user for rootful Podman) so runners can use it as Docker socket"
+	}
+	return result
+}
+
+// checkWSL reports the WSL version deskrun runs in on Windows, failing where
+// kind can't create clusters
+func checkWSL(version int, hostErr error) checkResult {
+	result := checkResult{Name: "WSL"}
+	if hostErr != nil {
+		result.Status = checkFail
+		result.Message = hostErr.Error()
+		return result
+	}
+
+	result.Status = checkOK
+	result.Message = fmt.Sprintf("WSL %d, Windows paths of mounts are translated to their WSL mount points", version)
+	return result
+}
+
+// checkClusterProvider checks the tool of the configured cluster provider: the
+// built-in kind, or the k3d or minikube CLI
+func checkClusterProvider(ctx context.Context, provider string) checkResult {
+	if provider == "" || provider == cluster.ProviderKind {
+		return checkKind(ctx)
+	}
+
+	result := checkResult{Name: provider}
+	if !slices.Contains(cluster.Providers(), provider) {
+		result.Status = checkFail
+		result.Message = "unknown cluster provider"
+		result.Hint = fmt.Sprintf("Set cluster_provider in the configuration to one of: %s", strings.Join(cluster.Providers(), ", "))
+		return result
+	}
+
+	path, err := exec.LookPath(provider)
+	if err != nil {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("%s CLI not found in PATH, but it is the configured cluster provider", provider)
+		result.Hint = "Install it, or remove cluster_provider from the configuration to use the built-in kind"
+		return result
+	}
+
+	result.Status = checkOK
+	result.Message = fmt.Sprintf("cluster provider (%s)", path)
+	return result
+}
+
+// checkKind reports the built-in kind version and warns when a kind CLI with
+// a different minor version is installed, as it may use other node images
+func checkKind(ctx context.Context) checkResult {
+	builtin := kindversion.Version()
+	result := checkResult{
+		Name:    "kind",
+		Status:  checkOK,
+		Message: fmt.Sprintf("built-in v%s", builtin),
+	}
+
+	path, err := exec.LookPath("kind")
+	if err != nil {
+		// The kind CLI is optional, deskrun embeds kind as a library
+		return result
+	}
+
+	out, err := exec.CommandContext(ctx, path, "version").Output()
+	if err != nil {
+		result.Status = checkWarn
+		result.Message += fmt.Sprintf(", failed to run %s: %v", path, err)
+		return result
+	}
+
+	cliVersion := parseKindVersion(string(out))
+	if !sameMinorVersion(cliVersion, builtin) {
+		result.Status = checkWarn
+		result.Message += fmt.Sprintf(", but kind CLI is v%s", cliVersion)
+		result.Hint = fmt.Sprintf("Clusters are created with kind v%s; install the same kind CLI version to manage them with kind directly", builtin)
+		return result
+	}
+
+	result.Message += fmt.Sprintf(", kind CLI v%s", cliVersion)
+	return result
+}
+
+// parseKindVersion extracts the version from 'kind version' output,
+// e.g. "kind v0.30.0 go1.24.6 linux/amd64" returns "0.30.0"
+func parseKindVersion(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) < 2 {
+		return strings.TrimSpace(output)
+	}
+	return strings.TrimPrefix(fields[1], "v")
+}
+
+// sameMinorVersion reports whether two semantic versions share major and minor
+func sameMinorVersion(a, b string) bool {
+	majorMinor := func(version string) string {
+		parts := strings.SplitN(version, ".", 3)
+		if len(parts) < 2 {
+			return version
+		}
+		return parts[0] + "." + parts[1]
+	}
+	return majorMinor(a) == majorMinor(b)
+}
+
+// diskSpacePaths returns the paths whose file systems hold the images of the
+// container runtime and the deskrun cache
+func diskSpacePaths(runtime cluster.ContainerRuntime) []string {
+	homeDir, homeErr := os.UserHomeDir()
+
+	var paths []string
+	switch {
+	case runtime.Name == cluster.RuntimePodman && runtime.Rootless && homeErr == nil:
+		paths = append(paths, filepath.Join(homeDir, ".local", "share", "containers", "storage"))
+	case runtime.Name == cluster.RuntimePodman:
+		paths = append(paths, "/var/lib/containers/storage")
+	default:
+		paths = append(paths, "/var/lib/docker")
+	}
+
+	if homeErr == nil {
+		paths = append(paths, filepath.Join(homeDir, ".cache", "deskrun"))
+	}
+	return paths
+}
+
+// checkDiskSpace checks the free space of the file systems holding the given
+// paths. Paths that do not exist are checked at their closest existing parent.
+func checkDiskSpace(paths []string) []checkResult {
+	var results []checkResult
+	for _, path := range paths {
+		result := checkResult{Name: "Disk space"}
+
+		existing := closestExistingPath(path)
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(existing, &stat); err != nil {
+			result.Status = checkWarn
+			result.Message = fmt.Sprintf("failed to check %s: %v", existing, err)
+			results = append(results, result)
+			continue
+		}
+
+		free := stat.Bavail * uint64(stat.Bsize)
+		result.Message = fmt.Sprintf("%s free on %s", formatBytes(free), path)
+		result.Hint = "Free up disk space, e.g. with 'docker system prune' or by removing unused caches in ~/.cache/deskrun"
+		switch {
+		case free < diskSpaceFailBytes:
+			result.Status = checkFail
+		case free < diskSpaceWarnBytes:
+			result.Status = checkWarn
+		default:
+			result.Status = checkOK
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// closestExistingPath walks up from path until it finds an existing directory
+func closestExistingPath(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// formatBytes renders a byte count in GiB or MiB
+func formatBytes(bytes uint64) string {
+	if bytes >= 1<<30 {
+		return fmt.Sprintf("%.1f GiB", float64(bytes)/(1<<30))
+	}
+	return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+}
+
+// checkIncus checks that the incus remotes cluster hosts live on are
+// reachable, or the default remote if there are no cluster hosts
+func checkIncus(ctx context.Context, hosts map[string]*types.ClusterHost) []checkResult {
+	hostsByRemote := make(map[string]int)
+	for _, host := range hosts {
+		hostsByRemote[host.Remote]++
+	}
+	if len(hostsByRemote) == 0 {
+		hostsByRemote[""] = 0
+	}
+
+	var results []checkResult
+	for _, remote := range slices.Sorted(maps.Keys(hostsByRemote)) {
+		results = append(results, checkIncusRemote(ctx, remote, hostsByRemote[remote]))
+	}
+	return results
+}
+
+// checkIncusRemote checks that an incus remote (the default remote if empty)
+// is reachable when cluster hosts live on it
+func checkIncusRemote(ctx context.Context, remote string, clusterHosts int) checkResult {
+	result := checkResult{Name: "Incus"}
+	if remote != "" {
+		result.Name = fmt.Sprintf("Incus remote %s", remote)
+	}
+
+	incusMgr, err := incus.NewManager(remote)
+	if err == nil {
+		_, err = incusMgr.ListContainers(ctx, "")
+	}
+	if err != nil {
+		if clusterHosts == 0 {
+			result.Status = checkSkip
+			result.Message = "not reachable (only needed for 'deskrun cluster-host')"
+			return result
+		}
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("not reachable, but %d cluster host(s) are configured: %v", clusterHosts, err)
+		result.Hint = "Install Incus (https://linuxcontainers.org/incus/docs/main/installing/), make sure the daemon is running and your user is in the incus-admin group"
+		if remote != "" {
+			result.Hint = fmt.Sprintf("Check that the incus server of remote %s is running and reachable ('incus remote list')", remote)
+		}
+		return result
+	}
+
+	result.Status = checkOK
+	result.Message = "reachable"
+	return result
+}
+
+// checkCluster runs all checks against a single cluster
+func checkCluster(ctx context.Context, cfg *config.Config, clusterName string) []checkResult {
+	installations := len(cfg.InstallationsForCluster(clusterName))
+	clusterMgr := newClusterManager(cfg, clusterName)
+
+	// Existing clusters of a kube context are only checked for reachability below
+	var results []checkResult
+	if !clusterMgr.IsExternal() {
+		exists, err := clusterMgr.Exists(ctx)
+		if err != nil {
+			return []checkResult{{
+				Name:    "Cluster",
+				Status:  checkFail,
+				Message: fmt.Sprintf("failed to check cluster: %v", err),
+			}}
+		}
+		if !exists {
+			result := checkResult{Name: "Cluster", Status: checkSkip, Message: "does not exist"}
+			if installations > 0 {
+				result.Status = checkWarn
+				result.Message = fmt.Sprintf("does not exist, but %d installation(s) are configured for it", installations)
+				result.Hint = "Run 'deskrun up' to create it"
+			}
+			return []checkResult{result}
+		}
+
+		if vm := cluster.CurrentHostVM(); vm != nil {
+			results = append(results, checkHostVM(vm, cfg.InstallationsForCluster(clusterName)))
+		}
+		results = append(results, checkNixMounts(ctx, clusterMgr, clusterName))
+		if usesHostDocker(cfg.InstallationsForCluster(clusterName)) {
+			results = append(results, checkDockerSocketMount(ctx, clusterMgr, clusterName))
+		}
+	}
+
+	runnerMgr := runner.NewManager(clusterMgr)
+	reachable := checkKubeContext(ctx, runnerMgr, clusterMgr, clusterName)
+	results = append(results, reachable)
+	if reachable.Status == checkFail {
+		return results
+	}
+
+	results = append(results, checkARCCRDs(ctx, runnerMgr, installations))
+	if runtimeClasses := installationRuntimeClasses(cfg.InstallationsForCluster(clusterName)); len(runtimeClasses) > 0 {
+		results = append(results, checkRuntimeClasses(ctx, runnerMgr, runtimeClasses))
+	}
+	results = append(results, checkStuckFinalizers(ctx, runnerMgr))
+	return results
+}
+
+// checkNixMounts verifies that the host Nix store is mounted into the cluster node
+func checkNixMounts(ctx context.Context, clusterMgr *cluster.Manager, clusterName string) checkResult {
+	result := checkResult{Name: "Nix store"}
+
+	nixStore, _ := cluster.DetectNixMounts()
+	if nixStore == nil {
+		result.Status = checkSkip
+		result.Message = "no Nix store on host"
+		if cluster.CurrentHostVM() != nil {
+			result.Message = "not mounted on macOS, the Nix store holds Darwin binaries"
+		}
+		return result
+	}
+
+	mounts, err := clusterMgr.NodeMounts(ctx)
+	if err != nil {
+		result.Status = checkWarn
+		result.Message = err.Error()
+		return result
+	}
+
+	if !slices.Contains(mounts, nixStore.ContainerPath) {
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("%s is not mounted into the cluster node", nixStore.HostPath)
+		result.Hint = fmt.Sprintf("The cluster was created before Nix was detected; recreate it with 'deskrun cluster delete --cluster %s && deskrun up'", clusterName)
+		return result
+	}
+
+	result.Status = checkOK
+	result.Message = fmt.Sprintf("%s mounted", nixStore.HostPath)
+	return result
+}
+
+// usesHostDocker returns true if one of the installations is in host-docker mode
+func usesHostDocker(installations map[string]*types.RunnerInstallation) bool {
+	for _, installation := range installations {
+		if installation.ContainerMode == types.ContainerModeHostDocker {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHostVM reports the VM cluster nodes run in on macOS, failing for the
+// installations whose container mode doesn't work in it
+func checkHostVM(vm *cluster.HostVM, installations map[string]*types.RunnerInstallation) checkResult {
+	result := checkResult{Name: "macOS VM"}
+
+	names := make([]string, 0, len(installations))
+	for name := range installations {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if warning := vm.ModeWarning(installations[name].ContainerMode); warning != "" {
+			result.Status = checkFail
+			result.Message = fmt.Sprintf("installation %s: %s", name, warning)
+			result.Hint = fmt.Sprintf("Switch it with 'deskrun edit %s --mode dind'", name)
+			return result
+		}
+	}
+
+	result.Status = checkOK
+	result.Message = fmt.Sprintf("%s, sharing %s", vm.Name, strings.Join(vm.SharedPaths, ", "))
+	return result
+}
+
+// checkDockerSocketMount verifies that the socket of the container runtime is
+// mounted into the cluster node for the runners of host-docker mode
+func checkDockerSocketMount(ctx context.Context, clusterMgr *cluster.Manager, clusterName string) checkResult {
+	result := checkResult{Name: "Docker socket"}
+
+	socket := cluster.DetectDockerSocket()
+	if socket == nil {
+		result.Status = checkFail
+		result.Message = "no Docker or Podman socket on host, but host-docker runners are configured"
+		result.Hint = "Start the Docker daemon or the Podman API service"
+		return result
+	}
+
+	mounts, err := clusterMgr.NodeMounts(ctx)
+	if err != nil {
+		result.Status = checkWarn
+		result.Message = err.Error()
+		return result
+	}
+
+	if !slices.Contains(mounts, socket.ContainerPath) {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("%s is not mounted into the cluster node", socket.HostPath)
+		result.Hint = fmt.Sprintf("The cluster was created without the socket; recreate it with 'deskrun cluster delete --cluster %s && deskrun up'", clusterName)
+		return result
+	}
+
+	result.Status = checkOK
+	result.Message = fmt.Sprintf("%s mounted", socket.HostPath)
+	return result
+}
+
+// installationRuntimeClasses returns the RuntimeClasses the installations run
+// their runner pods with
+func installationRuntimeClasses(installations map[string]*types.RunnerInstallation) []string {
+	var names []string
+	for _, installation := range installations {
+		if name := installation.RuntimeClassName; name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// checkRuntimeClasses verifies that the cluster has the RuntimeClasses of its
+// installations
+func checkRuntimeClasses(ctx context.Context, runnerMgr *runner.Manager, required []string) checkResult {
+	result := checkResult{Name: "Runtime classes"}
+
+	available, err := runnerMgr.RuntimeClasses(ctx)
+	if err != nil {
+		result.Status = checkWarn
+		result.Message = err.Error()
+		return result
+	}
+
+	var missing []string
+	for _, name := range required {
+		if !slices.Contains(available, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("missing: %s", strings.Join(missing, ", "))
+		result.Hint = "Install the runtime on the cluster nodes, e.g. sysbox with its sysbox-deploy-k8s daemonset, or change the --runtime-class of the installations"
+		return result
+	}
+
+	result.Status = checkOK
+	result.Message = strings.Join(required, ", ")
+	return result
+}
+
+// checkKubeContext verifies that the cluster API server is reachable through its kubeconfig context
+func checkKubeContext(ctx context.Context, runnerMgr *runner.Manager, clusterMgr *cluster.Manager, clusterName string) checkResult {
+	result := checkResult{Name: "Kubeconfig context"}
+
+	version, err := runnerMgr.ServerVersion(ctx)
+	if err != nil {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("%s not reachable: %v", clusterMgr.GetKubeconfig(), err)
+		result.Hint = fmt.Sprintf("Restore the kubeconfig context with 'kind export kubeconfig --name %s', or check that the node container is running with 'docker ps'", clusterName)
+		switch {
+		case clusterMgr.IsExternal():
+			result.Hint = "Check that the cluster of the kube context is running and reachable with 'kubectl --context " + clusterMgr.GetKubeconfig() + " get nodes'"
+		case !clusterMgr.IsKind():
+			result.Hint = fmt.Sprintf("Check that the %s cluster is running, or recreate it with 'deskrun cluster delete --cluster %s && deskrun up'", clusterMgr.ProviderName(), clusterName)
+		}
+		return result
+	}
+
+	result.Status = checkOK
+	result.Message = fmt.Sprintf("%s reachable (Kubernetes %s)", clusterMgr.GetKubeconfig(), version)
+	return result
+}
+
+// checkARCCRDs verifies that the ARC controller CRDs are installed and established
+func checkARCCRDs(ctx context.Context, runnerMgr *runner.Manager, installations int) checkResult {
+	result := checkResult{Name: "ARC CRDs"}
+
+	unhealthy, err := runnerMgr.UnhealthyCRDs(ctx)
+	if err != nil {
+		result.Status = checkFail
+		result.Message = err.Error()
+		return result
+	}
+
+	if len(unhealthy) > 0 {
+		result.Status = checkWarn
+		if installations > 0 {
+			result.Status = checkFail
+		}
+		result.Message = fmt.Sprintf("missing or not established: %s", strings.Join(unhealthy, ", "))
+		result.Hint = "Run 'deskrun up' to install the ARC controller"
+		return result
+	}
+
+	result.Status = checkOK
+	result.Message = "installed and established"
+	return result
+}
+
+// checkStuckFinalizers reports ARC resources that are stuck waiting on finalizers
+func checkStuckFinalizers(ctx context.Context, runnerMgr *runner.Manager) checkResult {
+	result := checkResult{Name: "Finalizers"}
+
+	stuck, err := runnerMgr.StuckResources(ctx, stuckFinalizerAge)
+	if err != nil {
+		result.Status = checkWarn
+		result.Message = err.Error()
+		return result
+	}
+
+	if len(stuck) == 0 {
+		result.Status = checkOK
+		result.Message = "no resources stuck on finalizers"
+		return result
+	}
+
+	first := stuck[0]
+	result.Status = checkWarn
+	result.Message = fmt.Sprintf("%d resource(s) stuck on finalizers, e.g. %s/%s for %s", len(stuck), first.Kind, first.Name, first.DeletedFor.Round(time.Minute))
+	result.Hint = "Run 'deskrun repair' to find the root cause, and 'deskrun repair --force' to remove the finalizers"
+	return result
+}
+
+// checkGitHubTokens validates the PAT of every installation against the GitHub API
+func checkGitHubTokens(ctx context.Context, installations map[string]*types.RunnerInstallation) []checkResult {
+	var results []checkResult
+	for _, installation := range sortedInstallations(installations) {
+		result := checkResult{Name: installation.Name}
+
+		if installation.AuthType == types.AuthTypeGitHubApp {
+			if err := validateAppInstallation(ctx, installation); err != nil {
+				result.Status = checkFail
+				result.Message = err.Error()
+				result.Hint = fmt.Sprintf("Check the app ID, installation ID and private key with 'deskrun edit %s'", installation.Name)
+			} else {
+				result.Status = checkOK
+				result.Message = fmt.Sprintf("GitHub App installation %d valid", installation.GitHubAppInstallationID)
+			}
+			results = append(results, result)
+			continue
+		}
+		if installation.AuthType != types.AuthTypePAT {
+			result.Status = checkSkip
+			result.Message = fmt.Sprintf("uses %s authentication, token check skipped", installation.AuthType)
+			results = append(results, result)
+			continue
+		}
+
+		info, err := github.NewClient(installation.AuthValue).ValidateRunnerToken(ctx, installation.Repository)
+		if err != nil {
+			result.Status = checkFail
+			result.Message = err.Error()
+			result.Hint = fmt.Sprintf("Create a new token and update it with 'deskrun edit %s --auth-value <token>'", installation.Name)
+			if info != nil {
+				// The token itself is valid but lacks scopes or admin access
+				result.Message = fmt.Sprintf("token of user %s cannot register runners: %v", info.Login, err)
+				result.Hint = fmt.Sprintf("Grant the token the required scopes and admin access to %s, or update it with 'deskrun edit %s --auth-value <token>'", installation.Repository, installation.Name)
+			}
+			results = append(results, result)
+			continue
+		}
+
+		result.Status = checkOK
+		result.Message = fmt.Sprintf("token valid (user %s)", info.Login)
+		results = append(results, result)
+	}
+	return results
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return "no output"
+}
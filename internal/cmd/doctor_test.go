@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rkoster/deskrun/internal/cluster"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Doctor Command", func() {
+	DescribeTable("parseKindVersion",
+		func(output, expected string) {
+			Expect(parseKindVersion(output)).To(Equal(expected))
+		},
+		Entry("release build", "kind v0.30.0 go1.24.6 linux/amd64\n", "0.30.0"),
+		Entry("pre-release build", "kind v0.31.0-alpha+abc go1.25 darwin/arm64", "0.31.0-alpha+abc"),
+		Entry("unexpected output", "0.29.0", "0.29.0"),
+	)
+
+	DescribeTable("sameMinorVersion",
+		func(a, b string, expected bool) {
+			Expect(sameMinorVersion(a, b)).To(Equal(expected))
+		},
+		Entry("same version", "0.30.0", "0.30.0", true),
+		Entry("different patch", "0.30.1", "0.30.0", true),
+		Entry("different minor", "0.29.0", "0.30.0", false),
+	)
+
+	It("fails for an unknown cluster provider", func() {
+		result := checkClusterProvider(context.Background(), "kindest")
+		Expect(result.Status).To(Equal(checkFail))
+		Expect(result.Hint).To(ContainSubstring("kind, k3d, minikube"))
+	})
+
+	It("fails where kind can't run on Windows", func() {
+		Expect(checkWSL(2, nil).Status).To(Equal(checkOK))
+
+		result := checkWSL(1, errors.New("kind does not run on WSL 1"))
+		Expect(result.Status).To(Equal(checkFail))
+		Expect(result.Message).To(Equal("kind does not run on WSL 1"))
+	})
+
+	It("checks the image storage of the container runtime", func() {
+		Expect(diskSpacePaths(cluster.ContainerRuntime{Name: cluster.RuntimeDocker})).To(ContainElement("/var/lib/docker"))
+		Expect(diskSpacePaths(cluster.ContainerRuntime{Name: cluster.RuntimePodman})).To(ContainElement("/var/lib/containers/storage"))
+		Expect(diskSpacePaths(cluster.ContainerRuntime{Name: cluster.RuntimePodman, Rootless: true})).To(ContainElement(HaveSuffix(".local/share/containers/storage")))
+	})
+
+	It("checks the Docker socket only for host-docker runners", func() {
+		installations := map[string]*types.RunnerInstallation{
+			"dind": {Name: "dind", ContainerMode: types.ContainerModeDinD},
+		}
+		Expect(usesHostDocker(installations)).To(BeFalse())
+
+		installations["host"] = &types.RunnerInstallation{Name: "host", ContainerMode: types.ContainerModeHostDocker}
+		Expect(usesHostDocker(installations)).To(BeTrue())
+	})
+
+	It("fails for runners whose mode doesn't work in the macOS VM", func() {
+		vm := &cluster.HostVM{Name: cluster.VMColima, SharedPaths: []string{"/Users/dev"}}
+		installations := map[string]*types.RunnerInstallation{
+			"dind": {Name: "dind", ContainerMode: types.ContainerModeDinD},
+		}
+		result := checkHostVM(vm, installations)
+		Expect(result.Status).To(Equal(checkOK))
+		Expect(result.Message).To(Equal("colima, sharing /Users/dev"))
+
+		installations["host"] = &types.RunnerInstallation{Name: "host", ContainerMode: types.ContainerModeHostDocker}
+		result = checkHostVM(vm, installations)
+		Expect(result.Status).To(Equal(checkFail))
+		Expect(result.Message).To(ContainSubstring("installation host: host-docker mode is not supported on macOS"))
+		Expect(result.Hint).To(ContainSubstring("deskrun edit host --mode dind"))
+	})
+
+	It("collects the runtime classes of the installations", func() {
+		installations := map[string]*types.RunnerInstallation{
+			"a": {Name: "a", RuntimeClassName: "sysbox-runc"},
+			"b": {Name: "b", RuntimeClassName: "gvisor"},
+			"c": {Name: "c", RuntimeClassName: "sysbox-runc"},
+			"d": {Name: "d"},
+		}
+		Expect(installationRuntimeClasses(installations)).To(Equal([]string{"gvisor", "sysbox-runc"}))
+	})
+
+	It("formats byte counts", func() {
+		Expect(formatBytes(15 << 30)).To(Equal("15.0 GiB"))
+		Expect(formatBytes(512 << 20)).To(Equal("512.0 MiB"))
+	})
+
+	It("prints hints only for warnings and failures", func() {
+		var buf bytes.Buffer
+		printCheckResult(&buf, checkResult{Name: "Docker", Status: checkOK, Message: "running", Hint: "ignored"})
+		printCheckResult(&buf, checkResult{Name: "Disk space", Status: checkWarn, Message: "low", Hint: "prune"})
+
+		Expect(buf.String()).To(Equal("  ✓ Docker: running\n  ⚠ Disk space: low\n      → prune\n"))
+	})
+})
@@ -3,15 +3,23 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
-	"github.com/rkoster/deskrun/internal/cluster"
 	"github.com/rkoster/deskrun/internal/config"
 	"github.com/rkoster/deskrun/internal/runner"
-	"github.com/rkoster/deskrun/pkg/types"
 	"github.com/spf13/cobra"
 )
 
+var (
+	downCluster        string
+	downRunner         string
+	downAll            bool
+	downKeepController bool
+	downDeleteCluster  bool
+	downForce          bool
+)
+
 var downCmd = &cobra.Command{
 	Use:   "down",
 	Short: "Remove all ARC runners from the cluster",
@@ -21,33 +29,105 @@ This command removes all deployed runner scale sets from the cluster.
 The runner configurations remain in deskrun's config and can be redeployed
 with 'deskrun up'.
 
+Without --cluster, runners are removed from every known cluster.
+
+Runners running a job are scaled down to zero and removed once their jobs
+finished, or after 10 minutes with an error. --force removes them right away.
+
+The teardown can be narrowed or widened:
+  --runner <name>     Only remove the scale sets of one runner (all its instances)
+  --all               Also remove the ARC controller, cache server and
+                      observability stack
+  --keep-controller   With --all, keep the ARC controller and its CRDs
+  --delete-cluster    Delete the cluster itself; existing clusters of a kube
+                      context are never deleted, everything deskrun deployed
+                      to them is removed instead
+
 To also delete the configuration, use 'deskrun remove' before running 'down',
 or delete individual runners with 'deskrun remove <name>'.
 
-Example:
+Examples:
   deskrun down
+  deskrun down --cluster deskrun-gpu  # Only remove runners from one cluster
+  deskrun down --runner my-runner     # Only remove one runner
+  deskrun down --all --keep-controller
+  deskrun down --delete-cluster
 `,
 	RunE: runDown,
 }
 
 func init() {
+	downCmd.Flags().StringVar(&downCluster, "cluster", "", "Only remove runners from this cluster")
+	downCmd.Flags().StringVar(&downRunner, "runner", "", "Only remove the scale sets of this runner")
+	downCmd.Flags().BoolVar(&downAll, "all", false, "Also remove the ARC controller, cache server and observability stack")
+	downCmd.Flags().BoolVar(&downKeepController, "keep-controller", false, "With --all, keep the ARC controller")
+	downCmd.Flags().BoolVar(&downDeleteCluster, "delete-cluster", false, "Delete the cluster after removing the runners")
+	downCmd.Flags().BoolVar(&downForce, "force", false, "Remove runners without waiting for their running jobs to finish")
 	rootCmd.AddCommand(downCmd)
 }
 
+// downOptions selects what 'deskrun down' removes from a cluster
+type downOptions struct {
+	// Runner limits the teardown to the scale sets of one runner (empty = all)
+	Runner string
+	// All also removes the cluster components deskrun deployed
+	All bool
+	// KeepController keeps the ARC controller when All is set
+	KeepController bool
+	// DeleteCluster deletes the cluster itself
+	DeleteCluster bool
+	// Force removes runners without draining them
+	Force bool
+}
+
+// validateDownOptions rejects flag combinations that contradict each other
+func validateDownOptions(opts downOptions) error {
+	if opts.Runner != "" && (opts.All || opts.KeepController || opts.DeleteCluster) {
+		return fmt.Errorf("--runner can't be combined with --all, --keep-controller or --delete-cluster")
+	}
+	if opts.KeepController && opts.DeleteCluster {
+		return fmt.Errorf("--keep-controller can't be combined with --delete-cluster, deleting the cluster removes the controller")
+	}
+	if opts.KeepController && !opts.All {
+		return fmt.Errorf("--keep-controller only applies with --all")
+	}
+	return nil
+}
+
 func runDown(cmd *cobra.Command, args []string) error {
+	opts := downOptions{Runner: downRunner, All: downAll, KeepController: downKeepController, DeleteCluster: downDeleteCluster, Force: downForce}
+	if err := validateDownOptions(opts); err != nil {
+		return err
+	}
+
 	// Load config
 	configMgr, err := config.NewManager()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Setup cluster manager
-	clusterConfig := &types.ClusterConfig{
-		Name: configMgr.GetConfig().ClusterName,
+	for i, name := range targetClusters(configMgr.GetConfig(), downCluster) {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := downClusterRunners(configMgr.GetConfig(), name, opts); err != nil {
+			return fmt.Errorf("failed to remove runners from cluster '%s': %w", name, err)
+		}
 	}
-	clusterMgr := cluster.NewManager(clusterConfig)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	fmt.Println("\nNote: Runner configurations are still saved.")
+	fmt.Println("To redeploy, run: deskrun up")
+	return nil
+}
+
+// downClusterRunners removes the deployed runners selected by opts from a
+// single cluster, and with opts.All or opts.DeleteCluster the rest of what
+// deskrun deployed or the cluster itself
+func downClusterRunners(cfg *config.Config, clusterName string, opts downOptions) error {
+	// Setup cluster manager
+	clusterMgr := newClusterManager(cfg, clusterName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute+runner.DrainTimeout)
 	defer cancel()
 
 	// Check if cluster exists
@@ -57,39 +137,101 @@ func runDown(cmd *cobra.Command, args []string) error {
 	}
 
 	if !exists {
-		fmt.Printf("Cluster '%s' does not exist\n", clusterConfig.Name)
+		slog.Info("Cluster does not exist", "cluster", clusterName)
 		return nil
 	}
 
+	// Deleting the cluster removes everything on it at once; existing clusters
+	// of a kube context are kept, so everything deskrun deployed is removed
+	if opts.DeleteCluster {
+		if !clusterMgr.IsExternal() {
+			return deleteCluster(ctx, clusterMgr, clusterName)
+		}
+		slog.Warn("Cluster is the existing cluster of a kube context and is not deleted by deskrun",
+			"cluster", clusterName, "context", clusterMgr.GetKubeconfig())
+		opts.All = true
+	}
+
 	// Setup runner manager
 	runnerMgr := runner.NewManager(clusterMgr)
 
 	// Get list of currently deployed runners
-	fmt.Println("Finding deployed runners...")
+	slog.Info("Finding deployed runners", "cluster", clusterName)
 	deployedRunners, err := runnerMgr.List(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list deployed runners: %w", err)
 	}
+	deployedRunners = selectDownRunners(deployedRunners, opts.Runner)
 
 	if len(deployedRunners) == 0 {
-		fmt.Println("No runners deployed in cluster")
-		return nil
+		if opts.Runner != "" {
+			slog.Info("Runner not deployed in cluster", "name", opts.Runner, "cluster", clusterName)
+		} else {
+			slog.Info("No runners deployed in cluster", "cluster", clusterName)
+		}
+	} else {
+		slog.Info("Found runners to remove", "count", len(deployedRunners))
 	}
 
-	fmt.Printf("Found %d runner(s) to remove\n\n", len(deployedRunners))
-
-	// Remove all deployed runners
-	for _, name := range deployedRunners {
-		fmt.Printf("Removing runner '%s'...\n", name)
-		if err := runnerMgr.Uninstall(ctx, name); err != nil {
-			fmt.Printf("  Warning: failed to remove runner '%s': %v\n", name, err)
+	// Drain the selected runners together, so their jobs finish in parallel,
+	// and remove the ones without running jobs
+	drainErrs := make([]error, len(deployedRunners))
+	if !opts.Force && len(deployedRunners) > 0 {
+		drainErrs = runnerMgr.DrainAll(ctx, deployedRunners, runner.DrainTimeout)
+	}
+	for i, name := range deployedRunners {
+		slog.Info("Removing runner", "name", name)
+		err := drainErrs[i]
+		if err == nil {
+			err = runnerMgr.Uninstall(ctx, name)
+		}
+		if err != nil {
+			slog.Warn("Failed to remove runner", "name", name, "error", err)
 		} else {
-			fmt.Printf("  ✓ Runner '%s' removed\n", name)
+			slog.Info("Runner removed", "name", name)
 		}
 	}
 
-	fmt.Println("\nAll runners removed from cluster")
-	fmt.Println("\nNote: Runner configurations are still saved.")
-	fmt.Println("To redeploy, run: deskrun up")
+	deleteUnusedNamespaces(ctx, runnerMgr)
+
+	if opts.All {
+		return removeClusterComponents(ctx, runnerMgr, opts.KeepController)
+	}
+	if opts.Runner == "" && len(deployedRunners) > 0 {
+		slog.Info("All runners removed from cluster", "cluster", clusterName)
+	}
 	return nil
 }
+
+// selectDownRunners returns the deployed scale sets of a runner, including
+// its instances, or all of them if runner is empty
+func selectDownRunners(deployed []string, runnerName string) []string {
+	if runnerName == "" {
+		return deployed
+	}
+	var selected []string
+	for _, name := range deployed {
+		if name == runnerName || isInstanceOf(name, runnerName) {
+			selected = append(selected, name)
+		}
+	}
+	return selected
+}
+
+// removeClusterComponents removes the cache server, the observability stack
+// and, unless keepController is set, the ARC controller from a cluster
+func removeClusterComponents(ctx context.Context, runnerMgr *runner.Manager, keepController bool) error {
+	slog.Info("Removing actions cache server")
+	if err := runnerMgr.DisableCacheServer(ctx); err != nil {
+		return err
+	}
+	slog.Info("Removing observability stack")
+	if err := runnerMgr.DisableObservability(ctx); err != nil {
+		return err
+	}
+	if keepController {
+		return nil
+	}
+	slog.Info("Removing ARC controller")
+	return runnerMgr.UninstallController(ctx)
+}
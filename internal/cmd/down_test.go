@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Down Command", func() {
+	Describe("validateDownOptions", func() {
+		It("accepts the default and the supported combinations", func() {
+			Expect(validateDownOptions(downOptions{})).To(Succeed())
+			Expect(validateDownOptions(downOptions{Runner: "my-runner"})).To(Succeed())
+			Expect(validateDownOptions(downOptions{All: true, KeepController: true})).To(Succeed())
+			Expect(validateDownOptions(downOptions{All: true, DeleteCluster: true})).To(Succeed())
+		})
+
+		It("rejects --runner with options affecting the whole cluster", func() {
+			Expect(validateDownOptions(downOptions{Runner: "my-runner", All: true})).To(MatchError(ContainSubstring("--runner can't be combined")))
+			Expect(validateDownOptions(downOptions{Runner: "my-runner", DeleteCluster: true})).To(MatchError(ContainSubstring("--runner can't be combined")))
+		})
+
+		It("rejects --keep-controller without --all or with --delete-cluster", func() {
+			Expect(validateDownOptions(downOptions{KeepController: true})).To(MatchError("--keep-controller only applies with --all"))
+			Expect(validateDownOptions(downOptions{All: true, KeepController: true, DeleteCluster: true})).To(MatchError(ContainSubstring("--delete-cluster")))
+		})
+	})
+
+	Describe("selectDownRunners", func() {
+		deployed := []string{"my-runner", "my-runner-1", "my-runner-2", "my-runner-gpu", "other"}
+
+		It("selects all runners by default", func() {
+			Expect(selectDownRunners(deployed, "")).To(Equal(deployed))
+		})
+
+		It("selects a runner and its instances", func() {
+			Expect(selectDownRunners(deployed, "my-runner")).To(Equal([]string{"my-runner", "my-runner-1", "my-runner-2"}))
+			Expect(selectDownRunners(deployed, "missing")).To(BeEmpty())
+		})
+	})
+})
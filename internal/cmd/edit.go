@@ -0,0 +1,781 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/templates"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	editRepository              string
+	editRunnerGroup             string
+	editMode                    string
+	editImage                   string
+	editNamespace               string
+	editMinRunners              int
+	editMaxRunners              int
+	editSchedule                []string
+	editInstances               int
+	editAuthType                string
+	editAuthValue               string
+	editCachePaths              []string
+	editMounts                  []string
+	editClearMounts             bool
+	editApply                   bool
+	editForce                   bool
+	editDeployTimeout           time.Duration
+	editNoWait                  bool
+	editGitHubAppID             int64
+	editGitHubAppInstallationID int64
+
+	editCPURequest    string
+	editCPULimit      string
+	editMemoryRequest string
+	editMemoryLimit   string
+
+	editDinDImage         string
+	editDinDCPURequest    string
+	editDinDCPULimit      string
+	editDinDMemoryRequest string
+	editDinDMemoryLimit   string
+	editDinDMTU           int
+	editDinDMirrors       []string
+	editDinDInsecure      []string
+
+	editListenerCPURequest    string
+	editListenerCPULimit      string
+	editListenerMemoryRequest string
+	editListenerMemoryLimit   string
+
+	editEphemeralStorageRequest string
+	editEphemeralStorageLimit   string
+
+	editHTTPProxy  string
+	editHTTPSProxy string
+	editNoProxy    string
+
+	editNodeSelector []string
+	editTolerations  []string
+	editRuntimeClass string
+	editArch         string
+
+	editEnv []string
+
+	editMountSecrets    []string
+	editMountConfigMaps []string
+
+	editCacheVolumes []string
+
+	editOverlays []string
+
+	editLabels      []string
+	editAnnotations []string
+
+	editNetworkPolicy string
+	editAllowEgress   []string
+
+	editPodSecurity string
+
+	editValueFiles  []string
+	editSetValues   []string
+	editClearValues bool
+
+	editCacheType         string
+	editCacheStorageClass string
+	editCacheSize         string
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Modify an existing runner installation",
+	Long: `Modify an existing GitHub Actions runner installation in the deskrun configuration.
+
+Only the flags that are given are changed; all other settings of the installation
+are preserved. Passing --mount or --cache replaces the existing list of mounts or
+cache paths. Use --clear-mounts to remove all mounts and cache paths.
+
+Like 'deskrun add', this is a config-only operation unless --apply is given, in
+which case the installation is redeployed to the cluster right away.
+
+Examples:
+  # Allow more concurrent runners
+  deskrun edit my-runner --max-runners 10
+
+  # Switch to privileged mode with a Docker cache and redeploy
+  deskrun edit my-runner \
+    --mode cached-privileged-kubernetes \
+    --max-runners 1 \
+    --mount /var/lib/docker \
+    --apply
+
+  # Rotate the PAT
+  deskrun edit my-runner --auth-value ghp_yyy
+
+  # Replace the extra environment variables, or remove them
+  deskrun edit my-runner --env ACTIONS_STEP_DEBUG=true --env TOOLS_DIR=/opt/tools
+  deskrun edit my-runner --env ""
+
+  # Share the npm cache volume with other installations, or stop mounting it
+  deskrun edit my-runner --cache-volume npm
+  deskrun edit my-runner --cache-volume ""
+
+  # Replace the ytt overlays of the runner manifests, or remove them
+  deskrun edit my-runner --overlay ./team-labels.yaml
+  deskrun edit my-runner --overlay ""
+
+  # Set extra scale set data values on top of the existing ones, or drop them all
+  deskrun edit my-runner --set labels.team=platform
+  deskrun edit my-runner --clear-values --values ./values.yaml
+
+  # Keep the caches in persistent volume claims of the fast-ssd storage class
+  deskrun edit my-runner --cache-type pvc --cache-storage-class fast-ssd --cache-size 50Gi
+
+  # Move the runner into a namespace of its own and redeploy
+  deskrun edit my-runner --namespace team-a --apply
+
+  # Route the runner through a proxy, or fall back to the cluster proxy again
+  deskrun edit my-runner --https-proxy http://proxy.corp:3128
+  deskrun edit my-runner --http-proxy "" --https-proxy "" --no-proxy ""
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runEdit,
+}
+
+func init() {
+	editCmd.Flags().StringVarP(&editRepository, "repository", "r", "", "GitHub repository or organization URL")
+	editCmd.Flags().StringVar(&editRunnerGroup, "runner-group", "", "Runner group for organization-level runners (empty for the Default group)")
+	editCmd.Flags().StringVarP(&editMode, "mode", "m", "", "Container mode (kubernetes, cached-privileged-kubernetes, dind, dind-rootless, host-docker)")
+	editCmd.Flags().StringVar(&editImage, "image", "", "Runner container image (empty for the default)")
+	editCmd.Flags().StringVar(&editNamespace, "namespace", "", "Kubernetes namespace of the runner scale sets (empty for arc-systems)")
+	editCmd.Flags().IntVar(&editMinRunners, "min-runners", 0, "Minimum number of runners")
+	editCmd.Flags().IntVar(&editMaxRunners, "max-runners", 0, "Maximum number of runners")
+	editCmd.Flags().StringArrayVar(&editSchedule, "schedule", []string{}, "Replace the runner limit schedule. Format: 'DAYS HH:MM-HH:MM MIN:MAX' (pass an empty value to remove it)")
+	editCmd.Flags().IntVar(&editInstances, "instances", 0, "Number of separate runner scale set instances")
+	editCmd.Flags().StringVar(&editAuthType, "auth-type", "", "Authentication type (pat, github-app)")
+	editCmd.Flags().StringVar(&editAuthValue, "auth-value", "", "Authentication value (PAT token or GitHub App private key)")
+	editCmd.Flags().Int64Var(&editGitHubAppID, "github-app-id", 0, "GitHub App ID")
+	editCmd.Flags().Int64Var(&editGitHubAppInstallationID, "github-app-installation-id", 0, "GitHub App installation ID")
+	editCmd.Flags().StringSliceVar(&editMounts, "mount", []string{}, "Replace mounts. Format: target, src:target, or src:target:type (can be specified multiple times)")
+	editCmd.Flags().StringSliceVar(&editCachePaths, "cache", []string{}, "Deprecated: use --mount instead. Replace cache paths. Format: target or src:target")
+	editCmd.Flags().StringSliceVar(&editCacheVolumes, "cache-volume", []string{}, "Replace the shared cache volumes (pass an empty value to remove them)")
+	editCmd.Flags().StringSliceVar(&editOverlays, "overlay", []string{}, "Replace the ytt overlay files of the runner manifests (pass an empty value to remove them)")
+	editCmd.Flags().StringVar(&editNetworkPolicy, "network-policy", "", "Restrict the egress of runner pods (restricted) or remove the restriction (none)")
+	editCmd.Flags().StringSliceVar(&editAllowEgress, "allow-egress", []string{}, "Replace the extra CIDRs runner pods may reach with --network-policy restricted (pass an empty value to remove them)")
+	editCmd.Flags().StringVar(&editPodSecurity, "pss", "", "Set the Pod Security Standards profile of the runner and job pods (baseline, restricted) or remove it (none)")
+	editCmd.Flags().StringArrayVar(&editLabels, "label", []string{}, "Replace the labels of the runner resources and pods. Format: key=value (pass an empty value to remove them)")
+	editCmd.Flags().StringArrayVar(&editAnnotations, "annotation", []string{}, "Replace the annotations of the runner resources and pods. Format: key=value (pass an empty value to remove them)")
+	editCmd.Flags().StringSliceVar(&editValueFiles, "values", []string{}, "YAML file of extra scale set data values, merged into the existing ones (can be specified multiple times)")
+	editCmd.Flags().StringArrayVar(&editSetValues, "set", []string{}, "Extra scale set data value, applied after --values. Format: key.path=value (can be specified multiple times)")
+	editCmd.Flags().BoolVar(&editClearValues, "clear-values", false, "Remove the extra scale set data values before applying --values and --set")
+	editCmd.Flags().StringVar(&editCacheType, "cache-type", "", "Storage of mounts without a source path (hostpath, pvc)")
+	editCmd.Flags().StringVar(&editCacheStorageClass, "cache-storage-class", "", "Storage class of the cache claims (empty for the default storage class)")
+	editCmd.Flags().StringVar(&editCacheSize, "cache-size", "", "Size of every cache claim (empty for 10Gi)")
+	editCmd.Flags().BoolVar(&editClearMounts, "clear-mounts", false, "Remove all mounts and cache paths")
+	editCmd.Flags().StringVar(&editCPURequest, "cpu-request", "", "CPU request for the runner container (empty to unset)")
+	editCmd.Flags().StringVar(&editCPULimit, "cpu-limit", "", "CPU limit for the runner container (empty to unset)")
+	editCmd.Flags().StringVar(&editMemoryRequest, "memory-request", "", "Memory request for the runner container (empty to unset)")
+	editCmd.Flags().StringVar(&editMemoryLimit, "memory-limit", "", "Memory limit for the runner container (empty to unset)")
+	editCmd.Flags().StringVar(&editListenerCPURequest, "listener-cpu-request", "", "CPU request for the autoscaling listener container (empty to unset)")
+	editCmd.Flags().StringVar(&editListenerCPULimit, "listener-cpu-limit", "", "CPU limit for the autoscaling listener container (empty to unset)")
+	editCmd.Flags().StringVar(&editListenerMemoryRequest, "listener-memory-request", "", "Memory request for the autoscaling listener container (empty to unset)")
+	editCmd.Flags().StringVar(&editListenerMemoryLimit, "listener-memory-limit", "", "Memory limit for the autoscaling listener container (empty to unset)")
+	editCmd.Flags().StringVar(&editEphemeralStorageRequest, "ephemeral-storage-request", "", "Ephemeral storage request for runner and job containers (empty to unset)")
+	editCmd.Flags().StringVar(&editEphemeralStorageLimit, "ephemeral-storage-limit", "", "Ephemeral storage limit for runner and job containers (empty to unset)")
+	editCmd.Flags().StringVar(&editDinDImage, "dind-image", "", "Docker-in-Docker sidecar image (empty for the default)")
+	editCmd.Flags().StringVar(&editDinDCPURequest, "dind-cpu-request", "", "CPU request for the dind sidecar")
+	editCmd.Flags().StringVar(&editDinDCPULimit, "dind-cpu-limit", "", "CPU limit for the dind sidecar")
+	editCmd.Flags().StringVar(&editDinDMemoryRequest, "dind-memory-request", "", "Memory request for the dind sidecar")
+	editCmd.Flags().StringVar(&editDinDMemoryLimit, "dind-memory-limit", "", "Memory limit for the dind sidecar")
+	editCmd.Flags().IntVar(&editDinDMTU, "dind-mtu", 0, "MTU of the networks of the dind daemon (0 for the default)")
+	editCmd.Flags().StringSliceVar(&editDinDMirrors, "dind-registry-mirror", []string{}, "Replace the Docker Hub mirrors of the dind daemon (pass an empty value to remove them)")
+	editCmd.Flags().StringSliceVar(&editDinDInsecure, "dind-insecure-registry", []string{}, "Replace the registries the dind daemon reaches over plain HTTP (pass an empty value to remove them)")
+	editCmd.Flags().StringVar(&editHTTPProxy, "http-proxy", "", "HTTP proxy URL for the runner and listener (empty to unset)")
+	editCmd.Flags().StringVar(&editHTTPSProxy, "https-proxy", "", "HTTPS proxy URL for the runner and listener (empty to unset)")
+	editCmd.Flags().StringVar(&editNoProxy, "no-proxy", "", "Comma-separated hosts, domains and CIDRs that bypass the proxy (empty to unset)")
+	editCmd.Flags().StringArrayVar(&editEnv, "env", []string{}, "Replace the extra environment variables. Format: KEY=VALUE (pass an empty value to remove them)")
+	editCmd.Flags().StringVar(&editRuntimeClass, "runtime-class", "", "RuntimeClass of the runner pods (pass an empty value for the default runtime)")
+	editCmd.Flags().StringVar(&editArch, "arch", "", "CPU architecture of the nodes runner pods run on: amd64 or arm64 (pass an empty value for any node)")
+	editCmd.Flags().StringSliceVar(&editNodeSelector, "node-selector", []string{}, "Replace the node selector. Format: key=value (pass an empty value to remove it)")
+	editCmd.Flags().StringSliceVar(&editTolerations, "toleration", []string{}, "Replace the tolerations. Format: key[=value][:effect] (pass an empty value to remove them)")
+	editCmd.Flags().StringSliceVar(&editMountSecrets, "mount-secret", []string{}, "Replace the mounted secrets. Format: name:/path (pass an empty value to remove them)")
+	editCmd.Flags().StringSliceVar(&editMountConfigMaps, "mount-configmap", []string{}, "Replace the mounted configmaps. Format: name:/path (pass an empty value to remove them)")
+	editCmd.Flags().BoolVar(&editApply, "apply", false, "Redeploy the installation to the cluster after updating the configuration")
+	editCmd.Flags().BoolVar(&editForce, "force", false, "With --apply, redeploy without waiting for running jobs to finish")
+	editCmd.Flags().DurationVar(&editDeployTimeout, "deploy-timeout", 0, "With --apply, how long kapp applies and waits for the resources (default 15m, or the config default)")
+	editCmd.Flags().BoolVar(&editNoWait, "no-wait", false, "With --apply, return once the resources are applied, without waiting for them to reconcile")
+
+	rootCmd.AddCommand(editCmd)
+}
+
+func runEdit(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	// Load config
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	existing, err := configMgr.GetInstallation(name)
+	if err != nil {
+		return fmt.Errorf("installation not found: %w", err)
+	}
+
+	// Patch a copy so a failed validation leaves the config untouched
+	installation := *existing
+	if err := applyEditFlags(cmd.Flags(), &installation); err != nil {
+		return err
+	}
+
+	if err := validateEditedInstallation(&installation); err != nil {
+		return err
+	}
+	if err := validateCacheVolumeReferences(configMgr.GetConfig(), &installation); err != nil {
+		return err
+	}
+
+	// Save to config
+	if err := configMgr.UpdateInstallation(&installation); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("Runner '%s' updated in configuration\n", name)
+
+	if !editApply {
+		fmt.Println("\nTo deploy this change, run:")
+		fmt.Println("  deskrun up")
+		return nil
+	}
+
+	return redeployInstallation(configMgr, &installation)
+}
+
+// applyEditFlags patches the installation with the flags that were explicitly set
+func applyEditFlags(flags *pflag.FlagSet, installation *types.RunnerInstallation) error {
+	if flags.Changed("repository") {
+		installation.Repository = sanitizeRepositoryURL(editRepository)
+	}
+	if flags.Changed("runner-group") {
+		installation.RunnerGroup = editRunnerGroup
+	}
+	if flags.Changed("mode") {
+		containerMode, err := parseContainerMode(editMode)
+		if err != nil {
+			return err
+		}
+		installation.ContainerMode = containerMode
+		warnHostVMMode(containerMode)
+	}
+	if flags.Changed("image") {
+		installation.Image = editImage
+	}
+	if flags.Changed("namespace") {
+		if err := validateNamespace(editNamespace); err != nil {
+			return err
+		}
+		installation.Namespace = editNamespace
+	}
+	if flags.Changed("min-runners") {
+		installation.MinRunners = editMinRunners
+	}
+	if flags.Changed("max-runners") {
+		installation.MaxRunners = editMaxRunners
+	}
+	if flags.Changed("schedule") {
+		schedule, err := parseSchedule(nonEmpty(editSchedule))
+		if err != nil {
+			return err
+		}
+		installation.Schedule = schedule
+	}
+	if flags.Changed("instances") {
+		installation.Instances = editInstances
+	}
+	// Multiple instances always run exactly one runner each
+	if installation.Instances > 1 {
+		installation.MinRunners = 1
+		installation.MaxRunners = 1
+	}
+
+	if flags.Changed("auth-type") {
+		authType, err := parseAuthType(editAuthType)
+		if err != nil {
+			return err
+		}
+		installation.AuthType = authType
+	}
+	if flags.Changed("auth-value") {
+		installation.AuthValue = editAuthValue
+	}
+	if flags.Changed("github-app-id") {
+		installation.GitHubAppID = editGitHubAppID
+	}
+	if flags.Changed("github-app-installation-id") {
+		installation.GitHubAppInstallationID = editGitHubAppInstallationID
+	}
+	if installation.AuthType != types.AuthTypeGitHubApp {
+		installation.GitHubAppID = 0
+		installation.GitHubAppInstallationID = 0
+	}
+
+	if editClearMounts {
+		installation.Mounts = []types.Mount{}
+		installation.CachePaths = []types.CachePath{}
+	}
+	if flags.Changed("mount") {
+		mounts, err := parseMounts(editMounts)
+		if err != nil {
+			return err
+		}
+		installation.Mounts = mounts
+	}
+	if flags.Changed("cache") {
+		cachePaths, err := parseCachePaths(editCachePaths)
+		if err != nil {
+			return err
+		}
+		installation.CachePaths = cachePaths
+	}
+	if flags.Changed("cache-volume") {
+		installation.CacheVolumes = nonEmpty(editCacheVolumes)
+	}
+	if flags.Changed("overlay") {
+		overlays, err := overlayPaths(nonEmpty(editOverlays))
+		if err != nil {
+			return err
+		}
+		installation.Overlays = overlays
+	}
+	if flags.Changed("network-policy") || flags.Changed("allow-egress") {
+		mode := editNetworkPolicy
+		allowEgress := nonEmpty(editAllowEgress)
+		if !flags.Changed("network-policy") && installation.NetworkPolicy != nil {
+			mode = string(installation.NetworkPolicy.Mode)
+		}
+		if !flags.Changed("allow-egress") && installation.NetworkPolicy != nil && mode != "none" {
+			allowEgress = installation.NetworkPolicy.AllowCIDRs
+		}
+		policy, err := buildNetworkPolicy(mode, allowEgress, installation.ContainerMode)
+		if err != nil {
+			return err
+		}
+		installation.NetworkPolicy = policy
+	}
+	if flags.Changed("pss") {
+		// Checked with the other edits by validateEditedInstallation
+		installation.PodSecurity = types.PodSecurityLevel(editPodSecurity)
+		if editPodSecurity == "none" {
+			installation.PodSecurity = ""
+		}
+	}
+	if flags.Changed("label") || flags.Changed("annotation") {
+		labels, annotations, err := parseMetadata(nonEmpty(editLabels), nonEmpty(editAnnotations))
+		if err != nil {
+			return err
+		}
+		if flags.Changed("label") {
+			installation.Labels = labels
+		}
+		if flags.Changed("annotation") {
+			installation.Annotations = annotations
+		}
+	}
+	if editClearValues || flags.Changed("values") || flags.Changed("set") {
+		current := installation.Values
+		if editClearValues {
+			current = nil
+		}
+		values, err := parseValues(current, editValueFiles, editSetValues)
+		if err != nil {
+			return err
+		}
+		installation.Values = values
+	}
+	if err := applyCacheStorageEditFlags(flags, installation); err != nil {
+		return err
+	}
+
+	if flags.Changed("env") {
+		envVars, err := parseEnvVars(nonEmpty(editEnv))
+		if err != nil {
+			return err
+		}
+		installation.EnvVars = envVars
+	}
+	if flags.Changed("runtime-class") {
+		if err := validateRuntimeClass(editRuntimeClass); err != nil {
+			return err
+		}
+		installation.RuntimeClassName = editRuntimeClass
+	}
+	if flags.Changed("arch") {
+		arch, err := parseArch(editArch)
+		if err != nil {
+			return err
+		}
+		installation.Arch = arch
+	}
+	if flags.Changed("node-selector") {
+		nodeSelector, err := parseNodeSelector(editNodeSelector)
+		if err != nil {
+			return err
+		}
+		installation.NodeSelector = nodeSelector
+	}
+	if flags.Changed("toleration") {
+		tolerations, err := parseTolerations(editTolerations)
+		if err != nil {
+			return err
+		}
+		installation.Tolerations = tolerations
+	}
+	if flags.Changed("mount-secret") || flags.Changed("mount-configmap") {
+		secrets := objectMountValues(installation.ObjectMounts, types.ObjectKindSecret)
+		if flags.Changed("mount-secret") {
+			secrets = nonEmpty(editMountSecrets)
+		}
+		configMaps := objectMountValues(installation.ObjectMounts, types.ObjectKindConfigMap)
+		if flags.Changed("mount-configmap") {
+			configMaps = nonEmpty(editMountConfigMaps)
+		}
+		objectMounts, err := parseObjectMounts(secrets, configMaps)
+		if err != nil {
+			return err
+		}
+		installation.ObjectMounts = objectMounts
+	}
+
+	if flags.Changed("ephemeral-storage-request") || flags.Changed("ephemeral-storage-limit") {
+		storage := types.EphemeralStorage{}
+		if installation.EphemeralStorage != nil {
+			storage = *installation.EphemeralStorage
+		}
+		if flags.Changed("ephemeral-storage-request") {
+			storage.Request = editEphemeralStorageRequest
+		}
+		if flags.Changed("ephemeral-storage-limit") {
+			storage.Limit = editEphemeralStorageLimit
+		}
+		installation.EphemeralStorage = nil
+		if storage != (types.EphemeralStorage{}) {
+			installation.EphemeralStorage = &storage
+		}
+	}
+
+	applyResourceEditFlags(flags, installation)
+	applyDinDEditFlags(flags, installation)
+	applyListenerEditFlags(flags, installation)
+	applyProxyEditFlags(flags, installation)
+	return nil
+}
+
+// applyCacheStorageEditFlags patches the cache storage with the --cache-*
+// flags that were explicitly set. Switching back to --cache-type hostpath
+// drops the claim settings.
+func applyCacheStorageEditFlags(flags *pflag.FlagSet, installation *types.RunnerInstallation) error {
+	if !flags.Changed("cache-type") && !flags.Changed("cache-storage-class") && !flags.Changed("cache-size") {
+		return nil
+	}
+
+	storage := types.CacheStorage{Type: types.CacheTypeHostPath}
+	if installation.CacheStorage != nil {
+		storage = *installation.CacheStorage
+	}
+	if flags.Changed("cache-type") {
+		storage.Type = types.CacheType(editCacheType)
+		if storage.Type == types.CacheTypeHostPath {
+			storage.StorageClass, storage.Size = "", ""
+		}
+	}
+	if flags.Changed("cache-storage-class") {
+		storage.StorageClass = editCacheStorageClass
+	}
+	if flags.Changed("cache-size") {
+		storage.Size = editCacheSize
+	}
+
+	cacheStorage, err := buildCacheStorage(string(storage.Type), storage.StorageClass, storage.Size)
+	if err != nil {
+		return err
+	}
+	installation.CacheStorage = cacheStorage
+	return nil
+}
+
+// applyProxyEditFlags patches the proxy settings with the --*-proxy flags that
+// were explicitly set, dropping them when nothing is left
+func applyProxyEditFlags(flags *pflag.FlagSet, installation *types.RunnerInstallation) {
+	proxy := types.ProxyConfig{}
+	if installation.Proxy != nil {
+		proxy = *installation.Proxy
+	}
+
+	if flags.Changed("http-proxy") {
+		proxy.HTTPProxy = editHTTPProxy
+	}
+	if flags.Changed("https-proxy") {
+		proxy.HTTPSProxy = editHTTPSProxy
+	}
+	if flags.Changed("no-proxy") {
+		proxy.NoProxy = editNoProxy
+	}
+
+	installation.Proxy = nil
+	if proxy != (types.ProxyConfig{}) {
+		installation.Proxy = &proxy
+	}
+}
+
+// nonEmpty returns the non-empty values of a repeatable flag, so passing an
+// empty value clears the list
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// objectMountValues returns the object mounts of a kind in name:/path flag notation
+func objectMountValues(objectMounts []types.ObjectMount, kind types.ObjectKind) []string {
+	var values []string
+	for _, objectMount := range objectMounts {
+		if objectMount.Kind == kind {
+			values = append(values, objectMount.Name+":"+objectMount.Path)
+		}
+	}
+	return values
+}
+
+// applyResourceEditFlags patches the runner container resources with the
+// --cpu-* and --memory-* flags that were explicitly set
+func applyResourceEditFlags(flags *pflag.FlagSet, installation *types.RunnerInstallation) {
+	resources := types.ResourceRequirements{}
+	if installation.Resources != nil {
+		resources = *installation.Resources
+	}
+
+	if flags.Changed("cpu-request") {
+		resources.Requests.CPU = editCPURequest
+	}
+	if flags.Changed("cpu-limit") {
+		resources.Limits.CPU = editCPULimit
+	}
+	if flags.Changed("memory-request") {
+		resources.Requests.Memory = editMemoryRequest
+	}
+	if flags.Changed("memory-limit") {
+		resources.Limits.Memory = editMemoryLimit
+	}
+
+	installation.Resources = nil
+	if !resources.IsEmpty() {
+		installation.Resources = &resources
+	}
+}
+
+// applyDinDEditFlags patches the dind sidecar configuration with the --dind-* flags
+// that were explicitly set, dropping the configuration when nothing is left
+func applyDinDEditFlags(flags *pflag.FlagSet, installation *types.RunnerInstallation) {
+	dind := types.DinDConfig{}
+	if installation.DinD != nil {
+		dind = *installation.DinD
+	}
+	resources := types.ResourceRequirements{}
+	if dind.Resources != nil {
+		resources = *dind.Resources
+	}
+
+	if flags.Changed("dind-image") {
+		dind.Image = editDinDImage
+	}
+	if flags.Changed("dind-cpu-request") {
+		resources.Requests.CPU = editDinDCPURequest
+	}
+	if flags.Changed("dind-cpu-limit") {
+		resources.Limits.CPU = editDinDCPULimit
+	}
+	if flags.Changed("dind-memory-request") {
+		resources.Requests.Memory = editDinDMemoryRequest
+	}
+	if flags.Changed("dind-memory-limit") {
+		resources.Limits.Memory = editDinDMemoryLimit
+	}
+	if flags.Changed("dind-mtu") {
+		dind.MTU = editDinDMTU
+	}
+	if flags.Changed("dind-registry-mirror") {
+		dind.RegistryMirrors = nonEmpty(editDinDMirrors)
+	}
+	if flags.Changed("dind-insecure-registry") {
+		dind.InsecureRegistries = nonEmpty(editDinDInsecure)
+	}
+
+	dind.Resources = nil
+	if !resources.IsEmpty() {
+		dind.Resources = &resources
+	}
+
+	installation.DinD = nil
+	if dind.Image != "" || dind.Resources != nil || dind.HasDaemonConfig() {
+		installation.DinD = &dind
+	}
+}
+
+// applyListenerEditFlags patches the listener container resources with the
+// --listener-* flags that were explicitly set
+func applyListenerEditFlags(flags *pflag.FlagSet, installation *types.RunnerInstallation) {
+	resources := types.ResourceRequirements{}
+	if installation.Listener != nil && installation.Listener.Resources != nil {
+		resources = *installation.Listener.Resources
+	}
+
+	if flags.Changed("listener-cpu-request") {
+		resources.Requests.CPU = editListenerCPURequest
+	}
+	if flags.Changed("listener-cpu-limit") {
+		resources.Limits.CPU = editListenerCPULimit
+	}
+	if flags.Changed("listener-memory-request") {
+		resources.Requests.Memory = editListenerMemoryRequest
+	}
+	if flags.Changed("listener-memory-limit") {
+		resources.Limits.Memory = editListenerMemoryLimit
+	}
+
+	installation.Listener = nil
+	if !resources.IsEmpty() {
+		installation.Listener = &types.ListenerConfig{Resources: &resources}
+	}
+}
+
+// validateEditedInstallation runs the same checks as 'deskrun add' on the patched installation
+func validateEditedInstallation(installation *types.RunnerInstallation) error {
+	instances := installation.Instances
+	if instances == 0 {
+		instances = 1
+	}
+	if err := validateAddParams(instances, installation.MaxRunners, installation.ContainerMode, installation.CachePaths, installation.Mounts); err != nil {
+		return err
+	}
+
+	if err := validateCacheStorage(installation); err != nil {
+		return err
+	}
+
+	if err := validateSchedule(installation); err != nil {
+		return err
+	}
+
+	if err := validateGitHubAppParams(installation.AuthType, installation.GitHubAppID, installation.GitHubAppInstallationID); err != nil {
+		return err
+	}
+
+	if installation.RunnerGroup != "" && !installation.IsOrganizationLevel() {
+		return fmt.Errorf("--runner-group can only be used with an organization URL (e.g. https://github.com/myorg)")
+	}
+
+	if installation.Proxy != nil {
+		if err := validateProxyConfig(installation.Proxy); err != nil {
+			return err
+		}
+	}
+
+	if err := templates.ValidateNetworkPolicy(installation.NetworkPolicy, installation.ContainerMode); err != nil {
+		return err
+	}
+
+	if err := templates.ValidatePodSecurity(installation); err != nil {
+		return err
+	}
+
+	if err := templates.ValidateDinDConfig(installation.DinD); err != nil {
+		return err
+	}
+
+	if installation.DinD != nil && !installation.ContainerMode.UsesDinD() {
+		return fmt.Errorf("dind sidecar settings can only be used with --mode dind or dind-rootless; unset them by passing empty --dind-* values")
+	}
+
+	return nil
+}
+
+// redeployInstallation uninstalls the deployed scale sets of an installation and
+// installs them again with the updated configuration
+func redeployInstallation(configMgr *config.Manager, installation *types.RunnerInstallation) error {
+	cfg := configMgr.GetConfig()
+	clusterName := cfg.ClusterFor(installation)
+	clusterMgr := newClusterManager(cfg, clusterName)
+
+	applyOpts, err := kappApplyOptions(cfg, editDeployTimeout, editNoWait)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), max(10*time.Minute, applyOpts.Timeout)+runner.DrainTimeout)
+	defer cancel()
+
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("cluster '%s' does not exist, run 'deskrun up' to create it", clusterName)
+	}
+
+	runnerMgr := runner.NewManager(clusterMgr).
+		WithControllerVersion(cfg.ControllerVersion).
+		WithControllerProxy(cfg.ClusterProxy(clusterName)).
+		WithApplyOptions(applyOpts)
+
+	deployedRunners, err := runnerMgr.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list runners: %w", err)
+	}
+
+	// Remove all previously deployed scale sets of this installation, including
+	// instances that no longer exist after lowering --instances
+	for _, deployed := range deployedRunners {
+		if deployed != installation.Name && !isInstanceOf(deployed, installation.Name) {
+			continue
+		}
+		slog.Info("Removing runner scale set", "name", deployed)
+		if err := uninstallRunner(ctx, runnerMgr, deployed, editForce, runner.DrainTimeout); err != nil {
+			return fmt.Errorf("failed to uninstall runner '%s': %w", deployed, err)
+		}
+	}
+
+	if err := runnerMgr.Install(ctx, installationToDeploy(cfg, installation)); err != nil {
+		return fmt.Errorf("failed to install runner: %w", err)
+	}
+	deleteUnusedNamespaces(ctx, runnerMgr)
+
+	fmt.Printf("✓ Runner '%s' redeployed\n", installation.Name)
+	return nil
+}
+
+// isInstanceOf returns true if the scale set name is a numbered instance (name-N) of the installation
+func isInstanceOf(scaleSetName, installationName string) bool {
+	suffix, found := strings.CutPrefix(scaleSetName, installationName+"-")
+	if !found || suffix == "" {
+		return false
+	}
+	for _, r := range suffix {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
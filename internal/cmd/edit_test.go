@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Edit Command", func() {
+	var installation *types.RunnerInstallation
+
+	BeforeEach(func() {
+		installation = &types.RunnerInstallation{
+			Name:          "my-runner",
+			Repository:    "https://github.com/owner/repo",
+			ContainerMode: types.ContainerModeDinD,
+			MinRunners:    1,
+			MaxRunners:    5,
+			Instances:     1,
+			AuthType:      types.AuthTypePAT,
+			AuthValue:     "ghp_xxx",
+			Mounts:        []types.Mount{{Source: "/tmp/cache", Target: "/cache", Type: types.MountTypeDirectoryOrCreate}},
+		}
+	})
+
+	Describe("applyEditFlags", func() {
+		It("only changes the fields of flags that were set", func() {
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.IntVar(&editMaxRunners, "max-runners", 0, "")
+			flags.IntVar(&editMinRunners, "min-runners", 0, "")
+			Expect(flags.Parse([]string{"--max-runners", "10"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.MaxRunners).To(Equal(10))
+			Expect(installation.MinRunners).To(Equal(1))
+			Expect(installation.Mounts).To(HaveLen(1))
+			Expect(installation.AuthValue).To(Equal("ghp_xxx"))
+		})
+
+		It("moves the installation to another namespace", func() {
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringVar(&editNamespace, "namespace", "", "")
+			Expect(flags.Parse([]string{"--namespace", "team-a"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.Namespace).To(Equal("team-a"))
+			Expect(installation.GetNamespace()).To(Equal("team-a"))
+
+			Expect(flags.Parse([]string{"--namespace", "Team_A"})).To(Succeed())
+			Expect(applyEditFlags(flags, installation)).To(MatchError(ContainSubstring("invalid --namespace")))
+		})
+
+		It("forces one runner per instance when using multiple instances", func() {
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.IntVar(&editInstances, "instances", 0, "")
+			Expect(flags.Parse([]string{"--instances", "3"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.Instances).To(Equal(3))
+			Expect(installation.MinRunners).To(Equal(1))
+			Expect(installation.MaxRunners).To(Equal(1))
+		})
+
+		It("merges dind flags into the existing dind configuration", func() {
+			installation.DinD = &types.DinDConfig{Image: "docker:27-dind"}
+
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringVar(&editDinDCPULimit, "dind-cpu-limit", "", "")
+			Expect(flags.Parse([]string{"--dind-cpu-limit", "2"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.DinD.Image).To(Equal("docker:27-dind"))
+			Expect(installation.DinD.Resources.Limits.CPU).To(Equal("2"))
+		})
+
+		It("drops the dind configuration when all values are cleared", func() {
+			installation.DinD = &types.DinDConfig{Image: "docker:27-dind"}
+
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringVar(&editDinDImage, "dind-image", "", "")
+			Expect(flags.Parse([]string{"--dind-image", ""})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.DinD).To(BeNil())
+		})
+
+		It("sets and clears the listener resources", func() {
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringVar(&editListenerMemoryLimit, "listener-memory-limit", "", "")
+			Expect(flags.Parse([]string{"--listener-memory-limit", "256Mi"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.Listener.Resources.Limits.Memory).To(Equal("256Mi"))
+
+			flags = pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringVar(&editListenerMemoryLimit, "listener-memory-limit", "", "")
+			Expect(flags.Parse([]string{"--listener-memory-limit", ""})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.Listener).To(BeNil())
+		})
+
+		It("replaces and removes the extra env vars", func() {
+			installation.EnvVars = map[string]string{"OLD": "1"}
+
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringArrayVar(&editEnv, "env", []string{}, "")
+			Expect(flags.Parse([]string{"--env", "DEBUG=true", "--env", "LIST=a,b"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.EnvVars).To(Equal(map[string]string{"DEBUG": "true", "LIST": "a,b"}))
+
+			flags = pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringArrayVar(&editEnv, "env", []string{}, "")
+			Expect(flags.Parse([]string{"--env", ""})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.EnvVars).To(BeNil())
+		})
+
+		It("replaces and removes the node selector", func() {
+			installation.NodeSelector = map[string]string{"disk": "hdd"}
+
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringSliceVar(&editNodeSelector, "node-selector", []string{}, "")
+			Expect(flags.Parse([]string{"--node-selector", "disk=ssd"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.NodeSelector).To(Equal(map[string]string{"disk": "ssd"}))
+
+			flags = pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringSliceVar(&editNodeSelector, "node-selector", []string{}, "")
+			Expect(flags.Parse([]string{"--node-selector", ""})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.NodeSelector).To(BeNil())
+		})
+
+		It("replaces the mounted secrets and keeps the mounted configmaps", func() {
+			installation.ObjectMounts = []types.ObjectMount{
+				{Kind: types.ObjectKindSecret, Name: "old-creds", Path: "/etc/creds"},
+				{Kind: types.ObjectKindConfigMap, Name: "ca-bundle", Path: "/etc/ssl/custom"},
+			}
+
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringSliceVar(&editMountSecrets, "mount-secret", []string{}, "")
+			flags.StringSliceVar(&editMountConfigMaps, "mount-configmap", []string{}, "")
+			Expect(flags.Parse([]string{"--mount-secret", "registry-creds:/etc/registry"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.ObjectMounts).To(Equal([]types.ObjectMount{
+				{Kind: types.ObjectKindSecret, Name: "registry-creds", Path: "/etc/registry"},
+				{Kind: types.ObjectKindConfigMap, Name: "ca-bundle", Path: "/etc/ssl/custom"},
+			}))
+
+			flags = pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringSliceVar(&editMountSecrets, "mount-secret", []string{}, "")
+			flags.StringSliceVar(&editMountConfigMaps, "mount-configmap", []string{}, "")
+			Expect(flags.Parse([]string{"--mount-secret", "", "--mount-configmap", ""})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.ObjectMounts).To(BeEmpty())
+		})
+
+		It("merges proxy flags and drops the proxy when all values are cleared", func() {
+			installation.Proxy = &types.ProxyConfig{HTTPSProxy: "http://proxy:3128"}
+
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringVar(&editHTTPSProxy, "https-proxy", "", "")
+			flags.StringVar(&editNoProxy, "no-proxy", "", "")
+			Expect(flags.Parse([]string{"--no-proxy", ".corp"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.Proxy).To(Equal(&types.ProxyConfig{HTTPSProxy: "http://proxy:3128", NoProxy: ".corp"}))
+
+			flags = pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringVar(&editHTTPSProxy, "https-proxy", "", "")
+			flags.StringVar(&editNoProxy, "no-proxy", "", "")
+			Expect(flags.Parse([]string{"--https-proxy", "", "--no-proxy", ""})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.Proxy).To(BeNil())
+		})
+
+		It("switches the caches to persistent volume claims and back", func() {
+			installation.ContainerMode = types.ContainerModePrivileged
+
+			flags := pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringVar(&editCacheType, "cache-type", "", "")
+			flags.StringVar(&editCacheSize, "cache-size", "", "")
+			Expect(flags.Parse([]string{"--cache-type", "pvc", "--cache-size", "50Gi"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.CacheStorage).To(Equal(&types.CacheStorage{Type: types.CacheTypePVC, Size: "50Gi"}))
+			Expect(validateEditedInstallation(installation)).To(Succeed())
+
+			flags = pflag.NewFlagSet("edit", pflag.ContinueOnError)
+			flags.StringVar(&editCacheType, "cache-type", "", "")
+			Expect(flags.Parse([]string{"--cache-type", "hostpath"})).To(Succeed())
+
+			Expect(applyEditFlags(flags, installation)).To(Succeed())
+			Expect(installation.CacheStorage).To(BeNil())
+		})
+	})
+
+	Describe("validateEditedInstallation", func() {
+		It("accepts a valid installation", func() {
+			Expect(validateEditedInstallation(installation)).To(Succeed())
+		})
+
+		It("rejects dind settings outside of dind mode", func() {
+			installation.ContainerMode = types.ContainerModeKubernetes
+			installation.DinD = &types.DinDConfig{Image: "docker:27-dind"}
+			Expect(validateEditedInstallation(installation)).To(MatchError(ContainSubstring("--mode dind")))
+		})
+
+		It("rejects a no-proxy list without a proxy", func() {
+			installation.Proxy = &types.ProxyConfig{NoProxy: ".corp"}
+			Expect(validateEditedInstallation(installation)).To(MatchError(ContainSubstring("--no-proxy requires")))
+		})
+
+		It("rejects a runner group on a repository URL", func() {
+			installation.RunnerGroup = "ci-runners"
+			Expect(validateEditedInstallation(installation)).To(MatchError(ContainSubstring("organization URL")))
+		})
+	})
+
+	DescribeTable("isInstanceOf",
+		func(scaleSetName string, expected bool) {
+			Expect(isInstanceOf(scaleSetName, "my-runner")).To(Equal(expected))
+		},
+		Entry("numbered instance", "my-runner-2", true),
+		Entry("installation itself", "my-runner", false),
+		Entry("other installation with shared prefix", "my-runner-cache", false),
+		Entry("missing instance number", "my-runner-", false),
+	)
+})
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/incus"
+	"github.com/spf13/cobra"
+)
+
+// remoteHost is the cluster host commands are forwarded to (--host)
+var remoteHost string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&remoteHost, "host", "", "Run the command on the named cluster host instead of locally")
+}
+
+// forwardToClusterHost runs the command on the cluster host given with --host
+func forwardToClusterHost(cmd *cobra.Command) error {
+	if remoteHost == "" {
+		return nil
+	}
+	return runOnClusterHost(cmd, remoteHost, stripHostFlag(os.Args[1:]))
+}
+
+// runOnClusterHost forwards a deskrun command to a cluster host: the local
+// configuration is pushed to the host, the command runs there with its output
+// streamed back, and deskrun exits with its exit status
+func runOnClusterHost(cmd *cobra.Command, name string, args []string) error {
+	if isClusterHostCommand(cmd) {
+		return fmt.Errorf("cluster-host commands manage the hosts themselves and can't run on a cluster host")
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	host, err := configMgr.GetClusterHost(name)
+	if err != nil {
+		return fmt.Errorf("cluster host %s not found in configuration", name)
+	}
+
+	incusMgr, err := incus.NewManager(host.Remote)
+	if err != nil {
+		return fmt.Errorf("failed to connect to incus: %w", err)
+	}
+
+	// The local configuration is the source of truth, the host's deskrun moves
+	// the auth values into its own secret store
+	configData, err := configMgr.ExportPlaintext()
+	if err != nil {
+		return fmt.Errorf("failed to export config: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := incusMgr.PushConfig(ctx, name, configData); err != nil {
+		return fmt.Errorf("failed to sync config to cluster host %s: %w", name, err)
+	}
+
+	if err := execOnClusterHost(incusMgr, name, append([]string{"deskrun"}, args...)); err != nil {
+		return err
+	}
+
+	// The command ran remotely, don't run it locally as well
+	os.Exit(0)
+	return nil
+}
+
+// isClusterHostCommand reports whether cmd is the cluster-host command or one
+// of its subcommands
+func isClusterHostCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == clusterHostCmd {
+			return true
+		}
+	}
+	return false
+}
+
+// stripHostFlag returns the command line arguments without the --host flag,
+// leaving arguments after -- untouched
+func stripHostFlag(args []string) []string {
+	var stripped []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(stripped, args[i:]...)
+		case arg == "--host":
+			i++ // skip the value
+		case strings.HasPrefix(arg, "--host="):
+		default:
+			stripped = append(stripped, arg)
+		}
+	}
+	return stripped
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Host Proxy", func() {
+	DescribeTable("stripping the --host flag",
+		func(args, expected []string) {
+			Expect(stripHostFlag(args)).To(Equal(expected))
+		},
+		Entry("separate value", []string{"--host", "my-host", "status"}, []string{"status"}),
+		Entry("inline value", []string{"status", "--host=my-host", "--verbose"}, []string{"status", "--verbose"}),
+		Entry("after the command", []string{"logs", "my-runner", "--host", "my-host", "-f"}, []string{"logs", "my-runner", "-f"}),
+		Entry("after --", []string{"--host", "my-host", "cluster-host", "shell", "x", "--", "deskrun", "--host", "y"},
+			[]string{"cluster-host", "shell", "x", "--", "deskrun", "--host", "y"}),
+	)
+
+	It("detects cluster-host commands", func() {
+		Expect(isClusterHostCommand(clusterHostShellCmd)).To(BeTrue())
+		Expect(isClusterHostCommand(clusterHostCmd)).To(BeTrue())
+		Expect(isClusterHostCommand(statusCmd)).To(BeFalse())
+	})
+})
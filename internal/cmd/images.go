@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/cluster"
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+// defaultImageArchive is the archive images export writes and images load reads
+const defaultImageArchive = "deskrun-images.tar"
+
+var (
+	imagesCluster string
+	imagesOutput  string
+	imagesOffline bool
+)
+
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "Export and load the images of a cluster for offline use",
+	Long: `Export the container images a cluster runs to an archive and load them
+into the cluster node, so deskrun runs on networks without registry access.
+
+The images are read from the rendered manifests: the ARC controller (which
+also runs the listeners), the runner and dind images of every installation and
+the cache server and observability stack when they are enabled. Images of
+workflow jobs are not included.
+
+By default the commands operate on the default cluster. Use --cluster to
+select one of the named clusters installations are pinned to.
+
+Examples:
+  deskrun images list                          # Show the images of the cluster
+  deskrun images export -o deskrun-images.tar  # On a machine with registry access
+  deskrun cluster create                       # On the offline machine
+  deskrun images load deskrun-images.tar
+  deskrun up                                   # Deploy without pulling images
+`,
+}
+
+var imagesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the images the cluster runs",
+	Args:  cobra.NoArgs,
+	RunE:  runImagesList,
+}
+
+var imagesExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Pull the images of the cluster and save them to an archive",
+	Args:  cobra.NoArgs,
+	RunE:  runImagesExport,
+}
+
+var imagesLoadCmd = &cobra.Command{
+	Use:   "load [archive]",
+	Short: "Load an image archive into the cluster node",
+	Long: `Load an archive written by 'deskrun images export' into the cluster node
+and switch the cluster to offline mode: runner pods only pull images missing
+from the node, also when they are tagged latest. Run 'deskrun up' afterwards to
+deploy the runners; 'deskrun images online' switches back.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImagesLoad,
+}
+
+var imagesOnlineCmd = &cobra.Command{
+	Use:   "online",
+	Short: "Pull images from their registries again",
+	Args:  cobra.NoArgs,
+	RunE:  runImagesOnline,
+}
+
+func init() {
+	imagesExportCmd.Flags().StringVarP(&imagesOutput, "output", "o", defaultImageArchive, "Archive to write")
+	imagesLoadCmd.Flags().BoolVar(&imagesOffline, "offline", true, "Switch the cluster to offline mode")
+
+	imagesCmd.PersistentFlags().StringVar(&imagesCluster, "cluster", "", "Name of the cluster (defaults to the default cluster)")
+	imagesCmd.AddCommand(imagesListCmd)
+	imagesCmd.AddCommand(imagesExportCmd)
+	imagesCmd.AddCommand(imagesLoadCmd)
+	imagesCmd.AddCommand(imagesOnlineCmd)
+	rootCmd.AddCommand(imagesCmd)
+}
+
+func runImagesList(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	images, err := clusterImages(cfg, resolveClusterName(cfg, imagesCluster))
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		fmt.Println(image)
+	}
+	return nil
+}
+
+func runImagesExport(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	images, err := clusterImages(cfg, resolveClusterName(cfg, imagesCluster))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	fmt.Printf("Saving %d images to %s\n", len(images), imagesOutput)
+	if err := cluster.SaveImages(ctx, imagesOutput, images); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Images saved to %s\n", imagesOutput)
+	return nil
+}
+
+func runImagesLoad(cmd *cobra.Command, args []string) error {
+	archive := defaultImageArchive
+	if len(args) > 0 {
+		archive = args[0]
+	}
+	if _, err := os.Stat(archive); err != nil {
+		return fmt.Errorf("image archive not found: %w", err)
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	clusterName := resolveClusterName(configMgr.GetConfig(), imagesCluster)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	clusterMgr := newClusterManager(configMgr.GetConfig(), clusterName)
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("cluster %s does not exist; create it with 'deskrun cluster create' first", clusterName)
+	}
+
+	if err := clusterMgr.LoadImages(ctx, archive); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Images of %s loaded into cluster '%s'\n", archive, clusterName)
+
+	if !imagesOffline {
+		return nil
+	}
+	if err := configMgr.SetClusterOffline(clusterName, true); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Println("\nTo deploy the runners without pulling images, run:")
+	fmt.Println("  deskrun up")
+	return nil
+}
+
+func runImagesOnline(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	clusterName := resolveClusterName(configMgr.GetConfig(), imagesCluster)
+	if err := configMgr.SetClusterOffline(clusterName, false); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Printf("✓ Cluster '%s' pulls images from their registries after the next 'deskrun up'\n", clusterName)
+	return nil
+}
+
+// clusterImages returns the images of the manifests deskrun deploys to a
+// cluster: the controller, the installations of the cluster and the cache
+// server and observability stack when they are enabled
+func clusterImages(cfg *config.Config, clusterName string) ([]string, error) {
+	controllerYAML, err := runner.RenderController(cfg.ControllerVersion, cfg.ClusterProxy(clusterName), true)
+	if err != nil {
+		return nil, err
+	}
+	manifests := [][]byte{controllerYAML}
+
+	for _, installation := range sortedInstallations(cfg.InstallationsForCluster(clusterName)) {
+		rendered, err := runner.Render(installationToDeploy(cfg, installation))
+		if err != nil {
+			return nil, fmt.Errorf("failed to render '%s': %w", installation.Name, err)
+		}
+		for _, manifest := range rendered {
+			manifests = append(manifests, manifest.YAML)
+		}
+	}
+
+	if cacheServer := cfg.ClusterCacheServer(clusterName); cacheServer != nil {
+		cacheServerYAML, err := runner.RenderCacheServer(cacheServer)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, cacheServerYAML)
+	}
+
+	if observability := cfg.ClusterObservability(clusterName); observability != nil {
+		observabilityYAML, err := runner.RenderObservability(observability)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, observabilityYAML)
+	}
+
+	return runner.ManifestImages(manifests...)
+}
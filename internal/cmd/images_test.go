@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Images Command", func() {
+	var cfg *config.Config
+
+	BeforeEach(func() {
+		cfg = &config.Config{
+			ClusterName: "deskrun",
+			Clusters: map[string]*types.ClusterSettings{
+				"deskrun": {Name: "deskrun", Offline: true},
+			},
+			Installations: map[string]*types.RunnerInstallation{
+				"my-runner": {
+					Name:          "my-runner",
+					Repository:    "https://github.com/owner/repo",
+					ContainerMode: types.ContainerModeDinD,
+					MinRunners:    1,
+					MaxRunners:    1,
+				},
+				"gpu-runner": {
+					Name:          "gpu-runner",
+					Repository:    "https://github.com/owner/repo",
+					ContainerMode: types.ContainerModeKubernetes,
+					Image:         "ghcr.io/owner/gpu-runner:1.0",
+					Cluster:       "deskrun-gpu",
+					MinRunners:    1,
+					MaxRunners:    1,
+				},
+			},
+		}
+	})
+
+	It("lists the controller, runner and dind images of a cluster", func() {
+		images, err := clusterImages(cfg, "deskrun")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(images).To(ContainElements(
+			"ghcr.io/actions/gha-runner-scale-set-controller:0.13.0",
+			"ghcr.io/actions/actions-runner:latest",
+			"docker:dind",
+		))
+		Expect(images).NotTo(ContainElement("ghcr.io/owner/gpu-runner:1.0"), "installations of other clusters should not be listed")
+	})
+
+	It("deploys installations of offline clusters without pulling present images", func() {
+		installation := cfg.Installations["my-runner"]
+		Expect(installationToDeploy(cfg, installation).ImagePullPolicy).To(Equal("IfNotPresent"))
+		Expect(installation.ImagePullPolicy).To(BeEmpty(), "the configured installation should not be modified")
+
+		Expect(installationToDeploy(cfg, cfg.Installations["gpu-runner"]).ImagePullPolicy).To(BeEmpty())
+	})
+})
@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs [name]",
+	Short: "Show queued and running workflow jobs per runner",
+	Long: `Show the queued and running GitHub Actions workflow jobs that target each
+runner scale set, next to the EphemeralRunners that serve them.
+
+Jobs are fetched from the GitHub Actions API and matched to a scale set by
+their runs-on label. Running jobs are correlated with the EphemeralRunner
+that picked them up, which helps to explain why runners are idle or why
+jobs stay queued.
+
+Jobs can only be listed for repository-level installations.
+
+Examples:
+  deskrun jobs              # Show jobs of all installations
+  deskrun jobs my-runner    # Show jobs of one installation
+`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runJobs,
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+}
+
+// jobEntry is a workflow job together with the EphemeralRunner running it
+type jobEntry struct {
+	Job github.WorkflowJob
+	// Runner is the EphemeralRunner that picked up the job, empty while queued
+	Runner string
+}
+
+// scaleSetJobs correlates the jobs targeting a scale set with its runners
+type scaleSetJobs struct {
+	Name        string
+	Jobs        []jobEntry
+	Runners     int
+	BusyRunners int
+	IdleRunners []string
+}
+
+// queuedJobs returns the number of jobs waiting for a runner
+func (s scaleSetJobs) queuedJobs() int {
+	queued := 0
+	for _, entry := range s.Jobs {
+		if entry.Job.Status != github.JobStatusInProgress {
+			queued++
+		}
+	}
+	return queued
+}
+
+func runJobs(cmd *cobra.Command, args []string) error {
+	// Load config
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var installations []*types.RunnerInstallation
+	if len(args) > 0 {
+		installation, err := configMgr.GetInstallation(args[0])
+		if err != nil {
+			return err
+		}
+		installations = append(installations, installation)
+	} else {
+		installations = sortedInstallations(configMgr.GetConfig().Installations)
+	}
+
+	if len(installations) == 0 {
+		fmt.Println("No runner installations found")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Runner managers per cluster, nil for clusters that do not exist
+	runnerMgrs := make(map[string]*runner.Manager)
+
+	for i, installation := range installations {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Runner: %s (%s)\n", installation.Name, installation.Repository)
+
+		jobs, err := activeJobs(ctx, installation)
+		if err != nil {
+			fmt.Printf("  %v\n", err)
+			continue
+		}
+
+		clusterName := configMgr.GetConfig().ClusterFor(installation)
+		runnerMgr, seen := runnerMgrs[clusterName]
+		if !seen {
+			runnerMgr, err = clusterRunnerManager(ctx, configMgr.GetConfig(), clusterName)
+			if err != nil {
+				return err
+			}
+			runnerMgrs[clusterName] = runnerMgr
+		}
+
+		scaleSets := scaleSetNames(configMgr, installation.Name)
+		for _, scaleSet := range scaleSets {
+			var runners []runner.EphemeralRunner
+			if runnerMgr != nil {
+				runners, err = runnerMgr.EphemeralRunners(ctx, scaleSet)
+				if err != nil {
+					return fmt.Errorf("failed to get runners of %s: %w", scaleSet, err)
+				}
+			}
+
+			printScaleSetJobs(os.Stdout, correlateJobs(scaleSet, jobs, runners), time.Now())
+		}
+
+		if labels := otherQueuedLabels(jobs, scaleSets); len(labels) > 0 {
+			fmt.Printf("  Other queued jobs in this repository target: %s\n", strings.Join(labels, ", "))
+		}
+	}
+
+	return nil
+}
+
+// githubClient returns a GitHub API client authenticating with the
+// credentials of an installation: its PAT, or tokens of its GitHub App
+// installation, which are created and renewed as needed
+func githubClient(installation *types.RunnerInstallation, apiBaseURL string) (*github.Client, error) {
+	if installation.AuthType != types.AuthTypeGitHubApp {
+		return github.NewClient(installation.AuthValue).WithBaseURL(apiBaseURL), nil
+	}
+
+	client, err := github.NewAppClient(installation.GitHubAppID, installation.GitHubAppInstallationID, []byte(installation.AuthValue))
+	if err != nil {
+		return nil, err
+	}
+	return client.WithBaseURL(apiBaseURL), nil
+}
+
+// activeJobs lists the queued and running jobs of an installation's repository
+func activeJobs(ctx context.Context, installation *types.RunnerInstallation) ([]github.WorkflowJob, error) {
+	target, err := github.ParseConfigURL(installation.Repository)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsOrganization() {
+		return nil, fmt.Errorf("skipped: jobs can only be listed for repository installations")
+	}
+
+	client, err := githubClient(installation, target.APIBaseURL)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := client.ActiveJobs(ctx, target.Owner, target.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// clusterRunnerManager returns a runner manager for a cluster, or nil if the cluster does not exist
+func clusterRunnerManager(ctx context.Context, cfg *config.Config, clusterName string) (*runner.Manager, error) {
+	clusterMgr := newClusterManager(cfg, clusterName)
+
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	return runner.NewManager(clusterMgr), nil
+}
+
+// correlateJobs matches the jobs targeting a scale set with the runners that picked them up
+func correlateJobs(scaleSet string, jobs []github.WorkflowJob, runners []runner.EphemeralRunner) scaleSetJobs {
+	result := scaleSetJobs{Name: scaleSet, Runners: len(runners)}
+
+	byRunnerName := make(map[string]string, len(runners))
+	for _, r := range runners {
+		byRunnerName[r.RunnerName] = r.Name
+	}
+
+	busy := make(map[string]bool)
+	for _, job := range jobs {
+		if !job.Targets(scaleSet) {
+			continue
+		}
+
+		entry := jobEntry{Job: job}
+		if name, ok := byRunnerName[job.RunnerName]; ok && job.RunnerName != "" {
+			entry.Runner = name
+			busy[name] = true
+		}
+		result.Jobs = append(result.Jobs, entry)
+	}
+
+	for _, r := range runners {
+		if busy[r.Name] || r.Busy() {
+			result.BusyRunners++
+			continue
+		}
+		result.IdleRunners = append(result.IdleRunners, r.Name)
+	}
+
+	return result
+}
+
+// otherQueuedLabels returns the labels of queued jobs that do not target any of the scale sets
+func otherQueuedLabels(jobs []github.WorkflowJob, scaleSets []string) []string {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, job := range jobs {
+		if job.Status == github.JobStatusInProgress {
+			continue
+		}
+
+		targeted := false
+		for _, scaleSet := range scaleSets {
+			if job.Targets(scaleSet) {
+				targeted = true
+				break
+			}
+		}
+
+		label := strings.Join(job.Labels, ",")
+		if !targeted && !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+
+	sort.Strings(labels)
+	return labels
+}
+
+// printScaleSetJobs displays the jobs and runners of a scale set, followed by
+// a hint explaining idle runners or queued jobs
+func printScaleSetJobs(w io.Writer, s scaleSetJobs, now time.Time) {
+	_, _ = fmt.Fprintf(w, "  Scale set %s: %d runner(s), %d busy, %d idle\n", s.Name, s.Runners, s.BusyRunners, len(s.IdleRunners))
+
+	for _, entry := range s.Jobs {
+		since := entry.Job.CreatedAt
+		if entry.Job.Status == github.JobStatusInProgress && !entry.Job.StartedAt.IsZero() {
+			since = entry.Job.StartedAt
+		}
+
+		runnerName := entry.Runner
+		if runnerName == "" {
+			runnerName = "-"
+		}
+
+		name := entry.Job.Name
+		if entry.Job.WorkflowName != "" {
+			name = entry.Job.WorkflowName + " / " + name
+		}
+
+		_, _ = fmt.Fprintf(w, "    %-12s %-5s %s  [%s]\n", entry.Job.Status, formatSince(now.Sub(since)), name, runnerName)
+	}
+
+	for _, name := range s.IdleRunners {
+		_, _ = fmt.Fprintf(w, "    %-12s %-5s [%s]\n", "idle", "", name)
+	}
+
+	if hint := jobsHint(s); hint != "" {
+		_, _ = fmt.Fprintf(w, "  → %s\n", hint)
+	}
+}
+
+// jobsHint explains the relation between queued jobs and runners of a scale set
+func jobsHint(s scaleSetJobs) string {
+	queued := s.queuedJobs()
+	switch {
+	case queued > 0 && s.Runners == 0:
+		return fmt.Sprintf("Jobs are queued but no runners exist; check the listener with 'deskrun logs %s'", s.Name)
+	case queued > 0 && len(s.IdleRunners) > 0:
+		return "Jobs are queued while runners are idle; the runners may still be registering with GitHub"
+	case queued > 0:
+		return "All runners are busy; queued jobs wait for a free runner (raise --max-runners to run more jobs in parallel)"
+	case len(s.IdleRunners) > 0 && len(s.Jobs) == 0:
+		return fmt.Sprintf("Runners are idle: no queued jobs use 'runs-on: %s'", s.Name)
+	default:
+		return ""
+	}
+}
+
+// formatSince formats a duration like kubectl ages (45s, 3m, 2h, 5d)
+func formatSince(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "0s"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
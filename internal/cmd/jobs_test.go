@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/internal/runner"
+)
+
+var _ = Describe("Jobs Command", func() {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	running := github.WorkflowJob{
+		ID: 1, Name: "test", WorkflowName: "CI", Status: github.JobStatusInProgress,
+		Labels: []string{"my-runner"}, RunnerName: "my-runner-abc12-runner-x7k2p",
+		CreatedAt: now.Add(-10 * time.Minute), StartedAt: now.Add(-3 * time.Minute),
+	}
+	queued := github.WorkflowJob{
+		ID: 2, Name: "deploy", WorkflowName: "CI", Status: github.JobStatusQueued,
+		Labels: []string{"my-runner"}, CreatedAt: now.Add(-5 * time.Minute),
+	}
+	otherQueued := github.WorkflowJob{
+		ID: 3, Name: "lint", Status: github.JobStatusQueued,
+		Labels: []string{"ubuntu-latest"}, CreatedAt: now.Add(-time.Minute),
+	}
+
+	busyRunner := runner.EphemeralRunner{Name: "my-runner-abc12-runner-x7k2p", RunnerName: "my-runner-abc12-runner-x7k2p", Ready: true}
+	idleRunner := runner.EphemeralRunner{Name: "my-runner-abc12-runner-q9w8e", RunnerName: "my-runner-abc12-runner-q9w8e", Ready: true}
+
+	Describe("correlateJobs", func() {
+		It("should match running jobs to their runner", func() {
+			result := correlateJobs("my-runner", []github.WorkflowJob{running, queued, otherQueued}, []runner.EphemeralRunner{busyRunner, idleRunner})
+
+			Expect(result.Jobs).To(HaveLen(2))
+			Expect(result.Jobs[0].Runner).To(Equal("my-runner-abc12-runner-x7k2p"))
+			Expect(result.Jobs[1].Runner).To(BeEmpty())
+			Expect(result.BusyRunners).To(Equal(1))
+			Expect(result.IdleRunners).To(Equal([]string{"my-runner-abc12-runner-q9w8e"}))
+			Expect(result.queuedJobs()).To(Equal(1))
+		})
+
+		It("should treat runners with an assigned job as busy", func() {
+			assigned := idleRunner
+			assigned.JobDisplayName = "build"
+
+			result := correlateJobs("my-runner", nil, []runner.EphemeralRunner{assigned})
+			Expect(result.BusyRunners).To(Equal(1))
+			Expect(result.IdleRunners).To(BeEmpty())
+		})
+	})
+
+	Describe("otherQueuedLabels", func() {
+		It("should list labels of queued jobs not targeting deskrun", func() {
+			labels := otherQueuedLabels([]github.WorkflowJob{running, queued, otherQueued}, []string{"my-runner"})
+			Expect(labels).To(Equal([]string{"ubuntu-latest"}))
+		})
+	})
+
+	Describe("jobsHint", func() {
+		DescribeTable("hints",
+			func(s scaleSetJobs, expected string) {
+				Expect(jobsHint(s)).To(ContainSubstring(expected))
+			},
+			Entry("queued without runners",
+				scaleSetJobs{Name: "my-runner", Jobs: []jobEntry{{Job: queued}}}, "no runners exist"),
+			Entry("queued with idle runners",
+				scaleSetJobs{Name: "my-runner", Jobs: []jobEntry{{Job: queued}}, Runners: 1, IdleRunners: []string{"r"}}, "may still be registering"),
+			Entry("queued with busy runners",
+				scaleSetJobs{Name: "my-runner", Jobs: []jobEntry{{Job: queued}}, Runners: 1, BusyRunners: 1}, "All runners are busy"),
+			Entry("idle without jobs",
+				scaleSetJobs{Name: "my-runner", Runners: 1, IdleRunners: []string{"r"}}, "runs-on: my-runner"),
+		)
+
+		It("should not hint when runners are busy with all jobs", func() {
+			Expect(jobsHint(scaleSetJobs{Name: "my-runner", Jobs: []jobEntry{{Job: running}}, Runners: 1, BusyRunners: 1})).To(BeEmpty())
+		})
+	})
+
+	Describe("printScaleSetJobs", func() {
+		It("should list jobs with their age and runner", func() {
+			var buf bytes.Buffer
+			result := correlateJobs("my-runner", []github.WorkflowJob{running, queued}, []runner.EphemeralRunner{busyRunner, idleRunner})
+			printScaleSetJobs(&buf, result, now)
+
+			output := buf.String()
+			Expect(output).To(ContainSubstring("Scale set my-runner: 2 runner(s), 1 busy, 1 idle\n"))
+			Expect(output).To(ContainSubstring("in_progress  3m    CI / test  [my-runner-abc12-runner-x7k2p]\n"))
+			Expect(output).To(ContainSubstring("queued       5m    CI / deploy  [-]\n"))
+			Expect(output).To(ContainSubstring("idle               [my-runner-abc12-runner-q9w8e]\n"))
+		})
+	})
+
+	Describe("formatSince", func() {
+		DescribeTable("durations",
+			func(d time.Duration, expected string) {
+				Expect(formatSince(d)).To(Equal(expected))
+			},
+			Entry("seconds", 45*time.Second, "45s"),
+			Entry("minutes", 3*time.Minute, "3m"),
+			Entry("hours", 2*time.Hour, "2h"),
+			Entry("days", 50*time.Hour, "2d"),
+			Entry("negative", -time.Second, "0s"),
+		)
+	})
+})
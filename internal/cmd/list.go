@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/rkoster/deskrun/internal/cluster"
 	"github.com/rkoster/deskrun/internal/config"
 	"github.com/rkoster/deskrun/internal/runner"
 	"github.com/rkoster/deskrun/pkg/types"
@@ -47,40 +46,44 @@ func runList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Printf("Cluster: %s\n\n", configMgr.GetConfig().ClusterName)
+	fmt.Printf("Cluster: %s\n\n", configMgr.GetConfig().DefaultCluster())
 	fmt.Println("Runner Installations:")
 	fmt.Println(strings.Repeat("-", 80))
 
-	// If showing instances, we need to connect to the cluster
-	var runnerMgr *runner.Manager
-	var actualInstances []string
+	// If showing instances, we need to connect to the cluster of every installation
+	deployedByCluster := make(map[string][]string)
 	if showInstances {
-		// Create cluster configuration
-		clusterConfig := &types.ClusterConfig{
-			Name: configMgr.GetConfig().ClusterName,
-		}
-		clusterMgr := cluster.NewManager(clusterConfig)
-
-		exists, err := clusterMgr.Exists(context.Background())
-		if err != nil {
-			return fmt.Errorf("failed to check cluster: %w", err)
-		}
-		if !exists {
-			fmt.Printf("Note: Cluster '%s' does not exist, cannot show running instances\n\n", configMgr.GetConfig().ClusterName)
-		} else {
-			runnerMgr = runner.NewManager(clusterMgr)
-			actualInstances, err = runnerMgr.List(context.Background())
+		for _, installation := range installations {
+			clusterName := configMgr.GetConfig().ClusterFor(installation)
+			if _, seen := deployedByCluster[clusterName]; seen {
+				continue
+			}
+			deployed, err := listDeployedRunners(configMgr.GetConfig(), clusterName)
 			if err != nil {
-				fmt.Printf("Warning: Failed to get running instances: %v\n\n", err)
-				actualInstances = []string{}
+				return err
 			}
+			deployedByCluster[clusterName] = deployed
 		}
 	}
 
 	for name, installation := range installations {
 		fmt.Printf("\nName:          %s\n", name)
 		fmt.Printf("Repository:    %s\n", installation.Repository)
+		if installation.RunnerGroup != "" {
+			fmt.Printf("Runner Group:  %s\n", installation.RunnerGroup)
+		}
+		if installation.Cluster != "" {
+			fmt.Printf("Cluster:       %s\n", installation.Cluster)
+		}
 		fmt.Printf("Mode:          %s\n", installation.ContainerMode)
+		if installation.Image != "" {
+			fmt.Printf("Image:         %s\n", installation.Image)
+		}
+		if installation.Arch != "" {
+			fmt.Printf("Arch:          %s\n", installation.Arch)
+		} else {
+			fmt.Printf("Arch:          any\n")
+		}
 
 		// Show configured instances
 		instances := installation.Instances
@@ -93,11 +96,50 @@ func runList(cmd *cobra.Command, args []string) error {
 		if instances == 1 {
 			fmt.Printf("Min Runners:   %d\n", installation.MinRunners)
 			fmt.Printf("Max Runners:   %d\n", installation.MaxRunners)
+			for _, window := range installation.Schedule {
+				fmt.Printf("Schedule:      %s\n", formatScheduleWindow(window))
+			}
 		} else {
 			fmt.Printf("Instances:     %d\n", instances)
 		}
+		if installation.Paused {
+			fmt.Printf("Paused:        yes (run 'deskrun resume %s' to pick up jobs again)\n", name)
+		}
 
 		fmt.Printf("Auth Type:     %s\n", installation.AuthType)
+		if installation.AuthType == types.AuthTypeGitHubApp {
+			fmt.Printf("GitHub App:    id=%d installation=%d\n", installation.GitHubAppID, installation.GitHubAppInstallationID)
+		}
+
+		if !installation.Resources.IsEmpty() {
+			fmt.Printf("Resources:     %s\n", formatResources(installation.Resources))
+		}
+
+		if installation.Listener != nil && !installation.Listener.Resources.IsEmpty() {
+			fmt.Printf("Listener:      %s\n", formatResources(installation.Listener.Resources))
+		}
+
+		if es := installation.EphemeralStorage; es != nil {
+			fmt.Printf("Ephemeral:     request=%s limit=%s\n", valueOrNone(es.Request), valueOrNone(es.Limit))
+		}
+
+		if installation.DinD != nil {
+			if installation.DinD.Image != "" {
+				fmt.Printf("DinD Image:    %s\n", installation.DinD.Image)
+			}
+			if !installation.DinD.Resources.IsEmpty() {
+				fmt.Printf("DinD Resources: %s\n", formatResources(installation.DinD.Resources))
+			}
+			if installation.DinD.MTU != 0 {
+				fmt.Printf("DinD MTU:      %d\n", installation.DinD.MTU)
+			}
+			if len(installation.DinD.RegistryMirrors) > 0 {
+				fmt.Printf("DinD Mirrors:  %s\n", strings.Join(installation.DinD.RegistryMirrors, ", "))
+			}
+			if len(installation.DinD.InsecureRegistries) > 0 {
+				fmt.Printf("DinD Insecure: %s\n", strings.Join(installation.DinD.InsecureRegistries, ", "))
+			}
+		}
 
 		if len(installation.Mounts) > 0 {
 			fmt.Printf("Mounts:        ")
@@ -131,8 +173,24 @@ func runList(cmd *cobra.Command, args []string) error {
 			}
 		}
 
+		if len(installation.CacheVolumes) > 0 {
+			fmt.Printf("Cache Volumes: %s\n", strings.Join(installation.CacheVolumes, ", "))
+		}
+
+		if storage := installation.CacheStorage; storage.IsPVC() {
+			size, storageClass := storage.Size, storage.StorageClass
+			if size == "" {
+				size = "10Gi"
+			}
+			if storageClass == "" {
+				storageClass = "default"
+			}
+			fmt.Printf("Cache Storage: pvc (%s, storage class %s)\n", size, storageClass)
+		}
+
 		// Show running instances if requested
-		if showInstances && runnerMgr != nil {
+		actualInstances := deployedByCluster[configMgr.GetConfig().ClusterFor(installation)]
+		if showInstances && actualInstances != nil {
 			fmt.Printf("Running:       ")
 
 			var runningInstances []string
@@ -165,3 +223,60 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// listDeployedRunners returns the runners deployed to a cluster, or nil if the
+// cluster does not exist
+func listDeployedRunners(cfg *config.Config, clusterName string) ([]string, error) {
+	clusterMgr := newClusterManager(cfg, clusterName)
+
+	exists, err := clusterMgr.Exists(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		fmt.Printf("Note: Cluster '%s' does not exist, cannot show running instances\n\n", clusterName)
+		return nil, nil
+	}
+
+	deployed, err := runner.NewManager(clusterMgr).List(context.Background())
+	if err != nil {
+		fmt.Printf("Warning: Failed to get running instances: %v\n\n", err)
+		return []string{}, nil
+	}
+	if deployed == nil {
+		deployed = []string{}
+	}
+	return deployed, nil
+}
+
+// formatResources renders resource requirements as a compact single line,
+// e.g. "requests cpu=500m,memory=1Gi; limits cpu=2"
+func formatResources(resources *types.ResourceRequirements) string {
+	format := func(list types.ResourceList) string {
+		var parts []string
+		if list.CPU != "" {
+			parts = append(parts, "cpu="+list.CPU)
+		}
+		if list.Memory != "" {
+			parts = append(parts, "memory="+list.Memory)
+		}
+		return strings.Join(parts, ",")
+	}
+
+	var sections []string
+	if requests := format(resources.Requests); requests != "" {
+		sections = append(sections, "requests "+requests)
+	}
+	if limits := format(resources.Limits); limits != "" {
+		sections = append(sections, "limits "+limits)
+	}
+	return strings.Join(sections, "; ")
+}
+
+// valueOrNone returns the value or "none" when it is empty
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollow     bool
+	logsSince      time.Duration
+	logsContainer  string
+	logsController bool
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [name]",
+	Short: "Show logs of runner and controller pods",
+	Long: `Show logs from the listener and EphemeralRunner pods of a runner installation.
+
+For installations with multiple instances, logs of all instances are shown.
+Every line is prefixed with the pod and container it came from. Without a
+name, logs of the ARC controller are shown.
+
+Examples:
+  deskrun logs my-runner                   # Show logs of all runner pods
+  deskrun logs my-runner --follow          # Stream logs, including new runner pods
+  deskrun logs my-runner --since 10m       # Only show the last 10 minutes
+  deskrun logs my-runner --container dind  # Only show the dind sidecar
+  deskrun logs my-runner --controller      # Include ARC controller logs
+  deskrun logs                             # Show ARC controller logs
+`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runLogs,
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Stream logs until interrupted")
+	logsCmd.Flags().DurationVar(&logsSince, "since", 0, "Only show logs newer than a relative duration like 5s, 2m, or 3h")
+	logsCmd.Flags().StringVarP(&logsContainer, "container", "c", "", "Only show logs of this container (e.g. runner, dind)")
+	logsCmd.Flags().BoolVar(&logsController, "controller", false, "Include logs of the ARC controller")
+	rootCmd.AddCommand(logsCmd)
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	// Load config
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var names []string
+	clusterName := configMgr.GetConfig().DefaultCluster()
+	if len(args) > 0 {
+		names = scaleSetNames(configMgr, args[0])
+		if installation, err := configMgr.GetInstallation(args[0]); err == nil {
+			clusterName = configMgr.GetConfig().ClusterFor(installation)
+		}
+	}
+
+	// Setup cluster manager
+	clusterMgr := newClusterManager(configMgr.GetConfig(), clusterName)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// Check if cluster exists
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+
+	if !exists {
+		return fmt.Errorf("cluster '%s' does not exist", clusterName)
+	}
+
+	runnerMgr := runner.NewManager(clusterMgr)
+	opts := runner.LogOptions{
+		Follow:            logsFollow,
+		Since:             logsSince,
+		Container:         logsContainer,
+		IncludeController: logsController || len(names) == 0,
+	}
+
+	if err := runnerMgr.StreamLogs(ctx, names, opts, os.Stdout); err != nil {
+		return fmt.Errorf("failed to stream logs: %w", err)
+	}
+
+	return nil
+}
+
+// scaleSetNames returns the deployed scale set names for an installation,
+// expanding installations with multiple instances to their numbered names.
+// Unknown names are returned as-is so deployed-only scale sets still work.
+func scaleSetNames(configMgr *config.Manager, name string) []string {
+	installation, err := configMgr.GetInstallation(name)
+	if err != nil {
+		return []string{name}
+	}
+	return instanceNames(name, installation.Instances)
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/cluster"
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror",
+	Short: "Manage local pull-through caches of container registries",
+	Long: `Manage local pull-through caches (registry mirrors) of container registries.
+
+Every mirrored registry gets a registry:2 pull-through cache container on the
+kind docker network. containerd on the cluster nodes pulls images of the
+registry through it, so repeated runs pull images from local disk instead of
+the internet. The mirrors are shared by all clusters.`,
+}
+
+var mirrorEnableCmd = &cobra.Command{
+	Use:   "enable [registry...]",
+	Short: "Start pull-through caches and configure the clusters to use them",
+	Long: `Start the pull-through cache of each registry (docker.io by default) and
+configure the nodes of all existing clusters to pull through it. New clusters
+are configured by 'deskrun up'.
+
+Clusters created by older deskrun versions don't read mirror configuration;
+recreate them with 'deskrun cluster delete' and 'deskrun up'.
+
+Examples:
+  deskrun mirror enable                     # Mirror Docker Hub
+  deskrun mirror enable ghcr.io quay.io     # Mirror other registries
+`,
+	RunE: runMirrorEnable,
+}
+
+var mirrorDisableCmd = &cobra.Command{
+	Use:   "disable [registry...]",
+	Short: "Stop pull-through caches",
+	Long: `Stop the pull-through cache of each registry (all mirrors by default) and
+pull images of the registry directly again. The cached images are kept in a
+docker volume per mirror.`,
+	RunE: runMirrorDisable,
+}
+
+var mirrorListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the mirrored registries",
+	RunE:  runMirrorList,
+}
+
+func init() {
+	mirrorCmd.AddCommand(mirrorEnableCmd)
+	mirrorCmd.AddCommand(mirrorDisableCmd)
+	mirrorCmd.AddCommand(mirrorListCmd)
+	rootCmd.AddCommand(mirrorCmd)
+}
+
+func runMirrorEnable(cmd *cobra.Command, args []string) error {
+	registries, err := normalizeMirrorRegistries(args)
+	if err != nil {
+		return err
+	}
+	if len(registries) == 0 {
+		registries = []string{"docker.io"}
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	for _, registry := range registries {
+		if err := configMgr.AddMirror(registry); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := configureClusterMirrors(ctx, configMgr.GetConfig(), registries, nil); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Mirroring %s\n", strings.Join(registries, ", "))
+	return nil
+}
+
+func runMirrorDisable(cmd *cobra.Command, args []string) error {
+	registries, err := normalizeMirrorRegistries(args)
+	if err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(registries) == 0 {
+		registries = append(registries, configMgr.GetConfig().Mirrors...)
+	}
+	if len(registries) == 0 {
+		fmt.Println("No mirrors enabled")
+		return nil
+	}
+
+	for _, registry := range registries {
+		if err := configMgr.RemoveMirror(registry); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := configureClusterMirrors(ctx, configMgr.GetConfig(), nil, registries); err != nil {
+		return err
+	}
+	for _, registry := range registries {
+		if err := cluster.RemoveMirror(ctx, registry); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✓ Stopped mirroring %s\n", strings.Join(registries, ", "))
+	return nil
+}
+
+func runMirrorList(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	mirrors := configMgr.GetConfig().Mirrors
+	if len(mirrors) == 0 {
+		fmt.Println("No mirrors enabled")
+		return nil
+	}
+
+	for _, registry := range mirrors {
+		fmt.Printf("%s\t%s\n", registry, cluster.MirrorContainerName(registry))
+	}
+	return nil
+}
+
+// configureClusterMirrors starts the enabled mirrors and updates the mirror
+// configuration of every existing cluster. The mirrors are only started once
+// a cluster (and with it the kind network) exists.
+func configureClusterMirrors(ctx context.Context, cfg *config.Config, enabled, disabled []string) error {
+	started := false
+	for _, clusterName := range targetClusters(cfg, "") {
+		clusterMgr := newClusterManager(cfg, clusterName)
+		if !clusterMgr.IsKind() {
+			continue
+		}
+		exists, err := clusterMgr.Exists(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check cluster: %w", err)
+		}
+		if !exists {
+			continue
+		}
+
+		if !started {
+			for _, registry := range enabled {
+				if err := cluster.EnsureMirror(ctx, registry); err != nil {
+					return err
+				}
+			}
+			started = true
+		}
+
+		if err := clusterMgr.ConfigureMirrors(ctx, enabled, disabled); err != nil {
+			return fmt.Errorf("failed to configure mirrors of cluster '%s': %w", clusterName, err)
+		}
+		fmt.Printf("✓ Mirrors of cluster '%s' updated\n", clusterName)
+	}
+	return nil
+}
+
+// normalizeMirrorRegistries converts registry arguments to registry hosts,
+// mapping the Docker Hub hosts to docker.io as used in image references
+func normalizeMirrorRegistries(values []string) ([]string, error) {
+	registries := make([]string, 0, len(values))
+	for _, value := range values {
+		registry := strings.TrimPrefix(strings.TrimPrefix(value, "https://"), "http://")
+		registry = strings.TrimSuffix(registry, "/")
+		if registry == "" || strings.ContainsAny(registry, "/ ") {
+			return nil, fmt.Errorf("invalid registry '%s', expected a registry host like docker.io or ghcr.io", value)
+		}
+
+		switch registry {
+		case "index.docker.io", "registry-1.docker.io":
+			registry = "docker.io"
+		}
+		registries = append(registries, registry)
+	}
+	return registries, nil
+}
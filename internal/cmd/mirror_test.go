@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mirror Command", func() {
+	DescribeTable("normalizing mirror registries",
+		func(value, expected, expectedErrorMsg string) {
+			registries, err := normalizeMirrorRegistries([]string{value})
+
+			if expectedErrorMsg == "" {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(registries).To(Equal([]string{expected}))
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectedErrorMsg)))
+			}
+		},
+		Entry("plain host", "ghcr.io", "ghcr.io", ""),
+		Entry("host with port and scheme", "https://registry.corp:5000/", "registry.corp:5000", ""),
+		Entry("docker hub", "docker.io", "docker.io", ""),
+		Entry("docker hub registry host", "https://registry-1.docker.io", "docker.io", ""),
+		Entry("invalid: repository path", "ghcr.io/owner/image", "", "invalid registry"),
+		Entry("invalid: empty", "", "", "invalid registry"),
+	)
+})
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/cluster"
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/templates"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	observabilityCluster string
+
+	observabilityGrafana     bool
+	observabilityPort        int32
+	observabilityGrafanaPort int32
+	observabilityRetention   string
+)
+
+// retentionPattern matches the Prometheus durations accepted by --retention
+var retentionPattern = regexp.MustCompile(`^[0-9]+(ms|s|m|h|d|w|y)$`)
+
+var observabilityCmd = &cobra.Command{
+	Use:   "observability",
+	Short: "Manage the opt-in Prometheus and Grafana metrics stack",
+	Long: `Manage the opt-in metrics stack of a cluster.
+
+The ARC controller and the listeners of the runner scale sets serve Prometheus
+metrics ('deskrun status --metrics' shows the key ones). When enabled, a
+Prometheus in the arc-systems namespace scrapes them. Optionally Grafana is
+deployed with a dashboard of the runners. The metrics include:
+
+  gha_job_startup_duration_seconds        Runner scale-up latency (queued to started)
+  gha_job_execution_duration_seconds      Job durations
+  gha_desired_runners, gha_busy_runners   Runners per scale set
+  controller_runtime_reconcile_errors_total  Controller errors
+
+Prometheus and Grafana are exposed on localhost of kind clusters; for other
+clusters use 'kubectl port-forward'.
+
+By default the commands operate on the default cluster. Use --cluster to
+manage one of the named clusters installations are pinned to.`,
+}
+
+var observabilityEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Deploy the metrics stack",
+	Long: `Deploy Prometheus (and with --grafana, Grafana) scraping the metrics of
+the ARC controller and its listeners.
+
+Prometheus is exposed on http://localhost:9090 and Grafana on
+http://localhost:3000 unless --port and --grafana-port are given. Metrics are
+kept for 7 days unless --retention is given, and are lost when Prometheus
+restarts.
+
+Examples:
+  deskrun observability enable
+  deskrun observability enable --grafana
+  deskrun observability enable --port 19090 --retention 30d
+  deskrun observability enable --cluster deskrun-gpu
+`,
+	RunE: runObservabilityEnable,
+}
+
+var observabilityDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Remove the metrics stack",
+	RunE:  runObservabilityDisable,
+}
+
+func init() {
+	observabilityEnableCmd.Flags().BoolVar(&observabilityGrafana, "grafana", false, "Also deploy Grafana with the deskrun dashboard")
+	observabilityEnableCmd.Flags().Int32Var(&observabilityPort, "port", templates.DefaultPrometheusPort, "Local port to expose Prometheus on")
+	observabilityEnableCmd.Flags().Int32Var(&observabilityGrafanaPort, "grafana-port", templates.DefaultGrafanaPort, "Local port to expose Grafana on")
+	observabilityEnableCmd.Flags().StringVar(&observabilityRetention, "retention", "", "How long Prometheus keeps metrics, e.g. 30d (defaults to 7d)")
+
+	observabilityCmd.PersistentFlags().StringVar(&observabilityCluster, "cluster", "", "Name of the cluster to manage (defaults to the default cluster)")
+	observabilityCmd.AddCommand(observabilityEnableCmd)
+	observabilityCmd.AddCommand(observabilityDisableCmd)
+	rootCmd.AddCommand(observabilityCmd)
+}
+
+func runObservabilityEnable(cmd *cobra.Command, args []string) error {
+	observability := &types.ObservabilityConfig{
+		Grafana:   observabilityGrafana,
+		Retention: observabilityRetention,
+	}
+	// Only store ports that differ from the defaults, so changed defaults apply
+	if observabilityPort != templates.DefaultPrometheusPort {
+		observability.Port = observabilityPort
+	}
+	if observabilityGrafanaPort != templates.DefaultGrafanaPort {
+		observability.GrafanaPort = observabilityGrafanaPort
+	}
+	if err := validateObservabilityConfig(observability); err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	cfg := configMgr.GetConfig()
+	clusterName := resolveClusterName(cfg, observabilityCluster)
+	if err := configMgr.SetClusterObservability(clusterName, observability); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	clusterMgr := newClusterManager(cfg, clusterName)
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		fmt.Printf("Observability enabled for cluster '%s'; it is deployed when the cluster is created with 'deskrun up'\n", clusterName)
+		return nil
+	}
+
+	runnerMgr := runner.NewManager(clusterMgr).
+		WithControllerVersion(cfg.ControllerVersion).
+		WithControllerProxy(cfg.ClusterProxy(clusterName))
+
+	slog.Info("Deploying observability stack", "cluster", clusterName)
+	if err := deployObservability(ctx, clusterMgr, runnerMgr, observability); err != nil {
+		return err
+	}
+
+	// Controllers deployed by older deskrun versions don't serve metrics yet
+	if _, err := runnerMgr.RedeployController(ctx); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Observability enabled for cluster '%s'\n", clusterName)
+	return nil
+}
+
+func runObservabilityDisable(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	cfg := configMgr.GetConfig()
+	clusterName := resolveClusterName(cfg, observabilityCluster)
+	if err := configMgr.SetClusterObservability(clusterName, nil); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	clusterMgr := newClusterManager(cfg, clusterName)
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if exists {
+		if err := runner.NewManager(clusterMgr).DisableObservability(ctx); err != nil {
+			return err
+		}
+	}
+	if clusterMgr.IsKind() {
+		if err := clusterMgr.RemoveExposedPorts(ctx); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✓ Observability of cluster '%s' disabled\n", clusterName)
+	return nil
+}
+
+// deployObservability deploys the metrics stack and exposes Prometheus and
+// Grafana on localhost of kind clusters, or prints how to reach them
+func deployObservability(ctx context.Context, clusterMgr *cluster.Manager, runnerMgr *runner.Manager, observability *types.ObservabilityConfig) error {
+	if err := runnerMgr.EnableObservability(ctx, observability); err != nil {
+		return fmt.Errorf("failed to deploy observability stack: %w", err)
+	}
+
+	prometheusPort, grafanaPort := observabilityPorts(observability)
+	if !clusterMgr.IsKind() {
+		fmt.Printf("To reach Prometheus, run:\n  kubectl --context %s -n arc-systems port-forward svc/%s-prometheus %d:9090\n",
+			clusterMgr.GetKubeconfig(), templates.ObservabilityName, prometheusPort)
+		if observability.Grafana {
+			fmt.Printf("To reach Grafana, run:\n  kubectl --context %s -n arc-systems port-forward svc/%s-grafana %d:3000\n",
+				clusterMgr.GetKubeconfig(), templates.ObservabilityName, grafanaPort)
+		}
+		return nil
+	}
+
+	// Forward the configured ports only, dropping those of earlier settings
+	if err := clusterMgr.RemoveExposedPorts(ctx); err != nil {
+		return err
+	}
+	if err := clusterMgr.ExposeNodePort(ctx, prometheusPort, templates.PrometheusNodePort); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Prometheus available at http://localhost:%d\n", prometheusPort)
+
+	if observability.Grafana {
+		if err := clusterMgr.ExposeNodePort(ctx, grafanaPort, templates.GrafanaNodePort); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Grafana available at http://localhost:%d\n", grafanaPort)
+	}
+	return nil
+}
+
+// observabilityPorts returns the local ports of Prometheus and Grafana,
+// falling back to the defaults
+func observabilityPorts(observability *types.ObservabilityConfig) (prometheus, grafana int32) {
+	prometheus, grafana = templates.DefaultPrometheusPort, templates.DefaultGrafanaPort
+	if observability.Port != 0 {
+		prometheus = observability.Port
+	}
+	if observability.GrafanaPort != 0 {
+		grafana = observability.GrafanaPort
+	}
+	return prometheus, grafana
+}
+
+// validateObservabilityConfig checks the flags of observability enable
+func validateObservabilityConfig(observability *types.ObservabilityConfig) error {
+	prometheusPort, grafanaPort := observabilityPorts(observability)
+	if prometheusPort < 1 || prometheusPort > 65535 {
+		return fmt.Errorf("invalid --port %d, must be between 1 and 65535", prometheusPort)
+	}
+	if grafanaPort < 1 || grafanaPort > 65535 {
+		return fmt.Errorf("invalid --grafana-port %d, must be between 1 and 65535", grafanaPort)
+	}
+	if observability.Grafana && prometheusPort == grafanaPort {
+		return fmt.Errorf("--port and --grafana-port must differ")
+	}
+	if observability.Retention != "" && !retentionPattern.MatchString(observability.Retention) {
+		return fmt.Errorf("invalid --retention '%s', expected a duration like 12h, 7d or 4w", observability.Retention)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Observability Command", func() {
+	DescribeTable("validating observability settings",
+		func(observability types.ObservabilityConfig, expectedErrorMsg string) {
+			err := validateObservabilityConfig(&observability)
+
+			if expectedErrorMsg == "" {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectedErrorMsg)))
+			}
+		},
+		Entry("valid: defaults", types.ObservabilityConfig{}, ""),
+		Entry("valid: grafana, ports and retention", types.ObservabilityConfig{Grafana: true, Port: 19090, GrafanaPort: 13000, Retention: "30d"}, ""),
+		Entry("valid: same ports without grafana", types.ObservabilityConfig{Port: 3000}, ""),
+		Entry("invalid: port", types.ObservabilityConfig{Port: 70000}, "invalid --port"),
+		Entry("invalid: grafana port", types.ObservabilityConfig{GrafanaPort: -1}, "invalid --grafana-port"),
+		Entry("invalid: same ports", types.ObservabilityConfig{Grafana: true, Port: 3000}, "must differ"),
+		Entry("invalid: retention", types.ObservabilityConfig{Retention: "a week"}, "invalid --retention"),
+	)
+
+	It("falls back to the default ports", func() {
+		prometheus, grafana := observabilityPorts(&types.ObservabilityConfig{})
+		Expect(prometheus).To(Equal(int32(9090)))
+		Expect(grafana).To(Equal(int32(3000)))
+
+		prometheus, grafana = observabilityPorts(&types.ObservabilityConfig{Port: 19090, GrafanaPort: 13000})
+		Expect(prometheus).To(Equal(int32(19090)))
+		Expect(grafana).To(Equal(int32(13000)))
+	})
+})
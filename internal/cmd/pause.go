@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause <name>",
+	Short: "Stop a runner installation from picking up jobs",
+	Long: `Stop the runners of an installation from picking up new jobs, e.g. before
+a laptop goes on battery.
+
+The scale sets of the installation are scaled down to zero runners. Running
+jobs finish, queued jobs wait for other runners, and all resources and the
+configuration are kept, so 'deskrun resume' restores the configured runner
+limits right away. The installation stays paused across 'deskrun up'.
+
+Examples:
+  deskrun pause my-runner
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSetPaused(args[0], true)
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <name>",
+	Short: "Let a paused runner installation pick up jobs again",
+	Long: `Resume a runner installation paused with 'deskrun pause', restoring the
+configured minimum and maximum runners of its scale sets.
+
+Examples:
+  deskrun resume my-runner
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSetPaused(args[0], false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+}
+
+func runSetPaused(name string, paused bool) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	installation, err := configMgr.GetInstallation(name)
+	if err != nil {
+		return fmt.Errorf("installation not found: %w", err)
+	}
+	if installation.Paused == paused {
+		if paused {
+			fmt.Printf("Runner '%s' is already paused\n", name)
+		} else {
+			fmt.Printf("Runner '%s' is not paused\n", name)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	clusterName := configMgr.GetConfig().ClusterFor(installation)
+	runnerMgr, err := clusterRunnerManager(ctx, configMgr.GetConfig(), clusterName)
+	if err != nil {
+		return err
+	}
+
+	if err := setPaused(ctx, runnerMgr, configMgr, installation, paused); err != nil {
+		return err
+	}
+
+	if runnerMgr == nil {
+		fmt.Printf("✓ Runner '%s' %s in configuration; cluster '%s' does not exist yet\n", name, pausedState(paused), clusterName)
+		return nil
+	}
+	fmt.Printf("✓ Runner '%s' %s\n", name, pausedState(paused))
+	return nil
+}
+
+// pausedState describes the result of pausing or resuming
+func pausedState(paused bool) string {
+	if paused {
+		return "paused"
+	}
+	return "resumed"
+}
+
+// setPaused scales the deployed scale sets of an installation down to zero
+// runners or back to its configured or scheduled limits, and records the state in the
+// configuration. Without a runner manager only the configuration is updated;
+// scale sets that are not deployed get the state on the next 'deskrun up'.
+func setPaused(ctx context.Context, runnerMgr *runner.Manager, configMgr *config.Manager, installation *types.RunnerInstallation, paused bool) error {
+	if runnerMgr != nil {
+		deployed, err := runnerMgr.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list runners: %w", err)
+		}
+
+		for _, scaleSet := range instanceNames(installation.Name, installation.Instances) {
+			if !slices.Contains(deployed, scaleSet) {
+				continue
+			}
+			if paused {
+				err = runnerMgr.PauseScaleSet(ctx, scaleSet)
+			} else {
+				minRunners, maxRunners := scheduledLimits(installation, time.Now())
+				err = runnerMgr.ScaleScaleSet(ctx, scaleSet, minRunners, maxRunners)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	installation.Paused = paused
+	if err := configMgr.UpdateInstallation(installation); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Pause Command", func() {
+	Describe("setPaused", func() {
+		It("records the paused state in the configuration without a cluster", func() {
+			GinkgoT().Setenv("HOME", GinkgoT().TempDir())
+
+			configMgr, err := config.NewManager()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configMgr.AddInstallation(&types.RunnerInstallation{Name: "my-runner", MinRunners: 1, MaxRunners: 3})).To(Succeed())
+
+			installation, err := configMgr.GetInstallation("my-runner")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(setPaused(context.Background(), nil, configMgr, installation, true)).To(Succeed())
+
+			reloaded, err := config.NewManager()
+			Expect(err).NotTo(HaveOccurred())
+			paused, err := reloaded.GetInstallation("my-runner")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paused.Paused).To(BeTrue())
+			Expect(paused.MinRunners).To(Equal(1))
+			Expect(paused.MaxRunners).To(Equal(3))
+		})
+	})
+})
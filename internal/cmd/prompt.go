@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdinReader buffers stdin for prompts that read whole lines
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// promptLine asks for a line of input on stderr and reads it from stdin
+func promptLine(label string) (string, error) {
+	_, _ = fmt.Fprint(os.Stderr, label)
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// promptSecret asks for a secret on stderr and reads it from stdin without
+// echoing it when stdin is a terminal
+func promptSecret(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return promptLine(label)
+	}
+
+	_, _ = fmt.Fprint(os.Stderr, label)
+	value, err := term.ReadPassword(fd)
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneCluster string
+	pruneDryRun  bool
+	pruneYes     bool
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove cluster resources and cache directories no installation uses",
+	Long: `Find and remove what renamed, removed or half-deployed installations left
+behind in a cluster:
+
+  - runner scale sets that are deployed but no longer configured
+  - namespaces deskrun created that no runner scale set is deployed to
+  - hook extension configmaps, GitHub secrets and cache claims that no
+    deployed runner scale set owns (e.g. privileged-hook-extension-*)
+  - cache directories on the cluster node that no configured mount uses,
+    in ` + strings.Join(runner.CacheDirRoots, ", ") + `
+
+The resources are listed and only removed after confirmation. Directories of
+shared cache volumes that are configured are always kept.
+
+Examples:
+  deskrun prune --dry-run               # Only list what would be removed
+  deskrun prune                         # Remove after confirmation
+  deskrun prune --cluster deskrun-gpu --yes
+`,
+	RunE: runPrune,
+}
+
+func init() {
+	pruneCmd.Flags().StringVar(&pruneCluster, "cluster", "", "Only prune this cluster (defaults to all known clusters)")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "List what would be removed without removing anything")
+	pruneCmd.Flags().BoolVarP(&pruneYes, "yes", "y", false, "Remove without asking for confirmation")
+	rootCmd.AddCommand(pruneCmd)
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	for i, clusterName := range targetClusters(cfg, pruneCluster) {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		runnerMgr, err := clusterRunnerManager(ctx, cfg, clusterName)
+		if err != nil {
+			return err
+		}
+		if runnerMgr == nil {
+			fmt.Printf("Cluster '%s' does not exist\n", clusterName)
+			continue
+		}
+
+		opts, err := pruneOptions(cfg, clusterName)
+		if err != nil {
+			return err
+		}
+		plan, err := runnerMgr.PrunePlan(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("failed to find unused resources in cluster '%s': %w", clusterName, err)
+		}
+
+		printPrunePlan(os.Stdout, clusterName, plan)
+		if plan.IsEmpty() || pruneDryRun {
+			continue
+		}
+
+		if !pruneYes {
+			answer, err := promptLine("\nRemove these resources? [y/N] ")
+			if err != nil {
+				return err
+			}
+			if !isConfirmation(answer) {
+				fmt.Println("Nothing removed")
+				continue
+			}
+		}
+
+		if err := runnerMgr.Prune(ctx, plan); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Pruned cluster '%s'\n", clusterName)
+	}
+	return nil
+}
+
+// pruneOptions returns the scale sets, cache claims and node directories the
+// configured installations of a cluster use
+func pruneOptions(cfg *config.Config, clusterName string) (runner.PruneOptions, error) {
+	var opts runner.PruneOptions
+	for _, installation := range sortedInstallations(cfg.Installations) {
+		if cfg.ClusterFor(installation) != clusterName {
+			continue
+		}
+
+		deployed := installationWithCacheVolumes(cfg, installation)
+		for _, scaleSet := range instanceNames(installation.Name, installation.Instances) {
+			opts.ScaleSets = append(opts.ScaleSets, scaleSet)
+			opts.Claims = append(opts.Claims, deployed.CacheClaimNames(scaleSet)...)
+		}
+
+		dirs, err := runner.CacheDirs(deployed)
+		if err != nil {
+			return runner.PruneOptions{}, fmt.Errorf("installation %s: %w", installation.Name, err)
+		}
+		for _, dir := range dirs {
+			opts.HostPaths = append(opts.HostPaths, dir.HostPath)
+		}
+	}
+
+	// Cache volumes are stored on the host, which all clusters share
+	for _, volume := range cfg.SortedCacheVolumes() {
+		opts.HostPaths = append(opts.HostPaths, volume.HostPath())
+	}
+	return opts, nil
+}
+
+// printPrunePlan lists the resources of a cluster that would be removed
+func printPrunePlan(w io.Writer, clusterName string, plan *runner.PrunePlan) {
+	if plan.IsEmpty() {
+		_, _ = fmt.Fprintf(w, "Cluster '%s': nothing to prune\n", clusterName)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "Cluster '%s':\n", clusterName)
+	if len(plan.Apps) > 0 {
+		_, _ = fmt.Fprintln(w, "  Runner scale sets not in the configuration:")
+		for _, app := range plan.Apps {
+			_, _ = fmt.Fprintf(w, "    %s/%s\n", app.Namespace, app.Name)
+		}
+	}
+	if len(plan.Namespaces) > 0 {
+		_, _ = fmt.Fprintln(w, "  Namespaces without runner scale sets:")
+		for _, namespace := range plan.Namespaces {
+			_, _ = fmt.Fprintf(w, "    %s\n", namespace)
+		}
+	}
+	if len(plan.Objects) > 0 {
+		_, _ = fmt.Fprintln(w, "  Leftover objects:")
+		for _, object := range plan.Objects {
+			_, _ = fmt.Fprintf(w, "    %s %s/%s\n", object.Kind, object.Namespace, object.Name)
+		}
+	}
+	if len(plan.CacheDirs) > 0 {
+		_, _ = fmt.Fprintln(w, "  Unused cache directories on the node:")
+		for _, dir := range plan.CacheDirs {
+			_, _ = fmt.Fprintf(w, "    %s\n", dir)
+		}
+	}
+}
+
+// isConfirmation returns true if a prompt was answered with yes
+func isConfirmation(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Prune Command", func() {
+	Describe("pruneOptions", func() {
+		cfg := &config.Config{
+			ClusterName: "deskrun",
+			Installations: map[string]*types.RunnerInstallation{
+				"a": {
+					Name:         "a",
+					Instances:    2,
+					CacheStorage: &types.CacheStorage{Type: types.CacheTypePVC},
+					Mounts:       []types.Mount{{Target: "/var/lib/docker"}, {Source: "/shared", Target: "/root/.npm"}},
+				},
+				"b":   {Name: "b", CacheVolumes: []string{"npm"}},
+				"gpu": {Name: "gpu", Cluster: "deskrun-gpu"},
+			},
+			CacheVolumes: map[string]*types.CacheVolume{
+				"npm": {Name: "npm", Path: "/root/.npm"},
+				"pip": {Name: "pip", Path: "/root/.cache/pip"},
+			},
+		}
+
+		It("keeps the scale sets, claims and host paths of the cluster's installations", func() {
+			opts, err := pruneOptions(cfg, "deskrun")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts.ScaleSets).To(Equal([]string{"a-1", "a-2", "b"}))
+			Expect(opts.Claims).To(Equal([]string{"a-1-mount-0", "a-2-mount-0"}))
+			Expect(opts.HostPaths).To(ContainElements("/shared", "/host-cache/deskrun/volumes/npm"))
+		})
+
+		It("keeps all cache volumes on every cluster", func() {
+			opts, err := pruneOptions(cfg, "deskrun-gpu")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts.ScaleSets).To(Equal([]string{"gpu"}))
+			Expect(opts.HostPaths).To(Equal([]string{"/host-cache/deskrun/volumes/npm", "/host-cache/deskrun/volumes/pip"}))
+		})
+	})
+
+	Describe("printPrunePlan", func() {
+		It("lists the resources to remove", func() {
+			plan := &runner.PrunePlan{
+				Apps:       []runner.PruneApp{{Name: "old-runner", Namespace: "arc-systems"}},
+				Namespaces: []string{"old-namespace"},
+				Objects:    []runner.PruneObject{{Kind: "ConfigMap", Namespace: "arc-systems", Name: "privileged-hook-extension-gone"}},
+				CacheDirs:  []string{"/tmp/github-runner-cache/gone"},
+			}
+
+			var out bytes.Buffer
+			printPrunePlan(&out, "deskrun", plan)
+			Expect(out.String()).To(Equal(`Cluster 'deskrun':
+  Runner scale sets not in the configuration:
+    arc-systems/old-runner
+  Namespaces without runner scale sets:
+    old-namespace
+  Leftover objects:
+    ConfigMap arc-systems/privileged-hook-extension-gone
+  Unused cache directories on the node:
+    /tmp/github-runner-cache/gone
+`))
+		})
+
+		It("reports when there is nothing to prune", func() {
+			var out bytes.Buffer
+			printPrunePlan(&out, "deskrun", &runner.PrunePlan{})
+			Expect(out.String()).To(Equal("Cluster 'deskrun': nothing to prune\n"))
+		})
+	})
+
+	It("only accepts yes as confirmation", func() {
+		Expect(isConfirmation("Y")).To(BeTrue())
+		Expect(isConfirmation("yes")).To(BeTrue())
+		Expect(isConfirmation("")).To(BeFalse())
+		Expect(isConfirmation("no")).To(BeFalse())
+	})
+})
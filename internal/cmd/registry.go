@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// dockerHubServer is the registry key Docker Hub credentials are stored under
+// by the docker CLI, which kubelet understands as well
+const dockerHubServer = "https://index.docker.io/v1/"
+
+var (
+	registryServer   string
+	registryUsername string
+	registryPassword string
+)
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Manage private container registry credentials",
+	Long: `Manage the credentials of private container registries that runner and
+job images are pulled from.
+
+The credentials of all registries are stored in the '` + runner.RegistrySecretName + `'
+docker-registry secret in arc-systems and the installation namespaces of every
+cluster. Runner
+pods pull their image with it, job pods of the kubernetes modes pull through
+the default service account, and in dind mode the runner's docker CLI is
+logged in to the registries.
+
+Passwords are kept in the secret store of deskrun, like runner auth values.`,
+}
+
+var registryAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or update the credentials of a registry",
+	Long: `Add or update the credentials of a private container registry.
+
+The registry secret is updated right away in clusters that exist; run
+'deskrun up' to redeploy the runners with it.
+
+Examples:
+  deskrun registry add ghcr --server ghcr.io --username my-bot --password ghp_xxx
+  deskrun registry add corp --server registry.corp:5000 --username ci --password "$(cat token)"
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRegistryAdd,
+}
+
+var registryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured registries",
+	RunE:  runRegistryList,
+}
+
+var registryRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove the credentials of a registry",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRegistryRemove,
+}
+
+func init() {
+	registryAddCmd.Flags().StringVar(&registryServer, "server", "", "Registry host, e.g. ghcr.io or registry.example.com:5000")
+	registryAddCmd.Flags().StringVar(&registryUsername, "username", "", "Registry username")
+	registryAddCmd.Flags().StringVar(&registryPassword, "password", "", "Registry password or access token")
+	for _, flag := range []string{"server", "username", "password"} {
+		if err := registryAddCmd.MarkFlagRequired(flag); err != nil {
+			panic(err)
+		}
+	}
+
+	registryCmd.AddCommand(registryAddCmd)
+	registryCmd.AddCommand(registryListCmd)
+	registryCmd.AddCommand(registryRemoveCmd)
+	rootCmd.AddCommand(registryCmd)
+}
+
+func runRegistryAdd(cmd *cobra.Command, args []string) error {
+	server, err := normalizeRegistryServer(registryServer)
+	if err != nil {
+		return err
+	}
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	registry := &types.RegistryCredential{
+		Name:     args[0],
+		Server:   server,
+		Username: registryUsername,
+		Password: registryPassword,
+	}
+	if err := configMgr.SetRegistry(registry); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("Registry '%s' (%s) saved to configuration\n", registry.Name, registry.Server)
+
+	if err := applyRegistryCredentials(configMgr.GetConfig()); err != nil {
+		return err
+	}
+
+	fmt.Println("\nTo pull runner images with the new credentials, run:")
+	fmt.Println("  deskrun up")
+	return nil
+}
+
+func runRegistryList(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	registries := configMgr.GetConfig().SortedRegistries()
+	if len(registries) == 0 {
+		fmt.Println("No registries configured")
+		return nil
+	}
+
+	for _, registry := range registries {
+		fmt.Printf("%s\t%s\t%s\n", registry.Name, registry.Server, registry.Username)
+	}
+	return nil
+}
+
+func runRegistryRemove(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := configMgr.RemoveRegistry(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Registry '%s' removed from configuration\n", args[0])
+
+	return applyRegistryCredentials(configMgr.GetConfig())
+}
+
+// applyRegistryCredentials updates the registry secret in every existing
+// cluster; clusters that don't exist yet get it on 'deskrun up'
+func applyRegistryCredentials(cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	for _, clusterName := range targetClusters(cfg, "") {
+		clusterMgr := newClusterManager(cfg, clusterName)
+		exists, err := clusterMgr.Exists(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check cluster: %w", err)
+		}
+		if !exists {
+			continue
+		}
+
+		if err := runner.NewManager(clusterMgr).ApplyRegistryCredentials(ctx, cfg.SortedRegistries(), cfg.ClusterNamespaces(clusterName)); err != nil {
+			return fmt.Errorf("failed to update registry credentials in cluster '%s': %w", clusterName, err)
+		}
+		fmt.Printf("✓ Registry credentials updated in cluster '%s'\n", clusterName)
+	}
+	return nil
+}
+
+// normalizeRegistryServer strips the scheme and trailing slash of a --server
+// value, so it matches the registry host of image references. Docker Hub
+// hosts are mapped to the key the docker CLI expects.
+func normalizeRegistryServer(server string) (string, error) {
+	if server == dockerHubServer {
+		return server, nil
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host = strings.TrimSuffix(host, "/")
+	if host == "" || strings.ContainsAny(host, "/ ") {
+		return "", fmt.Errorf("invalid --server '%s', expected a registry host like ghcr.io or registry.example.com:5000", server)
+	}
+
+	switch host {
+	case "docker.io", "index.docker.io", "registry-1.docker.io":
+		return dockerHubServer, nil
+	}
+	return host, nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Registry Command", func() {
+	DescribeTable("normalizing registry servers",
+		func(server, expected, expectedErrorMsg string) {
+			host, err := normalizeRegistryServer(server)
+
+			if expectedErrorMsg == "" {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(host).To(Equal(expected))
+			} else {
+				Expect(err).To(MatchError(ContainSubstring(expectedErrorMsg)))
+			}
+		},
+		Entry("plain host", "ghcr.io", "ghcr.io", ""),
+		Entry("host with port and scheme", "https://registry.corp:5000/", "registry.corp:5000", ""),
+		Entry("docker hub", "docker.io", dockerHubServer, ""),
+		Entry("docker hub key", dockerHubServer, dockerHubServer, ""),
+		Entry("invalid: repository path", "ghcr.io/owner/image", "", "invalid --server"),
+		Entry("invalid: empty", "https://", "", "invalid --server"),
+	)
+
+	It("sets the registry secret on deployed installations when registries are configured", func() {
+		cfg := &config.Config{ClusterName: "deskrun"}
+		installation := &types.RunnerInstallation{Name: "my-runner"}
+
+		Expect(installationToDeploy(cfg, installation)).To(BeIdenticalTo(installation))
+
+		cfg.Registries = map[string]*types.RegistryCredential{
+			"ghcr": {Name: "ghcr", Server: "ghcr.io", Username: "bot", Password: "ghp_xxx"},
+		}
+		deployed := installationToDeploy(cfg, installation)
+		Expect(deployed.ImagePullSecret).To(Equal(runner.RegistrySecretName))
+		Expect(installation.ImagePullSecret).To(BeEmpty(), "the configured installation should not be modified")
+	})
+})
@@ -19,8 +19,9 @@ Example:
   deskrun remove my-runner
   deskrun up
 `,
-	Args: cobra.ExactArgs(1),
-	RunE: runRemove,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runRemove,
 }
 
 func init() {
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	renderOutputDir  string
+	renderController bool
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render [name]",
+	Short: "Print the generated manifests without deploying",
+	Long: `Render the Kubernetes manifests of configured runner installations without
+deploying them.
+
+This runs the same template processing as 'deskrun up' and prints the resulting
+multi-document YAML, so you can review exactly what kapp would apply. No cluster
+is needed.
+
+Without a name, all configured installations are rendered. With --output-dir,
+one <app>.yaml file is written per kapp app instead of printing to stdout.
+
+Examples:
+  deskrun render my-runner                 # Print manifests of one installation
+  deskrun render                           # Print manifests of all installations
+  deskrun render --controller              # Include the ARC controller manifest
+  deskrun render my-runner -o ./manifests  # Write manifests to a directory
+`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runRender,
+}
+
+func init() {
+	renderCmd.Flags().StringVarP(&renderOutputDir, "output-dir", "o", "", "Write one manifest file per app to this directory instead of stdout")
+	renderCmd.Flags().BoolVar(&renderController, "controller", false, "Include the ARC controller manifest")
+	rootCmd.AddCommand(renderCmd)
+}
+
+func runRender(cmd *cobra.Command, args []string) error {
+	// Load config
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := configMgr.GetConfig()
+
+	var installations []*types.RunnerInstallation
+	if len(args) > 0 {
+		installation, err := configMgr.GetInstallation(args[0])
+		if err != nil {
+			return fmt.Errorf("installation not found: %w", err)
+		}
+		installations = append(installations, installation)
+	} else {
+		installations = sortedInstallations(cfg.Installations)
+		if len(installations) == 0 && !renderController {
+			return fmt.Errorf("no runner installations configured")
+		}
+	}
+
+	var manifests []runner.RenderedManifest
+	if renderController {
+		controllerYAML, err := runner.RenderController(cfg.ControllerVersion, cfg.ClusterProxy(cfg.DefaultCluster()), true)
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, runner.RenderedManifest{Name: "arc-controller", YAML: controllerYAML})
+	}
+
+	for _, installation := range installations {
+		rendered, err := runner.Render(installationToDeploy(cfg, installation))
+		if err != nil {
+			return fmt.Errorf("failed to render '%s': %w", installation.Name, err)
+		}
+		manifests = append(manifests, rendered...)
+	}
+
+	if renderOutputDir != "" {
+		return writeManifestFiles(renderOutputDir, manifests)
+	}
+
+	return writeManifests(os.Stdout, manifests)
+}
+
+// sortedInstallations returns the installations ordered by name for stable output
+func sortedInstallations(installations map[string]*types.RunnerInstallation) []*types.RunnerInstallation {
+	names := make([]string, 0, len(installations))
+	for name := range installations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sorted := make([]*types.RunnerInstallation, 0, len(names))
+	for _, name := range names {
+		sorted = append(sorted, installations[name])
+	}
+	return sorted
+}
+
+// writeManifests writes all manifests as a single multi-document YAML stream,
+// with a comment naming the kapp app each group of documents belongs to
+func writeManifests(w io.Writer, manifests []runner.RenderedManifest) error {
+	for i, manifest := range manifests {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w, "---"); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "# kapp app: %s\n", manifest.Name); err != nil {
+			return err
+		}
+		if _, err := w.Write(ensureTrailingNewline(manifest.YAML)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeManifestFiles writes each manifest to <dir>/<app>.yaml
+func writeManifestFiles(dir string, manifests []runner.RenderedManifest) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, manifest := range manifests {
+		path := filepath.Join(dir, manifest.Name+".yaml")
+		if err := os.WriteFile(path, ensureTrailingNewline(manifest.YAML), 0644); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+	return nil
+}
+
+func ensureTrailingNewline(data []byte) []byte {
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		return append(data, '\n')
+	}
+	return data
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Render Command", func() {
+	manifests := []runner.RenderedManifest{
+		{Name: "my-runner-1", YAML: []byte("kind: Secret\n---\nkind: AutoscalingRunnerSet")},
+		{Name: "my-runner-2", YAML: []byte("kind: Secret\n")},
+	}
+
+	It("writes all manifests as one multi-document stream", func() {
+		var buf bytes.Buffer
+		Expect(writeManifests(&buf, manifests)).To(Succeed())
+		Expect(buf.String()).To(Equal(
+			"# kapp app: my-runner-1\nkind: Secret\n---\nkind: AutoscalingRunnerSet\n" +
+				"---\n# kapp app: my-runner-2\nkind: Secret\n"))
+	})
+
+	It("writes one file per app to the output directory", func() {
+		dir := GinkgoT().TempDir()
+		Expect(writeManifestFiles(dir, manifests)).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(dir, "my-runner-2.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("kind: Secret\n"))
+		Expect(filepath.Join(dir, "my-runner-1.yaml")).To(BeAnExistingFile())
+	})
+
+	It("orders installations by name", func() {
+		sorted := sortedInstallations(map[string]*types.RunnerInstallation{
+			"b-runner": {Name: "b-runner"},
+			"a-runner": {Name: "a-runner"},
+		})
+		Expect(sorted).To(HaveLen(2))
+		Expect(sorted[0].Name).To(Equal("a-runner"))
+		Expect(sorted[1].Name).To(Equal("b-runner"))
+	})
+})
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+// maxControllerErrors limits how many controller log lines are shown as root cause
+const maxControllerErrors = 3
+
+var (
+	repairCluster   string
+	repairOlderThan time.Duration
+	repairForce     bool
+)
+
+var repairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Detect and repair resources stuck on finalizers",
+	Long: `Detect ARC resources (EphemeralRunners, listeners, runner sets) that are
+stuck in Terminating because their finalizers are never removed.
+
+For every stuck resource the likely root cause is reported: an ARC controller
+that is not running, RBAC permissions the controller lacks (e.g. list on
+secrets), and forbidden errors from the controller logs.
+
+With --force, the finalizers of the stuck resources are removed so Kubernetes
+can delete them. Fix the root cause first, or new runners will get stuck
+again. Runners removed this way may remain registered as offline runners
+on GitHub until GitHub cleans them up.
+
+Examples:
+  deskrun repair                        # Report stuck resources and their cause
+  deskrun repair --older-than 1m        # Include recently deleted resources
+  deskrun repair --force                # Remove finalizers of stuck resources
+`,
+	RunE: runRepair,
+}
+
+func init() {
+	repairCmd.Flags().StringVar(&repairCluster, "cluster", "", "Only repair this cluster (defaults to all known clusters)")
+	repairCmd.Flags().DurationVar(&repairOlderThan, "older-than", stuckFinalizerAge, "Only consider resources terminating for longer than this")
+	repairCmd.Flags().BoolVar(&repairForce, "force", false, "Remove the finalizers of stuck resources")
+	rootCmd.AddCommand(repairCmd)
+}
+
+func runRepair(cmd *cobra.Command, args []string) error {
+	// Load config
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	for i, clusterName := range targetClusters(configMgr.GetConfig(), repairCluster) {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := repairClusterResources(ctx, configMgr.GetConfig(), clusterName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// repairClusterResources reports and optionally removes stuck finalizers in one cluster
+func repairClusterResources(ctx context.Context, cfg *config.Config, clusterName string) error {
+	clusterMgr := newClusterManager(cfg, clusterName)
+
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		fmt.Printf("Cluster '%s' does not exist\n", clusterName)
+		return nil
+	}
+
+	runnerMgr := runner.NewManager(clusterMgr)
+
+	stuck, err := runnerMgr.StuckResources(ctx, repairOlderThan)
+	if err != nil {
+		return fmt.Errorf("failed to find stuck resources: %w", err)
+	}
+
+	if len(stuck) == 0 {
+		fmt.Printf("Cluster '%s': no resources stuck on finalizers\n", clusterName)
+		return nil
+	}
+
+	fmt.Printf("Cluster '%s': %d resource(s) stuck on finalizers\n", clusterName, len(stuck))
+	for _, resource := range stuck {
+		fmt.Printf("  %s\n", formatStuckResource(resource))
+	}
+
+	causes, err := diagnoseStuckResources(ctx, runnerMgr, stuck)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nRoot cause:")
+	for _, cause := range causes {
+		fmt.Printf("  - %s\n", cause)
+	}
+
+	if !repairForce {
+		fmt.Println("\nRun 'deskrun repair --force' to remove the finalizers once the root cause is fixed.")
+		return nil
+	}
+
+	fmt.Println()
+	for _, resource := range stuck {
+		if err := runnerMgr.RemoveFinalizers(ctx, resource); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Removed finalizers from %s %s/%s\n", resource.Kind, resource.Namespace, resource.Name)
+	}
+
+	return nil
+}
+
+// diagnoseStuckResources determines why the ARC controller does not remove the finalizers
+func diagnoseStuckResources(ctx context.Context, runnerMgr *runner.Manager, stuck []runner.StuckResource) ([]string, error) {
+	running, err := runnerMgr.ControllerRunning(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make(map[string][]runner.Permission)
+	for _, resource := range stuck {
+		if _, seen := missing[resource.Namespace]; seen {
+			continue
+		}
+		permissions, err := runnerMgr.MissingControllerPermissions(ctx, resource.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		missing[resource.Namespace] = permissions
+	}
+
+	var controllerErrors []string
+	if running {
+		controllerErrors, err = runnerMgr.ControllerErrors(ctx, "forbidden")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rootCauses(running, missing, controllerErrors), nil
+}
+
+// rootCauses describes why finalizers are not removed, based on the controller
+// state, its missing permissions per namespace and forbidden errors in its logs
+func rootCauses(controllerRunning bool, missing map[string][]runner.Permission, controllerErrors []string) []string {
+	var causes []string
+	if !controllerRunning {
+		causes = append(causes, "The ARC controller is not running, so nothing removes the finalizers; check it with 'deskrun logs'")
+	}
+
+	namespaces := make([]string, 0, len(missing))
+	for namespace := range missing {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	for _, namespace := range namespaces {
+		permissions := missing[namespace]
+		if len(permissions) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(permissions))
+		for _, permission := range permissions {
+			names = append(names, permission.String())
+		}
+		causes = append(causes, fmt.Sprintf("The ARC controller is not allowed to %s in namespace %s; restore its RBAC rules with 'deskrun upgrade-controller --force'",
+			strings.Join(names, ", "), namespace))
+	}
+
+	if len(controllerErrors) > maxControllerErrors {
+		controllerErrors = controllerErrors[len(controllerErrors)-maxControllerErrors:]
+	}
+	for _, line := range controllerErrors {
+		causes = append(causes, "Controller error: "+line)
+	}
+
+	if len(causes) == 0 {
+		causes = append(causes, "No root cause found; check the ARC controller logs with 'deskrun logs'")
+	}
+
+	return causes
+}
+
+// formatStuckResource describes a stuck resource on a single line
+func formatStuckResource(resource runner.StuckResource) string {
+	return fmt.Sprintf("%s %s/%s (terminating for %s, finalizers: %s)",
+		resource.Kind, resource.Namespace, resource.Name,
+		resource.DeletedFor.Round(time.Minute), strings.Join(resource.Finalizers, ", "))
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rkoster/deskrun/internal/runner"
+)
+
+var _ = Describe("Repair Command", func() {
+	Describe("rootCauses", func() {
+		It("should report a controller that is not running", func() {
+			causes := rootCauses(false, nil, nil)
+			Expect(causes).To(HaveLen(1))
+			Expect(causes[0]).To(ContainSubstring("ARC controller is not running"))
+		})
+
+		It("should report missing RBAC permissions per namespace", func() {
+			causes := rootCauses(true, map[string][]runner.Permission{
+				"arc-systems": {{Resource: "secrets", Verb: "list"}},
+				"other":       nil,
+			}, nil)
+			Expect(causes).To(Equal([]string{
+				"The ARC controller is not allowed to list secrets in namespace arc-systems; restore its RBAC rules with 'deskrun upgrade-controller --force'",
+			}))
+		})
+
+		It("should include the most recent forbidden controller errors", func() {
+			causes := rootCauses(true, nil, []string{"error 1", "error 2", "error 3", "error 4"})
+			Expect(causes).To(Equal([]string{
+				"Controller error: error 2",
+				"Controller error: error 3",
+				"Controller error: error 4",
+			}))
+		})
+
+		It("should say when no root cause was found", func() {
+			causes := rootCauses(true, map[string][]runner.Permission{"arc-systems": nil}, nil)
+			Expect(causes).To(HaveLen(1))
+			Expect(causes[0]).To(ContainSubstring("No root cause found"))
+		})
+	})
+
+	Describe("formatStuckResource", func() {
+		It("should describe the resource, its age and finalizers", func() {
+			resource := runner.StuckResource{
+				Kind:       "EphemeralRunner",
+				Namespace:  "arc-systems",
+				Name:       "my-runner-abc12-runner-x7k2p",
+				Finalizers: []string{"ephemeralrunner.actions.github.com/finalizer"},
+				DeletedFor: 2*time.Hour + 10*time.Second,
+			}
+			Expect(formatStuckResource(resource)).To(Equal(
+				"EphemeralRunner arc-systems/my-runner-abc12-runner-x7k2p (terminating for 2h0m0s, finalizers: ephemeralrunner.actions.github.com/finalizer)"))
+		})
+	})
+})
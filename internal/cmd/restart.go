@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/spf13/cobra"
+)
+
+var restartRunners bool
+
+var restartCmd = &cobra.Command{
+	Use:   "restart <name>",
+	Short: "Restart the listener of a runner installation",
+	Long: `Restart the listener pods of a runner installation, e.g. after its token was
+rotated or a network outage left the listener unable to reach GitHub.
+
+The ARC controller recreates the deleted listener pods, which register with
+GitHub again; the command waits until they are running. With --runners, the
+runners that do not run a job are replaced as well. Running jobs are never
+interrupted, and nothing is uninstalled.
+
+Examples:
+  deskrun restart my-runner
+  deskrun restart my-runner --runners   # Also replace idle runners
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runRestart,
+}
+
+func init() {
+	restartCmd.Flags().BoolVar(&restartRunners, "runners", false, "Also restart the runners that do not run a job")
+	rootCmd.AddCommand(restartCmd)
+}
+
+func runRestart(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	installation, err := configMgr.GetInstallation(name)
+	if err != nil {
+		return fmt.Errorf("installation not found: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), runner.RegistrationTimeout+time.Minute)
+	defer cancel()
+
+	clusterName := configMgr.GetConfig().ClusterFor(installation)
+	runnerMgr, err := clusterRunnerManager(ctx, configMgr.GetConfig(), clusterName)
+	if err != nil {
+		return err
+	}
+	if runnerMgr == nil {
+		return fmt.Errorf("cluster '%s' does not exist, run 'deskrun up' to create it", clusterName)
+	}
+
+	deployed, err := runnerMgr.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list runners: %w", err)
+	}
+
+	restarted := false
+	for _, scaleSet := range instanceNames(installation.Name, installation.Instances) {
+		if !slices.Contains(deployed, scaleSet) {
+			continue
+		}
+		restarted = true
+
+		if err := runnerMgr.RestartListener(ctx, scaleSet); err != nil {
+			return err
+		}
+		if restartRunners {
+			count, err := runnerMgr.RestartIdleRunners(ctx, scaleSet)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Restarted %d idle runner(s) of '%s'\n", count, scaleSet)
+		}
+		if err := runnerMgr.WaitForRegistration(ctx, installation.GetNamespace(), scaleSet, runner.RegistrationTimeout); err != nil {
+			return err
+		}
+	}
+
+	if !restarted {
+		return fmt.Errorf("runner '%s' is not deployed, run 'deskrun up' to deploy it", name)
+	}
+	fmt.Printf("✓ Runner '%s' restarted\n", name)
+	return nil
+}
@@ -1,7 +1,20 @@
 package cmd
 
 import (
+	"log/slog"
+	"os"
+
+	"github.com/rkoster/deskrun/internal/logging"
+	"github.com/rkoster/deskrun/internal/progress"
+	"github.com/rkoster/deskrun/internal/runner"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	logLevel  string
+	logFormat string
+	noColor   bool
 )
 
 var rootCmd = &cobra.Command{
@@ -18,5 +31,30 @@ func Execute() error {
 }
 
 func init() {
-	// Global flags will be added here
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Minimum level of progress messages: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "Format of progress messages: text or json")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also disabled by the NO_COLOR environment variable)")
+	rootCmd.PersistentFlags().StringVar(&runner.RenderCacheDir, "render-cache-dir", "", "Directory to cache rendered manifests in across runs (default: cache in memory only)")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := setupLogging(); err != nil {
+			return err
+		}
+		return forwardToClusterHost(cmd)
+	}
+}
+
+// setupLogging makes the logger of --log-level and --log-format the default
+// logger. Progress messages go to stderr, so stdout only holds the output of
+// a command, like a table or manifests. Long phases show a spinner when
+// stderr is a terminal and messages are logged as text.
+func setupLogging() error {
+	terminal := term.IsTerminal(int(os.Stderr.Fd()))
+	progress.Configure(os.Stderr, terminal && logFormat == logging.FormatText, terminal && !noColor && os.Getenv("NO_COLOR") == "")
+
+	logger, err := logging.New(progress.Writer(), logLevel, logFormat)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
 }
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// runPollInterval is how often the workflow run and its jobs are polled
+	runPollInterval = 5 * time.Second
+	// runDispatchSkew allows for clock differences between the host and GitHub
+	// when looking up the run created by a dispatch
+	runDispatchSkew = time.Minute
+)
+
+var (
+	runRunner  string
+	runRef     string
+	runInputs  []string
+	runTimeout time.Duration
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run <workflow>",
+	Short: "Dispatch a workflow and follow it on the local runners",
+	Long: `Trigger a workflow_dispatch of a workflow in the repository of a runner
+installation, wait for a job of the run to target the installation's runners
+and stream the logs of the runner pods until the run completes. This is a
+one-command smoke test of the local runner setup.
+
+The workflow is given by its file name or path, e.g. ci.yml or
+.github/workflows/ci.yml, and must have a workflow_dispatch trigger and a job
+with runs-on set to the installation name. The command fails unless the run
+concludes successfully.
+
+Workflows can only be dispatched for repository-level installations. Without
+--runner, the only installation is used.
+
+Examples:
+  deskrun run smoke-test.yml
+  deskrun run .github/workflows/ci.yml --runner my-runner --ref my-branch
+  deskrun run deploy.yml --input environment=staging
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRun,
+}
+
+func init() {
+	runCmd.Flags().StringVar(&runRunner, "runner", "", "Installation whose repository and runners are used (required with multiple installations)")
+	runCmd.Flags().StringVar(&runRef, "ref", "", "Branch or tag to run the workflow on (default branch if empty)")
+	runCmd.Flags().StringArrayVar(&runInputs, "input", []string{}, "Workflow input. Format: key=value (can be specified multiple times)")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 30*time.Minute, "How long to wait for the run to complete")
+
+	rootCmd.AddCommand(runCmd)
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	installation, err := runInstallation(configMgr.GetConfig(), runRunner)
+	if err != nil {
+		return err
+	}
+	inputs, err := parseWorkflowInputs(runInputs)
+	if err != nil {
+		return err
+	}
+
+	target, err := github.ParseConfigURL(installation.Repository)
+	if err != nil {
+		return err
+	}
+	if target.IsOrganization() {
+		return fmt.Errorf("workflows can only be dispatched for repository installations (%s targets organization %s)", installation.Name, target.Owner)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+
+	clusterName := configMgr.GetConfig().ClusterFor(installation)
+	runnerMgr, err := clusterRunnerManager(ctx, configMgr.GetConfig(), clusterName)
+	if err != nil {
+		return err
+	}
+	if runnerMgr == nil {
+		return fmt.Errorf("cluster '%s' does not exist, run 'deskrun up' to deploy %s", clusterName, installation.Name)
+	}
+
+	client, err := githubClient(installation, target.APIBaseURL)
+	if err != nil {
+		return err
+	}
+	ref := runRef
+	if ref == "" {
+		if ref, err = client.DefaultBranch(ctx, target.Owner, target.Repo); err != nil {
+			return err
+		}
+	}
+
+	workflow := filepath.Base(args[0])
+	dispatchedAt := time.Now()
+	if err := client.DispatchWorkflow(ctx, target.Owner, target.Repo, workflow, ref, inputs); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Dispatched %s on %s of %s/%s\n", workflow, ref, target.Owner, target.Repo)
+
+	run, err := waitForDispatchedRun(ctx, client, target, workflow, ref, dispatchedAt)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Run: %s\n", run.HTMLURL)
+
+	scaleSets := instanceNames(installation.Name, installation.Instances)
+	job, err := waitForTargetingJob(ctx, client, target, run.ID, scaleSets)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Job '%s' targets %s, streaming runner logs...\n\n", job.Name, installation.Name)
+
+	logsCtx, stopLogs := context.WithCancel(ctx)
+	logsDone := make(chan error, 1)
+	go func() {
+		logsDone <- runnerMgr.StreamLogs(logsCtx, scaleSets, runner.LogOptions{
+			Follow:    true,
+			Since:     time.Since(dispatchedAt),
+			Container: "runner",
+		}, os.Stdout)
+	}()
+
+	run, err = waitForRunCompletion(ctx, client, target, run.ID)
+	stopLogs()
+	if logsErr := <-logsDone; logsErr != nil {
+		fmt.Printf("Warning: failed to stream logs: %v\n", logsErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if run.Conclusion != "success" {
+		return fmt.Errorf("run of %s concluded with %s: %s", workflow, run.Conclusion, run.HTMLURL)
+	}
+	fmt.Printf("✓ Run of %s succeeded\n", workflow)
+	return nil
+}
+
+// runInstallation returns the installation a workflow is run for: the named
+// one, or the only configured installation
+func runInstallation(cfg *config.Config, name string) (*types.RunnerInstallation, error) {
+	if name != "" {
+		installation, ok := cfg.Installations[name]
+		if !ok {
+			return nil, fmt.Errorf("installation %s does not exist", name)
+		}
+		return installation, nil
+	}
+
+	switch len(cfg.Installations) {
+	case 0:
+		return nil, fmt.Errorf("no runner installations configured, add one with 'deskrun add'")
+	case 1:
+		for _, installation := range cfg.Installations {
+			return installation, nil
+		}
+	}
+	return nil, fmt.Errorf("multiple installations configured, select one with --runner")
+}
+
+// parseWorkflowInputs parses key=value workflow inputs
+func parseWorkflowInputs(inputs []string) (map[string]string, error) {
+	parsed := make(map[string]string, len(inputs))
+	for _, input := range inputs {
+		key, value, ok := strings.Cut(input, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --input '%s': expected key=value", input)
+		}
+		parsed[key] = value
+	}
+	return parsed, nil
+}
+
+// waitForDispatchedRun polls until the run created by a dispatch shows up.
+// GitHub does not return the run of a dispatch, so the newest dispatched run
+// of the workflow created since the dispatch is used.
+func waitForDispatchedRun(ctx context.Context, client *github.Client, target *github.ConfigURL, workflow, ref string, dispatchedAt time.Time) (*github.WorkflowRun, error) {
+	for {
+		runs, err := client.DispatchedRuns(ctx, target.Owner, target.Repo, workflow, ref, dispatchedAt.Add(-runDispatchSkew))
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) > 0 {
+			return &runs[0], nil
+		}
+
+		if err := sleepContext(ctx, runPollInterval); err != nil {
+			return nil, fmt.Errorf("run of %s did not start: %w", workflow, err)
+		}
+	}
+}
+
+// waitForTargetingJob polls the jobs of a run until one targets any of the
+// given scale sets
+func waitForTargetingJob(ctx context.Context, client *github.Client, target *github.ConfigURL, runID int64, scaleSets []string) (*github.WorkflowJob, error) {
+	for {
+		jobs, err := client.RunJobs(ctx, target.Owner, target.Repo, runID)
+		if err != nil {
+			return nil, err
+		}
+		if job := targetingJob(jobs, scaleSets); job != nil {
+			return job, nil
+		}
+
+		run, err := client.GetWorkflowRun(ctx, target.Owner, target.Repo, runID)
+		if err != nil {
+			return nil, err
+		}
+		if run.Status == github.JobStatusCompleted {
+			return nil, fmt.Errorf("run completed with %s without a job targeting %s, set 'runs-on: %s' in the workflow: %s",
+				run.Conclusion, strings.Join(scaleSets, ", "), scaleSets[0], run.HTMLURL)
+		}
+
+		if err := sleepContext(ctx, runPollInterval); err != nil {
+			return nil, fmt.Errorf("no job targeting %s: %w", strings.Join(scaleSets, ", "), err)
+		}
+	}
+}
+
+// targetingJob returns the first job that targets any of the scale sets
+func targetingJob(jobs []github.WorkflowJob, scaleSets []string) *github.WorkflowJob {
+	for i, job := range jobs {
+		for _, scaleSet := range scaleSets {
+			if job.Targets(scaleSet) {
+				return &jobs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// waitForRunCompletion polls a workflow run until it is completed
+func waitForRunCompletion(ctx context.Context, client *github.Client, target *github.ConfigURL, runID int64) (*github.WorkflowRun, error) {
+	for {
+		run, err := client.GetWorkflowRun(ctx, target.Owner, target.Repo, runID)
+		if err != nil {
+			return nil, err
+		}
+		if run.Status == github.JobStatusCompleted {
+			return run, nil
+		}
+
+		if err := sleepContext(ctx, runPollInterval); err != nil {
+			return nil, fmt.Errorf("run did not complete: %w", err)
+		}
+	}
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
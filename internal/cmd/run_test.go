@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Run Command", func() {
+	Describe("runInstallation", func() {
+		It("uses the only installation without --runner", func() {
+			cfg := &config.Config{Installations: map[string]*types.RunnerInstallation{"my-runner": {Name: "my-runner"}}}
+			installation, err := runInstallation(cfg, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installation.Name).To(Equal("my-runner"))
+		})
+
+		It("requires --runner with multiple installations", func() {
+			cfg := &config.Config{Installations: map[string]*types.RunnerInstallation{"a": {Name: "a"}, "b": {Name: "b"}}}
+			_, err := runInstallation(cfg, "")
+			Expect(err).To(MatchError("multiple installations configured, select one with --runner"))
+
+			installation, err := runInstallation(cfg, "b")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(installation.Name).To(Equal("b"))
+		})
+
+		It("rejects unknown installations", func() {
+			_, err := runInstallation(&config.Config{}, "missing")
+			Expect(err).To(MatchError("installation missing does not exist"))
+		})
+	})
+
+	Describe("parseWorkflowInputs", func() {
+		It("parses key=value inputs", func() {
+			inputs, err := parseWorkflowInputs([]string{"environment=staging", "flags=a=b"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inputs).To(Equal(map[string]string{"environment": "staging", "flags": "a=b"}))
+		})
+
+		It("rejects inputs without a key", func() {
+			_, err := parseWorkflowInputs([]string{"=value"})
+			Expect(err).To(MatchError("invalid --input '=value': expected key=value"))
+		})
+	})
+
+	Describe("targetingJob", func() {
+		jobs := []github.WorkflowJob{
+			{ID: 1, Labels: []string{"ubuntu-latest"}},
+			{ID: 2, Labels: []string{"my-runner-2"}},
+		}
+
+		It("returns the first job targeting any of the scale sets", func() {
+			job := targetingJob(jobs, []string{"my-runner-1", "my-runner-2"})
+			Expect(job).NotTo(BeNil())
+			Expect(job.ID).To(Equal(int64(2)))
+		})
+
+		It("returns nil when no job targets the scale sets", func() {
+			Expect(targetingJob(jobs, []string{"other"})).To(BeNil())
+		})
+	})
+})
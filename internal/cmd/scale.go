@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	scaleMinRunners int
+	scaleMaxRunners int
+)
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale <name>",
+	Short: "Change the runner limits of a deployed installation in place",
+	Long: `Change the minimum and maximum runners of a deployed runner installation
+without redeploying it, e.g. to bump capacity before a big CI run.
+
+The AutoscalingRunnerSet is patched on the cluster, so the listener picks up
+the new limits right away, and the limits are stored in the configuration so
+the next 'deskrun up' keeps them. Only the limits that are given are changed;
+lowering --max below the current minimum lowers the minimum as well.
+
+Installations with multiple instances run a single runner per instance; change
+their number of instances with 'deskrun edit --instances' instead.
+
+Examples:
+  # Allow up to 10 concurrent runners
+  deskrun scale my-runner --max 10
+
+  # Keep two runners warm and allow up to eight
+  deskrun scale my-runner --min 2 --max 8
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runScale,
+}
+
+func init() {
+	scaleCmd.Flags().IntVar(&scaleMinRunners, "min", 0, "Minimum number of runners")
+	scaleCmd.Flags().IntVar(&scaleMaxRunners, "max", 0, "Maximum number of runners")
+	scaleCmd.MarkFlagsOneRequired("min", "max")
+
+	rootCmd.AddCommand(scaleCmd)
+}
+
+func runScale(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	installation, err := configMgr.GetInstallation(args[0])
+	if err != nil {
+		return fmt.Errorf("installation not found: %w", err)
+	}
+
+	minRunners, maxRunners, err := scaledLimits(cmd.Flags(), installation)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	clusterName := configMgr.GetConfig().ClusterFor(installation)
+	runnerMgr, err := clusterRunnerManager(ctx, configMgr.GetConfig(), clusterName)
+	if err != nil {
+		return err
+	}
+	if runnerMgr == nil {
+		return fmt.Errorf("cluster '%s' does not exist, run 'deskrun up' to deploy %s", clusterName, installation.Name)
+	}
+
+	if err := setRunnerLimits(ctx, runnerMgr, configMgr, installation, minRunners, maxRunners); err != nil {
+		return err
+	}
+
+	if installation.Paused {
+		fmt.Printf("✓ Runner limits of %s set to min %d, max %d; they apply once it is resumed\n", installation.Name, minRunners, maxRunners)
+		return nil
+	}
+	fmt.Printf("✓ Scaled %s to min %d, max %d runners\n", installation.Name, minRunners, maxRunners)
+	return nil
+}
+
+// scaledLimits returns the runner limits of an installation with the --min and
+// --max flags that were set applied
+func scaledLimits(flags *pflag.FlagSet, installation *types.RunnerInstallation) (int, int, error) {
+	if installation.Instances > 1 {
+		return 0, 0, fmt.Errorf("instances of %s run a single runner each; change --instances with 'deskrun edit' instead", installation.Name)
+	}
+
+	minRunners := installation.MinRunners
+	maxRunners := installation.MaxRunners
+	if flags.Changed("max") {
+		maxRunners = scaleMaxRunners
+		if !flags.Changed("min") {
+			minRunners = min(minRunners, maxRunners)
+		}
+	}
+	if flags.Changed("min") {
+		minRunners = scaleMinRunners
+	}
+
+	switch {
+	case minRunners < 0:
+		return 0, 0, fmt.Errorf("--min must be 0 or more")
+	case maxRunners < 1:
+		return 0, 0, fmt.Errorf("--max must be at least 1")
+	case minRunners > maxRunners:
+		return 0, 0, fmt.Errorf("minimum of %d runners is above the maximum of %d", minRunners, maxRunners)
+	}
+	return minRunners, maxRunners, nil
+}
+
+// setRunnerLimits scales the deployed scale set of a single-instance
+// installation in place and stores the new limits in the configuration. The
+// scale set of a paused installation keeps zero runners until it is resumed.
+func setRunnerLimits(ctx context.Context, runnerMgr *runner.Manager, configMgr *config.Manager, installation *types.RunnerInstallation, minRunners, maxRunners int) error {
+	if !installation.Paused {
+		if err := runnerMgr.ScaleScaleSet(ctx, installation.Name, minRunners, maxRunners); err != nil {
+			return err
+		}
+	}
+
+	installation.MinRunners = minRunners
+	installation.MaxRunners = maxRunners
+	if err := configMgr.UpdateInstallation(installation); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
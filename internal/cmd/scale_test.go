@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Scale Command", func() {
+	var installation *types.RunnerInstallation
+
+	BeforeEach(func() {
+		installation = &types.RunnerInstallation{Name: "my-runner", MinRunners: 2, MaxRunners: 5, Instances: 1}
+	})
+
+	parse := func(args ...string) *pflag.FlagSet {
+		flags := pflag.NewFlagSet("scale", pflag.ContinueOnError)
+		flags.IntVar(&scaleMinRunners, "min", 0, "")
+		flags.IntVar(&scaleMaxRunners, "max", 0, "")
+		Expect(flags.Parse(args)).To(Succeed())
+		return flags
+	}
+
+	Describe("scaledLimits", func() {
+		It("only changes the limits that were given", func() {
+			minRunners, maxRunners, err := scaledLimits(parse("--max", "10"), installation)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(minRunners).To(Equal(2))
+			Expect(maxRunners).To(Equal(10))
+		})
+
+		It("lowers the minimum with the maximum", func() {
+			minRunners, maxRunners, err := scaledLimits(parse("--max", "1"), installation)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(minRunners).To(Equal(1))
+			Expect(maxRunners).To(Equal(1))
+		})
+
+		It("rejects a minimum above the maximum", func() {
+			_, _, err := scaledLimits(parse("--min", "6"), installation)
+			Expect(err).To(MatchError("minimum of 6 runners is above the maximum of 5"))
+		})
+
+		It("rejects a maximum of zero", func() {
+			_, _, err := scaledLimits(parse("--min", "0", "--max", "0"), installation)
+			Expect(err).To(MatchError("--max must be at least 1"))
+		})
+
+		It("rejects installations with multiple instances", func() {
+			installation.Instances = 3
+			_, _, err := scaledLimits(parse("--max", "10"), installation)
+			Expect(err).To(MatchError(ContainSubstring("change --instances with 'deskrun edit'")))
+		})
+	})
+})
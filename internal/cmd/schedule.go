@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+// weekdays are the day names of schedule windows, indexed by time.Weekday
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseSchedule parses the --schedule flags of add and edit. Each window has
+// the format "DAYS HH:MM-HH:MM MIN:MAX", e.g. "mon-fri 09:00-18:00 2:8".
+func parseSchedule(specs []string) ([]types.ScheduleWindow, error) {
+	var windows []types.ScheduleWindow
+	for _, spec := range specs {
+		fields := strings.Fields(spec)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid --schedule '%s': expected DAYS HH:MM-HH:MM MIN:MAX, e.g. 'mon-fri 09:00-18:00 2:8'", spec)
+		}
+		start, end, ok := strings.Cut(fields[1], "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid --schedule '%s': expected a time range like 09:00-18:00", spec)
+		}
+		minValue, maxValue, ok := strings.Cut(fields[2], ":")
+		minRunners, minErr := strconv.Atoi(minValue)
+		maxRunners, maxErr := strconv.Atoi(maxValue)
+		if !ok || minErr != nil || maxErr != nil {
+			return nil, fmt.Errorf("invalid --schedule '%s': expected runner limits like 2:8", spec)
+		}
+
+		window := types.ScheduleWindow{
+			Days:       strings.ToLower(fields[0]),
+			Start:      start,
+			End:        end,
+			MinRunners: minRunners,
+			MaxRunners: maxRunners,
+		}
+		if err := validateScheduleWindow(window); err != nil {
+			return nil, fmt.Errorf("invalid --schedule '%s': %w", spec, err)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// validateSchedule checks the schedule windows of an installation
+func validateSchedule(installation *types.RunnerInstallation) error {
+	if len(installation.Schedule) == 0 {
+		return nil
+	}
+	if installation.Instances > 1 {
+		return fmt.Errorf("instances of %s run a single runner each, --schedule can't change their limits", installation.Name)
+	}
+	for _, window := range installation.Schedule {
+		if err := validateScheduleWindow(window); err != nil {
+			return fmt.Errorf("invalid schedule window '%s': %w", formatScheduleWindow(window), err)
+		}
+	}
+	return nil
+}
+
+// validateScheduleWindow checks the days, times and runner limits of a window
+func validateScheduleWindow(window types.ScheduleWindow) error {
+	if _, err := parseDays(window.Days); err != nil {
+		return err
+	}
+	for _, clock := range []string{window.Start, window.End} {
+		if _, err := parseClock(clock); err != nil {
+			return err
+		}
+	}
+	switch {
+	case window.MinRunners < 0:
+		return fmt.Errorf("minimum must be 0 or more")
+	case window.MaxRunners < 1:
+		return fmt.Errorf("maximum must be at least 1")
+	case window.MinRunners > window.MaxRunners:
+		return fmt.Errorf("minimum of %d runners is above the maximum of %d", window.MinRunners, window.MaxRunners)
+	}
+	return nil
+}
+
+// parseDays returns the weekdays of a day list like mon-fri, sat,sun or *
+func parseDays(days string) ([7]bool, error) {
+	var result [7]bool
+	for _, part := range strings.Split(days, ",") {
+		if part == "*" {
+			return [7]bool{true, true, true, true, true, true, true}, nil
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+		from := slices.Index(weekdays, first)
+		to := slices.Index(weekdays, last)
+		if from < 0 || to < 0 {
+			return result, fmt.Errorf("unknown days '%s', expected e.g. mon-fri, sat,sun or *", days)
+		}
+		// Ranges may wrap around the end of the week, e.g. fri-mon
+		for day := from; ; day = (day + 1) % 7 {
+			result[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseClock returns the minutes since midnight of a HH:MM time
+func parseClock(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s', expected HH:MM", clock)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// scheduleWindowActive returns true if a window covers the given time. A
+// window spanning midnight belongs to the day it starts on.
+func scheduleWindowActive(window types.ScheduleWindow, now time.Time) bool {
+	days, err := parseDays(window.Days)
+	if err != nil {
+		return false
+	}
+	start, startErr := parseClock(window.Start)
+	end, endErr := parseClock(window.End)
+	if startErr != nil || endErr != nil {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	today := int(now.Weekday())
+	yesterday := (today + 6) % 7
+	if start < end {
+		return days[today] && minute >= start && minute < end
+	}
+	return (days[today] && minute >= start) || (days[yesterday] && minute < end)
+}
+
+// scheduledLimits returns the runner limits of an installation at the given
+// time: those of the first active schedule window, or its configured limits
+func scheduledLimits(installation *types.RunnerInstallation, now time.Time) (int, int) {
+	for _, window := range installation.Schedule {
+		if scheduleWindowActive(window, now) {
+			return window.MinRunners, window.MaxRunners
+		}
+	}
+	return installation.MinRunners, installation.MaxRunners
+}
+
+// formatScheduleWindow formats a window in --schedule notation
+func formatScheduleWindow(window types.ScheduleWindow) string {
+	return fmt.Sprintf("%s %s-%s %d:%d", window.Days, window.Start, window.End, window.MinRunners, window.MaxRunners)
+}
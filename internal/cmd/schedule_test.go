@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Runner Schedules", func() {
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.Local)
+	}
+
+	Describe("parseSchedule", func() {
+		It("parses schedule windows", func() {
+			windows, err := parseSchedule([]string{"Mon-Fri 09:00-18:00 2:8", "* 22:00-06:00 0:1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(windows).To(Equal([]types.ScheduleWindow{
+				{Days: "mon-fri", Start: "09:00", End: "18:00", MinRunners: 2, MaxRunners: 8},
+				{Days: "*", Start: "22:00", End: "06:00", MinRunners: 0, MaxRunners: 1},
+			}))
+			Expect(formatScheduleWindow(windows[0])).To(Equal("mon-fri 09:00-18:00 2:8"))
+		})
+
+		DescribeTable("rejects invalid windows",
+			func(spec string) {
+				_, err := parseSchedule([]string{spec})
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("missing limits", "mon-fri 09:00-18:00"),
+			Entry("unknown day", "monday 09:00-18:00 2:8"),
+			Entry("invalid time", "mon-fri 9-18 2:8"),
+			Entry("hour out of range", "mon-fri 09:00-25:00 2:8"),
+			Entry("invalid limits", "mon-fri 09:00-18:00 two:8"),
+			Entry("minimum above the maximum", "mon-fri 09:00-18:00 8:2"),
+			Entry("zero maximum", "mon-fri 09:00-18:00 0:0"),
+		)
+	})
+
+	It("rejects schedules of installations with multiple instances", func() {
+		installation := &types.RunnerInstallation{
+			Name:      "my-runner",
+			Instances: 3,
+			Schedule:  []types.ScheduleWindow{{Days: "*", Start: "09:00", End: "18:00", MinRunners: 1, MaxRunners: 1}},
+		}
+		Expect(validateSchedule(installation)).To(MatchError(ContainSubstring("single runner")))
+	})
+
+	Describe("scheduledLimits", func() {
+		installation := &types.RunnerInstallation{
+			MinRunners: 0,
+			MaxRunners: 1,
+			Schedule: []types.ScheduleWindow{
+				{Days: "mon-fri", Start: "09:00", End: "18:00", MinRunners: 2, MaxRunners: 8},
+				{Days: "fri", Start: "22:00", End: "02:00", MinRunners: 1, MaxRunners: 4},
+			},
+		}
+
+		DescribeTable("returns the limits of the active window",
+			func(now time.Time, minRunners, maxRunners int) {
+				gotMin, gotMax := scheduledLimits(installation, now)
+				Expect(gotMin).To(Equal(minRunners))
+				Expect(gotMax).To(Equal(maxRunners))
+			},
+			Entry("during work hours", at(1, 9, 0), 2, 8),
+			Entry("at the end of the window", at(1, 18, 0), 0, 1),
+			Entry("overnight", at(2, 3, 0), 0, 1),
+			Entry("on the weekend", at(6, 12, 0), 0, 1),
+			Entry("before midnight of a window spanning it", at(5, 23, 30), 1, 4),
+			Entry("after midnight of a window spanning it", at(6, 1, 30), 1, 4),
+			Entry("after midnight of a day without the window", at(5, 1, 30), 0, 1),
+		)
+	})
+
+	DescribeTable("parseDays",
+		func(days string, expected []time.Weekday) {
+			parsed, err := parseDays(days)
+			Expect(err).NotTo(HaveOccurred())
+			var got []time.Weekday
+			for day, active := range parsed {
+				if active {
+					got = append(got, time.Weekday(day))
+				}
+			}
+			Expect(got).To(Equal(expected))
+		},
+		Entry("a range", "mon-wed", []time.Weekday{time.Monday, time.Tuesday, time.Wednesday}),
+		Entry("a list", "sat,sun", []time.Weekday{time.Sunday, time.Saturday}),
+		Entry("a range wrapping around the week", "fri-mon", []time.Weekday{time.Sunday, time.Monday, time.Friday, time.Saturday}),
+		Entry("every day", "*", []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}),
+	)
+})
@@ -0,0 +1,356 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// selfTestWorkflowPath is where the throwaway self-test workflow is committed
+const selfTestWorkflowPath = ".github/workflows/deskrun-self-test.yml"
+
+var (
+	selfTestRepository string
+	selfTestTimeout    time.Duration
+)
+
+var selfTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Validate a runner installation end-to-end",
+	Long: `Validate a deployed runner installation end-to-end with a throwaway workflow.
+
+A temporary branch with a generated workflow is pushed to the repository of
+the installation. The workflow runs a job on every scale set of the
+installation, which writes and reads a file in every mount and cache path.
+The command waits for the run, checks that the jobs were picked up by the
+runners in the local cluster and that the mounts work, and reports pass/fail
+with timings. The branch is deleted afterwards; the workflow run stays in the
+repository's Actions history.
+
+Organization installations need a repository of the organization to run the
+workflow in, given with --repository. Self-tests need a PAT with the repo and
+workflow scopes, or a GitHub App with contents, workflows and actions access.
+
+Examples:
+  deskrun test my-runner
+  deskrun test org-runner --repository my-org/sandbox
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runSelfTest,
+}
+
+func init() {
+	selfTestCmd.Flags().StringVar(&selfTestRepository, "repository", "", "Repository (owner/repo) to run the workflow in, required for organization installations")
+	selfTestCmd.Flags().DurationVar(&selfTestTimeout, "timeout", 15*time.Minute, "How long to wait for the workflow run to complete")
+
+	rootCmd.AddCommand(selfTestCmd)
+}
+
+// selfTestCheck is a single result of a self-test
+type selfTestCheck struct {
+	Name     string
+	Passed   bool
+	Duration time.Duration
+	Detail   string
+}
+
+func runSelfTest(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	installation, err := configMgr.GetInstallation(args[0])
+	if err != nil {
+		return fmt.Errorf("installation not found: %w", err)
+	}
+	if installation.Paused {
+		return fmt.Errorf("runner '%s' is paused, resume it with 'deskrun resume %s' first", installation.Name, installation.Name)
+	}
+
+	target, err := github.ParseConfigURL(installation.Repository)
+	if err != nil {
+		return err
+	}
+	owner, repo, err := selfTestTarget(target, selfTestRepository)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	clusterName := configMgr.GetConfig().ClusterFor(installation)
+	runnerMgr, err := clusterRunnerManager(ctx, configMgr.GetConfig(), clusterName)
+	if err != nil {
+		return err
+	}
+	if runnerMgr == nil {
+		return fmt.Errorf("cluster '%s' does not exist, run 'deskrun up' to deploy %s", clusterName, installation.Name)
+	}
+	deployed, err := runnerMgr.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list runners: %w", err)
+	}
+	scaleSets := instanceNames(installation.Name, installation.Instances)
+	for _, scaleSet := range scaleSets {
+		if !slices.Contains(deployed, scaleSet) {
+			return fmt.Errorf("runner scale set %s is not deployed, run 'deskrun up' first", scaleSet)
+		}
+	}
+
+	client, err := githubClient(installation, target.APIBaseURL)
+	if err != nil {
+		return err
+	}
+	baseBranch, err := client.DefaultBranch(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+	sha, err := client.BranchSHA(ctx, owner, repo, baseBranch)
+	if err != nil {
+		return err
+	}
+
+	branch := fmt.Sprintf("deskrun-test-%s-%d", installation.Name, time.Now().Unix())
+	fmt.Printf("Testing %s with a workflow on branch %s of %s/%s\n", installation.Name, branch, owner, repo)
+
+	start := time.Now()
+	if err := client.CreateBranch(ctx, owner, repo, branch, sha); err != nil {
+		return err
+	}
+	defer func() {
+		// Clean up even if the test was interrupted
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := client.DeleteBranch(cleanupCtx, owner, repo, branch); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}()
+
+	workflow := selfTestWorkflow(installation, scaleSets)
+	if err := client.CreateFile(ctx, owner, repo, branch, selfTestWorkflowPath, "Add deskrun self-test workflow", []byte(workflow)); err != nil {
+		return err
+	}
+
+	testCtx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	run, err := waitForBranchRun(testCtx, client, owner, repo, branch)
+	if err != nil {
+		return err
+	}
+	checks := []selfTestCheck{{Name: "workflow started", Passed: true, Duration: time.Since(start), Detail: run.HTMLURL}}
+	slog.Info("Waiting for run", "url", run.HTMLURL)
+
+	_, waitErr := waitForRunCompletion(testCtx, client, &github.ConfigURL{Owner: owner, Repo: repo}, run.ID)
+	if waitErr != nil {
+		cancelCtx, cancelCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := client.CancelWorkflowRun(cancelCtx, owner, repo, run.ID); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		cancelCancel()
+		checks = append(checks, selfTestCheck{Name: "workflow completed", Detail: waitErr.Error()})
+	}
+
+	jobsCtx, jobsCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer jobsCancel()
+	jobs, err := client.RunJobs(jobsCtx, owner, repo, run.ID)
+	if err != nil {
+		return err
+	}
+	checks = append(checks, selfTestChecks(jobs, scaleSets)...)
+
+	fmt.Println()
+	if !printSelfTestChecks(os.Stdout, checks, time.Since(start)) {
+		return fmt.Errorf("self-test of %s failed", installation.Name)
+	}
+	return nil
+}
+
+// selfTestTarget returns the repository the self-test workflow runs in: the
+// repository of a repository installation, or the given repository of the
+// organization for organization installations
+func selfTestTarget(target *github.ConfigURL, repository string) (string, string, error) {
+	if !target.IsOrganization() {
+		if repository != "" {
+			return "", "", fmt.Errorf("--repository is only used for organization installations, %s/%s is used", target.Owner, target.Repo)
+		}
+		return target.Owner, target.Repo, nil
+	}
+
+	if repository == "" {
+		return "", "", fmt.Errorf("organization installations need --repository to run the workflow in, e.g. %s/sandbox", target.Owner)
+	}
+	owner, repo, ok := strings.Cut(repository, "/")
+	if !ok || repo == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("invalid --repository '%s': expected owner/repo", repository)
+	}
+	if !strings.EqualFold(owner, target.Owner) {
+		return "", "", fmt.Errorf("--repository must be a repository of organization %s", target.Owner)
+	}
+	return owner, repo, nil
+}
+
+// selfTestMountTargets returns the directories mounted into the runner
+// container. Socket and file mounts are left out, and so is a /nix/store
+// cache in privileged mode, which is only overlaid in job containers.
+func selfTestMountTargets(installation *types.RunnerInstallation) []string {
+	overlaid := func(target string) bool {
+		return target == nixStorePath && installation.ContainerMode == types.ContainerModePrivileged
+	}
+
+	var targets []string
+	for _, cachePath := range installation.CachePaths {
+		if !overlaid(cachePath.Target) {
+			targets = append(targets, cachePath.Target)
+		}
+	}
+	for _, mount := range installation.Mounts {
+		switch mount.Type {
+		case "", types.MountTypeDirectoryOrCreate, types.MountTypeDirectory:
+			if !overlaid(mount.Target) {
+				targets = append(targets, mount.Target)
+			}
+		}
+	}
+	return targets
+}
+
+// selfTestWorkflow returns a workflow with a job on every scale set, which
+// writes and reads a file in every mount of the installation
+func selfTestWorkflow(installation *types.RunnerInstallation, scaleSets []string) string {
+	var b strings.Builder
+	b.WriteString("name: deskrun self-test\non: push\njobs:\n")
+	for i, scaleSet := range scaleSets {
+		fmt.Fprintf(&b, "  test-%d:\n", i+1)
+		fmt.Fprintf(&b, "    name: %s\n", scaleSet)
+		fmt.Fprintf(&b, "    runs-on: %s\n", scaleSet)
+		b.WriteString("    timeout-minutes: 10\n")
+		b.WriteString("    steps:\n")
+		b.WriteString("      - name: Runner\n")
+		b.WriteString("        run: echo \"Running on $RUNNER_NAME ($(uname -srm))\"\n")
+		for _, target := range selfTestMountTargets(installation) {
+			fmt.Fprintf(&b, "      - name: %q\n", "Mount "+target)
+			b.WriteString("        run: |\n")
+			fmt.Fprintf(&b, "          file=%s/.deskrun-self-test-$GITHUB_RUN_ID-$GITHUB_JOB\n", shellQuote(target))
+			b.WriteString("          echo ok > \"$file\"\n")
+			b.WriteString("          test \"$(cat \"$file\")\" = ok\n")
+			b.WriteString("          rm -f \"$file\"\n")
+		}
+	}
+	return b.String()
+}
+
+// shellQuote quotes a string for use as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// waitForBranchRun polls until the run triggered by the push of the self-test
+// workflow to a branch shows up
+func waitForBranchRun(ctx context.Context, client *github.Client, owner, repo, branch string) (*github.WorkflowRun, error) {
+	for {
+		runs, err := client.BranchRuns(ctx, owner, repo, branch, "push")
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) > 0 {
+			return &runs[0], nil
+		}
+
+		if err := sleepContext(ctx, runPollInterval); err != nil {
+			return nil, fmt.Errorf("self-test workflow did not start: %w", err)
+		}
+	}
+}
+
+// selfTestChecks checks that the job of every scale set was picked up by a
+// runner of the scale set and that its steps succeeded. ARC names the
+// runners of a scale set after it, which tells local runners apart from
+// runners with the same label elsewhere.
+func selfTestChecks(jobs []github.WorkflowJob, scaleSets []string) []selfTestCheck {
+	var checks []selfTestCheck
+	for _, scaleSet := range scaleSets {
+		job := targetingJob(jobs, []string{scaleSet})
+		if job == nil {
+			checks = append(checks, selfTestCheck{Name: scaleSet + ": job", Detail: "no job found"})
+			continue
+		}
+
+		pickedUp := selfTestCheck{Name: scaleSet + ": picked up"}
+		switch {
+		case job.RunnerName == "":
+			pickedUp.Detail = "no runner picked up the job"
+		case !strings.HasPrefix(job.RunnerName, scaleSet+"-"):
+			pickedUp.Detail = fmt.Sprintf("by runner %s, which is not a runner of the local scale set", job.RunnerName)
+		default:
+			pickedUp.Passed = true
+			pickedUp.Duration = job.StartedAt.Sub(job.CreatedAt)
+			pickedUp.Detail = "by " + job.RunnerName
+		}
+		checks = append(checks, pickedUp)
+		if !pickedUp.Passed {
+			continue
+		}
+
+		for _, step := range job.Steps {
+			if !strings.HasPrefix(step.Name, "Mount ") {
+				continue
+			}
+			checks = append(checks, selfTestCheck{
+				Name:     fmt.Sprintf("%s: %s", scaleSet, strings.ToLower(step.Name[:1])+step.Name[1:]),
+				Passed:   step.Conclusion == "success",
+				Duration: step.CompletedAt.Sub(step.StartedAt),
+				Detail:   step.Conclusion,
+			})
+		}
+
+		checks = append(checks, selfTestCheck{
+			Name:     scaleSet + ": job",
+			Passed:   job.Conclusion == "success",
+			Duration: job.CompletedAt.Sub(job.StartedAt),
+			Detail:   job.Conclusion,
+		})
+	}
+	return checks
+}
+
+// printSelfTestChecks prints the results of a self-test and returns whether
+// all checks passed
+func printSelfTestChecks(out io.Writer, checks []selfTestCheck, total time.Duration) bool {
+	passed := true
+	for _, check := range checks {
+		mark := "✓"
+		if !check.Passed {
+			mark = "✗"
+			passed = false
+		}
+
+		duration := ""
+		if check.Duration > 0 {
+			duration = check.Duration.Round(time.Second).String()
+		}
+		_, _ = fmt.Fprintf(out, "  %s %-40s %6s  %s\n", mark, check.Name, duration, check.Detail)
+	}
+
+	result := "PASS"
+	if !passed {
+		result = "FAIL"
+	}
+	_, _ = fmt.Fprintf(out, "\n%s (%s)\n", result, total.Round(time.Second))
+	return passed
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/github"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Self-Test Command", func() {
+	Describe("selfTestTarget", func() {
+		It("uses the repository of a repository installation", func() {
+			owner, repo, err := selfTestTarget(&github.ConfigURL{Owner: "owner", Repo: "repo"}, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(owner).To(Equal("owner"))
+			Expect(repo).To(Equal("repo"))
+
+			_, _, err = selfTestTarget(&github.ConfigURL{Owner: "owner", Repo: "repo"}, "owner/other")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("requires a repository of the organization for organization installations", func() {
+			org := &github.ConfigURL{Owner: "my-org"}
+			_, _, err := selfTestTarget(org, "")
+			Expect(err).To(MatchError(ContainSubstring("need --repository")))
+
+			_, _, err = selfTestTarget(org, "other-org/sandbox")
+			Expect(err).To(MatchError("--repository must be a repository of organization my-org"))
+
+			owner, repo, err := selfTestTarget(org, "my-org/sandbox")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(owner).To(Equal("my-org"))
+			Expect(repo).To(Equal("sandbox"))
+		})
+	})
+
+	Describe("selfTestWorkflow", func() {
+		It("writes a job per scale set that checks every directory mount", func() {
+			installation := &types.RunnerInstallation{
+				Name:       "my-runner",
+				CachePaths: []types.CachePath{{Target: "/nix/store"}},
+				Mounts: []types.Mount{
+					{Target: "/var/run/docker.sock", Type: types.MountTypeSocket},
+					{Target: "/data", Type: types.MountTypeDirectory},
+				},
+			}
+
+			workflow := selfTestWorkflow(installation, []string{"my-runner-1", "my-runner-2"})
+			Expect(workflow).To(ContainSubstring("runs-on: my-runner-1\n"))
+			Expect(workflow).To(ContainSubstring("runs-on: my-runner-2\n"))
+			Expect(workflow).To(ContainSubstring(`name: "Mount /nix/store"`))
+			Expect(workflow).To(ContainSubstring(`name: "Mount /data"`))
+			Expect(workflow).NotTo(ContainSubstring("docker.sock"))
+		})
+	})
+
+	Describe("selfTestChecks", func() {
+		created := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+		It("passes jobs run by a runner of the local scale set", func() {
+			jobs := []github.WorkflowJob{{
+				Labels:      []string{"my-runner"},
+				RunnerName:  "my-runner-abcde-runner-xyz",
+				Conclusion:  "success",
+				CreatedAt:   created,
+				StartedAt:   created.Add(20 * time.Second),
+				CompletedAt: created.Add(time.Minute),
+				Steps:       []github.WorkflowStep{{Name: "Runner", Conclusion: "success"}, {Name: "Mount /data", Conclusion: "failure"}},
+			}}
+
+			checks := selfTestChecks(jobs, []string{"my-runner"})
+			Expect(checks).To(HaveLen(3))
+			Expect(checks[0]).To(Equal(selfTestCheck{Name: "my-runner: picked up", Passed: true, Duration: 20 * time.Second, Detail: "by my-runner-abcde-runner-xyz"}))
+			Expect(checks[1].Name).To(Equal("my-runner: mount /data"))
+			Expect(checks[1].Passed).To(BeFalse())
+			Expect(checks[2].Passed).To(BeTrue())
+		})
+
+		It("fails jobs picked up by another runner", func() {
+			jobs := []github.WorkflowJob{{Labels: []string{"my-runner"}, RunnerName: "laptop"}}
+
+			checks := selfTestChecks(jobs, []string{"my-runner", "missing"})
+			Expect(checks).To(HaveLen(2))
+			Expect(checks[0].Passed).To(BeFalse())
+			Expect(checks[0].Detail).To(ContainSubstring("not a runner of the local scale set"))
+			Expect(checks[1]).To(Equal(selfTestCheck{Name: "missing: job", Detail: "no job found"}))
+		})
+	})
+
+	Describe("printSelfTestChecks", func() {
+		It("reports failure when any check failed", func() {
+			var out bytes.Buffer
+			passed := printSelfTestChecks(&out, []selfTestCheck{{Name: "a", Passed: true}, {Name: "b"}}, time.Minute)
+			Expect(passed).To(BeFalse())
+			Expect(out.String()).To(ContainSubstring("FAIL (1m0s)"))
+		})
+	})
+})
@@ -3,10 +3,13 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/rkoster/deskrun/internal/cluster"
 	"github.com/rkoster/deskrun/internal/config"
 	"github.com/rkoster/deskrun/internal/kapp"
 	"github.com/rkoster/deskrun/internal/runner"
@@ -14,35 +17,146 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	statusAllHosts bool
+	statusCluster  string
+	statusJSON     bool
+	statusYAML     bool
+	statusWatch    bool
+	statusInterval time.Duration
+	statusMetrics  bool
+)
+
 var statusCmd = &cobra.Command{
 	Use:   "status [name]",
 	Short: "Show status of runner installations",
 	Long: `Show the status of runner installations in the kind cluster.
 
+Without --cluster, the status of every known cluster is shown. When a runner
+name is given, the cluster the installation is pinned to is used.
+
+Recent warning events of each resource and its pod, such as FailedScheduling,
+image pull back-offs and OOMKilled runner containers, are listed below it, so
+the cause of failing runners is visible without kubectl.
+
+With --all-hosts, status is gathered from the deskrun installation on every
+configured cluster host and rendered as a combined report grouped by host.
+
+With --json or --yaml, the status is printed in a machine-readable format
+including the kapp resources, runner counts and reconcile warnings of every
+runner, for use in scripts and dashboards.
+
+With --watch, the status is refreshed every --interval until interrupted.
+Resources are prefixed with + when they appeared, ~ when their reconcile
+state changed and - when they were removed since the previous refresh, so
+you can follow a job being picked up by a runner.
+
+With --metrics, the metrics the ARC controller and the listeners serve are
+scraped through the Kubernetes API and shown per cluster and runner: assigned
+and running jobs, registered, busy and idle runners, desired replicas and
+controller reconcile errors. No monitoring stack is needed.
+
 Examples:
-  deskrun status           # Show all runners
-  deskrun status my-runner # Show status for specific runner
+  deskrun status                        # Show all runners
+  deskrun status my-runner              # Show status for specific runner
+  deskrun status --cluster deskrun-gpu  # Show runners of one cluster
+  deskrun status --all-hosts            # Show status across all cluster hosts
+  deskrun status --json | jq '.clusters[].runners[].counts'
+  deskrun status my-runner --watch      # Follow a runner picking up jobs
+  deskrun status --metrics              # Include job and runner counts reported by ARC
 `,
-	Args: cobra.MaximumNArgs(1),
-	RunE: runStatus,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeInstallations,
+	RunE:              runStatus,
 }
 
 func init() {
+	statusCmd.Flags().BoolVar(&statusAllHosts, "all-hosts", false, "Gather status from all configured cluster hosts")
+	statusCmd.Flags().StringVar(&statusCluster, "cluster", "", "Only show runners of this cluster")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print status as JSON")
+	statusCmd.Flags().BoolVar(&statusYAML, "yaml", false, "Print status as YAML")
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "Continuously refresh the status and highlight changes")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", 2*time.Second, "Refresh interval for --watch")
+	statusCmd.Flags().BoolVar(&statusMetrics, "metrics", false, "Include the metrics of the ARC controller and listeners")
+	statusCmd.MarkFlagsMutuallyExclusive("all-hosts", "json", "yaml")
+	statusCmd.MarkFlagsMutuallyExclusive("watch", "all-hosts")
+	statusCmd.MarkFlagsMutuallyExclusive("watch", "json")
+	statusCmd.MarkFlagsMutuallyExclusive("watch", "yaml")
 	rootCmd.AddCommand(statusCmd)
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
+	if statusAllHosts {
+		return runStatusAllHosts(args)
+	}
+
 	// Load config
 	configMgr, err := config.NewManager()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Setup cluster manager
-	clusterConfig := &types.ClusterConfig{
-		Name: configMgr.GetConfig().ClusterName,
+	if statusWatch {
+		return runStatusWatch(configMgr, args)
+	}
+
+	report, err := collectStatusReport(configMgr, args)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case statusJSON:
+		return writeStatusJSON(os.Stdout, report)
+	case statusYAML:
+		return writeStatusYAML(os.Stdout, report)
+	default:
+		printStatusReport(os.Stdout, report, nil)
+		return nil
+	}
+}
+
+// collectStatusReport gathers the status of the runner given in args, or of
+// all runners in the targeted clusters
+func collectStatusReport(configMgr *config.Manager, args []string) (statusReport, error) {
+	cfg := configMgr.GetConfig()
+
+	var report statusReport
+	if len(args) > 0 {
+		// Show specific runner on the cluster its installation is pinned to
+		clusterName := statusCluster
+		if clusterName == "" {
+			clusterName = cfg.DefaultCluster()
+			if installation, err := configMgr.GetInstallation(args[0]); err == nil {
+				clusterName = cfg.ClusterFor(installation)
+			}
+		}
+		status, err := collectClusterStatus(cfg, clusterName, []string{args[0]})
+		if err != nil {
+			return report, err
+		}
+		report.Clusters = append(report.Clusters, status)
+		return report, nil
 	}
-	clusterMgr := cluster.NewManager(clusterConfig)
+
+	for _, clusterName := range targetClusters(cfg, statusCluster) {
+		status, err := collectClusterStatus(cfg, clusterName, nil)
+		if err != nil {
+			return report, err
+		}
+		report.Clusters = append(report.Clusters, status)
+	}
+
+	return report, nil
+}
+
+// collectClusterStatus gathers the status of the given runners in a cluster,
+// or of all deployed runners when names is empty
+func collectClusterStatus(cfg *config.Config, clusterName string, names []string) (clusterStatus, error) {
+	status := clusterStatus{Name: clusterName, Runners: []runnerStatus{}}
+
+	// Setup cluster manager
+	clusterMgr := newClusterManager(cfg, clusterName)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -50,63 +164,182 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	// Check if cluster exists
 	exists, err := clusterMgr.Exists(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to check cluster: %w", err)
+		return status, fmt.Errorf("failed to check cluster: %w", err)
 	}
 
+	status.Exists = exists
 	if !exists {
-		fmt.Printf("Cluster '%s' does not exist\n", clusterConfig.Name)
-		return nil
+		return status, nil
 	}
 
-	fmt.Printf("Cluster '%s' is running\n\n", clusterConfig.Name)
-
 	runnerMgr := runner.NewManager(clusterMgr)
 
+	// Runners are recorded as kapp apps in the namespace of their installation
+	appNamespaces, err := runnerMgr.AppNamespaces(ctx)
+	if err != nil {
+		return status, fmt.Errorf("failed to list runners: %w", err)
+	}
+
 	// Determine which runners to show
-	var names []string
-	if len(args) > 0 {
-		// Show specific runner
-		names = []string{args[0]}
-	} else {
+	if len(names) == 0 {
 		// Show all runners
-		var err error
-		names, err = runnerMgr.List(ctx)
+		names = slices.Sorted(maps.Keys(appNamespaces))
+	}
+
+	for _, name := range names {
+		namespace, ok := appNamespaces[name]
+		if !ok {
+			namespace = types.DefaultNamespace
+		}
+
+		// Get JSON output from kapp
+		inspectOutput, err := kapp.NewClient(clusterMgr.GetKubeconfig(), namespace).InspectJSON(name)
 		if err != nil {
-			return fmt.Errorf("failed to list runners: %w", err)
+			status.Runners = append(status.Runners, runnerStatus{Name: name, Error: err.Error()})
+			continue
 		}
+		status.Runners = append(status.Runners, newRunnerStatus(name, inspectOutput))
+	}
+
+	addStatusEvents(ctx, &status, runnerMgr)
+	if statusMetrics {
+		addStatusMetrics(ctx, &status, runnerMgr)
+	}
 
-		if len(names) == 0 {
-			fmt.Println("No runners found in cluster")
-			return nil
+	return status, nil
+}
+
+// addStatusEvents adds the recent warning events of the runners' resources to
+// the status. Failing to list them is reported in the status instead of
+// failing the command.
+func addStatusEvents(ctx context.Context, status *clusterStatus, runnerMgr *runner.Manager) {
+	var namespaces []string
+	for _, runnerStatus := range status.Runners {
+		for _, namespace := range runnerStatus.namespaces() {
+			if !slices.Contains(namespaces, namespace) {
+				namespaces = append(namespaces, namespace)
+			}
 		}
 	}
+	if len(namespaces) == 0 {
+		return
+	}
 
-	// Get kapp client once
-	kappClient := kapp.NewClient(clusterMgr.GetKubeconfig(), "arc-systems")
+	events, err := runnerMgr.ResourceEvents(ctx, namespaces)
+	if err != nil {
+		status.EventsError = err.Error()
+		return
+	}
 
-	// Display status for each runner using the same logic
-	for i, name := range names {
+	for i := range status.Runners {
+		status.Runners[i].addEvents(events)
+	}
+}
+
+// addStatusMetrics adds the ARC metrics of the cluster to its status. Failing
+// to scrape them is reported in the status instead of failing the command.
+func addStatusMetrics(ctx context.Context, status *clusterStatus, runnerMgr *runner.Manager) {
+	metrics, err := runnerMgr.CollectMetrics(ctx)
+	if err != nil {
+		status.MetricsError = err.Error()
+		return
+	}
+
+	status.Controller = &metrics.Controller
+	for i := range status.Runners {
+		status.Runners[i].Metrics = metrics.ScaleSets[status.Runners[i].Name]
+	}
+}
+
+// printStatusReport displays the status report in the human-readable format.
+// When changes is set, every resource is prefixed with its change marker.
+func printStatusReport(w io.Writer, report statusReport, changes *statusChanges) {
+	for i, status := range report.Clusters {
 		if i > 0 {
-			fmt.Println() // Add blank line between runners
+			_, _ = fmt.Fprintln(w)
 		}
 
-		// Add runner header
-		fmt.Printf("Runner: %s\n", name)
+		if !status.Exists {
+			_, _ = fmt.Fprintf(w, "Cluster '%s' does not exist\n", status.Name)
+			continue
+		}
 
-		// Get JSON output from kapp
-		inspectOutput, err := kappClient.InspectJSON(name)
-		if err != nil {
-			fmt.Printf("Error getting status for %s: %v\n", name, err)
+		_, _ = fmt.Fprintf(w, "Cluster '%s' is running\n", status.Name)
+		switch {
+		case status.MetricsError != "":
+			_, _ = fmt.Fprintf(w, "Metrics: %s\n", status.MetricsError)
+		case status.Controller != nil:
+			_, _ = fmt.Fprintf(w, "Controller: %s\n", formatControllerMetrics(status.Controller))
+		}
+		if status.EventsError != "" {
+			_, _ = fmt.Fprintf(w, "Events: %s\n", status.EventsError)
+		}
+		_, _ = fmt.Fprintln(w)
+
+		if len(status.Runners) == 0 {
+			_, _ = fmt.Fprintln(w, "No runners found in cluster")
 			continue
 		}
 
-		// Display resources in custom table format
-		if err := displayResourceTable(inspectOutput); err != nil {
-			fmt.Printf("Error displaying resources for %s: %v\n", name, err)
+		// Display status for each runner using the same logic
+		for j, runnerStatus := range status.Runners {
+			if j > 0 {
+				_, _ = fmt.Fprintln(w) // Add blank line between runners
+			}
+
+			// Add runner header
+			_, _ = fmt.Fprintf(w, "Runner: %s\n", runnerStatus.Name)
+			if runnerStatus.Metrics != nil {
+				_, _ = fmt.Fprintf(w, "Metrics: %s\n", formatScaleSetMetrics(runnerStatus.Metrics))
+			}
+
+			if runnerStatus.Error != "" {
+				_, _ = fmt.Fprintf(w, "Error getting status for %s: %s\n", runnerStatus.Name, runnerStatus.Error)
+				continue
+			}
+
+			var marker func(kind, namespace, name string) string
+			if changes != nil {
+				clusterName, runnerName := status.Name, runnerStatus.Name
+				marker = func(kind, namespace, name string) string {
+					return changes.marker(resourceKey(clusterName, runnerName, kind, namespace, name))
+				}
+			}
+
+			// Display resources in custom table format
+			if err := displayResourceTable(w, runnerStatus.inspect, runnerStatus.events, marker); err != nil {
+				_, _ = fmt.Fprintf(w, "Error displaying resources for %s: %v\n", runnerStatus.Name, err)
+			}
+
+			if changes != nil {
+				for _, r := range changes.removedResources(status.Name, runnerStatus.Name) {
+					_, _ = fmt.Fprintf(w, "%s     [%s] %s\n", changeRemoved, r.Kind, r.Name)
+				}
+			}
 		}
 	}
+}
 
-	return nil
+// formatControllerMetrics summarizes the metrics of the ARC controller
+func formatControllerMetrics(metrics *runner.ControllerMetrics) string {
+	return fmt.Sprintf("%d reconcile errors, ephemeral runners: %d pending, %d running, %d failed",
+		metrics.ReconcileErrors, metrics.PendingRunners, metrics.RunningRunners, metrics.FailedRunners)
+}
+
+// formatScaleSetMetrics summarizes the metrics the listener of a runner reports
+func formatScaleSetMetrics(metrics *runner.ScaleSetMetrics) string {
+	return fmt.Sprintf("jobs: %d assigned, %d running; runners: %d desired, %d registered (%d busy, %d idle)",
+		metrics.AssignedJobs, metrics.RunningJobs, metrics.DesiredRunners,
+		metrics.RegisteredRunners, metrics.BusyRunners, metrics.IdleRunners)
+}
+
+// formatResourceEvent summarizes a warning event on a single line
+func formatResourceEvent(event runner.ResourceEvent) string {
+	message := strings.Join(strings.Fields(event.Message), " ")
+	if event.Count > 1 {
+		return fmt.Sprintf("%s: %s (x%d)", event.Reason, message, event.Count)
+	}
+	return fmt.Sprintf("%s: %s", event.Reason, message)
 }
 
 // formatAge ensures age values are always 3 characters by adding leading zeros
@@ -167,7 +400,12 @@ func extractHierarchyInfo(name string) (hierarchyPrefix, actualName string) {
 // 22h   L [EphemeralRunner] rubionic-workspace-1-2zgjv-runner-6mckt
 //
 //	⚠ : Waiting on finalizers: ephemeralrunner.actions.github.com/finalizer
-func displayResourceTable(output *kapp.KappInspectOutput) error {
+//	✗ : FailedScheduling: 0/1 nodes are available: 1 Insufficient memory (x4)
+//
+// Recent warning events of a resource, keyed by runner.EventKey, are listed
+// below its reconcile info. When marker is set, each line is prefixed with
+// the marker returned for the resource.
+func displayResourceTable(w io.Writer, output *kapp.KappInspectOutput, events map[string][]runner.ResourceEvent, marker func(kind, namespace, name string) string) error {
 	if len(output.Tables) == 0 {
 		return fmt.Errorf("no tables in kapp output")
 	}
@@ -177,7 +415,7 @@ func displayResourceTable(output *kapp.KappInspectOutput) error {
 	resources := table.Rows
 
 	if len(resources) == 0 {
-		fmt.Println("No resources found")
+		_, _ = fmt.Fprintln(w, "No resources found")
 		return nil
 	}
 
@@ -188,28 +426,37 @@ func displayResourceTable(output *kapp.KappInspectOutput) error {
 
 		// Format: age hierarchyPrefix [Kind] name
 		formattedAge := formatAge(r.Age)
-		fmt.Printf("%s %s[%s] %s\n", formattedAge, hierarchyPrefix, r.Kind, name)
+		markerPrefix := ""
+		if marker != nil {
+			markerPrefix = marker(r.Kind, r.Namespace, name) + " "
+		}
+		_, _ = fmt.Fprintf(w, "%s%s %s[%s] %s\n", markerPrefix, formattedAge, hierarchyPrefix, r.Kind, name)
+
+		// Calculate warning indentation to align with resource name column
+		// Base indentation: 3 chars for age + 1 space = 4 chars
+		// Plus the length of the hierarchy prefix (e.g., "L ", "  L ")
+		// Plus the width of the change marker in watch mode
+		// Minus 2 to account for the "⚠ : " prefix characters
+		warningIndent := len(markerPrefix) + 4 + len(hierarchyPrefix) - 2
+		if warningIndent < 0 {
+			warningIndent = 0
+		}
+		warningPrefix := strings.Repeat(" ", warningIndent)
 
 		// If there's reconcile info and it's not ok/empty, show it as a warning
 		if r.ReconcileInfo != "" && r.ReconcileInfo != "-" {
-			// Calculate warning indentation to align with resource name column
-			// Base indentation: 3 chars for age + 1 space = 4 chars
-			// Plus the length of the hierarchy prefix (e.g., "L ", "  L ")
-			// Minus 2 to account for the "⚠ : " prefix characters
-			warningIndent := 4 + len(hierarchyPrefix) - 2
-			if warningIndent < 0 {
-				warningIndent = 0
-			}
-			warningPrefix := strings.Repeat(" ", warningIndent)
-
 			// Handle multi-line reconcile info
 			riLines := strings.Split(r.ReconcileInfo, "\n")
 			for _, line := range riLines {
 				if line != "" {
-					fmt.Printf("%s⚠ : %s\n", warningPrefix, line)
+					_, _ = fmt.Fprintf(w, "%s⚠ : %s\n", warningPrefix, line)
 				}
 			}
 		}
+
+		for _, event := range events[runner.EventKey(r.Namespace, name)] {
+			_, _ = fmt.Fprintf(w, "%s✗ : %s\n", warningPrefix, formatResourceEvent(event))
+		}
 	}
 
 	return nil
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/incus"
+)
+
+// hostStatus holds the status gathered from a single cluster host
+type hostStatus struct {
+	Name      string
+	Container string // Incus container status (RUNNING, STOPPED, ...)
+	Output    string // Output of 'deskrun status' on the host
+	Err       error
+}
+
+func runStatusAllHosts(args []string) error {
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	hosts := configMgr.GetConfig().ClusterHosts
+	if len(hosts) == 0 {
+		fmt.Println("No cluster hosts configured")
+		fmt.Println("\nTo create one, run:")
+		fmt.Println("  deskrun cluster-host create")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	containerStatus, incusMgrs := clusterHostStatuses(ctx, hosts)
+
+	names := make([]string, 0, len(hosts))
+	for name := range hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Query all hosts concurrently, remote deskrun invocations can be slow
+	statuses := make([]hostStatus, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		statuses[i] = hostStatus{Name: name, Container: containerStatus[name]}
+		if statuses[i].Container != "RUNNING" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(status *hostStatus, incusMgr *incus.Manager) {
+			defer wg.Done()
+			command := append([]string{"deskrun", "status"}, args...)
+			if statusMetrics {
+				command = append(command, "--metrics")
+			}
+			status.Output, status.Err = incusMgr.Exec(ctx, status.Name, command...)
+		}(&statuses[i], incusMgrs[hosts[name].Remote])
+	}
+	wg.Wait()
+
+	renderHostStatuses(os.Stdout, statuses)
+	return hostStatusErrors(statuses)
+}
+
+// hostStatusErrors joins the errors of querying the hosts, so the command fails
+// if any host couldn't report its status, including hosts that are not running
+func hostStatusErrors(statuses []hostStatus) error {
+	var errs []error
+	for _, status := range statuses {
+		switch {
+		case status.Container != "RUNNING":
+			errs = append(errs, fmt.Errorf("cluster host %s is %s", status.Name, strings.ToLower(status.Container)))
+		case status.Err != nil:
+			errs = append(errs, fmt.Errorf("failed to get status of cluster host %s: %w", status.Name, status.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// renderHostStatuses writes a summary table followed by the status output and
+// error of each host
+//
+// Output format:
+// HOST                 STATUS     RESULT
+// deskrun-a1b2c3       RUNNING    ok
+// deskrun-d4e5f6       STOPPED    unreachable
+//
+// === deskrun-a1b2c3 ===
+//
+//	Cluster 'deskrun' is running
+//	...
+func renderHostStatuses(w io.Writer, statuses []hostStatus) {
+	_, _ = fmt.Fprintf(w, "%-20s %-10s %s\n", "HOST", "STATUS", "RESULT")
+	for _, status := range statuses {
+		_, _ = fmt.Fprintf(w, "%-20s %-10s %s\n", status.Name, status.Container, hostStatusResult(status))
+	}
+
+	for _, status := range statuses {
+		if status.Output == "" && status.Err == nil {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "\n=== %s ===\n", status.Name)
+		if status.Output != "" {
+			for _, line := range strings.Split(strings.TrimRight(status.Output, "\n"), "\n") {
+				if line == "" {
+					_, _ = fmt.Fprintln(w)
+					continue
+				}
+				_, _ = fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+		if status.Err != nil {
+			_, _ = fmt.Fprintf(w, "  Error: %v\n", status.Err)
+		}
+	}
+}
+
+// hostStatusResult summarizes the outcome of querying a host
+func hostStatusResult(status hostStatus) string {
+	switch {
+	case status.Container != "RUNNING":
+		return "unreachable"
+	case status.Err != nil:
+		return "error"
+	default:
+		return "ok"
+	}
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/rkoster/deskrun/internal/kapp"
+	"github.com/rkoster/deskrun/internal/runner"
+	"gopkg.in/yaml.v3"
+)
+
+// statusReport is the machine-readable form of `deskrun status`
+type statusReport struct {
+	Clusters []clusterStatus `json:"clusters" yaml:"clusters"`
+}
+
+// clusterStatus describes the runners deployed to one kind cluster
+type clusterStatus struct {
+	Name    string         `json:"name" yaml:"name"`
+	Exists  bool           `json:"exists" yaml:"exists"`
+	Runners []runnerStatus `json:"runners" yaml:"runners"`
+
+	// Controller holds the ARC controller metrics (status --metrics only)
+	Controller *runner.ControllerMetrics `json:"controller_metrics,omitempty" yaml:"controller_metrics,omitempty"`
+	// MetricsError is why the metrics could not be scraped (status --metrics only)
+	MetricsError string `json:"metrics_error,omitempty" yaml:"metrics_error,omitempty"`
+	// EventsError is why the events of the resources could not be listed
+	EventsError string `json:"events_error,omitempty" yaml:"events_error,omitempty"`
+}
+
+// runnerStatus describes the kapp app of a single runner installation
+type runnerStatus struct {
+	Name      string            `json:"name" yaml:"name"`
+	Error     string            `json:"error,omitempty" yaml:"error,omitempty"`
+	Counts    runnerCounts      `json:"counts" yaml:"counts"`
+	Warnings  []resourceWarning `json:"warnings" yaml:"warnings"`
+	Resources []resourceStatus  `json:"resources" yaml:"resources"`
+	// Metrics holds the listener metrics of the runner (status --metrics only)
+	Metrics *runner.ScaleSetMetrics `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+
+	// inspect is the raw kapp output, kept for the human-readable table
+	inspect *kapp.KappInspectOutput
+	// events are the recent warnings of the resources by runner.EventKey
+	events map[string][]runner.ResourceEvent
+}
+
+// runnerCounts summarizes the runner related resources of an installation
+type runnerCounts struct {
+	Listeners        int `json:"listeners" yaml:"listeners"`
+	EphemeralRunners int `json:"ephemeral_runners" yaml:"ephemeral_runners"`
+	ReadyRunners     int `json:"ready_runners" yaml:"ready_runners"`
+}
+
+// resourceWarning is a reconcile message reported by kapp for a resource
+type resourceWarning struct {
+	Kind    string `json:"kind" yaml:"kind"`
+	Name    string `json:"name" yaml:"name"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// resourceStatus is a single resource of a runner's kapp app
+type resourceStatus struct {
+	Age            string `json:"age" yaml:"age"`
+	Kind           string `json:"kind" yaml:"kind"`
+	Name           string `json:"name" yaml:"name"`
+	Namespace      string `json:"namespace" yaml:"namespace"`
+	Owner          string `json:"owner" yaml:"owner"`
+	ReconcileState string `json:"reconcile_state" yaml:"reconcile_state"`
+	ReconcileInfo  string `json:"reconcile_info,omitempty" yaml:"reconcile_info,omitempty"`
+	// Events are the recent warnings reported for the resource or its pod
+	Events []runner.ResourceEvent `json:"events,omitempty" yaml:"events,omitempty"`
+}
+
+// newRunnerStatus builds the status of a runner from its kapp inspect output
+func newRunnerStatus(name string, output *kapp.KappInspectOutput) runnerStatus {
+	status := runnerStatus{
+		Name:      name,
+		Warnings:  []resourceWarning{},
+		Resources: []resourceStatus{},
+		inspect:   output,
+	}
+
+	if output == nil || len(output.Tables) == 0 {
+		return status
+	}
+
+	for _, r := range output.Tables[0].Rows {
+		// Strip the hierarchy markers kapp prefixes to owned resources
+		_, resourceName := extractHierarchyInfo(r.Name)
+
+		reconcileInfo := r.ReconcileInfo
+		if reconcileInfo == "-" {
+			reconcileInfo = ""
+		}
+
+		status.Resources = append(status.Resources, resourceStatus{
+			Age:            r.Age,
+			Kind:           r.Kind,
+			Name:           resourceName,
+			Namespace:      r.Namespace,
+			Owner:          r.Owner,
+			ReconcileState: r.ReconcileState,
+			ReconcileInfo:  reconcileInfo,
+		})
+
+		switch r.Kind {
+		case "AutoscalingListener":
+			status.Counts.Listeners++
+		case "EphemeralRunner":
+			status.Counts.EphemeralRunners++
+			if r.ReconcileState == "ok" {
+				status.Counts.ReadyRunners++
+			}
+		}
+
+		for _, line := range strings.Split(reconcileInfo, "\n") {
+			if line != "" {
+				status.Warnings = append(status.Warnings, resourceWarning{
+					Kind:    r.Kind,
+					Name:    resourceName,
+					Message: line,
+				})
+			}
+		}
+	}
+
+	return status
+}
+
+// addEvents attaches the recent warnings of the runner's resources
+func (s *runnerStatus) addEvents(events map[string][]runner.ResourceEvent) {
+	s.events = events
+	for i, r := range s.Resources {
+		s.Resources[i].Events = events[runner.EventKey(r.Namespace, r.Name)]
+	}
+}
+
+// namespaces returns the namespaces of the runner's resources
+func (s *runnerStatus) namespaces() []string {
+	var namespaces []string
+	for _, r := range s.Resources {
+		if r.Namespace != "" && r.Namespace != "(cluster)" && !slices.Contains(namespaces, r.Namespace) {
+			namespaces = append(namespaces, r.Namespace)
+		}
+	}
+	return namespaces
+}
+
+// writeStatusJSON writes the status report as indented JSON
+func writeStatusJSON(w io.Writer, report statusReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+
+	if _, err := fmt.Fprintln(w, string(data)); err != nil {
+		return fmt.Errorf("failed to write status: %w", err)
+	}
+	return nil
+}
+
+// writeStatusYAML writes the status report as YAML
+func writeStatusYAML(w io.Writer, report statusReport) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+	return encoder.Close()
+}
@@ -1,6 +1,14 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rkoster/deskrun/internal/kapp"
+	"github.com/rkoster/deskrun/internal/runner"
+	"gopkg.in/yaml.v3"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -135,3 +143,263 @@ var _ = Describe("Status Command Helpers", func() {
 		})
 	})
 })
+
+var _ = Describe("Status Across Cluster Hosts", func() {
+	Describe("renderHostStatuses", func() {
+		It("should render a summary row per host and group output by host", func() {
+			var buf bytes.Buffer
+			renderHostStatuses(&buf, []hostStatus{
+				{Name: "deskrun-a1b2c3", Container: "RUNNING", Output: "Cluster 'deskrun' is running\n\nRunner: my-runner\n"},
+				{Name: "deskrun-d4e5f6", Container: "STOPPED"},
+				{Name: "deskrun-g7h8i9", Container: "RUNNING", Output: "Error: boom\n", Err: fmt.Errorf("exit status 1")},
+			})
+
+			output := buf.String()
+			Expect(output).To(ContainSubstring("HOST                 STATUS     RESULT\n"))
+			Expect(output).To(ContainSubstring("deskrun-a1b2c3       RUNNING    ok\n"))
+			Expect(output).To(ContainSubstring("deskrun-d4e5f6       STOPPED    unreachable\n"))
+			Expect(output).To(ContainSubstring("deskrun-g7h8i9       RUNNING    error\n"))
+			Expect(output).To(ContainSubstring("=== deskrun-a1b2c3 ===\n  Cluster 'deskrun' is running\n\n  Runner: my-runner\n"))
+			Expect(output).To(ContainSubstring("=== deskrun-g7h8i9 ===\n  Error: boom\n  Error: exit status 1\n"))
+			Expect(output).NotTo(ContainSubstring("=== deskrun-d4e5f6 ==="))
+		})
+
+		It("should render the error of a host without output", func() {
+			var buf bytes.Buffer
+			renderHostStatuses(&buf, []hostStatus{
+				{Name: "deskrun-a1b2c3", Container: "RUNNING", Err: fmt.Errorf("connection refused")},
+			})
+
+			Expect(buf.String()).To(ContainSubstring("=== deskrun-a1b2c3 ===\n  Error: connection refused\n"))
+		})
+	})
+
+	Describe("hostStatusErrors", func() {
+		It("should join the errors of all hosts", func() {
+			err := hostStatusErrors([]hostStatus{
+				{Name: "deskrun-a1b2c3", Container: "RUNNING", Output: "ok\n"},
+				{Name: "deskrun-d4e5f6", Container: "RUNNING", Err: fmt.Errorf("exit status 1")},
+				{Name: "deskrun-g7h8i9", Container: "RUNNING", Err: fmt.Errorf("connection refused")},
+			})
+
+			Expect(err).To(MatchError(ContainSubstring("deskrun-d4e5f6: exit status 1")))
+			Expect(err).To(MatchError(ContainSubstring("deskrun-g7h8i9: connection refused")))
+			Expect(err.Error()).NotTo(ContainSubstring("deskrun-a1b2c3"))
+		})
+
+		It("should count hosts that are not running as failed", func() {
+			err := hostStatusErrors([]hostStatus{
+				{Name: "deskrun-a1b2c3", Container: "STOPPED"},
+				{Name: "deskrun-d4e5f6", Container: "MISSING"},
+			})
+
+			Expect(err).To(MatchError(ContainSubstring("cluster host deskrun-a1b2c3 is stopped")))
+			Expect(err).To(MatchError(ContainSubstring("cluster host deskrun-d4e5f6 is missing")))
+		})
+
+		It("should return nil if all hosts reported their status", func() {
+			Expect(hostStatusErrors([]hostStatus{{Name: "deskrun-a1b2c3", Container: "RUNNING"}})).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("Status Report", func() {
+	inspectOutput := &kapp.KappInspectOutput{
+		Tables: []kapp.KappTable{{
+			Rows: []kapp.KappResource{
+				{Age: "23h", Kind: "AutoscalingRunnerSet", Name: "my-runner", Namespace: "arc-systems", ReconcileState: "ok", ReconcileInfo: "-"},
+				{Age: "23h", Kind: "AutoscalingListener", Name: " L my-runner-listener", Namespace: "arc-systems", ReconcileState: "ok"},
+				{Age: "5m", Kind: "EphemeralRunner", Name: " L.. my-runner-abc", Namespace: "arc-systems", ReconcileState: "ok"},
+				{Age: "1h", Kind: "EphemeralRunner", Name: " L.. my-runner-def", Namespace: "arc-systems", ReconcileState: "ongoing",
+					ReconcileInfo: "Waiting on finalizers: ephemeralrunner.actions.github.com/finalizer"},
+			},
+		}},
+	}
+
+	Describe("newRunnerStatus", func() {
+		It("should count listeners and runners", func() {
+			status := newRunnerStatus("my-runner", inspectOutput)
+			Expect(status.Counts).To(Equal(runnerCounts{Listeners: 1, EphemeralRunners: 2, ReadyRunners: 1}))
+		})
+
+		It("should strip hierarchy markers from resource names", func() {
+			status := newRunnerStatus("my-runner", inspectOutput)
+			Expect(status.Resources).To(HaveLen(4))
+			Expect(status.Resources[1].Name).To(Equal("my-runner-listener"))
+			Expect(status.Resources[3].Name).To(Equal("my-runner-def"))
+		})
+
+		It("should collect reconcile warnings", func() {
+			status := newRunnerStatus("my-runner", inspectOutput)
+			Expect(status.Warnings).To(Equal([]resourceWarning{{
+				Kind:    "EphemeralRunner",
+				Name:    "my-runner-def",
+				Message: "Waiting on finalizers: ephemeralrunner.actions.github.com/finalizer",
+			}}))
+			Expect(status.Resources[0].ReconcileInfo).To(BeEmpty())
+		})
+
+		It("should attach events to their resources", func() {
+			status := newRunnerStatus("my-runner", inspectOutput)
+			status.addEvents(map[string][]runner.ResourceEvent{
+				runner.EventKey("arc-systems", "my-runner-abc"): {{Reason: "FailedScheduling", Message: "0/1 nodes are available", Count: 2}},
+			})
+			Expect(status.namespaces()).To(Equal([]string{"arc-systems"}))
+			Expect(status.Resources[2].Events).To(HaveLen(1))
+			Expect(status.Resources[3].Events).To(BeEmpty())
+		})
+
+		It("should handle output without tables", func() {
+			status := newRunnerStatus("my-runner", &kapp.KappInspectOutput{})
+			Expect(status.Resources).To(BeEmpty())
+			Expect(status.Warnings).To(BeEmpty())
+		})
+	})
+
+	Describe("structured output", func() {
+		report := statusReport{Clusters: []clusterStatus{
+			{Name: "deskrun", Exists: true, Runners: []runnerStatus{newRunnerStatus("my-runner", inspectOutput)}},
+			{Name: "deskrun-gpu", Runners: []runnerStatus{}},
+		}}
+
+		It("should write JSON", func() {
+			var buf bytes.Buffer
+			Expect(writeStatusJSON(&buf, report)).To(Succeed())
+
+			var decoded statusReport
+			Expect(json.Unmarshal(buf.Bytes(), &decoded)).To(Succeed())
+			Expect(decoded.Clusters).To(HaveLen(2))
+			Expect(decoded.Clusters[0].Runners[0].Counts.EphemeralRunners).To(Equal(2))
+			Expect(buf.String()).To(ContainSubstring(`"ready_runners": 1`))
+		})
+
+		It("should write YAML", func() {
+			var buf bytes.Buffer
+			Expect(writeStatusYAML(&buf, report)).To(Succeed())
+
+			var decoded statusReport
+			Expect(yaml.Unmarshal(buf.Bytes(), &decoded)).To(Succeed())
+			Expect(decoded.Clusters[1].Name).To(Equal("deskrun-gpu"))
+			Expect(decoded.Clusters[1].Exists).To(BeFalse())
+			Expect(buf.String()).To(ContainSubstring("reconcile_state: ongoing"))
+		})
+	})
+})
+
+var _ = Describe("Status Watch", func() {
+	newReport := func(resources ...kapp.KappResource) statusReport {
+		output := &kapp.KappInspectOutput{Tables: []kapp.KappTable{{Rows: resources}}}
+		return statusReport{Clusters: []clusterStatus{{
+			Name:    "deskrun",
+			Exists:  true,
+			Runners: []runnerStatus{newRunnerStatus("my-runner", output)},
+		}}}
+	}
+
+	listener := kapp.KappResource{Age: "1h", Kind: "AutoscalingListener", Name: "my-runner-listener", Namespace: "arc-systems", ReconcileState: "ok"}
+	pending := kapp.KappResource{Age: "5s", Kind: "EphemeralRunner", Name: " L my-runner-abc", Namespace: "arc-systems", ReconcileState: "ongoing"}
+	running := kapp.KappResource{Age: "9s", Kind: "EphemeralRunner", Name: " L my-runner-abc", Namespace: "arc-systems", ReconcileState: "ok"}
+
+	key := func(kind, name string) string {
+		return resourceKey("deskrun", "my-runner", kind, "arc-systems", name)
+	}
+
+	Describe("diffStatusReports", func() {
+		It("should not mark anything on the first refresh", func() {
+			changes := diffStatusReports(nil, newReport(listener, pending))
+			Expect(changes.marker(key("EphemeralRunner", "my-runner-abc"))).To(Equal(changeNone))
+		})
+
+		It("should mark new, changed and removed resources", func() {
+			previous := newReport(listener, pending)
+
+			changes := diffStatusReports(&previous, newReport(listener, running))
+			Expect(changes.marker(key("AutoscalingListener", "my-runner-listener"))).To(Equal(changeNone))
+			Expect(changes.marker(key("EphemeralRunner", "my-runner-abc"))).To(Equal(changeUpdated))
+
+			changes = diffStatusReports(&previous, newReport(pending))
+			Expect(changes.removedResources("deskrun", "my-runner")).To(HaveLen(1))
+
+			empty := newReport(listener)
+			changes = diffStatusReports(&empty, newReport(listener, pending))
+			Expect(changes.marker(key("EphemeralRunner", "my-runner-abc"))).To(Equal(changeAdded))
+		})
+	})
+
+	Describe("printStatusReport", func() {
+		It("should prefix resources with change markers", func() {
+			previous := newReport(listener, pending)
+			current := newReport(listener, running)
+
+			var buf bytes.Buffer
+			printStatusReport(&buf, current, diffStatusReports(&previous, current))
+
+			output := buf.String()
+			Expect(output).To(ContainSubstring("  01h [AutoscalingListener] my-runner-listener\n"))
+			Expect(output).To(ContainSubstring("~ 09s L [EphemeralRunner] my-runner-abc\n"))
+		})
+
+		It("should list removed resources", func() {
+			previous := newReport(listener, pending)
+			current := newReport(listener)
+
+			var buf bytes.Buffer
+			printStatusReport(&buf, current, diffStatusReports(&previous, current))
+			Expect(buf.String()).To(ContainSubstring("-     [EphemeralRunner] my-runner-abc\n"))
+		})
+
+		It("should not print markers outside watch mode", func() {
+			var buf bytes.Buffer
+			printStatusReport(&buf, newReport(listener), nil)
+			Expect(buf.String()).To(Equal("Cluster 'deskrun' is running\n\nRunner: my-runner\n01h [AutoscalingListener] my-runner-listener\n"))
+		})
+
+		It("should print the metrics of the controller and runners", func() {
+			report := newReport(listener)
+			report.Clusters[0].Controller = &runner.ControllerMetrics{ReconcileErrors: 2, RunningRunners: 1}
+			report.Clusters[0].Runners[0].Metrics = &runner.ScaleSetMetrics{AssignedJobs: 1, RunningJobs: 1, RegisteredRunners: 2, BusyRunners: 1, IdleRunners: 1, DesiredRunners: 2}
+
+			var buf bytes.Buffer
+			printStatusReport(&buf, report, nil)
+			Expect(buf.String()).To(Equal("Cluster 'deskrun' is running\n" +
+				"Controller: 2 reconcile errors, ephemeral runners: 0 pending, 1 running, 0 failed\n\n" +
+				"Runner: my-runner\n" +
+				"Metrics: jobs: 1 assigned, 1 running; runners: 2 desired, 2 registered (1 busy, 1 idle)\n" +
+				"01h [AutoscalingListener] my-runner-listener\n"))
+		})
+
+		It("should list recent warning events below their resource", func() {
+			report := newReport(listener, running)
+			report.Clusters[0].Runners[0].addEvents(map[string][]runner.ResourceEvent{
+				runner.EventKey("arc-systems", "my-runner-abc"): {
+					{Reason: "OOMKilled", Message: "Container runner ran out of memory (exit code 137)", Count: 1},
+					{Reason: "FailedScheduling", Message: "0/1 nodes are available:\n1 Insufficient memory.", Count: 4},
+				},
+			})
+
+			var buf bytes.Buffer
+			printStatusReport(&buf, report, nil)
+			Expect(buf.String()).To(HaveSuffix("09s L [EphemeralRunner] my-runner-abc\n" +
+				"    ✗ : OOMKilled: Container runner ran out of memory (exit code 137)\n" +
+				"    ✗ : FailedScheduling: 0/1 nodes are available: 1 Insufficient memory. (x4)\n"))
+		})
+
+		It("should print why events are unavailable", func() {
+			report := newReport(listener)
+			report.Clusters[0].EventsError = "failed to list events"
+
+			var buf bytes.Buffer
+			printStatusReport(&buf, report, nil)
+			Expect(buf.String()).To(HavePrefix("Cluster 'deskrun' is running\nEvents: failed to list events\n\n"))
+		})
+
+		It("should print why metrics are unavailable", func() {
+			report := newReport(listener)
+			report.Clusters[0].MetricsError = "no ARC metrics endpoints found"
+
+			var buf bytes.Buffer
+			printStatusReport(&buf, report, nil)
+			Expect(buf.String()).To(HavePrefix("Cluster 'deskrun' is running\nMetrics: no ARC metrics endpoints found\n\n"))
+		})
+	})
+})
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+)
+
+// Change markers shown in front of resources in watch mode
+const (
+	changeNone    = " "
+	changeAdded   = "+"
+	changeUpdated = "~"
+	changeRemoved = "-"
+)
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// statusChanges records how resources changed between two status refreshes
+type statusChanges struct {
+	markers map[string]string
+	removed map[string][]resourceStatus
+}
+
+// marker returns the change marker for the resource with the given key
+func (c *statusChanges) marker(key string) string {
+	if m, ok := c.markers[key]; ok {
+		return m
+	}
+	return changeNone
+}
+
+// removedResources returns the resources of a runner that disappeared since the previous refresh
+func (c *statusChanges) removedResources(clusterName, runnerName string) []resourceStatus {
+	return c.removed[clusterName+"/"+runnerName]
+}
+
+// resourceKey identifies a resource of a runner across status refreshes
+func resourceKey(clusterName, runnerName, kind, namespace, name string) string {
+	return strings.Join([]string{clusterName, runnerName, kind, namespace, name}, "/")
+}
+
+// diffStatusReports compares two status reports. Without a previous report,
+// nothing is marked as changed.
+func diffStatusReports(previous *statusReport, current statusReport) *statusChanges {
+	changes := &statusChanges{
+		markers: map[string]string{},
+		removed: map[string][]resourceStatus{},
+	}
+	if previous == nil {
+		return changes
+	}
+
+	before := map[string]resourceStatus{}
+	for _, cluster := range previous.Clusters {
+		for _, runner := range cluster.Runners {
+			for _, r := range runner.Resources {
+				before[resourceKey(cluster.Name, runner.Name, r.Kind, r.Namespace, r.Name)] = r
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, cluster := range current.Clusters {
+		for _, runner := range cluster.Runners {
+			for _, r := range runner.Resources {
+				key := resourceKey(cluster.Name, runner.Name, r.Kind, r.Namespace, r.Name)
+				seen[key] = true
+
+				old, ok := before[key]
+				switch {
+				case !ok:
+					changes.markers[key] = changeAdded
+				case old.ReconcileState != r.ReconcileState || old.ReconcileInfo != r.ReconcileInfo:
+					changes.markers[key] = changeUpdated
+				}
+			}
+		}
+	}
+
+	for _, cluster := range previous.Clusters {
+		for _, runner := range cluster.Runners {
+			for _, r := range runner.Resources {
+				if !seen[resourceKey(cluster.Name, runner.Name, r.Kind, r.Namespace, r.Name)] {
+					runnerKey := cluster.Name + "/" + runner.Name
+					changes.removed[runnerKey] = append(changes.removed[runnerKey], r)
+				}
+			}
+		}
+	}
+
+	return changes
+}
+
+// runStatusWatch refreshes the status every statusInterval until interrupted
+func runStatusWatch(configMgr *config.Manager, args []string) error {
+	if statusInterval <= 0 {
+		return fmt.Errorf("--interval must be positive, got %s", statusInterval)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	var previous *statusReport
+	for {
+		// Render into a buffer first so the screen is only cleared once the new status is ready
+		var buf bytes.Buffer
+		_, _ = fmt.Fprintf(&buf, "Every %s: deskrun status (+ new, ~ changed, - removed)    %s\n\n",
+			statusInterval, time.Now().Format("15:04:05"))
+
+		report, err := collectStatusReport(configMgr, args)
+		if err != nil {
+			_, _ = fmt.Fprintf(&buf, "Error: %v\n", err)
+		} else {
+			printStatusReport(&buf, report, diffStatusReports(previous, report))
+			previous = &report
+		}
+
+		fmt.Print(clearScreen + buf.String())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -3,13 +3,39 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/rkoster/deskrun/internal/cluster"
 	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/kapp"
+	"github.com/rkoster/deskrun/internal/progress"
 	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/templates"
 	"github.com/rkoster/deskrun/pkg/types"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// maxParallelDeploys bounds how many installations 'deskrun up' deploys at
+// the same time
+const maxParallelDeploys = 4
+
+var (
+	upCluster           string
+	upControllerVersion string
+	upForce             bool
+	upWaitTimeout       time.Duration
+	upDiff              bool
+	upDeployTimeout     time.Duration
+	upNoWait            bool
+	upPrePull           bool
+	upOverlays          []string
 )
 
 var upCmd = &cobra.Command{
@@ -20,19 +46,68 @@ var upCmd = &cobra.Command{
 This command idempotently:
 - Creates the kind cluster if it doesn't exist
 - Installs the ARC controller if it's not installed
-- Deploys all configured runner scale sets
-- Updates existing runners if their configuration has changed
+- Deploys all configured runner scale sets, up to 4 installations at a time
+- Updates existing runners if their configuration has changed, after waiting
+  for their running jobs to finish (skipped with --force)
+
+Runners that run a job are listed before they are replaced. On a terminal
+deskrun asks whether to wait for the jobs; otherwise it waits, for at most
+--wait-timeout, and fails the runner if its jobs did not finish in time.
+
+kapp waits up to 15 minutes for the resources of every runner to reconcile.
+--deploy-timeout changes the limit, e.g. on slow machines, and --no-wait
+returns as soon as the resources are applied. Their defaults can be set in
+the deploy section of the config.
+
+--pre-pull pulls the runner and dind images on the host and loads the ones
+missing on the cluster node into it before the runners are deployed, so the
+first job doesn't wait for a large image pull, also after the cluster was
+recreated. It is enabled by default with pre_pull in the deploy section of the
+config.
+
+--overlay applies extra ytt overlay files to the manifests of every runner
+deployed by this run, after deskrun's overlay and the overlays configured with
+'deskrun add --overlay'. They are not saved in the config.
+
+--diff shows the resources that would be created, updated or deleted for
+every runner, the way kapp shows them, without changing the cluster.
 
 This is the command to run after adding or modifying runner configurations
 with 'deskrun add' or 'deskrun remove'.
 
-Example:
+Installations pinned to a named cluster with 'deskrun add --cluster' are
+deployed to that cluster. Without --cluster, every known cluster is brought up.
+
+The ARC controller is installed at the version pinned with
+'deskrun upgrade-controller --controller-version', or the default version
+bundled with deskrun. Use --controller-version to install a specific embedded
+version on new clusters; existing controllers are changed with
+'deskrun upgrade-controller'.
+
+Examples:
   deskrun up
+  deskrun up --cluster deskrun-gpu         # Only deploy runners of one cluster
+  deskrun up --controller-version 0.13.0   # Install a specific ARC version
+  deskrun up --wait-timeout 1h             # Wait longer for running jobs
+  deskrun up --diff                        # Only show the changes
+  deskrun up --deploy-timeout 30m          # Give slow machines more time
+  deskrun up --pre-pull                    # Load runner images into the node first
+  deskrun up --overlay ./debug.yaml --diff  # Preview an overlay
+  deskrun up --force                       # Replace runners, cancelling their jobs
 `,
 	RunE: runUp,
 }
 
 func init() {
+	upCmd.Flags().StringVar(&upCluster, "cluster", "", "Only deploy the runners pinned to this cluster")
+	upCmd.Flags().BoolVar(&upForce, "force", false, "Update and remove runners without waiting for their running jobs to finish")
+	upCmd.Flags().DurationVar(&upWaitTimeout, "wait-timeout", runner.DrainTimeout, "How long to wait for the running jobs of a runner before updating or removing it")
+	upCmd.Flags().BoolVar(&upDiff, "diff", false, "Show the changes to the runners without applying them")
+	upCmd.Flags().DurationVar(&upDeployTimeout, "deploy-timeout", 0, "How long kapp applies and waits for the resources of a runner (default 15m, or the config default)")
+	upCmd.Flags().BoolVar(&upNoWait, "no-wait", false, "Return once the resources are applied, without waiting for them to reconcile")
+	upCmd.Flags().BoolVar(&upPrePull, "pre-pull", false, "Load the runner and dind images into the cluster node before deploying the runners")
+	upCmd.Flags().StringSliceVar(&upOverlays, "overlay", []string{}, "Extra ytt overlay file applied to the manifests of every runner (can be specified multiple times)")
+	upCmd.Flags().StringVar(&upControllerVersion, "controller-version", "", "ARC controller chart version to install (defaults to the pinned or bundled version)")
 	rootCmd.AddCommand(upCmd)
 }
 
@@ -43,15 +118,60 @@ func runUp(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	installations := configMgr.GetConfig().Installations
+	cfg := configMgr.GetConfig()
 
-	if len(installations) == 0 {
+	if len(cfg.Installations) == 0 {
 		fmt.Println("No runner installations configured")
 		fmt.Println("\nTo add a runner, run:")
 		fmt.Println("  deskrun add <name> --repository <url> --auth-type pat --auth-value <token>")
 		return nil
 	}
 
+	controllerVersion, err := resolveControllerVersion(upControllerVersion, cfg.ControllerVersion)
+	if err != nil {
+		return err
+	}
+	applyOpts, err := kappApplyOptions(cfg, upDeployTimeout, upNoWait)
+	if err != nil {
+		return err
+	}
+	overlays, err := overlayPaths(upOverlays)
+	if err != nil {
+		return err
+	}
+	cfg.Installations = withOverlays(cfg.Installations, overlays)
+
+	for i, name := range targetClusters(cfg, upCluster) {
+		if i > 0 {
+			fmt.Println()
+		}
+		if upDiff {
+			if err := diffClusterInstallations(cfg, name, controllerVersion); err != nil {
+				return fmt.Errorf("failed to diff cluster '%s': %w", name, err)
+			}
+			continue
+		}
+		if err := upClusterInstallations(cfg, name, controllerVersion, applyOpts); err != nil {
+			return fmt.Errorf("failed to deploy to cluster '%s': %w", name, err)
+		}
+	}
+
+	if upDiff {
+		return nil
+	}
+
+	fmt.Println("\nDeployment complete!")
+	return nil
+}
+
+// upClusterInstallations creates the cluster if needed, deploys the installations
+// configured for it and removes deployed runners that are no longer configured
+// for it. Clusters without installations are only cleaned up if they exist.
+// A missing ARC controller is installed at the given chart version. kapp
+// deploys with the given timeout and wait behavior.
+func upClusterInstallations(cfg *config.Config, clusterName, controllerVersion string, applyOpts kapp.ApplyOptions) error {
+	installations := cfg.InstallationsForCluster(clusterName)
+
 	// Detect available nix mounts
 	nixStore, nixSocket := cluster.DetectNixMounts()
 
@@ -60,14 +180,19 @@ func runUp(cmd *cobra.Command, args []string) error {
 
 	// Setup cluster manager
 	clusterConfig := &types.ClusterConfig{
-		Name:         configMgr.GetConfig().ClusterName,
-		NixStore:     nixStore,
-		NixSocket:    nixSocket,
-		DockerSocket: dockerSocket,
+		Name:              clusterName,
+		Provider:          cfg.ClusterProvider,
+		KubeContext:       cfg.ClusterKubeContext(clusterName),
+		KubernetesVersion: cfg.ClusterKubernetesVersion(clusterName),
+		PortMappings:      cfg.ClusterPortMappings(clusterName),
+		NixStore:          nixStore,
+		NixSocket:         nixSocket,
+		DockerSocket:      dockerSocket,
+		WindowsDrives:     cluster.DetectWindowsDrives(installations),
 	}
 	clusterMgr := cluster.NewManager(clusterConfig)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), max(10*time.Minute, applyOpts.Timeout)+upWaitTimeout)
 	defer cancel()
 
 	// Check if cluster exists, create if needed
@@ -77,22 +202,63 @@ func runUp(cmd *cobra.Command, args []string) error {
 	}
 
 	if !exists {
-		fmt.Printf("Creating kind cluster '%s'...\n", clusterConfig.Name)
-		if err := clusterMgr.Create(ctx); err != nil {
+		if len(installations) == 0 {
+			slog.Info("Skipping cluster without runners", "cluster", clusterConfig.Name)
+			return nil
+		}
+		phase := progress.Start("Creating %s cluster '%s'", clusterMgr.ProviderName(), clusterConfig.Name)
+		err := clusterMgr.Create(ctx)
+		phase.End(err)
+		if err != nil {
 			return fmt.Errorf("failed to create cluster: %w", err)
 		}
-		fmt.Println("Cluster created successfully")
+	} else if clusterMgr.IsExternal() {
+		slog.Info("Using external cluster", "cluster", clusterConfig.Name, "context", clusterMgr.GetKubeconfig())
 	} else {
-		fmt.Printf("Using existing cluster '%s'\n", clusterConfig.Name)
+		slog.Info("Using existing cluster", "cluster", clusterConfig.Name)
+	}
+
+	// Pull images through the registry mirrors, which need the kind network of the cluster
+	if len(cfg.Mirrors) > 0 && clusterMgr.IsKind() {
+		for _, registry := range cfg.Mirrors {
+			if err := cluster.EnsureMirror(ctx, registry); err != nil {
+				return err
+			}
+		}
+		if err := clusterMgr.ConfigureMirrors(ctx, cfg.Mirrors, nil); err != nil {
+			return fmt.Errorf("failed to configure mirrors: %w", err)
+		}
 	}
 
 	// Setup runner manager
-	runnerMgr := runner.NewManager(clusterMgr)
+	runnerMgr := runner.NewManager(clusterMgr).
+		WithControllerVersion(controllerVersion).
+		WithControllerProxy(cfg.ClusterProxy(clusterName)).
+		WithApplyOptions(applyOpts)
+
+	// Sync the credentials of private registries, removing them when none are configured
+	if err := runnerMgr.ApplyRegistryCredentials(ctx, cfg.SortedRegistries(), cfg.ClusterNamespaces(clusterName)); err != nil {
+		return fmt.Errorf("failed to apply registry credentials: %w", err)
+	}
+
+	if cacheServer := cfg.ClusterCacheServer(clusterName); cacheServer != nil {
+		slog.Info("Deploying actions cache server")
+		if err := runnerMgr.EnableCacheServer(ctx, cacheServer); err != nil {
+			return fmt.Errorf("failed to deploy cache server: %w", err)
+		}
+	}
+
+	if observability := cfg.ClusterObservability(clusterName); observability != nil {
+		slog.Info("Deploying observability stack")
+		if err := deployObservability(ctx, clusterMgr, runnerMgr, observability); err != nil {
+			return err
+		}
+	}
 
 	// Get list of currently deployed runners
 	deployedRunners, err := runnerMgr.List(ctx)
 	if err != nil {
-		fmt.Printf("Warning: failed to list deployed runners: %v\n", err)
+		slog.Warn("Failed to list deployed runners", "error", err)
 		deployedRunners = []string{}
 	}
 
@@ -102,39 +268,366 @@ func runUp(cmd *cobra.Command, args []string) error {
 		deployedMap[name] = true
 	}
 
+	// The controller is installed first, so the runners can be deployed concurrently
+	if len(installations) > 0 {
+		if err := runnerMgr.EnsureController(ctx); err != nil {
+			return err
+		}
+	}
+
+	if prePull(cfg, upPrePull) && !clusterMgr.IsExternal() && !cfg.ClusterOffline(clusterName) {
+		prePullImages(ctx, clusterMgr, runnerMgr, installations)
+	}
+
+	// Every deployed runner is either updated or removed; both wait for its
+	// running jobs, unless told not to
+	force := upForce
+	if !force {
+		force, err = confirmBusyRunners(ctx, runnerMgr, deployedRunners)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Install/update configured runners
-	fmt.Println("\nDeploying configured runners...")
-	for name, installation := range installations {
+	slog.Info("Deploying configured runners", "cluster", clusterName)
+	failures := deployInstallations(slices.Sorted(maps.Keys(installations)), func(name string) error {
 		if deployedMap[name] {
-			fmt.Printf("  Updating runner '%s'...\n", name)
+			slog.Info("Updating runner", "name", name)
 			// For now, we'll uninstall and reinstall to update
-			if err := runnerMgr.Uninstall(ctx, name); err != nil {
-				fmt.Printf("  Warning: failed to uninstall runner '%s': %v\n", name, err)
+			if err := uninstallRunner(ctx, runnerMgr, name, force, upWaitTimeout); err != nil {
+				return err
 			}
 		} else {
-			fmt.Printf("  Installing runner '%s'...\n", name)
+			slog.Info("Installing runner", "name", name)
 		}
 
-		if err := runnerMgr.Install(ctx, installation); err != nil {
-			fmt.Printf("  Error: failed to install runner '%s': %v\n", name, err)
-			continue
+		if err := runnerMgr.Install(ctx, installationToDeploy(cfg, installations[name])); err != nil {
+			return err
 		}
-		fmt.Printf("  ✓ Runner '%s' deployed\n", name)
-	}
+		slog.Info("Runner deployed", "name", name)
+		return nil
+	})
 
 	// Remove runners that are deployed but not in config
-	fmt.Println("\nCleaning up removed runners...")
+	slog.Info("Cleaning up removed runners", "cluster", clusterName)
 	for _, name := range deployedRunners {
 		if _, exists := installations[name]; !exists {
-			fmt.Printf("  Removing runner '%s'...\n", name)
-			if err := runnerMgr.Uninstall(ctx, name); err != nil {
-				fmt.Printf("  Warning: failed to remove runner '%s': %v\n", name, err)
+			slog.Info("Removing runner", "name", name)
+			if err := uninstallRunner(ctx, runnerMgr, name, force, upWaitTimeout); err != nil {
+				slog.Warn("Failed to remove runner", "name", name, "error", err)
 			} else {
-				fmt.Printf("  ✓ Runner '%s' removed\n", name)
+				slog.Info("Runner removed", "name", name)
 			}
 		}
 	}
 
-	fmt.Println("\nDeployment complete!")
+	deleteUnusedNamespaces(ctx, runnerMgr)
+
+	if len(failures) > 0 {
+		printDeployFailures(os.Stdout, clusterName, failures)
+		return fmt.Errorf("%d of %d runners failed to deploy", len(failures), len(installations))
+	}
 	return nil
 }
+
+// diffClusterInstallations shows the changes upClusterInstallations would
+// apply to the runners of a cluster, without applying them
+func diffClusterInstallations(cfg *config.Config, clusterName, controllerVersion string) error {
+	installations := cfg.InstallationsForCluster(clusterName)
+	clusterMgr := newClusterManager(cfg, clusterName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		if len(installations) > 0 {
+			fmt.Printf("Cluster '%s' does not exist, it would be created with %d runner(s)\n", clusterName, len(installations))
+		}
+		return nil
+	}
+
+	runnerMgr := runner.NewManager(clusterMgr).
+		WithControllerVersion(controllerVersion).
+		WithControllerProxy(cfg.ClusterProxy(clusterName))
+
+	if len(installations) > 0 {
+		version, err := runnerMgr.DeployedControllerVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version == "" {
+			fmt.Printf("Cluster '%s': ARC controller would be installed\n", clusterName)
+		}
+	}
+
+	for _, installation := range sortedInstallations(installations) {
+		if err := runnerMgr.Diff(ctx, installationToDeploy(cfg, installation)); err != nil {
+			return err
+		}
+	}
+
+	deployedRunners, err := runnerMgr.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list runners: %w", err)
+	}
+	for _, name := range removedScaleSets(deployedRunners, installations) {
+		slog.Info("Changes to remove runner scale set", "name", name)
+		if err := runnerMgr.DiffUninstall(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removedScaleSets returns the deployed scale sets that are no instance of
+// the configured installations
+func removedScaleSets(deployed []string, installations map[string]*types.RunnerInstallation) []string {
+	configured := make(map[string]bool)
+	for _, installation := range installations {
+		for _, name := range instanceNames(installation.Name, installation.Instances) {
+			configured[name] = true
+		}
+	}
+
+	var removed []string
+	for _, name := range deployed {
+		if !configured[name] {
+			removed = append(removed, name)
+		}
+	}
+	return removed
+}
+
+// deployFailure is an installation that failed to deploy
+type deployFailure struct {
+	Name string
+	Err  error
+}
+
+// deployInstallations deploys the named installations concurrently, at most
+// maxParallelDeploys at a time, and returns the ones that failed in the
+// order of names. A failing installation doesn't stop the others.
+func deployInstallations(names []string, deploy func(name string) error) []deployFailure {
+	errs := runner.RunParallel(len(names), maxParallelDeploys, func(i int) error {
+		return deploy(names[i])
+	})
+
+	var failures []deployFailure
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, deployFailure{Name: names[i], Err: err})
+		}
+	}
+	return failures
+}
+
+// printDeployFailures reports the installations of a cluster that failed to deploy
+func printDeployFailures(w io.Writer, clusterName string, failures []deployFailure) {
+	_, _ = fmt.Fprintf(w, "\nFailed to deploy %d runner(s) to cluster '%s':\n", len(failures), clusterName)
+	for _, failure := range failures {
+		_, _ = fmt.Fprintf(w, "  ✗ %s: %v\n", failure.Name, failure.Err)
+	}
+}
+
+// confirmBusyRunners warns about the runners of the scale sets to replace that
+// run a job. On a terminal it asks whether to wait for the jobs and returns
+// true if the scale sets should be replaced right away instead.
+func confirmBusyRunners(ctx context.Context, runnerMgr *runner.Manager, scaleSets []string) (bool, error) {
+	busy := 0
+	for _, name := range scaleSets {
+		runners, err := runnerMgr.BusyRunners(ctx, name)
+		if err != nil {
+			slog.Debug("Failed to check for running jobs", "name", name, "error", err)
+			continue
+		}
+		for _, r := range runners {
+			slog.Warn("Runner is running a job", "name", name, "runner", r.Name, "job", r.JobDisplayName, "repository", r.JobRepository)
+		}
+		busy += len(runners)
+	}
+	if busy == 0 || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, nil
+	}
+
+	answer, err := promptLine(fmt.Sprintf("Wait for %d running job(s) to finish? No cancels them [Y/n] ", busy))
+	if err != nil {
+		return false, err
+	}
+	return !waitConfirmed(answer), nil
+}
+
+// waitConfirmed returns true unless the prompt to wait for running jobs was
+// answered with no
+func waitConfirmed(answer string) bool {
+	return strings.TrimSpace(answer) == "" || isConfirmation(answer)
+}
+
+// uninstallRunner removes a runner scale set. Unless force is set, it is
+// drained first so running jobs are not killed; timeout bounds the wait.
+func uninstallRunner(ctx context.Context, runnerMgr *runner.Manager, name string, force bool, timeout time.Duration) error {
+	if !force {
+		if err := runnerMgr.Drain(ctx, name, timeout); err != nil {
+			return err
+		}
+	}
+	return runnerMgr.Uninstall(ctx, name)
+}
+
+// deleteUnusedNamespaces deletes the installation namespaces deskrun created
+// that no runner is deployed to anymore. Failing to do so only warns.
+func deleteUnusedNamespaces(ctx context.Context, runnerMgr *runner.Manager) {
+	deleted, err := runnerMgr.DeleteUnusedNamespaces(ctx)
+	for _, namespace := range deleted {
+		slog.Info("Namespace deleted", "namespace", namespace)
+	}
+	if err != nil {
+		slog.Warn("Failed to delete unused namespaces", "error", err)
+	}
+}
+
+// installationWithProxy returns the installation to deploy, with the proxy of
+// its cluster applied when it has no proxy settings of its own
+func installationWithProxy(cfg *config.Config, installation *types.RunnerInstallation) *types.RunnerInstallation {
+	proxy := cfg.ProxyFor(installation)
+	if proxy == installation.Proxy {
+		return installation
+	}
+
+	withProxy := *installation
+	withProxy.Proxy = proxy
+	return &withProxy
+}
+
+// kappApplyOptions returns how long kapp deploys and whether it waits for the
+// resources: the --deploy-timeout and --no-wait flags, falling back to the
+// deploy defaults of the config
+func kappApplyOptions(cfg *config.Config, timeout time.Duration, noWait bool) (kapp.ApplyOptions, error) {
+	if timeout < 0 {
+		return kapp.ApplyOptions{}, fmt.Errorf("invalid --deploy-timeout '%s': must be a positive duration, e.g. 30m", timeout)
+	}
+
+	opts := kapp.ApplyOptions{Timeout: timeout, NoWait: noWait}
+	if cfg.Deploy != nil {
+		opts.NoWait = opts.NoWait || cfg.Deploy.NoWait
+		if opts.Timeout == 0 {
+			configured, err := deployTimeout(cfg.Deploy)
+			if err != nil {
+				return kapp.ApplyOptions{}, err
+			}
+			opts.Timeout = configured
+		}
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = kapp.DefaultApplyTimeout
+	}
+	return opts, nil
+}
+
+// prePull returns whether up pre-pulls the runner images: the --pre-pull flag,
+// falling back to the deploy defaults of the config
+func prePull(cfg *config.Config, flag bool) bool {
+	return flag || (cfg.Deploy != nil && cfg.Deploy.PrePull)
+}
+
+// prePullImages loads the runner and dind images of installations that are
+// missing on the cluster node into it. Failures only slow down the first
+// jobs, so they are logged instead of failing the deployment.
+func prePullImages(ctx context.Context, clusterMgr *cluster.Manager, runnerMgr *runner.Manager, installations map[string]*types.RunnerInstallation) {
+	var images []string
+	for _, installation := range installations {
+		images = append(images, templates.InstallationImages(installation)...)
+	}
+	slices.Sort(images)
+
+	missing, err := runnerMgr.MissingImages(ctx, slices.Compact(images))
+	if err != nil {
+		slog.Warn("Failed to check the images of the cluster node", "error", err)
+		return
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	phase := progress.Start("Pre-pulling %s", strings.Join(missing, ", "))
+	err = clusterMgr.PrePullImages(ctx, missing)
+	phase.End(err)
+	if err != nil {
+		slog.Warn("Failed to pre-pull images", "error", err)
+	}
+}
+
+// deployTimeout parses the deploy timeout of the config, 0 if it has none
+func deployTimeout(settings *types.DeploySettings) (time.Duration, error) {
+	if settings.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(settings.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid deploy timeout '%s': must be a positive duration, e.g. 30m", settings.Timeout)
+	}
+	return timeout, nil
+}
+
+// validateDeployConfig checks the deploy defaults of the config
+func validateDeployConfig(cfg *config.Config) []error {
+	if cfg.Deploy == nil {
+		return nil
+	}
+	if _, err := deployTimeout(cfg.Deploy); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// withOverlays returns copies of the installations with extra overlays
+// applied after their own, leaving the configured installations unchanged
+func withOverlays(installations map[string]*types.RunnerInstallation, overlays []string) map[string]*types.RunnerInstallation {
+	if len(overlays) == 0 {
+		return installations
+	}
+
+	result := make(map[string]*types.RunnerInstallation, len(installations))
+	for name, installation := range installations {
+		withExtra := *installation
+		withExtra.Overlays = append(slices.Clone(installation.Overlays), overlays...)
+		result[name] = &withExtra
+	}
+	return result
+}
+
+// installationToDeploy returns the installation as it is deployed: with its
+// cache volumes mounted, the proxy, cache server and offline mode of its
+// cluster and the credentials of the configured registries applied
+func installationToDeploy(cfg *config.Config, installation *types.RunnerInstallation) *types.RunnerInstallation {
+	deployed := installationWithProxy(cfg, installationWithCacheVolumes(cfg, installation))
+	useCacheServer := cfg.ClusterCacheServer(cfg.ClusterFor(installation)) != nil
+	offline := cfg.ClusterOffline(cfg.ClusterFor(installation))
+	minRunners, maxRunners := scheduledLimits(installation, time.Now())
+	scheduled := minRunners != installation.MinRunners || maxRunners != installation.MaxRunners
+	if len(cfg.Registries) == 0 && !useCacheServer && !offline && !scheduled {
+		return deployed
+	}
+
+	withClusterSettings := *deployed
+	if len(cfg.Registries) > 0 {
+		withClusterSettings.ImagePullSecret = runner.RegistrySecretName
+	}
+	if useCacheServer {
+		withClusterSettings.ActionsCacheURL = runner.ActionsCacheURL
+	}
+	if offline {
+		// Images tagged latest are pulled on every start otherwise
+		withClusterSettings.ImagePullPolicy = "IfNotPresent"
+	}
+	if scheduled {
+		// Deploy with the limits 'deskrun agent' keeps during the current window
+		withClusterSettings.MinRunners = minRunners
+		withClusterSettings.MaxRunners = maxRunners
+	}
+	return &withClusterSettings
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/kapp"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+var _ = Describe("Up Command", func() {
+	Describe("deployInstallations", func() {
+		It("deploys every installation, at most maxParallelDeploys at a time", func() {
+			var mu sync.Mutex
+			var deployed []string
+			var running, peak atomic.Int32
+
+			names := []string{"a", "b", "c", "d", "e", "f"}
+			failures := deployInstallations(names, func(name string) error {
+				current := running.Add(1)
+				defer running.Add(-1)
+				for {
+					old := peak.Load()
+					if current <= old || peak.CompareAndSwap(old, current) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				deployed = append(deployed, name)
+				mu.Unlock()
+				return nil
+			})
+
+			Expect(failures).To(BeEmpty())
+			Expect(deployed).To(ConsistOf(names))
+			Expect(peak.Load()).To(BeNumerically("<=", maxParallelDeploys))
+		})
+
+		It("keeps deploying after a failure and reports the failures in order", func() {
+			failures := deployInstallations([]string{"a", "b", "c"}, func(name string) error {
+				if name == "a" || name == "c" {
+					return errors.New("kapp deploy timed out")
+				}
+				return nil
+			})
+
+			Expect(failures).To(HaveLen(2))
+			Expect(failures[0].Name).To(Equal("a"))
+			Expect(failures[1].Name).To(Equal("c"))
+		})
+	})
+
+	Describe("kappApplyOptions", func() {
+		It("defaults to kapp's timeout and waits", func() {
+			opts, err := kappApplyOptions(&config.Config{}, 0, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(Equal(kapp.ApplyOptions{Timeout: kapp.DefaultApplyTimeout}))
+		})
+
+		It("uses the config defaults unless overridden by the flags", func() {
+			cfg := &config.Config{Deploy: &types.DeploySettings{Timeout: "30m", NoWait: true}}
+
+			opts, err := kappApplyOptions(cfg, 0, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts).To(Equal(kapp.ApplyOptions{Timeout: 30 * time.Minute, NoWait: true}))
+
+			opts, err = kappApplyOptions(cfg, time.Hour, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(opts.Timeout).To(Equal(time.Hour))
+		})
+
+		It("rejects invalid timeouts", func() {
+			_, err := kappApplyOptions(&config.Config{Deploy: &types.DeploySettings{Timeout: "soon"}}, 0, false)
+			Expect(err).To(MatchError(ContainSubstring("invalid deploy timeout 'soon'")))
+
+			_, err = kappApplyOptions(&config.Config{}, -time.Minute, false)
+			Expect(err).To(MatchError(ContainSubstring("invalid --deploy-timeout")))
+		})
+	})
+
+	It("pre-pulls images when enabled with the flag or in the config", func() {
+		Expect(prePull(&config.Config{}, false)).To(BeFalse())
+		Expect(prePull(&config.Config{}, true)).To(BeTrue())
+		Expect(prePull(&config.Config{Deploy: &types.DeploySettings{PrePull: true}}, false)).To(BeTrue())
+	})
+
+	It("finds the deployed scale sets that are no longer configured", func() {
+		installations := map[string]*types.RunnerInstallation{
+			"a": {Name: "a", Instances: 2},
+			"b": {Name: "b"},
+		}
+		Expect(removedScaleSets([]string{"a-1", "a-2", "a-3", "b", "old"}, installations)).To(Equal([]string{"a-3", "old"}))
+	})
+
+	It("applies extra overlays after the configured ones without changing the config", func() {
+		configured := &types.RunnerInstallation{Name: "a", Overlays: []string{"/overlays/team.yaml"}}
+		installations := map[string]*types.RunnerInstallation{"a": configured}
+
+		Expect(withOverlays(installations, nil)["a"]).To(BeIdenticalTo(configured))
+
+		deployed := withOverlays(installations, []string{"/overlays/debug.yaml"})
+		Expect(deployed["a"].Overlays).To(Equal([]string{"/overlays/team.yaml", "/overlays/debug.yaml"}))
+		Expect(configured.Overlays).To(Equal([]string{"/overlays/team.yaml"}))
+	})
+
+	It("waits for running jobs unless the prompt is answered with no", func() {
+		Expect(waitConfirmed("")).To(BeTrue())
+		Expect(waitConfirmed("y")).To(BeTrue())
+		Expect(waitConfirmed("n")).To(BeFalse())
+		Expect(waitConfirmed("no")).To(BeFalse())
+	})
+
+	It("prints a summary of the failed installations", func() {
+		var out bytes.Buffer
+		printDeployFailures(&out, "deskrun", []deployFailure{{Name: "gpu", Err: errors.New("cluster does not exist")}})
+		Expect(out.String()).To(Equal("\nFailed to deploy 1 runner(s) to cluster 'deskrun':\n  ✗ gpu: cluster does not exist\n"))
+	})
+})
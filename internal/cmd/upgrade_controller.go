@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/config"
+	"github.com/rkoster/deskrun/internal/kapp"
+	"github.com/rkoster/deskrun/internal/runner"
+	"github.com/rkoster/deskrun/pkg/templates"
+	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
+)
+
+// defaultControllerVersion is the --controller-version value that removes the version pin
+const defaultControllerVersion = "default"
+
+var (
+	upgradeControllerCluster string
+	upgradeControllerDiff    bool
+	upgradeControllerForce   bool
+	upgradeControllerVersion string
+	upgradeControllerTimeout time.Duration
+	upgradeControllerNoWait  bool
+)
+
+var upgradeControllerCmd = &cobra.Command{
+	Use:   "upgrade-controller",
+	Short: "Upgrade the ARC controller to the version bundled with deskrun",
+	Long: `Upgrade the GitHub Actions Runner Controller (ARC) of a cluster to the
+version bundled with this deskrun release.
+
+'deskrun up' only installs the controller when it is missing, so clusters keep
+running the controller version they were created with. This command re-renders
+the bundled controller and deploys it with kapp, which shows the changes and
+only applies the resources that differ. The deployed version is recorded on
+the kapp app.
+
+Clusters that already run the target version are skipped unless --force is
+given, which re-applies the controller, e.g. to restore its RBAC rules or to
+apply changed proxy settings of the cluster.
+Downgrades also require --force, unless a version is pinned.
+
+deskrun embeds several ARC chart versions. --controller-version pins one of
+them in the config, so 'deskrun up' and later upgrades keep using it; this
+is how you roll back when a new ARC release breaks your runners. Pass
+'--controller-version default' to remove the pin and follow the version
+bundled with deskrun again.
+
+Examples:
+  deskrun upgrade-controller                                # Upgrade all clusters
+  deskrun upgrade-controller --diff                         # Only show the changes
+  deskrun upgrade-controller --cluster deskrun-gpu          # Upgrade one cluster
+  deskrun upgrade-controller --force                        # Re-apply the current version
+  deskrun upgrade-controller --controller-version 0.13.0    # Pin and deploy a version
+  deskrun upgrade-controller --controller-version default   # Remove the pin
+`,
+	RunE: runUpgradeController,
+}
+
+func init() {
+	upgradeControllerCmd.Flags().StringVar(&upgradeControllerCluster, "cluster", "", "Only upgrade this cluster (defaults to all known clusters)")
+	upgradeControllerCmd.Flags().BoolVar(&upgradeControllerDiff, "diff", false, "Show the changes without applying them")
+	upgradeControllerCmd.Flags().BoolVar(&upgradeControllerForce, "force", false, "Deploy even if the cluster runs the same or a newer version")
+	upgradeControllerCmd.Flags().DurationVar(&upgradeControllerTimeout, "deploy-timeout", 0, "How long kapp applies and waits for the controller resources (default 15m, or the config default)")
+	upgradeControllerCmd.Flags().BoolVar(&upgradeControllerNoWait, "no-wait", false, "Return once the controller resources are applied, without waiting for them to reconcile")
+	upgradeControllerCmd.Flags().StringVar(&upgradeControllerVersion, "controller-version", "", "Pin this embedded ARC chart version ('default' removes the pin)")
+	rootCmd.AddCommand(upgradeControllerCmd)
+}
+
+func runUpgradeController(cmd *cobra.Command, args []string) error {
+	// Load config
+	configMgr, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	cfg := configMgr.GetConfig()
+
+	chartVersion := cfg.ControllerVersion
+	if upgradeControllerVersion != "" {
+		chartVersion, err = resolveControllerVersion(upgradeControllerVersion, "")
+		if err != nil {
+			return err
+		}
+		if !upgradeControllerDiff && chartVersion != cfg.ControllerVersion {
+			cfg.ControllerVersion = chartVersion
+			if err := configMgr.Save(); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+			if chartVersion == "" {
+				fmt.Println("Removed the ARC controller version pin")
+			} else {
+				fmt.Printf("Pinned ARC controller version %s\n", chartVersion)
+			}
+		}
+	}
+
+	target, err := runner.EmbeddedControllerVersion(chartVersion)
+	if err != nil {
+		return fmt.Errorf("failed to determine target controller version: %w", err)
+	}
+
+	applyOpts, err := kappApplyOptions(cfg, upgradeControllerTimeout, upgradeControllerNoWait)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), max(20*time.Minute, applyOpts.Timeout+5*time.Minute))
+	defer cancel()
+
+	for _, clusterName := range targetClusters(cfg, upgradeControllerCluster) {
+		if err := upgradeClusterController(ctx, cfg, clusterName, chartVersion, target, applyOpts); err != nil {
+			return fmt.Errorf("cluster '%s': %w", clusterName, err)
+		}
+	}
+
+	return nil
+}
+
+// upgradeClusterController upgrades the ARC controller of one cluster to the
+// target version of the given embedded chart version (empty for the default),
+// configured with the proxy of the cluster and deployed with applyOpts
+func upgradeClusterController(ctx context.Context, cfg *config.Config, clusterName, chartVersion, target string, applyOpts kapp.ApplyOptions) error {
+	proxy := cfg.ClusterProxy(clusterName)
+	clusterMgr := newClusterManager(cfg, clusterName)
+
+	exists, err := clusterMgr.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		fmt.Printf("Cluster '%s' does not exist, skipping\n", clusterName)
+		return nil
+	}
+
+	runnerMgr := runner.NewManager(clusterMgr).
+		WithControllerVersion(chartVersion).
+		WithControllerProxy(proxy).
+		WithApplyOptions(applyOpts)
+
+	deployed, err := runnerMgr.DeployedControllerVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if deployed == "" {
+		fmt.Printf("Cluster '%s': ARC controller is not installed, it will be installed by 'deskrun up'\n", clusterName)
+		return nil
+	}
+
+	if skip, reason := skipControllerUpgrade(deployed, target, chartVersion != "", upgradeControllerForce); skip {
+		fmt.Printf("Cluster '%s': %s\n", clusterName, reason)
+		return nil
+	}
+
+	if upgradeControllerDiff {
+		slog.Info("Changes to upgrade ARC controller", "cluster", clusterName, "from", deployed, "to", target)
+	} else {
+		slog.Info("Upgrading ARC controller", "cluster", clusterName, "from", deployed, "to", target)
+	}
+
+	if err := runnerMgr.UpgradeController(ctx, runner.ControllerUpgradeOptions{DiffOnly: upgradeControllerDiff}); err != nil {
+		return err
+	}
+
+	if !upgradeControllerDiff {
+		fmt.Printf("✓ ARC controller of cluster '%s' is at version %s\n", clusterName, target)
+	}
+	return nil
+}
+
+// skipControllerUpgrade decides whether the deployed controller should be left
+// alone: when it already runs the target version, or a newer one while the
+// target version is not pinned, unless forced
+func skipControllerUpgrade(deployed, target string, pinned, force bool) (bool, string) {
+	if force {
+		return false, ""
+	}
+
+	switch cmp := compareVersions(deployed, target); {
+	case cmp == 0:
+		return true, fmt.Sprintf("ARC controller is up to date (%s)", deployed)
+	case cmp > 0 && !pinned:
+		return true, fmt.Sprintf("ARC controller %s is newer than the bundled %s, use --force to downgrade", deployed, target)
+	default:
+		return false, ""
+	}
+}
+
+// resolveControllerVersion returns the embedded ARC chart version selected by
+// the flag, falling back to the pinned version. An empty result or the value
+// "default" selects the default version bundled with deskrun.
+func resolveControllerVersion(flag, pinned string) (string, error) {
+	version := flag
+	if version == "" {
+		version = pinned
+	}
+	version = strings.TrimPrefix(version, "v")
+	if version == "" || version == defaultControllerVersion {
+		return "", nil
+	}
+
+	versions, err := templates.ControllerVersions()
+	if err != nil {
+		return "", fmt.Errorf("failed to list embedded controller versions: %w", err)
+	}
+	for _, embedded := range versions {
+		if embedded == version {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("ARC controller version %s is not embedded in this deskrun release (available: %s)",
+		version, strings.Join(versions, ", "))
+}
+
+// compareVersions compares two semantic versions with or without a leading v.
+// Invalid versions sort before valid ones.
+func compareVersions(a, b string) int {
+	canonical := func(version string) string {
+		if len(version) > 0 && version[0] != 'v' {
+			return "v" + version
+		}
+		return version
+	}
+	return semver.Compare(canonical(a), canonical(b))
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Upgrade Controller Command", func() {
+	Describe("skipControllerUpgrade", func() {
+		DescribeTable("upgrade decisions",
+			func(deployed, target string, pinned, force, expectedSkip bool, expectedReason string) {
+				skip, reason := skipControllerUpgrade(deployed, target, pinned, force)
+				Expect(skip).To(Equal(expectedSkip))
+				Expect(reason).To(ContainSubstring(expectedReason))
+			},
+			Entry("older deployed version", "0.12.1", "0.13.0", false, false, false, ""),
+			Entry("same version", "0.13.0", "0.13.0", false, false, true, "up to date"),
+			Entry("newer deployed version", "0.14.0", "0.13.0", false, false, true, "use --force to downgrade"),
+			Entry("same version with force", "0.13.0", "0.13.0", false, true, false, ""),
+			Entry("newer deployed version with force", "0.14.0", "0.13.0", false, true, false, ""),
+			Entry("newer deployed version with pinned target", "0.14.0", "0.13.0", true, false, false, ""),
+			Entry("same version with pinned target", "0.13.0", "0.13.0", true, false, true, "up to date"),
+		)
+	})
+
+	Describe("resolveControllerVersion", func() {
+		It("should prefer the flag over the pinned version", func() {
+			Expect(resolveControllerVersion("0.13.0", "0.12.1")).To(Equal("0.13.0"))
+		})
+
+		It("should fall back to the pinned version", func() {
+			Expect(resolveControllerVersion("", "v0.13.0")).To(Equal("0.13.0"))
+		})
+
+		It("should select the bundled version when nothing is pinned", func() {
+			Expect(resolveControllerVersion("", "")).To(BeEmpty())
+			Expect(resolveControllerVersion("default", "0.13.0")).To(BeEmpty())
+		})
+
+		It("should reject versions that are not embedded", func() {
+			_, err := resolveControllerVersion("0.1.0", "")
+			Expect(err).To(MatchError(ContainSubstring("not embedded")))
+			Expect(err).To(MatchError(ContainSubstring("0.13.0")))
+		})
+	})
+
+	Describe("compareVersions", func() {
+		It("should compare versions with and without a v prefix", func() {
+			Expect(compareVersions("0.9.3", "0.13.0")).To(Equal(-1))
+			Expect(compareVersions("v0.13.0", "0.13.0")).To(Equal(0))
+			Expect(compareVersions("0.13.1", "v0.13.0")).To(Equal(1))
+		})
+	})
+})
@@ -2,29 +2,257 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
+	"github.com/rkoster/deskrun/internal/secrets"
 	"github.com/rkoster/deskrun/pkg/types"
 )
 
 const (
 	configDirName  = ".deskrun"
-	configFileName = "config.json"
+	configFileName = "config.yaml"
+	// legacyConfigFileName is the JSON config of older deskrun versions, which
+	// is migrated to configFileName on first use
+	legacyConfigFileName = "config.json"
+
+	defaultClusterName = "deskrun"
+
+	// CurrentVersion is the version of the config file format written by this
+	// deskrun. Files without a version are JSON configs of older versions.
+	CurrentVersion = 1
 )
 
-// Config represents the deskrun configuration
+// Config represents the deskrun configuration. It is stored as YAML in
+// ~/.deskrun/config.yaml, with the field names of the json tags:
+//
+//	version: 1                  # config format version
+//	cluster_name: deskrun       # default cluster of installations
+//	installations:              # runner installations by name
+//	  my-runner:
+//	    Name: my-runner
+//	    Repository: https://github.com/owner/repo
+//	    ContainerMode: kubernetes   # kubernetes, dind or cached-privileged-kubernetes
+//	    MinRunners: 1
+//	    MaxRunners: 5
+//	    Instances: 1
+//	    AuthType: pat               # pat or github-app
+//	    Mounts:
+//	      - Source: /var/lib/deskrun/cache
+//	        Target: /cache
+//	        Type: DirectoryOrCreate
+//	cluster_hosts: {}           # incus cluster hosts by name
+//	clusters: {}                # per-cluster settings by cluster name
+//	secret_store: keyring       # keyring, file or plaintext
+//	controller_version: ""      # ARC controller chart version
+//	registries: {}              # private registry credentials by name
+//	cluster_provider: kind      # kind, k3d or minikube
+//	mirrors: []                 # registries pulled through the local cache
+//	cache_volumes:              # shared cache volumes by name
+//	  npm:
+//	    name: npm
+//	    path: /home/runner/.npm
+//	agent:                      # policies of 'deskrun agent'
+//	  pause_on_battery: true
+//	  max_load: 1.5
+//
+// Auth values and registry passwords are kept in the secret store, unless it
+// is plaintext. Run 'deskrun config validate' to check a hand-edited file.
 type Config struct {
+	// Version is the config format version, see CurrentVersion
+	Version       int                                  `json:"version"`
 	ClusterName   string                               `json:"cluster_name"`
 	Installations map[string]*types.RunnerInstallation `json:"installations"`
 	ClusterHosts  map[string]*types.ClusterHost        `json:"cluster_hosts,omitempty"`
+	Clusters      map[string]*types.ClusterSettings    `json:"clusters,omitempty"`
+	// SecretStore is the backend auth values are kept in (keyring, file or plaintext)
+	SecretStore secrets.Backend `json:"secret_store,omitempty"`
+	// ControllerVersion pins the embedded ARC controller chart version (empty for the default)
+	ControllerVersion string `json:"controller_version,omitempty"`
+	// Registries are the private container registries runner and job images are pulled from
+	Registries map[string]*types.RegistryCredential `json:"registries,omitempty"`
+	// ClusterProvider provisions the local clusters: kind (default), k3d or minikube
+	ClusterProvider string `json:"cluster_provider,omitempty"`
+	// Mirrors are the registries (e.g. docker.io) pulled through a local pull-through cache
+	Mirrors []string `json:"mirrors,omitempty"`
+	// Agent holds the policies 'deskrun agent' pauses and resumes installations by
+	Agent *types.AgentConfig `json:"agent,omitempty"`
+	// CacheVolumes are the shared cache volumes installations mount by name
+	CacheVolumes map[string]*types.CacheVolume `json:"cache_volumes,omitempty"`
+	// Deploy holds the defaults of how kapp deploys the runners and the controller
+	Deploy *types.DeploySettings `json:"deploy,omitempty"`
+}
+
+// DefaultCluster returns the name of the cluster used by installations
+// that are not pinned to a specific cluster
+func (c *Config) DefaultCluster() string {
+	if c.ClusterName == "" {
+		return defaultClusterName
+	}
+	return c.ClusterName
+}
+
+// ClusterFor returns the name of the cluster an installation is deployed to
+func (c *Config) ClusterFor(installation *types.RunnerInstallation) string {
+	if installation.Cluster != "" {
+		return installation.Cluster
+	}
+	return c.DefaultCluster()
+}
+
+// ClusterNames returns the sorted names of all known clusters: the default
+// cluster, all registered clusters and the clusters installations are pinned to
+func (c *Config) ClusterNames() []string {
+	seen := map[string]bool{c.DefaultCluster(): true}
+	for name := range c.Clusters {
+		seen[name] = true
+	}
+	for _, installation := range c.Installations {
+		seen[c.ClusterFor(installation)] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ClusterProxy returns the proxy configured for a cluster, or nil if it has none
+func (c *Config) ClusterProxy(clusterName string) *types.ProxyConfig {
+	if settings := c.Clusters[clusterName]; settings != nil && !settings.Proxy.IsEmpty() {
+		return settings.Proxy
+	}
+	return nil
+}
+
+// ProxyFor returns the proxy an installation uses: its own proxy settings,
+// falling back to the proxy of the cluster it is deployed to
+func (c *Config) ProxyFor(installation *types.RunnerInstallation) *types.ProxyConfig {
+	if !installation.Proxy.IsEmpty() {
+		return installation.Proxy
+	}
+	return c.ClusterProxy(c.ClusterFor(installation))
+}
+
+// ClusterCacheServer returns the cache server settings of a cluster, or nil if
+// its cache server is disabled
+func (c *Config) ClusterCacheServer(clusterName string) *types.CacheServerConfig {
+	if settings := c.Clusters[clusterName]; settings != nil {
+		return settings.CacheServer
+	}
+	return nil
+}
+
+// ClusterObservability returns the metrics stack settings of a cluster, or
+// nil if observability is disabled
+func (c *Config) ClusterObservability(clusterName string) *types.ObservabilityConfig {
+	if settings := c.Clusters[clusterName]; settings != nil {
+		return settings.Observability
+	}
+	return nil
+}
+
+// ClusterOffline reports whether a cluster runs from the images loaded into
+// its node, without registry access
+func (c *Config) ClusterOffline(clusterName string) bool {
+	if settings := c.Clusters[clusterName]; settings != nil {
+		return settings.Offline
+	}
+	return false
+}
+
+// ClusterKubernetesVersion returns the Kubernetes version a cluster is created
+// with, or an empty string for the default of kind
+func (c *Config) ClusterKubernetesVersion(clusterName string) string {
+	if settings := c.Clusters[clusterName]; settings != nil {
+		return settings.KubernetesVersion
+	}
+	return ""
+}
+
+// ClusterKubeContext returns the kube context of an existing cluster deskrun
+// deploys to instead of kind, or an empty string for kind clusters
+func (c *Config) ClusterKubeContext(clusterName string) string {
+	if settings := c.Clusters[clusterName]; settings != nil {
+		return settings.KubeContext
+	}
+	return ""
+}
+
+// ClusterPortMappings returns the ports of a cluster's node exposed on the host
+func (c *Config) ClusterPortMappings(clusterName string) []types.PortMapping {
+	if settings := c.Clusters[clusterName]; settings != nil {
+		return settings.PortMappings
+	}
+	return nil
+}
+
+// SortedRegistries returns the configured registries sorted by name
+func (c *Config) SortedRegistries() []*types.RegistryCredential {
+	names := make([]string, 0, len(c.Registries))
+	for name := range c.Registries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	registries := make([]*types.RegistryCredential, 0, len(names))
+	for _, name := range names {
+		registries = append(registries, c.Registries[name])
+	}
+	return registries
+}
+
+// SortedCacheVolumes returns the shared cache volumes sorted by name
+func (c *Config) SortedCacheVolumes() []*types.CacheVolume {
+	volumes := make([]*types.CacheVolume, 0, len(c.CacheVolumes))
+	for _, volume := range c.CacheVolumes {
+		volumes = append(volumes, volume)
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].Name < volumes[j].Name
+	})
+	return volumes
+}
+
+// InstallationsForCluster returns the installations deployed to the given cluster
+func (c *Config) InstallationsForCluster(clusterName string) map[string]*types.RunnerInstallation {
+	installations := make(map[string]*types.RunnerInstallation)
+	for name, installation := range c.Installations {
+		if c.ClusterFor(installation) == clusterName {
+			installations[name] = installation
+		}
+	}
+	return installations
+}
+
+// ClusterNamespaces returns the sorted namespaces the installations of the
+// given cluster are deployed to
+func (c *Config) ClusterNamespaces(clusterName string) []string {
+	var namespaces []string
+	for _, installation := range c.InstallationsForCluster(clusterName) {
+		if namespace := installation.GetNamespace(); !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
 }
 
 // Manager handles configuration persistence
 type Manager struct {
 	configPath string
 	config     *Config
+	secrets    secrets.Store
+	// storedSecrets caches the values known to be in the secret store so
+	// unchanged secrets are not rewritten on every save
+	storedSecrets map[string]string
 }
 
 // NewManager creates a new configuration manager
@@ -40,34 +268,140 @@ func NewManager() (*Manager, error) {
 	}
 
 	configPath := filepath.Join(configDir, configFileName)
+	legacyConfigPath := filepath.Join(configDir, legacyConfigFileName)
 
 	m := &Manager{
 		configPath: configPath,
 	}
 
+	// Load the JSON config of older versions until it has been migrated to YAML
+	migrateLegacy := false
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if _, err := os.Stat(legacyConfigPath); err == nil {
+			m.configPath = legacyConfigPath
+			migrateLegacy = true
+		}
+	}
+
 	if err := m.Load(); err != nil {
 		// If config doesn't exist, initialize with empty config
-		if os.IsNotExist(err) {
-			m.config = &Config{
-				ClusterName:   "deskrun",
-				Installations: make(map[string]*types.RunnerInstallation),
-				ClusterHosts:  make(map[string]*types.ClusterHost),
-			}
-			return m, nil
+		if !os.IsNotExist(err) {
+			return nil, err
 		}
+		m.config = &Config{
+			Version:       CurrentVersion,
+			ClusterName:   defaultClusterName,
+			Installations: make(map[string]*types.RunnerInstallation),
+			ClusterHosts:  make(map[string]*types.ClusterHost),
+			Clusters:      make(map[string]*types.ClusterSettings),
+		}
+	}
+
+	m.configPath = configPath
+	if err := m.initSecretStore(configDir); err != nil {
 		return nil, err
 	}
 
+	if migrateLegacy {
+		if err := m.Save(); err != nil {
+			return nil, fmt.Errorf("failed to migrate %s: %w", legacyConfigPath, err)
+		}
+		if err := os.Remove(legacyConfigPath); err != nil {
+			return nil, fmt.Errorf("failed to remove %s after migrating it: %w", legacyConfigPath, err)
+		}
+	}
+
 	return m, nil
 }
 
-// Load loads the configuration from disk
+// initSecretStore sets up the secret store of the config, reads the auth
+// values of all installations and the registry passwords from it and moves
+// plaintext values of older configs into the store
+func (m *Manager) initSecretStore(configDir string) error {
+	if m.config.SecretStore == "" {
+		m.config.SecretStore = secrets.DefaultBackend()
+	}
+
+	store, err := secrets.NewStore(m.config.SecretStore, configDir)
+	if err != nil {
+		return err
+	}
+	m.secrets = store
+	m.storedSecrets = make(map[string]string)
+
+	if m.secrets == nil {
+		return nil
+	}
+
+	needsMigration := false
+	for name, installation := range m.config.Installations {
+		if installation.AuthValue != "" {
+			// Plaintext auth value from a config written before the secret store
+			needsMigration = true
+			continue
+		}
+
+		value, err := m.secrets.Get(authValueKey(name))
+		if errors.Is(err, secrets.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read auth value of installation %s: %w", name, err)
+		}
+		installation.AuthValue = value
+		m.storedSecrets[authValueKey(name)] = value
+	}
+
+	for name, registry := range m.config.Registries {
+		if registry.Password != "" {
+			// Plaintext password, e.g. from a hand-edited config
+			needsMigration = true
+			continue
+		}
+
+		value, err := m.secrets.Get(registryPasswordKey(name))
+		if errors.Is(err, secrets.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read password of registry %s: %w", name, err)
+		}
+		registry.Password = value
+		m.storedSecrets[registryPasswordKey(name)] = value
+	}
+
+	if needsMigration {
+		if err := m.Save(); err != nil {
+			return fmt.Errorf("failed to move auth values to the %s secret store: %w", m.config.SecretStore, err)
+		}
+	}
+
+	return nil
+}
+
+// authValueKey returns the secret store key of an installation's auth value
+func authValueKey(installationName string) string {
+	return fmt.Sprintf("installation/%s/auth-value", installationName)
+}
+
+// registryPasswordKey returns the secret store key of a registry's password
+func registryPasswordKey(registryName string) string {
+	return fmt.Sprintf("registry/%s/password", registryName)
+}
+
+// Load loads the configuration from disk. Both the YAML format and the JSON
+// format of older versions are read.
 func (m *Manager) Load() error {
-	data, err := os.ReadFile(m.configPath)
+	fileData, err := os.ReadFile(m.configPath)
 	if err != nil {
 		return err
 	}
 
+	data, err := yamlToJSON(fileData)
+	if err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
 	// First, try to unmarshal into a temporary structure that can handle both old and new formats
 	var rawConfig map[string]interface{}
 	if err := json.Unmarshal(data, &rawConfig); err != nil {
@@ -115,6 +449,9 @@ func (m *Manager) Load() error {
 	if err := json.Unmarshal(data, m.config); err != nil {
 		return fmt.Errorf("failed to parse config: %w", err)
 	}
+	if err := checkVersion(m.config.Version); err != nil {
+		return err
+	}
 
 	if m.config.Installations == nil {
 		m.config.Installations = make(map[string]*types.RunnerInstallation)
@@ -124,6 +461,10 @@ func (m *Manager) Load() error {
 		m.config.ClusterHosts = make(map[string]*types.ClusterHost)
 	}
 
+	if m.config.Clusters == nil {
+		m.config.Clusters = make(map[string]*types.ClusterSettings)
+	}
+
 	return nil
 }
 
@@ -164,6 +505,8 @@ func (m *Manager) migrateConfig(data []byte) error {
 	m.config = &Config{
 		ClusterName:   oldConfig.ClusterName,
 		Installations: make(map[string]*types.RunnerInstallation),
+		ClusterHosts:  make(map[string]*types.ClusterHost),
+		Clusters:      make(map[string]*types.ClusterSettings),
 	}
 
 	for name, oldInstallation := range oldConfig.Installations {
@@ -193,11 +536,42 @@ func (m *Manager) migrateConfig(data []byte) error {
 	return nil
 }
 
-// Save saves the configuration to disk
+// checkVersion returns an error for files written for a newer deskrun
+func checkVersion(version int) error {
+	if version > CurrentVersion {
+		return fmt.Errorf("config version %d is not supported by this deskrun (up to version %d), please upgrade deskrun", version, CurrentVersion)
+	}
+	return nil
+}
+
+// marshalConfig encodes a config as YAML in the current format version
+func marshalConfig(cfg *Config) ([]byte, error) {
+	versioned := *cfg
+	versioned.Version = CurrentVersion
+
+	data, err := json.Marshal(&versioned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	data, err = jsonToYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return data, nil
+}
+
+// Save saves the configuration to disk. Auth values and registry passwords are
+// written to the secret store and left out of the config file.
 func (m *Manager) Save() error {
-	data, err := json.MarshalIndent(m.config, "", "  ")
+	persisted, err := m.persistedConfig()
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
+	}
+
+	m.config.Version = CurrentVersion
+	data, err := marshalConfig(persisted)
+	if err != nil {
+		return err
 	}
 
 	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
@@ -207,6 +581,49 @@ func (m *Manager) Save() error {
 	return nil
 }
 
+// persistedConfig returns the config as it is written to disk: with a secret
+// store, auth values are moved into the store and blanked in a copy of the config
+func (m *Manager) persistedConfig() (*Config, error) {
+	if m.secrets == nil {
+		return m.config, nil
+	}
+
+	persisted := *m.config
+	persisted.Installations = make(map[string]*types.RunnerInstallation, len(m.config.Installations))
+	for name, installation := range m.config.Installations {
+		key := authValueKey(name)
+		if installation.AuthValue != "" && m.storedSecrets[key] != installation.AuthValue {
+			if err := m.secrets.Set(key, installation.AuthValue); err != nil {
+				return nil, fmt.Errorf("failed to store auth value of installation %s: %w", name, err)
+			}
+			m.storedSecrets[key] = installation.AuthValue
+		}
+
+		redacted := *installation
+		redacted.AuthValue = ""
+		persisted.Installations[name] = &redacted
+	}
+
+	if m.config.Registries != nil {
+		persisted.Registries = make(map[string]*types.RegistryCredential, len(m.config.Registries))
+	}
+	for name, registry := range m.config.Registries {
+		key := registryPasswordKey(name)
+		if registry.Password != "" && m.storedSecrets[key] != registry.Password {
+			if err := m.secrets.Set(key, registry.Password); err != nil {
+				return nil, fmt.Errorf("failed to store password of registry %s: %w", name, err)
+			}
+			m.storedSecrets[key] = registry.Password
+		}
+
+		redacted := *registry
+		redacted.Password = ""
+		persisted.Registries[name] = &redacted
+	}
+
+	return &persisted, nil
+}
+
 // GetConfig returns the current configuration
 func (m *Manager) GetConfig() *Config {
 	return m.config
@@ -222,6 +639,16 @@ func (m *Manager) AddInstallation(installation *types.RunnerInstallation) error
 	return m.Save()
 }
 
+// UpdateInstallation replaces an existing runner installation in the config
+func (m *Manager) UpdateInstallation(installation *types.RunnerInstallation) error {
+	if m.config.Installations[installation.Name] == nil {
+		return fmt.Errorf("installation %s does not exist", installation.Name)
+	}
+
+	m.config.Installations[installation.Name] = installation
+	return m.Save()
+}
+
 // RemoveInstallation removes a runner installation from the config
 func (m *Manager) RemoveInstallation(name string) error {
 	if m.config.Installations[name] == nil {
@@ -229,7 +656,19 @@ func (m *Manager) RemoveInstallation(name string) error {
 	}
 
 	delete(m.config.Installations, name)
-	return m.Save()
+	if err := m.Save(); err != nil {
+		return err
+	}
+
+	if m.secrets != nil {
+		key := authValueKey(name)
+		if err := m.secrets.Delete(key); err != nil && !errors.Is(err, secrets.ErrNotFound) {
+			return fmt.Errorf("failed to delete auth value of installation %s: %w", name, err)
+		}
+		delete(m.storedSecrets, key)
+	}
+
+	return nil
 }
 
 // GetInstallation gets a runner installation by name
@@ -250,6 +689,16 @@ func (m *Manager) AddClusterHost(host *types.ClusterHost) error {
 	return m.Save()
 }
 
+// UpdateClusterHost saves the changed settings of an existing cluster host
+func (m *Manager) UpdateClusterHost(host *types.ClusterHost) error {
+	if m.config.ClusterHosts[host.Name] == nil {
+		return fmt.Errorf("cluster host %s does not exist", host.Name)
+	}
+
+	m.config.ClusterHosts[host.Name] = host
+	return m.Save()
+}
+
 func (m *Manager) RemoveClusterHost(name string) error {
 	if m.config.ClusterHosts[name] == nil {
 		return fmt.Errorf("cluster host %s does not exist", name)
@@ -267,6 +716,236 @@ func (m *Manager) GetClusterHost(name string) (*types.ClusterHost, error) {
 	return host, nil
 }
 
+// AddCluster registers a named cluster, doing nothing if it is already known
+func (m *Manager) AddCluster(name string) error {
+	if m.config.Clusters[name] != nil {
+		return nil
+	}
+
+	m.config.Clusters[name] = &types.ClusterSettings{Name: name}
+	return m.Save()
+}
+
+// SetClusterProxy sets the proxy of a cluster, registering the cluster if it is
+// not known yet. A nil or empty proxy removes the proxy settings.
+func (m *Manager) SetClusterProxy(name string, proxy *types.ProxyConfig) error {
+	settings := m.config.Clusters[name]
+	if settings == nil {
+		settings = &types.ClusterSettings{Name: name}
+		m.config.Clusters[name] = settings
+	}
+
+	settings.Proxy = nil
+	if !proxy.IsEmpty() {
+		settings.Proxy = proxy
+	}
+	return m.Save()
+}
+
+// SetClusterCacheServer enables the cache server of a cluster with the given
+// settings, registering the cluster if it is not known yet. A nil config
+// disables the cache server.
+func (m *Manager) SetClusterCacheServer(name string, cacheServer *types.CacheServerConfig) error {
+	settings := m.config.Clusters[name]
+	if settings == nil {
+		settings = &types.ClusterSettings{Name: name}
+		m.config.Clusters[name] = settings
+	}
+
+	settings.CacheServer = cacheServer
+	return m.Save()
+}
+
+// SetClusterObservability enables the metrics stack of a cluster with the
+// given settings, registering the cluster if it is not known yet. A nil config
+// disables observability.
+func (m *Manager) SetClusterObservability(name string, observability *types.ObservabilityConfig) error {
+	settings := m.config.Clusters[name]
+	if settings == nil {
+		settings = &types.ClusterSettings{Name: name}
+		m.config.Clusters[name] = settings
+	}
+
+	settings.Observability = observability
+	return m.Save()
+}
+
+// SetClusterKubernetesVersion sets the Kubernetes version a cluster is created
+// with, registering the cluster if it is not known yet. An empty version
+// restores the default of kind.
+func (m *Manager) SetClusterKubernetesVersion(name, version string) error {
+	settings := m.config.Clusters[name]
+	if settings == nil {
+		settings = &types.ClusterSettings{Name: name}
+		m.config.Clusters[name] = settings
+	}
+
+	settings.KubernetesVersion = version
+	return m.Save()
+}
+
+// SetClusterOffline switches a cluster to and from offline mode, registering
+// the cluster if it is not known yet
+func (m *Manager) SetClusterOffline(name string, offline bool) error {
+	settings := m.config.Clusters[name]
+	if settings == nil {
+		settings = &types.ClusterSettings{Name: name}
+		m.config.Clusters[name] = settings
+	}
+
+	settings.Offline = offline
+	return m.Save()
+}
+
+// SetClusterKubeContext makes deskrun deploy the cluster to an existing
+// cluster through the given kube context instead of kind, registering the
+// cluster if it is not known yet. An empty context switches back to kind.
+func (m *Manager) SetClusterKubeContext(name, kubeContext string) error {
+	settings := m.config.Clusters[name]
+	if settings == nil {
+		settings = &types.ClusterSettings{Name: name}
+		m.config.Clusters[name] = settings
+	}
+
+	settings.KubeContext = kubeContext
+	return m.Save()
+}
+
+// AddClusterPortMapping exposes a port of a cluster's node on the host,
+// registering the cluster if it is not known yet
+func (m *Manager) AddClusterPortMapping(name string, mapping types.PortMapping) error {
+	settings := m.config.Clusters[name]
+	if settings == nil {
+		settings = &types.ClusterSettings{Name: name}
+		m.config.Clusters[name] = settings
+	}
+
+	for _, existing := range settings.PortMappings {
+		if existing.HostPort == mapping.HostPort && existing.Protocol == mapping.Protocol {
+			return fmt.Errorf("host port %d is already mapped to %s", mapping.HostPort, existing)
+		}
+	}
+
+	settings.PortMappings = append(settings.PortMappings, mapping)
+	sort.Slice(settings.PortMappings, func(i, j int) bool {
+		return settings.PortMappings[i].HostPort < settings.PortMappings[j].HostPort
+	})
+	return m.Save()
+}
+
+// RemoveClusterPortMapping removes the mappings of a host port of a cluster
+func (m *Manager) RemoveClusterPortMapping(name string, hostPort int32) error {
+	settings := m.config.Clusters[name]
+	if settings == nil {
+		return fmt.Errorf("host port %d is not mapped", hostPort)
+	}
+
+	mappings := slices.DeleteFunc(slices.Clone(settings.PortMappings), func(mapping types.PortMapping) bool {
+		return mapping.HostPort == hostPort
+	})
+	if len(mappings) == len(settings.PortMappings) {
+		return fmt.Errorf("host port %d is not mapped", hostPort)
+	}
+
+	settings.PortMappings = mappings
+	return m.Save()
+}
+
+// AddMirror enables the pull-through cache of a registry, doing nothing if it
+// is already enabled
+func (m *Manager) AddMirror(registry string) error {
+	if slices.Contains(m.config.Mirrors, registry) {
+		return nil
+	}
+
+	m.config.Mirrors = append(m.config.Mirrors, registry)
+	sort.Strings(m.config.Mirrors)
+	return m.Save()
+}
+
+// RemoveMirror disables the pull-through cache of a registry
+func (m *Manager) RemoveMirror(registry string) error {
+	index := slices.Index(m.config.Mirrors, registry)
+	if index < 0 {
+		return fmt.Errorf("mirror of %s is not enabled", registry)
+	}
+
+	m.config.Mirrors = slices.Delete(m.config.Mirrors, index, index+1)
+	return m.Save()
+}
+
+// SetAgent stores the policies of 'deskrun agent', removing them if agent is nil
+func (m *Manager) SetAgent(agent *types.AgentConfig) error {
+	m.config.Agent = agent
+	return m.Save()
+}
+
+// SetRegistry adds a private container registry to the config, replacing the
+// registry of the same name
+func (m *Manager) SetRegistry(registry *types.RegistryCredential) error {
+	if m.config.Registries == nil {
+		m.config.Registries = make(map[string]*types.RegistryCredential)
+	}
+
+	m.config.Registries[registry.Name] = registry
+	return m.Save()
+}
+
+// RemoveRegistry removes a private container registry from the config
+func (m *Manager) RemoveRegistry(name string) error {
+	if m.config.Registries[name] == nil {
+		return fmt.Errorf("registry %s does not exist", name)
+	}
+
+	delete(m.config.Registries, name)
+	if err := m.Save(); err != nil {
+		return err
+	}
+
+	if m.secrets != nil {
+		key := registryPasswordKey(name)
+		if err := m.secrets.Delete(key); err != nil && !errors.Is(err, secrets.ErrNotFound) {
+			return fmt.Errorf("failed to delete password of registry %s: %w", name, err)
+		}
+		delete(m.storedSecrets, key)
+	}
+
+	return nil
+}
+
+// SetCacheVolume adds a shared cache volume to the config, replacing the
+// volume of the same name
+func (m *Manager) SetCacheVolume(volume *types.CacheVolume) error {
+	if m.config.CacheVolumes == nil {
+		m.config.CacheVolumes = make(map[string]*types.CacheVolume)
+	}
+
+	m.config.CacheVolumes[volume.Name] = volume
+	return m.Save()
+}
+
+// RemoveCacheVolume removes a shared cache volume from the config. Volumes
+// that installations still mount cannot be removed.
+func (m *Manager) RemoveCacheVolume(name string) error {
+	if m.config.CacheVolumes[name] == nil {
+		return fmt.Errorf("cache volume %s does not exist", name)
+	}
+
+	var users []string
+	for _, installation := range m.config.Installations {
+		if slices.Contains(installation.CacheVolumes, name) {
+			users = append(users, installation.Name)
+		}
+	}
+	if len(users) > 0 {
+		sort.Strings(users)
+		return fmt.Errorf("cache volume %s is used by installation(s) %s", name, strings.Join(users, ", "))
+	}
+
+	delete(m.config.CacheVolumes, name)
+	return m.Save()
+}
+
 // GetConfigPath returns the path to the config file
 func (m *Manager) GetConfigPath() string {
 	return m.configPath
@@ -3,11 +3,21 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/rkoster/deskrun/internal/secrets"
 	"github.com/rkoster/deskrun/pkg/types"
 )
 
+func TestMain(m *testing.M) {
+	// Never touch the OS keychain of the machine running the tests
+	_ = os.Setenv(secrets.BackendEnvVar, string(secrets.BackendFile))
+	os.Exit(m.Run())
+}
+
 func TestNewManager(t *testing.T) {
 	// Create temporary home directory
 	tmpHome, err := os.MkdirTemp("", "deskrun-test-*")
@@ -154,6 +164,59 @@ func TestRemoveInstallation(t *testing.T) {
 	}
 }
 
+func TestUpdateInstallation(t *testing.T) {
+	tmpHome, err := os.MkdirTemp("", "deskrun-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp home: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(tmpHome)
+	})
+
+	oldHome := os.Getenv("HOME")
+	if err := os.Setenv("HOME", tmpHome); err != nil {
+		t.Fatalf("Failed to set HOME: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Setenv("HOME", oldHome)
+	})
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	installation := &types.RunnerInstallation{
+		Name:          "test-runner",
+		Repository:    "https://github.com/owner/repo",
+		ContainerMode: types.ContainerModeKubernetes,
+		MaxRunners:    5,
+	}
+
+	// Updating a non-existent installation fails
+	if err := mgr.UpdateInstallation(installation); err == nil {
+		t.Error("UpdateInstallation() expected error for non-existent installation, got nil")
+	}
+
+	if err := mgr.AddInstallation(installation); err != nil {
+		t.Fatalf("AddInstallation() error = %v", err)
+	}
+
+	updated := *installation
+	updated.MaxRunners = 10
+	if err := mgr.UpdateInstallation(&updated); err != nil {
+		t.Fatalf("UpdateInstallation() error = %v", err)
+	}
+
+	saved, err := mgr.GetInstallation("test-runner")
+	if err != nil {
+		t.Fatalf("GetInstallation() error = %v", err)
+	}
+	if saved.MaxRunners != 10 {
+		t.Errorf("MaxRunners = %v, want 10", saved.MaxRunners)
+	}
+}
+
 func TestSaveAndLoad(t *testing.T) {
 	tmpHome, err := os.MkdirTemp("", "deskrun-test-*")
 	if err != nil {
@@ -208,7 +271,7 @@ func TestSaveAndLoad(t *testing.T) {
 	}
 
 	// Verify config file exists
-	configPath := filepath.Join(tmpHome, ".deskrun", "config.json")
+	configPath := filepath.Join(tmpHome, ".deskrun", "config.yaml")
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		t.Error("Config file was not created")
 	}
@@ -237,6 +300,7 @@ func TestAddClusterHost(t *testing.T) {
 
 	host := &types.ClusterHost{
 		Name:      "test-host",
+		Remote:    "my-server",
 		Image:     "images:nixos/25.11",
 		DiskSize:  "200GiB",
 		CreatedAt: "2026-01-08T00:00:00Z",
@@ -256,6 +320,18 @@ func TestAddClusterHost(t *testing.T) {
 		t.Errorf("Name = %v, want test-host", saved.Name)
 	}
 
+	reloaded, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	saved, err = reloaded.GetClusterHost("test-host")
+	if err != nil {
+		t.Fatalf("GetClusterHost() after reload error = %v", err)
+	}
+	if saved.Remote != "my-server" {
+		t.Errorf("Remote after reload = %v, want my-server", saved.Remote)
+	}
+
 	err = mgr.AddClusterHost(host)
 	if err == nil {
 		t.Error("AddClusterHost() expected error for duplicate, got nil")
@@ -369,3 +445,660 @@ func TestGetClusterHost(t *testing.T) {
 		t.Errorf("CreatedAt = %v, want %v", retrieved.CreatedAt, host.CreatedAt)
 	}
 }
+
+func TestClusterFor(t *testing.T) {
+	cfg := &Config{ClusterName: "deskrun"}
+
+	if got := cfg.ClusterFor(&types.RunnerInstallation{Name: "default"}); got != "deskrun" {
+		t.Errorf("ClusterFor() = %v, want deskrun", got)
+	}
+
+	if got := cfg.ClusterFor(&types.RunnerInstallation{Name: "pinned", Cluster: "gpu"}); got != "gpu" {
+		t.Errorf("ClusterFor() = %v, want gpu", got)
+	}
+
+	if got := (&Config{}).DefaultCluster(); got != "deskrun" {
+		t.Errorf("DefaultCluster() with empty ClusterName = %v, want deskrun", got)
+	}
+}
+
+func TestClusterNames(t *testing.T) {
+	cfg := &Config{
+		ClusterName: "deskrun",
+		Installations: map[string]*types.RunnerInstallation{
+			"a": {Name: "a"},
+			"b": {Name: "b", Cluster: "gpu"},
+		},
+		Clusters: map[string]*types.ClusterSettings{
+			"arm": {Name: "arm"},
+		},
+	}
+
+	want := []string{"arm", "deskrun", "gpu"}
+	if got := cfg.ClusterNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ClusterNames() = %v, want %v", got, want)
+	}
+
+	gpu := cfg.InstallationsForCluster("gpu")
+	if len(gpu) != 1 || gpu["b"] == nil {
+		t.Errorf("InstallationsForCluster(gpu) = %v, want only b", gpu)
+	}
+
+	if arm := cfg.InstallationsForCluster("arm"); len(arm) != 0 {
+		t.Errorf("InstallationsForCluster(arm) = %v, want none", arm)
+	}
+}
+
+func TestClusterNamespaces(t *testing.T) {
+	cfg := &Config{
+		Installations: map[string]*types.RunnerInstallation{
+			"a": {Name: "a"},
+			"b": {Name: "b", Namespace: "team-b"},
+			"c": {Name: "c", Namespace: "team-b"},
+			"d": {Name: "d", Namespace: "team-d", Cluster: "gpu"},
+		},
+	}
+
+	want := []string{"arc-systems", "team-b"}
+	if got := cfg.ClusterNamespaces("deskrun"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ClusterNamespaces(deskrun) = %v, want %v", got, want)
+	}
+}
+
+func TestProxyFor(t *testing.T) {
+	clusterProxy := &types.ProxyConfig{HTTPSProxy: "http://proxy.corp:3128"}
+	ownProxy := &types.ProxyConfig{HTTPProxy: "http://other:8080"}
+	cfg := &Config{
+		ClusterName: "deskrun",
+		Clusters: map[string]*types.ClusterSettings{
+			"deskrun": {Name: "deskrun", Proxy: clusterProxy},
+			"gpu":     {Name: "gpu"},
+		},
+	}
+
+	if got := cfg.ProxyFor(&types.RunnerInstallation{Name: "a"}); got != clusterProxy {
+		t.Errorf("ProxyFor() = %v, want cluster proxy", got)
+	}
+
+	if got := cfg.ProxyFor(&types.RunnerInstallation{Name: "b", Proxy: ownProxy}); got != ownProxy {
+		t.Errorf("ProxyFor() = %v, want installation proxy", got)
+	}
+
+	if got := cfg.ProxyFor(&types.RunnerInstallation{Name: "c", Cluster: "gpu"}); got != nil {
+		t.Errorf("ProxyFor() = %v, want nil for cluster without proxy", got)
+	}
+
+	if got := cfg.ClusterProxy("unknown"); got != nil {
+		t.Errorf("ClusterProxy(unknown) = %v, want nil", got)
+	}
+}
+
+func TestAddCluster(t *testing.T) {
+	tmpHome, err := os.MkdirTemp("", "deskrun-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp home: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(tmpHome)
+	})
+
+	oldHome := os.Getenv("HOME")
+	if err := os.Setenv("HOME", tmpHome); err != nil {
+		t.Fatalf("Failed to set HOME: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Setenv("HOME", oldHome)
+	})
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if err := mgr.AddCluster("gpu"); err != nil {
+		t.Fatalf("AddCluster() error = %v", err)
+	}
+
+	// Adding a known cluster again is a no-op
+	if err := mgr.AddCluster("gpu"); err != nil {
+		t.Errorf("AddCluster() for existing cluster error = %v", err)
+	}
+
+	// Verify the cluster survives a reload
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if mgr2.GetConfig().Clusters["gpu"] == nil {
+		t.Error("cluster gpu not persisted")
+	}
+}
+
+func TestClusterKubernetesVersion(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if got := mgr.GetConfig().ClusterKubernetesVersion("deskrun"); got != "" {
+		t.Errorf("ClusterKubernetesVersion() = %v, want empty", got)
+	}
+
+	if err := mgr.SetClusterKubernetesVersion("deskrun", "v1.33"); err != nil {
+		t.Fatalf("SetClusterKubernetesVersion() error = %v", err)
+	}
+
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if got := mgr2.GetConfig().ClusterKubernetesVersion("deskrun"); got != "v1.33" {
+		t.Errorf("ClusterKubernetesVersion() = %v after reload, want v1.33", got)
+	}
+}
+
+func TestClusterObservability(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if got := mgr.GetConfig().ClusterObservability("deskrun"); got != nil {
+		t.Errorf("ClusterObservability() = %v, want nil", got)
+	}
+
+	if err := mgr.SetClusterObservability("deskrun", &types.ObservabilityConfig{Grafana: true, Port: 19090}); err != nil {
+		t.Fatalf("SetClusterObservability() error = %v", err)
+	}
+
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	got := mgr2.GetConfig().ClusterObservability("deskrun")
+	if got == nil || !got.Grafana || got.Port != 19090 {
+		t.Errorf("ClusterObservability() = %+v after reload, want grafana on port 19090", got)
+	}
+
+	if err := mgr2.SetClusterObservability("deskrun", nil); err != nil {
+		t.Fatalf("SetClusterObservability(nil) error = %v", err)
+	}
+	if got := mgr2.GetConfig().ClusterObservability("deskrun"); got != nil {
+		t.Errorf("ClusterObservability() = %v after disabling, want nil", got)
+	}
+}
+
+func TestClusterKubeContext(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if err := mgr.SetClusterKubeContext("k3s", "default"); err != nil {
+		t.Fatalf("SetClusterKubeContext() error = %v", err)
+	}
+
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	cfg := mgr2.GetConfig()
+	if got := cfg.ClusterKubeContext("k3s"); got != "default" {
+		t.Errorf("ClusterKubeContext() = %v after reload, want default", got)
+	}
+	if got := cfg.ClusterKubeContext("deskrun"); got != "" {
+		t.Errorf("ClusterKubeContext() of kind cluster = %v, want empty", got)
+	}
+	if !slices.Contains(cfg.ClusterNames(), "k3s") {
+		t.Errorf("ClusterNames() = %v, want k3s included", cfg.ClusterNames())
+	}
+}
+
+func TestSecretStore(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if mgr.GetConfig().SecretStore != secrets.BackendFile {
+		t.Errorf("SecretStore = %v, want file", mgr.GetConfig().SecretStore)
+	}
+
+	installation := &types.RunnerInstallation{
+		Name:       "test-runner",
+		Repository: "https://github.com/owner/repo",
+		AuthType:   types.AuthTypePAT,
+		AuthValue:  "ghp_supersecret",
+	}
+	if err := mgr.AddInstallation(installation); err != nil {
+		t.Fatalf("AddInstallation() error = %v", err)
+	}
+
+	data, err := os.ReadFile(mgr.GetConfigPath())
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if strings.Contains(string(data), "ghp_supersecret") {
+		t.Error("config.json contains the plaintext auth value")
+	}
+
+	// The in-memory installation keeps its auth value
+	if installation.AuthValue != "ghp_supersecret" {
+		t.Errorf("AuthValue = %v after save, want ghp_supersecret", installation.AuthValue)
+	}
+
+	// A reload reads the auth value back from the secret store
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	saved, err := mgr2.GetInstallation("test-runner")
+	if err != nil {
+		t.Fatalf("GetInstallation() error = %v", err)
+	}
+	if saved.AuthValue != "ghp_supersecret" {
+		t.Errorf("AuthValue = %v after reload, want ghp_supersecret", saved.AuthValue)
+	}
+
+	exported, err := mgr2.ExportPlaintext()
+	if err != nil {
+		t.Fatalf("ExportPlaintext() error = %v", err)
+	}
+	if !strings.Contains(string(exported), "ghp_supersecret") {
+		t.Error("ExportPlaintext() does not contain the auth value")
+	}
+
+	if err := mgr2.RemoveInstallation("test-runner"); err != nil {
+		t.Fatalf("RemoveInstallation() error = %v", err)
+	}
+	store := secrets.NewFileStore(filepath.Join(tmpHome, ".deskrun"))
+	if _, err := store.Get(authValueKey("test-runner")); err == nil {
+		t.Error("auth value still in secret store after RemoveInstallation()")
+	}
+}
+
+func TestRegistries(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	for _, registry := range []*types.RegistryCredential{
+		{Name: "ghcr", Server: "ghcr.io", Username: "bot", Password: "ghp_registry"},
+		{Name: "corp", Server: "registry.corp:5000", Username: "ci", Password: "hunter2"},
+	} {
+		if err := mgr.SetRegistry(registry); err != nil {
+			t.Fatalf("SetRegistry() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(mgr.GetConfigPath())
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if strings.Contains(string(data), "ghp_registry") {
+		t.Error("config.json contains the plaintext registry password")
+	}
+
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	registries := mgr2.GetConfig().SortedRegistries()
+	if len(registries) != 2 || registries[0].Name != "corp" || registries[1].Name != "ghcr" {
+		t.Fatalf("SortedRegistries() = %v, want corp and ghcr", registries)
+	}
+	if registries[1].Password != "ghp_registry" {
+		t.Errorf("Password = %v after reload, want ghp_registry", registries[1].Password)
+	}
+
+	if err := mgr2.RemoveRegistry("ghcr"); err != nil {
+		t.Fatalf("RemoveRegistry() error = %v", err)
+	}
+	if err := mgr2.RemoveRegistry("ghcr"); err == nil {
+		t.Error("RemoveRegistry() of unknown registry error = nil, want error")
+	}
+	store := secrets.NewFileStore(filepath.Join(tmpHome, ".deskrun"))
+	if _, err := store.Get(registryPasswordKey("ghcr")); err == nil {
+		t.Error("registry password still in secret store after RemoveRegistry()")
+	}
+}
+
+func TestClusterPortMappings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	for _, mapping := range []types.PortMapping{
+		{HostPort: 8080, ContainerPort: 30080, Protocol: "TCP"},
+		{HostPort: 5353, ContainerPort: 30053, Protocol: "UDP"},
+	} {
+		if err := mgr.AddClusterPortMapping("deskrun", mapping); err != nil {
+			t.Fatalf("AddClusterPortMapping() error = %v", err)
+		}
+	}
+	if err := mgr.AddClusterPortMapping("deskrun", types.PortMapping{HostPort: 8080, ContainerPort: 30081, Protocol: "TCP"}); err == nil {
+		t.Error("AddClusterPortMapping() of mapped host port error = nil, want error")
+	}
+
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	mappings := mgr2.GetConfig().ClusterPortMappings("deskrun")
+	if len(mappings) != 2 || mappings[0].HostPort != 5353 || mappings[1].HostPort != 8080 {
+		t.Fatalf("ClusterPortMappings() = %v, want host ports 5353 and 8080", mappings)
+	}
+
+	if err := mgr2.RemoveClusterPortMapping("deskrun", 8080); err != nil {
+		t.Fatalf("RemoveClusterPortMapping() error = %v", err)
+	}
+	if got := mgr2.GetConfig().ClusterPortMappings("deskrun"); len(got) != 1 {
+		t.Errorf("ClusterPortMappings() = %v after RemoveClusterPortMapping(), want one mapping", got)
+	}
+	if err := mgr2.RemoveClusterPortMapping("deskrun", 8080); err == nil {
+		t.Error("RemoveClusterPortMapping() of unmapped port error = nil, want error")
+	}
+}
+
+func TestMirrors(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	for _, registry := range []string{"ghcr.io", "docker.io", "ghcr.io"} {
+		if err := mgr.AddMirror(registry); err != nil {
+			t.Fatalf("AddMirror() error = %v", err)
+		}
+	}
+
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if got := mgr2.GetConfig().Mirrors; !reflect.DeepEqual(got, []string{"docker.io", "ghcr.io"}) {
+		t.Fatalf("Mirrors = %v, want [docker.io ghcr.io]", got)
+	}
+
+	if err := mgr2.RemoveMirror("docker.io"); err != nil {
+		t.Fatalf("RemoveMirror() error = %v", err)
+	}
+	if got := mgr2.GetConfig().Mirrors; !reflect.DeepEqual(got, []string{"ghcr.io"}) {
+		t.Errorf("Mirrors = %v after RemoveMirror(), want [ghcr.io]", got)
+	}
+	if err := mgr2.RemoveMirror("docker.io"); err == nil {
+		t.Error("RemoveMirror() of disabled mirror error = nil, want error")
+	}
+}
+
+func TestCacheVolumes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	for _, volume := range []*types.CacheVolume{
+		{Name: "npm", Path: "/home/runner/.npm"},
+		{Name: "go-mod", Path: "/home/runner/go/pkg/mod", MaxSize: "10Gi"},
+	} {
+		if err := mgr.SetCacheVolume(volume); err != nil {
+			t.Fatalf("SetCacheVolume() error = %v", err)
+		}
+	}
+	if err := mgr.AddInstallation(&types.RunnerInstallation{Name: "web", CacheVolumes: []string{"npm"}}); err != nil {
+		t.Fatalf("AddInstallation() error = %v", err)
+	}
+
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	volumes := mgr2.GetConfig().SortedCacheVolumes()
+	if len(volumes) != 2 || volumes[0].Name != "go-mod" || volumes[0].MaxSize != "10Gi" || volumes[1].Name != "npm" {
+		t.Fatalf("SortedCacheVolumes() = %v, want go-mod and npm", volumes)
+	}
+	if got := mgr2.GetConfig().Installations["web"].CacheVolumes; !reflect.DeepEqual(got, []string{"npm"}) {
+		t.Errorf("CacheVolumes of web = %v, want [npm]", got)
+	}
+
+	if err := mgr2.RemoveCacheVolume("npm"); err == nil {
+		t.Error("RemoveCacheVolume() of a mounted volume error = nil, want error")
+	}
+	if err := mgr2.RemoveCacheVolume("go-mod"); err != nil {
+		t.Fatalf("RemoveCacheVolume() error = %v", err)
+	}
+	if err := mgr2.RemoveCacheVolume("go-mod"); err == nil {
+		t.Error("RemoveCacheVolume() of a missing volume error = nil, want error")
+	}
+}
+
+func TestSecretStoreMigratesPlaintextAuthValues(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	configDir := filepath.Join(tmpHome, ".deskrun")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+
+	legacyConfig := `{
+  "cluster_name": "deskrun",
+  "installations": {
+    "legacy-runner": {
+      "Name": "legacy-runner",
+      "Repository": "https://github.com/owner/repo",
+      "AuthType": "pat",
+      "AuthValue": "ghp_legacy"
+    }
+  }
+}`
+	configPath := filepath.Join(configDir, "config.json")
+	if err := os.WriteFile(configPath, []byte(legacyConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	saved, err := mgr.GetInstallation("legacy-runner")
+	if err != nil {
+		t.Fatalf("GetInstallation() error = %v", err)
+	}
+	if saved.AuthValue != "ghp_legacy" {
+		t.Errorf("AuthValue = %v, want ghp_legacy", saved.AuthValue)
+	}
+
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Error("config.json still exists after migration")
+	}
+	data, err := os.ReadFile(mgr.GetConfigPath())
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if strings.Contains(string(data), "ghp_legacy") {
+		t.Error("config.yaml contains the plaintext auth value after migration")
+	}
+	if !strings.Contains(string(data), "secret_store: file") {
+		t.Errorf("config.yaml does not record the secret store:\n%s", data)
+	}
+}
+
+func TestPlaintextSecretStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(secrets.BackendEnvVar, string(secrets.BackendPlaintext))
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if err := mgr.AddInstallation(&types.RunnerInstallation{Name: "test-runner", AuthValue: "ghp_plain"}); err != nil {
+		t.Fatalf("AddInstallation() error = %v", err)
+	}
+
+	data, err := os.ReadFile(mgr.GetConfigPath())
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if !strings.Contains(string(data), "ghp_plain") {
+		t.Error("plaintext secret store did not keep the auth value in config.json")
+	}
+}
+
+func TestLegacyJSONConfigMigratesToYAML(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	configDir := filepath.Join(tmpHome, ".deskrun")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+
+	legacyConfig := `{
+  "cluster_name": "work",
+  "installations": {
+    "my-runner": {
+      "Name": "my-runner",
+      "Repository": "https://github.com/owner/repo",
+      "ContainerMode": "dind",
+      "MinRunners": 1,
+      "MaxRunners": 3,
+      "Instances": 2,
+      "Proxy": null,
+      "EnvVars": {"FOO": "123"}
+    }
+  },
+  "mirrors": ["docker.io"]
+}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(legacyConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if got, want := mgr.GetConfigPath(), filepath.Join(configDir, "config.yaml"); got != want {
+		t.Errorf("GetConfigPath() = %s, want %s", got, want)
+	}
+
+	data, err := os.ReadFile(mgr.GetConfigPath())
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	for _, want := range []string{"version: 1\n", "cluster_name: work\n", "    ContainerMode: dind\n", `FOO: "123"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("config.yaml does not contain %q:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "null") {
+		t.Errorf("config.yaml contains null values:\n%s", data)
+	}
+
+	cfg, err := ReadFile(mgr.GetConfigPath())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	installation := cfg.Installations["my-runner"]
+	if installation == nil {
+		t.Fatal("installation my-runner missing after migration")
+	}
+	if installation.ContainerMode != types.ContainerModeDinD || installation.Instances != 2 || installation.MaxRunners != 3 {
+		t.Errorf("installation = %+v, want dind with 2 instances and 3 max runners", installation)
+	}
+	if installation.EnvVars["FOO"] != "123" {
+		t.Errorf("EnvVars = %v, want FOO=123", installation.EnvVars)
+	}
+	if !reflect.DeepEqual(cfg.Mirrors, []string{"docker.io"}) {
+		t.Errorf("Mirrors = %v, want [docker.io]", cfg.Mirrors)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			content: "version: 1\ninstallations:\n  r:\n    Name: r\n    MaxRunners: 2\n",
+		},
+		{
+			name:    "unknown field",
+			content: "version: 1\ninstallations:\n  r:\n    Name: r\n    MaxRunner: 2\n",
+			wantErr: `unknown field "MaxRunner"`,
+		},
+		{
+			name:    "newer version",
+			content: "version: 2\n",
+			wantErr: "config version 2 is not supported",
+		},
+		{
+			name:    "invalid yaml",
+			content: "installations: [\n",
+			wantErr: "failed to parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			_, err := ReadFile(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ReadFile() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadFile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsNewerVersion(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	configDir := filepath.Join(tmpHome, ".deskrun")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte("version: 99\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := NewManager(); err == nil || !strings.Contains(err.Error(), "please upgrade deskrun") {
+		t.Errorf("NewManager() error = %v, want unsupported version error", err)
+	}
+}
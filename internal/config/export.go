@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rkoster/deskrun/internal/secrets"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+// RedactedValue replaces auth values and registry passwords in configs
+// exported with redacted secrets
+const RedactedValue = "<redacted>"
+
+// ExportPlaintext returns the config file contents with all auth values
+// included, e.g. to copy the configuration to a cluster host
+func (m *Manager) ExportPlaintext() ([]byte, error) {
+	return m.Export(false)
+}
+
+// Export returns the config file contents with all auth values and registry
+// passwords included, or replaced with RedactedValue when redactSecrets is set.
+// The secret store is left out, as it is specific to this machine.
+func (m *Manager) Export(redactSecrets bool) ([]byte, error) {
+	exported := *m.config
+	exported.SecretStore = ""
+
+	if redactSecrets {
+		exported.Installations = make(map[string]*types.RunnerInstallation, len(m.config.Installations))
+		for name, installation := range m.config.Installations {
+			redacted := *installation
+			if redacted.AuthValue != "" {
+				redacted.AuthValue = RedactedValue
+			}
+			exported.Installations[name] = &redacted
+		}
+
+		if m.config.Registries != nil {
+			exported.Registries = make(map[string]*types.RegistryCredential, len(m.config.Registries))
+		}
+		for name, registry := range m.config.Registries {
+			redacted := *registry
+			if redacted.Password != "" {
+				redacted.Password = RedactedValue
+			}
+			exported.Registries[name] = &redacted
+		}
+	}
+
+	return marshalConfig(&exported)
+}
+
+// Import replaces the configuration with an imported one, e.g. exported with
+// Export on another machine. The secret store of this machine is kept and the
+// previous config file is backed up next to it. Redacted values must have been
+// replaced before.
+func (m *Manager) Import(imported *Config) error {
+	for name, installation := range imported.Installations {
+		if installation.AuthValue == RedactedValue {
+			return fmt.Errorf("auth value of installation %s is redacted", name)
+		}
+	}
+	for name, registry := range imported.Registries {
+		if registry.Password == RedactedValue {
+			return fmt.Errorf("password of registry %s is redacted", name)
+		}
+	}
+
+	if data, err := os.ReadFile(m.configPath); err == nil {
+		if err := os.WriteFile(m.configPath+".bak", data, 0644); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	previous := m.config
+	cfg := *imported
+	cfg.SecretStore = previous.SecretStore
+	if cfg.ClusterName == "" {
+		cfg.ClusterName = defaultClusterName
+	}
+	if cfg.Installations == nil {
+		cfg.Installations = make(map[string]*types.RunnerInstallation)
+	}
+	if cfg.ClusterHosts == nil {
+		cfg.ClusterHosts = make(map[string]*types.ClusterHost)
+	}
+	if cfg.Clusters == nil {
+		cfg.Clusters = make(map[string]*types.ClusterSettings)
+	}
+
+	m.config = &cfg
+	if err := m.Save(); err != nil {
+		return err
+	}
+
+	// Drop the secrets of installations and registries that were not imported
+	if m.secrets == nil {
+		return nil
+	}
+	var stale []string
+	for name := range previous.Installations {
+		if cfg.Installations[name] == nil {
+			stale = append(stale, authValueKey(name))
+		}
+	}
+	for name := range previous.Registries {
+		if cfg.Registries[name] == nil {
+			stale = append(stale, registryPasswordKey(name))
+		}
+	}
+	for _, key := range stale {
+		if err := m.secrets.Delete(key); err != nil && !errors.Is(err, secrets.ErrNotFound) {
+			return fmt.Errorf("failed to delete secret %s: %w", key, err)
+		}
+		delete(m.storedSecrets, key)
+	}
+
+	return nil
+}
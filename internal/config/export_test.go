@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rkoster/deskrun/internal/secrets"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+func TestExportRedactsSecrets(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := mgr.AddInstallation(&types.RunnerInstallation{Name: "test-runner", AuthValue: "ghp_secret"}); err != nil {
+		t.Fatalf("AddInstallation() error = %v", err)
+	}
+	if err := mgr.SetRegistry(&types.RegistryCredential{Name: "ghcr", Server: "ghcr.io", Username: "bot", Password: "registry_secret"}); err != nil {
+		t.Fatalf("SetRegistry() error = %v", err)
+	}
+
+	redacted, err := mgr.Export(true)
+	if err != nil {
+		t.Fatalf("Export(true) error = %v", err)
+	}
+	for _, secret := range []string{"ghp_secret", "registry_secret", "secret_store"} {
+		if strings.Contains(string(redacted), secret) {
+			t.Errorf("Export(true) contains %q:\n%s", secret, redacted)
+		}
+	}
+	if strings.Count(string(redacted), RedactedValue) != 2 {
+		t.Errorf("Export(true) does not redact both secrets:\n%s", redacted)
+	}
+
+	// The in-memory config keeps its secrets
+	if got := mgr.GetConfig().Installations["test-runner"].AuthValue; got != "ghp_secret" {
+		t.Errorf("AuthValue = %q after Export(true), want ghp_secret", got)
+	}
+
+	plaintext, err := mgr.Export(false)
+	if err != nil {
+		t.Fatalf("Export(false) error = %v", err)
+	}
+	if !strings.Contains(string(plaintext), "ghp_secret") || !strings.Contains(string(plaintext), "registry_secret") {
+		t.Errorf("Export(false) does not contain the secrets:\n%s", plaintext)
+	}
+}
+
+func TestImport(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := mgr.AddInstallation(&types.RunnerInstallation{Name: "old-runner", AuthValue: "ghp_old"}); err != nil {
+		t.Fatalf("AddInstallation() error = %v", err)
+	}
+
+	imported := &Config{
+		SecretStore: secrets.BackendPlaintext,
+		Installations: map[string]*types.RunnerInstallation{
+			"new-runner": {Name: "new-runner", AuthValue: RedactedValue},
+		},
+	}
+	if err := mgr.Import(imported); err == nil || !strings.Contains(err.Error(), "auth value of installation new-runner is redacted") {
+		t.Fatalf("Import() error = %v, want redacted auth value error", err)
+	}
+
+	imported.Installations["new-runner"].AuthValue = "ghp_new"
+	if err := mgr.Import(imported); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if _, err := os.Stat(mgr.GetConfigPath() + ".bak"); err != nil {
+		t.Errorf("config backup missing: %v", err)
+	}
+
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	cfg := mgr2.GetConfig()
+	if cfg.SecretStore != secrets.BackendFile {
+		t.Errorf("SecretStore = %v after import, want the local file store", cfg.SecretStore)
+	}
+	if cfg.Installations["old-runner"] != nil {
+		t.Error("installation old-runner still exists after import")
+	}
+	if got := cfg.Installations["new-runner"]; got == nil || got.AuthValue != "ghp_new" {
+		t.Errorf("installation new-runner = %+v, want auth value ghp_new", got)
+	}
+
+	store := secrets.NewFileStore(filepath.Join(tmpHome, ".deskrun"))
+	if _, err := store.Get(authValueKey("old-runner")); err == nil {
+		t.Error("auth value of old-runner still in secret store after import")
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/rkoster/deskrun/internal/secrets"
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+// Manifest declares the clusters and runner installations of a desk-runner
+// fleet, e.g. in a version-controlled deskrun.yaml applied with
+// 'deskrun apply'. Its sections use the schema of the config file:
+//
+//	version: 1
+//	clusters:
+//	  deskrun:
+//	    cache_server: {}
+//	installations:
+//	  my-runner:
+//	    Repository: https://github.com/owner/repo
+//	    ContainerMode: kubernetes
+//	    MaxRunners: 3
+//	    AuthType: pat
+//
+// The names of clusters and installations default to their keys. AuthValue
+// may be left out for installations that are already configured, so tokens
+// don't need to be committed.
+type Manifest struct {
+	Version       int                                  `json:"version"`
+	Clusters      map[string]*types.ClusterSettings    `json:"clusters,omitempty"`
+	Installations map[string]*types.RunnerInstallation `json:"installations"`
+}
+
+// ChangeAction is what applying a manifest does to a cluster or installation
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeUpdate ChangeAction = "update"
+	ChangeDelete ChangeAction = "delete"
+)
+
+// ManifestChange is a change applying a manifest makes to the config
+type ManifestChange struct {
+	Action ChangeAction
+	// Kind is "cluster" or "installation"
+	Kind string
+	Name string
+}
+
+// ReadManifest parses a manifest file, rejecting fields that are not part of
+// the schema
+func ReadManifest(path string) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := decodeFile(path, manifest); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(manifest.Version); err != nil {
+		return nil, err
+	}
+
+	for name, settings := range manifest.Clusters {
+		if settings == nil {
+			settings = &types.ClusterSettings{}
+			manifest.Clusters[name] = settings
+		}
+		if settings.Name == "" {
+			settings.Name = name
+		}
+		if settings.Name != name {
+			return nil, fmt.Errorf("cluster %s has a different name: %s", name, settings.Name)
+		}
+	}
+	for name, installation := range manifest.Installations {
+		if installation == nil {
+			return nil, fmt.Errorf("installation %s is empty", name)
+		}
+		if installation.Name == "" {
+			installation.Name = name
+		}
+		if installation.Name != name {
+			return nil, fmt.Errorf("installation %s has a different name: %s", name, installation.Name)
+		}
+	}
+
+	return manifest, nil
+}
+
+// PlanManifest returns the changes applying a manifest makes to the config,
+// sorted by kind and name. An error is returned for new installations
+// without an auth value.
+func (c *Config) PlanManifest(manifest *Manifest) ([]ManifestChange, error) {
+	var changes []ManifestChange
+
+	for name, settings := range manifest.Clusters {
+		current := c.Clusters[name]
+		switch {
+		case current == nil:
+			changes = append(changes, ManifestChange{Action: ChangeCreate, Kind: "cluster", Name: name})
+		case !reflect.DeepEqual(current, settings):
+			changes = append(changes, ManifestChange{Action: ChangeUpdate, Kind: "cluster", Name: name})
+		}
+	}
+	for name := range c.Clusters {
+		if manifest.Clusters[name] == nil {
+			changes = append(changes, ManifestChange{Action: ChangeDelete, Kind: "cluster", Name: name})
+		}
+	}
+
+	for name, installation := range manifest.Installations {
+		current := c.Installations[name]
+		if current == nil {
+			if installation.AuthValue == "" {
+				return nil, fmt.Errorf("installation %s is new and has no AuthValue", name)
+			}
+			changes = append(changes, ManifestChange{Action: ChangeCreate, Kind: "installation", Name: name})
+			continue
+		}
+		if !reflect.DeepEqual(current, withAuthValueOf(installation, current)) {
+			changes = append(changes, ManifestChange{Action: ChangeUpdate, Kind: "installation", Name: name})
+		}
+	}
+	for name := range c.Installations {
+		if manifest.Installations[name] == nil {
+			changes = append(changes, ManifestChange{Action: ChangeDelete, Kind: "installation", Name: name})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+// withAuthValueOf returns the installation with the auth value of the
+// configured installation when it has none of its own
+func withAuthValueOf(installation, configured *types.RunnerInstallation) *types.RunnerInstallation {
+	if installation.AuthValue != "" {
+		return installation
+	}
+
+	withAuthValue := *installation
+	withAuthValue.AuthValue = configured.AuthValue
+	return &withAuthValue
+}
+
+// ApplyManifest replaces the clusters and installations of the config with
+// those of a manifest. Installations without an auth value keep their
+// configured one, and the auth values of removed installations are deleted
+// from the secret store.
+func (m *Manager) ApplyManifest(manifest *Manifest) error {
+	changes, err := m.config.PlanManifest(manifest)
+	if err != nil {
+		return err
+	}
+
+	installations := make(map[string]*types.RunnerInstallation, len(manifest.Installations))
+	for name, installation := range manifest.Installations {
+		if current := m.config.Installations[name]; current != nil {
+			installation = withAuthValueOf(installation, current)
+		}
+		installations[name] = installation
+	}
+	clusters := make(map[string]*types.ClusterSettings, len(manifest.Clusters))
+	for name, settings := range manifest.Clusters {
+		clusters[name] = settings
+	}
+
+	m.config.Installations = installations
+	m.config.Clusters = clusters
+	if err := m.Save(); err != nil {
+		return err
+	}
+
+	if m.secrets != nil {
+		for _, change := range changes {
+			if change.Kind != "installation" || change.Action != ChangeDelete {
+				continue
+			}
+			key := authValueKey(change.Name)
+			if err := m.secrets.Delete(key); err != nil && !errors.Is(err, secrets.ErrNotFound) {
+				return fmt.Errorf("failed to delete auth value of installation %s: %w", change.Name, err)
+			}
+			delete(m.storedSecrets, key)
+		}
+	}
+
+	return nil
+}
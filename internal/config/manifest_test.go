@@ -0,0 +1,182 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+func writeManifest(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "deskrun.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	return path
+}
+
+func TestReadManifest(t *testing.T) {
+	path := writeManifest(t, `version: 1
+clusters:
+  deskrun:
+  gpu:
+    kubernetes_version: v1.30.0
+installations:
+  my-runner:
+    Repository: https://github.com/owner/repo
+    ContainerMode: kubernetes
+    MaxRunners: 3
+`)
+
+	manifest, err := ReadManifest(path)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if got := manifest.Installations["my-runner"].Name; got != "my-runner" {
+		t.Errorf("installation Name = %q, want my-runner", got)
+	}
+	if got := manifest.Clusters["deskrun"]; got == nil || got.Name != "deskrun" {
+		t.Errorf("cluster deskrun = %+v, want settings named deskrun", got)
+	}
+	if got := manifest.Clusters["gpu"].KubernetesVersion; got != "v1.30.0" {
+		t.Errorf("cluster gpu KubernetesVersion = %q, want v1.30.0", got)
+	}
+}
+
+func TestReadManifestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "unknown field",
+			content: "installations:\n  r:\n    Repo: https://github.com/owner/repo\n",
+			wantErr: `unknown field "Repo"`,
+		},
+		{
+			name:    "mismatched name",
+			content: "installations:\n  r:\n    Name: other\n",
+			wantErr: "installation r has a different name: other",
+		},
+		{
+			name:    "empty installation",
+			content: "installations:\n  r:\n",
+			wantErr: "installation r is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadManifest(writeManifest(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadManifest() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlanManifest(t *testing.T) {
+	cfg := &Config{
+		Clusters: map[string]*types.ClusterSettings{
+			"deskrun": {Name: "deskrun"},
+			"old":     {Name: "old"},
+		},
+		Installations: map[string]*types.RunnerInstallation{
+			"same":    {Name: "same", Repository: "https://github.com/owner/repo", AuthValue: "ghp_same"},
+			"changed": {Name: "changed", MaxRunners: 1, AuthValue: "ghp_changed"},
+			"removed": {Name: "removed", AuthValue: "ghp_removed"},
+		},
+	}
+	manifest := &Manifest{
+		Clusters: map[string]*types.ClusterSettings{
+			"deskrun": {Name: "deskrun", KubernetesVersion: "v1.30.0"},
+			"new":     {Name: "new"},
+		},
+		Installations: map[string]*types.RunnerInstallation{
+			"same":    {Name: "same", Repository: "https://github.com/owner/repo"},
+			"changed": {Name: "changed", MaxRunners: 2},
+			"added":   {Name: "added", AuthValue: "ghp_added"},
+		},
+	}
+
+	changes, err := cfg.PlanManifest(manifest)
+	if err != nil {
+		t.Fatalf("PlanManifest() error = %v", err)
+	}
+	want := []ManifestChange{
+		{Action: ChangeUpdate, Kind: "cluster", Name: "deskrun"},
+		{Action: ChangeCreate, Kind: "cluster", Name: "new"},
+		{Action: ChangeDelete, Kind: "cluster", Name: "old"},
+		{Action: ChangeCreate, Kind: "installation", Name: "added"},
+		{Action: ChangeUpdate, Kind: "installation", Name: "changed"},
+		{Action: ChangeDelete, Kind: "installation", Name: "removed"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("PlanManifest() = %+v, want %+v", changes, want)
+	}
+
+	manifest.Installations["added"].AuthValue = ""
+	if _, err := cfg.PlanManifest(manifest); err == nil || !strings.Contains(err.Error(), "installation added is new and has no AuthValue") {
+		t.Errorf("PlanManifest() error = %v, want missing auth value error", err)
+	}
+}
+
+func TestApplyManifest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	mgr, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	for _, installation := range []*types.RunnerInstallation{
+		{Name: "kept", MaxRunners: 1, AuthValue: "ghp_kept"},
+		{Name: "removed", AuthValue: "ghp_removed"},
+	} {
+		if err := mgr.AddInstallation(installation); err != nil {
+			t.Fatalf("AddInstallation() error = %v", err)
+		}
+	}
+
+	manifest := &Manifest{
+		Clusters: map[string]*types.ClusterSettings{"deskrun": {Name: "deskrun"}},
+		Installations: map[string]*types.RunnerInstallation{
+			"kept":  {Name: "kept", MaxRunners: 3},
+			"added": {Name: "added", AuthValue: "ghp_added"},
+		},
+	}
+	if err := mgr.ApplyManifest(manifest); err != nil {
+		t.Fatalf("ApplyManifest() error = %v", err)
+	}
+
+	mgr2, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	cfg := mgr2.GetConfig()
+	if kept := cfg.Installations["kept"]; kept == nil || kept.MaxRunners != 3 || kept.AuthValue != "ghp_kept" {
+		t.Errorf("installation kept = %+v, want 3 max runners and the configured auth value", kept)
+	}
+	if added := cfg.Installations["added"]; added == nil || added.AuthValue != "ghp_added" {
+		t.Errorf("installation added = %+v, want auth value ghp_added", added)
+	}
+	if cfg.Installations["removed"] != nil {
+		t.Error("installation removed still exists")
+	}
+	if cfg.Clusters["deskrun"] == nil {
+		t.Error("cluster deskrun was not created")
+	}
+
+	// Applying the same manifest again changes nothing
+	changes, err := cfg.PlanManifest(manifest)
+	if err != nil {
+		t.Fatalf("PlanManifest() error = %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("PlanManifest() after apply = %+v, want no changes", changes)
+	}
+}
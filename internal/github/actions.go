@@ -0,0 +1,249 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Workflow job statuses reported by the GitHub Actions API
+const (
+	JobStatusQueued     = "queued"
+	JobStatusInProgress = "in_progress"
+	JobStatusCompleted  = "completed"
+)
+
+// WorkflowJob is a job of a GitHub Actions workflow run
+type WorkflowJob struct {
+	ID           int64  `json:"id"`
+	RunID        int64  `json:"run_id"`
+	Name         string `json:"name"`
+	WorkflowName string `json:"workflow_name"`
+	Status       string `json:"status"`
+	// Conclusion is the outcome of completed jobs, e.g. success or failure
+	Conclusion  string    `json:"conclusion"`
+	Labels      []string  `json:"labels"`
+	RunnerName  string    `json:"runner_name"`
+	CreatedAt   time.Time `json:"created_at"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	HTMLURL     string    `json:"html_url"`
+	// Steps are the steps of the job, in order
+	Steps []WorkflowStep `json:"steps"`
+}
+
+// WorkflowStep is a step of a workflow job
+type WorkflowStep struct {
+	Name        string    `json:"name"`
+	Number      int       `json:"number"`
+	Status      string    `json:"status"`
+	Conclusion  string    `json:"conclusion"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// WorkflowRun is a run of a GitHub Actions workflow
+type WorkflowRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Event      string `json:"event"`
+	HeadBranch string `json:"head_branch"`
+	Status     string `json:"status"`
+	// Conclusion is the outcome of completed runs, e.g. success or failure
+	Conclusion string    `json:"conclusion"`
+	CreatedAt  time.Time `json:"created_at"`
+	HTMLURL    string    `json:"html_url"`
+}
+
+// Targets returns true if the job requested a runner with the given label.
+// ARC runner scale sets are targeted by their scale set name.
+func (j WorkflowJob) Targets(label string) bool {
+	return slices.Contains(j.Labels, label)
+}
+
+// ActiveJobs returns the jobs of a repository that are not completed yet,
+// i.e. queued, waiting or in progress, oldest first
+func (c *Client) ActiveJobs(ctx context.Context, owner, repo string) ([]WorkflowJob, error) {
+	var jobs []WorkflowJob
+	seen := map[int64]bool{}
+
+	// Workflow runs only transition from queued to in_progress, so list both
+	// to catch runs whose jobs are waiting for a runner
+	for _, status := range []string{JobStatusQueued, JobStatusInProgress} {
+		var runs struct {
+			WorkflowRuns []struct {
+				ID int64 `json:"id"`
+			} `json:"workflow_runs"`
+		}
+
+		path := fmt.Sprintf("/repos/%s/%s/actions/runs?status=%s&per_page=100", owner, repo, status)
+		if _, err := c.get(ctx, path, &runs); err != nil {
+			return nil, fmt.Errorf("failed to list %s workflow runs: %w", status, err)
+		}
+
+		for _, run := range runs.WorkflowRuns {
+			if seen[run.ID] {
+				continue
+			}
+			seen[run.ID] = true
+
+			runJobs, err := c.RunJobs(ctx, owner, repo, run.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, job := range runJobs {
+				if job.Status != JobStatusCompleted {
+					jobs = append(jobs, job)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	return jobs, nil
+}
+
+// RecentJobs returns the jobs of the given number of most recently completed
+// workflow runs of a repository, most recently completed first
+func (c *Client) RecentJobs(ctx context.Context, owner, repo string, runs int) ([]WorkflowJob, error) {
+	var completed struct {
+		WorkflowRuns []struct {
+			ID int64 `json:"id"`
+		} `json:"workflow_runs"`
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs?status=%s&per_page=%d", owner, repo, JobStatusCompleted, runs)
+	if _, err := c.get(ctx, path, &completed); err != nil {
+		return nil, fmt.Errorf("failed to list completed workflow runs: %w", err)
+	}
+
+	var jobs []WorkflowJob
+	for _, run := range completed.WorkflowRuns {
+		runJobs, err := c.RunJobs(ctx, owner, repo, run.ID)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, runJobs...)
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CompletedAt.After(jobs[j].CompletedAt)
+	})
+
+	return jobs, nil
+}
+
+// RunJobs lists the jobs of the latest attempt of a workflow run
+func (c *Client) RunJobs(ctx context.Context, owner, repo string, runID int64) ([]WorkflowJob, error) {
+	var response struct {
+		Jobs []WorkflowJob `json:"jobs"`
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/jobs?filter=latest&per_page=100", owner, repo, runID)
+	if _, err := c.get(ctx, path, &response); err != nil {
+		return nil, fmt.Errorf("failed to list jobs of workflow run %d: %w", runID, err)
+	}
+
+	return response.Jobs, nil
+}
+
+// DefaultBranch returns the default branch of a repository
+func (c *Client) DefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	var repository struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+
+	if _, err := c.get(ctx, fmt.Sprintf("/repos/%s/%s", owner, repo), &repository); err != nil {
+		return "", fmt.Errorf("failed to get repository %s/%s: %w", owner, repo, err)
+	}
+
+	return repository.DefaultBranch, nil
+}
+
+// DispatchWorkflow triggers a workflow_dispatch event for a workflow, given by
+// its file name (e.g. ci.yml) or ID, on the given ref
+func (c *Client) DispatchWorkflow(ctx context.Context, owner, repo, workflow, ref string, inputs map[string]string) error {
+	body := map[string]any{"ref": ref}
+	if len(inputs) > 0 {
+		body["inputs"] = inputs
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/actions/workflows/%s/dispatches", owner, repo, url.PathEscape(workflow))
+	if _, err := c.post(ctx, path, body, nil); err != nil {
+		return fmt.Errorf("failed to dispatch workflow %s: %w", workflow, err)
+	}
+
+	return nil
+}
+
+// DispatchedRuns returns the workflow_dispatch runs of a workflow on a branch
+// that were created at or after since, newest first
+func (c *Client) DispatchedRuns(ctx context.Context, owner, repo, workflow, branch string, since time.Time) ([]WorkflowRun, error) {
+	var response struct {
+		WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+	}
+
+	query := url.Values{}
+	query.Set("event", "workflow_dispatch")
+	query.Set("branch", branch)
+	query.Set("created", ">="+since.UTC().Format(time.RFC3339))
+	path := fmt.Sprintf("/repos/%s/%s/actions/workflows/%s/runs?%s", owner, repo, url.PathEscape(workflow), query.Encode())
+	if _, err := c.get(ctx, path, &response); err != nil {
+		return nil, fmt.Errorf("failed to list runs of workflow %s: %w", workflow, err)
+	}
+
+	sort.SliceStable(response.WorkflowRuns, func(i, j int) bool {
+		return response.WorkflowRuns[i].CreatedAt.After(response.WorkflowRuns[j].CreatedAt)
+	})
+
+	return response.WorkflowRuns, nil
+}
+
+// BranchRuns returns the runs triggered by the given event on a branch, newest first
+func (c *Client) BranchRuns(ctx context.Context, owner, repo, branch, event string) ([]WorkflowRun, error) {
+	var response struct {
+		WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+	}
+
+	query := url.Values{}
+	query.Set("branch", branch)
+	query.Set("event", event)
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs?%s", owner, repo, query.Encode())
+	if _, err := c.get(ctx, path, &response); err != nil {
+		return nil, fmt.Errorf("failed to list workflow runs of branch %s: %w", branch, err)
+	}
+
+	sort.SliceStable(response.WorkflowRuns, func(i, j int) bool {
+		return response.WorkflowRuns[i].CreatedAt.After(response.WorkflowRuns[j].CreatedAt)
+	})
+
+	return response.WorkflowRuns, nil
+}
+
+// GetWorkflowRun returns a workflow run by ID
+func (c *Client) GetWorkflowRun(ctx context.Context, owner, repo string, runID int64) (*WorkflowRun, error) {
+	var run WorkflowRun
+
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d", owner, repo, runID)
+	if _, err := c.get(ctx, path, &run); err != nil {
+		return nil, fmt.Errorf("failed to get workflow run %d: %w", runID, err)
+	}
+
+	return &run, nil
+}
+
+// CancelWorkflowRun cancels a queued or in progress workflow run
+func (c *Client) CancelWorkflowRun(ctx context.Context, owner, repo string, runID int64) error {
+	path := fmt.Sprintf("/repos/%s/%s/actions/runs/%d/cancel", owner, repo, runID)
+	if _, err := c.post(ctx, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to cancel workflow run %d: %w", runID, err)
+	}
+
+	return nil
+}
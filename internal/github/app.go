@@ -0,0 +1,203 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// appTokenRefreshMargin is how long before it expires a cached installation
+// token is replaced, so requests never use a token that expires on the way
+const appTokenRefreshMargin = 5 * time.Minute
+
+// appAuth exchanges the credentials of a GitHub App for installation access
+// tokens, which GitHub issues for an hour, and caches them until shortly
+// before they expire
+type appAuth struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	now            func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// appAuthKey identifies the installation of a GitHub App on a GitHub
+// instance, github.com and GitHub Enterprise Server tokens are never shared
+type appAuthKey struct {
+	baseURL        string
+	appID          int64
+	installationID int64
+}
+
+var (
+	appAuthsMu sync.Mutex
+	// appAuths are shared by the clients of an installation, so a long running
+	// command like the dashboard reuses its token
+	appAuths = map[appAuthKey]*appAuth{}
+)
+
+// NewAppClient creates a GitHub API client authenticating as an installation
+// of a GitHub App, given the app's PEM encoded private key. Installation
+// tokens are created and renewed as needed.
+func NewAppClient(appID, installationID int64, privateKey []byte) (*Client, error) {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewClient("")
+	client.app = sharedAppAuth(client.baseURL, appID, installationID, key)
+	return client, nil
+}
+
+// sharedAppAuth returns the token cache of an installation on the GitHub
+// instance at baseURL, replacing it when the app's key changed
+func sharedAppAuth(baseURL string, appID, installationID int64, key *rsa.PrivateKey) *appAuth {
+	appAuthsMu.Lock()
+	defer appAuthsMu.Unlock()
+	id := appAuthKey{baseURL: baseURL, appID: appID, installationID: installationID}
+	auth, ok := appAuths[id]
+	if !ok || !auth.key.Equal(key) {
+		auth = &appAuth{
+			appID:          appID,
+			installationID: installationID,
+			key:            key,
+			now:            time.Now,
+		}
+		appAuths[id] = auth
+	}
+	return auth
+}
+
+// parsePrivateKey parses the PKCS#1 or PKCS#8 encoded RSA key of a GitHub App
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("GitHub App private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("GitHub App private key must be an RSA key")
+	}
+	return key, nil
+}
+
+// appJWT returns the JSON Web Token a GitHub App authenticates with to create
+// installation tokens. It is valid for 10 minutes, starting a minute in the
+// past to allow for clock drift.
+func appJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	claims, err := json.Marshal(struct {
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Issuer    string `json:"iss"`
+	}{
+		IssuedAt:  now.Add(-time.Minute).Unix(),
+		ExpiresAt: now.Add(9 * time.Minute).Unix(),
+		Issuer:    strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	signingInput := encoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + encoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App token: %w", err)
+	}
+	return signingInput + "." + encoding.EncodeToString(signature), nil
+}
+
+// installationToken returns the cached installation token, creating a new
+// one through c when there is none or it is about to expire
+func (a *appAuth) installationToken(ctx context.Context, c *Client) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if a.token != "" && now.Add(appTokenRefreshMargin).Before(a.expires) {
+		return a.token, nil
+	}
+
+	jwt, err := appJWT(a.appID, a.key, now)
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", a.installationID)
+	if _, err := c.doWithToken(ctx, http.MethodPost, path, jwt, nil, &token); err != nil {
+		return "", fmt.Errorf("failed to create GitHub App installation token: %w", err)
+	}
+
+	a.token, a.expires = token.Token, token.ExpiresAt
+	return a.token, nil
+}
+
+// ValidateAppInstallation verifies that the client can authenticate as its
+// GitHub App installation, that the installation belongs to the owner of the
+// given repository or organization URL and, for repositories, can access it
+func (c *Client) ValidateAppInstallation(ctx context.Context, configURL string) error {
+	if c.app == nil {
+		return fmt.Errorf("client does not authenticate as a GitHub App")
+	}
+	target, err := ParseConfigURL(configURL)
+	if err != nil {
+		return err
+	}
+
+	client := c
+	if target.APIBaseURL != defaultBaseURL {
+		client = c.WithBaseURL(target.APIBaseURL)
+	}
+
+	jwt, err := appJWT(c.app.appID, c.app.key, c.app.now())
+	if err != nil {
+		return err
+	}
+	var installation struct {
+		Account struct {
+			Login string `json:"login"`
+		} `json:"account"`
+	}
+	path := fmt.Sprintf("/app/installations/%d", c.app.installationID)
+	if _, err := client.doWithToken(ctx, http.MethodGet, path, jwt, nil, &installation); err != nil {
+		return fmt.Errorf("GitHub App %d has no installation %d: %w", c.app.appID, c.app.installationID, err)
+	}
+	if !strings.EqualFold(installation.Account.Login, target.Owner) {
+		return fmt.Errorf("GitHub App installation %d belongs to %s, not to %s", c.app.installationID, installation.Account.Login, target.Owner)
+	}
+
+	if target.IsOrganization() {
+		_, err := c.app.installationToken(ctx, client)
+		return err
+	}
+	_, err = client.get(ctx, fmt.Sprintf("/repos/%s/%s", target.Owner, target.Repo), nil)
+	return err
+}
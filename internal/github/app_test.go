@@ -0,0 +1,139 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestAppJWT(t *testing.T) {
+	key, _ := newTestAppKey(t)
+	now := time.Unix(1700000000, 0)
+
+	jwt, err := appJWT(42, key, now)
+	if err != nil {
+		t.Fatalf("appJWT() error = %v", err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("appJWT() = %q, want three parts", jwt)
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("invalid claims: %v", err)
+	}
+	if claims["iss"] != "42" || claims["iat"] != float64(now.Unix()-60) || claims["exp"] != float64(now.Unix()+540) {
+		t.Errorf("claims = %v", claims)
+	}
+}
+
+func TestAppClientCachesInstallationTokens(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	exchanges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/7/access_tokens":
+			exchanges++
+			expires := now.Add(time.Hour).UTC().Format(time.RFC3339)
+			_, _ = fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":%q}`, exchanges, expires)
+		case "/repos/owner/repo":
+			if r.Header.Get("Authorization") != fmt.Sprintf("Bearer ghs_%d", exchanges) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"default_branch":"main"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	_, keyPEM := newTestAppKey(t)
+	client, err := NewAppClient(42, 7, keyPEM)
+	if err != nil {
+		t.Fatalf("NewAppClient() error = %v", err)
+	}
+	client = client.WithBaseURL(server.URL)
+	client.app.now = func() time.Time { return now }
+
+	for range 2 {
+		if _, err := client.DefaultBranch(context.Background(), "owner", "repo"); err != nil {
+			t.Fatalf("DefaultBranch() error = %v", err)
+		}
+	}
+	if exchanges != 1 {
+		t.Errorf("exchanged %d tokens, want 1 cached token", exchanges)
+	}
+
+	// A token about to expire is replaced
+	now = now.Add(58 * time.Minute)
+	if _, err := client.DefaultBranch(context.Background(), "owner", "repo"); err != nil {
+		t.Fatalf("DefaultBranch() error = %v", err)
+	}
+	if exchanges != 2 {
+		t.Errorf("exchanged %d tokens, want a new token before expiry", exchanges)
+	}
+}
+
+func TestAppClientTokensPerBaseURL(t *testing.T) {
+	_, keyPEM := newTestAppKey(t)
+	client, err := NewAppClient(42, 8, keyPEM)
+	if err != nil {
+		t.Fatalf("NewAppClient() error = %v", err)
+	}
+
+	enterprise := client.WithBaseURL("https://github.example.com/api/v3/")
+	if enterprise.app == client.app {
+		t.Errorf("github.com and GitHub Enterprise Server clients share installation tokens")
+	}
+	if again := client.WithBaseURL("https://github.example.com/api/v3"); again.app != enterprise.app {
+		t.Errorf("clients of the same GitHub Enterprise Server don't share installation tokens")
+	}
+	if client.WithBaseURL(defaultBaseURL).app != client.app {
+		t.Errorf("github.com clients don't share installation tokens")
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	if _, err := parsePrivateKey([]byte("not a key")); err == nil {
+		t.Errorf("parsePrivateKey() accepted a key that is not PEM encoded")
+	}
+
+	key, _ := newTestAppKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	if _, err := parsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err != nil {
+		t.Errorf("parsePrivateKey() PKCS#8 error = %v", err)
+	}
+}
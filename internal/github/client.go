@@ -0,0 +1,312 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://api.github.com"
+
+// Client is a minimal GitHub REST API client used to validate credentials and
+// to inspect and dispatch GitHub Actions workflows
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	// app creates the token of clients authenticating as a GitHub App installation
+	app *appAuth
+}
+
+// TokenInfo describes the user a token authenticates as
+type TokenInfo struct {
+	Login string
+	// Scopes lists the OAuth scopes of a classic PAT. Fine-grained tokens
+	// do not report scopes, in which case Scopes is empty.
+	Scopes []string
+}
+
+// NewClient creates a GitHub API client authenticating with the given token
+func NewClient(token string) *Client {
+	return &Client{
+		baseURL:    defaultBaseURL,
+		token:      token,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// WithBaseURL returns a copy of the client that talks to a different API
+// endpoint, e.g. a GitHub Enterprise Server instance. Clients authenticating
+// as a GitHub App use the installation tokens of that endpoint.
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	clone := *c
+	clone.baseURL = strings.TrimSuffix(baseURL, "/")
+	if c.app != nil {
+		clone.app = sharedAppAuth(clone.baseURL, c.app.appID, c.app.installationID, c.app.key)
+	}
+	return &clone
+}
+
+// CheckToken verifies that the token is valid and returns the user it belongs to
+func (c *Client) CheckToken(ctx context.Context) (*TokenInfo, error) {
+	var user struct {
+		Login string `json:"login"`
+	}
+
+	resp, err := c.get(ctx, "/user", &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenInfo{
+		Login:  user.Login,
+		Scopes: parseScopes(resp.Header.Get("X-OAuth-Scopes")),
+	}, nil
+}
+
+// ValidateRunnerToken verifies that the token can register runners for the
+// given repository or organization URL: the token must be valid, carry the
+// required scopes (classic PATs only) and have admin access to the target.
+func (c *Client) ValidateRunnerToken(ctx context.Context, configURL string) (*TokenInfo, error) {
+	target, err := ParseConfigURL(configURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c
+	if target.APIBaseURL != defaultBaseURL {
+		client = c.WithBaseURL(target.APIBaseURL)
+	}
+
+	info, err := client.CheckToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if missing := MissingScopes(info.Scopes, target.IsOrganization()); len(missing) > 0 {
+		return info, fmt.Errorf("token is missing required scope(s): %s", strings.Join(missing, ", "))
+	}
+
+	if target.IsOrganization() {
+		err = client.checkOrgAdmin(ctx, target.Owner)
+	} else {
+		err = client.checkRepoAdmin(ctx, target.Owner, target.Repo)
+	}
+	if err != nil {
+		return info, err
+	}
+
+	return info, nil
+}
+
+// checkRepoAdmin verifies that the authenticated user has admin access to a repository
+func (c *Client) checkRepoAdmin(ctx context.Context, owner, repo string) error {
+	var repository struct {
+		Permissions struct {
+			Admin bool `json:"admin"`
+		} `json:"permissions"`
+	}
+
+	if _, err := c.get(ctx, fmt.Sprintf("/repos/%s/%s", owner, repo), &repository); err != nil {
+		return err
+	}
+
+	if !repository.Permissions.Admin {
+		return fmt.Errorf("token does not have admin access to %s/%s, which is required to register runners", owner, repo)
+	}
+
+	return nil
+}
+
+// checkOrgAdmin verifies that the authenticated user is an admin of an organization
+func (c *Client) checkOrgAdmin(ctx context.Context, org string) error {
+	var membership struct {
+		State string `json:"state"`
+		Role  string `json:"role"`
+	}
+
+	if _, err := c.get(ctx, fmt.Sprintf("/user/memberships/orgs/%s", org), &membership); err != nil {
+		return err
+	}
+
+	if membership.State != "active" || membership.Role != "admin" {
+		return fmt.Errorf("token user is not an admin of organization %s (role %q, state %q), which is required to register runners", org, membership.Role, membership.State)
+	}
+
+	return nil
+}
+
+// get performs an authenticated GET request and decodes the JSON response into out
+func (c *Client) get(ctx context.Context, path string, out any) (*http.Response, error) {
+	return c.do(ctx, http.MethodGet, path, nil, out)
+}
+
+// post performs an authenticated POST request with a JSON body and decodes
+// the JSON response, if any, into out
+func (c *Client) post(ctx context.Context, path string, body, out any) (*http.Response, error) {
+	return c.do(ctx, http.MethodPost, path, body, out)
+}
+
+// do performs an authenticated request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out any) (*http.Response, error) {
+	token := c.token
+	if c.app != nil {
+		var err error
+		if token, err = c.app.installationToken(ctx, c); err != nil {
+			return nil, err
+		}
+	}
+	return c.doWithToken(ctx, method, path, token, body, out)
+}
+
+// doWithToken performs a request authenticated with the given token and
+// decodes the JSON response into out
+func (c *Client) doWithToken(ctx context.Context, method, path, token string, body, out any) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach GitHub API: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, &APIError{StatusCode: resp.StatusCode, Path: path, Message: errorMessage(resp)}
+	}
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp, fmt.Errorf("failed to parse GitHub API response: %w", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// APIError is returned when the GitHub API responds with an error status
+type APIError struct {
+	StatusCode int
+	Path       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return "GitHub rejected the token (401 Unauthorized): the token is invalid or expired"
+	case http.StatusForbidden:
+		return fmt.Sprintf("GitHub denied access to %s (403 Forbidden): %s", e.Path, e.Message)
+	case http.StatusNotFound:
+		return fmt.Sprintf("GitHub returned 404 Not Found for %s: it does not exist or the token cannot see it", e.Path)
+	default:
+		return fmt.Sprintf("GitHub API request %s failed with status %d: %s", e.Path, e.StatusCode, e.Message)
+	}
+}
+
+// errorMessage extracts the message field of a GitHub API error response
+func errorMessage(resp *http.Response) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Message == "" {
+		return http.StatusText(resp.StatusCode)
+	}
+	return body.Message
+}
+
+// parseScopes splits the comma separated X-OAuth-Scopes header
+func parseScopes(header string) []string {
+	var scopes []string
+	for _, scope := range strings.Split(header, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// MissingScopes returns the OAuth scopes a classic PAT needs for runner
+// registration but lacks. Fine-grained tokens report no scopes and are not checked.
+func MissingScopes(scopes []string, organizationLevel bool) []string {
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	required := []string{"repo"}
+	if organizationLevel {
+		required = []string{"admin:org"}
+	}
+
+	var missing []string
+	for _, scope := range required {
+		if !slices.Contains(scopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// ConfigURL is a parsed GitHub repository or organization URL
+type ConfigURL struct {
+	APIBaseURL string
+	Owner      string
+	Repo       string // Empty for organization URLs
+}
+
+// IsOrganization returns true if the URL points to an organization
+func (u *ConfigURL) IsOrganization() bool {
+	return u.Repo == ""
+}
+
+// ParseConfigURL parses a repository (https://github.com/owner/repo) or
+// organization (https://github.com/org) URL. URLs of other hosts are treated
+// as GitHub Enterprise Server, whose API is served under /api/v3.
+func ParseConfigURL(configURL string) (*ConfigURL, error) {
+	parsed, err := url.Parse(configURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid GitHub URL: %s", configURL)
+	}
+
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if segments[0] == "" || len(segments) > 2 {
+		return nil, fmt.Errorf("GitHub URL must point to a repository or organization: %s", configURL)
+	}
+
+	target := &ConfigURL{
+		APIBaseURL: defaultBaseURL,
+		Owner:      segments[0],
+	}
+	if len(segments) == 2 {
+		target.Repo = segments[1]
+	}
+	if host := strings.ToLower(parsed.Host); host != "github.com" && host != "www.github.com" {
+		target.APIBaseURL = fmt.Sprintf("%s://%s/api/v3", parsed.Scheme, parsed.Host)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,319 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ghp_valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		w.Header().Set("X-OAuth-Scopes", "repo, workflow")
+		_, _ = w.Write([]byte(`{"login":"octocat"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	info, err := NewClient("ghp_valid").WithBaseURL(server.URL).CheckToken(context.Background())
+	if err != nil {
+		t.Fatalf("CheckToken() error = %v", err)
+	}
+	if info.Login != "octocat" {
+		t.Errorf("Login = %v, want octocat", info.Login)
+	}
+	if want := []string{"repo", "workflow"}; !reflect.DeepEqual(info.Scopes, want) {
+		t.Errorf("Scopes = %v, want %v", info.Scopes, want)
+	}
+
+	_, err = NewClient("ghp_invalid").WithBaseURL(server.URL).CheckToken(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("CheckToken() with invalid token error = %v, want 401 APIError", err)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{header: "", want: nil},
+		{header: "repo", want: []string{"repo"}},
+		{header: "admin:org, repo,workflow", want: []string{"admin:org", "repo", "workflow"}},
+	}
+
+	for _, tt := range tests {
+		if got := parseScopes(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseScopes(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRunnerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user":
+			w.Header().Set("X-OAuth-Scopes", "repo")
+			_, _ = w.Write([]byte(`{"login":"octocat"}`))
+		case "/repos/owner/admin-repo":
+			_, _ = w.Write([]byte(`{"permissions":{"admin":true}}`))
+		case "/repos/owner/read-repo":
+			_, _ = w.Write([]byte(`{"permissions":{"admin":false}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("ghp_token").WithBaseURL(server.URL)
+
+	tests := []struct {
+		name      string
+		configURL string
+		wantErr   bool
+	}{
+		{name: "admin access", configURL: "https://github.com/owner/admin-repo", wantErr: false},
+		{name: "no admin access", configURL: "https://github.com/owner/read-repo", wantErr: true},
+		{name: "missing repository", configURL: "https://github.com/owner/missing", wantErr: true},
+		{name: "organization without admin:org scope", configURL: "https://github.com/myorg", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := client.ValidateRunnerToken(context.Background(), tt.configURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateRunnerToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if info == nil || info.Login != "octocat" {
+				t.Errorf("ValidateRunnerToken() info = %+v, want login octocat", info)
+			}
+		})
+	}
+}
+
+func TestParseConfigURL(t *testing.T) {
+	tests := []struct {
+		configURL string
+		want      *ConfigURL
+		wantErr   bool
+	}{
+		{
+			configURL: "https://github.com/owner/repo",
+			want:      &ConfigURL{APIBaseURL: "https://api.github.com", Owner: "owner", Repo: "repo"},
+		},
+		{
+			configURL: "https://github.com/myorg/",
+			want:      &ConfigURL{APIBaseURL: "https://api.github.com", Owner: "myorg"},
+		},
+		{
+			configURL: "https://ghes.example.com/owner/repo",
+			want:      &ConfigURL{APIBaseURL: "https://ghes.example.com/api/v3", Owner: "owner", Repo: "repo"},
+		},
+		{configURL: "https://github.com/", wantErr: true},
+		{configURL: "https://github.com/owner/repo/issues", wantErr: true},
+		{configURL: "not a url", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseConfigURL(tt.configURL)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseConfigURL(%q) error = %v, wantErr %v", tt.configURL, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseConfigURL(%q) = %+v, want %+v", tt.configURL, got, tt.want)
+		}
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	tests := []struct {
+		name              string
+		scopes            []string
+		organizationLevel bool
+		want              []string
+	}{
+		{name: "fine-grained token", scopes: nil, want: nil},
+		{name: "repository token with repo scope", scopes: []string{"repo", "workflow"}, want: nil},
+		{name: "repository token without repo scope", scopes: []string{"workflow"}, want: []string{"repo"}},
+		{name: "organization token without admin:org", scopes: []string{"repo"}, organizationLevel: true, want: []string{"admin:org"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MissingScopes(tt.scopes, tt.organizationLevel); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MissingScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActiveJobs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/runs":
+			switch r.URL.Query().Get("status") {
+			case "queued":
+				_, _ = w.Write([]byte(`{"workflow_runs":[{"id":2}]}`))
+			case "in_progress":
+				_, _ = w.Write([]byte(`{"workflow_runs":[{"id":1},{"id":2}]}`))
+			}
+		case "/repos/owner/repo/actions/runs/1/jobs":
+			_, _ = w.Write([]byte(`{"jobs":[
+				{"id":10,"name":"build","status":"completed","labels":["my-runner"],"created_at":"2024-01-01T10:00:00Z"},
+				{"id":11,"name":"test","status":"in_progress","labels":["my-runner"],"runner_name":"my-runner-abc","created_at":"2024-01-01T10:01:00Z"}
+			]}`))
+		case "/repos/owner/repo/actions/runs/2/jobs":
+			_, _ = w.Write([]byte(`{"jobs":[
+				{"id":20,"name":"deploy","status":"queued","labels":["ubuntu-latest"],"created_at":"2024-01-01T09:00:00Z"}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	jobs, err := NewClient("ghp_token").WithBaseURL(server.URL).ActiveJobs(context.Background(), "owner", "repo")
+	if err != nil {
+		t.Fatalf("ActiveJobs() error = %v", err)
+	}
+
+	var ids []int64
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+	if want := []int64{20, 11}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ActiveJobs() job IDs = %v, want %v", ids, want)
+	}
+	if !jobs[1].Targets("my-runner") || jobs[0].Targets("my-runner") {
+		t.Errorf("Targets() did not match job labels: %+v", jobs)
+	}
+}
+
+func TestRecentJobs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/runs":
+			if r.URL.Query().Get("status") != "completed" || r.URL.Query().Get("per_page") != "2" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"workflow_runs":[{"id":1},{"id":2}]}`))
+		case "/repos/owner/repo/actions/runs/1/jobs":
+			_, _ = w.Write([]byte(`{"jobs":[
+				{"id":10,"name":"build","status":"completed","conclusion":"success","completed_at":"2024-01-01T10:05:00Z"}
+			]}`))
+		case "/repos/owner/repo/actions/runs/2/jobs":
+			_, _ = w.Write([]byte(`{"jobs":[
+				{"id":20,"name":"test","status":"completed","conclusion":"failure","completed_at":"2024-01-01T10:10:00Z"}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	jobs, err := NewClient("ghp_token").WithBaseURL(server.URL).RecentJobs(context.Background(), "owner", "repo", 2)
+	if err != nil {
+		t.Fatalf("RecentJobs() error = %v", err)
+	}
+
+	if len(jobs) != 2 || jobs[0].ID != 20 || jobs[1].ID != 10 {
+		t.Fatalf("RecentJobs() = %+v, want jobs 20 and 10", jobs)
+	}
+	if jobs[0].Conclusion != "failure" {
+		t.Errorf("Conclusion = %q, want failure", jobs[0].Conclusion)
+	}
+}
+
+func TestDispatchWorkflow(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/owner/repo/actions/workflows/ci.yml/dispatches" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("ghp_token").WithBaseURL(server.URL)
+	if err := client.DispatchWorkflow(context.Background(), "owner", "repo", "ci.yml", "main", map[string]string{"env": "staging"}); err != nil {
+		t.Fatalf("DispatchWorkflow() error = %v", err)
+	}
+
+	want := map[string]any{"ref": "main", "inputs": map[string]any{"env": "staging"}}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("DispatchWorkflow() body = %v, want %v", body, want)
+	}
+
+	if err := client.DispatchWorkflow(context.Background(), "owner", "repo", "missing.yml", "main", nil); err == nil {
+		t.Error("DispatchWorkflow() expected an error for an unknown workflow")
+	}
+}
+
+func TestDispatchedRuns(t *testing.T) {
+	since := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/repos/owner/repo/actions/workflows/ci.yml/runs" ||
+			query.Get("event") != "workflow_dispatch" || query.Get("branch") != "main" ||
+			query.Get("created") != ">=2024-01-01T10:00:00Z" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"workflow_runs":[
+			{"id":1,"status":"completed","created_at":"2024-01-01T10:00:30Z"},
+			{"id":2,"status":"queued","created_at":"2024-01-01T10:01:00Z"}
+		]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	runs, err := NewClient("ghp_token").WithBaseURL(server.URL).DispatchedRuns(context.Background(), "owner", "repo", "ci.yml", "main", since)
+	if err != nil {
+		t.Fatalf("DispatchedRuns() error = %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != 2 || runs[1].ID != 1 {
+		t.Fatalf("DispatchedRuns() = %+v, want runs 2 and 1", runs)
+	}
+}
+
+func TestCreateFile(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.EscapedPath() != "/repos/owner/repo/contents/.github/workflows/test%20me.yml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("ghp_token").WithBaseURL(server.URL)
+	if err := client.CreateFile(context.Background(), "owner", "repo", "test", ".github/workflows/test me.yml", "Add test", []byte("on: push\n")); err != nil {
+		t.Fatalf("CreateFile() error = %v", err)
+	}
+
+	want := map[string]string{"message": "Add test", "content": "b246IHB1c2gK", "branch": "test"}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("CreateFile() body = %v, want %v", body, want)
+	}
+}
@@ -0,0 +1,73 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BranchSHA returns the commit SHA a branch points to
+func (c *Client) BranchSHA(ctx context.Context, owner, repo, branch string) (string, error) {
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/git/ref/heads/%s", owner, repo, escapePath(branch))
+	if _, err := c.get(ctx, path, &ref); err != nil {
+		return "", fmt.Errorf("failed to get branch %s: %w", branch, err)
+	}
+
+	return ref.Object.SHA, nil
+}
+
+// CreateBranch creates a branch pointing to the given commit SHA
+func (c *Client) CreateBranch(ctx context.Context, owner, repo, branch, sha string) error {
+	body := map[string]string{"ref": "refs/heads/" + branch, "sha": sha}
+
+	path := fmt.Sprintf("/repos/%s/%s/git/refs", owner, repo)
+	if _, err := c.post(ctx, path, body, nil); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", branch, err)
+	}
+
+	return nil
+}
+
+// DeleteBranch deletes a branch
+func (c *Client) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	path := fmt.Sprintf("/repos/%s/%s/git/refs/heads/%s", owner, repo, escapePath(branch))
+	if _, err := c.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
+	}
+
+	return nil
+}
+
+// CreateFile commits a new file to a branch
+func (c *Client) CreateFile(ctx context.Context, owner, repo, branch, filePath, message string, content []byte) error {
+	body := map[string]string{
+		"message": message,
+		"content": base64.StdEncoding.EncodeToString(content),
+		"branch":  branch,
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/contents/%s", owner, repo, escapePath(filePath))
+	if _, err := c.do(ctx, http.MethodPut, path, body, nil); err != nil {
+		return fmt.Errorf("failed to create %s on branch %s: %w", filePath, branch, err)
+	}
+
+	return nil
+}
+
+// escapePath escapes the segments of a slash separated path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,166 @@
+// Package host reads the power state and CPU load of the machine deskrun
+// runs on, for the policies of 'deskrun agent'
+package host
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	linuxPowerSupplyDir = "/sys/class/power_supply"
+	linuxLoadAvgPath    = "/proc/loadavg"
+)
+
+// State is the power state and CPU load of the host
+type State struct {
+	// OnBattery is true while the host is running on battery power
+	OnBattery bool
+	// BatteryPercent is the battery charge, -1 when the host has no battery
+	BatteryPercent int
+	// Load is the 1-minute load average divided by the number of CPUs
+	Load float64
+}
+
+// ReadState reads the current state of the host. Linux and macOS are supported.
+func ReadState(ctx context.Context) (State, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return readLinuxState(linuxPowerSupplyDir, linuxLoadAvgPath, runtime.NumCPU())
+	case "darwin":
+		return readDarwinState(ctx, runtime.NumCPU())
+	default:
+		return State{}, fmt.Errorf("reading the host state is not supported on %s", runtime.GOOS)
+	}
+}
+
+func readLinuxState(powerSupplyDir, loadAvgPath string, cpus int) (State, error) {
+	state, err := readLinuxPowerSupply(powerSupplyDir)
+	if err != nil {
+		return State{}, err
+	}
+
+	data, err := os.ReadFile(loadAvgPath)
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read load average: %w", err)
+	}
+	load, err := parseLoadAverage(string(data))
+	if err != nil {
+		return State{}, err
+	}
+	state.Load = load / float64(cpus)
+	return state, nil
+}
+
+// readLinuxPowerSupply reads the battery and AC adapter state from sysfs. The
+// host is on battery when it has a battery and no AC adapter is online, or,
+// without an AC adapter that reports its state, when a battery discharges.
+func readLinuxPowerSupply(dir string) (State, error) {
+	state := State{BatteryPercent: -1}
+
+	supplies, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read power supplies: %w", err)
+	}
+
+	var hasBattery, hasMains, mainsOnline, discharging bool
+	for _, supply := range supplies {
+		path := filepath.Join(dir, supply.Name())
+		switch readSysfsValue(path, "type") {
+		case "Mains":
+			hasMains = true
+			if readSysfsValue(path, "online") == "1" {
+				mainsOnline = true
+			}
+		case "Battery":
+			// Batteries of peripherals (mice, headsets) are not the host's
+			if readSysfsValue(path, "scope") == "Device" {
+				continue
+			}
+			hasBattery = true
+			if readSysfsValue(path, "status") == "Discharging" {
+				discharging = true
+			}
+			if percent, err := strconv.Atoi(readSysfsValue(path, "capacity")); err == nil {
+				state.BatteryPercent = percent
+			}
+		}
+	}
+
+	if hasMains {
+		state.OnBattery = hasBattery && !mainsOnline
+	} else {
+		state.OnBattery = discharging
+	}
+	return state, nil
+}
+
+// readSysfsValue returns the trimmed content of an attribute of a sysfs
+// device, or an empty string if it can't be read
+func readSysfsValue(device, attribute string) string {
+	data, err := os.ReadFile(filepath.Join(device, attribute))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// parseLoadAverage returns the 1-minute load average of /proc/loadavg
+func parseLoadAverage(loadAvg string) (float64, error) {
+	fields := strings.Fields(loadAvg)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("failed to parse load average %q", loadAvg)
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse load average %q: %w", loadAvg, err)
+	}
+	return load, nil
+}
+
+func readDarwinState(ctx context.Context, cpus int) (State, error) {
+	output, err := exec.CommandContext(ctx, "pmset", "-g", "batt").Output()
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read power state: %w", err)
+	}
+	state := parsePmset(string(output))
+
+	output, err = exec.CommandContext(ctx, "sysctl", "-n", "vm.loadavg").Output()
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read load average: %w", err)
+	}
+	// vm.loadavg is formatted as "{ 1.52 1.60 1.71 }"
+	load, err := parseLoadAverage(strings.Trim(strings.TrimSpace(string(output)), "{ }"))
+	if err != nil {
+		return State{}, err
+	}
+	state.Load = load / float64(cpus)
+	return state, nil
+}
+
+var pmsetPercentRegexp = regexp.MustCompile(`(\d+)%;`)
+
+// parsePmset parses the output of 'pmset -g batt', e.g.:
+//
+//	Now drawing from 'Battery Power'
+//	 -InternalBattery-0 (id=4653155)	85%; discharging; 4:12 remaining present: true
+func parsePmset(output string) State {
+	state := State{
+		OnBattery:      strings.Contains(output, "'Battery Power'"),
+		BatteryPercent: -1,
+	}
+	if match := pmsetPercentRegexp.FindStringSubmatch(output); match != nil {
+		state.BatteryPercent, _ = strconv.Atoi(match[1])
+	}
+	return state
+}
@@ -0,0 +1,144 @@
+package host
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePowerSupply(t *testing.T, dir, name string, attributes map[string]string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for attribute, value := range attributes {
+		if err := os.WriteFile(filepath.Join(path, attribute), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadLinuxPowerSupply(t *testing.T) {
+	tests := []struct {
+		name     string
+		supplies map[string]map[string]string
+		want     State
+	}{
+		{
+			name: "unplugged laptop",
+			supplies: map[string]map[string]string{
+				"AC":   {"type": "Mains", "online": "0"},
+				"BAT0": {"type": "Battery", "status": "Discharging", "capacity": "64"},
+			},
+			want: State{OnBattery: true, BatteryPercent: 64},
+		},
+		{
+			name: "plugged in laptop",
+			supplies: map[string]map[string]string{
+				"AC":   {"type": "Mains", "online": "1"},
+				"BAT0": {"type": "Battery", "status": "Charging", "capacity": "80"},
+			},
+			want: State{BatteryPercent: 80},
+		},
+		{
+			name: "battery without AC adapter",
+			supplies: map[string]map[string]string{
+				"BAT1": {"type": "Battery", "status": "Discharging", "capacity": "30"},
+			},
+			want: State{OnBattery: true, BatteryPercent: 30},
+		},
+		{
+			name: "desktop with a wireless mouse",
+			supplies: map[string]map[string]string{
+				"hidpp_battery_0": {"type": "Battery", "scope": "Device", "status": "Discharging", "capacity": "10"},
+			},
+			want: State{BatteryPercent: -1},
+		},
+		{
+			name: "desktop",
+			want: State{BatteryPercent: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, attributes := range tt.supplies {
+				writePowerSupply(t, dir, name, attributes)
+			}
+
+			got, err := readLinuxPowerSupply(dir)
+			if err != nil {
+				t.Fatalf("readLinuxPowerSupply() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readLinuxPowerSupply() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLinuxState(t *testing.T) {
+	dir := t.TempDir()
+	loadAvg := filepath.Join(dir, "loadavg")
+	if err := os.WriteFile(loadAvg, []byte("6.00 4.50 3.25 2/1234 56789\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readLinuxState(filepath.Join(dir, "missing"), loadAvg, 4)
+	if err != nil {
+		t.Fatalf("readLinuxState() error = %v", err)
+	}
+	want := State{BatteryPercent: -1, Load: 1.5}
+	if got != want {
+		t.Errorf("readLinuxState() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParsePmset(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   State
+	}{
+		{
+			name: "on battery",
+			output: "Now drawing from 'Battery Power'\n" +
+				" -InternalBattery-0 (id=4653155)\t85%; discharging; 4:12 remaining present: true\n",
+			want: State{OnBattery: true, BatteryPercent: 85},
+		},
+		{
+			name: "on AC",
+			output: "Now drawing from 'AC Power'\n" +
+				" -InternalBattery-0 (id=4653155)\t100%; charged; 0:00 remaining present: true\n",
+			want: State{BatteryPercent: 100},
+		},
+		{
+			name:   "without battery",
+			output: "Now drawing from 'AC Power'\n",
+			want:   State{BatteryPercent: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePmset(tt.output); got != tt.want {
+				t.Errorf("parsePmset() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLoadAverage(t *testing.T) {
+	if _, err := parseLoadAverage(""); err == nil {
+		t.Error("parseLoadAverage() expected an error for empty input")
+	}
+	got, err := parseLoadAverage("1.52 1.60 1.71")
+	if err != nil {
+		t.Fatalf("parseLoadAverage() error = %v", err)
+	}
+	if got != 1.52 {
+		t.Errorf("parseLoadAverage() = %v, want 1.52", got)
+	}
+}
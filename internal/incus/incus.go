@@ -1,165 +1,336 @@
 package incus
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+
+	incus "github.com/lxc/incus/v6/client"
+	"github.com/lxc/incus/v6/shared/api"
+	"github.com/lxc/incus/v6/shared/cliconfig"
 )
 
-type Manager struct{}
+// bridgeNetwork is the managed bridge cluster hosts are attached to
+const bridgeNetwork = "incusbr0"
+
+// Manager manages cluster host instances on an incus remote through the Incus API
+type Manager struct {
+	config *cliconfig.Config
+	remote string
+	server incus.InstanceServer
+}
 
 type ContainerInfo struct {
 	Name   string
 	Status string
 }
 
-func NewManager() *Manager {
-	return &Manager{}
+// VMOptions are the resources and provisioning of a virtual machine
+type VMOptions struct {
+	CPUs     int    // number of vCPUs
+	Memory   string // memory limit, e.g. 8GiB
+	UserData string // cloud-init user-data, empty for images without cloud-init
 }
 
-func (m *Manager) CreateContainer(ctx context.Context, name, image, diskSize, storagePool string) error {
-	if name == "" {
-		return fmt.Errorf("container name cannot be empty")
+// NewManager connects to a remote of the incus client configuration. An empty
+// remote selects the default remote, the local daemon unless
+// 'incus remote switch' selected another.
+func NewManager(remote string) (*Manager, error) {
+	config, err := loadClientConfig()
+	if err != nil {
+		return nil, err
 	}
-	if strings.ContainsAny(name, " /\\:@#$%^&*()[]{}!?'\"<>,;|`~+=") {
-		return fmt.Errorf("container name contains invalid characters: %s", name)
+
+	if remote == "" {
+		remote = config.DefaultRemote
 	}
-	if image == "" {
-		return fmt.Errorf("image cannot be empty")
+	if _, ok := config.Remotes[remote]; !ok {
+		return nil, fmt.Errorf("unknown incus remote %s, add it with 'incus remote add'", remote)
 	}
-	if diskSize == "" {
-		return fmt.Errorf("disk size cannot be empty")
+
+	server, err := config.GetInstanceServer(remote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to incus remote %s: %w", remote, err)
 	}
-	if !strings.HasSuffix(diskSize, "GiB") && !strings.HasSuffix(diskSize, "GB") &&
-		!strings.HasSuffix(diskSize, "MiB") && !strings.HasSuffix(diskSize, "MB") {
-		return fmt.Errorf("disk size must end with GiB, GB, MiB, or MB: %s", diskSize)
+
+	return &Manager{config: config, remote: remote, server: server}, nil
+}
+
+// Remote returns the name of the incus remote the manager is connected to
+func (m *Manager) Remote() string {
+	return m.remote
+}
+
+// loadClientConfig loads the incus client configuration like the incus CLI
+// does, falling back to the default remotes if there is none
+func loadClientConfig() (*cliconfig.Config, error) {
+	configDir := os.Getenv("INCUS_CONF")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		configDir = filepath.Join(homeDir, ".config", "incus")
 	}
 
-	// Ensure the default bridge network exists
-	if err := m.ensureNetwork(ctx); err != nil {
-		return fmt.Errorf("failed to ensure network: %w", err)
+	configPath := filepath.Join(configDir, "config.yml")
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return cliconfig.NewConfig(configDir, true), nil
+	}
+
+	config, err := cliconfig.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load incus client config: %w", err)
+	}
+	return config, nil
+}
+
+// CreateContainer launches a privileged container, provisioned with the
+// cloud-init user-data if not empty
+func (m *Manager) CreateContainer(ctx context.Context, name, image, diskSize, storagePool, userData string) error {
+	if err := validateInstance(name, image, diskSize); err != nil {
+		return err
+	}
+
+	req := containerInstance(name, diskSize, userData)
+	if err := m.createInstance(ctx, req, image, storagePool); err != nil {
+		return fmt.Errorf("failed to create container: %w", err)
+	}
+
+	return nil
+}
+
+// CreateVM launches a virtual machine. Unlike containers, VMs run their own
+// kernel, so privileged and dind runners need no nesting or privileges.
+func (m *Manager) CreateVM(ctx context.Context, name, image, diskSize, storagePool string, opts VMOptions) error {
+	if err := validateInstance(name, image, diskSize); err != nil {
+		return err
 	}
 
-	args := []string{
-		"launch",
-		image,
-		name,
-		"-d", fmt.Sprintf("root,size=%s", diskSize),
-		"-n", "incusbr0",
-		"-c", "security.nesting=true",
-		"-c", "security.privileged=true",
+	req := vmInstance(name, image, diskSize, opts)
+	if err := m.createInstance(ctx, req, image, storagePool); err != nil {
+		return fmt.Errorf("failed to create VM: %w", err)
 	}
 
-	// Add storage pool if specified
-	if storagePool != "" {
-		args = append(args, "-s", storagePool)
+	return nil
+}
+
+// createInstance creates the instance from the image on the storage pool
+// (the pool of the default profile if empty) and starts it
+func (m *Manager) createInstance(ctx context.Context, req api.InstancesPost, image, storagePool string) error {
+	source, err := imageSource(m.config, image)
+	if err != nil {
+		return err
 	}
+	req.Source = source
 
-	cmd := exec.CommandContext(ctx, "incus", args...)
-	output, err := cmd.CombinedOutput()
+	pool, err := m.storagePool(storagePool)
 	if err != nil {
-		return fmt.Errorf("failed to create container: %w (output: %s)", err, string(output))
+		return err
+	}
+	req.Devices["root"]["pool"] = pool
+
+	// Ensure the default bridge network exists
+	if err := m.ensureNetwork(); err != nil {
+		return fmt.Errorf("failed to ensure network: %w", err)
 	}
 
-	// Add /dev/kmsg device for kubelet
-	addDeviceCmd := exec.CommandContext(ctx, "incus", "config", "device", "add", name, "kmsg", "unix-char", "source=/dev/kmsg", "path=/dev/kmsg")
-	output, err = addDeviceCmd.CombinedOutput()
+	op, err := m.server.CreateInstance(req)
 	if err != nil {
-		return fmt.Errorf("failed to add kmsg device: %w (output: %s)", err, string(output))
+		return err
+	}
+	if err := op.WaitContext(ctx); err != nil {
+		return err
 	}
 
-	return nil
+	return m.updateState(ctx, req.Name, api.InstanceStatePut{Action: "start", Timeout: -1})
+}
+
+// containerInstance returns the request that creates a privileged container
+// able to run Docker and kind
+func containerInstance(name, diskSize, userData string) api.InstancesPost {
+	req := api.InstancesPost{
+		Name: name,
+		Type: api.InstanceTypeContainer,
+		InstancePut: api.InstancePut{
+			Config: map[string]string{
+				"security.nesting":    "true",
+				"security.privileged": "true",
+			},
+			Devices: map[string]map[string]string{
+				"root": rootDisk(diskSize),
+				"eth0": nicDevice(),
+				// kubelet reads /dev/kmsg
+				"kmsg": {"type": "unix-char", "source": "/dev/kmsg", "path": "/dev/kmsg"},
+			},
+		},
+	}
+
+	if userData != "" {
+		req.Config["cloud-init.user-data"] = userData
+	}
+	return req
+}
+
+// vmInstance returns the request that creates a virtual machine
+func vmInstance(name, image, diskSize string, opts VMOptions) api.InstancesPost {
+	req := api.InstancesPost{
+		Name: name,
+		Type: api.InstanceTypeVM,
+		InstancePut: api.InstancePut{
+			Config: map[string]string{},
+			Devices: map[string]map[string]string{
+				"root": rootDisk(diskSize),
+				"eth0": nicDevice(),
+			},
+		},
+	}
+
+	if opts.CPUs > 0 {
+		req.Config["limits.cpu"] = fmt.Sprintf("%d", opts.CPUs)
+	}
+	if opts.Memory != "" {
+		req.Config["limits.memory"] = opts.Memory
+	}
+	if IsNixOSImage(image) {
+		// The NixOS images are not signed for secure boot
+		req.Config["security.secureboot"] = "false"
+	}
+	if opts.UserData != "" {
+		req.Config["cloud-init.user-data"] = opts.UserData
+	}
+
+	return req
+}
+
+func rootDisk(size string) map[string]string {
+	return map[string]string{"type": "disk", "path": "/", "size": size}
+}
+
+func nicDevice() map[string]string {
+	return map[string]string{"type": "nic", "network": bridgeNetwork, "name": "eth0"}
+}
+
+// imageSource returns the instance source of an image reference like the
+// incus CLI accepts them: remote:alias, or an alias on the server itself
+func imageSource(config *cliconfig.Config, image string) (api.InstanceSource, error) {
+	source := api.InstanceSource{Type: "image", Alias: image}
+
+	remoteName, alias, found := strings.Cut(image, ":")
+	if !found {
+		return source, nil
+	}
+
+	remote, ok := config.Remotes[remoteName]
+	if !ok {
+		return api.InstanceSource{}, fmt.Errorf("unknown image remote %s", remoteName)
+	}
+
+	source.Alias = alias
+	source.Server = remote.Addr
+	source.Protocol = remote.Protocol
+	return source, nil
+}
+
+// storagePool checks that the storage pool exists, or returns the pool of the
+// root disk of the default profile if no pool is given
+func (m *Manager) storagePool(name string) (string, error) {
+	if name == "" {
+		profile, _, err := m.server.GetProfile("default")
+		if err != nil {
+			return "", fmt.Errorf("failed to get default profile: %w", err)
+		}
+		pool := profile.Devices["root"]["pool"]
+		if pool == "" {
+			return "", fmt.Errorf("default profile has no root disk, specify a storage pool")
+		}
+		return pool, nil
+	}
+
+	pools, err := m.server.GetStoragePoolNames()
+	if err != nil {
+		return "", fmt.Errorf("failed to list storage pools: %w", err)
+	}
+	if !slices.Contains(pools, name) {
+		return "", fmt.Errorf("storage pool %s not found, available pools: %s", name, strings.Join(pools, ", "))
+	}
+	return name, nil
 }
 
-func (m *Manager) ensureNetwork(ctx context.Context) error {
-	// Check if incusbr0 network exists
-	cmd := exec.CommandContext(ctx, "incus", "network", "show", "incusbr0")
-	if err := cmd.Run(); err == nil {
+func (m *Manager) ensureNetwork() error {
+	// Check if the bridge network exists
+	if _, _, err := m.server.GetNetwork(bridgeNetwork); err == nil {
 		return nil // Network already exists
 	}
 
 	// Create the bridge network
-	cmd = exec.CommandContext(ctx, "incus", "network", "create", "incusbr0", "--type=bridge")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to create network: %w (output: %s)", err, string(output))
+	if err := m.server.CreateNetwork(api.NetworksPost{Name: bridgeNetwork, Type: "bridge"}); err != nil {
+		return fmt.Errorf("failed to create network: %w", err)
 	}
 
 	return nil
 }
 
 func (m *Manager) DeleteContainer(ctx context.Context, name string) error {
-	running, err := m.isRunning(ctx, name)
+	running, err := m.isRunning(name)
 	if err != nil {
 		return err
 	}
 
 	if running {
-		stopCmd := exec.CommandContext(ctx, "incus", "stop", name, "--force")
-		if output, err := stopCmd.CombinedOutput(); err != nil {
-			return fmt.Errorf("failed to stop container: %w (output: %s)", err, string(output))
+		if err := m.updateState(ctx, name, api.InstanceStatePut{Action: "stop", Force: true, Timeout: -1}); err != nil {
+			return fmt.Errorf("failed to stop container: %w", err)
 		}
 	}
 
-	deleteCmd := exec.CommandContext(ctx, "incus", "delete", name)
-	output, err := deleteCmd.CombinedOutput()
+	op, err := m.server.DeleteInstance(name)
+	if err == nil {
+		err = op.WaitContext(ctx)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to delete container: %w (output: %s)", err, string(output))
+		return fmt.Errorf("failed to delete container: %w", err)
 	}
 
 	return nil
 }
 
-func (m *Manager) ContainerExists(ctx context.Context, name string) (bool, error) {
-	cmd := exec.CommandContext(ctx, "incus", "list", "--format=csv", "-c", "n")
-	output, err := cmd.CombinedOutput()
+// updateState changes the state of an instance and waits for it
+func (m *Manager) updateState(ctx context.Context, name string, state api.InstanceStatePut) error {
+	op, err := m.server.UpdateInstanceState(name, state, "")
 	if err != nil {
-		return false, fmt.Errorf("failed to list containers: %w (output: %s)", err, string(output))
-	}
-
-	outputStr := strings.TrimSpace(string(output))
-	if outputStr == "" {
-		return false, nil
+		return err
 	}
+	return op.WaitContext(ctx)
+}
 
-	lines := strings.Split(outputStr, "\n")
-	for _, line := range lines {
-		if strings.TrimSpace(line) == name {
-			return true, nil
-		}
+func (m *Manager) ContainerExists(ctx context.Context, name string) (bool, error) {
+	names, err := m.server.GetInstanceNames(api.InstanceTypeAny)
+	if err != nil {
+		return false, fmt.Errorf("failed to list containers: %w", err)
 	}
 
-	return false, nil
+	return slices.Contains(names, name), nil
 }
 
 func (m *Manager) ListContainers(ctx context.Context, prefix string) ([]ContainerInfo, error) {
-	cmd := exec.CommandContext(ctx, "incus", "list", "--format=csv", "-c", "ns")
-	output, err := cmd.CombinedOutput()
+	instances, err := m.server.GetInstances(api.InstanceTypeAny)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list containers: %w (output: %s)", err, string(output))
+		return nil, fmt.Errorf("failed to list containers: %w", err)
 	}
 
 	var containers []ContainerInfo
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-
-		parts := strings.Split(line, ",")
-		if len(parts) < 2 {
-			continue
-		}
-
-		name := strings.TrimSpace(parts[0])
-		status := strings.TrimSpace(parts[1])
-
-		if prefix == "" || strings.HasPrefix(name, prefix) {
+	for _, instance := range instances {
+		if prefix == "" || strings.HasPrefix(instance.Name, prefix) {
 			containers = append(containers, ContainerInfo{
-				Name:   name,
-				Status: status,
+				Name:   instance.Name,
+				Status: strings.ToUpper(instance.Status),
 			})
 		}
 	}
@@ -167,34 +338,121 @@ func (m *Manager) ListContainers(ctx context.Context, prefix string) ([]Containe
 	return containers, nil
 }
 
-func (m *Manager) PushContent(ctx context.Context, container, content, remotePath string) error {
-	// Use stdin redirection to avoid shell injection vulnerabilities
-	cmd := exec.CommandContext(ctx, "incus", "exec", container, "--", "sh", "-c", `cat > "$1"`, "sh", remotePath)
-	cmd.Stdin = strings.NewReader(content)
-	output, err := cmd.CombinedOutput()
+// PushContent writes content to a file in the instance with the given mode
+func (m *Manager) PushContent(ctx context.Context, container, content, remotePath string, mode int) error {
+	err := m.server.CreateInstanceFile(container, remotePath, incus.InstanceFileArgs{
+		Content:   strings.NewReader(content),
+		Mode:      mode,
+		Type:      "file",
+		WriteMode: "overwrite",
+	})
 	if err != nil {
-		return fmt.Errorf("failed to push content: %w (output: %s)", err, string(output))
+		return fmt.Errorf("failed to push content: %w", err)
 	}
 
 	return nil
 }
 
+// Exec runs a command in the instance and returns its combined output
 func (m *Manager) Exec(ctx context.Context, container string, command ...string) (string, error) {
-	args := append([]string{"exec", container, "--"}, command...)
-	cmd := exec.CommandContext(ctx, "incus", args...)
-	output, err := cmd.CombinedOutput()
+	var output syncBuffer
+	if err := m.ExecStream(ctx, container, nil, &output, &output, command...); err != nil {
+		return output.String(), fmt.Errorf("failed to execute command: %w (output: %s)", err, output.String())
+	}
+
+	return output.String(), nil
+}
+
+// ExecStream runs a command in the instance, streaming its standard streams
+// from and to the given reader and writers. A nil stdin is empty.
+func (m *Manager) ExecStream(ctx context.Context, container string, stdin io.Reader, stdout, stderr io.Writer, command ...string) error {
+	return m.exec(ctx, container, api.InstanceExecPost{Command: command}, stdin, stdout, stderr)
+}
+
+// ExecInteractive runs a command in the instance attached to a terminal of
+// the given size, with the environment variables added to the defaults. The
+// terminal output (stdout and stderr) is written to stdout.
+func (m *Manager) ExecInteractive(ctx context.Context, container string, stdin io.Reader, stdout io.Writer, width, height int, env map[string]string, command ...string) error {
+	return m.exec(ctx, container, api.InstanceExecPost{
+		Command:     command,
+		Interactive: true,
+		Width:       width,
+		Height:      height,
+		Environment: env,
+	}, stdin, stdout, stdout)
+}
+
+func (m *Manager) exec(ctx context.Context, container string, req api.InstanceExecPost, stdin io.Reader, stdout, stderr io.Writer) error {
+	if stdin == nil {
+		stdin = bytes.NewReader(nil)
+	}
+	req.WaitForWS = true
+
+	dataDone := make(chan bool)
+	op, err := m.server.ExecInstance(container, req, &incus.InstanceExecArgs{
+		Stdin:    stdin,
+		Stdout:   stdout,
+		Stderr:   stderr,
+		DataDone: dataDone,
+	})
 	if err != nil {
-		return string(output), fmt.Errorf("failed to execute command: %w (output: %s)", err, string(output))
+		return err
 	}
+	if err := op.WaitContext(ctx); err != nil {
+		return err
+	}
+
+	// Wait for all output to be copied before reading the result
+	select {
+	case <-dataDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if code := exitCode(op.Get()); code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
+
+// ExitError is returned when a command in an instance exits with a non-zero status
+type ExitError struct {
+	Code int
+}
 
-	return string(output), nil
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.Code)
+}
+
+// exitCode returns the exit code of a finished exec operation
+func exitCode(op api.Operation) int {
+	code, _ := op.Metadata["return"].(float64)
+	return int(code)
+}
+
+// syncBuffer is a buffer that stdout and stderr can be copied to concurrently
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
 }
 
 func (m *Manager) WaitForRunning(ctx context.Context, name string, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
-		running, err := m.isRunning(ctx, name)
+		running, err := m.isRunning(name)
 		if err != nil {
 			return err
 		}
@@ -213,12 +471,15 @@ func (m *Manager) WaitForRunning(ctx context.Context, name string, timeout time.
 	return fmt.Errorf("timeout waiting for container to be running")
 }
 
+// networkCheckCommand checks network connectivity by pinging a well-known DNS
+// server, or by resolving a host name on images without ping (Debian, Ubuntu)
+var networkCheckCommand = []string{"sh", "-c", "if command -v ping >/dev/null; then timeout 2 ping -c 1 1.1.1.1; else getent hosts github.com; fi"}
+
 func (m *Manager) WaitForNetwork(ctx context.Context, name string, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
-		// Try to ping a well-known DNS server to check network connectivity
-		_, err := m.Exec(ctx, name, "timeout", "2", "ping", "-c", "1", "1.1.1.1")
+		_, err := m.Exec(ctx, name, networkCheckCommand...)
 		if err == nil {
 			return nil
 		}
@@ -233,29 +494,47 @@ func (m *Manager) WaitForNetwork(ctx context.Context, name string, timeout time.
 	return fmt.Errorf("timeout waiting for network connectivity")
 }
 
-func (m *Manager) isRunning(ctx context.Context, name string) (bool, error) {
-	cmd := exec.CommandContext(ctx, "incus", "list", name, "--format=csv", "-c", "s")
-	output, err := cmd.CombinedOutput()
+func (m *Manager) isRunning(name string) (bool, error) {
+	state, _, err := m.server.GetInstanceState(name)
 	if err != nil {
-		return false, fmt.Errorf("failed to check container status: %w (output: %s)", err, string(output))
+		return false, fmt.Errorf("failed to check container status: %w", err)
 	}
 
-	status := strings.TrimSpace(string(output))
-	return status == "RUNNING", nil
+	return state.StatusCode == api.Running, nil
 }
 
-func (m *Manager) PushConfigFile(ctx context.Context, containerName, configPath string) error {
+// PushConfig writes the deskrun config to /root/.deskrun/config.yaml in the container
+func (m *Manager) PushConfig(ctx context.Context, containerName string, configData []byte) error {
 	// Create .deskrun directory in container
 	if _, err := m.Exec(ctx, containerName, "mkdir", "-p", "/root/.deskrun"); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Use incus file push to copy the config file
-	cmd := exec.CommandContext(ctx, "incus", "file", "push", configPath, fmt.Sprintf("%s/root/.deskrun/config.json", containerName))
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to push config file: %w (output: %s)", err, string(output))
+	// The config contains auth values, so make sure only root can read it
+	if err := m.PushContent(ctx, containerName, string(configData), "/root/.deskrun/config.yaml", 0600); err != nil {
+		return fmt.Errorf("failed to push config file: %w", err)
 	}
 
 	return nil
 }
+
+// validateInstance validates the name, image and disk size of a new instance
+func validateInstance(name, image, diskSize string) error {
+	if name == "" {
+		return fmt.Errorf("container name cannot be empty")
+	}
+	if strings.ContainsAny(name, " /\\:@#$%^&*()[]{}!?'\"<>,;|`~+=") {
+		return fmt.Errorf("container name contains invalid characters: %s", name)
+	}
+	if image == "" {
+		return fmt.Errorf("image cannot be empty")
+	}
+	if diskSize == "" {
+		return fmt.Errorf("disk size cannot be empty")
+	}
+	if !strings.HasSuffix(diskSize, "GiB") && !strings.HasSuffix(diskSize, "GB") &&
+		!strings.HasSuffix(diskSize, "MiB") && !strings.HasSuffix(diskSize, "MB") {
+		return fmt.Errorf("disk size must end with GiB, GB, MiB, or MB: %s", diskSize)
+	}
+	return nil
+}
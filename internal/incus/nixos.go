@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 )
@@ -11,9 +12,17 @@ import (
 //go:embed templates/deskrun.nix
 var deskrunNixTemplate string
 
-func (m *Manager) ConfigureNixOS(ctx context.Context, containerName string) error {
+// IsNixOSImage reports whether the image is a NixOS image, which is
+// configured with a NixOS module instead of cloud-init
+func IsNixOSImage(image string) bool {
+	return strings.Contains(strings.ToLower(image), "nixos")
+}
+
+// ConfigureNixOS applies the deskrun NixOS module to the host, with SSH access
+// for the given public keys if any
+func (m *Manager) ConfigureNixOS(ctx context.Context, containerName string, sshKeys []string) error {
 	// Update nix channels to ensure NIX_PATH is properly set up
-	fmt.Println("Updating nix channels...")
+	slog.Info("Updating nix channels", "host", containerName)
 	if _, err := m.Exec(ctx, containerName, "nix-channel", "--update"); err != nil {
 		return fmt.Errorf("failed to update nix channels: %w", err)
 	}
@@ -32,7 +41,7 @@ func (m *Manager) ConfigureNixOS(ctx context.Context, containerName string) erro
 		}
 
 		if i < 4 {
-			fmt.Println("Channel not ready yet, retrying...")
+			slog.Info("Channel not ready yet, retrying", "host", containerName)
 			time.Sleep(3 * time.Second)
 			if _, err := m.Exec(ctx, containerName, "nix-channel", "--update"); err != nil {
 				return fmt.Errorf("failed to retry nix channel update: %w", err)
@@ -44,10 +53,14 @@ func (m *Manager) ConfigureNixOS(ctx context.Context, containerName string) erro
 		return fmt.Errorf("nixos channel symlink not found after update")
 	}
 
-	if err := m.PushContent(ctx, containerName, deskrunNixTemplate, "/etc/nixos/deskrun.nix"); err != nil {
+	if err := m.PushContent(ctx, containerName, deskrunNixTemplate, "/etc/nixos/deskrun.nix", 0644); err != nil {
 		return fmt.Errorf("failed to push deskrun.nix: %w", err)
 	}
 
+	if err := m.pushNixSSHModule(ctx, containerName, sshKeys); err != nil {
+		return err
+	}
+
 	configContent, err := m.Exec(ctx, containerName, "cat", "/etc/nixos/configuration.nix")
 	if err != nil {
 		return fmt.Errorf("failed to read configuration.nix: %w", err)
@@ -107,12 +120,12 @@ func (m *Manager) ConfigureNixOS(ctx context.Context, containerName string) erro
 			configContent = strings.Join(newLines, "\n")
 		}
 
-		if err := m.PushContent(ctx, containerName, configContent, "/etc/nixos/configuration.nix"); err != nil {
+		if err := m.PushContent(ctx, containerName, configContent, "/etc/nixos/configuration.nix", 0644); err != nil {
 			return fmt.Errorf("failed to update configuration.nix: %w", err)
 		}
 	}
 
-	fmt.Println("Running nixos-rebuild switch (this may take a few minutes)...")
+	slog.Info("Running nixos-rebuild switch, this may take a few minutes", "host", containerName)
 	// Run nixos-rebuild with NIX_PATH set to use the channels
 	nixPathCmd := "export NIX_PATH=\"nixpkgs=/nix/var/nix/profiles/per-user/root/channels/nixos:nixos-config=/etc/nixos/configuration.nix\" && nixos-rebuild switch"
 	if _, err := m.Exec(ctx, containerName, "bash", "-c", nixPathCmd); err != nil {
@@ -0,0 +1,92 @@
+package incus
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+//go:embed templates/provision.sh
+var provisionScript string
+
+// provisionScriptPath is where the provisioning script is installed on cluster hosts
+const provisionScriptPath = "/usr/local/sbin/deskrun-provision"
+
+// Provisioning is how Docker, kind and deskrun are installed on a cluster host
+type Provisioning string
+
+const (
+	// ProvisionNixOS applies the deskrun NixOS module
+	ProvisionNixOS Provisioning = "nixos"
+	// ProvisionCloudInit runs the provisioning script with cloud-init on first boot
+	ProvisionCloudInit Provisioning = "cloud-init"
+	// ProvisionScript runs the provisioning script through incus exec
+	ProvisionScript Provisioning = "script"
+)
+
+// ImageProvisioning returns how a cluster host running the image is
+// provisioned: NixOS images with the NixOS module, Debian and Ubuntu images
+// with the provisioning script, run by cloud-init for the /cloud variants of
+// the images
+func ImageProvisioning(image string) (Provisioning, error) {
+	if IsNixOSImage(image) {
+		return ProvisionNixOS, nil
+	}
+
+	lower := strings.ToLower(image)
+	if !strings.Contains(lower, "ubuntu") && !strings.Contains(lower, "debian") {
+		return "", fmt.Errorf("unsupported cluster host image %s, use a NixOS, Ubuntu or Debian image", image)
+	}
+
+	_, alias, found := strings.Cut(lower, ":")
+	if !found {
+		alias = lower
+	}
+	if slices.Contains(strings.Split(alias, "/"), "cloud") {
+		return ProvisionCloudInit, nil
+	}
+	return ProvisionScript, nil
+}
+
+// CloudInitUserData returns the cloud-init user-data that provisions a
+// Debian or Ubuntu cluster host on first boot with the provisioning script
+func CloudInitUserData() string {
+	var b strings.Builder
+	b.WriteString("#cloud-config\n")
+	b.WriteString("write_files:\n")
+	fmt.Fprintf(&b, "  - path: %s\n", provisionScriptPath)
+	b.WriteString("    permissions: '0755'\n")
+	b.WriteString("    content: |\n")
+	for _, line := range strings.Split(strings.TrimRight(provisionScript, "\n"), "\n") {
+		if line == "" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString("      " + line + "\n")
+	}
+	b.WriteString("runcmd:\n")
+	fmt.Fprintf(&b, "  - [%s]\n", provisionScriptPath)
+	return b.String()
+}
+
+// WaitForCloudInit waits until cloud-init finished provisioning the instance
+func (m *Manager) WaitForCloudInit(ctx context.Context, name string) error {
+	if _, err := m.Exec(ctx, name, "cloud-init", "status", "--wait"); err != nil {
+		return fmt.Errorf("cloud-init provisioning failed: %w", err)
+	}
+	return nil
+}
+
+// RunProvisionScript installs and runs the current provisioning script on a
+// Debian or Ubuntu cluster host
+func (m *Manager) RunProvisionScript(ctx context.Context, name string) error {
+	if err := m.PushContent(ctx, name, provisionScript, provisionScriptPath, 0755); err != nil {
+		return fmt.Errorf("failed to push provisioning script: %w", err)
+	}
+	if _, err := m.Exec(ctx, name, "sh", provisionScriptPath); err != nil {
+		return fmt.Errorf("failed to run provisioning script: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package incus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/api"
+)
+
+// SnapshotInfo describes a snapshot of an instance
+type SnapshotInfo struct {
+	Name      string
+	CreatedAt time.Time
+	Size      int64 // bytes, -1 if unknown
+}
+
+// CreateSnapshot takes a snapshot of the instance's disk
+func (m *Manager) CreateSnapshot(ctx context.Context, name, snapshot string) error {
+	op, err := m.server.CreateInstanceSnapshot(name, api.InstanceSnapshotsPost{Name: snapshot})
+	if err == nil {
+		err = op.WaitContext(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	return nil
+}
+
+// ListSnapshots returns the snapshots of the instance, oldest first
+func (m *Manager) ListSnapshots(ctx context.Context, name string) ([]SnapshotInfo, error) {
+	snapshots, err := m.server.GetInstanceSnapshots(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	infos := make([]SnapshotInfo, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		infos = append(infos, SnapshotInfo{
+			Name:      snapshot.Name,
+			CreatedAt: snapshot.CreatedAt,
+			Size:      snapshot.Size,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.Before(infos[j].CreatedAt)
+	})
+	return infos, nil
+}
+
+// RestoreSnapshot rolls the instance back to a snapshot. Running instances are
+// restarted by incus.
+func (m *Manager) RestoreSnapshot(ctx context.Context, name, snapshot string) error {
+	instance, etag, err := m.server.GetInstance(name)
+	if err != nil {
+		return fmt.Errorf("failed to get instance: %w", err)
+	}
+
+	put := instance.InstancePut
+	put.Restore = snapshot
+	op, err := m.server.UpdateInstance(name, put, etag)
+	if err == nil {
+		err = op.WaitContext(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore snapshot %s: %w", snapshot, err)
+	}
+	return nil
+}
+
+// DeleteSnapshot deletes a snapshot of the instance
+func (m *Manager) DeleteSnapshot(ctx context.Context, name, snapshot string) error {
+	op, err := m.server.DeleteInstanceSnapshot(name, snapshot)
+	if err == nil {
+		err = op.WaitContext(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", snapshot, err)
+	}
+	return nil
+}
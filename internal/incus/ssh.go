@@ -0,0 +1,87 @@
+package incus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// nixSSHModulePath is the NixOS module that enables SSH access to a host
+	nixSSHModulePath = "/etc/nixos/deskrun-ssh.nix"
+	// authorizedKeysPath is the authorized keys file of root on cloud-init hosts
+	authorizedKeysPath = "/root/.ssh/authorized_keys"
+)
+
+// ValidateSSHPublicKey checks that a key is a single line OpenSSH public key
+// that can be embedded in the NixOS configuration
+func ValidateSSHPublicKey(key string) error {
+	fields := strings.Fields(key)
+	if len(fields) < 2 {
+		return fmt.Errorf("not an OpenSSH public key: %q", key)
+	}
+
+	keyType := fields[0]
+	if !strings.HasPrefix(keyType, "ssh-") && !strings.HasPrefix(keyType, "ecdsa-") && !strings.HasPrefix(keyType, "sk-") {
+		return fmt.Errorf("unsupported SSH key type %s", keyType)
+	}
+
+	if strings.ContainsAny(key, "\"\\\n") || strings.Contains(key, "${") {
+		return fmt.Errorf("SSH public key contains invalid characters")
+	}
+	return nil
+}
+
+// nixSSHModule returns the NixOS module that enables the OpenSSH server with
+// key-only root logins for the given keys
+func nixSSHModule(keys []string) string {
+	var b strings.Builder
+	b.WriteString("{ ... }:\n\n")
+	b.WriteString("# Managed by deskrun, changes are overwritten by 'deskrun cluster-host configure'\n")
+	b.WriteString("{\n")
+	b.WriteString("  services.openssh = {\n")
+	b.WriteString("    enable = true;\n")
+	b.WriteString("    settings.PasswordAuthentication = false;\n")
+	b.WriteString("    settings.PermitRootLogin = \"prohibit-password\";\n")
+	b.WriteString("  };\n\n")
+	b.WriteString("  users.users.root.openssh.authorizedKeys.keys = [\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "    \"%s\"\n", key)
+	}
+	b.WriteString("  ];\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// pushNixSSHModule writes the SSH module for the keys, or removes it if there
+// are none. nixos-rebuild applies it.
+func (m *Manager) pushNixSSHModule(ctx context.Context, name string, keys []string) error {
+	if len(keys) == 0 {
+		if _, err := m.Exec(ctx, name, "rm", "-f", nixSSHModulePath); err != nil {
+			return fmt.Errorf("failed to remove SSH module: %w", err)
+		}
+		return nil
+	}
+
+	if err := m.PushContent(ctx, name, nixSSHModule(keys), nixSSHModulePath, 0644); err != nil {
+		return fmt.Errorf("failed to push SSH module: %w", err)
+	}
+	return nil
+}
+
+// PushAuthorizedKeys authorizes the keys to log in as root on a cluster host
+// provisioned with cloud-init, replacing the previously authorized keys
+func (m *Manager) PushAuthorizedKeys(ctx context.Context, name string, keys []string) error {
+	if _, err := m.Exec(ctx, name, "mkdir", "-p", "-m", "0700", "/root/.ssh"); err != nil {
+		return fmt.Errorf("failed to create .ssh directory: %w", err)
+	}
+
+	content := ""
+	if len(keys) > 0 {
+		content = strings.Join(keys, "\n") + "\n"
+	}
+	if err := m.PushContent(ctx, name, content, authorizedKeysPath, 0600); err != nil {
+		return fmt.Errorf("failed to push authorized keys: %w", err)
+	}
+	return nil
+}
@@ -6,6 +6,9 @@ let
   '';
 in
 {
+  # SSH access, written by deskrun when SSH keys are configured for the host
+  imports = lib.optional (builtins.pathExists ./deskrun-ssh.nix) ./deskrun-ssh.nix;
+
   nix.settings.experimental-features = [ "nix-command" "flakes" ];
 
   virtualisation.docker = {
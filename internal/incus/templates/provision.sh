@@ -0,0 +1,47 @@
+#!/bin/sh
+# Provisions a Debian or Ubuntu cluster host with Docker, kind, kubectl, Nix
+# and deskrun. Safe to run again, e.g. by 'deskrun cluster-host configure'.
+set -eu
+
+export DEBIAN_FRONTEND=noninteractive
+
+arch=$(dpkg --print-architecture)
+
+apt-get update
+apt-get install -y ca-certificates curl git htop xz-utils docker.io openssh-server
+systemctl enable --now docker
+
+if ! command -v kind >/dev/null; then
+  curl -fsSLo /usr/local/bin/kind "https://kind.sigs.k8s.io/dl/latest/kind-linux-${arch}"
+  chmod +x /usr/local/bin/kind
+fi
+
+if ! command -v kubectl >/dev/null; then
+  version=$(curl -fsSL https://dl.k8s.io/release/stable.txt)
+  curl -fsSLo /usr/local/bin/kubectl "https://dl.k8s.io/release/${version}/bin/linux/${arch}/kubectl"
+  chmod +x /usr/local/bin/kubectl
+fi
+
+if [ ! -e /nix/var/nix/profiles/default/bin/nix ]; then
+  curl -fsSL https://nixos.org/nix/install | sh -s -- --daemon --yes
+fi
+mkdir -p /etc/nix
+grep -q '^experimental-features' /etc/nix/nix.conf 2>/dev/null ||
+  echo 'experimental-features = nix-command flakes' >>/etc/nix/nix.conf
+systemctl restart nix-daemon
+
+cat >/usr/local/bin/deskrun <<'WRAPPER'
+#!/bin/sh
+exec /nix/var/nix/profiles/default/bin/nix run github:rkoster/deskrun -- "$@"
+WRAPPER
+chmod +x /usr/local/bin/deskrun
+
+cat >/etc/sysctl.d/90-deskrun.conf <<'SYSCTL'
+fs.inotify.max_user_watches = 524288
+fs.inotify.max_user_instances = 512
+# Disable IPv6 to avoid connection delays with Happy Eyeballs
+net.ipv6.conf.all.disable_ipv6 = 1
+net.ipv6.conf.default.disable_ipv6 = 1
+SYSCTL
+# Containers share the kernel of the incus server, which may not allow this
+sysctl --system >/dev/null || true
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"log/slog"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	cmdapp "carvel.dev/kapp/pkg/kapp/cmd/app"
@@ -14,13 +16,32 @@ import (
 	"carvel.dev/kapp/pkg/kapp/logger"
 	"carvel.dev/kapp/pkg/kapp/preflight"
 	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/rkoster/deskrun/internal/logging"
 )
 
+// maxOutputLines is how many of the last lines of kapp output are kept to
+// explain a failed deploy or delete
+const maxOutputLines = 20
+
+// DefaultApplyTimeout is how long kapp applies and waits for the changes of
+// an app when no timeout is configured
+const DefaultApplyTimeout = 15 * time.Minute
+
 // Client provides an interface for kapp operations
 type Client struct {
-	kubeconfig string
-	namespace  string
-	uiConfig   UIConfig
+	kubeconfig   string
+	namespace    string
+	uiConfig     UIConfig
+	applyOptions ApplyOptions
+}
+
+// ApplyOptions controls how long deploys and deletes take
+type ApplyOptions struct {
+	// Timeout bounds applying and waiting for the changes (0 for DefaultApplyTimeout)
+	Timeout time.Duration
+	// NoWait returns once the changes are applied, without waiting for the
+	// resources to reconcile
+	NoWait bool
 }
 
 // KappResource represents a single resource from kapp JSON output
@@ -61,11 +82,12 @@ type UIConfig struct {
 	JSON   bool // Output in JSON format (disables color)
 }
 
-// NewClient creates a new kapp client with default UI configuration
+// NewClient creates a new kapp client with default UI configuration. The
+// output of kapp is logged line by line with the default logger.
 func NewClient(kubeconfig, namespace string) *Client {
 	return NewClientWithUI(kubeconfig, namespace, UIConfig{
-		Stdout: os.Stdout,
-		Stderr: os.Stderr,
+		Stdout: logging.Writer(slog.LevelInfo),
+		Stderr: logging.Writer(slog.LevelWarn),
 		Silent: true,  // Non-interactive by default
 		Color:  false, // No color by default
 		JSON:   false,
@@ -81,11 +103,67 @@ func NewClientWithUI(kubeconfig, namespace string, uiConfig UIConfig) *Client {
 	}
 }
 
+// WithApplyOptions returns a copy of the client that deploys and deletes
+// apps with the given timeout and wait behavior
+func (c *Client) WithApplyOptions(opts ApplyOptions) *Client {
+	clone := *c
+	clone.applyOptions = opts
+	return &clone
+}
+
+// DeployResult is the outcome of a deploy
+type DeployResult struct {
+	// Output are the last lines kapp wrote, e.g. the resources it waited for
+	Output []string
+}
+
+// Error is a failed kapp operation with the last lines of its output, which
+// tell e.g. which resource kapp was waiting for
+type Error struct {
+	Err    error
+	Output []string
+}
+
+func (e *Error) Error() string {
+	if len(e.Output) == 0 {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + "\nkapp output:\n  " + strings.Join(e.Output, "\n  ")
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// DeployOptions controls optional deploy behavior
+type DeployOptions struct {
+	// Labels are set on the kapp app record, e.g. to track the deployed version
+	Labels map[string]string
+	// ShowChanges prints the diff of every changed resource, not only the summary
+	ShowChanges bool
+	// DiffOnly shows the changes without applying them
+	DiffOnly bool
+}
+
 // Deploy deploys resources using the native kapp Go API (not by executing the kapp CLI binary).
 // This approach may result in error messages and behavior that differ from the CLI.
 func (c *Client) Deploy(appName string, manifestPath string) error {
-	// Create a custom UI with the configured writers
-	confUI := c.createConfUI()
+	return c.DeployWithOptions(appName, manifestPath, DeployOptions{})
+}
+
+// DeployWithOptions deploys resources like Deploy, with additional app labels and diff options
+func (c *Client) DeployWithOptions(appName string, manifestPath string, opts DeployOptions) error {
+	_, err := c.DeployWithResult(appName, manifestPath, opts)
+	return err
+}
+
+// DeployWithResult deploys resources like DeployWithOptions and returns the
+// last lines of the kapp output. If the deploy fails, the error is an *Error
+// with the same lines.
+func (c *Client) DeployWithResult(appName string, manifestPath string, opts DeployOptions) (*DeployResult, error) {
+	// Create a custom UI with the configured writers, keeping the last lines of output
+	tail := newOutputTail(maxOutputLines)
+	confUI := c.createConfUI(tail)
 
 	// Create kapp dependencies with proper kubeconfig configuration
 	configFactory := c.createConfigFactory()
@@ -104,15 +182,58 @@ func (c *Client) Deploy(appName string, manifestPath string) error {
 	// These match the defaults used by kapp CLI in ApplyFlagsDeployDefaults
 	c.setDefaultApplyOptions(deployOpts)
 
+	deployOpts.LabelFlags.Labels = appLabelFlags(opts.Labels)
+	if opts.ShowChanges {
+		// Match the kapp CLI defaults for --diff-changes
+		deployOpts.DiffFlags.Changes = true
+		deployOpts.DiffFlags.Context = 2
+		deployOpts.DiffFlags.AgainstLastApplied = true
+	}
+	deployOpts.DiffFlags.Run = opts.DiffOnly
+
 	// Execute deploy (non-interactive mode is handled by createConfUI based on UIConfig.Silent)
-	return deployOpts.Run()
+	result := &DeployResult{}
+	err := deployOpts.Run()
+	result.Output = tail.Lines()
+	if err != nil {
+		return result, &Error{Err: err, Output: result.Output}
+	}
+	return result, nil
+}
+
+// Diff shows the changes deploying the manifest would apply to an app, with
+// the diff of every changed resource and the changes summary, without
+// applying them
+func (c *Client) Diff(appName string, manifestPath string) error {
+	return c.DeployWithOptions(appName, manifestPath, DeployOptions{ShowChanges: true, DiffOnly: true})
+}
+
+// appLabelFlags converts app labels to kapp's key=value label flags, sorted by key
+func appLabelFlags(labels map[string]string) []string {
+	flags := make([]string, 0, len(labels))
+	for key, value := range labels {
+		flags = append(flags, key+"="+value)
+	}
+	sort.Strings(flags)
+	return flags
 }
 
 // Delete deletes an app using the native kapp Go API (not by executing the kapp CLI binary).
 // This approach may result in error messages and behavior that differ from the CLI.
 func (c *Client) Delete(appName string) error {
-	// Create a custom UI with the configured writers
-	confUI := c.createConfUI()
+	return c.delete(appName, false)
+}
+
+// DiffDelete shows the resources deleting an app would delete, without deleting them
+func (c *Client) DiffDelete(appName string) error {
+	return c.delete(appName, true)
+}
+
+// delete deletes an app, or only shows the changes when diffOnly is set
+func (c *Client) delete(appName string, diffOnly bool) error {
+	// Create a custom UI with the configured writers, keeping the last lines of output
+	tail := newOutputTail(maxOutputLines)
+	confUI := c.createConfUI(tail)
 
 	// Create kapp dependencies with proper kubeconfig configuration
 	configFactory := c.createConfigFactory()
@@ -127,14 +248,20 @@ func (c *Client) Delete(appName string) error {
 
 	// Set default apply options (required to prevent throttle panic)
 	c.setDefaultDeleteOptions(deleteOpts)
+	deleteOpts.DiffFlags.Run = diffOnly
 
 	// Execute delete (non-interactive mode is handled by createConfUI based on UIConfig.Silent)
-	return deleteOpts.Run()
+	if err := deleteOpts.Run(); err != nil {
+		return &Error{Err: err, Output: tail.Lines()}
+	}
+	return nil
 }
 
 // KappListApp represents a single app from kapp list JSON output
 type KappListApp struct {
 	Name string `json:"name"`
+	// Namespace is only set when listing the apps of all namespaces
+	Namespace string `json:"namespace"`
 }
 
 // KappListTable represents the table structure in kapp list JSON output
@@ -148,8 +275,28 @@ type KappListOutput struct {
 }
 
 // List lists all kapp apps using the native kapp Go API
-// Note: JSON output requires explicit Flush() call to write accumulated data
 func (c *Client) List() ([]string, error) {
+	apps, err := c.list(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, app := range apps {
+		names = append(names, app.Name)
+	}
+	return names, nil
+}
+
+// ListAllNamespaces lists the kapp apps of all namespaces, with the namespace
+// each app is recorded in
+func (c *Client) ListAllNamespaces() ([]KappListApp, error) {
+	return c.list(true)
+}
+
+// list runs kapp list in the client namespace or all namespaces
+// Note: JSON output requires explicit Flush() call to write accumulated data
+func (c *Client) list(allNamespaces bool) ([]KappListApp, error) {
 	// Create a buffer to capture JSON output
 	var outputBuf bytes.Buffer
 
@@ -165,13 +312,14 @@ func (c *Client) List() ([]string, error) {
 
 	// Set the required flags programmatically
 	listOpts.NamespaceFlags.Name = c.namespace
+	listOpts.AllNamespaces = allNamespaces
 
 	// Execute list
 	err := listOpts.Run()
 	if err != nil {
 		// Check if error is specifically about a missing namespace.
 		if strings.Contains(err.Error(), "namespace") && strings.Contains(err.Error(), "not found") {
-			return []string{}, nil
+			return []KappListApp{}, nil
 		}
 		return nil, fmt.Errorf("kapp list failed: %w", err)
 	}
@@ -183,26 +331,29 @@ func (c *Client) List() ([]string, error) {
 	outputBytes := outputBuf.Bytes()
 	if len(outputBytes) == 0 {
 		// Empty output - this likely means no apps are deployed in the namespace
-		return []string{}, nil
+		return []KappListApp{}, nil
 	}
 
+	return parseListOutput(outputBytes)
+}
+
+// parseListOutput extracts the apps from kapp list JSON output
+func parseListOutput(output []byte) ([]KappListApp, error) {
 	var listOutput KappListOutput
-	if err := json.Unmarshal(outputBytes, &listOutput); err != nil {
+	if err := json.Unmarshal(output, &listOutput); err != nil {
 		// Provide detailed error with actual output for debugging
-		return nil, fmt.Errorf("failed to parse kapp list JSON output: %w (output length: %d, output: %q)", err, len(outputBytes), string(outputBytes))
+		return nil, fmt.Errorf("failed to parse kapp list JSON output: %w (output length: %d, output: %q)", err, len(output), string(output))
 	}
 
-	// Extract app names from JSON
-	var names []string
+	var apps []KappListApp
 	if len(listOutput.Tables) > 0 {
 		for _, app := range listOutput.Tables[0].Rows {
 			if app.Name != "" {
-				names = append(names, app.Name)
+				apps = append(apps, app)
 			}
 		}
 	}
-
-	return names, nil
+	return apps, nil
 }
 
 // InspectJSON gets the output from kapp inspect with tree hierarchy using native kapp Go API
@@ -279,21 +430,22 @@ func (c *Client) createConfigFactory() *cmdcore.ConfigFactoryImpl {
 	return configFactory
 }
 
-// createConfUI creates a go-cli-ui ConfUI based on the client's UI configuration
-func (c *Client) createConfUI() *ui.ConfUI {
+// createConfUI creates a go-cli-ui ConfUI based on the client's UI configuration,
+// which also writes all output to tail
+func (c *Client) createConfUI(tail io.Writer) *ui.ConfUI {
 	// Determine output and error writers
 	outWriter := c.uiConfig.Stdout
 	if outWriter == nil {
-		outWriter = os.Stdout
+		outWriter = logging.Writer(slog.LevelInfo)
 	}
 
 	errWriter := c.uiConfig.Stderr
 	if errWriter == nil {
-		errWriter = os.Stderr
+		errWriter = logging.Writer(slog.LevelWarn)
 	}
 
 	// Create a writer UI with custom writers
-	writerUI := ui.NewWriterUI(outWriter, errWriter, ui.NewNoopLogger())
+	writerUI := ui.NewWriterUI(io.MultiWriter(outWriter, tail), io.MultiWriter(errWriter, tail), ui.NewNoopLogger())
 
 	// Wrap in ConfUI for configuration
 	confUI := ui.NewWrappingConfUI(writerUI, ui.NewNoopLogger())
@@ -318,8 +470,8 @@ func (c *Client) createConfUI() *ui.ConfUI {
 // This is used by List() and InspectJSON() methods which require JSON output for parsing,
 // independent of the client's UIConfig settings.
 func (c *Client) createJSONUI(outputBuf *bytes.Buffer) *ui.ConfUI {
-	// Create a writer UI with the buffer for output and stderr for error messages
-	writerUI := ui.NewWriterUI(outputBuf, os.Stderr, ui.NewNoopLogger())
+	// Create a writer UI with the buffer for output, logging error messages
+	writerUI := ui.NewWriterUI(outputBuf, logging.Writer(slog.LevelWarn), ui.NewNoopLogger())
 
 	// Wrap in ConfUI for configuration - try with TTY disabled
 	confUI := ui.NewWrappingConfUI(writerUI, ui.NewNoopLogger())
@@ -331,22 +483,31 @@ func (c *Client) createJSONUI(outputBuf *bytes.Buffer) *ui.ConfUI {
 	return confUI
 }
 
+// timeout returns how long kapp applies and waits for changes
+func (c *Client) timeout() time.Duration {
+	if c.applyOptions.Timeout > 0 {
+		return c.applyOptions.Timeout
+	}
+	return DefaultApplyTimeout
+}
+
 // setDefaultApplyOptions sets the default apply options that match kapp CLI defaults.
 // This is required to prevent panics and ensure consistent behavior with the CLI.
+// The timeouts and waiting follow the client's ApplyOptions.
 func (c *Client) setDefaultApplyOptions(deployOpts *cmdapp.DeployOptions) {
 	// Set default cluster change options (matches ApplyFlagsDeployDefaults)
 	deployOpts.ApplyFlags.ApplyIgnored = false
-	deployOpts.ApplyFlags.Wait = true
+	deployOpts.ApplyFlags.Wait = !c.applyOptions.NoWait
 	deployOpts.ApplyFlags.WaitIgnored = false
 
 	// Set default applying changes options (prevents throttle panic)
 	deployOpts.ApplyFlags.ApplyingChangesOpts.Concurrency = 5
-	deployOpts.ApplyFlags.ApplyingChangesOpts.Timeout = 15 * time.Minute
+	deployOpts.ApplyFlags.ApplyingChangesOpts.Timeout = c.timeout()
 	deployOpts.ApplyFlags.ApplyingChangesOpts.CheckInterval = 1 * time.Second
 
 	// Set default waiting changes options
 	deployOpts.ApplyFlags.WaitingChangesOpts.Concurrency = 5
-	deployOpts.ApplyFlags.WaitingChangesOpts.Timeout = 15 * time.Minute
+	deployOpts.ApplyFlags.WaitingChangesOpts.Timeout = c.timeout()
 	deployOpts.ApplyFlags.WaitingChangesOpts.CheckInterval = 3 * time.Second
 	deployOpts.ApplyFlags.ResourceTimeout = 0 * time.Second
 
@@ -357,20 +518,21 @@ func (c *Client) setDefaultApplyOptions(deployOpts *cmdapp.DeployOptions) {
 
 // setDefaultDeleteOptions sets the default delete options that match kapp CLI defaults.
 // This is required to prevent panics and ensure consistent behavior with the CLI.
+// The timeouts and waiting follow the client's ApplyOptions.
 func (c *Client) setDefaultDeleteOptions(deleteOpts *cmdapp.DeleteOptions) {
 	// Set default cluster change options (matches kapp delete CLI defaults)
 	deleteOpts.ApplyFlags.ApplyIgnored = false
-	deleteOpts.ApplyFlags.Wait = true
+	deleteOpts.ApplyFlags.Wait = !c.applyOptions.NoWait
 	deleteOpts.ApplyFlags.WaitIgnored = false
 
 	// Set default applying changes options (prevents throttle panic)
 	deleteOpts.ApplyFlags.ApplyingChangesOpts.Concurrency = 5
-	deleteOpts.ApplyFlags.ApplyingChangesOpts.Timeout = 15 * time.Minute
+	deleteOpts.ApplyFlags.ApplyingChangesOpts.Timeout = c.timeout()
 	deleteOpts.ApplyFlags.ApplyingChangesOpts.CheckInterval = 1 * time.Second
 
 	// Set default waiting changes options
 	deleteOpts.ApplyFlags.WaitingChangesOpts.Concurrency = 5
-	deleteOpts.ApplyFlags.WaitingChangesOpts.Timeout = 15 * time.Minute
+	deleteOpts.ApplyFlags.WaitingChangesOpts.Timeout = c.timeout()
 	deleteOpts.ApplyFlags.WaitingChangesOpts.CheckInterval = 3 * time.Second
 	deleteOpts.ApplyFlags.ResourceTimeout = 0 * time.Second
 
@@ -378,3 +540,58 @@ func (c *Client) setDefaultDeleteOptions(deleteOpts *cmdapp.DeleteOptions) {
 	deleteOpts.ApplyFlags.ExitEarlyOnApplyError = true
 	deleteOpts.ApplyFlags.ExitEarlyOnWaitError = true
 }
+
+// outputTail is a writer that keeps the last lines written to it
+type outputTail struct {
+	mu       sync.Mutex
+	maxLines int
+	lines    []string
+	partial  []byte
+}
+
+func newOutputTail(maxLines int) *outputTail {
+	return &outputTail{maxLines: maxLines}
+}
+
+func (t *outputTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.partial = append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+		t.add(string(t.partial[:i]))
+		t.partial = t.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// add keeps a non-empty line, dropping the oldest line beyond maxLines; mu must be held
+func (t *outputTail) add(line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.maxLines {
+		t.lines = t.lines[len(t.lines)-t.maxLines:]
+	}
+}
+
+// Lines returns the kept lines, including a last line without newline
+func (t *outputTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := append([]string(nil), t.lines...)
+	if last := strings.TrimSpace(string(t.partial)); last != "" {
+		lines = append(lines, string(t.partial))
+		if len(lines) > t.maxLines {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
@@ -0,0 +1,31 @@
+package kapp
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestOutputTail(t *testing.T) {
+	tail := newOutputTail(2)
+	_, _ = tail.Write([]byte("first\nsecond\n\nthi"))
+	_, _ = tail.Write([]byte("rd\nwaiting on deployment/listener"))
+
+	want := []string{"third", "waiting on deployment/listener"}
+	if got := tail.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %q, want %q", got, want)
+	}
+}
+
+func TestErrorIncludesOutput(t *testing.T) {
+	cause := errors.New("timed out waiting after 15m0s")
+	err := &Error{Err: cause, Output: []string{"ongoing: reconcile deployment/listener", "Waiting for 1 unavailable replicas"}}
+
+	want := "timed out waiting after 15m0s\nkapp output:\n  ongoing: reconcile deployment/listener\n  Waiting for 1 unavailable replicas"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("errors.Is(err, cause) = false, want true")
+	}
+}
@@ -0,0 +1,153 @@
+// Package logging configures the leveled logger deskrun reports progress
+// with (--log-level, --log-format)
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// FormatText logs human readable lines: the message followed by its attributes
+	FormatText = "text"
+	// FormatJSON logs one JSON object per line, e.g. for CI bootstrap scripts
+	FormatJSON = "json"
+)
+
+// Formats are the supported values of --log-format
+var Formats = []string{FormatText, FormatJSON}
+
+// New returns a logger writing to w at the given level ("debug", "info",
+// "warn" or "error") in the given format
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level '%s': must be one of debug, info, warn, error", level)
+	}
+
+	switch format {
+	case FormatText:
+		return slog.New(&textHandler{w: w, level: lvl, mu: &sync.Mutex{}}), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl})), nil
+	default:
+		return nil, fmt.Errorf("invalid log format '%s': must be one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// textHandler writes the message of a record followed by its attributes as
+// key=value pairs. Warnings and errors are prefixed the way deskrun always
+// printed them; the time and level of other records are left out.
+type textHandler struct {
+	w      io.Writer
+	level  slog.Level
+	attrs  []slog.Attr
+	prefix string
+	mu     *sync.Mutex
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	switch {
+	case r.Level >= slog.LevelError:
+		buf.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		buf.WriteString("Warning: ")
+	}
+	buf.WriteString(r.Message)
+
+	for _, attr := range h.attrs {
+		appendAttr(&buf, "", attr)
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		appendAttr(&buf, h.prefix, attr)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	clone.attrs = append(clone.attrs, h.attrs...)
+	for _, attr := range attrs {
+		attr.Key = h.prefix + attr.Key
+		clone.attrs = append(clone.attrs, attr)
+	}
+	return &clone
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// appendAttr writes an attribute as " key=value", quoting values with spaces
+func appendAttr(buf *bytes.Buffer, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		for _, member := range attr.Value.Group() {
+			appendAttr(buf, prefix+attr.Key+".", member)
+		}
+		return
+	}
+
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	buf.WriteString(" " + prefix + attr.Key + "=" + value)
+}
+
+// Writer returns a writer that logs every non-empty line written to it at
+// the given level with the default logger, e.g. for the output of kapp
+func Writer(level slog.Level) io.Writer {
+	return &lineWriter{level: level}
+}
+
+type lineWriter struct {
+	level slog.Level
+	mu    sync.Mutex
+	buf   []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(w.buf[:i]), "\r")
+		w.buf = w.buf[i+1:]
+		if strings.TrimSpace(line) != "" {
+			slog.Log(context.Background(), w.level, line)
+		}
+	}
+	return len(p), nil
+}
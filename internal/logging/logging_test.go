@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatText)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Debug("hidden")
+	logger.Info("Installing runner scale set", "name", "my-runner", "namespace", "arc systems")
+	logger.With("cluster", "deskrun").Warn("Cluster is not running")
+
+	want := "Installing runner scale set name=my-runner namespace=\"arc systems\"\n" +
+		"Warning: Cluster is not running cluster=deskrun\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug", FormatJSON)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	logger.Debug("Rendering manifests", "name", "my-runner")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("output %q is not JSON: %v", buf.String(), err)
+	}
+	if record["level"] != "DEBUG" || record["msg"] != "Rendering manifests" || record["name"] != "my-runner" {
+		t.Errorf("record = %v", record)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "verbose", FormatText); err == nil {
+		t.Errorf("New() accepted log level verbose")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "yaml"); err == nil {
+		t.Errorf("New() accepted log format yaml")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatText)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(previous)
+
+	w := Writer(slog.LevelInfo)
+	_, _ = w.Write([]byte("Changes\n\nOp:  1 create"))
+	_, _ = w.Write([]byte(", 0 delete\n"))
+
+	want := "Changes\nOp:  1 create, 0 delete\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,189 @@
+// Package progress shows the phases of long operations, like creating a
+// cluster or waiting for a deploy, with a spinner and the elapsed time
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// clearLine moves the cursor to the start of the line and erases it
+	clearLine = "\r\033[K"
+	// spinInterval is how often the spinner of a running phase is redrawn
+	spinInterval = 100 * time.Millisecond
+
+	colorCyan  = "\033[36m"
+	colorGreen = "\033[32m"
+	colorRed   = "\033[31m"
+	colorReset = "\033[0m"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+var (
+	mu       sync.Mutex
+	out      io.Writer = os.Stderr
+	animated bool
+	colored  bool
+	// running are the animated phases that did not end yet, in start order
+	running []*Phase
+)
+
+// Configure sets where phases are shown. Animated phases redraw a spinner
+// with the elapsed time on their line, which only works on a terminal;
+// otherwise phases are logged when they start and end. Colored adds ANSI
+// colors to the spinner and the result of a phase.
+func Configure(w io.Writer, animate, color bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	out, animated, colored = w, animate, color
+}
+
+// Writer returns a writer to the output of the phases that moves the spinner
+// of a running phase below what is written, e.g. for log messages
+func Writer() io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		active := activePhase()
+		if active == nil {
+			return out.Write(p)
+		}
+
+		_, _ = io.WriteString(out, clearLine)
+		n, err := out.Write(p)
+		active.draw()
+		return n, err
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// Phase is a running step of a long operation. Phases that run at the same
+// time, like the deploys of several instances, share the spinner line: it
+// shows the latest phase that is still running.
+type Phase struct {
+	label   string
+	started time.Time
+	frame   int
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Start starts a phase with a label formatted like fmt.Sprintf
+func Start(format string, args ...any) *Phase {
+	p := &Phase{
+		label:   fmt.Sprintf(format, args...),
+		started: time.Now(),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	mu.Lock()
+	animate := animated
+	if animate {
+		running = append(running, p)
+		p.draw()
+	}
+	mu.Unlock()
+
+	// Log messages are written through Writer, so they are logged without mu
+	if !animate {
+		close(p.stopped)
+		slog.Info(p.label)
+		return p
+	}
+	go p.spin()
+	return p
+}
+
+// End ends the phase, successfully if err is nil, with the time it took
+func (p *Phase) End(err error) {
+	elapsed := time.Since(p.started).Round(time.Second)
+
+	select {
+	case <-p.stop:
+		return // already ended
+	default:
+		close(p.stop)
+	}
+	<-p.stopped
+
+	mu.Lock()
+	animate := animated
+	if !animate {
+		mu.Unlock()
+		// The caller reports the error itself
+		if err != nil {
+			slog.Info(p.label+" failed", "elapsed", elapsed)
+		} else {
+			slog.Info(p.label+" done", "elapsed", elapsed)
+		}
+		return
+	}
+	defer mu.Unlock()
+
+	running = slices.DeleteFunc(running, func(phase *Phase) bool { return phase == p })
+	mark, color := "✓", colorGreen
+	if err != nil {
+		mark, color = "✗", colorRed
+	}
+	_, _ = fmt.Fprintf(out, "%s%s %s (%s)\n", clearLine, paint(mark, color), p.label, elapsed)
+	if active := activePhase(); active != nil {
+		active.draw()
+	}
+}
+
+// spin redraws the spinner until the phase ends
+func (p *Phase) spin() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(spinInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			mu.Lock()
+			if activePhase() == p {
+				p.frame++
+				p.draw()
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+// activePhase returns the phase the spinner is drawn for; mu must be held
+func activePhase() *Phase {
+	if len(running) == 0 {
+		return nil
+	}
+	return running[len(running)-1]
+}
+
+// draw writes the spinner line of the phase; mu must be held
+func (p *Phase) draw() {
+	frame := spinnerFrames[p.frame%len(spinnerFrames)]
+	elapsed := time.Since(p.started).Round(time.Second)
+	_, _ = fmt.Fprintf(out, "%s%s %s (%s)", clearLine, paint(frame, colorCyan), p.label, elapsed)
+}
+
+// paint colors text when colors are enabled; mu must be held
+func paint(text, color string) string {
+	if !colored {
+		return text
+	}
+	return color + text + colorReset
+}
@@ -0,0 +1,92 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// configure shows phases in a buffer and restores the defaults after the test
+func configure(t *testing.T, animate, color bool) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	Configure(&buf, animate, color)
+	t.Cleanup(func() { Configure(&bytes.Buffer{}, false, false) })
+	return &buf
+}
+
+func TestAnimatedPhase(t *testing.T) {
+	buf := configure(t, true, false)
+
+	phase := Start("Creating kind cluster '%s'", "deskrun")
+	phase.End(nil)
+
+	if !strings.HasPrefix(buf.String(), clearLine+"⠋ Creating kind cluster 'deskrun' (0s)") {
+		t.Errorf("output = %q, want it to start with the spinner", buf.String())
+	}
+	if !strings.HasSuffix(buf.String(), clearLine+"✓ Creating kind cluster 'deskrun' (0s)\n") {
+		t.Errorf("output = %q, want it to end with the result", buf.String())
+	}
+}
+
+func TestAnimatedPhaseFailure(t *testing.T) {
+	buf := configure(t, true, true)
+
+	Start("Waiting for CRDs to be ready").End(errors.New("timeout"))
+
+	want := clearLine + colorRed + "✗" + colorReset + " Waiting for CRDs to be ready (0s)\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("output = %q, want suffix %q", buf.String(), want)
+	}
+}
+
+func TestWriterKeepsSpinnerBelow(t *testing.T) {
+	buf := configure(t, true, false)
+
+	phase := Start("Deploying runner scale set 'my-runner'")
+	buf.Reset()
+	_, _ = Writer().Write([]byte("Wait to: 1 reconcile\n"))
+	phase.End(nil)
+
+	if !strings.HasPrefix(buf.String(), clearLine+"Wait to: 1 reconcile\n"+clearLine+"⠋ Deploying") {
+		t.Errorf("output = %q, want the message above the spinner", buf.String())
+	}
+}
+
+func TestPhaseWithoutAnimation(t *testing.T) {
+	buf := configure(t, false, false)
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(Writer(), &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})))
+	defer slog.SetDefault(previous)
+
+	Start("Waiting for CRDs to be ready").End(nil)
+
+	want := "level=INFO msg=\"Waiting for CRDs to be ready\"\n" +
+		"level=INFO msg=\"Waiting for CRDs to be ready done\" elapsed=0s\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestConcurrentPhasesShareTheSpinner(t *testing.T) {
+	buf := configure(t, true, false)
+
+	first := Start("Deploying runner scale set 'my-runner-1'")
+	second := Start("Deploying runner scale set 'my-runner-2'")
+	second.End(nil)
+
+	want := clearLine + "✓ Deploying runner scale set 'my-runner-2' (0s)\n" + clearLine + "⠋ Deploying runner scale set 'my-runner-1' (0s)"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("output = %q, want suffix %q", buf.String(), want)
+	}
+	first.End(nil)
+}
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeArchitectures returns the CPU architectures of the cluster nodes, from
+// their kubernetes.io/arch labels
+func (m *Manager) NodeArchitectures(ctx context.Context) ([]string, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var archs []string
+	for _, node := range nodes.Items {
+		if arch := node.Labels[corev1.LabelArchStable]; arch != "" && !slices.Contains(archs, arch) {
+			archs = append(archs, arch)
+		}
+	}
+	slices.Sort(archs)
+	return archs, nil
+}
+
+// checkArch verifies that the cluster has nodes of the architecture of an
+// installation, as its runner pods are never scheduled otherwise
+func (m *Manager) checkArch(ctx context.Context, arch deskruntypes.Architecture) error {
+	if arch == "" {
+		return nil
+	}
+
+	available, err := m.NodeArchitectures(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Detected node architectures", "architectures", available)
+	return archError(arch, available)
+}
+
+// archError returns an error listing the node architectures when none of the
+// nodes has the architecture of an installation
+func archError(arch deskruntypes.Architecture, available []string) error {
+	if slices.Contains(available, string(arch)) {
+		return nil
+	}
+	return fmt.Errorf("no %s nodes in the cluster (nodes: %s); runner pods would never be scheduled, change the architecture with 'deskrun edit --arch'",
+		arch, strings.Join(available, ", "))
+}
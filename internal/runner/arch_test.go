@@ -0,0 +1,19 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+)
+
+func TestArchError(t *testing.T) {
+	if err := archError(deskruntypes.ArchARM64, []string{"amd64", "arm64"}); err != nil {
+		t.Errorf("archError() = %v, want nil", err)
+	}
+
+	err := archError(deskruntypes.ArchAMD64, []string{"arm64"})
+	if err == nil || !strings.Contains(err.Error(), "no amd64 nodes in the cluster (nodes: arm64)") {
+		t.Errorf("archError() = %v, want the node architectures", err)
+	}
+}
@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rkoster/deskrun/internal/kapp"
+	"github.com/rkoster/deskrun/pkg/templates"
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+)
+
+// cacheServerAppName is the kapp app the actions cache server is deployed as
+const cacheServerAppName = templates.CacheServerName
+
+// ActionsCacheURL is the in-cluster URL runners reach the actions cache server at
+var ActionsCacheURL = templates.CacheServerURL(defaultNamespace)
+
+// RenderCacheServer processes the actions cache server template without deploying it
+func RenderCacheServer(cacheServer *deskruntypes.CacheServerConfig) ([]byte, error) {
+	processor := newProcessor()
+	config := templates.Config{
+		Installation: &deskruntypes.RunnerInstallation{
+			Name:          cacheServerAppName,
+			Repository:    "https://github.com/placeholder",
+			ContainerMode: deskruntypes.ContainerModeKubernetes,
+		},
+		InstanceName: cacheServerAppName,
+		InstanceNum:  1,
+		Namespace:    defaultNamespace,
+		CacheServer:  cacheServer,
+	}
+
+	cacheServerYAML, err := processor.ProcessTemplate(templates.TemplateTypeCacheServer, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render cache server: %w", err)
+	}
+
+	return cacheServerYAML, nil
+}
+
+// EnableCacheServer deploys the actions cache server to the cluster, updating
+// an existing deployment with the given settings
+func (m *Manager) EnableCacheServer(ctx context.Context, cacheServer *deskruntypes.CacheServerConfig) error {
+	exists, err := m.clusterManager.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("cluster does not exist")
+	}
+
+	if err := m.createNamespace(ctx, defaultNamespace); err != nil {
+		return fmt.Errorf("failed to create namespace: %w", err)
+	}
+
+	cacheServerYAML, err := RenderCacheServer(cacheServer)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("/tmp", "deskrun-cache-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	manifestPath := filepath.Join(tmpDir, "cache-server.yaml")
+	if err := os.WriteFile(manifestPath, cacheServerYAML, 0644); err != nil {
+		return fmt.Errorf("failed to write cache server manifest: %w", err)
+	}
+
+	if err := kappDeploy(ctx, m.getKappClient(), cacheServerAppName, manifestPath, kapp.DeployOptions{}); err != nil {
+		return fmt.Errorf("failed to deploy cache server: %w", err)
+	}
+	return nil
+}
+
+// DisableCacheServer removes the actions cache server from the cluster. The
+// cached data stays on the node, so enabling it again reuses the cache.
+func (m *Manager) DisableCacheServer(ctx context.Context) error {
+	if err := kappDelete(ctx, m.getKappClient(), cacheServerAppName); err != nil {
+		return fmt.Errorf("failed to delete cache server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// cacheGCImage runs the pruning of file caches
+	cacheGCImage = "busybox:1.36"
+	// dockerCacheGCImage runs a Docker daemon on a Docker data directory to prune it
+	dockerCacheGCImage = "docker:dind"
+	// dockerDataRoot is the target of mounts holding a Docker data directory
+	dockerDataRoot = "/var/lib/docker"
+	// cacheGCMountPath is where file caches are mounted into the gc pod
+	cacheGCMountPath = "/cache"
+	// cacheGCTimeout is how long a gc pod may run
+	cacheGCTimeout = 15 * time.Minute
+	// cacheGCResultPrefix marks the line a gc pod reports its result on
+	cacheGCResultPrefix = "deskrun-gc"
+)
+
+// fileCacheGCScript removes the least recently accessed files of the cache
+// until it is below the max size. It reports the size before pruning, the
+// bytes freed and the number of files removed.
+const fileCacheGCScript = `set -eu
+max=$1 dry_run=$2
+size=$(( $(du -sk /cache | cut -f1) * 1024 ))
+freed=0
+removed=0
+if [ "$size" -gt "$max" ]; then
+  find /cache -type f -exec stat -c '%X %s %n' {} + | sort -n > /tmp/files
+  while read -r atime bytes file; do
+    [ $((size - freed)) -gt "$max" ] || break
+    [ "$dry_run" = true ] || rm -f "$file"
+    freed=$((freed + bytes))
+    removed=$((removed + 1))
+  done < /tmp/files
+  [ "$dry_run" = true ] || find /cache -mindepth 1 -type d -empty -delete
+fi
+echo "deskrun-gc $size $freed $removed"
+`
+
+// dockerCacheGCScript starts a Docker daemon on the data directory and prunes
+// the build cache down to the max size, and all unused images when that is not
+// enough. Removing files of a Docker data directory directly would corrupt it.
+const dockerCacheGCScript = `set -eu
+max=$1 dry_run=$2
+usage() { echo $(( $(du -sk /var/lib/docker | cut -f1) * 1024 )); }
+size=$(usage)
+if [ "$size" -le "$max" ]; then
+  echo "deskrun-gc $size 0 0"
+  exit 0
+fi
+if [ "$dry_run" = true ]; then
+  echo "deskrun-gc $size $((size - max)) 0"
+  exit 0
+fi
+export DOCKER_HOST=unix:///tmp/docker.sock
+dockerd --data-root /var/lib/docker --host "$DOCKER_HOST" > /tmp/dockerd.log 2>&1 &
+tries=0
+until docker info > /dev/null 2>&1; do
+  tries=$((tries + 1))
+  if [ "$tries" -gt 60 ]; then cat /tmp/dockerd.log; exit 1; fi
+  sleep 1
+done
+docker builder prune --all --force --keep-storage "$max" > /dev/null
+if [ "$(usage)" -gt "$max" ]; then
+  docker image prune --all --force > /dev/null
+fi
+kill "$!"
+wait "$!" || true
+echo "deskrun-gc $size $((size - $(usage))) 0"
+`
+
+// CacheDir is a hostPath directory on the cluster node that a runner scale
+// set mounts as cache
+type CacheDir struct {
+	ScaleSet string
+	// Target is the path inside the runner container
+	Target string
+	// HostPath is the directory on the node
+	HostPath string
+	// MaxSize is the size in bytes above which the directory is pruned (0 = unbounded)
+	MaxSize int64
+}
+
+// IsDocker returns true if the directory is the data directory of a Docker daemon
+func (d CacheDir) IsDocker() bool {
+	return d.Target == dockerDataRoot
+}
+
+// CacheGCResult is the outcome of pruning a cache directory
+type CacheGCResult struct {
+	Dir CacheDir
+	// Size is the size of the directory before pruning, in bytes
+	Size int64
+	// Freed is the number of bytes removed, or that would be removed in a dry run
+	Freed int64
+	// Removed is the number of files removed (always 0 for Docker data directories)
+	Removed int
+}
+
+// CacheDirs returns the cache directories of the scale sets of an
+// installation, with instance placeholders and auto-generated host paths
+// resolved the way the runner template does. Socket mounts and mounts backed
+// by persistent volume claims are left out.
+func CacheDirs(installation *deskruntypes.RunnerInstallation) ([]CacheDir, error) {
+	instances := installation.Instances
+	if instances < 1 {
+		instances = 1
+	}
+
+	var dirs []CacheDir
+	for instance := 1; instance <= instances; instance++ {
+		scaleSet, instanceNum := installation.Name, 0
+		if instances > 1 {
+			scaleSet, instanceNum = fmt.Sprintf("%s-%d", installation.Name, instance), instance
+		}
+
+		for i, cachePath := range installation.CachePaths {
+			if installation.ClaimsCachePath(cachePath) {
+				continue
+			}
+			source := installation.CachePathSource(cachePath, instanceNum)
+			dir, err := newCacheDir(scaleSet, instanceNum, i, cachePath.Target, source, cachePath.MaxSize)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, dir)
+		}
+		for i, mount := range installation.Mounts {
+			if mount.Type == deskruntypes.MountTypeSocket || installation.ClaimsMount(mount) {
+				continue
+			}
+			source := installation.MountSource(mount, instanceNum)
+			dir, err := newCacheDir(scaleSet, instanceNum, i+len(installation.CachePaths), mount.Target, source, mount.MaxSize)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// newCacheDir resolves the host path of the mount with the given index
+func newCacheDir(scaleSet string, instanceNum, index int, target, source, maxSize string) (CacheDir, error) {
+	dir := CacheDir{ScaleSet: scaleSet, Target: target, HostPath: source}
+	if dir.HostPath == "" {
+		name := scaleSet
+		if instanceNum > 0 {
+			name = fmt.Sprintf("%s-%d", scaleSet, instanceNum)
+		}
+		dir.HostPath = fmt.Sprintf("/tmp/github-runner-cache/%s/mount-%d", name, index)
+	}
+
+	if maxSize != "" {
+		quantity, err := resource.ParseQuantity(maxSize)
+		if err != nil {
+			return CacheDir{}, fmt.Errorf("invalid max size '%s' of %s: %w", maxSize, target, err)
+		}
+		dir.MaxSize = quantity.Value()
+	}
+	return dir, nil
+}
+
+// GarbageCollectCache prunes a cache directory down to its max size. A pod
+// with the directory mounted runs on the node: Docker data directories are
+// pruned with the Docker CLI, other caches lose their least recently accessed
+// files. With dryRun nothing is removed.
+func (m *Manager) GarbageCollectCache(ctx context.Context, dir CacheDir, dryRun bool) (*CacheGCResult, error) {
+	if dir.MaxSize <= 0 {
+		return nil, fmt.Errorf("cache %s of %s has no max size", dir.Target, dir.ScaleSet)
+	}
+
+	logs, err := m.runNodePod(ctx, cacheGCPod(dir, dryRun), cacheGCTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("cache gc of %s: %w", dir.HostPath, err)
+	}
+
+	result, err := parseCacheGCResult(logs)
+	if err != nil {
+		return nil, fmt.Errorf("cache gc of %s: %w", dir.HostPath, err)
+	}
+	result.Dir = dir
+	return result, nil
+}
+
+// runNodePod runs a pod to completion and returns its logs. The pod is
+// removed afterwards, also when it failed.
+func (m *Manager) runNodePod(ctx context.Context, pod *corev1.Pod, timeout time.Duration) (string, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return "", err
+	}
+
+	pod, err = clientset.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to create pod: %w", err)
+	}
+	defer func() {
+		// Remove the pod even if the wait was interrupted
+		deleteCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = clientset.CoreV1().Pods(pod.Namespace).Delete(deleteCtx, pod.Name, metav1.DeleteOptions{})
+	}()
+
+	var phase corev1.PodPhase
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		phase = current.Status.Phase
+		return phase == corev1.PodSucceeded || phase == corev1.PodFailed, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("pod %s did not complete: %w", pod.Name, err)
+	}
+
+	logs, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs of pod %s: %w", pod.Name, err)
+	}
+	if phase == corev1.PodFailed {
+		return "", fmt.Errorf("pod %s failed: %s", pod.Name, strings.TrimSpace(string(logs)))
+	}
+	return string(logs), nil
+}
+
+// cacheGCPod returns the pod that prunes a cache directory
+func cacheGCPod(dir CacheDir, dryRun bool) *corev1.Pod {
+	image, script, mountPath := cacheGCImage, fileCacheGCScript, cacheGCMountPath
+	var securityContext *corev1.SecurityContext
+	if dir.IsDocker() {
+		privileged := true
+		image, script, mountPath = dockerCacheGCImage, dockerCacheGCScript, dockerDataRoot
+		securityContext = &corev1.SecurityContext{Privileged: &privileged}
+	}
+
+	hostPathType := corev1.HostPathDirectory
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "deskrun-cache-gc-",
+			Namespace:    defaultNamespace,
+			Labels:       map[string]string{"app.kubernetes.io/name": "deskrun-cache-gc"},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:            "gc",
+				Image:           image,
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Command:         []string{"sh", "-c", script, "sh", strconv.FormatInt(dir.MaxSize, 10), strconv.FormatBool(dryRun)},
+				SecurityContext: securityContext,
+				VolumeMounts:    []corev1.VolumeMount{{Name: "cache", MountPath: mountPath}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "cache",
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: dir.HostPath, Type: &hostPathType},
+				},
+			}},
+		},
+	}
+}
+
+// parseCacheGCResult parses the result line of a gc pod's logs
+func parseCacheGCResult(logs string) (*CacheGCResult, error) {
+	for _, line := range strings.Split(logs, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[0] != cacheGCResultPrefix {
+			continue
+		}
+
+		size, sizeErr := strconv.ParseInt(fields[1], 10, 64)
+		freed, freedErr := strconv.ParseInt(fields[2], 10, 64)
+		removed, removedErr := strconv.Atoi(fields[3])
+		if sizeErr != nil || freedErr != nil || removedErr != nil {
+			return nil, fmt.Errorf("invalid result %q", line)
+		}
+		return &CacheGCResult{Size: size, Freed: freed, Removed: removed}, nil
+	}
+	return nil, fmt.Errorf("no result reported")
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+)
+
+func TestCacheDirs(t *testing.T) {
+	installation := &deskruntypes.RunnerInstallation{
+		Name:       "my-runner",
+		Instances:  2,
+		CachePaths: []deskruntypes.CachePath{{Target: "/root/.cache", MaxSize: "1Gi"}},
+		Mounts: []deskruntypes.Mount{
+			{Target: "/var/run/docker.sock", Source: "/var/run/docker.sock", Type: deskruntypes.MountTypeSocket},
+			{Target: "/var/lib/docker", Source: "/host-cache/deskrun/docker"},
+		},
+	}
+
+	dirs, err := CacheDirs(installation)
+	if err != nil {
+		t.Fatalf("CacheDirs() error = %v", err)
+	}
+
+	want := []CacheDir{
+		{ScaleSet: "my-runner-1", Target: "/root/.cache", HostPath: "/tmp/github-runner-cache/my-runner-1-1/mount-0", MaxSize: 1 << 30},
+		{ScaleSet: "my-runner-1", Target: "/var/lib/docker", HostPath: "/host-cache/deskrun/docker"},
+		{ScaleSet: "my-runner-2", Target: "/root/.cache", HostPath: "/tmp/github-runner-cache/my-runner-2-2/mount-0", MaxSize: 1 << 30},
+		{ScaleSet: "my-runner-2", Target: "/var/lib/docker", HostPath: "/host-cache/deskrun/docker"},
+	}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("CacheDirs() = %+v, want %+v", dirs, want)
+	}
+
+	single := &deskruntypes.RunnerInstallation{Name: "single", Mounts: []deskruntypes.Mount{{Target: "/cache"}}}
+	dirs, err = CacheDirs(single)
+	if err != nil {
+		t.Fatalf("CacheDirs() error = %v", err)
+	}
+	if len(dirs) != 1 || dirs[0].HostPath != "/tmp/github-runner-cache/single/mount-0" {
+		t.Errorf("CacheDirs() = %+v, want the auto-generated path of a single instance", dirs)
+	}
+
+	pvc := &deskruntypes.RunnerInstallation{
+		Name:         "pvc",
+		CacheStorage: &deskruntypes.CacheStorage{Type: deskruntypes.CacheTypePVC},
+		Mounts:       []deskruntypes.Mount{{Target: "/cache"}, {Target: "/var/lib/docker", Source: "/host-cache/deskrun/docker"}},
+	}
+	dirs, err = CacheDirs(pvc)
+	if err != nil {
+		t.Fatalf("CacheDirs() error = %v", err)
+	}
+	if len(dirs) != 1 || dirs[0].Target != "/var/lib/docker" {
+		t.Errorf("CacheDirs() = %+v, want only the mount with a host path", dirs)
+	}
+}
+
+func TestCacheGCPod(t *testing.T) {
+	files := cacheGCPod(CacheDir{Target: "/root/.cache", HostPath: "/cache/dir", MaxSize: 1024}, true)
+	container := files.Spec.Containers[0]
+	if container.Image != cacheGCImage || container.SecurityContext != nil {
+		t.Errorf("file cache gc container = %+v, want an unprivileged %s container", container, cacheGCImage)
+	}
+	if args := container.Command[len(container.Command)-2:]; !reflect.DeepEqual(args, []string{"1024", "true"}) {
+		t.Errorf("file cache gc arguments = %v, want max size and dry run", args)
+	}
+	if path := files.Spec.Volumes[0].HostPath.Path; path != "/cache/dir" {
+		t.Errorf("hostPath = %s, want /cache/dir", path)
+	}
+
+	docker := cacheGCPod(CacheDir{Target: "/var/lib/docker", HostPath: "/docker", MaxSize: 1024}, false)
+	container = docker.Spec.Containers[0]
+	if container.Image != dockerCacheGCImage || container.SecurityContext == nil || !*container.SecurityContext.Privileged {
+		t.Errorf("docker cache gc container = %+v, want a privileged %s container", container, dockerCacheGCImage)
+	}
+	if container.VolumeMounts[0].MountPath != "/var/lib/docker" {
+		t.Errorf("docker cache gc mount = %s, want /var/lib/docker", container.VolumeMounts[0].MountPath)
+	}
+}
+
+func TestParseCacheGCResult(t *testing.T) {
+	result, err := parseCacheGCResult("pruning\ndeskrun-gc 2048 1024 3\n")
+	if err != nil {
+		t.Fatalf("parseCacheGCResult() error = %v", err)
+	}
+	if result.Size != 2048 || result.Freed != 1024 || result.Removed != 3 {
+		t.Errorf("parseCacheGCResult() = %+v", result)
+	}
+
+	if _, err := parseCacheGCResult("dockerd failed\n"); err == nil {
+		t.Error("parseCacheGCResult() expected an error without a result line")
+	}
+}
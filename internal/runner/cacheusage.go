@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// cacheUsageTimeout is how long measuring the cache directories may take
+	cacheUsageTimeout = 10 * time.Minute
+	// cacheUsageResultPrefix marks the lines the usage pod reports a directory on
+	cacheUsageResultPrefix = "deskrun-usage"
+	// nodeRootMountPath is where the node's root filesystem is mounted read-only
+	nodeRootMountPath = "/node"
+)
+
+// cacheUsageScript reports the size of every directory given as argument and
+// the newest modification time of the directory and its entries, which is
+// when a job last wrote to it. Missing directories are reported with a dash.
+const cacheUsageScript = `for dir in "$@"; do
+  if [ -d "/node$dir" ]; then
+    size=$(du -sk "/node$dir" | cut -f1)
+    used=$(stat -c %Y "/node$dir" "/node$dir"/* 2>/dev/null | sort -n | tail -n 1)
+    echo "deskrun-usage $((size * 1024)) $used $dir"
+  else
+    echo "deskrun-usage - - $dir"
+  fi
+done
+`
+
+// CacheUsage is the disk usage of a directory on the cluster node
+type CacheUsage struct {
+	HostPath string
+	// Exists is false if the directory was not created yet, i.e. no runner
+	// mounted it so far
+	Exists bool
+	// Size is the size of the directory in bytes
+	Size int64
+	// LastUsed is when the directory or one of its entries was last modified
+	LastUsed time.Time
+}
+
+// CacheVolume is a persistent volume claim bound to a hostPath volume, e.g.
+// the one of the actions cache server
+type CacheVolume struct {
+	Namespace string
+	Name      string
+	// Capacity is the requested size of the claim, e.g. 50Gi
+	Capacity string
+	// HostPath is the directory on the node backing the claim
+	HostPath string
+}
+
+// CacheUsage measures the given directories on the cluster node with a pod
+// that mounts the node's root filesystem read-only
+func (m *Manager) CacheUsage(ctx context.Context, hostPaths []string) ([]CacheUsage, error) {
+	if len(hostPaths) == 0 {
+		return nil, nil
+	}
+
+	logs, err := m.runNodePod(ctx, cacheUsagePod(hostPaths), cacheUsageTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure caches: %w", err)
+	}
+	return parseCacheUsage(logs)
+}
+
+// CacheVolumes returns the persistent volume claims of the cluster that are
+// bound to hostPath volumes, sorted by namespace and name
+func (m *Manager) CacheVolumes(ctx context.Context) ([]CacheVolume, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := clientset.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volume claims: %w", err)
+	}
+	volumes, err := clientset.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volumes: %w", err)
+	}
+
+	return hostPathClaims(claims.Items, volumes.Items), nil
+}
+
+// hostPathClaims returns the claims bound to hostPath volumes
+func hostPathClaims(claims []corev1.PersistentVolumeClaim, volumes []corev1.PersistentVolume) []CacheVolume {
+	hostPaths := make(map[string]string, len(volumes))
+	for _, volume := range volumes {
+		if volume.Spec.HostPath != nil {
+			hostPaths[volume.Name] = volume.Spec.HostPath.Path
+		}
+	}
+
+	var cacheVolumes []CacheVolume
+	for _, claim := range claims {
+		hostPath, ok := hostPaths[claim.Spec.VolumeName]
+		if !ok {
+			continue
+		}
+		capacity := ""
+		if storage, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+			capacity = storage.String()
+		}
+		cacheVolumes = append(cacheVolumes, CacheVolume{
+			Namespace: claim.Namespace,
+			Name:      claim.Name,
+			Capacity:  capacity,
+			HostPath:  hostPath,
+		})
+	}
+
+	sort.Slice(cacheVolumes, func(i, j int) bool {
+		if cacheVolumes[i].Namespace != cacheVolumes[j].Namespace {
+			return cacheVolumes[i].Namespace < cacheVolumes[j].Namespace
+		}
+		return cacheVolumes[i].Name < cacheVolumes[j].Name
+	})
+	return cacheVolumes
+}
+
+// cacheUsagePod returns the pod that measures directories on the node
+func cacheUsagePod(hostPaths []string) *corev1.Pod {
+	command := append([]string{"sh", "-c", cacheUsageScript, "sh"}, hostPaths...)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "deskrun-cache-usage-",
+			Namespace:    defaultNamespace,
+			Labels:       map[string]string{"app.kubernetes.io/name": "deskrun-cache-usage"},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:            "usage",
+				Image:           cacheGCImage,
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Command:         command,
+				VolumeMounts:    []corev1.VolumeMount{{Name: "node", MountPath: nodeRootMountPath, ReadOnly: true}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "node",
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: "/"},
+				},
+			}},
+		},
+	}
+}
+
+// parseCacheUsage parses the lines the usage pod reports directories on
+func parseCacheUsage(logs string) ([]CacheUsage, error) {
+	var usages []CacheUsage
+	for _, line := range strings.Split(logs, "\n") {
+		fields := strings.SplitN(line, " ", 4)
+		if len(fields) != 4 || fields[0] != cacheUsageResultPrefix {
+			continue
+		}
+
+		usage := CacheUsage{HostPath: fields[3]}
+		if fields[1] != "-" {
+			size, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cache usage %q", line)
+			}
+			usage.Exists = true
+			usage.Size = size
+			if modified, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+				usage.LastUsed = time.Unix(modified, 0)
+			}
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseCacheUsage(t *testing.T) {
+	usages, err := parseCacheUsage("deskrun-usage 4096 1700000000 /tmp/github-runner-cache/my runner/mount-0\ndeskrun-usage - - /missing\n")
+	if err != nil {
+		t.Fatalf("parseCacheUsage() error = %v", err)
+	}
+
+	want := []CacheUsage{
+		{HostPath: "/tmp/github-runner-cache/my runner/mount-0", Exists: true, Size: 4096, LastUsed: time.Unix(1700000000, 0)},
+		{HostPath: "/missing"},
+	}
+	if !reflect.DeepEqual(usages, want) {
+		t.Errorf("parseCacheUsage() = %+v, want %+v", usages, want)
+	}
+}
+
+func TestHostPathClaims(t *testing.T) {
+	volumes := []corev1.PersistentVolume{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache-pv"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: "/var/lib/deskrun/actions-cache"},
+			}},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "nfs-pv"}},
+	}
+	claims := []corev1.PersistentVolumeClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "arc-systems", Name: "cache"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				VolumeName: "cache-pv",
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("50Gi")},
+				},
+			},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data"}, Spec: corev1.PersistentVolumeClaimSpec{VolumeName: "nfs-pv"}},
+	}
+
+	want := []CacheVolume{{Namespace: "arc-systems", Name: "cache", Capacity: "50Gi", HostPath: "/var/lib/deskrun/actions-cache"}}
+	if got := hostPathClaims(claims, volumes); !reflect.DeepEqual(got, want) {
+		t.Errorf("hostPathClaims() = %+v, want %+v", got, want)
+	}
+}
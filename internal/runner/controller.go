@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rkoster/deskrun/internal/kapp"
+	"github.com/rkoster/deskrun/pkg/templates"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// controllerVersionLabel records the deployed controller version on the kapp app
+	controllerVersionLabel = "deskrun.io/controller-version"
+	// chartVersionLabel is set by the ARC Helm chart on the controller resources
+	chartVersionLabel = "app.kubernetes.io/version"
+	// kappAppSuffix is appended to kapp app ConfigMap names when kapp uses fully qualified names
+	kappAppSuffix = ".apps.k14s.io"
+)
+
+// ControllerUpgradeOptions controls how the ARC controller is upgraded
+type ControllerUpgradeOptions struct {
+	// DiffOnly shows the changes without applying them
+	DiffOnly bool
+}
+
+// EmbeddedControllerVersion returns the ARC controller version of an embedded
+// chart version (empty for the default version bundled with deskrun)
+func EmbeddedControllerVersion(chartVersion string) (string, error) {
+	controllerYAML, err := RenderController(chartVersion, nil, false)
+	if err != nil {
+		return "", err
+	}
+	return controllerVersionFromYAML(controllerYAML)
+}
+
+// DeployedControllerVersion returns the version of the ARC controller in the
+// cluster, or an empty string if it is not installed. Controllers installed
+// before versions were recorded on the kapp app fall back to the chart label
+// of the controller Deployment.
+func (m *Manager) DeployedControllerVersion(ctx context.Context) (string, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range []string{arcControllerAppName + kappAppSuffix, arcControllerAppName} {
+		configMap, err := clientset.CoreV1().ConfigMaps(arcControllerNamespace).Get(ctx, name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to get controller app: %w", err)
+		}
+		if version := configMap.Labels[controllerVersionLabel]; version != "" {
+			return version, nil
+		}
+		break
+	}
+
+	deployments, err := clientset.AppsV1().Deployments(arcControllerNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: controllerPodSelector,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list controller deployments: %w", err)
+	}
+	for _, deployment := range deployments.Items {
+		if version := deployment.Labels[chartVersionLabel]; version != "" {
+			return version, nil
+		}
+	}
+
+	return "", nil
+}
+
+// UpgradeController re-renders the embedded ARC controller of the manager's
+// controller version and deploys it with kapp, which only applies the
+// resources that changed
+func (m *Manager) UpgradeController(ctx context.Context, opts ControllerUpgradeOptions) error {
+	exists, err := m.clusterManager.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("cluster does not exist")
+	}
+
+	if err := m.deployController(ctx, kapp.DeployOptions{ShowChanges: true, DiffOnly: opts.DiffOnly}); err != nil {
+		return fmt.Errorf("failed to upgrade ARC controller: %w", err)
+	}
+
+	if opts.DiffOnly {
+		return nil
+	}
+
+	if err := m.waitForCRD(ctx, "autoscalingrunnersets.actions.github.com"); err != nil {
+		return fmt.Errorf("timeout waiting for CRDs to be ready: %w", err)
+	}
+
+	return nil
+}
+
+// UninstallController removes the ARC controller and its CRDs from the
+// cluster. Runner scale sets still deployed lose their custom resources, so
+// they should be uninstalled first.
+func (m *Manager) UninstallController(ctx context.Context) error {
+	if err := kappDelete(ctx, m.getKappClient(), arcControllerAppName); err != nil {
+		return fmt.Errorf("failed to delete ARC controller: %w", err)
+	}
+	return nil
+}
+
+// deployController renders the ARC controller and deploys it as a kapp app
+// labeled with the controller version
+func (m *Manager) deployController(ctx context.Context, opts kapp.DeployOptions) error {
+	// Get controller template using the unified template package
+	// RenderController applies the overlay which adds required RBAC permissions
+	// and enables the metrics of the controller and listeners
+	controllerYAML, err := RenderController(m.controllerVersion, m.controllerProxy, true)
+	if err != nil {
+		return err
+	}
+
+	version, err := controllerVersionFromYAML(controllerYAML)
+	if err != nil {
+		return err
+	}
+	opts.Labels = map[string]string{controllerVersionLabel: version}
+
+	// Create temporary directory for controller templates
+	tmpDir, err := os.MkdirTemp("/tmp", "deskrun-controller-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	// Write to temp file for kapp
+	controllerPath := filepath.Join(tmpDir, "controller.yaml")
+	if err := os.WriteFile(controllerPath, controllerYAML, 0644); err != nil {
+		return fmt.Errorf("failed to write controller template: %w", err)
+	}
+
+	// Deploy controller using kapp (no ytt processing needed for controller - it's pre-rendered)
+	return kappDeploy(ctx, m.getKappClient(), arcControllerAppName, controllerPath, opts)
+}
+
+// controllerVersionFromYAML extracts the chart version of the controller
+// Deployment from the rendered controller manifests
+func controllerVersionFromYAML(controllerYAML []byte) (string, error) {
+	objects, err := templates.ParseObjects(controllerYAML)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse controller manifests: %w", err)
+	}
+
+	for _, obj := range objects {
+		if version := obj.GetLabels()[chartVersionLabel]; obj.GetKind() == "Deployment" && version != "" {
+			return version, nil
+		}
+	}
+
+	return "", fmt.Errorf("controller manifests do not contain a versioned Deployment")
+}
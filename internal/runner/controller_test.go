@@ -0,0 +1,32 @@
+package runner
+
+import "testing"
+
+func TestControllerVersionFromYAML(t *testing.T) {
+	controllerYAML := []byte(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: arc-controller-gha-rs-controller
+  labels:
+    app.kubernetes.io/version: "0.12.0"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: arc-controller-gha-rs-controller
+  labels:
+    app.kubernetes.io/version: "0.13.0"
+`)
+
+	version, err := controllerVersionFromYAML(controllerYAML)
+	if err != nil {
+		t.Fatalf("controllerVersionFromYAML() error = %v", err)
+	}
+	if version != "0.13.0" {
+		t.Errorf("controllerVersionFromYAML() = %q, want 0.13.0", version)
+	}
+
+	if _, err := controllerVersionFromYAML([]byte("kind: ConfigMap\n")); err == nil {
+		t.Error("controllerVersionFromYAML() without Deployment error = nil, want error")
+	}
+}
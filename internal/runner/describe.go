@@ -0,0 +1,242 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// describeRunnerLimit is the number of most recent EphemeralRunners described
+const describeRunnerLimit = 10
+
+var (
+	// autoscalingRunnerSetGVR identifies the ARC AutoscalingRunnerSet resource
+	autoscalingRunnerSetGVR = schema.GroupVersionResource{
+		Group:    "actions.github.com",
+		Version:  "v1alpha1",
+		Resource: "autoscalingrunnersets",
+	}
+	// autoscalingListenerGVR identifies the ARC AutoscalingListener resource
+	autoscalingListenerGVR = schema.GroupVersionResource{
+		Group:    "actions.github.com",
+		Version:  "v1alpha1",
+		Resource: "autoscalinglisteners",
+	}
+)
+
+// ScaleSetDescription aggregates the state of a runner scale set and the
+// resources ARC created for it
+type ScaleSetDescription struct {
+	Name            string
+	Namespace       string
+	GitHubConfigURL string
+	RunnerGroup     string
+	// MinRunners and MaxRunners are nil when not set on the scale set
+	MinRunners *int64
+	MaxRunners *int64
+
+	CurrentRunners int64
+	PendingRunners int64
+	RunningRunners int64
+	FailedRunners  int64
+
+	// Listener is nil while ARC has not created the listener yet
+	Listener *ListenerDescription
+	// Runners are the most recent EphemeralRunners, newest first
+	Runners []EphemeralRunner
+	// TotalRunners is the number of EphemeralRunners, including those not described
+	TotalRunners int
+	Secrets      []SecretPresence
+	RoleBindings []RoleBindingDescription
+	// Events are the recent warnings of the resources above by EventKey
+	Events map[string][]ResourceEvent
+}
+
+// ListenerDescription describes the AutoscalingListener of a scale set and its pod
+type ListenerDescription struct {
+	Name      string
+	Namespace string
+	// PodPhase is empty when the listener pod does not exist
+	PodPhase string
+	Ready    bool
+	Restarts int32
+}
+
+// SecretPresence reports whether a secret the scale set depends on exists
+type SecretPresence struct {
+	Name   string
+	Exists bool
+}
+
+// RoleBindingDescription describes a RoleBinding granting permissions to the
+// scale set's runners or listener
+type RoleBindingDescription struct {
+	Name     string
+	Role     string
+	Subjects []string
+}
+
+// DescribeScaleSet gathers the spec and status of a runner scale set, its
+// listener, recent EphemeralRunners, RBAC bindings and recent warning events.
+// Besides the GitHub config secret, the presence of the given secrets is
+// checked, e.g. the secrets mounted into the runners.
+func (m *Manager) DescribeScaleSet(ctx context.Context, name string, secrets []string) (*ScaleSetDescription, error) {
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, err := m.scaleSetNamespace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	scaleSet, err := dynamicClient.Resource(autoscalingRunnerSetGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("runner scale set %s not found in namespace %s", name, namespace)
+		}
+		return nil, fmt.Errorf("failed to get runner scale set: %w", err)
+	}
+	description, configSecret := parseScaleSet(*scaleSet)
+
+	listener, err := findListener(ctx, dynamicClient, name)
+	if err != nil {
+		return nil, err
+	}
+	if listener != "" {
+		description.Listener = &ListenerDescription{Name: listener, Namespace: arcControllerNamespace}
+		pod, err := clientset.CoreV1().Pods(arcControllerNamespace).Get(ctx, listener, metav1.GetOptions{})
+		switch {
+		case err == nil:
+			describeListenerPod(description.Listener, *pod)
+		case !k8serrors.IsNotFound(err):
+			return nil, fmt.Errorf("failed to get listener pod: %w", err)
+		}
+	}
+
+	runners, err := m.EphemeralRunners(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	description.TotalRunners = len(runners)
+	description.Runners = recentRunners(runners, describeRunnerLimit)
+
+	if configSecret != "" {
+		secrets = append([]string{configSecret}, secrets...)
+	}
+	for _, secret := range secrets {
+		_, err := clientset.CoreV1().Secrets(description.Namespace).Get(ctx, secret, metav1.GetOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get secret %s: %w", secret, err)
+		}
+		description.Secrets = append(description.Secrets, SecretPresence{Name: secret, Exists: err == nil})
+	}
+
+	roleBindings, err := clientset.RbacV1().RoleBindings(description.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role bindings: %w", err)
+	}
+	description.RoleBindings = scaleSetRoleBindings(name, roleBindings.Items)
+
+	namespaces := []string{description.Namespace}
+	if description.Namespace != arcControllerNamespace {
+		namespaces = append(namespaces, arcControllerNamespace)
+	}
+	description.Events, err = m.ResourceEvents(ctx, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	return description, nil
+}
+
+// parseScaleSet extracts the spec and status of an AutoscalingRunnerSet and
+// returns the name of its GitHub config secret
+func parseScaleSet(obj unstructured.Unstructured) (*ScaleSetDescription, string) {
+	description := &ScaleSetDescription{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+	}
+
+	description.GitHubConfigURL, _, _ = unstructured.NestedString(obj.Object, "spec", "githubConfigUrl")
+	description.RunnerGroup, _, _ = unstructured.NestedString(obj.Object, "spec", "runnerGroup")
+	if minRunners, found, _ := unstructured.NestedInt64(obj.Object, "spec", "minRunners"); found {
+		description.MinRunners = &minRunners
+	}
+	if maxRunners, found, _ := unstructured.NestedInt64(obj.Object, "spec", "maxRunners"); found {
+		description.MaxRunners = &maxRunners
+	}
+
+	description.CurrentRunners, _, _ = unstructured.NestedInt64(obj.Object, "status", "currentRunners")
+	description.PendingRunners, _, _ = unstructured.NestedInt64(obj.Object, "status", "pendingEphemeralRunners")
+	description.RunningRunners, _, _ = unstructured.NestedInt64(obj.Object, "status", "runningEphemeralRunners")
+	description.FailedRunners, _, _ = unstructured.NestedInt64(obj.Object, "status", "failedEphemeralRunners")
+
+	configSecret, _, _ := unstructured.NestedString(obj.Object, "spec", "githubConfigSecret")
+	return description, configSecret
+}
+
+// describeListenerPod adds the phase, readiness and restarts of the listener pod
+func describeListenerPod(listener *ListenerDescription, pod corev1.Pod) {
+	listener.PodPhase = string(pod.Status.Phase)
+	listener.Ready = podReady(pod)
+	for _, container := range pod.Status.ContainerStatuses {
+		listener.Restarts += container.RestartCount
+	}
+}
+
+// recentRunners returns up to limit EphemeralRunners, newest first
+func recentRunners(runners []EphemeralRunner, limit int) []EphemeralRunner {
+	recent := append([]EphemeralRunner(nil), runners...)
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].Created.After(recent[j].Created)
+	})
+	if len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent
+}
+
+// scaleSetRoleBindings returns the RoleBindings deskrun and ARC create for a
+// scale set: the <name>-gha-rs-* bindings of the runners and the
+// <name>-*-listener binding of the listener
+func scaleSetRoleBindings(name string, roleBindings []rbacv1.RoleBinding) []RoleBindingDescription {
+	var descriptions []RoleBindingDescription
+	for _, rb := range roleBindings {
+		runnerBinding := strings.HasPrefix(rb.Name, name+"-gha-rs-")
+		listenerBinding := strings.HasPrefix(rb.Name, name+"-") && strings.HasSuffix(rb.Name, "-listener")
+		if !runnerBinding && !listenerBinding {
+			continue
+		}
+
+		description := RoleBindingDescription{
+			Name: rb.Name,
+			Role: rb.RoleRef.Kind + "/" + rb.RoleRef.Name,
+		}
+		for _, subject := range rb.Subjects {
+			if subject.Namespace != "" {
+				description.Subjects = append(description.Subjects, fmt.Sprintf("%s %s/%s", subject.Kind, subject.Namespace, subject.Name))
+			} else {
+				description.Subjects = append(description.Subjects, fmt.Sprintf("%s %s", subject.Kind, subject.Name))
+			}
+		}
+		descriptions = append(descriptions, description)
+	}
+
+	sort.Slice(descriptions, func(i, j int) bool {
+		return descriptions[i].Name < descriptions[j].Name
+	})
+	return descriptions
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseScaleSet(t *testing.T) {
+	obj := unstructured.Unstructured{Object: map[string]any{
+		"metadata": map[string]any{"name": "my-runner", "namespace": "arc-systems"},
+		"spec": map[string]any{
+			"githubConfigUrl":    "https://github.com/owner/repo",
+			"githubConfigSecret": "my-runner-gha-rs-github-secret",
+			"maxRunners":         int64(5),
+		},
+		"status": map[string]any{
+			"currentRunners":          int64(2),
+			"pendingEphemeralRunners": int64(1),
+			"runningEphemeralRunners": int64(1),
+		},
+	}}
+
+	description, configSecret := parseScaleSet(obj)
+	if description.Name != "my-runner" || description.GitHubConfigURL != "https://github.com/owner/repo" {
+		t.Errorf("parseScaleSet() = %+v", description)
+	}
+	if configSecret != "my-runner-gha-rs-github-secret" {
+		t.Errorf("config secret = %q", configSecret)
+	}
+	if description.MinRunners != nil || description.MaxRunners == nil || *description.MaxRunners != 5 {
+		t.Errorf("runner limits = %v, %v, want unset and 5", description.MinRunners, description.MaxRunners)
+	}
+	if description.CurrentRunners != 2 || description.PendingRunners != 1 || description.RunningRunners != 1 {
+		t.Errorf("runner counts = %+v", description)
+	}
+}
+
+func TestRecentRunners(t *testing.T) {
+	now := time.Now()
+	runners := []EphemeralRunner{
+		{Name: "a", Created: now.Add(-3 * time.Minute)},
+		{Name: "b", Created: now.Add(-time.Minute)},
+		{Name: "c", Created: now.Add(-2 * time.Minute)},
+	}
+
+	recent := recentRunners(runners, 2)
+	if len(recent) != 2 || recent[0].Name != "b" || recent[1].Name != "c" {
+		t.Errorf("recentRunners() = %+v, want b and c", recent)
+	}
+	if runners[0].Name != "a" {
+		t.Error("recentRunners() modified its input")
+	}
+}
+
+func TestScaleSetRoleBindings(t *testing.T) {
+	newRoleBinding := func(name string) rbacv1.RoleBinding {
+		return rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: name},
+			Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Namespace: "arc-systems", Name: name}},
+		}
+	}
+
+	descriptions := scaleSetRoleBindings("my-runner", []rbacv1.RoleBinding{
+		newRoleBinding("my-runner-gha-rs-kube-mode"),
+		newRoleBinding("my-runner-754b578d-listener"),
+		newRoleBinding("other-runner-gha-rs-kube-mode"),
+		newRoleBinding("arc-controller-gha-rs-controller"),
+	})
+
+	if len(descriptions) != 2 {
+		t.Fatalf("scaleSetRoleBindings() returned %d bindings, want 2: %+v", len(descriptions), descriptions)
+	}
+	if descriptions[0].Name != "my-runner-754b578d-listener" || descriptions[1].Name != "my-runner-gha-rs-kube-mode" {
+		t.Errorf("scaleSetRoleBindings() = %+v", descriptions)
+	}
+	if descriptions[1].Role != "Role/my-runner-gha-rs-kube-mode" ||
+		descriptions[1].Subjects[0] != "ServiceAccount arc-systems/my-runner-gha-rs-kube-mode" {
+		t.Errorf("binding = %+v", descriptions[1])
+	}
+}
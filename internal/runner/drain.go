@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/progress"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// DrainTimeout is how long Drain waits for the running jobs of a scale set
+	DrainTimeout = 10 * time.Minute
+	// drainPollInterval is how often Drain checks for running jobs
+	drainPollInterval = 5 * time.Second
+	// drainRestoreTimeout bounds restoring the runner limits of a scale set
+	// that didn't drain in time
+	drainRestoreTimeout = 30 * time.Second
+)
+
+// Drain stops a runner scale set from picking up new jobs by scaling it down
+// to zero runners, and waits until none of its runners runs a job anymore.
+// Scale sets without running jobs are left untouched.
+func (m *Manager) Drain(ctx context.Context, name string, timeout time.Duration) error {
+	return m.DrainAll(ctx, []string{name}, timeout)[0]
+}
+
+// DrainAll drains the given runner scale sets together: all busy scale sets
+// are scaled down first, and their jobs then get the same timeout to finish.
+// Scale sets still running jobs after the timeout get their previous runner
+// limits back, so they keep picking up jobs. It returns the error of each
+// scale set by index.
+func (m *Manager) DrainAll(ctx context.Context, names []string, timeout time.Duration) []error {
+	errs := make([]error, len(names))
+	draining := make(map[int]runnerLimits)
+	var jobs int
+	var busyNames []string
+	for i, name := range names {
+		busy, err := m.BusyRunners(ctx, name)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		if len(busy) == 0 {
+			continue
+		}
+
+		limits, err := m.getRunnerLimits(ctx, name)
+		if err == nil {
+			err = m.PauseScaleSet(ctx, name)
+		}
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to drain runner scale set %s: %w", name, err)
+			continue
+		}
+		draining[i] = limits
+		jobs += len(busy)
+		busyNames = append(busyNames, name)
+	}
+	if len(draining) == 0 {
+		return errs
+	}
+
+	phase := progress.Start("Waiting for %d running job(s) of '%s' to finish", jobs, strings.Join(busyNames, "', '"))
+	err := wait.PollUntilContextTimeout(ctx, drainPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		for i := range draining {
+			busy, err := m.BusyRunners(ctx, names[i])
+			if err != nil {
+				return false, err
+			}
+			if len(busy) == 0 {
+				delete(draining, i)
+			}
+		}
+		return len(draining) == 0, nil
+	})
+	phase.End(err)
+	if err == nil {
+		return errs
+	}
+
+	// The context may be done already, restoring the limits must still happen
+	restoreCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), drainRestoreTimeout)
+	defer cancel()
+	for i, limits := range draining {
+		errs[i] = fmt.Errorf("runner scale set %s still runs jobs, use --force to remove it anyway: %w", names[i], err)
+		if restoreErr := m.setRunnerLimits(restoreCtx, names[i], limits); restoreErr != nil {
+			errs[i] = errors.Join(errs[i], fmt.Errorf("failed to restore the runner limits: %w", restoreErr))
+		}
+	}
+	return errs
+}
+
+// BusyRunners returns the runners of a runner scale set that run a job
+func (m *Manager) BusyRunners(ctx context.Context, name string) ([]EphemeralRunner, error) {
+	runners, err := m.EphemeralRunners(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return busyRunners(runners), nil
+}
+
+// busyRunners returns the runners that run a job
+func busyRunners(runners []EphemeralRunner) []EphemeralRunner {
+	var busy []EphemeralRunner
+	for _, r := range runners {
+		if r.Busy() {
+			busy = append(busy, r)
+		}
+	}
+	return busy
+}
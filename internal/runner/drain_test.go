@@ -0,0 +1,15 @@
+package runner
+
+import "testing"
+
+func TestBusyRunners(t *testing.T) {
+	runners := []EphemeralRunner{
+		{Name: "idle"},
+		{Name: "job", JobDisplayName: "build"},
+		{Name: "assigned", WorkflowRunID: 42},
+	}
+	busy := busyRunners(runners)
+	if len(busy) != 2 || busy[0].Name != "job" || busy[1].Name != "assigned" {
+		t.Errorf("busyRunners() = %v, want the runners job and assigned", busy)
+	}
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ephemeralRunnerGVR identifies the ARC EphemeralRunner resource
+var ephemeralRunnerGVR = schema.GroupVersionResource{
+	Group:    "actions.github.com",
+	Version:  "v1alpha1",
+	Resource: "ephemeralrunners",
+}
+
+// EphemeralRunner summarizes an ARC EphemeralRunner and the job assigned to it
+type EphemeralRunner struct {
+	Name      string
+	Namespace string
+	// RunnerName is the name the runner registered with at GitHub
+	RunnerName string
+	Phase      string
+	// Message explains the phase, e.g. why the runner failed
+	Message        string
+	Ready          bool
+	Created        time.Time
+	JobDisplayName string
+	JobRepository  string
+	WorkflowRunID  int64
+}
+
+// Busy returns true if a job has been assigned to the runner
+func (r EphemeralRunner) Busy() bool {
+	return r.JobDisplayName != "" || r.WorkflowRunID != 0
+}
+
+// EphemeralRunners returns the EphemeralRunners of a runner scale set, sorted by name
+func (m *Manager) EphemeralRunners(ctx context.Context, scaleSetName string) ([]EphemeralRunner, error) {
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := dynamicClient.Resource(ephemeralRunnerGVR).Namespace("").List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", scaleSetNameLabel, scaleSetName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ephemeral runners: %w", err)
+	}
+
+	runners := make([]EphemeralRunner, 0, len(list.Items))
+	for _, item := range list.Items {
+		runners = append(runners, parseEphemeralRunner(item))
+	}
+
+	sort.Slice(runners, func(i, j int) bool {
+		return runners[i].Name < runners[j].Name
+	})
+
+	return runners, nil
+}
+
+// parseEphemeralRunner extracts the runner and job details from an EphemeralRunner object
+func parseEphemeralRunner(obj unstructured.Unstructured) EphemeralRunner {
+	runner := EphemeralRunner{
+		Name:      obj.GetName(),
+		Namespace: obj.GetNamespace(),
+		Created:   obj.GetCreationTimestamp().Time,
+	}
+
+	runner.RunnerName, _, _ = unstructured.NestedString(obj.Object, "status", "runnerName")
+	runner.Phase, _, _ = unstructured.NestedString(obj.Object, "status", "phase")
+	runner.Message, _, _ = unstructured.NestedString(obj.Object, "status", "message")
+	runner.Ready, _, _ = unstructured.NestedBool(obj.Object, "status", "ready")
+	runner.JobDisplayName, _, _ = unstructured.NestedString(obj.Object, "status", "jobDisplayName")
+	runner.JobRepository, _, _ = unstructured.NestedString(obj.Object, "status", "jobRepositoryName")
+	runner.WorkflowRunID, _, _ = unstructured.NestedInt64(obj.Object, "status", "workflowRunId")
+
+	if runner.RunnerName == "" {
+		runner.RunnerName = runner.Name
+	}
+
+	return runner
+}
@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseEphemeralRunner(t *testing.T) {
+	busy := unstructured.Unstructured{Object: map[string]any{
+		"metadata": map[string]any{"name": "my-runner-abc12-runner-x7k2p", "namespace": "arc-systems"},
+		"status": map[string]any{
+			"runnerName":        "my-runner-abc12-runner-x7k2p",
+			"phase":             "Running",
+			"ready":             true,
+			"jobDisplayName":    "test",
+			"jobRepositoryName": "owner/repo",
+			"workflowRunId":     int64(42),
+		},
+	}}
+
+	runner := parseEphemeralRunner(busy)
+	if runner.Name != "my-runner-abc12-runner-x7k2p" || runner.Namespace != "arc-systems" || runner.Phase != "Running" || !runner.Ready {
+		t.Errorf("parseEphemeralRunner() = %+v", runner)
+	}
+	if !runner.Busy() || runner.WorkflowRunID != 42 || runner.JobRepository != "owner/repo" {
+		t.Errorf("parseEphemeralRunner() job details = %+v", runner)
+	}
+
+	pending := unstructured.Unstructured{Object: map[string]any{
+		"metadata": map[string]any{"name": "my-runner-abc12-runner-q9w8e"},
+	}}
+
+	runner = parseEphemeralRunner(pending)
+	if runner.RunnerName != "my-runner-abc12-runner-q9w8e" {
+		t.Errorf("RunnerName = %q, want fallback to object name", runner.RunnerName)
+	}
+	if runner.Busy() || runner.Ready {
+		t.Errorf("parseEphemeralRunner() = %+v, want idle and not ready", runner)
+	}
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// eventWindow is how far back events are considered recent
+	eventWindow = time.Hour
+	// maxResourceEvents is the number of events kept per resource
+	maxResourceEvents = 3
+)
+
+// ResourceEvent is a recent warning reported for a resource, e.g. a
+// FailedScheduling event or an OOMKilled runner container
+type ResourceEvent struct {
+	Reason   string    `json:"reason" yaml:"reason"`
+	Message  string    `json:"message" yaml:"message"`
+	Count    int32     `json:"count" yaml:"count"`
+	LastSeen time.Time `json:"last_seen" yaml:"last_seen"`
+}
+
+// EventKey returns the key ResourceEvents uses for a resource
+func EventKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// ResourceEvents returns the recent warnings of the resources in the given
+// namespaces by EventKey, most recent first. Besides Warning events, containers
+// terminated because they ran out of memory are reported as OOMKilled. Pods
+// share their name with the EphemeralRunner or AutoscalingListener they belong
+// to, so their warnings are listed under that resource.
+func (m *Manager) ResourceEvents(ctx context.Context, namespaces []string) (map[string][]ResourceEvent, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []corev1.Event
+	var pods []corev1.Pod
+	for _, namespace := range namespaces {
+		eventList, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: "type=" + corev1.EventTypeWarning,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list events in namespace %s: %w", namespace, err)
+		}
+		events = append(events, eventList.Items...)
+
+		podList, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods in namespace %s: %w", namespace, err)
+		}
+		pods = append(pods, podList.Items...)
+	}
+
+	return resourceEvents(events, pods, time.Now()), nil
+}
+
+// resourceEvents groups the recent warning events and OOMKilled containers by
+// resource, keeping the most recent maxResourceEvents of each
+func resourceEvents(events []corev1.Event, pods []corev1.Pod, now time.Time) map[string][]ResourceEvent {
+	byResource := map[string][]ResourceEvent{}
+	for _, event := range events {
+		if event.Type != corev1.EventTypeWarning {
+			continue
+		}
+		lastSeen := eventTime(event)
+		if now.Sub(lastSeen) > eventWindow {
+			continue
+		}
+
+		count := event.Count
+		if event.Series != nil {
+			count = event.Series.Count
+		}
+		if count < 1 {
+			count = 1
+		}
+
+		key := EventKey(event.InvolvedObject.Namespace, event.InvolvedObject.Name)
+		byResource[key] = append(byResource[key], ResourceEvent{
+			Reason:   event.Reason,
+			Message:  event.Message,
+			Count:    count,
+			LastSeen: lastSeen,
+		})
+	}
+
+	for _, pod := range pods {
+		for _, container := range pod.Status.ContainerStatuses {
+			terminated := container.State.Terminated
+			if terminated == nil {
+				terminated = container.LastTerminationState.Terminated
+			}
+			if terminated == nil || terminated.Reason != "OOMKilled" {
+				continue
+			}
+			if now.Sub(terminated.FinishedAt.Time) > eventWindow {
+				continue
+			}
+
+			key := EventKey(pod.Namespace, pod.Name)
+			byResource[key] = append(byResource[key], ResourceEvent{
+				Reason:   "OOMKilled",
+				Message:  fmt.Sprintf("Container %s ran out of memory (exit code %d)", container.Name, terminated.ExitCode),
+				Count:    1,
+				LastSeen: terminated.FinishedAt.Time,
+			})
+		}
+	}
+
+	for key, resourceEvents := range byResource {
+		sort.SliceStable(resourceEvents, func(i, j int) bool {
+			return resourceEvents[i].LastSeen.After(resourceEvents[j].LastSeen)
+		})
+		if len(resourceEvents) > maxResourceEvents {
+			resourceEvents = resourceEvents[:maxResourceEvents]
+		}
+		byResource[key] = resourceEvents
+	}
+
+	return byResource
+}
+
+// eventTime returns when an event was last seen, falling back through the
+// timestamps set by the different event APIs
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResourceEvents(t *testing.T) {
+	now := time.Now()
+
+	newEvent := func(name, eventType, reason string, ago time.Duration, count int32) corev1.Event {
+		return corev1.Event{
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "arc-runners", Name: name},
+			Type:           eventType,
+			Reason:         reason,
+			Message:        reason + " message",
+			Count:          count,
+			LastTimestamp:  metav1.NewTime(now.Add(-ago)),
+		}
+	}
+
+	events := []corev1.Event{
+		newEvent("runner-abc", corev1.EventTypeWarning, "FailedScheduling", 5*time.Minute, 4),
+		newEvent("runner-abc", corev1.EventTypeNormal, "Scheduled", time.Minute, 1),
+		newEvent("runner-abc", corev1.EventTypeWarning, "Failed", time.Minute, 0),
+		newEvent("runner-def", corev1.EventTypeWarning, "BackOff", 2*time.Hour, 1),
+	}
+	pods := []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "arc-runners", Name: "runner-ghi"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name: "runner",
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Reason:     "OOMKilled",
+				ExitCode:   137,
+				FinishedAt: metav1.NewTime(now.Add(-time.Minute)),
+			}},
+		}}},
+	}}
+
+	got := resourceEvents(events, pods, now)
+
+	abc := got[EventKey("arc-runners", "runner-abc")]
+	if len(abc) != 2 {
+		t.Fatalf("resourceEvents() returned %d events for runner-abc, want 2", len(abc))
+	}
+	if abc[0].Reason != "Failed" || abc[0].Count != 1 {
+		t.Errorf("first event = %+v, want the most recent Failed event with count 1", abc[0])
+	}
+	if abc[1].Reason != "FailedScheduling" || abc[1].Count != 4 {
+		t.Errorf("second event = %+v, want FailedScheduling with count 4", abc[1])
+	}
+
+	if _, ok := got[EventKey("arc-runners", "runner-def")]; ok {
+		t.Error("resourceEvents() kept an event older than the event window")
+	}
+
+	ghi := got[EventKey("arc-runners", "runner-ghi")]
+	if len(ghi) != 1 || ghi[0].Reason != "OOMKilled" {
+		t.Fatalf("resourceEvents() = %+v for runner-ghi, want an OOMKilled event", ghi)
+	}
+	if ghi[0].Message != "Container runner ran out of memory (exit code 137)" {
+		t.Errorf("OOMKilled message = %q", ghi[0].Message)
+	}
+}
+
+func TestResourceEventsLimit(t *testing.T) {
+	now := time.Now()
+
+	var events []corev1.Event
+	for i := 0; i < maxResourceEvents+2; i++ {
+		events = append(events, corev1.Event{
+			InvolvedObject: corev1.ObjectReference{Namespace: "arc-runners", Name: "runner-abc"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Duration(i) * time.Minute)),
+		})
+	}
+
+	got := resourceEvents(events, nil, now)[EventKey("arc-runners", "runner-abc")]
+	if len(got) != maxResourceEvents {
+		t.Fatalf("resourceEvents() kept %d events, want %d", len(got), maxResourceEvents)
+	}
+	if got[0].LastSeen.Before(got[1].LastSeen) {
+		t.Errorf("resourceEvents() did not sort the most recent event first: %+v", got)
+	}
+}
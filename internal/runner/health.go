@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// arcCRDs are the custom resource definitions installed by the ARC controller
+var arcCRDs = []string{
+	"autoscalinglisteners.actions.github.com",
+	"autoscalingrunnersets.actions.github.com",
+	"ephemeralrunners.actions.github.com",
+	"ephemeralrunnersets.actions.github.com",
+}
+
+// arcResources are the ARC resources that carry finalizers
+var arcResources = []string{
+	"autoscalingrunnersets",
+	"autoscalinglisteners",
+	"ephemeralrunnersets",
+	"ephemeralrunners",
+}
+
+// StuckResource is a resource that has been waiting on finalizers for too long
+type StuckResource struct {
+	Kind       string
+	Resource   string // Plural API resource name, e.g. ephemeralrunners
+	Namespace  string
+	Name       string
+	Finalizers []string
+	DeletedFor time.Duration
+}
+
+// ServerVersion checks that the cluster API server is reachable through the
+// kubeconfig context and returns its version
+func (m *Manager) ServerVersion(ctx context.Context) (string, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return "", err
+	}
+
+	version, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to reach API server: %w", err)
+	}
+
+	return version.GitVersion, nil
+}
+
+// UnhealthyCRDs returns the ARC CRDs that are missing or not established
+func (m *Manager) UnhealthyCRDs(ctx context.Context) ([]string, error) {
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+
+	gvr := schema.GroupVersionResource{
+		Group:    "apiextensions.k8s.io",
+		Version:  "v1",
+		Resource: "customresourcedefinitions",
+	}
+
+	list, err := dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CRDs: %w", err)
+	}
+
+	established := make(map[string]bool, len(list.Items))
+	for _, crd := range list.Items {
+		established[crd.GetName()] = crdEstablished(crd)
+	}
+
+	var unhealthy []string
+	for _, name := range arcCRDs {
+		if !established[name] {
+			unhealthy = append(unhealthy, name)
+		}
+	}
+
+	return unhealthy, nil
+}
+
+// StuckResources returns ARC resources that have been marked for deletion
+// for longer than the given duration but are still held by finalizers
+func (m *Manager) StuckResources(ctx context.Context, olderThan time.Duration) ([]StuckResource, error) {
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var stuck []StuckResource
+	for _, resource := range arcResources {
+		gvr := schema.GroupVersionResource{
+			Group:    "actions.github.com",
+			Version:  "v1alpha1",
+			Resource: resource,
+		}
+
+		list, err := dynamicClient.Resource(gvr).Namespace("").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", resource, err)
+		}
+
+		for _, r := range stuckResources(list.Items, olderThan, time.Now()) {
+			r.Resource = resource
+			stuck = append(stuck, r)
+		}
+	}
+
+	sort.Slice(stuck, func(i, j int) bool {
+		return stuck[i].DeletedFor > stuck[j].DeletedFor
+	})
+
+	return stuck, nil
+}
+
+// stuckResources filters objects with a deletion timestamp older than olderThan
+// that still have finalizers
+func stuckResources(items []unstructured.Unstructured, olderThan time.Duration, now time.Time) []StuckResource {
+	var stuck []StuckResource
+	for _, item := range items {
+		deletedAt := item.GetDeletionTimestamp()
+		if deletedAt == nil || len(item.GetFinalizers()) == 0 {
+			continue
+		}
+
+		deletedFor := now.Sub(deletedAt.Time)
+		if deletedFor < olderThan {
+			continue
+		}
+
+		stuck = append(stuck, StuckResource{
+			Kind:       item.GetKind(),
+			Namespace:  item.GetNamespace(),
+			Name:       item.GetName(),
+			Finalizers: item.GetFinalizers(),
+			DeletedFor: deletedFor,
+		})
+	}
+	return stuck
+}
+
+// crdEstablished reports whether a CRD has the Established condition set to True
+func crdEstablished(crd unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+	for _, condition := range conditions {
+		c, ok := condition.(map[string]any)
+		if !ok {
+			continue
+		}
+		if c["type"] == "Established" && c["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestStuckResources(t *testing.T) {
+	now := time.Now()
+
+	newRunner := func(name string, deletedAgo time.Duration, finalizers ...string) unstructured.Unstructured {
+		obj := unstructured.Unstructured{}
+		obj.SetKind("EphemeralRunner")
+		obj.SetNamespace("arc-systems")
+		obj.SetName(name)
+		obj.SetFinalizers(finalizers)
+		if deletedAgo > 0 {
+			deletedAt := metav1.NewTime(now.Add(-deletedAgo))
+			obj.SetDeletionTimestamp(&deletedAt)
+		}
+		return obj
+	}
+
+	items := []unstructured.Unstructured{
+		newRunner("running", 0, "ephemeralrunner.actions.github.com/finalizer"),
+		newRunner("deleting", time.Minute, "ephemeralrunner.actions.github.com/finalizer"),
+		newRunner("stuck", time.Hour, "ephemeralrunner.actions.github.com/finalizer"),
+		newRunner("no-finalizers", time.Hour),
+	}
+
+	stuck := stuckResources(items, 10*time.Minute, now)
+	if len(stuck) != 1 {
+		t.Fatalf("stuckResources() returned %d resources, want 1", len(stuck))
+	}
+	if stuck[0].Name != "stuck" || stuck[0].Kind != "EphemeralRunner" {
+		t.Errorf("stuckResources() = %+v, want EphemeralRunner stuck", stuck[0])
+	}
+}
+
+func TestCRDEstablished(t *testing.T) {
+	established := unstructured.Unstructured{Object: map[string]any{
+		"status": map[string]any{
+			"conditions": []any{
+				map[string]any{"type": "NamesAccepted", "status": "True"},
+				map[string]any{"type": "Established", "status": "True"},
+			},
+		},
+	}}
+	if !crdEstablished(established) {
+		t.Error("crdEstablished() = false, want true")
+	}
+
+	pending := unstructured.Unstructured{Object: map[string]any{
+		"status": map[string]any{
+			"conditions": []any{
+				map[string]any{"type": "Established", "status": "False"},
+			},
+		},
+	}}
+	if crdEstablished(pending) {
+		t.Error("crdEstablished() = true, want false")
+	}
+}
+
+func TestMatchingLines(t *testing.T) {
+	logs := `INFO reconciling EphemeralRunner
+ERROR secrets is forbidden: User "system:serviceaccount:arc-systems:arc-controller-gha-rs-controller" cannot list resource "secrets"
+ERROR secrets is forbidden: User "system:serviceaccount:arc-systems:arc-controller-gha-rs-controller" cannot list resource "secrets"
+
+INFO done`
+
+	lines := matchingLines(logs, "forbidden")
+	if len(lines) != 1 {
+		t.Fatalf("matchingLines() returned %d lines, want 1 distinct line: %v", len(lines), lines)
+	}
+}
@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rkoster/deskrun/pkg/templates"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ManifestImages returns the sorted container images the pod specs of
+// rendered manifests run, including the pod templates of workloads and ARC
+// scale sets
+func ManifestImages(manifests ...[]byte) ([]string, error) {
+	var images []string
+	for _, manifest := range manifests {
+		objects, err := templates.ParseObjects(manifest)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			images = appendContainerImages(images, object.Object)
+		}
+	}
+	slices.Sort(images)
+	return slices.Compact(images), nil
+}
+
+// appendContainerImages appends the images of all containers and init
+// containers below a manifest value
+func appendContainerImages(images []string, value any) []string {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if key == "containers" || key == "initContainers" {
+				images = appendImages(images, child)
+			}
+			images = appendContainerImages(images, child)
+		}
+	case []any:
+		for _, child := range value {
+			images = appendContainerImages(images, child)
+		}
+	}
+	return images
+}
+
+// appendImages appends the images of a list of containers
+func appendImages(images []string, containers any) []string {
+	list, _ := containers.([]any)
+	for _, container := range list {
+		container, _ := container.(map[string]any)
+		if image, _ := container["image"].(string); image != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// MissingImages returns the images that are not present on every node of the
+// cluster yet, as reported in the node status
+func (m *Manager) MissingImages(ctx context.Context, images []string) ([]string, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	return missingImages(nodes.Items, images), nil
+}
+
+// missingImages returns the images some of the nodes don't have
+func missingImages(nodes []corev1.Node, images []string) []string {
+	var missing []string
+	for _, image := range images {
+		for _, node := range nodes {
+			if !nodeHasImage(node, normalizeImage(image)) {
+				missing = append(missing, image)
+				break
+			}
+		}
+	}
+	return missing
+}
+
+// nodeHasImage returns true if the node status lists the normalized image
+func nodeHasImage(node corev1.Node, image string) bool {
+	for _, nodeImage := range node.Status.Images {
+		for _, name := range nodeImage.Names {
+			if normalizeImage(name) == image {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeImage returns the fully qualified name of an image reference the
+// way containerd reports it, e.g. docker.io/library/docker:dind for docker:dind
+func normalizeImage(image string) string {
+	name, digest, hasDigest := strings.Cut(image, "@")
+	repository, tag := name, ""
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		repository, tag = name[:i], name[i+1:]
+	}
+
+	registry, _, hasRegistry := strings.Cut(repository, "/")
+	if !hasRegistry || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+		if !hasRegistry {
+			repository = "library/" + repository
+		}
+		repository = "docker.io/" + repository
+	}
+
+	switch {
+	case hasDigest:
+		return repository + "@" + digest
+	case tag == "":
+		return repository + ":latest"
+	default:
+		return repository + ":" + tag
+	}
+}
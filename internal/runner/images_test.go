@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestManifestImages(t *testing.T) {
+	controller := []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: controller
+spec:
+  template:
+    spec:
+      containers:
+      - name: manager
+        image: ghcr.io/actions/gha-runner-scale-set-controller:0.13.0
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: controller
+`)
+	scaleSet := []byte(`apiVersion: actions.github.com/v1alpha1
+kind: AutoscalingRunnerSet
+metadata:
+  name: runner
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init-dind-externals
+        image: ghcr.io/actions/actions-runner:latest
+      - name: dind
+        image: docker:dind
+      containers:
+      - name: runner
+        image: ghcr.io/actions/actions-runner:latest
+`)
+
+	images, err := ManifestImages(controller, scaleSet)
+	if err != nil {
+		t.Fatalf("ManifestImages() error = %v", err)
+	}
+	want := []string{
+		"docker:dind",
+		"ghcr.io/actions/actions-runner:latest",
+		"ghcr.io/actions/gha-runner-scale-set-controller:0.13.0",
+	}
+	if !slices.Equal(images, want) {
+		t.Errorf("ManifestImages() = %v, want %v", images, want)
+	}
+
+	if _, err := ManifestImages([]byte("kind: [")); err == nil {
+		t.Error("ManifestImages() accepted invalid YAML")
+	}
+}
+
+func TestNormalizeImage(t *testing.T) {
+	for image, want := range map[string]string{
+		"docker:dind":                           "docker.io/library/docker:dind",
+		"busybox":                               "docker.io/library/busybox:latest",
+		"owner/image:1.0":                       "docker.io/owner/image:1.0",
+		"ghcr.io/actions/actions-runner:latest": "ghcr.io/actions/actions-runner:latest",
+		"registry.local:5000/runner":            "registry.local:5000/runner:latest",
+		"localhost/runner:dev":                  "localhost/runner:dev",
+		"docker@sha256:abc":                     "docker.io/library/docker@sha256:abc",
+	} {
+		if got := normalizeImage(image); got != want {
+			t.Errorf("normalizeImage(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestMissingImages(t *testing.T) {
+	node := corev1.Node{Status: corev1.NodeStatus{Images: []corev1.ContainerImage{
+		{Names: []string{"docker.io/library/docker@sha256:abc", "docker.io/library/docker:dind"}},
+		{Names: []string{"ghcr.io/actions/actions-runner:2.328.0"}},
+	}}}
+
+	missing := missingImages([]corev1.Node{node}, []string{"docker:dind", "ghcr.io/actions/actions-runner:latest"})
+	if want := []string{"ghcr.io/actions/actions-runner:latest"}; !slices.Equal(missing, want) {
+		t.Errorf("missingImages() = %v, want %v", missing, want)
+	}
+}
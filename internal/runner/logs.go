@@ -0,0 +1,236 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// controllerPodSelector matches the ARC controller manager pods
+	controllerPodSelector = "app.kubernetes.io/name=gha-rs-controller"
+	// scaleSetNameLabel is set by ARC on listener and EphemeralRunner pods
+	scaleSetNameLabel = "actions.github.com/scale-set-name"
+	// logPodPollInterval is how often new pods are discovered while following
+	logPodPollInterval = 5 * time.Second
+)
+
+// LogOptions controls which logs are streamed and how
+type LogOptions struct {
+	Follow            bool          // Keep streaming and pick up newly created pods
+	Since             time.Duration // Only return logs newer than this duration (0 = all)
+	Container         string        // Only stream this container (empty = all containers)
+	IncludeController bool          // Also stream logs from the ARC controller pods
+}
+
+// StreamLogs streams logs from the listener and EphemeralRunner pods of the given
+// runner scale sets (and optionally the ARC controller) to out. Every line is
+// prefixed with the pod and container it came from.
+func (m *Manager) StreamLogs(ctx context.Context, names []string, opts LogOptions, out io.Writer) error {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	selectors := logPodSelectors(names, opts.IncludeController)
+	writer := &prefixedLogWriter{out: out}
+	seen := make(map[string]bool)
+	open := func(namespace, podName, container string) (io.ReadCloser, error) {
+		return openContainerLogs(ctx, clientset, namespace, podName, container, opts)
+	}
+	var wg sync.WaitGroup
+
+	for {
+		pods, err := listLogPods(ctx, clientset, selectors)
+		if err != nil {
+			wg.Wait()
+			return err
+		}
+
+		if !opts.Follow {
+			if len(pods) == 0 {
+				return fmt.Errorf("no pods found for %v", names)
+			}
+			for _, pod := range pods {
+				for _, container := range podLogContainers(pod, opts.Container) {
+					if err := streamContainerLogs(ctx, clientset, pod.Namespace, pod.Name, container, opts, writer); err != nil {
+						writer.writeLine(logPrefix(pod.Name, container), fmt.Sprintf("error: %v", err))
+					}
+				}
+			}
+			return nil
+		}
+
+		for _, ls := range openNewLogStreams(pods, opts.Container, seen, open) {
+			wg.Add(1)
+			go func(ls logStream) {
+				defer wg.Done()
+				if err := copyContainerLogs(ls.stream, ls.podName, ls.container, writer); err != nil && ctx.Err() == nil {
+					writer.writeLine(logPrefix(ls.podName, ls.container), fmt.Sprintf("error: %v", err))
+				}
+			}(ls)
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case <-time.After(logPodPollInterval):
+		}
+	}
+}
+
+// logPodSelectors returns the label selectors for the pods whose logs should be streamed
+func logPodSelectors(names []string, includeController bool) []string {
+	var selectors []string
+	if includeController {
+		selectors = append(selectors, controllerPodSelector)
+	}
+	for _, name := range names {
+		selectors = append(selectors, fmt.Sprintf("%s=%s", scaleSetNameLabel, name))
+	}
+	return selectors
+}
+
+// listLogPods lists the pods matching any of the given selectors in all
+// namespaces, without duplicates
+func listLogPods(ctx context.Context, clientset kubernetes.Interface, selectors []string) ([]corev1.Pod, error) {
+	var pods []corev1.Pod
+	seen := make(map[string]bool)
+	for _, selector := range selectors {
+		podList, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			LabelSelector: selector,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods: %w", err)
+		}
+		for _, pod := range podList.Items {
+			key := pod.Namespace + "/" + pod.Name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// podLogContainers returns the containers of a pod to stream logs from. Init
+// containers are included because the dind sidecar runs as one. When a container
+// filter is given, only a matching container is returned.
+func podLogContainers(pod corev1.Pod, container string) []string {
+	var names []string
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+
+	if container == "" {
+		return names
+	}
+	for _, name := range names {
+		if name == container {
+			return []string{name}
+		}
+	}
+	return nil
+}
+
+// logStream is an open log stream of a single container
+type logStream struct {
+	podName   string
+	container string
+	stream    io.ReadCloser
+}
+
+// openNewLogStreams opens the log streams of the containers that aren't streamed
+// yet. A container is only marked as seen once its stream is open, so containers
+// that haven't started yet are picked up again on the next poll.
+func openNewLogStreams(pods []corev1.Pod, containerFilter string, seen map[string]bool, open func(namespace, podName, container string) (io.ReadCloser, error)) []logStream {
+	var streams []logStream
+	for _, pod := range pods {
+		for _, container := range podLogContainers(pod, containerFilter) {
+			key := pod.Namespace + "/" + pod.Name + "/" + container
+			if seen[key] {
+				continue
+			}
+			stream, err := open(pod.Namespace, pod.Name, container)
+			if err != nil {
+				slog.Debug("Log stream not available yet", "pod", pod.Name, "container", container, "error", err)
+				continue
+			}
+			seen[key] = true
+			streams = append(streams, logStream{podName: pod.Name, container: container, stream: stream})
+		}
+	}
+	return streams
+}
+
+// streamContainerLogs copies the logs of a single container to the writer
+func streamContainerLogs(ctx context.Context, clientset kubernetes.Interface, namespace, podName, container string, opts LogOptions, writer *prefixedLogWriter) error {
+	stream, err := openContainerLogs(ctx, clientset, namespace, podName, container, opts)
+	if err != nil {
+		return err
+	}
+	return copyContainerLogs(stream, podName, container, writer)
+}
+
+// openContainerLogs opens the log stream of a single container
+func openContainerLogs(ctx context.Context, clientset kubernetes.Interface, namespace, podName, container string, opts LogOptions) (io.ReadCloser, error) {
+	podLogOpts := &corev1.PodLogOptions{
+		Container: container,
+		Follow:    opts.Follow,
+	}
+	if opts.Since > 0 {
+		sinceSeconds := int64(opts.Since.Seconds())
+		podLogOpts.SinceSeconds = &sinceSeconds
+	}
+
+	stream, err := clientset.CoreV1().Pods(namespace).GetLogs(podName, podLogOpts).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log stream: %w", err)
+	}
+	return stream, nil
+}
+
+// copyContainerLogs copies the lines of an open log stream to the writer and
+// closes the stream
+func copyContainerLogs(stream io.ReadCloser, podName, container string, writer *prefixedLogWriter) error {
+	defer func() { _ = stream.Close() }()
+
+	prefix := logPrefix(podName, container)
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		writer.writeLine(prefix, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// logPrefix formats the prefix identifying the source of a log line
+func logPrefix(podName, container string) string {
+	return fmt.Sprintf("[%s/%s]", podName, container)
+}
+
+// prefixedLogWriter serializes log lines from concurrent streams
+type prefixedLogWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (w *prefixedLogWriter) writeLine(prefix, line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = fmt.Fprintf(w.out, "%s %s\n", prefix, line)
+}
@@ -0,0 +1,104 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLogPodSelectors(t *testing.T) {
+	got := logPodSelectors([]string{"runner-1", "runner-2"}, true)
+	want := []string{
+		"app.kubernetes.io/name=gha-rs-controller",
+		"actions.github.com/scale-set-name=runner-1",
+		"actions.github.com/scale-set-name=runner-2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logPodSelectors() = %v, want %v", got, want)
+	}
+
+	if got := logPodSelectors([]string{"runner"}, false); len(got) != 1 {
+		t.Errorf("logPodSelectors() without controller = %v, want a single selector", got)
+	}
+}
+
+func TestPodLogContainers(t *testing.T) {
+	pod := corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "dind"}},
+			Containers:     []corev1.Container{{Name: "runner"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		container string
+		want      []string
+	}{
+		{name: "all containers including init", container: "", want: []string{"dind", "runner"}},
+		{name: "filter main container", container: "runner", want: []string{"runner"}},
+		{name: "filter init container", container: "dind", want: []string{"dind"}},
+		{name: "unknown container", container: "missing", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := podLogContainers(pod, tt.container)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("podLogContainers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixedLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer := &prefixedLogWriter{out: &buf}
+	writer.writeLine(logPrefix("runner-abc", "runner"), "Listening for Jobs")
+
+	want := "[runner-abc/runner] Listening for Jobs\n"
+	if buf.String() != want {
+		t.Errorf("writeLine() wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestOpenNewLogStreams(t *testing.T) {
+	pods := []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "arc-runners", Name: "runner-abc"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "dind"}},
+			Containers:     []corev1.Container{{Name: "runner"}},
+		},
+	}}
+	seen := make(map[string]bool)
+
+	// The runner container hasn't started yet, so only the dind stream opens
+	notStarted := func(namespace, podName, container string) (io.ReadCloser, error) {
+		if container == "runner" {
+			return nil, errors.New("container is waiting to start")
+		}
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	streams := openNewLogStreams(pods, "", seen, notStarted)
+	if len(streams) != 1 || streams[0].container != "dind" {
+		t.Fatalf("openNewLogStreams() = %v, want only the dind stream", streams)
+	}
+	if seen["arc-runners/runner-abc/runner"] {
+		t.Error("container whose stream failed to open should not be marked as seen")
+	}
+
+	// The next poll opens the runner stream and doesn't reopen the dind stream
+	started := func(namespace, podName, container string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	streams = openNewLogStreams(pods, "", seen, started)
+	if len(streams) != 1 || streams[0].container != "runner" {
+		t.Fatalf("openNewLogStreams() on the next poll = %v, want only the runner stream", streams)
+	}
+}
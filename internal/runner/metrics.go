@@ -0,0 +1,233 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// metricsPortName is the container port the ARC controller and listeners
+// serve Prometheus metrics on
+const metricsPortName = "metrics"
+
+// ErrMetricsUnavailable is returned when no pod of the cluster serves ARC
+// metrics, e.g. because the controller was deployed by an older deskrun
+var ErrMetricsUnavailable = errors.New("no ARC metrics endpoints found, redeploy the controller with 'deskrun up' to enable them")
+
+// ScaleSetMetrics are the key metrics the listener of a runner scale set reports
+type ScaleSetMetrics struct {
+	AssignedJobs      int `json:"assigned_jobs" yaml:"assigned_jobs"`
+	RunningJobs       int `json:"running_jobs" yaml:"running_jobs"`
+	RegisteredRunners int `json:"registered_runners" yaml:"registered_runners"`
+	BusyRunners       int `json:"busy_runners" yaml:"busy_runners"`
+	IdleRunners       int `json:"idle_runners" yaml:"idle_runners"`
+	DesiredRunners    int `json:"desired_runners" yaml:"desired_runners"`
+}
+
+// ControllerMetrics are the key metrics the ARC controller reports
+type ControllerMetrics struct {
+	ReconcileErrors int `json:"reconcile_errors" yaml:"reconcile_errors"`
+	PendingRunners  int `json:"pending_runners" yaml:"pending_runners"`
+	RunningRunners  int `json:"running_runners" yaml:"running_runners"`
+	FailedRunners   int `json:"failed_runners" yaml:"failed_runners"`
+}
+
+// Metrics are the key ARC metrics of a cluster
+type Metrics struct {
+	Controller ControllerMetrics
+	// ScaleSets holds the listener metrics by runner scale set name
+	ScaleSets map[string]*ScaleSetMetrics
+}
+
+// metricSample is a single sample of the Prometheus text format
+type metricSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// CollectMetrics scrapes the metrics endpoints of the ARC controller and
+// listener pods through the API server's pod proxy, so no port-forward or
+// monitoring stack is needed
+func (m *Manager) CollectMetrics(ctx context.Context) (*Metrics, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := clientset.CoreV1().Pods(arcControllerNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	metrics := &Metrics{ScaleSets: map[string]*ScaleSetMetrics{}}
+	scraped := false
+	for _, pod := range pods.Items {
+		port := podMetricsPort(pod)
+		if port == 0 || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		body, err := clientset.CoreV1().Pods(pod.Namespace).
+			ProxyGet("http", pod.Name, strconv.Itoa(int(port)), "/metrics", nil).
+			DoRaw(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scrape metrics of pod %s: %w", pod.Name, err)
+		}
+
+		samples, err := parseMetrics(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse metrics of pod %s: %w", pod.Name, err)
+		}
+		metrics.add(samples)
+		scraped = true
+	}
+
+	if !scraped {
+		return nil, ErrMetricsUnavailable
+	}
+	return metrics, nil
+}
+
+// podMetricsPort returns the metrics container port of a pod, or 0 if it has none
+func podMetricsPort(pod corev1.Pod) int32 {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == metricsPortName {
+				return port.ContainerPort
+			}
+		}
+	}
+	return 0
+}
+
+// add adds the key series of the scraped samples to the metrics
+func (m *Metrics) add(samples []metricSample) {
+	for _, sample := range samples {
+		value := int(sample.value)
+		switch sample.name {
+		case "controller_runtime_reconcile_errors_total":
+			m.Controller.ReconcileErrors += value
+		case "gha_controller_pending_ephemeral_runners":
+			m.Controller.PendingRunners += value
+		case "gha_controller_running_ephemeral_runners":
+			m.Controller.RunningRunners += value
+		case "gha_controller_failed_ephemeral_runners":
+			m.Controller.FailedRunners += value
+		}
+
+		if !strings.HasPrefix(sample.name, "gha_") || strings.HasPrefix(sample.name, "gha_controller_") {
+			continue
+		}
+		name := sample.labels["name"]
+		if name == "" {
+			continue
+		}
+		scaleSet := m.ScaleSets[name]
+		if scaleSet == nil {
+			scaleSet = &ScaleSetMetrics{}
+			m.ScaleSets[name] = scaleSet
+		}
+
+		switch sample.name {
+		case "gha_assigned_jobs":
+			scaleSet.AssignedJobs = value
+		case "gha_running_jobs":
+			scaleSet.RunningJobs = value
+		case "gha_registered_runners":
+			scaleSet.RegisteredRunners = value
+		case "gha_busy_runners":
+			scaleSet.BusyRunners = value
+		case "gha_idle_runners":
+			scaleSet.IdleRunners = value
+		case "gha_desired_runners":
+			scaleSet.DesiredRunners = value
+		}
+	}
+}
+
+// parseMetrics parses the samples of the Prometheus text exposition format,
+// skipping comments and samples with timestamps or values it can't read
+func parseMetrics(data []byte) ([]metricSample, error) {
+	var samples []metricSample
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sample := metricSample{labels: map[string]string{}}
+		rest := line
+		if i := strings.IndexAny(line, "{ "); i >= 0 && line[i] == '{' {
+			end := strings.LastIndex(line, "}")
+			if end < i {
+				return nil, fmt.Errorf("invalid sample %q", line)
+			}
+			sample.name = line[:i]
+			sample.labels = parseLabels(line[i+1 : end])
+			rest = line[end+1:]
+		} else {
+			sample.name, rest, _ = strings.Cut(line, " ")
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid sample %q", line)
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		sample.value = value
+		samples = append(samples, sample)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// parseLabels parses the label pairs of a sample, e.g. name="a",namespace="b"
+func parseLabels(s string) map[string]string {
+	labels := map[string]string{}
+	for s != "" {
+		key, rest, found := strings.Cut(s, "=")
+		if !found || !strings.HasPrefix(rest, `"`) {
+			break
+		}
+
+		// Find the closing quote, skipping escaped characters
+		var value strings.Builder
+		i := 1
+		for ; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] == '\\' && i+1 < len(rest) {
+				i++
+				switch rest[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(rest[i])
+				}
+				continue
+			}
+			value.WriteByte(rest[i])
+		}
+		labels[strings.TrimSpace(key)] = value.String()
+
+		if i+1 >= len(rest) {
+			break
+		}
+		s = strings.TrimPrefix(rest[i+1:], ",")
+	}
+	return labels
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const listenerMetrics = `# HELP gha_assigned_jobs Number of jobs assigned to this scale set.
+# TYPE gha_assigned_jobs gauge
+gha_assigned_jobs{enterprise="",name="my-runner",namespace="arc-systems",organization="owner",repository="repo"} 2
+gha_running_jobs{enterprise="",name="my-runner",namespace="arc-systems",organization="owner",repository="repo"} 1
+gha_registered_runners{name="my-runner",namespace="arc-systems"} 3
+gha_busy_runners{name="my-runner",namespace="arc-systems"} 1
+gha_idle_runners{name="my-runner",namespace="arc-systems"} 2
+gha_desired_runners{name="my-runner",namespace="arc-systems"} 3
+gha_job_startup_duration_seconds_bucket{name="my-runner",le="+Inf"} 4
+`
+
+const controllerMetrics = `controller_runtime_reconcile_errors_total{controller="autoscalingrunnerset"} 1
+controller_runtime_reconcile_errors_total{controller="ephemeralrunner"} 2
+gha_controller_pending_ephemeral_runners{name="my-runner",namespace="arc-systems"} 1
+gha_controller_running_ephemeral_runners{name="my-runner",namespace="arc-systems"} 1
+gha_controller_failed_ephemeral_runners{name="my-runner",namespace="arc-systems"} 0
+process_open_fds 12
+`
+
+func TestParseMetrics(t *testing.T) {
+	samples, err := parseMetrics([]byte(listenerMetrics + "weird_value NaN_ish\n"))
+	if err != nil {
+		t.Fatalf("parseMetrics() error = %v", err)
+	}
+	if len(samples) != 7 {
+		t.Fatalf("parseMetrics() returned %d samples, want 7", len(samples))
+	}
+
+	want := metricSample{
+		name:   "gha_assigned_jobs",
+		labels: map[string]string{"enterprise": "", "name": "my-runner", "namespace": "arc-systems", "organization": "owner", "repository": "repo"},
+		value:  2,
+	}
+	if !reflect.DeepEqual(samples[0], want) {
+		t.Errorf("parseMetrics()[0] = %+v, want %+v", samples[0], want)
+	}
+
+	if _, err := parseMetrics([]byte("broken{name=\"x\" 1\n")); err == nil {
+		t.Error("parseMetrics() of unterminated labels should fail")
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	got := parseLabels(`a="1",b="with \"quotes\", and comma",c=""`)
+	want := map[string]string{"a": "1", "b": `with "quotes", and comma`, "c": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLabels() = %v, want %v", got, want)
+	}
+}
+
+func TestMetricsAdd(t *testing.T) {
+	metrics := &Metrics{ScaleSets: map[string]*ScaleSetMetrics{}}
+	for _, data := range []string{listenerMetrics, controllerMetrics} {
+		samples, err := parseMetrics([]byte(data))
+		if err != nil {
+			t.Fatalf("parseMetrics() error = %v", err)
+		}
+		metrics.add(samples)
+	}
+
+	wantController := ControllerMetrics{ReconcileErrors: 3, PendingRunners: 1, RunningRunners: 1}
+	if metrics.Controller != wantController {
+		t.Errorf("Controller = %+v, want %+v", metrics.Controller, wantController)
+	}
+
+	wantScaleSets := map[string]*ScaleSetMetrics{
+		"my-runner": {AssignedJobs: 2, RunningJobs: 1, RegisteredRunners: 3, BusyRunners: 1, IdleRunners: 2, DesiredRunners: 3},
+	}
+	if !reflect.DeepEqual(metrics.ScaleSets, wantScaleSets) {
+		t.Errorf("ScaleSets = %+v, want %+v", metrics.ScaleSets["my-runner"], wantScaleSets["my-runner"])
+	}
+}
+
+func TestPodMetricsPort(t *testing.T) {
+	pod := corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "listener", Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 8080}}},
+	}}}
+	if got := podMetricsPort(pod); got != 8080 {
+		t.Errorf("podMetricsPort() = %d, want 8080", got)
+	}
+	if got := podMetricsPort(corev1.Pod{}); got != 0 {
+		t.Errorf("podMetricsPort() of pod without ports = %d, want 0", got)
+	}
+}
@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/rkoster/deskrun/internal/kapp"
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// runnerNamespaceLabel marks the namespaces other than arc-systems that
+	// runner scale sets are deployed to
+	runnerNamespaceLabel = "deskrun.io/runner-namespace"
+	// managedByLabel marks the namespaces deskrun created, which are deleted
+	// once no runners are deployed to them anymore
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "deskrun"
+	// podSecurityEnforceLabel sets the Pod Security Standards profile the
+	// namespace enforces
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+)
+
+// ensureRunnerNamespace creates the namespace of an installation. Namespaces
+// that already exist are labeled as runner namespace but not as managed by
+// deskrun, so they are never deleted. A pod security profile is enforced by
+// the namespace; it is not removed when the installation drops it, as other
+// installations may share the namespace.
+func (m *Manager) ensureRunnerNamespace(ctx context.Context, namespace string, podSecurity deskruntypes.PodSecurityLevel) error {
+	if namespace == defaultNamespace {
+		return nil
+	}
+
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   namespace,
+			Labels: runnerNamespaceLabels(podSecurity),
+		},
+	}
+	ns.Labels[managedByLabel] = managedByValue
+	_, err = clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	if err == nil {
+		return nil
+	}
+	if !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create namespace %s: %w", namespace, err)
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"labels": runnerNamespaceLabels(podSecurity)},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := clientset.CoreV1().Namespaces().Patch(ctx, namespace, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to label namespace %s: %w", namespace, err)
+	}
+	return nil
+}
+
+// runnerNamespaceLabels returns the labels deskrun sets on the namespaces it
+// deploys runners to
+func runnerNamespaceLabels(podSecurity deskruntypes.PodSecurityLevel) map[string]string {
+	labels := map[string]string{runnerNamespaceLabel: "true"}
+	if podSecurity != "" {
+		labels[podSecurityEnforceLabel] = string(podSecurity)
+	}
+	return labels
+}
+
+// runnerNamespaces returns arc-systems and the labeled runner namespaces
+func (m *Manager) runnerNamespaces(ctx context.Context) ([]corev1.Namespace, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: runnerNamespaceLabel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runner namespaces: %w", err)
+	}
+
+	namespaces := []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: defaultNamespace}}}
+	for _, namespace := range list.Items {
+		if namespace.Name != defaultNamespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+// AppNamespaces returns the namespace of every deployed runner scale set by
+// name. The controller, cache server and observability apps are left out.
+func (m *Manager) AppNamespaces(ctx context.Context) (map[string]string, error) {
+	namespaces, err := m.runnerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []kapp.KappListApp
+	err = retry(ctx, "list apps", func() error {
+		var err error
+		apps, err = m.getKappClient().ListAllNamespaces()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kapp apps: %w", err)
+	}
+
+	// Apps in other namespaces are not deployed by deskrun
+	runnerNamespace := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		runnerNamespace[namespace.Name] = true
+	}
+
+	appNamespaces := make(map[string]string)
+	for _, app := range apps {
+		if runnerNamespace[app.Namespace] && isRunnerApp(app.Name) {
+			appNamespaces[app.Name] = app.Namespace
+		}
+	}
+	return appNamespaces, nil
+}
+
+// isRunnerApp returns false for the kapp apps of the controller, cache server
+// and observability stack
+func isRunnerApp(name string) bool {
+	return name != arcControllerAppName && name != cacheServerAppName && name != observabilityAppName
+}
+
+// DeleteUnusedNamespaces deletes the namespaces deskrun created for
+// installations that no runner scale set is deployed to anymore, and returns
+// their names
+func (m *Manager) DeleteUnusedNamespaces(ctx context.Context) ([]string, error) {
+	namespaces, err := m.runnerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	appNamespaces, err := m.AppNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for _, namespace := range unusedNamespaces(namespaces, appNamespaces) {
+		err := clientset.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return deleted, fmt.Errorf("failed to delete namespace %s: %w", namespace, err)
+		}
+		deleted = append(deleted, namespace)
+	}
+	return deleted, nil
+}
+
+// unusedNamespaces returns the namespaces managed by deskrun that none of the
+// apps is deployed to
+func unusedNamespaces(namespaces []corev1.Namespace, appNamespaces map[string]string) []string {
+	var used []string
+	for _, appNamespace := range appNamespaces {
+		used = append(used, appNamespace)
+	}
+
+	var unused []string
+	for _, namespace := range namespaces {
+		if namespace.Name == defaultNamespace || namespace.Labels[managedByLabel] != managedByValue {
+			continue
+		}
+		if !slices.Contains(used, namespace.Name) {
+			unused = append(unused, namespace.Name)
+		}
+	}
+	return unused
+}
+
+// scaleSetNamespace returns the namespace the runner scale set with the given
+// name is deployed to, arc-systems when it is not found
+func (m *Manager) scaleSetNamespace(ctx context.Context, name string) (string, error) {
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return "", err
+	}
+
+	list, err := dynamicClient.Resource(autoscalingRunnerSetGVR).Namespace("").List(ctx, metav1.ListOptions{
+		FieldSelector: "metadata.name=" + name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find runner scale set %s: %w", name, err)
+	}
+	if len(list.Items) == 0 {
+		return defaultNamespace, nil
+	}
+	return list.Items[0].GetNamespace(), nil
+}
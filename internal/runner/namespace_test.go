@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUnusedNamespaces(t *testing.T) {
+	namespace := func(name string, labels map[string]string) corev1.Namespace {
+		return corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	managed := map[string]string{runnerNamespaceLabel: "true", managedByLabel: managedByValue}
+
+	namespaces := []corev1.Namespace{
+		namespace("arc-systems", nil),
+		namespace("team-a", managed),
+		namespace("team-b", managed),
+		namespace("existing", map[string]string{runnerNamespaceLabel: "true"}),
+	}
+	appNamespaces := map[string]string{
+		"runner-a": "team-a",
+		"runner":   "arc-systems",
+	}
+
+	want := []string{"team-b"}
+	if got := unusedNamespaces(namespaces, appNamespaces); !reflect.DeepEqual(got, want) {
+		t.Errorf("unusedNamespaces() = %v, want %v", got, want)
+	}
+}
+
+func TestRunnerNamespaceLabels(t *testing.T) {
+	want := map[string]string{runnerNamespaceLabel: "true"}
+	if got := runnerNamespaceLabels(""); !reflect.DeepEqual(got, want) {
+		t.Errorf("runnerNamespaceLabels(\"\") = %v, want %v", got, want)
+	}
+
+	want = map[string]string{runnerNamespaceLabel: "true", podSecurityEnforceLabel: "restricted"}
+	if got := runnerNamespaceLabels(deskruntypes.PodSecurityRestricted); !reflect.DeepEqual(got, want) {
+		t.Errorf("runnerNamespaceLabels(restricted) = %v, want %v", got, want)
+	}
+}
+
+func TestRegistryNamespaces(t *testing.T) {
+	want := []string{"arc-systems", "team-a"}
+	if got := registryNamespaces([]string{"arc-systems", "team-a", "team-a"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("registryNamespaces() = %v, want %v", got, want)
+	}
+}
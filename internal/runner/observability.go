@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rkoster/deskrun/internal/kapp"
+	"github.com/rkoster/deskrun/pkg/templates"
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+)
+
+// observabilityAppName is the kapp app the metrics stack is deployed as
+const observabilityAppName = templates.ObservabilityName
+
+// RenderObservability processes the metrics stack template without deploying it
+func RenderObservability(observability *deskruntypes.ObservabilityConfig) ([]byte, error) {
+	processor := newProcessor()
+	config := templates.Config{
+		Installation: &deskruntypes.RunnerInstallation{
+			Name:          observabilityAppName,
+			Repository:    "https://github.com/placeholder",
+			ContainerMode: deskruntypes.ContainerModeKubernetes,
+		},
+		InstanceName:  observabilityAppName,
+		InstanceNum:   1,
+		Namespace:     arcControllerNamespace,
+		Observability: observability,
+	}
+
+	observabilityYAML, err := processor.ProcessTemplate(templates.TemplateTypeObservability, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render observability stack: %w", err)
+	}
+
+	return observabilityYAML, nil
+}
+
+// EnableObservability deploys the metrics stack to the cluster, updating an
+// existing deployment with the given settings
+func (m *Manager) EnableObservability(ctx context.Context, observability *deskruntypes.ObservabilityConfig) error {
+	exists, err := m.clusterManager.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check cluster: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("cluster does not exist")
+	}
+
+	if err := m.createNamespace(ctx, arcControllerNamespace); err != nil {
+		return fmt.Errorf("failed to create namespace: %w", err)
+	}
+
+	observabilityYAML, err := RenderObservability(observability)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("/tmp", "deskrun-observability-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	manifestPath := filepath.Join(tmpDir, "observability.yaml")
+	if err := os.WriteFile(manifestPath, observabilityYAML, 0644); err != nil {
+		return fmt.Errorf("failed to write observability manifest: %w", err)
+	}
+
+	if err := kappDeploy(ctx, m.getKappClient(), observabilityAppName, manifestPath, kapp.DeployOptions{}); err != nil {
+		return fmt.Errorf("failed to deploy observability stack: %w", err)
+	}
+	return nil
+}
+
+// DisableObservability removes the metrics stack from the cluster
+func (m *Manager) DisableObservability(ctx context.Context) error {
+	if err := kappDelete(ctx, m.getKappClient(), observabilityAppName); err != nil {
+		return fmt.Errorf("failed to delete observability stack: %w", err)
+	}
+	return nil
+}
+
+// RedeployController redeploys the ARC controller if it is installed, e.g.
+// to enable the metrics of controllers deployed before deskrun enabled them.
+// It reports whether the controller was redeployed.
+func (m *Manager) RedeployController(ctx context.Context) (bool, error) {
+	deployed, err := m.DeployedControllerVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+	if deployed == "" {
+		return false, nil
+	}
+
+	if err := m.deployController(ctx, kapp.DeployOptions{}); err != nil {
+		return false, fmt.Errorf("failed to redeploy ARC controller: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,311 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// kappAppLabel marks the resources of a kapp app. kapp removes it from
+	// the resources it orphans on delete, like cache claims.
+	kappAppLabel = "kapp.k14s.io/app"
+	// hookExtensionPrefix starts the name of the hook extension configmap of
+	// a scale set in privileged mode
+	hookExtensionPrefix = "privileged-hook-extension-"
+	// githubSecretSuffix ends the name of the GitHub secret of a scale set
+	githubSecretSuffix = "-gha-rs-github-secret"
+	// pruneTimeout is how long listing or removing cache directories may take
+	pruneTimeout = 10 * time.Minute
+	// pruneDirPrefix marks the lines the listing pod reports a directory on
+	pruneDirPrefix = "deskrun-dir"
+)
+
+// CacheDirRoots are the directories on the cluster node that only hold cache
+// directories deskrun creates: the generated host paths of mounts and the
+// shared cache volumes
+var CacheDirRoots = []string{"/tmp/github-runner-cache", "/tmp/deskrun-cache", deskruntypes.CacheVolumeRoot}
+
+// cacheDirListScript reports the directories in every root given as argument
+const cacheDirListScript = `for root in "$@"; do
+  [ -d "/node$root" ] || continue
+  for dir in "/node$root"/*/; do
+    [ -d "$dir" ] && echo "deskrun-dir ${dir#/node}"
+  done
+done
+`
+
+// cacheDirRemoveScript removes the directories given as argument
+const cacheDirRemoveScript = `set -eu
+for dir in "$@"; do
+  rm -rf "/node$dir"
+done
+`
+
+// PruneApp is the kapp app of a runner scale set
+type PruneApp struct {
+	Name      string
+	Namespace string
+}
+
+// PruneObject is a namespaced object left behind by a runner scale set
+type PruneObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// PrunePlan lists the resources of a cluster that no configured installation uses
+type PrunePlan struct {
+	// Apps are the runner scale sets that are deployed but not configured
+	Apps []PruneApp
+	// Namespaces are the namespaces deskrun created that no scale set is
+	// deployed to once Apps are removed
+	Namespaces []string
+	// Objects are configmaps, secrets and cache claims of scale sets that no
+	// kapp app owns anymore
+	Objects []PruneObject
+	// CacheDirs are the directories in CacheDirRoots on the node that no
+	// configured mount uses
+	CacheDirs []string
+}
+
+// IsEmpty returns true if there is nothing to prune
+func (p *PrunePlan) IsEmpty() bool {
+	return len(p.Apps) == 0 && len(p.Namespaces) == 0 && len(p.Objects) == 0 && len(p.CacheDirs) == 0
+}
+
+// PruneOptions describes what a cluster should keep
+type PruneOptions struct {
+	// ScaleSets are the names of the configured runner scale sets
+	ScaleSets []string
+	// Claims are the names of the cache claims of the configured scale sets
+	Claims []string
+	// HostPaths are the node directories the configured scale sets mount
+	HostPaths []string
+}
+
+// PrunePlan finds the runner scale sets, namespaces, objects and cache
+// directories of the cluster that are not in use by the configured ones
+func (m *Manager) PrunePlan(ctx context.Context, opts PruneOptions) (*PrunePlan, error) {
+	plan := &PrunePlan{}
+
+	appNamespaces, err := m.AppNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[string]string)
+	for name, namespace := range appNamespaces {
+		if slices.Contains(opts.ScaleSets, name) {
+			kept[name] = namespace
+			continue
+		}
+		plan.Apps = append(plan.Apps, PruneApp{Name: name, Namespace: namespace})
+	}
+	sort.Slice(plan.Apps, func(i, j int) bool {
+		return plan.Apps[i].Name < plan.Apps[j].Name
+	})
+
+	namespaces, err := m.runnerNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan.Namespaces = unusedNamespaces(namespaces, kept)
+
+	if plan.Objects, err = m.orphanedObjects(ctx, namespaces, opts); err != nil {
+		return nil, err
+	}
+
+	// Directories of hostPath volumes, like the one of the cache server, are in use too
+	volumes, err := m.CacheVolumes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hostPaths := slices.Clone(opts.HostPaths)
+	for _, volume := range volumes {
+		hostPaths = append(hostPaths, volume.HostPath)
+	}
+
+	logs, err := m.runNodePod(ctx, cacheDirPod("deskrun-cache-list-", cacheDirListScript, CacheDirRoots, true), pruneTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache directories: %w", err)
+	}
+	plan.CacheDirs = unreferencedCacheDirs(parseCacheDirs(logs), hostPaths)
+
+	return plan, nil
+}
+
+// Prune removes the resources of the plan: the kapp apps first, so the
+// namespaces are empty when they are deleted
+func (m *Manager) Prune(ctx context.Context, plan *PrunePlan) error {
+	for _, app := range plan.Apps {
+		if err := kappDelete(ctx, m.getNamespaceKappClient(app.Namespace), app.Name); err != nil {
+			return fmt.Errorf("failed to delete runner scale set %s: %w", app.Name, err)
+		}
+	}
+
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	for _, object := range plan.Objects {
+		var err error
+		switch object.Kind {
+		case "ConfigMap":
+			err = clientset.CoreV1().ConfigMaps(object.Namespace).Delete(ctx, object.Name, metav1.DeleteOptions{})
+		case "Secret":
+			err = clientset.CoreV1().Secrets(object.Namespace).Delete(ctx, object.Name, metav1.DeleteOptions{})
+		case "PersistentVolumeClaim":
+			err = clientset.CoreV1().PersistentVolumeClaims(object.Namespace).Delete(ctx, object.Name, metav1.DeleteOptions{})
+		}
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s %s/%s: %w", object.Kind, object.Namespace, object.Name, err)
+		}
+	}
+
+	for _, namespace := range plan.Namespaces {
+		err := clientset.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete namespace %s: %w", namespace, err)
+		}
+	}
+
+	if len(plan.CacheDirs) > 0 {
+		pod := cacheDirPod("deskrun-cache-prune-", cacheDirRemoveScript, plan.CacheDirs, false)
+		if _, err := m.runNodePod(ctx, pod, pruneTimeout); err != nil {
+			return fmt.Errorf("failed to remove cache directories: %w", err)
+		}
+	}
+	return nil
+}
+
+// orphanedObjects returns the hook extension configmaps, GitHub secrets and
+// cache claims in the runner namespaces that no kapp app owns and no
+// configured scale set uses, sorted by namespace, kind and name
+func (m *Manager) orphanedObjects(ctx context.Context, namespaces []corev1.Namespace, opts PruneOptions) ([]PruneObject, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []PruneObject
+	for _, namespace := range namespaces {
+		configMaps, err := clientset.CoreV1().ConfigMaps(namespace.Name).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list configmaps in %s: %w", namespace.Name, err)
+		}
+		for _, configMap := range configMaps.Items {
+			scaleSet, ok := strings.CutPrefix(configMap.Name, hookExtensionPrefix)
+			if ok && isOrphaned(configMap.ObjectMeta, scaleSet, opts.ScaleSets) {
+				objects = append(objects, PruneObject{Kind: "ConfigMap", Namespace: namespace.Name, Name: configMap.Name})
+			}
+		}
+
+		secrets, err := clientset.CoreV1().Secrets(namespace.Name).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list secrets in %s: %w", namespace.Name, err)
+		}
+		for _, secret := range secrets.Items {
+			scaleSet, ok := strings.CutSuffix(secret.Name, githubSecretSuffix)
+			if ok && isOrphaned(secret.ObjectMeta, scaleSet, opts.ScaleSets) {
+				objects = append(objects, PruneObject{Kind: "Secret", Namespace: namespace.Name, Name: secret.Name})
+			}
+		}
+
+		claims, err := clientset.CoreV1().PersistentVolumeClaims(namespace.Name).List(ctx, metav1.ListOptions{LabelSelector: scaleSetNameLabel})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list persistent volume claims in %s: %w", namespace.Name, err)
+		}
+		for _, claim := range claims.Items {
+			if isOrphaned(claim.ObjectMeta, claim.Name, opts.Claims) {
+				objects = append(objects, PruneObject{Kind: "PersistentVolumeClaim", Namespace: namespace.Name, Name: claim.Name})
+			}
+		}
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		if objects[i].Namespace != objects[j].Namespace {
+			return objects[i].Namespace < objects[j].Namespace
+		}
+		if objects[i].Kind != objects[j].Kind {
+			return objects[i].Kind < objects[j].Kind
+		}
+		return objects[i].Name < objects[j].Name
+	})
+	return objects, nil
+}
+
+// isOrphaned returns true if an object belongs to no kapp app and its name
+// is not in the given names in use
+func isOrphaned(meta metav1.ObjectMeta, name string, inUse []string) bool {
+	if _, owned := meta.Labels[kappAppLabel]; owned {
+		return false
+	}
+	return !slices.Contains(inUse, name)
+}
+
+// unreferencedCacheDirs returns the directories that none of the host paths
+// is, contains or is contained in, sorted
+func unreferencedCacheDirs(dirs, hostPaths []string) []string {
+	var unreferenced []string
+	for _, dir := range dirs {
+		referenced := slices.ContainsFunc(hostPaths, func(hostPath string) bool {
+			return hostPath == dir || strings.HasPrefix(hostPath, dir+"/") || strings.HasPrefix(dir, hostPath+"/")
+		})
+		if !referenced {
+			unreferenced = append(unreferenced, dir)
+		}
+	}
+	sort.Strings(unreferenced)
+	return unreferenced
+}
+
+// parseCacheDirs parses the lines the listing pod reports directories on
+func parseCacheDirs(logs string) []string {
+	var dirs []string
+	for _, line := range strings.Split(logs, "\n") {
+		dir, ok := strings.CutPrefix(line, pruneDirPrefix+" ")
+		if ok && dir != "" {
+			dirs = append(dirs, strings.TrimSuffix(dir, "/"))
+		}
+	}
+	return dirs
+}
+
+// cacheDirPod returns a pod that runs a script on directories of the node,
+// with the node's root filesystem mounted
+func cacheDirPod(generateName, script string, hostPaths []string, readOnly bool) *corev1.Pod {
+	command := append([]string{"sh", "-c", script, "sh"}, hostPaths...)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: generateName,
+			Namespace:    defaultNamespace,
+			Labels:       map[string]string{"app.kubernetes.io/name": "deskrun-cache-prune"},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:            "prune",
+				Image:           cacheGCImage,
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Command:         command,
+				VolumeMounts:    []corev1.VolumeMount{{Name: "node", MountPath: nodeRootMountPath, ReadOnly: readOnly}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "node",
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: "/"},
+				},
+			}},
+		},
+	}
+}
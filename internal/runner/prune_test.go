@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseCacheDirs(t *testing.T) {
+	logs := "deskrun-dir /tmp/github-runner-cache/old-runner/\ndeskrun-dir /host-cache/deskrun/volumes/npm/\nother output\n"
+
+	want := []string{"/tmp/github-runner-cache/old-runner", "/host-cache/deskrun/volumes/npm"}
+	if got := parseCacheDirs(logs); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCacheDirs() = %v, want %v", got, want)
+	}
+}
+
+func TestUnreferencedCacheDirs(t *testing.T) {
+	dirs := []string{
+		"/tmp/github-runner-cache/my-runner",
+		"/tmp/github-runner-cache/my-runner-old",
+		"/tmp/deskrun-cache/var-lib-docker",
+		"/host-cache/deskrun/volumes/npm",
+		"/host-cache/deskrun/volumes/pip",
+	}
+	hostPaths := []string{
+		"/tmp/github-runner-cache/my-runner/mount-0",
+		"/tmp/deskrun-cache/var-lib-docker/instance-1",
+		"/host-cache/deskrun/volumes",
+	}
+
+	want := []string{"/tmp/github-runner-cache/my-runner-old"}
+	if got := unreferencedCacheDirs(dirs, hostPaths); !reflect.DeepEqual(got, want) {
+		t.Errorf("unreferencedCacheDirs() = %v, want %v", got, want)
+	}
+}
+
+func TestIsOrphaned(t *testing.T) {
+	owned := metav1.ObjectMeta{Labels: map[string]string{kappAppLabel: "1234"}}
+	if isOrphaned(owned, "old-runner", nil) {
+		t.Errorf("isOrphaned() = true for an object of a kapp app")
+	}
+	if isOrphaned(metav1.ObjectMeta{}, "my-runner", []string{"my-runner"}) {
+		t.Errorf("isOrphaned() = true for an object in use")
+	}
+	if !isOrphaned(metav1.ObjectMeta{}, "old-runner", []string{"my-runner"}) {
+		t.Errorf("isOrphaned() = false for a leftover object")
+	}
+}
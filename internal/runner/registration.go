@@ -0,0 +1,157 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/progress"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// RegistrationTimeout is how long a deploy waits for a runner scale set to
+	// register with GitHub
+	RegistrationTimeout = 5 * time.Minute
+	// registrationPollInterval is how often the registration is checked
+	registrationPollInterval = 3 * time.Second
+	// scaleSetIDAnnotation is set by ARC on an AutoscalingRunnerSet once GitHub
+	// created the runner scale set
+	scaleSetIDAnnotation = "runner-scale-set-id"
+	// listenerLogLines is how many log lines of a failed listener are reported
+	listenerLogLines = 20
+)
+
+// errListenerFailed stops waiting for a registration that will not succeed
+var errListenerFailed = errors.New("listener failed")
+
+// WaitForRegistration waits until a runner scale set is registered with
+// GitHub and its listener is running, which means it can receive jobs. When
+// the listener fails, e.g. because of a bad token or a repository that does
+// not exist, it fails right away with the last lines of the listener logs.
+func (m *Manager) WaitForRegistration(ctx context.Context, namespace, name string, timeout time.Duration) error {
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return err
+	}
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	registered := false
+	var listenerPod *corev1.Pod
+	phase := progress.Start("Waiting for runner scale set '%s' to register with GitHub", name)
+	err = wait.PollUntilContextTimeout(ctx, registrationPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		scaleSet, err := dynamicClient.Resource(autoscalingRunnerSetGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("failed to get runner scale set: %w", err)
+		}
+		registered = scaleSet.GetAnnotations()[scaleSetIDAnnotation] != ""
+
+		listener, err := findListener(ctx, dynamicClient, name)
+		if err != nil || listener == "" {
+			return false, err
+		}
+		pod, err := clientset.CoreV1().Pods(arcControllerNamespace).Get(ctx, listener, metav1.GetOptions{})
+		switch {
+		case k8serrors.IsNotFound(err):
+			return false, nil
+		case err != nil:
+			return false, fmt.Errorf("failed to get listener pod: %w", err)
+		}
+		listenerPod = pod
+
+		if listenerFailed(*pod) {
+			return false, errListenerFailed
+		}
+		// A restarted listener pod still reports ready while it terminates
+		return registered && pod.DeletionTimestamp == nil && podReady(*pod), nil
+	})
+	phase.End(err)
+
+	switch {
+	case errors.Is(err, errListenerFailed):
+		return fmt.Errorf("listener of runner scale set %s failed, check the token and the repository or organization:\n  %s",
+			name, strings.Join(m.listenerLogs(ctx, *listenerPod), "\n  "))
+	case err != nil && !registered:
+		return fmt.Errorf("runner scale set %s did not register with GitHub within %s, check the controller logs: %w", name, timeout, err)
+	case err != nil:
+		return fmt.Errorf("listener of runner scale set %s did not start within %s: %w", name, timeout, err)
+	}
+	return nil
+}
+
+// findListener returns the name of the AutoscalingListener of a runner scale
+// set, empty while ARC has not created it. Listeners run in the controller
+// namespace and reference their scale set by name.
+func findListener(ctx context.Context, dynamicClient dynamic.Interface, scaleSetName string) (string, error) {
+	listeners, err := dynamicClient.Resource(autoscalingListenerGVR).Namespace(arcControllerNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list listeners: %w", err)
+	}
+	for _, listener := range listeners.Items {
+		if name, _, _ := unstructured.NestedString(listener.Object, "spec", "autoscalingRunnerSetName"); name == scaleSetName {
+			return listener.GetName(), nil
+		}
+	}
+	return "", nil
+}
+
+// listenerFailed returns true if the listener pod exited or crashed, which
+// it does when it cannot create a message session with GitHub
+func listenerFailed(pod corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodFailed {
+		return true
+	}
+	for _, container := range pod.Status.ContainerStatuses {
+		if waiting := container.State.Waiting; waiting != nil && waiting.Reason == "CrashLoopBackOff" {
+			return true
+		}
+		if terminated := container.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// listenerLogs returns the last log lines of a failed listener pod, of its
+// previous container if it restarted
+func (m *Manager) listenerLogs(ctx context.Context, pod corev1.Pod) []string {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return []string{fmt.Sprintf("(failed to get listener logs: %v)", err)}
+	}
+
+	previous := false
+	for _, container := range pod.Status.ContainerStatuses {
+		previous = previous || container.RestartCount > 0 && container.State.Terminated == nil
+	}
+	tailLines := int64(listenerLogLines)
+	data, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Previous:  previous,
+		TailLines: &tailLines,
+	}).DoRaw(ctx)
+	if err != nil {
+		return []string{fmt.Sprintf("(failed to get listener logs: %v)", err)}
+	}
+	return nonEmptyLines(string(data))
+}
+
+// nonEmptyLines splits text into its lines, leaving out empty ones
+func nonEmptyLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, "\r"))
+		}
+	}
+	return lines
+}
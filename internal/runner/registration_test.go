@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestListenerFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		status corev1.PodStatus
+		want   bool
+	}{
+		{"starting", corev1.PodStatus{Phase: corev1.PodPending}, false},
+		{"running", corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{
+			{State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+		}}, false},
+		{"failed", corev1.PodStatus{Phase: corev1.PodFailed}, true},
+		{"crash looping", corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{
+			{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+		}}, true},
+		{"exited with an error", corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{
+			{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}}},
+		}}, true},
+	}
+	for _, test := range tests {
+		if got := listenerFailed(corev1.Pod{Status: test.status}); got != test.want {
+			t.Errorf("listenerFailed(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNonEmptyLines(t *testing.T) {
+	want := []string{"creating message session", "401 Unauthorized"}
+	if got := nonEmptyLines("creating message session\r\n\n401 Unauthorized\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("nonEmptyLines() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,181 @@
+package runner
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// RegistrySecretName is the docker-registry secret holding the credentials
+	// of all configured registries
+	RegistrySecretName = "deskrun-registries"
+	// jobServiceAccountName is the service account of the job pods the
+	// kubernetes mode container hooks create
+	jobServiceAccountName = "default"
+)
+
+// dockerConfigJSON renders the .dockerconfigjson content of the registries
+func dockerConfigJSON(registries []*deskruntypes.RegistryCredential) ([]byte, error) {
+	type authEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+
+	auths := make(map[string]authEntry, len(registries))
+	for _, registry := range registries {
+		auths[registry.Server] = authEntry{
+			Username: registry.Username,
+			Password: registry.Password,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(registry.Username + ":" + registry.Password)),
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal registry credentials: %w", err)
+	}
+	return data, nil
+}
+
+// ApplyRegistryCredentials writes the credentials of the registries to the
+// registry secret in arc-systems and the given installation namespaces and
+// lets the job pods of kubernetes mode pull with it. Without registries the
+// secrets are removed.
+func (m *Manager) ApplyRegistryCredentials(ctx context.Context, registries []*deskruntypes.RegistryCredential, namespaces []string) error {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	if err := m.createNamespace(ctx, defaultNamespace); err != nil {
+		return fmt.Errorf("failed to create namespace: %w", err)
+	}
+	for _, namespace := range namespaces {
+		if err := m.ensureRunnerNamespace(ctx, namespace, ""); err != nil {
+			return err
+		}
+	}
+
+	for _, namespace := range registryNamespaces(namespaces) {
+		if err := applyRegistrySecret(ctx, clientset, namespace, registries); err != nil {
+			return fmt.Errorf("failed to apply registry credentials in namespace %s: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// registryNamespaces returns arc-systems followed by the other given
+// namespaces, without duplicates
+func registryNamespaces(namespaces []string) []string {
+	result := []string{defaultNamespace}
+	for _, namespace := range namespaces {
+		if !slices.Contains(result, namespace) {
+			result = append(result, namespace)
+		}
+	}
+	return result
+}
+
+// applyRegistrySecret writes the registry secret of a single namespace, or
+// removes it when there are no registries
+func applyRegistrySecret(ctx context.Context, clientset *kubernetes.Clientset, namespace string, registries []*deskruntypes.RegistryCredential) error {
+	secrets := clientset.CoreV1().Secrets(namespace)
+	if len(registries) == 0 {
+		if err := secrets.Delete(ctx, RegistrySecretName, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete registry secret: %w", err)
+		}
+		return retry(ctx, "update service account", func() error {
+			return setJobImagePullSecret(ctx, clientset, namespace, false)
+		})
+	}
+
+	config, err := dockerConfigJSON(registries)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RegistrySecretName,
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: config},
+	}
+
+	existing, err := secrets.Get(ctx, RegistrySecretName, metav1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+		if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create registry secret: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get registry secret: %w", err)
+	default:
+		existing.Type = secret.Type
+		existing.Data = secret.Data
+		if _, err := secrets.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update registry secret: %w", err)
+		}
+	}
+
+	return retry(ctx, "update service account", func() error {
+		return setJobImagePullSecret(ctx, clientset, namespace, true)
+	})
+}
+
+// setJobImagePullSecret adds the registry secret to (or removes it from) the
+// image pull secrets of the service account job pods run as. The service
+// account is created by Kubernetes shortly after the namespace.
+func setJobImagePullSecret(ctx context.Context, clientset *kubernetes.Clientset, namespace string, enabled bool) error {
+	serviceAccounts := clientset.CoreV1().ServiceAccounts(namespace)
+
+	var serviceAccount *corev1.ServiceAccount
+	err := wait.PollUntilContextTimeout(ctx, time.Second, 30*time.Second, true, func(ctx context.Context) (bool, error) {
+		var err error
+		serviceAccount, err = serviceAccounts.Get(ctx, jobServiceAccountName, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get service account %s: %w", jobServiceAccountName, err)
+	}
+
+	var pullSecrets []corev1.LocalObjectReference
+	found := false
+	for _, ref := range serviceAccount.ImagePullSecrets {
+		if ref.Name == RegistrySecretName {
+			found = true
+			if !enabled {
+				continue
+			}
+		}
+		pullSecrets = append(pullSecrets, ref)
+	}
+	if found == enabled {
+		return nil
+	}
+	if enabled {
+		pullSecrets = append(pullSecrets, corev1.LocalObjectReference{Name: RegistrySecretName})
+	}
+
+	serviceAccount.ImagePullSecrets = pullSecrets
+	if _, err := serviceAccounts.Update(ctx, serviceAccount, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update service account %s: %w", jobServiceAccountName, err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"encoding/json"
+	"testing"
+
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+)
+
+func TestDockerConfigJSON(t *testing.T) {
+	data, err := dockerConfigJSON([]*deskruntypes.RegistryCredential{
+		{Name: "ghcr", Server: "ghcr.io", Username: "bot", Password: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("dockerConfigJSON() error = %v", err)
+	}
+
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("failed to parse docker config: %v", err)
+	}
+
+	auth, ok := config.Auths["ghcr.io"]
+	if !ok {
+		t.Fatalf("docker config has no auth for ghcr.io: %s", data)
+	}
+	if auth.Username != "bot" || auth.Password != "secret" {
+		t.Errorf("auth = %+v, want username bot and password secret", auth)
+	}
+	if auth.Auth != "Ym90OnNlY3JldA==" {
+		t.Errorf("auth.Auth = %q, want base64 of bot:secret", auth.Auth)
+	}
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/rkoster/deskrun/internal/cluster"
+	"github.com/rkoster/deskrun/pkg/templates"
+	deskruntypes "github.com/rkoster/deskrun/pkg/types"
+)
+
+// RenderCacheDir is the directory rendered manifests are cached in across
+// deskrun runs; when empty they are only cached in memory
+var RenderCacheDir string
+
+// newProcessor returns a template processor using the render cache
+func newProcessor() *templates.Processor {
+	return templates.NewProcessor().WithCacheDir(RenderCacheDir)
+}
+
+// RenderedManifest is the processed multi-document YAML for a single kapp app
+type RenderedManifest struct {
+	Name string // kapp app name (runner scale set instance name or controller app name)
+	YAML []byte
+}
+
+// Render processes the templates of an installation without deploying them.
+// Installations with multiple instances render one manifest per instance, using
+// the same instance names and numbering as Install.
+func Render(installation *deskruntypes.RunnerInstallation) ([]RenderedManifest, error) {
+	instances := installation.Instances
+	if instances < 1 {
+		instances = 1
+	}
+
+	if instances == 1 {
+		processedYAML, err := renderInstance(installation, installation.Name, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []RenderedManifest{{Name: installation.Name, YAML: processedYAML}}, nil
+	}
+
+	manifests := make([]RenderedManifest, 0, instances)
+	for i := 1; i <= instances; i++ {
+		instanceName := fmt.Sprintf("%s-%d", installation.Name, i)
+		processedYAML, err := renderInstance(installation, instanceName, i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render instance %d: %w", i, err)
+		}
+		manifests = append(manifests, RenderedManifest{Name: instanceName, YAML: processedYAML})
+	}
+
+	return manifests, nil
+}
+
+// RenderController processes the ARC controller template of the given chart
+// version (empty for the default version) without deploying it. A non-nil
+// proxy is injected into the controller Deployment; with metrics the
+// controller and its listeners serve Prometheus metrics.
+func RenderController(version string, proxy *deskruntypes.ProxyConfig, metrics bool) ([]byte, error) {
+	processor := newProcessor()
+	config := templates.Config{
+		Installation: &deskruntypes.RunnerInstallation{
+			Name:          arcControllerAppName,
+			Repository:    "https://github.com/placeholder",
+			ContainerMode: deskruntypes.ContainerModeKubernetes,
+		},
+		InstanceName:      arcControllerAppName,
+		InstanceNum:       1,
+		ControllerVersion: version,
+		Proxy:             proxy,
+		Metrics:           metrics,
+	}
+
+	controllerYAML, err := processor.ProcessTemplate(templates.TemplateTypeController, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get controller chart: %w", err)
+	}
+
+	return controllerYAML, nil
+}
+
+// renderInstance processes the scale set template for a single instance using the
+// unified template processing package (ytt Go library, no shell execution)
+func renderInstance(installation *deskruntypes.RunnerInstallation, instanceName string, instanceNum int) ([]byte, error) {
+	opts := templates.Options{
+		InstanceName: instanceName,
+		InstanceNum:  instanceNum,
+	}
+	if installation.ContainerMode == deskruntypes.ContainerModeHostDocker {
+		opts.DockerSocketGroup = cluster.DockerSocketGroup()
+	}
+	processedYAML, err := newProcessor().RenderScaleSet(installation, opts)
+	if err != nil {
+		// Check if it's a TemplateError with verbose information
+		if templateErr, ok := err.(*templates.TemplateError); ok {
+			return nil, fmt.Errorf("failed to process template: %s", templateErr.VerboseError())
+		}
+		return nil, fmt.Errorf("failed to process template: %w", err)
+	}
+
+	return processedYAML, nil
+}
@@ -0,0 +1,181 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// controllerServiceAccount is the identity the ARC controller runs as
+	controllerServiceAccount = "system:serviceaccount:arc-systems:arc-controller-gha-rs-controller"
+	// controllerLogTailLines is how many recent controller log lines are scanned for errors
+	controllerLogTailLines = 500
+)
+
+// Permission is a Kubernetes API permission
+type Permission struct {
+	Group    string
+	Resource string
+	Verb     string
+}
+
+func (p Permission) String() string {
+	if p.Group == "" {
+		return fmt.Sprintf("%s %s", p.Verb, p.Resource)
+	}
+	return fmt.Sprintf("%s %s.%s", p.Verb, p.Resource, p.Group)
+}
+
+// finalizerPermissions are the permissions the ARC controller needs in a
+// runner namespace to clean up EphemeralRunners and remove their finalizers
+var finalizerPermissions = []Permission{
+	{Resource: "secrets", Verb: "get"},
+	{Resource: "secrets", Verb: "list"},
+	{Resource: "secrets", Verb: "delete"},
+	{Resource: "pods", Verb: "get"},
+	{Resource: "pods", Verb: "list"},
+	{Resource: "pods", Verb: "delete"},
+	{Group: "actions.github.com", Resource: "ephemeralrunners", Verb: "update"},
+}
+
+// ControllerRunning reports whether at least one ARC controller pod is running and ready
+func (m *Manager) ControllerRunning(ctx context.Context) (bool, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return false, err
+	}
+
+	pods, err := clientset.CoreV1().Pods(arcControllerNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: controllerPodSelector,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list controller pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		if podReady(pod) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MissingControllerPermissions returns the finalizer cleanup permissions the
+// ARC controller service account lacks in the given namespace
+func (m *Manager) MissingControllerPermissions(ctx context.Context, namespace string) ([]Permission, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []Permission
+	for _, permission := range finalizerPermissions {
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   controllerServiceAccount,
+				Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + arcControllerNamespace},
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Group:     permission.Group,
+					Resource:  permission.Resource,
+					Verb:      permission.Verb,
+				},
+			},
+		}
+
+		result, err := clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review %s permission: %w", permission, err)
+		}
+		if !result.Status.Allowed {
+			missing = append(missing, permission)
+		}
+	}
+
+	return missing, nil
+}
+
+// ControllerErrors returns recent ARC controller log lines that contain the given substring
+func (m *Manager) ControllerErrors(ctx context.Context, substring string) ([]string, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := clientset.CoreV1().Pods(arcControllerNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: controllerPodSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list controller pods: %w", err)
+	}
+
+	var lines []string
+	for _, pod := range pods.Items {
+		tailLines := int64(controllerLogTailLines)
+		data, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			TailLines: &tailLines,
+		}).DoRaw(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get logs of %s: %w", pod.Name, err)
+		}
+		lines = append(lines, matchingLines(string(data), substring)...)
+	}
+
+	return lines, nil
+}
+
+// RemoveFinalizers clears the finalizers of a stuck resource so Kubernetes can delete it
+func (m *Manager) RemoveFinalizers(ctx context.Context, resource StuckResource) error {
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return err
+	}
+
+	gvr := schema.GroupVersionResource{
+		Group:    "actions.github.com",
+		Version:  "v1alpha1",
+		Resource: resource.Resource,
+	}
+
+	patch := []byte(`{"metadata":{"finalizers":null}}`)
+	if _, err := dynamicClient.Resource(gvr).Namespace(resource.Namespace).Patch(ctx, resource.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to remove finalizers of %s %s: %w", resource.Kind, resource.Name, err)
+	}
+
+	return nil
+}
+
+// matchingLines returns the distinct lines of s that contain substring, in order
+func matchingLines(s, substring string) []string {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || !strings.Contains(line, substring) || seen[line] {
+			continue
+		}
+		seen[line] = true
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// podReady reports whether a pod is running with the Ready condition set
+func podReady(pod corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
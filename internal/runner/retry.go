@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/kapp"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// retryBackoff is how often and how long apart transient failures of kapp
+// and the Kubernetes API are retried: 5 retries within about a minute
+var retryBackoff = wait.Backoff{
+	Duration: 2 * time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+// transientErrorMessages are parts of error messages, e.g. of kapp, that go
+// away by themselves while a cluster or the ARC controller starts
+var transientErrorMessages = []string{
+	"connection refused",
+	"connection reset by peer",
+	"tls handshake timeout",
+	"failed calling webhook",
+	"no endpoints available for service",
+	"the object has been modified",
+	"etcdserver: request timed out",
+}
+
+// isTransient returns true for errors that are worth retrying: a refused
+// connection during cluster warm-up, a webhook that is not ready yet, a
+// conflicting update or an overloaded API server
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// The output of kapp may mention errors it recovered from; only its error counts
+	var kappErr *kapp.Error
+	if errors.As(err, &kappErr) {
+		err = kappErr.Err
+	}
+
+	if k8serrors.IsConflict(err) || k8serrors.IsServiceUnavailable(err) || k8serrors.IsServerTimeout(err) ||
+		k8serrors.IsTimeout(err) || k8serrors.IsTooManyRequests(err) || isConnectionError(err) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, transient := range transientErrorMessages {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// isConnectionError returns true if a request failed to reach the server
+func isConnectionError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// retry calls fn until it succeeds or fails with an error that is not
+// transient, backing off exponentially between the attempts. It gives up
+// after the steps of retryBackoff or when ctx is done, with the last error.
+func retry(ctx context.Context, operation string, fn func() error) error {
+	backoff := retryBackoff
+	for {
+		err := fn()
+		if !isTransient(err) || backoff.Steps == 0 {
+			return err
+		}
+
+		delay := backoff.Step()
+		slog.Debug("Retrying after a transient error", "operation", operation, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// kappDeploy deploys an app with kapp, retrying transient failures
+func kappDeploy(ctx context.Context, client *kapp.Client, appName, manifestPath string, opts kapp.DeployOptions) error {
+	return retry(ctx, "deploy "+appName, func() error {
+		return client.DeployWithOptions(appName, manifestPath, opts)
+	})
+}
+
+// kappDelete deletes an app with kapp, retrying transient failures
+func kappDelete(ctx context.Context, client *kapp.Client, appName string) error {
+	return retry(ctx, "delete "+appName, func() error {
+		return client.Delete(appName)
+	})
+}
+
+// retryTransport retries requests to the Kubernetes API that could not
+// connect, e.g. while the API server of a new cluster starts. Requests that
+// reached the server are left to client-go, which retries throttled ones.
+// A reset connection may have reached the server, so only idempotent
+// requests are retried after one.
+type retryTransport struct {
+	next http.RoundTripper
+}
+
+// wrapRetryTransport is a rest.Config WrapTransport that adds retryTransport
+func wrapRetryTransport(next http.RoundTripper) http.RoundTripper {
+	return &retryTransport{next: next}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := retryBackoff
+	for {
+		resp, err := t.next.RoundTrip(req)
+		// A request body can only be sent again if it can be recreated
+		replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if err == nil || !retryableRequestError(req, err) || !replayable || backoff.Steps == 0 {
+			return resp, err
+		}
+
+		delay := backoff.Step()
+		slog.Debug("Retrying Kubernetes API request", "method", req.Method, "url", req.URL.Redacted(), "delay", delay, "error", err)
+		select {
+		case <-req.Context().Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryableRequestError returns true if a request can be sent again after it
+// failed with err: it was refused before reaching the server, or its
+// connection was reset and sending it twice has the same effect
+func retryableRequestError(req *http.Request, err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	if !errors.Is(err, syscall.ECONNRESET) {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
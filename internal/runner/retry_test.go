@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rkoster/deskrun/internal/kapp"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"connection refused", fmt.Errorf("dial tcp: %w", syscall.ECONNREFUSED), true},
+		{"webhook not ready", errors.New(`Internal error occurred: failed calling webhook "mutate-runner-pod.autoscalingrunnerset.actions.github.com"`), true},
+		{"conflict", k8serrors.NewConflict(schema.GroupResource{Resource: "serviceaccounts"}, "default", errors.New("modified")), true},
+		{"not found", k8serrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "token"), false},
+		{"canceled", context.Canceled, false},
+		{"kapp output only", &kapp.Error{Err: errors.New("timed out waiting"), Output: []string{"connection refused"}}, false},
+		{"kapp error", &kapp.Error{Err: errors.New("Get https://127.0.0.1:6443: connection refused")}, true},
+	}
+	for _, test := range tests {
+		if got := isTransient(test.err); got != test.want {
+			t.Errorf("isTransient(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	defer func(backoff wait.Backoff) { retryBackoff = backoff }(retryBackoff)
+	retryBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+
+	attempts := 0
+	err := retry(context.Background(), "test", func() error {
+		attempts++
+		if attempts < 3 {
+			return syscall.ECONNREFUSED
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("retry() = %v after %d attempts, want success after 3", err, attempts)
+	}
+
+	attempts = 0
+	permanent := errors.New("invalid manifest")
+	err = retry(context.Background(), "test", func() error {
+		attempts++
+		return permanent
+	})
+	if !errors.Is(err, permanent) || attempts != 1 {
+		t.Errorf("retry() = %v after %d attempts, want the permanent error after 1", err, attempts)
+	}
+
+	attempts = 0
+	err = retry(context.Background(), "test", func() error {
+		attempts++
+		return syscall.ECONNREFUSED
+	})
+	if !errors.Is(err, syscall.ECONNREFUSED) || attempts != 4 {
+		t.Errorf("retry() = %v after %d attempts, want to give up after 4", err, attempts)
+	}
+}
+
+func TestRetryableRequestError(t *testing.T) {
+	refused := fmt.Errorf("dial tcp: %w", syscall.ECONNREFUSED)
+	reset := fmt.Errorf("read tcp: %w", syscall.ECONNRESET)
+	tests := []struct {
+		method string
+		err    error
+		want   bool
+	}{
+		{http.MethodGet, refused, true},
+		{http.MethodPost, refused, true},
+		{http.MethodPatch, refused, true},
+		{http.MethodGet, reset, true},
+		{http.MethodHead, reset, true},
+		{http.MethodPut, reset, true},
+		{http.MethodDelete, reset, true},
+		{http.MethodPost, reset, false},
+		{http.MethodPatch, reset, false},
+		{http.MethodGet, errors.New("certificate signed by unknown authority"), false},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, "https://127.0.0.1:6443/api", nil)
+		if got := retryableRequestError(req, test.err); got != test.want {
+			t.Errorf("retryableRequestError(%s, %v) = %v, want %v", test.method, test.err, got, test.want)
+		}
+	}
+}
@@ -2,15 +2,19 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rkoster/deskrun/internal/cluster"
 	"github.com/rkoster/deskrun/internal/kapp"
-	"github.com/rkoster/deskrun/pkg/templates"
+	"github.com/rkoster/deskrun/internal/progress"
 	deskruntypes "github.com/rkoster/deskrun/pkg/types"
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
@@ -25,14 +29,23 @@ import (
 )
 
 const (
-	defaultNamespace       = "arc-systems"
+	defaultNamespace       = deskruntypes.DefaultNamespace
 	arcControllerNamespace = "arc-systems"
 	arcControllerAppName   = "arc-controller"
+	// maxParallelInstalls bounds how many instances of an installation are
+	// deployed at the same time
+	maxParallelInstalls = 4
 )
 
 // Manager handles runner operations
 type Manager struct {
 	clusterManager *cluster.Manager
+	// controllerVersion is the ARC chart version to install (empty = default)
+	controllerVersion string
+	// controllerProxy is the HTTP(S) proxy the ARC controller uses (nil = none)
+	controllerProxy *deskruntypes.ProxyConfig
+	// applyOptions bound how long kapp deploys and deletes apps
+	applyOptions kapp.ApplyOptions
 }
 
 // NewManager creates a new runner manager
@@ -42,9 +55,39 @@ func NewManager(clusterManager *cluster.Manager) *Manager {
 	}
 }
 
+// WithControllerVersion returns a copy of the manager that installs the given
+// ARC controller chart version instead of the default one
+func (m *Manager) WithControllerVersion(version string) *Manager {
+	clone := *m
+	clone.controllerVersion = version
+	return &clone
+}
+
+// WithControllerProxy returns a copy of the manager that installs the ARC
+// controller with the given HTTP(S) proxy
+func (m *Manager) WithControllerProxy(proxy *deskruntypes.ProxyConfig) *Manager {
+	clone := *m
+	clone.controllerProxy = proxy
+	return &clone
+}
+
+// WithApplyOptions returns a copy of the manager that deploys and deletes
+// apps with the given kapp timeout and wait behavior
+func (m *Manager) WithApplyOptions(opts kapp.ApplyOptions) *Manager {
+	clone := *m
+	clone.applyOptions = opts
+	return &clone
+}
+
 // getKappClient returns a kapp client configured for the current cluster
 func (m *Manager) getKappClient() *kapp.Client {
-	return kapp.NewClient(m.clusterManager.GetKubeconfig(), defaultNamespace)
+	return m.getNamespaceKappClient(defaultNamespace)
+}
+
+// getNamespaceKappClient returns a kapp client that records apps in the given
+// namespace of the current cluster
+func (m *Manager) getNamespaceKappClient(namespace string) *kapp.Client {
+	return kapp.NewClient(m.clusterManager.GetKubeconfig(), namespace).WithApplyOptions(m.applyOptions)
 }
 
 // customWarningHandler is a warning handler that filters out unrecognized format warnings
@@ -57,8 +100,8 @@ func (h customWarningHandler) HandleWarningHeader(code int, agent string, text s
 		(strings.Contains(text, "int32") || strings.Contains(text, "int64")) {
 		return // Skip these warnings
 	}
-	// For other warnings, print them normally (this mimics the default behavior)
-	fmt.Printf("Warning: %s\n", text)
+	// For other warnings, log them normally (this mimics the default behavior)
+	slog.Warn(text)
 }
 
 // getKubernetesClient creates a Kubernetes clientset
@@ -76,6 +119,7 @@ func (m *Manager) getKubernetesClient() (*kubernetes.Clientset, error) {
 	// Set custom warning handler to filter out unrecognized format warnings
 	// This cast ensures we use the rest package
 	config.WarningHandler = rest.WarningHandler(customWarningHandler{})
+	config.WrapTransport = wrapRetryTransport
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -99,6 +143,7 @@ func (m *Manager) getDynamicClient() (dynamic.Interface, error) {
 
 	// Set custom warning handler to filter out unrecognized format warnings
 	config.WarningHandler = rest.WarningHandler(customWarningHandler{})
+	config.WrapTransport = wrapRetryTransport
 
 	dynamicClient, err := dynamic.NewForConfig(config)
 	if err != nil {
@@ -156,10 +201,25 @@ func (m *Manager) Install(ctx context.Context, installation *deskruntypes.Runner
 		return fmt.Errorf("cluster does not exist, please create it first")
 	}
 
-	// Create namespace
+	// Create the controller namespace and the namespace of the installation
 	if err := m.createNamespace(ctx, defaultNamespace); err != nil {
 		return fmt.Errorf("failed to create namespace: %w", err)
 	}
+	namespace := installation.GetNamespace()
+	if err := m.ensureRunnerNamespace(ctx, namespace, installation.PodSecurity); err != nil {
+		return err
+	}
+
+	// Runner pods never start when a mounted secret or configmap is missing
+	if err := m.checkObjectMounts(ctx, namespace, installation.ObjectMounts); err != nil {
+		return err
+	}
+	if err := m.checkRuntimeClass(ctx, installation.RuntimeClassName); err != nil {
+		return err
+	}
+	if err := m.checkArch(ctx, installation.Arch); err != nil {
+		return err
+	}
 
 	// Ensure ARC controller is installed
 	if err := m.ensureARCController(ctx); err != nil {
@@ -177,19 +237,109 @@ func (m *Manager) Install(ctx context.Context, installation *deskruntypes.Runner
 		return m.installInstance(ctx, installation, installation.Name, 0)
 	}
 
-	// Multiple instances - create separate scale sets with numbered suffixes
-	fmt.Printf("Installing %d runner scale set instances for '%s'...\n", instances, installation.Name)
-	for i := 1; i <= instances; i++ {
-		instanceName := fmt.Sprintf("%s-%d", installation.Name, i)
-		if err := m.installInstance(ctx, installation, instanceName, i); err != nil {
-			return fmt.Errorf("failed to install instance %d: %w", i, err)
+	// Multiple instances - create separate scale sets with numbered suffixes,
+	// deployed concurrently as every kapp deploy waits for reconciliation
+	slog.Info("Installing runner scale set instances", "installation", installation.Name, "instances", instances)
+	errs := RunParallel(instances, maxParallelInstalls, func(i int) error {
+		instance := i + 1
+		instanceName := fmt.Sprintf("%s-%d", installation.Name, instance)
+		if err := m.installInstance(ctx, installation, instanceName, instance); err != nil {
+			return fmt.Errorf("failed to install instance %d: %w", instance, err)
+		}
+		return nil
+	})
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	slog.Info("All instances installed", "installation", installation.Name, "instances", instances)
+	return nil
+}
+
+// RunParallel calls fn for the indexes 0 to n-1, at most parallel at a time,
+// and returns the error of each call by index. A failing call doesn't stop the
+// others.
+func RunParallel(n, parallel int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+// Diff shows the changes deploying an installation would apply to its runner
+// scale sets, without applying them
+func (m *Manager) Diff(ctx context.Context, installation *deskruntypes.RunnerInstallation) error {
+	instances := installation.Instances
+	if instances < 1 {
+		instances = 1
+	}
+
+	// The diffs are shown one after the other, so they are not interleaved
+	for instance := 1; instance <= instances; instance++ {
+		instanceName, instanceNum := installation.Name, 0
+		if instances > 1 {
+			instanceName, instanceNum = fmt.Sprintf("%s-%d", installation.Name, instance), instance
+		}
+
+		tmpDir, err := os.MkdirTemp("/tmp", "deskrun-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temp dir: %w", err)
+		}
+		manifestPath, err := writeInstanceManifest(tmpDir, installation, instanceName, instanceNum)
+		if err == nil {
+			slog.Info("Changes to runner scale set", "name", instanceName)
+			err = m.getNamespaceKappClient(installation.GetNamespace()).Diff(instanceName, manifestPath)
+		}
+		_ = os.RemoveAll(tmpDir)
+		if err != nil {
+			return fmt.Errorf("failed to diff runner scale set %s: %w", instanceName, err)
 		}
 	}
+	return nil
+}
+
+// DiffUninstall shows the resources removing a runner scale set would delete
+func (m *Manager) DiffUninstall(ctx context.Context, name string) error {
+	appNamespaces, err := m.AppNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	namespace, ok := appNamespaces[name]
+	if !ok {
+		namespace = defaultNamespace
+	}
 
-	fmt.Printf("All %d instances installed successfully\n", instances)
+	if err := m.getNamespaceKappClient(namespace).DiffDelete(name); err != nil {
+		return fmt.Errorf("failed to diff removal of runner scale set %s: %w", name, err)
+	}
 	return nil
 }
 
+// writeInstanceManifest renders a runner scale set instance to manifest.yaml
+// in dir, for kapp, and returns its path
+func writeInstanceManifest(dir string, installation *deskruntypes.RunnerInstallation, instanceName string, instanceNum int) (string, error) {
+	processedYAML, err := renderInstance(installation, instanceName, instanceNum)
+	if err != nil {
+		return "", err
+	}
+
+	manifestPath := filepath.Join(dir, "manifest.yaml")
+	if err := os.WriteFile(manifestPath, processedYAML, 0644); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return manifestPath, nil
+}
+
 // installInstance installs a single runner scale set instance using the unified template processing package
 func (m *Manager) installInstance(ctx context.Context, installation *deskruntypes.RunnerInstallation, instanceName string, instanceNum int) error {
 	// Create temporary directory for manifests
@@ -199,70 +349,85 @@ func (m *Manager) installInstance(ctx context.Context, installation *deskruntype
 	}
 	defer func() { _ = os.RemoveAll(tmpDir) }()
 
-	fmt.Printf("  Installing runner scale set '%s'...\n", instanceName)
-
-	// Use the unified template processing package (ytt Go library, no shell execution)
-	processor := templates.NewProcessor()
-	config := templates.Config{
-		Installation: installation,
-		InstanceName: instanceName,
-		InstanceNum:  instanceNum,
-		Namespace:    defaultNamespace,
+	manifestPath, err := writeInstanceManifest(tmpDir, installation, instanceName, instanceNum)
+	if err != nil {
+		return err
 	}
 
-	processedYAML, err := processor.ProcessTemplate(templates.TemplateTypeScaleSet, config)
+	// An instance deployed to another namespace before is removed first, as
+	// kapp records the app in the namespace of the installation
+	namespace := installation.GetNamespace()
+	appNamespaces, err := m.AppNamespaces(ctx)
 	if err != nil {
-		// Check if it's a TemplateError with verbose information
-		if templateErr, ok := err.(*templates.TemplateError); ok {
-			return fmt.Errorf("failed to process template: %s", templateErr.VerboseError())
-		}
-		return fmt.Errorf("failed to process template: %w", err)
+		return err
 	}
-
-	// Write processed YAML to file for kapp
-	manifestPath := filepath.Join(tmpDir, "manifest.yaml")
-	if err := os.WriteFile(manifestPath, processedYAML, 0644); err != nil {
-		return fmt.Errorf("failed to write manifest: %w", err)
+	if previous, ok := appNamespaces[instanceName]; ok && previous != namespace {
+		slog.Info("Moving runner scale set to another namespace", "name", instanceName, "from", previous, "to", namespace)
+		if err := kappDelete(ctx, m.getNamespaceKappClient(previous), instanceName); err != nil {
+			return fmt.Errorf("failed to remove runner from namespace %s: %w", previous, err)
+		}
 	}
 
 	// Deploy using kapp
-	kappClient := m.getKappClient()
+	kappClient := m.getNamespaceKappClient(namespace)
 	appName := instanceName
-	if err := kappClient.Deploy(appName, manifestPath); err != nil {
+	phase := progress.Start("Deploying runner scale set '%s'", instanceName)
+	err = kappDeploy(ctx, kappClient, appName, manifestPath, kapp.DeployOptions{})
+	phase.End(err)
+	if err != nil {
 		return fmt.Errorf("failed to deploy with kapp: %w", err)
 	}
 
-	fmt.Printf("  Instance '%s' installed successfully\n", instanceName)
+	// A paused installation is deployed without runners
+	if installation.Paused {
+		if err := m.PauseScaleSet(ctx, instanceName); err != nil {
+			return fmt.Errorf("failed to pause runner scale set: %w", err)
+		}
+	}
+
+	// A deployed scale set is only usable once GitHub accepted it
+	if !m.applyOptions.NoWait {
+		if err := m.WaitForRegistration(ctx, namespace, instanceName, RegistrationTimeout); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// Uninstall removes a runner scale set
+// Uninstall removes a runner scale set from the namespace it is deployed to
 func (m *Manager) Uninstall(ctx context.Context, name string) error {
+	appNamespaces, err := m.AppNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+	namespace, ok := appNamespaces[name]
+	if !ok {
+		namespace = defaultNamespace
+	}
+
 	// Uninstall using kapp delete
-	kappClient := m.getKappClient()
-	if err := kappClient.Delete(name); err != nil {
+	kappClient := m.getNamespaceKappClient(namespace)
+	if err := kappDelete(ctx, kappClient, name); err != nil {
 		return fmt.Errorf("failed to uninstall runner: %w", err)
 	}
 
 	return nil
 }
 
-// List returns all runner scale sets
+// List returns all runner scale sets, sorted by name
 func (m *Manager) List(ctx context.Context) ([]string, error) {
 	// List kapp apps since the status command uses kapp inspect
-	kappClient := m.getKappClient()
-	appNames, err := kappClient.List()
+	appNamespaces, err := m.AppNamespaces(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list kapp apps: %w", err)
+		return nil, err
 	}
 
-	// Filter out the controller app to only show runner apps
-	var runnerNames []string
-	for _, name := range appNames {
-		if name != arcControllerAppName {
-			runnerNames = append(runnerNames, name)
-		}
+	runnerNames := make([]string, 0, len(appNamespaces))
+	for name := range appNamespaces {
+		runnerNames = append(runnerNames, name)
 	}
+	sort.Strings(runnerNames)
 
 	return runnerNames, nil
 }
@@ -287,6 +452,45 @@ func (m *Manager) createNamespace(ctx context.Context, namespace string) error {
 	return nil
 }
 
+// checkObjectMounts verifies that the secrets and configmaps mounted into the
+// runner pods exist in the runner namespace
+func (m *Manager) checkObjectMounts(ctx context.Context, namespace string, objectMounts []deskruntypes.ObjectMount) error {
+	if len(objectMounts) == 0 {
+		return nil
+	}
+
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, objectMount := range objectMounts {
+		switch objectMount.Kind {
+		case deskruntypes.ObjectKindSecret:
+			_, err = clientset.CoreV1().Secrets(namespace).Get(ctx, objectMount.Name, metav1.GetOptions{})
+		case deskruntypes.ObjectKindConfigMap:
+			_, err = clientset.CoreV1().ConfigMaps(namespace).Get(ctx, objectMount.Name, metav1.GetOptions{})
+		default:
+			return fmt.Errorf("unsupported object mount kind '%s'", objectMount.Kind)
+		}
+		if k8serrors.IsNotFound(err) {
+			missing = append(missing, fmt.Sprintf("%s '%s'", strings.ToLower(string(objectMount.Kind)), objectMount.Name))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get %s '%s': %w", strings.ToLower(string(objectMount.Kind)), objectMount.Name, err)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("mounted %s not found in namespace %s; create them with kubectl -n %s before running up",
+			strings.Join(missing, ", "), namespace, namespace)
+	}
+
+	return nil
+}
+
 // generateYTTDataValues generates ytt data values for the runner scale set
 func (m *Manager) generateYTTDataValues(installation *deskruntypes.RunnerInstallation, instanceName string, instanceNum int) (string, error) {
 	// Build the values map
@@ -301,6 +505,11 @@ func (m *Manager) generateYTTDataValues(installation *deskruntypes.RunnerInstall
 		// so we don't add it here.
 	}
 
+	// Runner groups only exist for organization-level runner scale sets
+	if installation.RunnerGroup != "" && installation.IsOrganizationLevel() {
+		values["runnerGroup"] = installation.RunnerGroup
+	}
+
 	// Determine authentication method
 	if installation.AuthType == deskruntypes.AuthTypePAT {
 		values["githubConfigSecret"] = map[string]interface{}{
@@ -308,12 +517,18 @@ func (m *Manager) generateYTTDataValues(installation *deskruntypes.RunnerInstall
 		}
 	} else {
 		values["githubConfigSecret"] = map[string]interface{}{
-			"github_app_id":              "",
-			"github_app_installation_id": "",
+			"github_app_id":              installation.GitHubAppID,
+			"github_app_installation_id": installation.GitHubAppInstallationID,
 			"github_app_private_key":     installation.AuthValue,
 		}
 	}
 
+	// Runner container image, defaulting to the upstream image
+	runnerImage := installation.Image
+	if runnerImage == "" {
+		runnerImage = "ghcr.io/actions/actions-runner:latest"
+	}
+
 	// Build container mode configuration
 	var containerModeConfig map[string]interface{}
 	switch installation.ContainerMode {
@@ -338,7 +553,7 @@ func (m *Manager) generateYTTDataValues(installation *deskruntypes.RunnerInstall
 				"containers": []map[string]interface{}{
 					{
 						"name":    "runner",
-						"image":   "ghcr.io/actions/actions-runner:latest",
+						"image":   runnerImage,
 						"command": []string{"/home/runner/run.sh"},
 						"env": []map[string]interface{}{
 							{
@@ -350,7 +565,7 @@ func (m *Manager) generateYTTDataValues(installation *deskruntypes.RunnerInstall
 				},
 			},
 		}
-	case deskruntypes.ContainerModeDinD:
+	case deskruntypes.ContainerModeDinD, deskruntypes.ContainerModeDinDRootless:
 		containerModeConfig = map[string]interface{}{
 			"type": "dind",
 		}
@@ -373,6 +588,18 @@ func (m *Manager) generateYTTDataValues(installation *deskruntypes.RunnerInstall
 	return string(yamlData), nil
 }
 
+// EnsureController installs the ARC controller if it is missing, so runner
+// scale sets can be installed concurrently afterwards
+func (m *Manager) EnsureController(ctx context.Context) error {
+	if err := m.createNamespace(ctx, defaultNamespace); err != nil {
+		return fmt.Errorf("failed to create namespace: %w", err)
+	}
+	if err := m.ensureARCController(ctx); err != nil {
+		return fmt.Errorf("failed to ensure ARC controller: %w", err)
+	}
+	return nil
+}
+
 func (m *Manager) ensureARCController(ctx context.Context) error {
 	// Check if CRDs are already installed
 	exists, err := m.crdExists(ctx, "autoscalingrunnersets.actions.github.com")
@@ -385,58 +612,25 @@ func (m *Manager) ensureARCController(ctx context.Context) error {
 	}
 
 	// CRDs don't exist, install the controller
-	fmt.Println("Installing GitHub Actions Runner Controller...")
-
-	// Create temporary directory for controller templates
-	tmpDir, err := os.MkdirTemp("/tmp", "deskrun-controller-*")
-	if err != nil {
-		return fmt.Errorf("failed to create temp dir: %w", err)
-	}
-	defer func() { _ = os.RemoveAll(tmpDir) }()
-
-	// Get controller template using the unified template package
-	// ProcessTemplate applies the overlay which adds required RBAC permissions
-	processor := templates.NewProcessor()
-	config := templates.Config{
-		Installation: &deskruntypes.RunnerInstallation{
-			Name:          "arc-controller",
-			Repository:    "https://github.com/placeholder",
-			ContainerMode: deskruntypes.ContainerModeKubernetes,
-		},
-		InstanceName: "arc-controller",
-		InstanceNum:  1,
+	phase := progress.Start("Installing GitHub Actions Runner Controller")
+	err = m.deployController(ctx, kapp.DeployOptions{})
+	// Check if already installed
+	if err != nil && strings.Contains(err.Error(), "already exists") {
+		phase.End(nil)
+		slog.Info("Controller already installed")
+		return nil
 	}
-	controllerYAML, err := processor.ProcessTemplate(templates.TemplateTypeController, config)
+	phase.End(err)
 	if err != nil {
-		return fmt.Errorf("failed to get controller chart: %w", err)
-	}
-
-	// Write to temp file for kapp
-	controllerPath := filepath.Join(tmpDir, "controller.yaml")
-	if err := os.WriteFile(controllerPath, controllerYAML, 0644); err != nil {
-		return fmt.Errorf("failed to write controller template: %w", err)
-	}
-
-	// Deploy controller using kapp (no ytt processing needed for controller - it's pre-rendered)
-	appName := arcControllerAppName
-	kappClient := m.getKappClient()
-	if err := kappClient.Deploy(appName, controllerPath); err != nil {
-		// Check if already installed
-		if strings.Contains(err.Error(), "already exists") {
-			fmt.Println("Controller already installed")
-			return nil
-		}
 		return fmt.Errorf("failed to install ARC controller: %w", err)
 	}
 
-	fmt.Println("ARC controller installed successfully")
-
 	// Wait for CRDs to be ready
-	fmt.Println("Waiting for CRDs to be ready...")
-	if err := m.waitForCRD(ctx, "autoscalingrunnersets.actions.github.com"); err != nil {
+	phase = progress.Start("Waiting for CRDs to be ready")
+	err = m.waitForCRD(ctx, "autoscalingrunnersets.actions.github.com")
+	phase.End(err)
+	if err != nil {
 		return fmt.Errorf("timeout waiting for CRDs to be ready: %w", err)
 	}
-
-	fmt.Println("CRDs are ready")
 	return nil
 }
@@ -1,8 +1,12 @@
 package runner
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/rkoster/deskrun/pkg/types"
 )
@@ -119,6 +123,32 @@ func TestGenerateYTTDataValues_NoRunnerGroupForRepoLevel(t *testing.T) {
 	}
 }
 
+func TestGenerateYTTDataValues_RunnerGroupForOrgLevel(t *testing.T) {
+	installation := &types.RunnerInstallation{
+		Name:          "org-runner",
+		Repository:    "https://github.com/myorg",
+		RunnerGroup:   "ci-runners",
+		ContainerMode: types.ContainerModeKubernetes,
+		MinRunners:    1,
+		MaxRunners:    5,
+		AuthType:      types.AuthTypePAT,
+		AuthValue:     "test-token",
+	}
+
+	m := &Manager{}
+	got, err := m.generateYTTDataValues(installation, installation.Name, 0)
+	if err != nil {
+		t.Fatalf("generateYTTDataValues() error = %v", err)
+	}
+
+	if !strings.Contains(got, "runnerGroup: ci-runners") {
+		t.Errorf("generateYTTDataValues() output does not contain runnerGroup\nGot:\n%s", got)
+	}
+	if !strings.Contains(got, "githubConfigUrl: https://github.com/myorg") {
+		t.Errorf("generateYTTDataValues() output does not contain organization URL\nGot:\n%s", got)
+	}
+}
+
 func TestGenerateYTTDataValues_MinMaxRunners(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -178,3 +208,42 @@ func TestGenerateYTTDataValues_MinMaxRunners(t *testing.T) {
 		})
 	}
 }
+
+func TestRunParallel(t *testing.T) {
+	var mu sync.Mutex
+	var current, peak int
+	called := make([]bool, 6)
+
+	errs := RunParallel(6, 2, func(i int) error {
+		mu.Lock()
+		called[i] = true
+		current++
+		peak = max(peak, current)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		current--
+		mu.Unlock()
+		if i%3 == 2 {
+			return fmt.Errorf("call %d failed", i)
+		}
+		return nil
+	})
+
+	if err := errors.Join(errs...); err == nil || err.Error() != "call 2 failed\ncall 5 failed" {
+		t.Errorf("RunParallel() errors = %v, want the errors of calls 2 and 5", err)
+	}
+	if errs[2] == nil || errs[0] != nil {
+		t.Errorf("RunParallel() errors = %v, want them by index", errs)
+	}
+	if peak > 2 {
+		t.Errorf("RunParallel() ran %d calls at once, want at most 2", peak)
+	}
+	for i, ok := range called {
+		if !ok {
+			t.Errorf("RunParallel() did not call %d", i)
+		}
+	}
+}
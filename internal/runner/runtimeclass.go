@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RuntimeClasses returns the names of the RuntimeClasses of the cluster, e.g.
+// sysbox-runc when sysbox is installed on the nodes
+func (m *Manager) RuntimeClasses(ctx context.Context) ([]string, error) {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := clientset.NodeV1().RuntimeClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runtime classes: %w", err)
+	}
+
+	names := make([]string, 0, len(list.Items))
+	for _, runtimeClass := range list.Items {
+		names = append(names, runtimeClass.Name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// checkRuntimeClass verifies that the RuntimeClass runner pods run with
+// exists, as pods of a missing class are rejected by the API server
+func (m *Manager) checkRuntimeClass(ctx context.Context, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	available, err := m.RuntimeClasses(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Detected runtime classes", "runtimeClasses", available)
+	return runtimeClassError(name, available)
+}
+
+// runtimeClassError returns an error listing the available RuntimeClasses
+// when the named one is not among them
+func runtimeClassError(name string, available []string) error {
+	if slices.Contains(available, name) {
+		return nil
+	}
+	if len(available) == 0 {
+		return fmt.Errorf("runtime class %s not found: the cluster has no runtime classes; install the runtime (e.g. sysbox) on the nodes first", name)
+	}
+	return fmt.Errorf("runtime class %s not found, available: %s", name, strings.Join(available, ", "))
+}
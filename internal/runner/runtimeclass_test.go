@@ -0,0 +1,22 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuntimeClassError(t *testing.T) {
+	if err := runtimeClassError("sysbox-runc", []string{"gvisor", "sysbox-runc"}); err != nil {
+		t.Errorf("runtimeClassError() = %v, want nil", err)
+	}
+
+	err := runtimeClassError("sysbox-runc", []string{"gvisor", "kata"})
+	if err == nil || !strings.Contains(err.Error(), "available: gvisor, kata") {
+		t.Errorf("runtimeClassError() = %v, want the available classes", err)
+	}
+
+	err = runtimeClassError("sysbox-runc", nil)
+	if err == nil || !strings.Contains(err.Error(), "no runtime classes") {
+		t.Errorf("runtimeClassError() = %v, want no runtime classes", err)
+	}
+}
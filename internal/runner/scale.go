@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// listenerPodSelector matches the listener pods ARC creates for scale sets
+const listenerPodSelector = "app.kubernetes.io/component=runner-scale-set-listener"
+
+// ScaleScaleSet sets the minimum and maximum runners of a deployed runner
+// scale set in place. The listener picks up the new limits without a
+// redeploy; the next 'deskrun up' applies the configured limits again.
+func (m *Manager) ScaleScaleSet(ctx context.Context, name string, minRunners, maxRunners int) error {
+	if minRunners < 0 || maxRunners < minRunners {
+		return fmt.Errorf("invalid runner limits: min %d, max %d", minRunners, maxRunners)
+	}
+
+	minLimit, maxLimit := int64(minRunners), int64(maxRunners)
+	return m.setRunnerLimits(ctx, name, runnerLimits{MinRunners: &minLimit, MaxRunners: &maxLimit})
+}
+
+// runnerLimits are the minimum and maximum runners of a runner scale set, nil
+// when the AutoscalingRunnerSet doesn't set them
+type runnerLimits struct {
+	MinRunners *int64
+	MaxRunners *int64
+}
+
+// getRunnerLimits returns the runner limits a deployed runner scale set has
+func (m *Manager) getRunnerLimits(ctx context.Context, name string) (runnerLimits, error) {
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return runnerLimits{}, err
+	}
+
+	namespace, err := m.scaleSetNamespace(ctx, name)
+	if err != nil {
+		return runnerLimits{}, err
+	}
+	scaleSet, err := dynamicClient.Resource(autoscalingRunnerSetGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return runnerLimits{}, fmt.Errorf("runner scale set %s not found in namespace %s", name, namespace)
+		}
+		return runnerLimits{}, fmt.Errorf("failed to get runner scale set: %w", err)
+	}
+
+	var limits runnerLimits
+	if minRunners, found, _ := unstructured.NestedInt64(scaleSet.Object, "spec", "minRunners"); found {
+		limits.MinRunners = &minRunners
+	}
+	if maxRunners, found, _ := unstructured.NestedInt64(scaleSet.Object, "spec", "maxRunners"); found {
+		limits.MaxRunners = &maxRunners
+	}
+	return limits, nil
+}
+
+// setRunnerLimits patches the runner limits of a deployed runner scale set;
+// unset limits are removed from the AutoscalingRunnerSet
+func (m *Manager) setRunnerLimits(ctx context.Context, name string, limits runnerLimits) error {
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return err
+	}
+
+	patch, err := runnerLimitsPatch(limits)
+	if err != nil {
+		return err
+	}
+
+	namespace, err := m.scaleSetNamespace(ctx, name)
+	if err != nil {
+		return err
+	}
+	_, err = dynamicClient.Resource(autoscalingRunnerSetGVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("runner scale set %s not found in namespace %s", name, namespace)
+		}
+		return fmt.Errorf("failed to scale runner scale set %s: %w", name, err)
+	}
+	return nil
+}
+
+// PauseScaleSet scales a runner scale set down to zero runners while keeping
+// the scale set and its listener, so queued jobs wait for other runners or
+// until the scale set is scaled up again
+func (m *Manager) PauseScaleSet(ctx context.Context, name string) error {
+	return m.ScaleScaleSet(ctx, name, 0, 0)
+}
+
+// runnerLimitsPatch returns the merge patch setting the runner limits of an
+// AutoscalingRunnerSet. Unset limits are null, which removes them.
+func runnerLimitsPatch(limits runnerLimits) ([]byte, error) {
+	patch := map[string]any{
+		"spec": map[string]any{
+			"minRunners": limits.MinRunners,
+			"maxRunners": limits.MaxRunners,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patch: %w", err)
+	}
+	return data, nil
+}
+
+// RestartListener deletes the listener pods of a runner scale set. The ARC
+// controller recreates them, which recovers listeners that lost their
+// connection to GitHub.
+func (m *Manager) RestartListener(ctx context.Context, name string) error {
+	clientset, err := m.getKubernetesClient()
+	if err != nil {
+		return err
+	}
+
+	pods, err := clientset.CoreV1().Pods(arcControllerNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s,%s=%s", listenerPodSelector, scaleSetNameLabel, name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list listener pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no listener pod found for %s", name)
+	}
+
+	for _, pod := range pods.Items {
+		err := clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete listener pod %s: %w", pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// RestartIdleRunners deletes the EphemeralRunners of a runner scale set that
+// do not run a job, with their pods. ARC replaces them with runners that
+// register with GitHub again. It returns how many runners were restarted.
+func (m *Manager) RestartIdleRunners(ctx context.Context, name string) (int, error) {
+	runners, err := m.EphemeralRunners(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	dynamicClient, err := m.getDynamicClient()
+	if err != nil {
+		return 0, err
+	}
+
+	restarted := 0
+	for _, r := range runners {
+		if r.Busy() {
+			continue
+		}
+		err := dynamicClient.Resource(ephemeralRunnerGVR).Namespace(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return restarted, fmt.Errorf("failed to delete ephemeral runner %s: %w", r.Name, err)
+		}
+		restarted++
+	}
+	return restarted, nil
+}
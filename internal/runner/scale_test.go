@@ -0,0 +1,22 @@
+package runner
+
+import "testing"
+
+func TestRunnerLimitsPatch(t *testing.T) {
+	minRunners, maxRunners := int64(1), int64(5)
+	patch, err := runnerLimitsPatch(runnerLimits{MinRunners: &minRunners, MaxRunners: &maxRunners})
+	if err != nil {
+		t.Fatalf("runnerLimitsPatch() error = %v", err)
+	}
+	if want := `{"spec":{"maxRunners":5,"minRunners":1}}`; string(patch) != want {
+		t.Errorf("runnerLimitsPatch() = %s, want %s", patch, want)
+	}
+
+	patch, err = runnerLimitsPatch(runnerLimits{MaxRunners: &maxRunners})
+	if err != nil {
+		t.Fatalf("runnerLimitsPatch() error = %v", err)
+	}
+	if want := `{"spec":{"maxRunners":5,"minRunners":null}}`; string(patch) != want {
+		t.Errorf("runnerLimitsPatch() without minRunners = %s, want %s", patch, want)
+	}
+}
@@ -0,0 +1,233 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	secretsFileName = "secrets.json"
+	keyFileName     = "secrets.key"
+
+	// MasterKeyEnvVar can hold a base64 encoded 32 byte master key, in which
+	// case no key file is read or created
+	MasterKeyEnvVar = "DESKRUN_SECRET_KEY"
+
+	keySize = 32
+)
+
+// FileStore stores secrets in an encrypted file using envelope encryption:
+// every secret is sealed with a random data key, and the data key itself is
+// sealed with a master key kept in a separate file (or DESKRUN_SECRET_KEY).
+type FileStore struct {
+	path    string
+	keyPath string
+}
+
+// secretsFile is the on-disk format of the encrypted secrets file
+type secretsFile struct {
+	DataKey string            `json:"data_key"` // Data key sealed with the master key
+	Secrets map[string]string `json:"secrets"`  // Secrets sealed with the data key
+}
+
+// NewFileStore creates a store that keeps its files in dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{
+		path:    filepath.Join(dir, secretsFileName),
+		keyPath: filepath.Join(dir, keyFileName),
+	}
+}
+
+// Get returns the secret stored under key
+func (s *FileStore) Get(key string) (string, error) {
+	file, dataKey, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, ok := file.Secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	value, err := open(dataKey, sealed, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret %s: %w", key, err)
+	}
+	return string(value), nil
+}
+
+// Set stores value under key
+func (s *FileStore) Set(key, value string) error {
+	file, dataKey, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	sealed, err := seal(dataKey, []byte(value), key)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secret %s: %w", key, err)
+	}
+	file.Secrets[key] = sealed
+
+	return s.save(file)
+}
+
+// Delete removes the secret stored under key
+func (s *FileStore) Delete(key string) error {
+	file, _, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := file.Secrets[key]; !ok {
+		return ErrNotFound
+	}
+	delete(file.Secrets, key)
+
+	return s.save(file)
+}
+
+// load reads the secrets file and unseals its data key. A missing file
+// yields an empty secrets file with a new data key.
+func (s *FileStore) load() (*secretsFile, []byte, error) {
+	masterKey, err := s.masterKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		dataKey := make([]byte, keySize)
+		if _, err := rand.Read(dataKey); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+		}
+		sealedKey, err := seal(masterKey, dataKey, "data-key")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt data key: %w", err)
+		}
+		return &secretsFile{DataKey: sealedKey, Secrets: make(map[string]string)}, dataKey, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	var file secretsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse secrets file: %w", err)
+	}
+	if file.Secrets == nil {
+		file.Secrets = make(map[string]string)
+	}
+
+	dataKey, err := open(masterKey, file.DataKey, "data-key")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt data key, was the master key changed? %w", err)
+	}
+
+	return &file, dataKey, nil
+}
+
+// save atomically writes the secrets file
+func (s *FileStore) save(file *secretsFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets file: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+
+	return nil
+}
+
+// masterKey returns the master key from DESKRUN_SECRET_KEY or the key file,
+// creating the key file on first use
+func (s *FileStore) masterKey() ([]byte, error) {
+	if encoded := os.Getenv(MasterKeyEnvVar); encoded != "" {
+		return decodeKey(encoded, MasterKeyEnvVar)
+	}
+
+	data, err := os.ReadFile(s.keyPath)
+	if err == nil {
+		return decodeKey(string(data), s.keyPath)
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read master key: %w", err)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %w", err)
+	}
+	if err := os.WriteFile(s.keyPath, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write master key: %w", err)
+	}
+
+	return key, nil
+}
+
+// decodeKey decodes a base64 encoded 32 byte key
+func decodeKey(encoded, source string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid master key in %s: must be %d base64 encoded bytes", source, keySize)
+	}
+	return key, nil
+}
+
+// seal encrypts plaintext with AES-256-GCM, binding it to the given label,
+// and returns the base64 encoded nonce and ciphertext
+func seal(key, plaintext []byte, label string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, []byte(label))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a value produced by seal with the same label
+func open(key []byte, encoded, label string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, []byte(label))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
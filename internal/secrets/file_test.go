@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of missing secret error = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Set("installation/my-runner/auth-value", "ghp_secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// A new store instance reads the same secrets back
+	value, err := NewFileStore(dir).Get("installation/my-runner/auth-value")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if value != "ghp_secret" {
+		t.Errorf("Get() = %v, want ghp_secret", value)
+	}
+
+	// The secret must not be stored in clear text
+	data, err := os.ReadFile(filepath.Join(dir, secretsFileName))
+	if err != nil {
+		t.Fatalf("failed to read secrets file: %v", err)
+	}
+	if strings.Contains(string(data), "ghp_secret") {
+		t.Error("secrets file contains the plaintext secret")
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, keyFileName)); err != nil {
+		t.Errorf("master key file not created: %v", err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("master key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if err := store.Delete("installation/my-runner/auth-value"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("installation/my-runner/auth-value"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreMasterKeyFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	key := base64.StdEncoding.EncodeToString(make([]byte, keySize))
+	t.Setenv(MasterKeyEnvVar, key)
+
+	if err := NewFileStore(dir).Set("token", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, keyFileName)); !os.IsNotExist(err) {
+		t.Error("master key file created although DESKRUN_SECRET_KEY is set")
+	}
+
+	// A different master key cannot decrypt the data key
+	other := make([]byte, keySize)
+	other[0] = 1
+	t.Setenv(MasterKeyEnvVar, base64.StdEncoding.EncodeToString(other))
+	if _, err := NewFileStore(dir).Get("token"); err == nil {
+		t.Error("Get() with a different master key succeeded, want error")
+	}
+
+	t.Setenv(MasterKeyEnvVar, "too-short")
+	if _, err := NewFileStore(dir).Get("token"); err == nil {
+		t.Error("Get() with an invalid master key succeeded, want error")
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	dir := t.TempDir()
+
+	if store, err := NewStore(BackendPlaintext, dir); err != nil || store != nil {
+		t.Errorf("NewStore(plaintext) = %v, %v, want nil store", store, err)
+	}
+
+	if store, err := NewStore(BackendFile, dir); err != nil || store == nil {
+		t.Errorf("NewStore(file) = %v, %v, want file store", store, err)
+	}
+
+	if _, err := NewStore("vault", dir); err == nil {
+		t.Error("NewStore(vault) error = nil, want error for unknown backend")
+	}
+}
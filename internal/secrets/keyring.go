@@ -0,0 +1,60 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+const keyringService = "deskrun"
+
+// KeyringStore stores secrets in the OS keychain
+type KeyringStore struct{}
+
+// NewKeyringStore creates a store backed by the OS keychain
+func NewKeyringStore() *KeyringStore {
+	return &KeyringStore{}
+}
+
+// Get returns the secret stored under key
+func (s *KeyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(keyringService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from keyring: %w", err)
+	}
+	return value, nil
+}
+
+// Set stores value under key
+func (s *KeyringStore) Set(key, value string) error {
+	if err := keyring.Set(keyringService, key, value); err != nil {
+		return fmt.Errorf("failed to write secret to keyring: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the secret stored under key
+func (s *KeyringStore) Delete(key string) error {
+	err := keyring.Delete(keyringService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete secret from keyring: %w", err)
+	}
+	return nil
+}
+
+// keyringAvailable checks whether the OS keychain can be written to
+func keyringAvailable() bool {
+	const probeKey = "deskrun-probe"
+	if err := keyring.Set(keyringService, probeKey, "probe"); err != nil {
+		return false
+	}
+	_ = keyring.Delete(keyringService, probeKey)
+	return true
+}
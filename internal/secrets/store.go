@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Backend names a secret storage backend
+type Backend string
+
+const (
+	// BackendKeyring stores secrets in the OS keychain (Secret Service, macOS Keychain, Windows Credential Manager)
+	BackendKeyring Backend = "keyring"
+	// BackendFile stores secrets in an encrypted file next to the config
+	BackendFile Backend = "file"
+	// BackendPlaintext keeps secrets in the config file, as deskrun did originally
+	BackendPlaintext Backend = "plaintext"
+)
+
+// BackendEnvVar selects the backend for configs that don't have one yet
+const BackendEnvVar = "DESKRUN_SECRET_STORE"
+
+// ErrNotFound is returned when a secret does not exist in the store
+var ErrNotFound = errors.New("secret not found")
+
+// Store persists secret values outside of the plain-text config
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// NewStore creates a store for the given backend. The file backend keeps its
+// files in dir. The plaintext backend has no store and returns nil.
+func NewStore(backend Backend, dir string) (Store, error) {
+	switch backend {
+	case BackendKeyring:
+		return NewKeyringStore(), nil
+	case BackendFile:
+		return NewFileStore(dir), nil
+	case BackendPlaintext:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown secret store backend: %s (valid: keyring, file, plaintext)", backend)
+	}
+}
+
+// DefaultBackend returns the backend to use for a new config: the backend
+// named by DESKRUN_SECRET_STORE, otherwise the OS keychain if it is usable
+// and the encrypted file as a fallback (e.g. on headless hosts without a
+// Secret Service).
+func DefaultBackend() Backend {
+	if backend := os.Getenv(BackendEnvVar); backend != "" {
+		return Backend(backend)
+	}
+
+	if keyringAvailable() {
+		return BackendKeyring
+	}
+	return BackendFile
+}
@@ -0,0 +1,125 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/k14s/ytt/pkg/files"
+)
+
+// authValuePlaceholder is rendered in place of the PAT or GitHub App private
+// key of an installation, so cached renders never hold credentials. The
+// rendered Secret gets the real value after rendering.
+const authValuePlaceholder = "deskrun-auth-value-placeholder"
+
+var (
+	renderCacheMu sync.Mutex
+	// renderCache holds the output of the renders of this process by the hash
+	// of their inputs
+	renderCache = map[string][]byte{}
+)
+
+// renderKey hashes the ytt input files, including the data values, the
+// controller version the output is validated against and the output version,
+// so renders cached by another version of the processor are not reused
+func renderKey(inputFiles []*files.File, controllerVersion string) (string, error) {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s\x00%s\x00", OutputVersion, controllerVersion)
+	for _, file := range files.NewSortedFiles(inputFiles) {
+		content, err := file.Bytes()
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(hash, "%s\x00%d\x00", file.RelativePath(), len(content))
+		_, _ = hash.Write(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// authValueInput returns the auth value the templates are rendered with
+func authValueInput(authValue string) string {
+	if authValue == "" {
+		return ""
+	}
+	return authValuePlaceholder
+}
+
+// injectAuthValue replaces the base64 encoded placeholder the Secret of a
+// rendered scale set holds with the encoded auth value
+func injectAuthValue(rendered []byte, authValue string) []byte {
+	if authValue == "" {
+		return rendered
+	}
+	placeholder := base64.StdEncoding.EncodeToString([]byte(authValuePlaceholder))
+	encoded := base64.StdEncoding.EncodeToString([]byte(authValue))
+	return bytes.ReplaceAll(rendered, []byte(placeholder), []byte(encoded))
+}
+
+// cachedRender returns the output of an earlier render with the same key,
+// from memory or from the cache directory of the processor
+func (p *Processor) cachedRender(key string) ([]byte, bool) {
+	renderCacheMu.Lock()
+	rendered, ok := renderCache[key]
+	renderCacheMu.Unlock()
+	if ok {
+		return bytes.Clone(rendered), true
+	}
+	if p.cacheDir == "" {
+		return nil, false
+	}
+
+	rendered, err := os.ReadFile(filepath.Join(p.cacheDir, key+".yaml"))
+	if err != nil {
+		return nil, false
+	}
+	renderCacheMu.Lock()
+	renderCache[key] = rendered
+	renderCacheMu.Unlock()
+	return bytes.Clone(rendered), true
+}
+
+// storeRender caches the output of a render in memory and in the cache
+// directory of the processor. Failing to write the cache directory only
+// costs a render next time, so it is not an error.
+func (p *Processor) storeRender(key string, rendered []byte) {
+	renderCacheMu.Lock()
+	renderCache[key] = bytes.Clone(rendered)
+	renderCacheMu.Unlock()
+	if p.cacheDir == "" {
+		return
+	}
+
+	if err := writeCacheFile(p.cacheDir, key+".yaml", rendered); err != nil {
+		slog.Debug("Failed to cache rendered manifests", "dir", p.cacheDir, "error", err)
+	}
+}
+
+// writeCacheFile writes a file to the cache directory through a temporary
+// file, so concurrent renders never read a partial file. Only the user can
+// read the cache: renders hold the settings of the installations.
+func writeCacheFile(dir, name string, content []byte) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
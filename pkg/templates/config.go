@@ -2,8 +2,12 @@ package templates
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"strings"
 
 	"github.com/rkoster/deskrun/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // TemplateType represents the type of template to process
@@ -14,8 +18,65 @@ const (
 	TemplateTypeController TemplateType = "controller"
 	// TemplateTypeScaleSet is the runner scale-set template
 	TemplateTypeScaleSet TemplateType = "scale-set"
+	// TemplateTypeCacheServer is the in-cluster actions cache server template
+	TemplateTypeCacheServer TemplateType = "cache-server"
+	// TemplateTypeObservability is the Prometheus and Grafana metrics stack template
+	TemplateTypeObservability TemplateType = "observability"
 )
 
+// defaultRunnerImage is the upstream runner image used when no override is configured
+const defaultRunnerImage = "ghcr.io/actions/actions-runner:latest"
+
+// defaultDinDImage is the upstream dind sidecar image used when no override is configured
+const defaultDinDImage = "docker:dind"
+
+// defaultDinDRootlessImage is the upstream dind sidecar image of dind-rootless mode
+const defaultDinDRootlessImage = "docker:dind-rootless"
+
+// archNodeLabel is the well-known node label holding the CPU architecture
+const archNodeLabel = "kubernetes.io/arch"
+
+// defaultCacheClaimSize is the size of the persistent volume claims backing
+// caches when the installation's cache storage leaves it empty
+const defaultCacheClaimSize = "10Gi"
+
+// Cache server defaults, used when the cluster's cache server settings leave them empty
+const (
+	// CacheServerName names the cache server resources (volume, Deployment and Service)
+	CacheServerName = "deskrun-actions-cache"
+	// CacheServerPort is the port the cache server Service listens on
+	CacheServerPort = 3000
+
+	defaultCacheServerImage    = "ghcr.io/falcondev-oss/github-actions-cache-server:latest"
+	defaultCacheServerHostPath = "/var/lib/deskrun/actions-cache"
+	defaultCacheServerSize     = "50Gi"
+)
+
+// Observability defaults, used when the cluster's observability settings leave them empty
+const (
+	// ObservabilityName names the metrics stack resources and prefixes the Grafana ones
+	ObservabilityName = "deskrun-observability"
+	// PrometheusNodePort is the NodePort the Prometheus UI and API are exposed on
+	PrometheusNodePort = 30090
+	// GrafanaNodePort is the NodePort Grafana is exposed on
+	GrafanaNodePort = 30300
+	// DefaultPrometheusPort is the host port Prometheus is exposed on by default
+	DefaultPrometheusPort = 9090
+	// DefaultGrafanaPort is the host port Grafana is exposed on by default
+	DefaultGrafanaPort = 3000
+	// MetricsPort is the port the ARC controller and listeners serve metrics on
+	MetricsPort = 8080
+
+	defaultPrometheusImage     = "quay.io/prometheus/prometheus:v3.5.0"
+	defaultGrafanaImage        = "docker.io/grafana/grafana:12.1.1"
+	defaultPrometheusRetention = "7d"
+)
+
+// clusterNoProxy lists the in-cluster destinations that always bypass the proxy, so
+// runners, listeners and the controller keep reaching the Kubernetes API and services
+// (kind's default service and pod subnets)
+const clusterNoProxy = "localhost,127.0.0.1,.svc,.cluster.local,10.96.0.0/12,10.244.0.0/16"
+
 // Config contains configuration for template processing
 type Config struct {
 	// Runner installation configuration
@@ -26,7 +87,26 @@ type Config struct {
 	InstanceNum  int
 
 	// Optional: Override defaults
-	Namespace string // default: "arc-systems"
+	Namespace         string // default: "arc-systems"
+	ControllerVersion string // default: DefaultControllerVersion (controller template only)
+
+	// Proxy is injected into the controller Deployment (controller template only;
+	// scale sets use Installation.Proxy)
+	Proxy *types.ProxyConfig
+
+	// CacheServer configures the actions cache server (cache-server template only)
+	CacheServer *types.CacheServerConfig
+
+	// Observability configures the metrics stack (observability template only)
+	Observability *types.ObservabilityConfig
+
+	// Metrics exposes the metrics of the ARC controller and its listeners on
+	// MetricsPort (controller template only)
+	Metrics bool
+
+	// DockerSocketGroup is the group owning the Docker socket of the host,
+	// which runner pods of host-docker mode join (0 = none)
+	DockerSocketGroup int
 }
 
 // Validate validates the configuration
@@ -46,12 +126,158 @@ func (c *Config) Validate() error {
 
 	// Validate container mode is one of the known values
 	switch c.Installation.ContainerMode {
-	case types.ContainerModeKubernetes, types.ContainerModeDinD, types.ContainerModePrivileged:
+	case types.ContainerModeKubernetes, types.ContainerModeDinD, types.ContainerModeDinDRootless, types.ContainerModeHostDocker, types.ContainerModePrivileged:
 		// Valid
 	default:
-		return fmt.Errorf("invalid container mode: %s (must be one of: kubernetes, dind, cached-privileged-kubernetes)", c.Installation.ContainerMode)
+		return fmt.Errorf("invalid container mode: %s (must be one of: kubernetes, dind, dind-rootless, host-docker, cached-privileged-kubernetes)", c.Installation.ContainerMode)
+	}
+
+	if namespace := c.Installation.Namespace; namespace != "" {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("invalid namespace %s: %s", namespace, strings.Join(errs, ", "))
+		}
+	}
+
+	if name := c.Installation.RuntimeClassName; name != "" {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("invalid runtime class %s: %s", name, strings.Join(errs, ", "))
+		}
+	}
+
+	switch c.Installation.Arch {
+	case "", types.ArchAMD64, types.ArchARM64:
+	default:
+		return fmt.Errorf("invalid architecture %s: must be one of: %s, %s", c.Installation.Arch, types.ArchAMD64, types.ArchARM64)
+	}
+
+	if err := ValidatePodSecurity(c.Installation); err != nil {
+		return err
+	}
+
+	if err := ValidateNetworkPolicy(c.Installation.NetworkPolicy, c.Installation.ContainerMode); err != nil {
+		return err
+	}
+
+	if err := ValidateDinDConfig(c.Installation.DinD); err != nil {
+		return err
+	}
+
+	if err := ValidateMetadata(c.Installation.Labels, c.Installation.Annotations); err != nil {
+		return err
+	}
+
+	if err := ValidateValues(c.Installation.Values); err != nil {
+		return fmt.Errorf("invalid values: %w", err)
+	}
+
+	return nil
+}
+
+// ValidatePodSecurity checks the Pod Security Standards profile of an
+// installation. The other modes need privileged containers, and the profile
+// is enforced by a label of the namespace, which arc-systems can't have as
+// the controller and other installations run there.
+func ValidatePodSecurity(installation *types.RunnerInstallation) error {
+	switch installation.PodSecurity {
+	case "":
+		return nil
+	case types.PodSecurityBaseline, types.PodSecurityRestricted:
+	default:
+		return fmt.Errorf("invalid pod security profile '%s': must be baseline or restricted", installation.PodSecurity)
+	}
+
+	if installation.ContainerMode != types.ContainerModeKubernetes {
+		return fmt.Errorf("pod security profiles need kubernetes mode: %s mode runs privileged containers", installation.ContainerMode)
+	}
+	if installation.GetNamespace() == types.DefaultNamespace {
+		return fmt.Errorf("pod security profiles are enforced by namespace, so they need a namespace other than %s", types.DefaultNamespace)
+	}
+	return nil
+}
+
+// ValidateDinDConfig checks the daemon.json settings of the dind sidecar:
+// the MTU must be a valid interface MTU, registry mirrors http(s) URLs and
+// insecure registries host[:port] or CIDR values
+func ValidateDinDConfig(dind *types.DinDConfig) error {
+	if dind == nil {
+		return nil
+	}
+	if dind.MTU != 0 && (dind.MTU < 68 || dind.MTU > 65535) {
+		return fmt.Errorf("invalid dind MTU %d: must be between 68 and 65535", dind.MTU)
+	}
+	for _, mirror := range dind.RegistryMirrors {
+		parsed, err := url.Parse(mirror)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid registry mirror '%s': expected an http(s) URL, e.g. https://mirror.gcr.io", mirror)
+		}
+	}
+	for _, registry := range dind.InsecureRegistries {
+		if strings.Contains(registry, "/") {
+			if _, _, err := net.ParseCIDR(registry); err != nil {
+				return fmt.Errorf("invalid insecure registry '%s': expected host[:port] or a CIDR", registry)
+			}
+			continue
+		}
+		if registry == "" || strings.ContainsAny(registry, " \t") {
+			return fmt.Errorf("invalid insecure registry '%s': expected host[:port] or a CIDR", registry)
+		}
 	}
+	return nil
+}
 
+// ValidateNetworkPolicy checks the network policy of an installation. Job
+// pods of kubernetes mode are created by the container hooks without the
+// label the policy selects, so restricting them needs dind mode, where jobs
+// run in the runner pod, or privileged mode, whose hook extension labels them.
+func ValidateNetworkPolicy(policy *types.NetworkPolicyConfig, mode types.ContainerMode) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.Mode != types.NetworkPolicyRestricted {
+		return fmt.Errorf("invalid network policy '%s': must be restricted", policy.Mode)
+	}
+	if mode == types.ContainerModeKubernetes {
+		return fmt.Errorf("network policies need dind or cached-privileged-kubernetes mode: job pods of kubernetes mode would not be restricted")
+	}
+	if mode == types.ContainerModeHostDocker {
+		return fmt.Errorf("network policies can't restrict host-docker mode: job containers run on the Docker daemon of the host")
+	}
+	for _, cidr := range policy.AllowCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid egress CIDR '%s': expected e.g. 192.168.1.0/24", cidr)
+		}
+	}
+	return nil
+}
+
+// reservedMetadataPrefixes are the label and annotation prefixes of ARC, Helm
+// and kapp, which select and track the rendered resources by them
+var reservedMetadataPrefixes = []string{"actions.github.com/", "app.kubernetes.io/", "helm.sh/", "kapp.k14s.io/"}
+
+// ValidateMetadata checks the labels and annotations of an installation:
+// keys and label values must be valid Kubernetes metadata, and keys may not
+// use the prefixes ARC, Helm and kapp rely on.
+func ValidateMetadata(labels, annotations map[string]string) error {
+	for _, kind := range []struct {
+		name   string
+		values map[string]string
+	}{{"label", labels}, {"annotation", annotations}} {
+		for key, value := range kind.values {
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return fmt.Errorf("invalid %s key %s: %s", kind.name, key, strings.Join(errs, ", "))
+			}
+			for _, prefix := range reservedMetadataPrefixes {
+				if strings.HasPrefix(key, prefix) {
+					return fmt.Errorf("%s %s is reserved: keys may not start with %s", kind.name, key, prefix)
+				}
+			}
+			if kind.name == "label" {
+				if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+					return fmt.Errorf("invalid value of label %s: %s", key, strings.Join(errs, ", "))
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -63,6 +289,20 @@ func (c *Config) GetNamespace() string {
 	return c.Namespace
 }
 
+// GetControllerVersion returns the ARC controller chart version, using
+// DefaultControllerVersion as default
+func (c *Config) GetControllerVersion() string {
+	if c.ControllerVersion == "" {
+		return DefaultControllerVersion
+	}
+	return c.ControllerVersion
+}
+
+// CacheServerURL returns the in-cluster URL of the actions cache server in a namespace
+func CacheServerURL(namespace string) string {
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d/", CacheServerName, namespace, CacheServerPort)
+}
+
 // ErrorType represents the type of template processing error
 type ErrorType string
 
@@ -0,0 +1,31 @@
+// Package templates renders the Kubernetes manifests deskrun deploys: the ARC
+// controller, runner scale sets, the actions cache server and the metrics
+// stack. Templates are embedded and processed with the ytt Go library, so
+// rendering needs neither a cluster nor external binaries.
+//
+// Tools that want the same manifests as deskrun, e.g. to generate them in CI,
+// render a scale set with RenderScaleSet:
+//
+//	processor := templates.NewProcessor()
+//	manifests, err := processor.RenderScaleSet(&types.RunnerInstallation{
+//		Name:          "ci-runner",
+//		Repository:    "https://github.com/my-org/my-repo",
+//		ContainerMode: types.ContainerModeKubernetes,
+//		AuthType:      types.AuthTypePAT,
+//		AuthValue:     token,
+//	}, templates.Options{
+//		Namespace: "ci",
+//		Labels:    map[string]string{"team": "platform"},
+//	})
+//
+// The rendered output is versioned by OutputVersion. The golden files in
+// testdata/expected pin the output of every container mode, so changes to it
+// are deliberate and show up in review.
+package templates
+
+// OutputVersion is the version of the manifests the Processor renders,
+// following semantic versioning: the major version changes when the same
+// inputs render different resources or fields, the minor version when new
+// settings render new fields, and the patch version for fixes that only
+// change the output of broken settings.
+const OutputVersion = "1.0.0"
@@ -4,9 +4,13 @@ import (
 	"embed"
 	"fmt"
 	"io/fs"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/rkoster/deskrun/pkg/types"
+	"golang.org/x/mod/semver"
 )
 
 // Embedded ARC templates using ytt format
@@ -59,15 +63,51 @@ func GetTemplateFiles() (map[string]string, error) {
 	return files, nil
 }
 
-// GetControllerChart returns the controller chart YAML
+// DefaultControllerVersion is the ARC controller chart version deployed when
+// no version is pinned
+const DefaultControllerVersion = "0.13.0"
+
+// ControllerVersions returns the embedded ARC controller chart versions, oldest first.
+// Every version is rendered to templates/controller/<version>/rendered.yaml.
+func ControllerVersions() ([]string, error) {
+	entries, err := embeddedFS.ReadDir("templates/controller")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() && semver.IsValid("v"+entry.Name()) {
+			versions = append(versions, entry.Name())
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return semver.Compare("v"+versions[i], "v"+versions[j]) < 0
+	})
+	return versions, nil
+}
+
+// GetControllerChart returns the controller chart YAML of the default version
 func GetControllerChart() (string, error) {
-	content, err := embeddedFS.ReadFile("templates/controller/rendered.yaml")
+	return GetControllerChartVersion(DefaultControllerVersion)
+}
+
+// GetControllerChartVersion returns the controller chart YAML of an embedded version
+func GetControllerChartVersion(version string) (string, error) {
+	content, err := embeddedFS.ReadFile(controllerChartPath(version))
 	if err != nil {
-		return "", err
+		versions, _ := ControllerVersions()
+		return "", fmt.Errorf("ARC controller version %s is not embedded (available: %s)", version, strings.Join(versions, ", "))
 	}
 	return string(content), nil
 }
 
+// controllerChartPath returns the embedded path of a controller chart version
+func controllerChartPath(version string) string {
+	return path.Join("templates/controller", version, "rendered.yaml")
+}
+
 // GetControllerOverlay returns the controller overlay file
 func GetControllerOverlay() (string, error) {
 	content, err := embeddedFS.ReadFile("templates/controller/overlay.yaml")
@@ -86,6 +126,33 @@ func GetUniversalOverlay() (string, error) {
 	return string(content), nil
 }
 
+// GetCacheServerTemplate returns the actions cache server template
+func GetCacheServerTemplate() (string, error) {
+	content, err := embeddedFS.ReadFile("templates/cache-server/cache-server.yaml")
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// GetObservabilityTemplate returns the Prometheus and Grafana metrics stack template
+func GetObservabilityTemplate() (string, error) {
+	content, err := embeddedFS.ReadFile("templates/observability/observability.yaml")
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// GetGrafanaDashboard returns the Grafana dashboard of the runner metrics
+func GetGrafanaDashboard() (string, error) {
+	content, err := embeddedFS.ReadFile("templates/observability/dashboard.json")
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
 // GetSchema returns the data values schema
 func GetSchema() (string, error) {
 	content, err := embeddedFS.ReadFile("templates/values/schema.yaml")
@@ -96,13 +163,15 @@ func GetSchema() (string, error) {
 }
 
 // GetScaleSetBase returns the base template for the specified container mode.
-// This enables runtime selection of the appropriate helm-rendered template.
+// This enables runtime selection of the appropriate helm-rendered template; the
+// bases reference the installation data values (scripts/generate-base-templates.sh).
 func GetScaleSetBase(containerMode types.ContainerMode) (string, error) {
 	var basePath string
 	switch containerMode {
 	case types.ContainerModeKubernetes:
 		basePath = "templates/scale-set/bases/kubernetes.yaml"
-	case types.ContainerModeDinD:
+	case types.ContainerModeDinD, types.ContainerModeDinDRootless, types.ContainerModeHostDocker:
+		// The rootless daemon and the host socket are configured by the overlay
 		basePath = "templates/scale-set/bases/dind.yaml"
 	case types.ContainerModePrivileged:
 		basePath = "templates/scale-set/bases/privileged.yaml"
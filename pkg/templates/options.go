@@ -0,0 +1,101 @@
+package templates
+
+import (
+	"github.com/rkoster/deskrun/pkg/types"
+)
+
+// Options are the settings of RenderScaleSet that are not part of the
+// installation. Empty fields keep the settings of the installation.
+type Options struct {
+	// InstanceName names the scale set (default: the installation name)
+	InstanceName string
+	// InstanceNum numbers the instance of an installation with multiple
+	// instances, starting at 1 (default: 0 for a single instance)
+	InstanceNum int
+
+	// Namespace overrides the namespace of the installation
+	Namespace string
+	// Labels and Annotations are added to the AutoscalingRunnerSet, over the
+	// labels and annotations of the installation's values
+	Labels      map[string]string
+	Annotations map[string]string
+	// Image overrides the runner image and DinDImage the dind sidecar image
+	Image     string
+	DinDImage string
+
+	// ControllerVersion is the ARC controller version whose CRD schemas the
+	// output is validated against (default: DefaultControllerVersion)
+	ControllerVersion string
+
+	// DockerSocketGroup is the group owning the Docker socket of the host in
+	// host-docker mode (default: no supplemental group)
+	DockerSocketGroup int
+}
+
+// RenderScaleSet renders the runner scale set manifests of an installation,
+// the way deskrun deploys them. The installation is not modified.
+func (p *Processor) RenderScaleSet(installation *types.RunnerInstallation, opts Options) ([]byte, error) {
+	config, err := opts.config(installation)
+	if err != nil {
+		return nil, err
+	}
+	return p.ProcessTemplate(TemplateTypeScaleSet, config)
+}
+
+// config returns the processing configuration of an installation with the
+// options applied to a copy of it
+func (o Options) config(installation *types.RunnerInstallation) (Config, error) {
+	if installation == nil {
+		return Config{}, NewTemplateError(ErrorTypeValidation, "installation configuration is required", nil)
+	}
+
+	applied := *installation
+	if o.Namespace != "" {
+		applied.Namespace = o.Namespace
+	}
+	if o.Image != "" {
+		applied.Image = o.Image
+	}
+	if o.DinDImage != "" {
+		var dind types.DinDConfig
+		if applied.DinD != nil {
+			dind = *applied.DinD
+		}
+		dind.Image = o.DinDImage
+		applied.DinD = &dind
+	}
+
+	metadata := map[string]any{}
+	if len(o.Labels) > 0 {
+		metadata["labels"] = stringValues(o.Labels)
+	}
+	if len(o.Annotations) > 0 {
+		metadata["annotations"] = stringValues(o.Annotations)
+	}
+	if len(metadata) > 0 {
+		applied.Values = MergeValues(applied.Values, metadata)
+	}
+
+	instanceName := o.InstanceName
+	if instanceName == "" {
+		instanceName = applied.Name
+	}
+
+	return Config{
+		Installation:      &applied,
+		InstanceName:      instanceName,
+		InstanceNum:       o.InstanceNum,
+		Namespace:         applied.GetNamespace(),
+		ControllerVersion: o.ControllerVersion,
+		DockerSocketGroup: o.DockerSocketGroup,
+	}, nil
+}
+
+// stringValues converts a string map to data values
+func stringValues(values map[string]string) map[string]any {
+	result := make(map[string]any, len(values))
+	for key, value := range values {
+		result[key] = value
+	}
+	return result
+}
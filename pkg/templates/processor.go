@@ -1,26 +1,47 @@
 package templates
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	cmdtpl "github.com/k14s/ytt/pkg/cmd/template"
 	"github.com/k14s/ytt/pkg/cmd/ui"
 	"github.com/k14s/ytt/pkg/files"
+	"github.com/rkoster/deskrun/pkg/types"
 	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 )
 
-// Processor handles template processing using the ytt Go library
-type Processor struct{}
+// Processor handles template processing using the ytt Go library. Renders
+// are cached in memory by the hash of their inputs, so rendering the same
+// installation again skips ytt.
+type Processor struct {
+	cacheDir string
+}
 
 // NewProcessor creates a new template processor
 func NewProcessor() *Processor {
 	return &Processor{}
 }
 
+// WithCacheDir also caches renders in a directory, so they are reused by
+// later deskrun runs. An empty directory only caches in memory.
+func (p *Processor) WithCacheDir(dir string) *Processor {
+	p.cacheDir = dir
+	return p
+}
+
 // ProcessTemplate processes templates based on the template type and configuration
 // This is the main API for the unified template processing package
 func (p *Processor) ProcessTemplate(templateType TemplateType, config Config) ([]byte, error) {
@@ -34,12 +55,62 @@ func (p *Processor) ProcessTemplate(templateType TemplateType, config Config) ([
 		return p.processControllerTemplate(config)
 	case TemplateTypeScaleSet:
 		return p.processScaleSetTemplate(config)
+	case TemplateTypeCacheServer:
+		return p.processCacheServerTemplate(config)
+	case TemplateTypeObservability:
+		return p.processObservabilityTemplate(config)
 	default:
 		return nil, NewTemplateError(ErrorTypeValidation,
 			fmt.Sprintf("unknown template type: %s", templateType), nil)
 	}
 }
 
+// ProcessTemplateToObjects processes templates like ProcessTemplate and also
+// returns the rendered resources as objects, in document order, so callers
+// can inspect or modify them without parsing the YAML themselves
+func (p *Processor) ProcessTemplateToObjects(templateType TemplateType, config Config) ([]unstructured.Unstructured, []byte, error) {
+	rendered, err := p.ProcessTemplate(templateType, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	objects, err := ParseObjects(rendered)
+	if err != nil {
+		return nil, rendered, NewTemplateError(ErrorTypeData, "failed to parse rendered manifests", err)
+	}
+	return objects, rendered, nil
+}
+
+// ParseObjects parses multi-document YAML manifests into objects, skipping
+// empty documents
+func ParseObjects(manifests []byte) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifests)))
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := utilyaml.ToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || string(trimmed) == "null" {
+			continue
+		}
+
+		var object unstructured.Unstructured
+		if err := object.UnmarshalJSON(data); err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		objects = append(objects, object)
+	}
+}
+
 // GetRawTemplate returns the raw template content without processing
 // For scale-set templates, this returns the kubernetes base template as the default
 func (p *Processor) GetRawTemplate(templateType TemplateType) ([]byte, error) {
@@ -57,6 +128,18 @@ func (p *Processor) GetRawTemplate(templateType TemplateType) ([]byte, error) {
 			return nil, NewTemplateError(ErrorTypeIO, "failed to read scale-set template", err)
 		}
 		return []byte(content), nil
+	case TemplateTypeCacheServer:
+		content, err := GetCacheServerTemplate()
+		if err != nil {
+			return nil, NewTemplateError(ErrorTypeIO, "failed to read cache server template", err)
+		}
+		return []byte(content), nil
+	case TemplateTypeObservability:
+		content, err := GetObservabilityTemplate()
+		if err != nil {
+			return nil, NewTemplateError(ErrorTypeIO, "failed to read observability template", err)
+		}
+		return []byte(content), nil
 	default:
 		return nil, NewTemplateError(ErrorTypeValidation,
 			fmt.Sprintf("unknown template type: %s", templateType), nil)
@@ -65,10 +148,11 @@ func (p *Processor) GetRawTemplate(templateType TemplateType) ([]byte, error) {
 
 // processControllerTemplate processes the ARC controller template with overlays
 func (p *Processor) processControllerTemplate(config Config) ([]byte, error) {
-	content, err := GetControllerChart()
+	version := config.GetControllerVersion()
+	content, err := GetControllerChartVersion(version)
 	if err != nil {
 		return nil, NewTemplateError(ErrorTypeIO, "failed to read controller template", err).
-			WithTemplate("controller/rendered.yaml")
+			WithTemplate(fmt.Sprintf("controller/%s/rendered.yaml", version))
 	}
 
 	// Get the controller overlay
@@ -91,10 +175,143 @@ func (p *Processor) processControllerTemplate(config Config) ([]byte, error) {
 	)
 	inputFiles = append(inputFiles, overlayFile)
 
+	dataValuesYAML, err := marshalDataValues(map[string]any{
+		"controller": map[string]any{
+			"proxyEnv":    proxyEnv(config.Proxy),
+			"metrics":     config.Metrics,
+			"metricsPort": MetricsPort,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dataValuesFile := files.MustNewFileFromSource(
+		files.NewBytesSource("data-values.yaml", dataValuesYAML),
+	)
+	dataValuesFile.MarkType(files.TypeYAML)
+	inputFiles = append(inputFiles, dataValuesFile)
+
 	// Process with ytt library
 	return p.processWithYttLibrary(inputFiles, config)
 }
 
+// processCacheServerTemplate processes the actions cache server template
+func (p *Processor) processCacheServerTemplate(config Config) ([]byte, error) {
+	content, err := GetCacheServerTemplate()
+	if err != nil {
+		return nil, NewTemplateError(ErrorTypeIO, "failed to read cache server template", err).
+			WithTemplate("cache-server/cache-server.yaml")
+	}
+
+	templateFile := files.MustNewFileFromSource(
+		files.NewBytesSource("cache-server.yaml", []byte(content)),
+	)
+
+	dataValuesYAML, err := marshalDataValues(map[string]any{
+		"cacheServer": cacheServerValues(config.CacheServer, config.GetNamespace()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dataValuesFile := files.MustNewFileFromSource(
+		files.NewBytesSource("data-values.yaml", dataValuesYAML),
+	)
+	dataValuesFile.MarkType(files.TypeYAML)
+
+	return p.processWithYttLibrary([]*files.File{templateFile, dataValuesFile}, config)
+}
+
+// cacheServerValues returns the cache server data values, filling in the
+// defaults for settings that are not configured
+func cacheServerValues(cacheServer *types.CacheServerConfig, namespace string) map[string]any {
+	settings := types.CacheServerConfig{}
+	if cacheServer != nil {
+		settings = *cacheServer
+	}
+	if settings.Image == "" {
+		settings.Image = defaultCacheServerImage
+	}
+	if settings.HostPath == "" {
+		settings.HostPath = defaultCacheServerHostPath
+	}
+	if settings.Size == "" {
+		settings.Size = defaultCacheServerSize
+	}
+
+	return map[string]any{
+		"name":      CacheServerName,
+		"namespace": namespace,
+		"image":     settings.Image,
+		"hostPath":  settings.HostPath,
+		"size":      settings.Size,
+		"port":      CacheServerPort,
+		"url":       CacheServerURL(namespace),
+	}
+}
+
+// processObservabilityTemplate processes the Prometheus and Grafana metrics stack template
+func (p *Processor) processObservabilityTemplate(config Config) ([]byte, error) {
+	content, err := GetObservabilityTemplate()
+	if err != nil {
+		return nil, NewTemplateError(ErrorTypeIO, "failed to read observability template", err).
+			WithTemplate("observability/observability.yaml")
+	}
+
+	dashboard, err := GetGrafanaDashboard()
+	if err != nil {
+		return nil, NewTemplateError(ErrorTypeIO, "failed to read Grafana dashboard", err).
+			WithTemplate("observability/dashboard.json")
+	}
+
+	templateFile := files.MustNewFileFromSource(
+		files.NewBytesSource("observability.yaml", []byte(content)),
+	)
+
+	dataValuesYAML, err := marshalDataValues(map[string]any{
+		"observability": observabilityValues(config.Observability, config.GetNamespace(), dashboard),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dataValuesFile := files.MustNewFileFromSource(
+		files.NewBytesSource("data-values.yaml", dataValuesYAML),
+	)
+	dataValuesFile.MarkType(files.TypeYAML)
+
+	return p.processWithYttLibrary([]*files.File{templateFile, dataValuesFile}, config)
+}
+
+// observabilityValues returns the metrics stack data values, filling in the
+// defaults for settings that are not configured
+func observabilityValues(observability *types.ObservabilityConfig, namespace, dashboard string) map[string]any {
+	settings := types.ObservabilityConfig{}
+	if observability != nil {
+		settings = *observability
+	}
+	if settings.Retention == "" {
+		settings.Retention = defaultPrometheusRetention
+	}
+
+	return map[string]any{
+		"name":      ObservabilityName,
+		"namespace": namespace,
+		"prometheus": map[string]any{
+			"image":     defaultPrometheusImage,
+			"retention": settings.Retention,
+			"nodePort":  PrometheusNodePort,
+		},
+		"grafana": map[string]any{
+			"enabled":   settings.Grafana,
+			"image":     defaultGrafanaImage,
+			"nodePort":  GrafanaNodePort,
+			"dashboard": dashboard,
+		},
+	}
+}
+
 // processScaleSetTemplate processes the scale-set template with ytt overlays
 func (p *Processor) processScaleSetTemplate(config Config) ([]byte, error) {
 	// Build input files for ytt
@@ -104,25 +321,27 @@ func (p *Processor) processScaleSetTemplate(config Config) ([]byte, error) {
 	}
 
 	// Process with ytt library
-	return p.processWithYttLibrary(inputFiles, config)
+	rendered, err := p.processWithYttLibrary(inputFiles, config)
+	if err != nil {
+		return nil, err
+	}
+	return injectAuthValue(rendered, config.Installation.AuthValue), nil
 }
 
 // buildInputFiles creates the input files for ytt processing
 func (p *Processor) buildInputFiles(config Config) ([]*files.File, error) {
 	var inputFiles []*files.File
 
-	// 1. Get the base scale-set template based on container mode (runtime selection)
+	// 1. Get the base scale-set template based on container mode (runtime
+	// selection). The bases reference the data values themselves.
 	scaleSetContent, err := GetScaleSetBase(config.Installation.ContainerMode)
 	if err != nil {
 		return nil, NewTemplateError(ErrorTypeIO, "failed to read scale-set base template", err).
 			WithTemplate(fmt.Sprintf("scale-set/bases/%s.yaml", config.Installation.ContainerMode))
 	}
 
-	// Transform static values to ytt data value expressions
-	transformedTemplate := p.transformTemplateForYtt(scaleSetContent)
-
 	templateFile := files.MustNewFileFromSource(
-		files.NewBytesSource("scale-set.yaml", []byte(transformedTemplate)),
+		files.NewBytesSource("scale-set.yaml", []byte(scaleSetContent)),
 	)
 	inputFiles = append(inputFiles, templateFile)
 
@@ -138,7 +357,22 @@ func (p *Processor) buildInputFiles(config Config) ([]*files.File, error) {
 	)
 	inputFiles = append(inputFiles, overlayFile)
 
-	// 3. Create data values file
+	// 3. Add the user's overlays, named so they sort after the universal
+	// overlay and ytt errors still mention the file
+	for i, path := range config.Installation.Overlays {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, NewTemplateError(ErrorTypeIO, "failed to read overlay", err).
+				WithTemplate(path)
+		}
+
+		userOverlayFile := files.MustNewFileFromSource(
+			files.NewBytesSource(userOverlayName(i, path), content),
+		)
+		inputFiles = append(inputFiles, userOverlayFile)
+	}
+
+	// 4. Create data values file
 	dataValuesYAML, err := p.buildDataValues(config)
 	if err != nil {
 		return nil, err
@@ -154,72 +388,157 @@ func (p *Processor) buildInputFiles(config Config) ([]*files.File, error) {
 	return inputFiles, nil
 }
 
-// transformTemplateForYtt transforms static template values to ytt data value expressions
-func (p *Processor) transformTemplateForYtt(templateContent string) string {
-	// Replace static values with ytt data value expressions - be specific to avoid partial matches
-	result := strings.ReplaceAll(templateContent, "https://github.com/example/repo", "#@ data.values.installation.repository")
-	result = strings.ReplaceAll(result, "arc-runner-gha-rs-github-secret", "#@ data.values.installation.name + \"-gha-rs-github-secret\"")
-	result = strings.ReplaceAll(result, "arc-runner-gha-rs-no-permission", "#@ data.values.installation.name + \"-gha-rs-no-permission\"")
-	result = strings.ReplaceAll(result, "arc-runner-gha-rs-manager", "#@ data.values.installation.name + \"-gha-rs-manager\"")
-	result = strings.ReplaceAll(result, "arc-runner-gha-rs-kube-mode", "#@ data.values.installation.name + \"-gha-rs-kube-mode\"")
-
-	// Replace remaining arc-runner references (labels, names, etc.)
-	result = strings.ReplaceAll(result, "\"arc-runner\"", "#@ data.values.installation.name")
-	result = strings.ReplaceAll(result, ": arc-runner", ": #@ data.values.installation.name")
-	result = strings.ReplaceAll(result, "name: arc-runner", "name: #@ data.values.installation.name")
-	result = strings.ReplaceAll(result, "cGxhY2Vob2xkZXI=", "#@ base64.encode(data.values.installation.authValue)")
-
-	// Add ytt load directive at the beginning of the file
-	result = "#@ load(\"@ytt:data\", \"data\")\n#@ load(\"@ytt:base64\", \"base64\")\n" + result
-
-	return result
+// userOverlayName returns the ytt file name of the i-th user overlay
+func userOverlayName(i int, path string) string {
+	base := filepath.Base(path)
+	return fmt.Sprintf("user-overlay-%02d-%s.yaml", i, strings.TrimSuffix(base, filepath.Ext(base)))
 }
 
 // buildDataValues creates the ytt data values YAML from the configuration
 func (p *Processor) buildDataValues(config Config) ([]byte, error) {
 	// Convert cache paths to simple map format for easier ytt access (deprecated, for backward compatibility)
-	var cachePaths []map[string]string
+	var cachePaths []map[string]any
 	for _, cp := range config.Installation.CachePaths {
-		cachePaths = append(cachePaths, map[string]string{
+		cachePaths = append(cachePaths, map[string]any{
 			"target": cp.Target,
-			"source": cp.Source,
+			"source": config.Installation.CachePathSource(cp, config.InstanceNum),
+			"claim":  config.Installation.ClaimsCachePath(cp),
 		})
 	}
 
 	// If no cache paths, use empty array (not nil) for ytt
 	if cachePaths == nil {
-		cachePaths = []map[string]string{}
+		cachePaths = []map[string]any{}
 	}
 
 	// Convert mounts to map format for ytt access
-	var mounts []map[string]string
+	var mounts []map[string]any
 	for _, m := range config.Installation.Mounts {
-		mounts = append(mounts, map[string]string{
+		mounts = append(mounts, map[string]any{
 			"target": m.Target,
-			"source": m.Source,
+			"source": config.Installation.MountSource(m, config.InstanceNum),
 			"type":   string(m.Type),
+			"claim":  config.Installation.ClaimsMount(m),
 		})
 	}
 
 	// If no mounts, use empty array (not nil) for ytt
 	if mounts == nil {
-		mounts = []map[string]string{}
+		mounts = []map[string]any{}
 	}
 
-	dataValues := map[string]any{
-		"installation": map[string]any{
-			"name":          config.InstanceName,
-			"repository":    config.Installation.Repository,
-			"authValue":     config.Installation.AuthValue,
-			"containerMode": string(config.Installation.ContainerMode),
-			"minRunners":    config.Installation.MinRunners,
-			"maxRunners":    config.Installation.MaxRunners,
-			"cachePaths":    cachePaths, // Deprecated, for backward compatibility
-			"mounts":        mounts,
-			"instanceNum":   config.InstanceNum,
+	var dindResources *types.ResourceRequirements
+	if config.Installation.DinD != nil {
+		dindResources = config.Installation.DinD.Resources
+	}
+
+	var listenerResources *types.ResourceRequirements
+	if config.Installation.Listener != nil {
+		listenerResources = config.Installation.Listener.Resources
+	}
+
+	ephemeralStorage := map[string]string{"request": "", "limit": ""}
+	if es := config.Installation.EphemeralStorage; es != nil {
+		ephemeralStorage["request"] = es.Request
+		ephemeralStorage["limit"] = es.Limit
+	}
+
+	installation := map[string]any{
+		"name":             config.InstanceName,
+		"namespace":        config.GetNamespace(),
+		"repository":       config.Installation.Repository,
+		"runnerGroup":      config.Installation.RunnerGroup,
+		"authType":         string(config.Installation.AuthType),
+		"authValue":        authValueInput(config.Installation.AuthValue),
+		"containerMode":    string(config.Installation.ContainerMode),
+		"image":            runnerImage(config.Installation),
+		"resources":        runnerResourcesToMap(config.Installation.Resources, config.Installation.EphemeralStorage),
+		"minRunners":       config.Installation.MinRunners,
+		"maxRunners":       config.Installation.MaxRunners,
+		"cachePaths":       cachePaths, // Deprecated, for backward compatibility
+		"mounts":           mounts,
+		"instanceNum":      config.InstanceNum,
+		"cacheStorage":     cacheStorageValues(config.Installation.CacheStorage),
+		"ephemeralStorage": ephemeralStorage,
+		"githubApp": map[string]string{
+			"appId":          formatGitHubAppID(config.Installation.GitHubAppID),
+			"installationId": formatGitHubAppID(config.Installation.GitHubAppInstallationID),
+		},
+		"dind": map[string]any{
+			"image":        dindImage(config.Installation),
+			"resources":    resourcesToMap(dindResources),
+			"daemonConfig": dindDaemonConfig(config.Installation.DinD),
 		},
+		"listener": map[string]any{
+			"resources": resourcesToMap(listenerResources),
+		},
+		"proxyEnv":          proxyEnv(config.Installation.Proxy),
+		"env":               envVarsToList(config.Installation.EnvVars),
+		"runtimeClassName":  config.Installation.RuntimeClassName,
+		"nodeSelector":      nodeSelectorToMap(config.Installation.NodeSelector, config.Installation.Arch),
+		"tolerations":       tolerationsToList(config.Installation.Tolerations),
+		"affinity":          affinityToMap(config.Installation.Affinity),
+		"objectMounts":      objectMountsToList(config.Installation.ObjectMounts),
+		"imagePullSecret":   config.Installation.ImagePullSecret,
+		"imagePullPolicy":   config.Installation.ImagePullPolicy,
+		"actionsCache":      actionsCacheValues(config.Installation.ActionsCacheURL),
+		"podSecurity":       string(config.Installation.PodSecurity),
+		"hostDocker":        map[string]any{"socketGroup": config.DockerSocketGroup},
+		"privilegedHook":    privilegedHookValues(),
+		"networkPolicy":     networkPolicyValues(config.Installation.NetworkPolicy),
+		"commonLabels":      metadataToMap(config.Installation.Labels),
+		"commonAnnotations": metadataToMap(config.Installation.Annotations),
+		// Only set through the installation's values
+		"labels":           map[string]any{},
+		"annotations":      map[string]any{},
+		"listenerTemplate": map[string]any{},
+		"extra":            map[string]any{},
+	}
+
+	dataValues := map[string]any{
+		"installation": MergeValues(installation, config.Installation.Values),
 	}
+	return marshalDataValues(dataValues)
+}
+
+// privilegedHookCapabilities are the Linux capabilities the job containers of
+// the privileged hook extension get unless an installation's values set them
+var privilegedHookCapabilities = []string{
+	"SYS_ADMIN", "NET_ADMIN", "SYS_PTRACE", "SYS_CHROOT",
+	"SETFCAP", "SETPCAP", "NET_RAW", "IPC_LOCK",
+	"SYS_RESOURCE", "MKNOD", "AUDIT_WRITE", "AUDIT_CONTROL",
+}
+
+// privilegedHookValues returns the default privilegedHook data values, the job
+// pod spec of the privileged hook extension
+func privilegedHookValues() map[string]any {
+	return map[string]any{
+		"hostPID":      true,
+		"capabilities": privilegedHookCapabilities,
+		"extraMounts":  []map[string]string{},
+	}
+}
+
+// cacheStorageValues returns the cacheStorage data values, defaulting to
+// node directories and claims of defaultCacheClaimSize
+func cacheStorageValues(storage *types.CacheStorage) map[string]string {
+	values := map[string]string{
+		"type":         string(types.CacheTypeHostPath),
+		"storageClass": "",
+		"size":         defaultCacheClaimSize,
+	}
+	if storage.IsPVC() {
+		values["type"] = string(types.CacheTypePVC)
+		values["storageClass"] = storage.StorageClass
+		if storage.Size != "" {
+			values["size"] = storage.Size
+		}
+	}
+	return values
+}
 
+// marshalDataValues renders data values as a ytt data values document
+func marshalDataValues(dataValues map[string]any) ([]byte, error) {
 	yamlBytes, err := yaml.Marshal(dataValues)
 	if err != nil {
 		return nil, NewTemplateError(ErrorTypeData, "failed to marshal data values", err).
@@ -231,9 +550,301 @@ func (p *Processor) buildDataValues(config Config) ([]byte, error) {
 	return append([]byte(header), yamlBytes...), nil
 }
 
+// proxyEnv converts proxy settings to container env vars, in upper and lower case
+// because tools disagree on which ones they read. The in-cluster destinations are
+// always added to NO_PROXY. Returns an empty list (not nil) when no proxy is set.
+func proxyEnv(proxy *types.ProxyConfig) []map[string]string {
+	env := []map[string]string{}
+	if proxy.IsEmpty() {
+		return env
+	}
+
+	noProxy := clusterNoProxy
+	if proxy.NoProxy != "" {
+		noProxy = proxy.NoProxy + "," + clusterNoProxy
+	}
+
+	add := func(name, value string) {
+		if value == "" {
+			return
+		}
+		env = append(env,
+			map[string]string{"name": name, "value": value},
+			map[string]string{"name": strings.ToLower(name), "value": value},
+		)
+	}
+	add("HTTP_PROXY", proxy.HTTPProxy)
+	add("HTTPS_PROXY", proxy.HTTPSProxy)
+	add("NO_PROXY", noProxy)
+	return env
+}
+
+// formatGitHubAppID formats a GitHub App (installation) ID for the github secret,
+// returning an empty string when unset
+func formatGitHubAppID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// dindDaemonConfig returns the daemon.json settings of the dind sidecar,
+// empty when the daemon defaults are kept
+func dindDaemonConfig(dind *types.DinDConfig) map[string]any {
+	config := map[string]any{}
+	if !dind.HasDaemonConfig() {
+		return config
+	}
+	if dind.MTU != 0 {
+		config["mtu"] = dind.MTU
+	}
+	if len(dind.RegistryMirrors) > 0 {
+		config["registry-mirrors"] = dind.RegistryMirrors
+	}
+	if len(dind.InsecureRegistries) > 0 {
+		config["insecure-registries"] = dind.InsecureRegistries
+	}
+	return config
+}
+
+// resourcesToMap converts resource requirements to the Kubernetes resources map format,
+// omitting unset quantities. Returns an empty map (not nil) when nothing is set.
+func resourcesToMap(resources *types.ResourceRequirements) map[string]any {
+	result := map[string]any{}
+	if resources.IsEmpty() {
+		return result
+	}
+
+	toList := func(list types.ResourceList) map[string]string {
+		m := map[string]string{}
+		if list.CPU != "" {
+			m["cpu"] = list.CPU
+		}
+		if list.Memory != "" {
+			m["memory"] = list.Memory
+		}
+		return m
+	}
+
+	if requests := toList(resources.Requests); len(requests) > 0 {
+		result["requests"] = requests
+	}
+	if limits := toList(resources.Limits); len(limits) > 0 {
+		result["limits"] = limits
+	}
+	return result
+}
+
+// runnerResourcesToMap combines the cpu/memory resources of the runner container with
+// its ephemeral storage request/limit into a single Kubernetes resources map
+func runnerResourcesToMap(resources *types.ResourceRequirements, storage *types.EphemeralStorage) map[string]any {
+	result := resourcesToMap(resources)
+	if storage == nil {
+		return result
+	}
+
+	addQuantity := func(key, quantity string) {
+		if quantity == "" {
+			return
+		}
+		list, ok := result[key].(map[string]string)
+		if !ok {
+			list = map[string]string{}
+			result[key] = list
+		}
+		list["ephemeral-storage"] = quantity
+	}
+	addQuantity("requests", storage.Request)
+	addQuantity("limits", storage.Limit)
+	return result
+}
+
+// envVarsToList converts extra env vars to a Kubernetes env list sorted by name,
+// so the rendered manifests are stable. Returns an empty list (not nil) when none are set.
+func envVarsToList(envVars map[string]string) []map[string]string {
+	names := make([]string, 0, len(envVars))
+	for name := range envVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := []map[string]string{}
+	for _, name := range names {
+		env = append(env, map[string]string{"name": name, "value": envVars[name]})
+	}
+	return env
+}
+
+// nodeSelectorToMap returns the node selector of the runner pods, or an empty
+// map (not nil) when none is configured. The architecture of the installation
+// selects nodes by their kubernetes.io/arch label, unless the node selector
+// already does.
+func nodeSelectorToMap(nodeSelector map[string]string, arch types.Architecture) map[string]string {
+	result := make(map[string]string, len(nodeSelector)+1)
+	maps.Copy(result, nodeSelector)
+	if _, ok := result[archNodeLabel]; !ok && arch != "" {
+		result[archNodeLabel] = string(arch)
+	}
+	return result
+}
+
+// runnerImage returns the runner image of an installation, falling back to
+// the upstream default image
+func runnerImage(installation *types.RunnerInstallation) string {
+	if installation.Image != "" {
+		return installation.Image
+	}
+	return defaultRunnerImage
+}
+
+// dindImage returns the dind sidecar image of an installation, falling back
+// to the upstream default image of its mode
+func dindImage(installation *types.RunnerInstallation) string {
+	if installation.DinD != nil && installation.DinD.Image != "" {
+		return installation.DinD.Image
+	}
+	if installation.ContainerMode == types.ContainerModeDinDRootless {
+		return defaultDinDRootlessImage
+	}
+	return defaultDinDImage
+}
+
+// InstallationImages returns the images the runner pods of an installation
+// run: the runner image and, in dind modes, the dind sidecar image
+func InstallationImages(installation *types.RunnerInstallation) []string {
+	images := []string{runnerImage(installation)}
+	if installation.ContainerMode.UsesDinD() {
+		images = append(images, dindImage(installation))
+	}
+	return images
+}
+
+// networkPolicyValues returns the networkPolicy data values, with an empty
+// mode when no policy is configured
+func networkPolicyValues(policy *types.NetworkPolicyConfig) map[string]any {
+	values := map[string]any{"mode": "", "allowCIDRs": []string{}}
+	if policy != nil {
+		values["mode"] = string(policy.Mode)
+		if policy.AllowCIDRs != nil {
+			values["allowCIDRs"] = policy.AllowCIDRs
+		}
+	}
+	return values
+}
+
+// metadataToMap returns labels or annotations as data values. Returns an
+// empty map (not nil) when none are configured.
+func metadataToMap(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return map[string]string{}
+	}
+	return metadata
+}
+
+// tolerationsToList converts tolerations to the Kubernetes format, omitting
+// unset fields. Returns an empty list (not nil) when none are configured.
+func tolerationsToList(tolerations []types.Toleration) []map[string]string {
+	result := []map[string]string{}
+	for _, toleration := range tolerations {
+		m := map[string]string{"key": toleration.Key}
+		if toleration.Operator != "" {
+			m["operator"] = toleration.Operator
+		}
+		if toleration.Value != "" {
+			m["value"] = toleration.Value
+		}
+		if toleration.Effect != "" {
+			m["effect"] = string(toleration.Effect)
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// objectMountsToList converts secret and configmap mounts to the volume names,
+// kinds and paths the overlay renders. Returns an empty list (not nil) when none
+// are configured.
+func objectMountsToList(objectMounts []types.ObjectMount) []map[string]string {
+	result := []map[string]string{}
+	for i, objectMount := range objectMounts {
+		result = append(result, map[string]string{
+			"volume": fmt.Sprintf("object-mount-%d", i),
+			"kind":   string(objectMount.Kind),
+			"name":   objectMount.Name,
+			"path":   objectMount.Path,
+		})
+	}
+	return result
+}
+
+// actionsCacheVariables are the job variables GitHub sends to point the cache
+// toolkit at its hosted cache, mapped to the same-length names they are renamed
+// to in the runner so they can't override the cache server set on the container
+var actionsCacheVariables = [][2]string{
+	{"ACTIONS_CACHE_URL", "ACTIONS_CACHE_ORL"},
+	// Without ACTIONS_CACHE_SERVICE_V2 the toolkit uses the cache API at ACTIONS_CACHE_URL
+	{"ACTIONS_CACHE_SERVICE_V2", "ACTIONS_CACHE_SERVICE_X2"},
+}
+
+// actionsCacheValues returns the cache server URL of the runners and the
+// command that patches the runner so job variables don't override it. Both are
+// empty when the GitHub hosted cache is used.
+func actionsCacheValues(url string) map[string]string {
+	if url == "" {
+		return map[string]string{"url": "", "runnerPatch": ""}
+	}
+
+	expressions := make([]string, 0, len(actionsCacheVariables))
+	for _, variable := range actionsCacheVariables {
+		expressions = append(expressions, fmt.Sprintf("s/%s/%s/g", utf16Escape(variable[0]), utf16Escape(variable[1])))
+	}
+	return map[string]string{
+		"url":         url,
+		"runnerPatch": fmt.Sprintf("sed -i '%s' /home/runner/bin/Runner.Worker.dll", strings.Join(expressions, "; ")),
+	}
+}
+
+// utf16Escape returns the UTF-16LE bytes of an ASCII string as sed \x escapes,
+// the encoding .NET string literals are stored in
+func utf16Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		fmt.Fprintf(&b, `\x%02X\x00`, s[i])
+	}
+	return b.String()
+}
+
+// affinityToMap returns the affinity of the runner pods, or an empty map (not
+// nil) when none is configured
+func affinityToMap(affinity map[string]any) map[string]any {
+	if affinity == nil {
+		return map[string]any{}
+	}
+	return affinity
+}
+
 // processWithYttLibrary uses the ytt Go library to process templates
-// This is the key function that AVOIDS shell execution
+// This is the key function that AVOIDS shell execution. Renders with the same
+// inputs are reused from the cache.
 func (p *Processor) processWithYttLibrary(inputFiles []*files.File, config Config) ([]byte, error) {
+	key, err := renderKey(inputFiles, config.GetControllerVersion())
+	if err != nil {
+		return nil, NewTemplateError(ErrorTypeIO, "failed to read template inputs", err)
+	}
+	if rendered, ok := p.cachedRender(key); ok {
+		return rendered, nil
+	}
+
+	rendered, err := p.runYtt(inputFiles, config)
+	if err != nil {
+		return nil, err
+	}
+	p.storeRender(key, rendered)
+	return rendered, nil
+}
+
+// runYtt runs ytt on the input files and validates the rendered documents
+func (p *Processor) runYtt(inputFiles []*files.File, config Config) ([]byte, error) {
 	// Create ytt options
 	opts := cmdtpl.NewOptions()
 	opts.IgnoreUnknownComments = true
@@ -273,6 +884,10 @@ func (p *Processor) processWithYttLibrary(inputFiles []*files.File, config Confi
 			return nil, NewTemplateError(ErrorTypeUnknown,
 				fmt.Sprintf("failed to render document %d", i), err)
 		}
+		if err := validateDocument(docBytes, config.GetControllerVersion()); err != nil {
+			return nil, NewTemplateError(ErrorTypeValidation,
+				fmt.Sprintf("rendered document %d is invalid: %v", i, err), err)
+		}
 		result.Write(docBytes)
 	}
 
@@ -0,0 +1,283 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// strictDecoder decodes the built-in Kubernetes kinds, failing on unknown
+// and duplicate fields
+var strictDecoder = serializer.NewCodecFactory(scheme.Scheme, serializer.EnableStrict).UniversalDeserializer()
+
+// crdKey identifies a custom resource kind
+type crdKey struct {
+	apiVersion string
+	kind       string
+}
+
+var (
+	crdSchemasMu sync.Mutex
+	// crdSchemas caches the openAPIV3Schema of the ARC custom resources by
+	// controller version, parsed from the embedded controller charts
+	crdSchemas = map[string]map[crdKey]map[string]any{}
+)
+
+// validateDocument checks a rendered document against its Kubernetes API type
+// or the ARC CRD schema of the controller version, the way the API server
+// would, so template errors are reported with the offending field. Kinds
+// without a known schema, like the CRDs themselves, are not checked.
+func validateDocument(doc []byte, controllerVersion string) error {
+	var object map[string]any
+	if err := yaml.Unmarshal(doc, &object); err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(object) == 0 {
+		return nil
+	}
+
+	_, _, err := strictDecoder.Decode(doc, nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !runtime.IsNotRegisteredError(err) {
+		return fmt.Errorf("%s: %w", describeObject(object), err)
+	}
+
+	schemas, err := controllerCRDSchemas(controllerVersion)
+	if err != nil {
+		return err
+	}
+	apiVersion, _ := object["apiVersion"].(string)
+	kind, _ := object["kind"].(string)
+	crdSchema := schemas[crdKey{apiVersion: apiVersion, kind: kind}]
+	if crdSchema == nil {
+		return nil
+	}
+	if err := checkSchema("", crdSchema, object); err != nil {
+		return fmt.Errorf("%s: %w", describeObject(object), err)
+	}
+	return nil
+}
+
+// describeObject returns the kind and name of an object for error messages
+func describeObject(object map[string]any) string {
+	kind, _ := object["kind"].(string)
+	metadata, _ := object["metadata"].(map[string]any)
+	name, _ := metadata["name"].(string)
+	return fmt.Sprintf("%s %s", kind, name)
+}
+
+// controllerCRDSchemas returns the schemas of the custom resources defined by
+// an embedded controller chart version
+func controllerCRDSchemas(version string) (map[crdKey]map[string]any, error) {
+	crdSchemasMu.Lock()
+	defer crdSchemasMu.Unlock()
+	if schemas, ok := crdSchemas[version]; ok {
+		return schemas, nil
+	}
+
+	content, err := GetControllerChartVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := parseCRDSchemas(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CRDs of controller version %s: %w", version, err)
+	}
+	crdSchemas[version] = schemas
+	return schemas, nil
+}
+
+// crdDocument holds the fields of a CustomResourceDefinition that are
+// needed to validate its custom resources
+type crdDocument struct {
+	Kind string `yaml:"kind"`
+	Spec struct {
+		Group string `yaml:"group"`
+		Names struct {
+			Kind string `yaml:"kind"`
+		} `yaml:"names"`
+		Versions []struct {
+			Name   string `yaml:"name"`
+			Schema struct {
+				OpenAPIV3Schema map[string]any `yaml:"openAPIV3Schema"`
+			} `yaml:"schema"`
+		} `yaml:"versions"`
+	} `yaml:"spec"`
+}
+
+// parseCRDSchemas returns the openAPIV3Schema of every version of the
+// CustomResourceDefinitions in a multi-document manifest
+func parseCRDSchemas(manifest string) (map[crdKey]map[string]any, error) {
+	schemas := map[crdKey]map[string]any{}
+	decoder := yaml.NewDecoder(strings.NewReader(manifest))
+	for {
+		var crd crdDocument
+		if err := decoder.Decode(&crd); err != nil {
+			if errors.Is(err, io.EOF) {
+				return schemas, nil
+			}
+			return nil, err
+		}
+		if crd.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		for _, version := range crd.Spec.Versions {
+			key := crdKey{apiVersion: crd.Spec.Group + "/" + version.Name, kind: crd.Spec.Names.Kind}
+			schemas[key] = version.Schema.OpenAPIV3Schema
+		}
+	}
+}
+
+// checkSchema checks a value against a structural OpenAPI v3 schema of a
+// CRD: types, required and unknown fields, and enums. Formats and patterns are
+// left to the API server.
+func checkSchema(path string, schema map[string]any, value any) error {
+	if value == nil {
+		return nil
+	}
+	if schema["x-kubernetes-int-or-string"] == true {
+		if _, ok := value.(string); ok || isInteger(value) {
+			return nil
+		}
+		return fmt.Errorf("%s: expected an integer or a string", fieldPath(path))
+	}
+	if branches, ok := schema["anyOf"].([]any); ok && schema["type"] == nil {
+		var firstErr error
+		for _, branch := range branches {
+			branchSchema, _ := branch.(map[string]any)
+			err := checkSchema(path, branchSchema, value)
+			if err == nil {
+				return nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	switch schema["type"] {
+	case "object":
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object", fieldPath(path))
+		}
+		if err := checkObject(path, schema, fields); err != nil {
+			return err
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected a list", fieldPath(path))
+		}
+		itemSchema, _ := schema["items"].(map[string]any)
+		for i, item := range items {
+			if err := checkSchema(fmt.Sprintf("%s[%d]", path, i), itemSchema, item); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected a string, got %v", fieldPath(path), value)
+		}
+	case "integer":
+		if !isInteger(value) {
+			return fmt.Errorf("%s: expected an integer, got %v", fieldPath(path), value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok && !isInteger(value) {
+			return fmt.Errorf("%s: expected a number, got %v", fieldPath(path), value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected true or false, got %v", fieldPath(path), value)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		return fmt.Errorf("%s: unsupported value %v, must be one of %v", fieldPath(path), value, enum)
+	}
+	return nil
+}
+
+// checkObject checks the required and the known fields of an object. Fields
+// not in the schema are only allowed by additionalProperties or
+// x-kubernetes-preserve-unknown-fields, or when the schema lists no fields.
+func checkObject(path string, schema map[string]any, fields map[string]any) error {
+	properties, _ := schema["properties"].(map[string]any)
+	additional := schema["additionalProperties"]
+	additionalSchema, _ := additional.(map[string]any)
+	allowUnknown := additional == true || schema["x-kubernetes-preserve-unknown-fields"] == true ||
+		(properties == nil && additional == nil)
+
+	if required, ok := schema["required"].([]any); ok {
+		for _, item := range required {
+			name, _ := item.(string)
+			if _, ok := fields[name]; !ok {
+				return fmt.Errorf("%s: required field is missing", fieldPath(joinPath(path, name)))
+			}
+		}
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := joinPath(path, name)
+		var fieldSchema map[string]any
+		switch {
+		case properties[name] != nil:
+			fieldSchema, _ = properties[name].(map[string]any)
+		case additionalSchema != nil:
+			fieldSchema = additionalSchema
+		case allowUnknown:
+			continue
+		default:
+			return fmt.Errorf("%s: unknown field", fieldPath(field))
+		}
+		if err := checkSchema(field, fieldSchema, fields[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// joinPath appends a field name to a dotted field path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// fieldPath quotes a field path for error messages
+func fieldPath(path string) string {
+	if path == "" {
+		return "document"
+	}
+	return fmt.Sprintf("field %q", path)
+}
+
+// containsValue returns true if an enum lists a value
+func containsValue(enum []any, value any) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
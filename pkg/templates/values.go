@@ -0,0 +1,202 @@
+package templates
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidateValues checks extra data values of an installation against the
+// installation section of the embedded data values schema: every key must be
+// in the schema and every value must have the type of its default. Values of
+// keys marked any=True are not checked.
+func ValidateValues(values map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	schema, err := installationSchema()
+	if err != nil {
+		return err
+	}
+	return checkValue("", schema, "", values)
+}
+
+// MergeValues returns base with overrides deep merged into it: maps are
+// merged key by key, other values are replaced. Neither map is modified.
+func MergeValues(base, overrides map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(overrides))
+	maps.Copy(merged, base)
+	for key, value := range overrides {
+		baseMap, isBaseMap := valuesMap(merged[key])
+		overrideMap, isOverrideMap := value.(map[string]any)
+		if isBaseMap && isOverrideMap {
+			merged[key] = MergeValues(baseMap, overrideMap)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// valuesMap returns a data values map as a map of any values
+func valuesMap(value any) (map[string]any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		return v, true
+	case map[string]string:
+		result := make(map[string]any, len(v))
+		for key, item := range v {
+			result[key] = item
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// installationSchema returns the schema node of the installation data values
+func installationSchema() (*yaml.Node, error) {
+	content, err := GetSchema()
+	if err != nil {
+		return nil, NewTemplateError(ErrorTypeIO, "failed to read data values schema", err).
+			WithTemplate("values/schema.yaml")
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, NewTemplateError(ErrorTypeSyntax, "failed to parse data values schema", err).
+			WithTemplate("values/schema.yaml")
+	}
+	if len(doc.Content) == 0 {
+		return nil, NewTemplateError(ErrorTypeData, "data values schema is empty", nil).
+			WithTemplate("values/schema.yaml")
+	}
+	installation, _ := schemaField(doc.Content[0], "installation")
+	if installation == nil {
+		return nil, NewTemplateError(ErrorTypeData, "data values schema has no installation section", nil).
+			WithTemplate("values/schema.yaml")
+	}
+	return installation, nil
+}
+
+// schemaField returns the value node of a key of a schema mapping, and the
+// comment above the key with its schema annotations
+func schemaField(mapping *yaml.Node, key string) (*yaml.Node, string) {
+	if mapping.Kind != yaml.MappingNode {
+		return nil, ""
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1], mapping.Content[i].HeadComment
+		}
+	}
+	return nil, ""
+}
+
+// checkValue checks a value against its schema node. comment holds the
+// schema annotations of the node.
+func checkValue(path string, schema *yaml.Node, comment string, value any) error {
+	if strings.Contains(comment, "@schema/type any=True") {
+		return nil
+	}
+	if value == nil {
+		if strings.Contains(comment, "@schema/nullable") {
+			return nil
+		}
+		return fmt.Errorf("invalid value %s: must not be null", path)
+	}
+
+	switch schema.Kind {
+	case yaml.MappingNode:
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("invalid value %s: expected a map", path)
+		}
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			field, fieldComment := schemaField(schema, key)
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			if field == nil {
+				return fmt.Errorf("unknown value %s", fieldPath)
+			}
+			if err := checkValue(fieldPath, field, fieldComment, fields[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case yaml.SequenceNode:
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("invalid value %s: expected a list", path)
+		}
+		if len(schema.Content) == 0 {
+			return nil
+		}
+		for i, item := range items {
+			if err := checkValue(fmt.Sprintf("%s[%d]", path, i), schema.Content[0], "", item); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return checkScalar(path, schema.Tag, value)
+	}
+}
+
+// checkScalar checks that a value has the type of a schema default
+func checkScalar(path, tag string, value any) error {
+	var ok bool
+	var expected string
+	switch tag {
+	case "!!str", "!!null":
+		_, ok = value.(string)
+		expected = "a string"
+	case "!!bool":
+		_, ok = value.(bool)
+		expected = "true or false"
+	case "!!int":
+		ok = isInteger(value)
+		expected = "an integer"
+	case "!!float":
+		ok = isInteger(value)
+		if _, isFloat := value.(float64); isFloat {
+			ok = true
+		}
+		expected = "a number"
+	default:
+		return nil
+	}
+
+	if !ok {
+		return fmt.Errorf("invalid value %s: expected %s, got %v", path, expected, value)
+	}
+	return nil
+}
+
+// isInteger returns true for integers, including the whole floats integers
+// are decoded to from JSON
+func isInteger(value any) bool {
+	switch v := value.(type) {
+	case int, int64, uint64:
+		return true
+	case float64:
+		return v == math.Trunc(v)
+	default:
+		return false
+	}
+}
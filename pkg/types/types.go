@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"strings"
+)
+
 // ContainerMode represents the different container modes for runners
 type ContainerMode string
 
@@ -10,13 +15,25 @@ const (
 	ContainerModeDinD ContainerMode = "dind"
 	// ContainerModePrivileged is privileged kubernetes mode with special capabilities
 	ContainerModePrivileged ContainerMode = "cached-privileged-kubernetes"
+	// ContainerModeDinDRootless is Docker-in-Docker mode with a rootless daemon
+	ContainerModeDinDRootless ContainerMode = "dind-rootless"
+	// ContainerModeHostDocker uses the Docker daemon of the host through the
+	// socket mounted into the cluster node
+	ContainerModeHostDocker ContainerMode = "host-docker"
 )
 
+// UsesDinD returns true for the modes that run jobs in a Docker-in-Docker sidecar
+func (m ContainerMode) UsesDinD() bool {
+	return m == ContainerModeDinD || m == ContainerModeDinDRootless
+}
+
 // RunnerInstallation represents a runner installation configuration
 type RunnerInstallation struct {
 	Name          string
-	Repository    string
+	Repository    string // GitHub repository or organization URL
+	RunnerGroup   string // Runner group (organization-level only, empty = default group)
 	ContainerMode ContainerMode
+	Image         string // Runner container image (empty = ghcr.io/actions/actions-runner:latest)
 	MinRunners    int
 	MaxRunners    int
 	Instances     int // Number of separate runner scale set instances to create
@@ -24,6 +41,213 @@ type RunnerInstallation struct {
 	CachePaths    []CachePath // Deprecated: Use Mounts instead. Kept for backward compatibility.
 	AuthType      AuthType
 	AuthValue     string
+	// GitHubAppID and GitHubAppInstallationID identify the GitHub App (github-app auth only).
+	// AuthValue holds the app's private key in that case.
+	GitHubAppID             int64
+	GitHubAppInstallationID int64
+	DinD                    *DinDConfig // Optional dind sidecar overrides (dind modes only)
+	// Listener tunes the autoscaling listener pod of the scale sets
+	Listener *ListenerConfig
+	// Resources sets cpu/memory requests and limits on the runner container
+	Resources *ResourceRequirements
+	// EphemeralStorage sets ephemeral-storage requests/limits on runner and job containers
+	EphemeralStorage *EphemeralStorage
+	// Cluster is the name of the kind cluster to deploy to (empty = default cluster)
+	Cluster string
+	// Namespace is the Kubernetes namespace the runner scale sets, their
+	// secrets and RBAC are deployed to (empty = arc-systems)
+	Namespace string
+	// Paused keeps the scale sets deployed with zero runners, so no jobs are
+	// picked up until the installation is resumed
+	Paused bool
+	// Schedule overrides MinRunners and MaxRunners during recurring time
+	// windows, applied by 'deskrun agent' (the first matching window wins)
+	Schedule []ScheduleWindow
+	// Proxy overrides the proxy settings of the cluster for this installation
+	Proxy *ProxyConfig
+	// EnvVars are extra environment variables of the runner container
+	EnvVars map[string]string
+	// RuntimeClassName is the Kubernetes RuntimeClass the runner pods run with,
+	// e.g. sysbox-runc or gvisor (empty = the default runtime of the cluster)
+	RuntimeClassName string
+	// Arch is the CPU architecture of the nodes runner pods are scheduled on,
+	// e.g. arm64 (empty = any node)
+	Arch Architecture
+	// NodeSelector, Tolerations and Affinity control which nodes runner pods are scheduled on
+	NodeSelector map[string]string
+	Tolerations  []Toleration
+	// Affinity is a Kubernetes pod affinity object (nodeAffinity, podAffinity,
+	// podAntiAffinity), passed through to the runner pod template as is
+	Affinity map[string]any
+	// ObjectMounts are Kubernetes secrets and configmaps mounted into the runner container
+	ObjectMounts []ObjectMount
+	// CacheVolumes are the names of the shared cache volumes mounted into the
+	// runner container, see CacheVolume
+	CacheVolumes []string
+	// CacheStorage backs the mounts without an explicit host path with
+	// persistent volume claims instead of node directories (nil = node directories)
+	CacheStorage *CacheStorage
+	// Overlays are paths of ytt overlay files applied to the scale set
+	// manifests after deskrun's own overlay, in order
+	Overlays []string
+	// PodSecurity is the Pod Security Standards profile the runner and job
+	// pods comply with and the namespace enforces (empty = none)
+	PodSecurity PodSecurityLevel
+	// NetworkPolicy restricts the egress of the runner pods (nil = unrestricted)
+	NetworkPolicy *NetworkPolicyConfig
+	// Labels and Annotations are added to every resource rendered for the
+	// installation and to its runner pods
+	Labels      map[string]string
+	Annotations map[string]string
+	// Values are extra data values of the scale set templates, below
+	// installation (e.g. {"labels": {"team": "platform"}}). They override the
+	// values deskrun derives from the other settings.
+	Values map[string]any
+	// ImagePullSecret is the docker-registry secret runner and job images are
+	// pulled with. It is set at deploy time from the configured registries.
+	ImagePullSecret string `json:"-"`
+	// ActionsCacheURL is the cache server runners use for actions/cache (empty
+	// = GitHub hosted cache). It is set at deploy time when the cache server of
+	// the cluster is enabled.
+	ActionsCacheURL string `json:"-"`
+	// ImagePullPolicy is the pull policy of the runner pod containers (empty
+	// for the Kubernetes default). It is set at deploy time to IfNotPresent
+	// on offline clusters.
+	ImagePullPolicy string `json:"-"`
+}
+
+// DefaultNamespace is the namespace of the ARC controller and of installations
+// without a namespace of their own
+const DefaultNamespace = "arc-systems"
+
+// GetNamespace returns the namespace the installation is deployed to
+func (r *RunnerInstallation) GetNamespace() string {
+	if r.Namespace == "" {
+		return DefaultNamespace
+	}
+	return r.Namespace
+}
+
+// IsOrganizationLevel returns true if the installation targets a GitHub organization
+// (e.g. https://github.com/myorg) rather than a single repository
+func (r *RunnerInstallation) IsOrganizationLevel() bool {
+	return IsOrganizationURL(r.Repository)
+}
+
+// IsOrganizationURL returns true if the GitHub config URL points to an organization,
+// i.e. it has exactly one path segment after the host
+func IsOrganizationURL(configURL string) bool {
+	path := configURL
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+	}
+	path = strings.Trim(path, "/")
+	segments := strings.Split(path, "/")
+	return len(segments) == 2 && segments[1] != ""
+}
+
+// EphemeralStorage represents ephemeral-storage quantities (e.g. "10Gi") applied to
+// runner and job containers so a single workflow can't fill up the node's disk
+type EphemeralStorage struct {
+	Request string
+	Limit   string
+}
+
+// TolerationEffect is the taint effect a toleration matches
+type TolerationEffect string
+
+const (
+	TolerationEffectNoSchedule       TolerationEffect = "NoSchedule"
+	TolerationEffectPreferNoSchedule TolerationEffect = "PreferNoSchedule"
+	TolerationEffectNoExecute        TolerationEffect = "NoExecute"
+)
+
+// Toleration allows runner pods to be scheduled onto nodes with a matching taint
+type Toleration struct {
+	Key string `json:"key"`
+	// Operator is Equal (match Value) or Exists (match any value of Key)
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+	// Effect is the taint effect to match (empty matches all effects)
+	Effect TolerationEffect `json:"effect,omitempty"`
+}
+
+// RegistryCredential represents the login of a private container registry that
+// runner and job images are pulled from
+type RegistryCredential struct {
+	Name string `json:"name"`
+	// Server is the registry host, e.g. ghcr.io or registry.example.com:5000
+	Server   string `json:"server"`
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// ProxyConfig represents the HTTP(S) proxy runners, listeners and the ARC
+// controller use to reach GitHub
+type ProxyConfig struct {
+	HTTPProxy  string `json:"http_proxy,omitempty"`
+	HTTPSProxy string `json:"https_proxy,omitempty"`
+	// NoProxy is a comma-separated list of hosts, domains and CIDRs that bypass the proxy
+	NoProxy string `json:"no_proxy,omitempty"`
+}
+
+// IsEmpty returns true if no proxy is configured
+func (p *ProxyConfig) IsEmpty() bool {
+	return p == nil || (p.HTTPProxy == "" && p.HTTPSProxy == "")
+}
+
+// DinDConfig represents configuration for the Docker-in-Docker sidecar container
+type DinDConfig struct {
+	// Image overrides the dind sidecar image (empty means the upstream
+	// docker:dind or docker:dind-rootless default of the mode)
+	Image string
+	// Resources sets resource requests and limits on the dind sidecar container
+	Resources *ResourceRequirements
+	// MTU, RegistryMirrors and InsecureRegistries are written to the
+	// daemon.json of the dind daemon (zero values keep the daemon defaults)
+	MTU                int
+	RegistryMirrors    []string
+	InsecureRegistries []string
+}
+
+// ScheduleWindow sets the runner limits of an installation during a recurring
+// window of local time
+type ScheduleWindow struct {
+	Days       string // days of the week, e.g. mon-fri or sat,sun (* = every day)
+	Start      string // start of the window, HH:MM
+	End        string // end of the window, HH:MM; before Start for windows spanning midnight
+	MinRunners int
+	MaxRunners int
+}
+
+// ListenerConfig represents configuration for the autoscaling listener pod
+// the ARC controller creates for every scale set
+type ListenerConfig struct {
+	// Resources sets resource requests and limits on the listener container
+	Resources *ResourceRequirements
+}
+
+// HasDaemonConfig returns true if the dind daemon needs a daemon.json
+func (d *DinDConfig) HasDaemonConfig() bool {
+	return d != nil && (d.MTU != 0 || len(d.RegistryMirrors) > 0 || len(d.InsecureRegistries) > 0)
+}
+
+// ResourceRequirements represents Kubernetes resource requests and limits for a container
+type ResourceRequirements struct {
+	Requests ResourceList
+	Limits   ResourceList
+}
+
+// ResourceList represents a set of Kubernetes resource quantities (e.g. "500m", "2Gi").
+// Empty values are omitted from the rendered manifests.
+type ResourceList struct {
+	CPU    string
+	Memory string
+}
+
+// IsEmpty returns true if no resource quantity is set
+func (r *ResourceRequirements) IsEmpty() bool {
+	return r == nil || (r.Requests == ResourceList{} && r.Limits == ResourceList{})
 }
 
 // MountType represents the type of host mount
@@ -46,6 +270,27 @@ type Mount struct {
 	Target string
 	// Type specifies the hostPath volume type (defaults to DirectoryOrCreate)
 	Type MountType
+	// MaxSize is the size (e.g. 20Gi) above which 'deskrun cache gc' prunes the
+	// mounted directory (empty = unbounded)
+	MaxSize string `json:",omitempty"`
+}
+
+// ObjectKind is the kind of Kubernetes object mounted into runner pods
+type ObjectKind string
+
+const (
+	ObjectKindSecret    ObjectKind = "Secret"
+	ObjectKindConfigMap ObjectKind = "ConfigMap"
+)
+
+// ObjectMount represents a secret or configmap in the runner namespace that is
+// mounted read-only into the runner container
+type ObjectMount struct {
+	Kind ObjectKind `json:"kind"`
+	// Name of the secret or configmap
+	Name string `json:"name"`
+	// Path inside the runner container where the object's keys appear as files
+	Path string `json:"path"`
 }
 
 // CachePath represents a path to be cached using hostPath volumes
@@ -55,6 +300,190 @@ type CachePath struct {
 	Target string
 	// Source path on the host machine (empty means auto-generated)
 	Source string
+	// MaxSize is the size (e.g. 20Gi) above which 'deskrun cache gc' prunes the
+	// cache (empty = unbounded)
+	MaxSize string `json:",omitempty"`
+}
+
+// CacheType is the kind of volume backing the mounts of an installation that
+// have no explicit host path
+type CacheType string
+
+const (
+	// CacheTypeHostPath stores caches in directories on the cluster node
+	CacheTypeHostPath CacheType = "hostpath"
+	// CacheTypePVC stores caches in persistent volume claims, for clusters
+	// where node directories are unsafe or unavailable
+	CacheTypePVC CacheType = "pvc"
+)
+
+// PodSecurityLevel is a Pod Security Standards profile
+type PodSecurityLevel string
+
+const (
+	// PodSecurityBaseline forbids privileged pods, host namespaces and host paths
+	PodSecurityBaseline PodSecurityLevel = "baseline"
+	// PodSecurityRestricted also requires non-root containers without
+	// capabilities or privilege escalation and the RuntimeDefault seccomp profile
+	PodSecurityRestricted PodSecurityLevel = "restricted"
+)
+
+// Architecture is a CPU architecture of cluster nodes and container images,
+// named like the kubernetes.io/arch node label
+type Architecture string
+
+const (
+	// ArchAMD64 is the x86-64 architecture
+	ArchAMD64 Architecture = "amd64"
+	// ArchARM64 is the 64-bit ARM architecture, e.g. of Apple Silicon
+	ArchARM64 Architecture = "arm64"
+)
+
+// NetworkPolicyMode is the kind of NetworkPolicy rendered for the runner pods
+type NetworkPolicyMode string
+
+const (
+	// NetworkPolicyRestricted limits the egress of runner pods to DNS, the
+	// Kubernetes API, public HTTPS endpoints like GitHub, the actions cache
+	// server and the allowed CIDRs
+	NetworkPolicyRestricted NetworkPolicyMode = "restricted"
+)
+
+// NetworkPolicyConfig configures the NetworkPolicy of the runner pods of an installation
+type NetworkPolicyConfig struct {
+	Mode NetworkPolicyMode `json:"mode"`
+	// AllowCIDRs are extra destinations runner pods may reach on any port,
+	// e.g. a proxy or a service on the local network
+	AllowCIDRs []string `json:"allow_cidrs,omitempty"`
+}
+
+// CacheStorage configures the volumes backing the caches of an installation
+type CacheStorage struct {
+	Type CacheType `json:"type"`
+	// StorageClass of the claims (empty = the default storage class)
+	StorageClass string `json:"storage_class,omitempty"`
+	// Size requested by every claim, e.g. 20Gi (empty = 10Gi)
+	Size string `json:"size,omitempty"`
+}
+
+// IsPVC returns true if caches are backed by persistent volume claims
+func (s *CacheStorage) IsPVC() bool {
+	return s != nil && s.Type == CacheTypePVC
+}
+
+// GeneratedCacheSource returns the host path 'deskrun add --cache' picks for
+// a cache path given without a source
+func GeneratedCacheSource(target string) string {
+	return "/host-cache/deskrun/" + generatedSourceName(target)
+}
+
+// GeneratedMountSource returns the host path 'deskrun add --mount' picks for
+// a mount given without a source
+func GeneratedMountSource(target string) string {
+	return "/tmp/deskrun-cache/" + generatedSourceName(target)
+}
+
+// generatedSourceName turns a target path into a directory name by replacing
+// slashes with dashes and removing the leading slash
+func generatedSourceName(target string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(target, "/"), "/", "-")
+}
+
+// CacheClaimNames returns the names of the persistent volume claims backing
+// the caches of a scale set of the installation, see ClaimsMount
+func (r *RunnerInstallation) CacheClaimNames(scaleSet string) []string {
+	var names []string
+	for i, cachePath := range r.CachePaths {
+		if r.ClaimsCachePath(cachePath) {
+			names = append(names, fmt.Sprintf("%s-mount-%d", scaleSet, i))
+		}
+	}
+	for i, mount := range r.Mounts {
+		if r.ClaimsMount(mount) {
+			names = append(names, fmt.Sprintf("%s-mount-%d", scaleSet, i+len(r.CachePaths)))
+		}
+	}
+	return names
+}
+
+// InstancePlaceholder in the source path of a mount or cache path is replaced
+// by the instance number, so every instance of a multi-instance installation
+// gets a directory of its own, e.g. /nvme/docker-cache/instance-{instance}
+const InstancePlaceholder = "{instance}"
+
+// CachePathSource returns the host path of a cache path for the instance with
+// the given number (0 for single-instance installations), see InstanceSource
+func (r *RunnerInstallation) CachePathSource(cachePath CachePath, instanceNum int) string {
+	return InstanceSource(cachePath.Source, cachePath.Source == GeneratedCacheSource(cachePath.Target), instanceNum)
+}
+
+// MountSource returns the host path of a mount for the instance with the
+// given number (0 for single-instance installations), see InstanceSource
+func (r *RunnerInstallation) MountSource(mount Mount, instanceNum int) string {
+	return InstanceSource(mount.Source, mount.Source == GeneratedMountSource(mount.Target), instanceNum)
+}
+
+// InstanceSource returns the host path of a source path for the instance
+// with the given number (0 for single-instance installations). Instances are
+// numbered from 1, so InstancePlaceholder is replaced by 1 for a single
+// instance. Generated source paths get an instance-<n> subdirectory on
+// multi-instance installations, so concurrent Docker daemons never share a
+// data directory.
+func InstanceSource(source string, generated bool, instanceNum int) string {
+	if strings.Contains(source, InstancePlaceholder) {
+		return strings.ReplaceAll(source, InstancePlaceholder, fmt.Sprint(max(instanceNum, 1)))
+	}
+	if generated && instanceNum > 0 {
+		return fmt.Sprintf("%s/instance-%d", source, instanceNum)
+	}
+	return source
+}
+
+// ClaimsCachePath returns true if a cache path is backed by a persistent
+// volume claim: with --cache-type pvc, cache paths without a source or with
+// the generated one are
+func (r *RunnerInstallation) ClaimsCachePath(cachePath CachePath) bool {
+	return r.CacheStorage.IsPVC() && (cachePath.Source == "" || cachePath.Source == GeneratedCacheSource(cachePath.Target))
+}
+
+// ClaimsMount returns true if a mount is backed by a persistent volume claim:
+// with --cache-type pvc, directory mounts without a source or with the
+// generated one are
+func (r *RunnerInstallation) ClaimsMount(mount Mount) bool {
+	if !r.CacheStorage.IsPVC() || mount.Type == MountTypeSocket {
+		return false
+	}
+	return mount.Source == "" || mount.Source == GeneratedMountSource(mount.Target)
+}
+
+// CacheVolumeRoot is the directory on the cluster node that holds the shared
+// cache volumes. It is the deskrun cache directory of the host (~/.cache/deskrun).
+const CacheVolumeRoot = "/host-cache/deskrun/volumes"
+
+// CacheVolume is a named cache directory that several installations mount at
+// the same path, e.g. an npm cache shared by all runners
+type CacheVolume struct {
+	Name string `json:"name"`
+	// Path is where the volume is mounted inside the runner container
+	Path string `json:"path"`
+	// MaxSize is the size (e.g. 20Gi) above which 'deskrun cache gc' prunes the
+	// volume (empty = unbounded)
+	MaxSize string `json:"max_size,omitempty"`
+}
+
+// HostPath returns the directory on the cluster node backing the volume
+func (v *CacheVolume) HostPath() string {
+	return CacheVolumeRoot + "/" + v.Name
+}
+
+// Mount returns the hostPath mount of the volume into the runner container
+func (v *CacheVolume) Mount() Mount {
+	return Mount{
+		Source:  v.HostPath(),
+		Target:  v.Path,
+		Type:    MountTypeDirectoryOrCreate,
+		MaxSize: v.MaxSize,
+	}
 }
 
 // AuthType represents the authentication type
@@ -67,12 +496,17 @@ const (
 
 // ClusterConfig represents the kind cluster configuration
 type ClusterConfig struct {
-	Name         string
-	Network      string
-	NixStore     *ClusterMount // Optional nix store mount
-	NixSocket    *ClusterMount // Optional nix socket mount
-	DeskrunCache *ClusterMount // Optional deskrun cache mount
-	DockerSocket *ClusterMount // Optional docker socket mount
+	Name              string
+	Network           string
+	Provider          string         // Optional cluster provider: kind (default), k3d or minikube
+	KubeContext       string         // Optional kubeconfig context of an existing cluster to use instead of kind
+	KubernetesVersion string         // Optional Kubernetes version, e.g. v1.33 (empty for the kind default)
+	PortMappings      []PortMapping  // Optional node ports exposed on the host
+	NixStore          *ClusterMount  // Optional nix store mount
+	NixSocket         *ClusterMount  // Optional nix socket mount
+	DeskrunCache      *ClusterMount  // Optional deskrun cache mount
+	DockerSocket      *ClusterMount  // Optional docker socket mount
+	WindowsDrives     []ClusterMount // Optional Windows drives of WSL, mounted at their WSL paths
 }
 
 // ClusterMount represents a host-to-container mount configuration for cluster nodes
@@ -85,10 +519,121 @@ type ClusterMount struct {
 // Kept for backward compatibility
 type NixMount = ClusterMount
 
-// ClusterHost represents a remote Incus container running deskrun
+// ClusterSettings represents a named kind cluster that installations can be pinned to
+type ClusterSettings struct {
+	Name string `json:"name"`
+	// Proxy is used by the ARC controller and by all installations of the cluster
+	// that do not set their own proxy
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+	// CacheServer is the in-cluster actions cache server (nil = disabled)
+	CacheServer *CacheServerConfig `json:"cache_server,omitempty"`
+	// Observability is the metrics stack of the cluster (nil = disabled)
+	Observability *ObservabilityConfig `json:"observability,omitempty"`
+	// KubernetesVersion is the Kubernetes version the cluster is created with,
+	// e.g. v1.33 (empty for the default of kind)
+	KubernetesVersion string `json:"kubernetes_version,omitempty"`
+	// KubeContext is the kubeconfig context of an existing cluster (k3s,
+	// minikube, remote, ...) deskrun deploys to instead of creating a kind cluster
+	KubeContext string `json:"kube_context,omitempty"`
+	// PortMappings expose ports of the kind node on the host, so services
+	// started by jobs can be reached from localhost
+	PortMappings []PortMapping `json:"port_mappings,omitempty"`
+	// Offline deploys without pulling images, which 'deskrun images load'
+	// loaded into the cluster node
+	Offline bool `json:"offline,omitempty"`
+}
+
+// PortMapping exposes a port of the kind node on the host
+type PortMapping struct {
+	// HostPort is the port on the host
+	HostPort int32 `json:"host_port"`
+	// ContainerPort is the port on the kind node, typically a NodePort of a service
+	ContainerPort int32 `json:"container_port"`
+	// Protocol is TCP or UDP (empty for TCP)
+	Protocol string `json:"protocol,omitempty"`
+	// ListenAddress is the host address to listen on (empty for 127.0.0.1)
+	ListenAddress string `json:"listen_address,omitempty"`
+}
+
+// String formats the mapping as [address:]hostPort:containerPort/protocol
+func (p PortMapping) String() string {
+	protocol := p.Protocol
+	if protocol == "" {
+		protocol = "TCP"
+	}
+	mapping := fmt.Sprintf("%d:%d/%s", p.HostPort, p.ContainerPort, strings.ToLower(protocol))
+	if p.ListenAddress != "" {
+		mapping = p.ListenAddress + ":" + mapping
+	}
+	return mapping
+}
+
+// CacheServerConfig represents the in-cluster GitHub Actions cache server that
+// runners of a cluster use instead of the GitHub hosted cache
+type CacheServerConfig struct {
+	// Image overrides the cache server image (empty for the default)
+	Image string `json:"image,omitempty"`
+	// HostPath is the directory on the kind node the cache is stored in (empty for the default)
+	HostPath string `json:"host_path,omitempty"`
+	// Size is the capacity of the cache volume, e.g. 50Gi (empty for the default)
+	Size string `json:"size,omitempty"`
+}
+
+// ObservabilityConfig represents the opt-in metrics stack of a cluster: a
+// Prometheus scraping the ARC controller and listeners, and optionally Grafana
+type ObservabilityConfig struct {
+	// Grafana also deploys Grafana with the deskrun dashboard
+	Grafana bool `json:"grafana,omitempty"`
+	// Port is the host port Prometheus is exposed on (0 for the default)
+	Port int32 `json:"port,omitempty"`
+	// GrafanaPort is the host port Grafana is exposed on (0 for the default)
+	GrafanaPort int32 `json:"grafana_port,omitempty"`
+	// Retention is how long Prometheus keeps metrics, e.g. 7d (empty for the default)
+	Retention string `json:"retention,omitempty"`
+}
+
+// AgentConfig represents the policies 'deskrun agent' pauses and resumes
+// runner installations by, so a workstation stops taking jobs when it is
+// unplugged or busy
+type AgentConfig struct {
+	// Installations are the installations the agent manages (empty for all)
+	Installations []string `json:"installations,omitempty"`
+	// PauseOnBattery pauses the installations while the host runs on battery
+	PauseOnBattery bool `json:"pause_on_battery,omitempty"`
+	// MinBattery pauses the installations while the battery charge in percent
+	// is below it and the host is not charging (0 to disable)
+	MinBattery int `json:"min_battery,omitempty"`
+	// MaxLoad pauses the installations while the 1-minute load average per CPU
+	// is above it (0 to disable)
+	MaxLoad float64 `json:"max_load,omitempty"`
+	// Interval is how often the host state is checked, e.g. 30s (empty for the default)
+	Interval string `json:"interval,omitempty"`
+	// CacheGCInterval is how often cache mounts above their max size are
+	// pruned, e.g. 24h (empty to disable)
+	CacheGCInterval string `json:"cache_gc_interval,omitempty"`
+}
+
+// DeploySettings are the defaults of how kapp deploys and deletes apps,
+// overridden by --deploy-timeout and --no-wait
+type DeploySettings struct {
+	// Timeout bounds applying and waiting for the changes of an app, e.g. 30m (empty for 15m)
+	Timeout string `json:"timeout,omitempty"`
+	// NoWait returns once the changes are applied, without waiting for the resources to reconcile
+	NoWait bool `json:"no_wait,omitempty"`
+	// PrePull loads the runner and dind images into the cluster node before
+	// the runners are deployed
+	PrePull bool `json:"pre_pull,omitempty"`
+}
+
+// ClusterHost represents a remote Incus container or virtual machine running deskrun
 type ClusterHost struct {
-	Name      string `json:"name"`
-	Image     string `json:"image"`
-	DiskSize  string `json:"disk_size"`
-	CreatedAt string `json:"created_at"`
+	Name      string   `json:"name"`
+	Remote    string   `json:"remote,omitempty"` // incus remote the host lives on, the default remote if empty
+	Image     string   `json:"image"`
+	DiskSize  string   `json:"disk_size"`
+	VM        bool     `json:"vm,omitempty"`
+	CPUs      int      `json:"cpus,omitempty"`
+	Memory    string   `json:"memory,omitempty"`
+	SSHKeys   []string `json:"ssh_keys,omitempty"` // public keys authorized to log in as root
+	CreatedAt string   `json:"created_at"`
 }
@@ -269,3 +269,121 @@ func TestRunnerInstallationWithMounts(t *testing.T) {
 		t.Errorf("Second mount Type = %v, want DirectoryOrCreate", installation.Mounts[1].Type)
 	}
 }
+
+func TestIsOrganizationURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want bool
+	}{
+		{name: "organization URL", url: "https://github.com/myorg", want: true},
+		{name: "organization URL with trailing slash", url: "https://github.com/myorg/", want: true},
+		{name: "repository URL", url: "https://github.com/owner/repo", want: false},
+		{name: "enterprise URL", url: "https://github.com/enterprises/my-enterprise", want: false},
+		{name: "host only", url: "https://github.com", want: false},
+		{name: "empty", url: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsOrganizationURL(tt.url); got != tt.want {
+				t.Errorf("IsOrganizationURL(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortMappingString(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping PortMapping
+		want    string
+	}{
+		{
+			name:    "default protocol",
+			mapping: PortMapping{HostPort: 8080, ContainerPort: 30080},
+			want:    "8080:30080/tcp",
+		},
+		{
+			name:    "udp with listen address",
+			mapping: PortMapping{HostPort: 5353, ContainerPort: 30053, Protocol: "UDP", ListenAddress: "0.0.0.0"},
+			want:    "0.0.0.0:5353:30053/udp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mapping.String(); got != tt.want {
+				t.Errorf("PortMapping.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimsMount(t *testing.T) {
+	pvc := &RunnerInstallation{CacheStorage: &CacheStorage{Type: CacheTypePVC}}
+	hostPath := &RunnerInstallation{}
+
+	tests := []struct {
+		name  string
+		mount Mount
+		want  bool
+	}{
+		{name: "no source", mount: Mount{Target: "/var/lib/docker"}, want: true},
+		{name: "generated source", mount: Mount{Source: "/tmp/deskrun-cache/var-lib-docker", Target: "/var/lib/docker"}, want: true},
+		{name: "explicit source", mount: Mount{Source: "/nvme/docker", Target: "/var/lib/docker"}, want: false},
+		{name: "socket", mount: Mount{Target: "/var/run/docker.sock", Type: MountTypeSocket}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pvc.ClaimsMount(tt.mount); got != tt.want {
+				t.Errorf("ClaimsMount() = %v, want %v", got, tt.want)
+			}
+			if hostPath.ClaimsMount(tt.mount) {
+				t.Errorf("ClaimsMount() = true without --cache-type pvc")
+			}
+		})
+	}
+
+	if !pvc.ClaimsCachePath(CachePath{Source: "/host-cache/deskrun/root-.cache", Target: "/root/.cache"}) {
+		t.Errorf("ClaimsCachePath() = false for the generated source")
+	}
+}
+
+func TestInstanceSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		generated   bool
+		instanceNum int
+		want        string
+	}{
+		{name: "placeholder", source: "/nvme/docker-cache/instance-{instance}", instanceNum: 2, want: "/nvme/docker-cache/instance-2"},
+		{name: "placeholder of a single instance", source: "/nvme/docker-cache/instance-{instance}", want: "/nvme/docker-cache/instance-1"},
+		{name: "generated", source: "/tmp/deskrun-cache/var-lib-docker", generated: true, instanceNum: 3, want: "/tmp/deskrun-cache/var-lib-docker/instance-3"},
+		{name: "generated of a single instance", source: "/tmp/deskrun-cache/var-lib-docker", generated: true, want: "/tmp/deskrun-cache/var-lib-docker"},
+		{name: "explicit", source: "/nvme/shared", instanceNum: 2, want: "/nvme/shared"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InstanceSource(tt.source, tt.generated, tt.instanceNum); got != tt.want {
+				t.Errorf("InstanceSource() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheClaimNames(t *testing.T) {
+	installation := &RunnerInstallation{
+		CacheStorage: &CacheStorage{Type: CacheTypePVC},
+		CachePaths:   []CachePath{{Target: "/root/.cache"}},
+		Mounts:       []Mount{{Source: "/nvme/cache", Target: "/cache"}, {Target: "/var/lib/docker"}},
+	}
+
+	got := installation.CacheClaimNames("my-runner-2")
+	if len(got) != 2 || got[0] != "my-runner-2-mount-0" || got[1] != "my-runner-2-mount-2" {
+		t.Errorf("CacheClaimNames() = %v, want [my-runner-2-mount-0 my-runner-2-mount-2]", got)
+	}
+}
@@ -2,6 +2,8 @@
 # Generate base templates from Helm for each container mode
 # This script creates pre-rendered templates for kubernetes, dind, and privileged modes
 # that are selected at runtime based on the container mode configuration.
+# The placeholder values the charts are rendered with are turned into ytt data
+# value references, so the bases are ytt templates.
 
 set -e
 
@@ -24,6 +26,20 @@ COMMON_VALUES=(
     --set controllerServiceAccount.namespace=arc-systems
 )
 
+# to_ytt_template turns rendered chart output into a ytt template. Only whole
+# YAML values equal to a placeholder of COMMON_VALUES or the release name are
+# replaced, so names that merely contain them are left alone.
+to_ytt_template() {
+    echo '#@ load("@ytt:data", "data")'
+    echo '#@ load("@ytt:base64", "base64")'
+    sed -E \
+        -e 's%^# Source:%#! Source:%' \
+        -e 's%^( *(- )?[A-Za-z0-9_./-]+): https://github\.com/example/repo$%\1: #@ data.values.installation.repository%' \
+        -e 's%^( *(- )?[A-Za-z0-9_./-]+): cGxhY2Vob2xkZXI=$%\1: #@ base64.encode(data.values.installation.authValue)%' \
+        -e 's%^( *(- )?[A-Za-z0-9_./-]+): "?arc-runner-gha-rs-(github-secret|no-permission|manager|kube-mode)"?$%\1: #@ data.values.installation.name + "-gha-rs-\3"%' \
+        -e 's%^( *(- )?[A-Za-z0-9_./-]+): "?arc-runner"?$%\1: #@ data.values.installation.name%'
+}
+
 # Generate Kubernetes mode template
 echo "  Generating kubernetes mode template..."
 helm template arc-runner "$UPSTREAM_DIR/gha-runner-scale-set" \
@@ -32,7 +48,7 @@ helm template arc-runner "$UPSTREAM_DIR/gha-runner-scale-set" \
     --set 'containerMode.kubernetesModeWorkVolumeClaim.accessModes={ReadWriteOnce}' \
     --set containerMode.kubernetesModeWorkVolumeClaim.storageClassName=standard \
     --set containerMode.kubernetesModeWorkVolumeClaim.resources.requests.storage=1Gi \
-    | sed 's/^# Source:/#! Source:/g' \
+    | to_ytt_template \
     > "$OUTPUT_DIR/kubernetes.yaml"
 echo "    -> $OUTPUT_DIR/kubernetes.yaml"
 
@@ -41,15 +57,17 @@ echo "  Generating dind mode template..."
 helm template arc-runner "$UPSTREAM_DIR/gha-runner-scale-set" \
     "${COMMON_VALUES[@]}" \
     --set containerMode.type=dind \
-    | sed 's/^# Source:/#! Source:/g' \
+    | to_ytt_template \
     > "$OUTPUT_DIR/dind.yaml"
 echo "    -> $OUTPUT_DIR/dind.yaml"
 
 # Generate Privileged mode template (kubernetes-novolume)
 # This is used for cached-privileged-kubernetes mode
 echo "  Generating privileged mode template..."
-# First, add a placeholder ConfigMap for hook extension (will be overlayed with actual content)
-cat > "$OUTPUT_DIR/privileged.yaml" << 'EOF'
+# A placeholder ConfigMap for the hook extension (overlayed with the actual
+# content), followed by the helm-generated template
+{
+    cat << 'EOF'
 #! Placeholder ConfigMap for privileged mode hook extension
 #! This ConfigMap is populated by the overlay with the actual hook extension spec
 apiVersion: v1
@@ -61,15 +79,13 @@ data:
   content: ""
 ---
 EOF
-# Then append the helm-generated template
-helm template arc-runner "$UPSTREAM_DIR/gha-runner-scale-set" \
-    "${COMMON_VALUES[@]}" \
-    --set containerMode.type=kubernetes \
-    --set 'containerMode.kubernetesModeWorkVolumeClaim.accessModes={ReadWriteOnce}' \
-    --set containerMode.kubernetesModeWorkVolumeClaim.storageClassName=standard \
-    --set containerMode.kubernetesModeWorkVolumeClaim.resources.requests.storage=1Gi \
-    | sed 's/^# Source:/#! Source:/g' \
-    >> "$OUTPUT_DIR/privileged.yaml"
+    helm template arc-runner "$UPSTREAM_DIR/gha-runner-scale-set" \
+        "${COMMON_VALUES[@]}" \
+        --set containerMode.type=kubernetes \
+        --set 'containerMode.kubernetesModeWorkVolumeClaim.accessModes={ReadWriteOnce}' \
+        --set containerMode.kubernetesModeWorkVolumeClaim.storageClassName=standard \
+        --set containerMode.kubernetesModeWorkVolumeClaim.resources.requests.storage=1Gi
+} | to_ytt_template > "$OUTPUT_DIR/privileged.yaml"
 echo "    -> $OUTPUT_DIR/privileged.yaml"
 
 echo ""